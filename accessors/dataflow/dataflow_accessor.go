@@ -36,7 +36,7 @@ type DataflowAccessor interface {
 type DataflowAccessorImpl struct{}
 
 func (dfA *DataflowAccessorImpl) LaunchDataflowTemplate(ctx context.Context, c dataflowclient.DataflowClient, parameters map[string]string, cfg DataflowTuningConfig) (string, string, error) {
-	req, err := getDataflowLaunchRequest(parameters, cfg)
+	req, err := GetDataflowLaunchRequest(parameters, cfg)
 	if err != nil {
 		return "", "", err
 	}
@@ -49,7 +49,9 @@ func (dfA *DataflowAccessorImpl) LaunchDataflowTemplate(ctx context.Context, c d
 	return respDf.Job.Id, gCloudCmd, nil
 }
 
-func getDataflowLaunchRequest(parameters map[string]string, cfg DataflowTuningConfig) (*dataflowpb.LaunchFlexTemplateRequest, error) {
+// GetDataflowLaunchRequest generates the flex template launch request for the template parameters (@parameters) and
+// runtime environment config (@cfg), without launching it.
+func GetDataflowLaunchRequest(parameters map[string]string, cfg DataflowTuningConfig) (*dataflowpb.LaunchFlexTemplateRequest, error) {
 	// If custom network is not selected, use public IP. Typical for internal testing flow.
 	vpcSubnetwork := ""
 	workerIpAddressConfig := dataflowpb.WorkerIPAddressConfiguration_WORKER_IP_PUBLIC
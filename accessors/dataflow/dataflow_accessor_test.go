@@ -224,7 +224,7 @@ func TestLaunchDataflowTemplate(t *testing.T) {
 func TestGetDataflowLaunchRequestBasic(t *testing.T) {
 	params := getParameters()
 	cfg := getTuningConfig()
-	actual, err := getDataflowLaunchRequest(params, cfg)
+	actual, err := GetDataflowLaunchRequest(params, cfg)
 	if err != nil {
 		t.Fail()
 	}
@@ -236,7 +236,7 @@ func TestGetDataflowLaunchRequestMissingVpcHost(t *testing.T) {
 	params := getParameters()
 	cfg := getTuningConfig()
 	cfg.VpcHostProjectId = ""
-	_, err := getDataflowLaunchRequest(params, cfg)
+	_, err := GetDataflowLaunchRequest(params, cfg)
 	assert.True(t, err != nil)
 }
 
@@ -244,7 +244,7 @@ func TestGetDataflowLaunchRequestNameToLowerCase(t *testing.T) {
 	params := getParameters()
 	cfg := getTuningConfig()
 	cfg.JobName = "CAPITalJobName"
-	actual, err := getDataflowLaunchRequest(params, cfg)
+	actual, err := GetDataflowLaunchRequest(params, cfg)
 	if err != nil {
 		t.Fail()
 	}
@@ -23,6 +23,7 @@ import (
 // Pass in unit tests where SpannerAccessor is an input parameter.
 type SpannerAccessorMock struct {
 	GetDatabaseDialectMock          func(ctx context.Context, dbURI string) (string, error)
+	GetDatabaseDdlMock              func(ctx context.Context, dbURI string) ([]string, error)
 	CheckExistingDbMock             func(ctx context.Context, dbURI string) (bool, error)
 	CreateEmptyDatabaseMock         func(ctx context.Context, dbURI string) error
 	GetSpannerLeaderLocationMock    func(ctx context.Context, instanceURI string) (string, error)
@@ -43,6 +44,10 @@ func (sam *SpannerAccessorMock) GetDatabaseDialect(ctx context.Context, dbURI st
 	return sam.GetDatabaseDialectMock(ctx, dbURI)
 }
 
+func (sam *SpannerAccessorMock) GetDatabaseDdl(ctx context.Context, dbURI string) ([]string, error) {
+	return sam.GetDatabaseDdlMock(ctx, dbURI)
+}
+
 func (sam *SpannerAccessorMock) CheckExistingDb(ctx context.Context, dbURI string) (bool, error) {
 	return sam.CheckExistingDbMock(ctx, dbURI)
 }
@@ -84,6 +89,8 @@ func (sam *SpannerAccessorMock) ValidateDDL(ctx context.Context, dbURI string) e
 }
 func (sam *SpannerAccessorMock) UpdateDDLForeignKeys(ctx context.Context, dbURI string, conv *internal.Conv, driver string, migrationType string) {
 }
+func (sam *SpannerAccessorMock) CreateDeferredIndexes(ctx context.Context, dbURI string, conv *internal.Conv, driver string) {
+}
 // DropDatabase implements SpannerAccessor.
 func (sam *SpannerAccessorMock) DropDatabase(ctx context.Context, dbURI string) error {
 	return sam.DropDatabaseMock(ctx, dbURI)
@@ -42,6 +42,13 @@ var (
 	// AdminQuota limits are mentioned here: https://cloud.google.com/spanner/quotas#administrative_limits
 	// If facing a quota limit error, consider reducing this value.
 	MaxWorkers = 50
+	// Set the maximum number of secondary indexes built in parallel when index
+	// creation is deferred until after the data migration. Each index build
+	// backfills all rows of its table, so this is kept well below MaxWorkers.
+	MaxIndexWorkers = 10
+	// Delay between two consecutive batches of deferred index creation requests,
+	// to stay under the AdminQuota limit.
+	IndexBatchInterval = 5 * time.Second
 )
 
 // The SpannerAccessor provides methods that internally use a spanner client (can be adminClient/databaseclient/instanceclient etc).
@@ -49,6 +56,8 @@ var (
 type SpannerAccessor interface {
 	// Fetch the dialect of the spanner database.
 	GetDatabaseDialect(ctx context.Context, dbURI string) (string, error)
+	// Fetch the DDL statements of the schema of the spanner database.
+	GetDatabaseDdl(ctx context.Context, dbURI string) ([]string, error)
 	// CheckExistingDb checks whether the database with dbURI exists or not.
 	// If API call doesn't respond then user is informed after every 5 minutes on command line.
 	CheckExistingDb(ctx context.Context, dbURI string) (bool, error)
@@ -74,6 +83,8 @@ type SpannerAccessor interface {
 	ValidateDDL(ctx context.Context, dbURI string) error
 	// UpdateDDLForeignKeys updates the Spanner database with foreign key constraints using ALTER TABLE statements.
 	UpdateDDLForeignKeys(ctx context.Context, dbURI string, conv *internal.Conv, driver string, migrationType string)
+	// CreateDeferredIndexes creates the secondary indexes that were left out of the initial schema.
+	CreateDeferredIndexes(ctx context.Context, dbURI string, conv *internal.Conv, driver string)
 	// Deletes a database.
 	DropDatabase(ctx context.Context, dbURI string) error
 	//Runs a query against the provided spanner database and returns if the executed DML is validate or not
@@ -123,6 +134,14 @@ func (sp *SpannerAccessorImpl) GetDatabaseDialect(ctx context.Context, dbURI str
 	return strings.ToLower(result.DatabaseDialect.String()), nil
 }
 
+func (sp *SpannerAccessorImpl) GetDatabaseDdl(ctx context.Context, dbURI string) ([]string, error) {
+	result, err := sp.AdminClient.GetDatabaseDdl(ctx, &adminpb.GetDatabaseDdlRequest{Database: dbURI})
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch database ddl: %v", err)
+	}
+	return result.Statements, nil
+}
+
 func (sp *SpannerAccessorImpl) CheckExistingDb(ctx context.Context, dbURI string) (bool, error) {
 	gotResponse := make(chan bool)
 	var err error
@@ -290,7 +309,7 @@ func (sp *SpannerAccessorImpl) CreateDatabase(ctx context.Context, dbURI string,
 		if migrationType == constants.DATAFLOW_MIGRATION {
 			req.ExtraStatements = ddl.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: true, SpDialect: conv.SpDialect, Source: driver}, conv.SpSchema, conv.SpSequences)
 		} else {
-			req.ExtraStatements = ddl.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: false, SpDialect: conv.SpDialect, Source: driver}, conv.SpSchema, conv.SpSequences)
+			req.ExtraStatements = ddl.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: false, SkipIndexes: conv.Audit.DeferIndexes, SpDialect: conv.SpDialect, Source: driver}, conv.SpSchema, conv.SpSequences)
 		}
 
 	}
@@ -316,7 +335,8 @@ func (sp *SpannerAccessorImpl) UpdateDatabase(ctx context.Context, dbURI string,
 	// Spanner DDL doesn't accept them), and protects table and col names
 	// using backticks (to avoid any issues with Spanner reserved words).
 	// Foreign Keys are set to false since we create them post data migration.
-	schema := ddl.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: false, SpDialect: conv.SpDialect, Source: driver}, conv.SpSchema, conv.SpSequences)
+	// Secondary indexes are skipped if they are to be created post data migration.
+	schema := ddl.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: false, SkipIndexes: conv.Audit.DeferIndexes, SpDialect: conv.SpDialect, Source: driver}, conv.SpSchema, conv.SpSequences)
 	req := &adminpb.UpdateDatabaseDdlRequest{
 		Database:   dbURI,
 		Statements: schema,
@@ -466,6 +486,125 @@ func (sp *SpannerAccessorImpl) UpdateDDLForeignKeys(ctx context.Context, dbURI s
 	conv.Audit.Progress.Done()
 }
 
+// CreateDeferredIndexes creates the secondary indexes of conv.SpSchema in an
+// existing database whose tables were created without them (see
+// Audit.DeferIndexes). Indexes that already exist, e.g. because they were
+// created by the interrupted run of a resumed migration, are skipped. Indexes
+// are built in batches of at most MaxIndexWorkers parallel requests. Failure
+// to create an index is recorded in conv.FailedIndexes for the report and
+// doesn't stop the creation of the others.
+func (sp *SpannerAccessorImpl) CreateDeferredIndexes(ctx context.Context, dbURI string, conv *internal.Conv, driver string) {
+	type indexStmt struct {
+		name, table, stmt string
+	}
+	existing, err := sp.existingIndexes(ctx, dbURI, conv.SpDialect)
+	if err != nil {
+		// Creating an existing index fails, which is reported.
+		logger.Log.Warn("Can't read existing indexes, creating all secondary indexes", zap.Error(err))
+	}
+	c := ddl.Config{Comments: false, ProtectIds: true, SpDialect: conv.SpDialect, Source: driver}
+	var indexStmts []indexStmt
+	for _, tableId := range ddl.GetSortedTableIdsBySpName(conv.SpSchema) {
+		table := conv.SpSchema[tableId]
+		for _, index := range table.Indexes {
+			if existing[index.Name] {
+				logger.Log.Debug("Skipping existing index", zap.String("index", index.Name))
+				continue
+			}
+			indexStmts = append(indexStmts, indexStmt{name: index.Name, table: table.Name, stmt: index.PrintCreateIndex(table, c)})
+		}
+	}
+	if len(indexStmts) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("Updating schema of database %s with secondary indexes ...", dbURI)
+	conv.Audit.Progress = *internal.NewProgress(int64(len(indexStmts)), msg, internal.Verbose(), true, int(internal.IndexCreationInProgress))
+
+	var progressMutex sync.Mutex
+	progress := int64(0)
+	for start := 0; start < len(indexStmts); start += MaxIndexWorkers {
+		if start > 0 {
+			time.Sleep(IndexBatchInterval)
+		}
+		end := start + MaxIndexWorkers
+		if end > len(indexStmts) {
+			end = len(indexStmts)
+		}
+		var wg sync.WaitGroup
+		for _, is := range indexStmts[start:end] {
+			wg.Add(1)
+			go func(is indexStmt) {
+				defer func() {
+					progressMutex.Lock()
+					progress++
+					conv.Audit.Progress.MaybeReport(progress)
+					progressMutex.Unlock()
+					wg.Done()
+				}()
+				internal.VerbosePrintf("Submitting new index create request: %s\n", is.stmt)
+				logger.Log.Debug("Submitting new index create request", zap.String("indexStmt", is.stmt))
+
+				op, err := sp.AdminClient.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+					Database:   dbURI,
+					Statements: []string{is.stmt},
+				})
+				if err == nil {
+					err = op.Wait(ctx)
+				}
+				if err != nil {
+					logger.Log.Debug("Can't create index with statement:" + is.stmt + "\n due to error:" + err.Error() + " Skipping this index...\n")
+					progressMutex.Lock()
+					conv.FailedIndexes = append(conv.FailedIndexes, internal.FailedIndex{Name: is.name, Table: is.table, Error: err.Error()})
+					progressMutex.Unlock()
+					return
+				}
+				internal.VerbosePrintln("Updated schema with statement: " + is.stmt)
+				logger.Log.Debug("Updated schema with statement", zap.String("indexStmt", is.stmt))
+			}(is)
+		}
+		wg.Wait()
+	}
+	conv.Audit.Progress.UpdateProgress("Secondary index creation complete.", 100, internal.IndexCreationComplete)
+	conv.Audit.Progress.Done()
+}
+
+// existingIndexes returns the names of the secondary indexes of the
+// database, read from information_schema.indexes.
+func (sp *SpannerAccessorImpl) existingIndexes(ctx context.Context, dbURI, dialect string) (map[string]bool, error) {
+	client := sp.SpannerClient
+	if client == nil {
+		c, err := spannerclient.NewSpannerClientImpl(ctx, dbURI)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	}
+	stmt := spanner.Statement{
+		SQL: `SELECT INDEX_NAME FROM INFORMATION_SCHEMA.INDEXES WHERE TABLE_SCHEMA = '' AND INDEX_TYPE = 'INDEX'`,
+	}
+	if dialect == constants.DIALECT_POSTGRESQL {
+		stmt.SQL = `SELECT index_name FROM information_schema.indexes WHERE table_schema = 'public' AND index_type = 'INDEX'`
+	}
+	iter := client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+	existing := make(map[string]bool)
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read row from indexes table: %w", err)
+		}
+		var name string
+		if err := row.Columns(&name); err != nil {
+			return nil, fmt.Errorf("can't scan row from indexes table: %v", err)
+		}
+		existing[name] = true
+	}
+	return existing, nil
+}
+
 func (sp *SpannerAccessorImpl) DropDatabase(ctx context.Context, dbURI string) error {
 
 	err := sp.AdminClient.DropDatabase(ctx, &adminpb.DropDatabaseRequest{Database: dbURI})
@@ -17,6 +17,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
 
 	"cloud.google.com/go/spanner"
@@ -101,6 +102,43 @@ func TestSpannerAccessorImpl_GetDatabaseDialect(t *testing.T) {
 	}
 }
 
+func TestSpannerAccessorImpl_GetDatabaseDdl(t *testing.T) {
+	testCases := []struct {
+		name        string
+		acm         spanneradmin.AdminClientMock
+		expectError bool
+		want        []string
+	}{
+		{
+			name: "Basic",
+			acm: spanneradmin.AdminClientMock{
+				GetDatabaseDdlMock: func(ctx context.Context, req *databasepb.GetDatabaseDdlRequest, opts ...gax.CallOption) (*databasepb.GetDatabaseDdlResponse, error) {
+					return &databasepb.GetDatabaseDdlResponse{Statements: []string{"CREATE TABLE t (a INT64) PRIMARY KEY (a)"}}, nil
+				},
+			},
+			expectError: false,
+			want:        []string{"CREATE TABLE t (a INT64) PRIMARY KEY (a)"},
+		},
+		{
+			name: "Error case",
+			acm: spanneradmin.AdminClientMock{
+				GetDatabaseDdlMock: func(ctx context.Context, req *databasepb.GetDatabaseDdlRequest, opts ...gax.CallOption) (*databasepb.GetDatabaseDdlResponse, error) {
+					return nil, fmt.Errorf("test-error")
+				},
+			},
+			expectError: true,
+			want:        nil,
+		},
+	}
+	ctx := context.Background()
+	for _, tc := range testCases {
+		spA := SpannerAccessorImpl{AdminClient: &tc.acm}
+		got, err := spA.GetDatabaseDdl(ctx, "testUri")
+		assert.Equal(t, tc.expectError, err != nil, tc.name)
+		assert.Equal(t, tc.want, got, tc.name)
+	}
+}
+
 func TestSpannerAccessorImpl_CheckExistingDb(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -802,6 +840,100 @@ func TestSpannerAccessorImpl_UpdateDDLForeignKey(t *testing.T) {
 	}
 }
 
+func TestSpannerAccessorImpl_CreateDeferredIndexes(t *testing.T) {
+	spSchema := ddl.Schema{
+		"t1": {
+			Name:        "table1",
+			Id:          "t1",
+			ColIds:      []string{"c1", "c2"},
+			ColDefs:     map[string]ddl.ColumnDef{"c1": {Name: "a", Id: "c1", T: ddl.Type{Name: ddl.Int64}}, "c2": {Name: "b", Id: "c2", T: ddl.Type{Name: ddl.Int64}}},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+			Indexes: []ddl.CreateIndex{
+				{Name: "index1", TableId: "t1", Id: "i1", Keys: []ddl.IndexKey{{ColId: "c2"}}},
+				{Name: "index2", TableId: "t1", Id: "i2", Unique: true, Keys: []ddl.IndexKey{{ColId: "c2", Desc: true}}},
+			},
+		},
+	}
+	testCases := []struct {
+		name           string
+		failingStmt    string
+		existing       []string
+		spSchema       ddl.Schema
+		expectedStmts  []string
+		expectedFailed []internal.FailedIndex
+	}{
+		{
+			name:          "All indexes created",
+			spSchema:      spSchema,
+			expectedStmts: []string{"CREATE INDEX `index1` ON `table1` (`b`)", "CREATE UNIQUE INDEX `index2` ON `table1` (`b` DESC)"},
+		},
+		{
+			name:           "Failed index doesn't stop the others",
+			failingStmt:    "CREATE INDEX `index1` ON `table1` (`b`)",
+			spSchema:       spSchema,
+			expectedStmts:  []string{"CREATE INDEX `index1` ON `table1` (`b`)", "CREATE UNIQUE INDEX `index2` ON `table1` (`b` DESC)"},
+			expectedFailed: []internal.FailedIndex{{Name: "index1", Table: "table1", Error: "error"}},
+		},
+		{
+			name:          "Existing indexes skipped",
+			existing:      []string{"index1"},
+			spSchema:      spSchema,
+			expectedStmts: []string{"CREATE UNIQUE INDEX `index2` ON `table1` (`b` DESC)"},
+		},
+		{
+			name:     "No indexes",
+			spSchema: ddl.Schema{},
+		},
+	}
+	ctx := context.Background()
+	for _, tc := range testCases {
+		var mu sync.Mutex
+		var stmts []string
+		acm := spanneradmin.AdminClientMock{
+			UpdateDatabaseDdlMock: func(ctx context.Context, req *databasepb.UpdateDatabaseDdlRequest, opts ...gax.CallOption) (spanneradmin.UpdateDatabaseDdlOperation, error) {
+				mu.Lock()
+				stmts = append(stmts, req.Statements...)
+				mu.Unlock()
+				return &spanneradmin.UpdateDatabaseDdlOperationMock{
+					WaitMock: func(ctx context.Context, opts ...gax.CallOption) error {
+						if req.Statements[0] == tc.failingStmt {
+							return fmt.Errorf("error")
+						}
+						return nil
+					},
+				}, nil
+			},
+		}
+		existing := tc.existing
+		scm := spannerclient.SpannerClientMock{
+			SingleMock: func() spannerclient.ReadOnlyTransaction {
+				return &spannerclient.ReadOnlyTransactionMock{
+					QueryMock: func(ctx context.Context, stmt spanner.Statement) spannerclient.RowIterator {
+						return &spannerclient.RowIteratorMock{
+							NextMock: func() (*spanner.Row, error) {
+								if len(existing) == 0 {
+									return nil, iterator.Done
+								}
+								row, err := spanner.NewRow([]string{"INDEX_NAME"}, []interface{}{existing[0]})
+								existing = existing[1:]
+								return row, err
+							},
+							StopMock: func() {},
+						}
+					},
+				}
+			},
+		}
+		conv := internal.MakeConv()
+		conv.SpDialect = constants.DIALECT_GOOGLESQL
+		conv.SpSchema = tc.spSchema
+		spA := SpannerAccessorImpl{AdminClient: &acm, SpannerClient: scm}
+		spA.CreateDeferredIndexes(ctx, "projects/project-id/instances/instance-id/databases/database-id", conv, "")
+		assert.ElementsMatch(t, tc.expectedStmts, stmts, tc.name)
+		assert.Equal(t, tc.expectedFailed, conv.FailedIndexes, tc.name)
+	}
+}
+
 func TestValidateDML(t *testing.T) {
 	ctx := context.Background()
 	t.Run("Valid DML", func(t *testing.T) {
@@ -34,7 +34,9 @@ type CostAssessmentOutput struct {
 
 type SchemaAssessmentOutput struct {
 	//TBD
-	tableNames []string
+	tableNames       []string
+	storedProcedures []assessment.StoredProcedureAssessment
+	triggers         []assessment.TriggerAssessment
 }
 
 type AppCodeAssessmentOutput struct {
@@ -50,7 +52,8 @@ type PerformanceAssessmentOutput struct {
 }
 
 type assessmentCollectors struct {
-	sampleCollector assessment.SampleCollector
+	sampleCollector        assessment.SampleCollector
+	sourceObjectsCollector assessment.SourceObjectsCollector
 }
 
 func PerformAssessment(conv *internal.Conv) (AssessmentOutput, error) {
@@ -59,7 +62,7 @@ func PerformAssessment(conv *internal.Conv) (AssessmentOutput, error) {
 
 	output := AssessmentOutput{}
 	// Initialize collectors
-	c, err := initializeCollectors()
+	c, err := initializeCollectors(conv)
 	if err != nil {
 		logger.Log.Fatal("unable to initiliaze collectors")
 		return output, err
@@ -76,13 +79,18 @@ func PerformAssessment(conv *internal.Conv) (AssessmentOutput, error) {
 }
 
 // Initilize collectors. Take a decision here on which collectors are mandatory and which are optional
-func initializeCollectors() (assessmentCollectors, error) {
+func initializeCollectors(conv *internal.Conv) (assessmentCollectors, error) {
 	c := assessmentCollectors{}
 	sampleCollector, err := assessment.CreateSampleCollector()
 	if err != nil {
 		return c, err
 	}
 	c.sampleCollector = sampleCollector
+	sourceObjectsCollector, err := assessment.CreateSourceObjectsCollector(conv)
+	if err != nil {
+		return c, err
+	}
+	c.sourceObjectsCollector = sourceObjectsCollector
 	return c, nil
 }
 
@@ -90,5 +98,7 @@ func performSchemaAssessment(collectors assessmentCollectors) (SchemaAssessmentO
 	schemaOut := SchemaAssessmentOutput{}
 	tables := collectors.sampleCollector.ListTables()
 	schemaOut.tableNames = tables
+	schemaOut.storedProcedures = collectors.sourceObjectsCollector.ListStoredProcedures()
+	schemaOut.triggers = collectors.sourceObjectsCollector.ListTriggers()
 	return schemaOut, nil
 }
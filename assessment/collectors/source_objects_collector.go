@@ -0,0 +1,70 @@
+/* Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.*/
+
+package assessment
+
+import (
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+)
+
+// Collects the stored procedures, functions and triggers of the source
+// database from the conversion context
+type SourceObjectsCollector struct {
+	storedProcedures []StoredProcedureAssessment
+	triggers         []TriggerAssessment
+}
+
+func CreateSourceObjectsCollector(conv *internal.Conv) (SourceObjectsCollector, error) {
+	logger.Log.Info("initializing source objects collector")
+	c := SourceObjectsCollector{}
+	for _, o := range conv.SrcObjects {
+		db := DbIdentifier{namespace: o.Schema}
+		switch o.Kind {
+		case schema.Procedure, schema.Function:
+			c.storedProcedures = append(c.storedProcedures, StoredProcedureAssessment{
+				db:             db,
+				name:           o.Name,
+				linesOfCode:    countLines(o.Body),
+				tablesAffected: o.ReferencedTables,
+			})
+		case schema.Trigger:
+			t := TriggerAssessment{db: db, name: o.Name, operation: o.Event}
+			if o.Table != "" {
+				t.targetTables = []string{o.Table}
+			}
+			c.triggers = append(c.triggers, t)
+		}
+	}
+	return c, nil
+}
+
+func (c SourceObjectsCollector) ListStoredProcedures() []StoredProcedureAssessment {
+	return c.storedProcedures
+}
+
+func (c SourceObjectsCollector) ListTriggers() []TriggerAssessment {
+	return c.triggers
+}
+
+func countLines(body string) int {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return 0
+	}
+	return strings.Count(body, "\n") + 1
+}
@@ -141,7 +141,7 @@ func generateConv(cmd *AssessmentCmd) (*internal.Conv, subcommands.ExitStatus) {
 		if err != nil {
 			return nil, subcommands.ExitFailure
 		}
-		expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessorImpl(context.Background(), targetProfile.Conn.Sp.Project, targetProfile.Conn.Sp.Instance)
+		expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessor(context.Background(), targetProfile.Conn.Sp.Project, targetProfile.Conn.Sp.Instance, false)
 		schemaToSpanner := common.SchemaToSpannerImpl{
 			ExpressionVerificationAccessor: expressionVerificationAccessor,
 		}
@@ -152,7 +152,7 @@ func generateConv(cmd *AssessmentCmd) (*internal.Conv, subcommands.ExitStatus) {
 		}
 	} else {
 		ctx := context.Background()
-		ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, "", "")
+		ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, "", "", false)
 		if err != nil {
 			logger.Log.Error(fmt.Sprintf("error trying create ddl verifier: %v", err))
 			return nil, subcommands.ExitFailure
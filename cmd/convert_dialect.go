@@ -0,0 +1,291 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	spanneraccessor "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/utils"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/conversion"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
+	"github.com/google/subcommands"
+)
+
+// dialectReportFile holds the parts of the schema that are dropped or
+// changed by a dialect conversion.
+const dialectReportFile = ".dialect_report.txt"
+
+// ConvertDialectCmd is the command for converting a Spanner database to a new
+// database of the other dialect.
+type ConvertDialectCmd struct {
+	sourceProfile string
+	targetProfile string
+	schemaOnly    bool
+	dryRun        bool
+	filePrefix    string
+	WriteLimit    int64
+	logLevel      string
+}
+
+// Name returns the name of operation.
+func (cmd *ConvertDialectCmd) Name() string {
+	return "convert-dialect"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *ConvertDialectCmd) Synopsis() string {
+	return "convert a Spanner database to a new database of the other dialect"
+}
+
+// Usage returns usage info of the command.
+func (cmd *ConvertDialectCmd) Usage() string {
+	return fmt.Sprintf(`%v convert-dialect --source-profile="instance=my-instance,dbName=my-db" --target-profile="instance=my-instance,dbName=my-pg-db" ...
+
+Convert the schema of an existing Spanner database from GoogleSQL to the
+PostgreSQL dialect or back, create a new database of the target dialect with
+the converted schema and copy the data to it. The parts of the schema that
+can't be expressed in the target dialect are written to a report. The
+convert-dialect flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *ConvertDialectCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.sourceProfile, "source-profile", "", "Connection profile of the Spanner database to convert, e.g. \"instance=my-instance,dbName=my-db\"")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Connection profile of the new Spanner database, e.g. \"instance=my-instance,dbName=my-pg-db\"; the dialect defaults to the other dialect")
+	f.BoolVar(&cmd.schemaOnly, "schema-only", false, "Create the converted schema without copying the data")
+	f.BoolVar(&cmd.dryRun, "dry-run", false, "Write the converted schema and the report without creating the database")
+	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
+	f.Int64Var(&cmd.WriteLimit, "write-limit", DefaultWritersLimit, "Write limit for writes to spanner")
+	f.StringVar(&cmd.logLevel, "log-level", "DEBUG", "Configure the logging level for the command (INFO, DEBUG), defaults to DEBUG")
+}
+
+func (cmd *ConvertDialectCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	err := logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+	srcProfile, targetProfile, err := parseDialectProfiles(cmd.sourceProfile, cmd.targetProfile)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitUsageError
+	}
+	srcDbURI, err := dialectDbURI(ctx, &srcProfile)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	dbURI, err := dialectDbURI(ctx, &targetProfile)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	spA, err := spanneraccessor.NewSpannerAccessorClientImpl(ctx)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	conv, srcSchema, srcDialect, issues, err := convertDialectSchema(ctx, spA, srcDbURI, targetProfile)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = targetProfile.Conn.Sp.Dbname
+	}
+	banner := utils.GetBanner(time.Now(), dbURI)
+	if err = writeDialectFiles(conv, issues, cmd.filePrefix, banner); err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("Converted %d tables from %s to %s with %d issues, see '%s'\n", len(conv.SpSchema), srcDialect, conv.SpDialect, len(issues), cmd.filePrefix+dialectReportFile)
+	if cmd.dryRun {
+		fmt.Printf("Dry run: the converted schema was written to '%s'\n", cmd.filePrefix+schemaFile)
+		return subcommands.ExitSuccess
+	}
+	if err = cmd.createAndCopy(ctx, spA, conv, srcSchema, srcDialect, srcDbURI, dbURI, banner); err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// parseDialectProfiles parses the source and target profiles of a dialect
+// conversion. The target dialect defaults to the dialect the source isn't,
+// which is only known once the source database is read, so it is left empty
+// when the target profile doesn't set it.
+func parseDialectProfiles(source, target string) (profiles.TargetProfile, profiles.TargetProfile, error) {
+	srcProfile, err := profiles.NewTargetProfile(source)
+	if err != nil {
+		return profiles.TargetProfile{}, profiles.TargetProfile{}, fmt.Errorf("source profile is not properly configured: %v", err)
+	}
+	targetProfile, err := profiles.NewTargetProfile(target)
+	if err != nil {
+		return profiles.TargetProfile{}, profiles.TargetProfile{}, fmt.Errorf("target profile is not properly configured: %v", err)
+	}
+	if srcProfile.Conn.Sp.Instance == "" || srcProfile.Conn.Sp.Dbname == "" {
+		return profiles.TargetProfile{}, profiles.TargetProfile{}, fmt.Errorf("instance and dbName must be specified in source-profile")
+	}
+	if targetProfile.Conn.Sp.Instance == "" || targetProfile.Conn.Sp.Dbname == "" {
+		return profiles.TargetProfile{}, profiles.TargetProfile{}, fmt.Errorf("instance and dbName must be specified in target-profile")
+	}
+	params, _ := profiles.ParseMap(target)
+	if _, ok := params["dialect"]; !ok {
+		targetProfile.Conn.Sp.Dialect = ""
+	}
+	return srcProfile, targetProfile, nil
+}
+
+func dialectDbURI(ctx context.Context, profile *profiles.TargetProfile) (string, error) {
+	project, instance, dbName, err := profile.GetResourceIds(ctx, time.Now(), constants.SPANNER, os.Stdout, &utils.GetUtilInfoImpl{})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, dbName), nil
+}
+
+// convertDialectSchema reads the schema of the database srcDbURI and converts
+// it to the dialect of targetProfile. It returns the conv holding the
+// converted schema, the schema and dialect of the source database and the
+// conversion issues.
+func convertDialectSchema(ctx context.Context, spA spanneraccessor.SpannerAccessor, srcDbURI string, targetProfile profiles.TargetProfile) (*internal.Conv, ddl.Schema, string, []spanner.DialectIssue, error) {
+	srcDialect, err := spA.GetDatabaseDialect(ctx, srcDbURI)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	if srcDialect == "database_dialect_unspecified" {
+		// Databases created before dialects existed use GoogleSQL.
+		srcDialect = constants.DIALECT_GOOGLESQL
+	}
+	if srcDialect != constants.DIALECT_GOOGLESQL && srcDialect != constants.DIALECT_POSTGRESQL {
+		return nil, nil, "", nil, fmt.Errorf("unsupported dialect %s of database %s", srcDialect, srcDbURI)
+	}
+	dialect := targetProfile.Conn.Sp.Dialect
+	if dialect == "" {
+		dialect = constants.DIALECT_POSTGRESQL
+		if srcDialect == constants.DIALECT_POSTGRESQL {
+			dialect = constants.DIALECT_GOOGLESQL
+		}
+	}
+	if dialect == srcDialect {
+		return nil, nil, "", nil, fmt.Errorf("database %s already uses the %s dialect", srcDbURI, dialect)
+	}
+	statements, err := spA.GetDatabaseDdl(ctx, srcDbURI)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	conv := internal.MakeConv()
+	conv.SpDialect = dialect
+	conv.Source = constants.SPANNER
+	conv.Audit.SkipMetricsPopulation = os.Getenv("SKIP_METRICS_POPULATION") == "true"
+	srcSchema, issues, err := spanner.ConvertDialect(conv, statements, srcDialect)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	return conv, srcSchema, srcDialect, issues, nil
+}
+
+// writeDialectFiles writes the conversion issues and the DDL of the
+// converted schema.
+func writeDialectFiles(conv *internal.Conv, issues []spanner.DialectIssue, filePrefix, banner string) error {
+	f, err := os.Create(filePrefix + dialectReportFile)
+	if err != nil {
+		return fmt.Errorf("can't create dialect report file %s: %v", filePrefix+dialectReportFile, err)
+	}
+	w := bufio.NewWriter(f)
+	w.WriteString(banner)
+	writeDialectReport(w, conv.SpDialect, issues)
+	w.Flush()
+	f.Close()
+	stmts := ddl.GetDDL(ddl.Config{Comments: false, ProtectIds: true, Tables: true, ForeignKeys: true, SpDialect: conv.SpDialect, Source: constants.SPANNER}, conv.SpSchema, conv.SpSequences)
+	if err = os.WriteFile(filePrefix+schemaFile, []byte(strings.Join(stmts, ";\n\n")+";\n"), 0644); err != nil {
+		return fmt.Errorf("can't write schema file %s: %v", filePrefix+schemaFile, err)
+	}
+	return nil
+}
+
+func writeDialectReport(w io.Writer, dialect string, issues []spanner.DialectIssue) {
+	if len(issues) == 0 {
+		fmt.Fprintf(w, "The schema was converted to the %s dialect without issues.\n", dialect)
+		return
+	}
+	fmt.Fprintf(w, "%d parts of the schema were dropped or changed by the conversion to the %s dialect:\n", len(issues), dialect)
+	for _, issue := range issues {
+		fmt.Fprintf(w, "  - %s\n", issue)
+	}
+}
+
+// createAndCopy creates the database dbURI with the converted schema and,
+// unless only the schema is converted, copies the data of srcDbURI to it.
+// Indexes are created after the data is copied, and foreign keys last.
+func (cmd *ConvertDialectCmd) createAndCopy(ctx context.Context, spA spanneraccessor.SpannerAccessor, conv *internal.Conv, srcSchema ddl.Schema, srcDialect, srcDbURI, dbURI, banner string) error {
+	exists, err := spA.CheckExistingDb(ctx, dbURI)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("database %s already exists, the converted database must be a new database", dbURI)
+	}
+	conv.Audit.DeferIndexes = !cmd.schemaOnly
+	if err = spA.CreateDatabase(ctx, dbURI, conv, constants.SPANNER, ""); err != nil {
+		return fmt.Errorf("can't create database %s: %v", dbURI, err)
+	}
+	if !cmd.schemaOnly {
+		srcClient, err := utils.GetClient(ctx, srcDbURI)
+		if err != nil {
+			return fmt.Errorf("can't create client for db %s: %v", srcDbURI, err)
+		}
+		defer srcClient.Close()
+		client, err := utils.GetClient(ctx, dbURI)
+		if err != nil {
+			return fmt.Errorf("can't create client for db %s: %v", dbURI, err)
+		}
+		defer client.Close()
+		config := writer.BatchWriterConfig{
+			BytesLimit: 100 * 1000 * 1000,
+			WriteLimit: cmd.WriteLimit,
+			RetryLimit: 1000,
+			Verbose:    internal.Verbose(),
+		}
+		conv.SetDataMode()
+		bw, err := conversion.CopyDialectData(ctx, conv, srcSchema, srcDialect, srcClient, config, client, &conversion.PopulateDataConvImpl{})
+		if err != nil {
+			return err
+		}
+		spA.CreateDeferredIndexes(ctx, dbURI, conv, constants.SPANNER)
+		conversion.WriteBadData(bw, conv, banner, cmd.filePrefix+badDataFile, os.Stdout)
+		written := utils.SumMapValues(bw.WrittenRowsByTable())
+		fmt.Printf("Copied %d of %d rows, see '%s' for the rows that couldn't be copied\n", written, utils.SumMapValues(conv.Stats.Rows), cmd.filePrefix+badDataFile)
+	}
+	spA.UpdateDDLForeignKeys(ctx, dbURI, conv, constants.SPANNER, "")
+	fmt.Printf("Created database %s\n", dbURI)
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	spanneraccessor "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/spanner"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestParseDialectProfiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		target      string
+		wantDialect string
+		wantErr     bool
+	}{
+		{"default dialect", "instance=i,dbName=src", "instance=i,dbName=dst", "", false},
+		{"explicit dialect", "instance=i,dbName=src", "instance=i,dbName=dst,dialect=PostgreSQL", constants.DIALECT_POSTGRESQL, false},
+		{"missing source db", "instance=i", "instance=i,dbName=dst", "", true},
+		{"missing target db", "instance=i,dbName=src", "instance=i", "", true},
+		{"invalid dialect", "instance=i,dbName=src", "instance=i,dbName=dst,dialect=mysql", "", true},
+	}
+	for _, tc := range tests {
+		_, targetProfile, err := parseDialectProfiles(tc.source, tc.target)
+		assert.Equal(t, tc.wantErr, err != nil, tc.name)
+		if !tc.wantErr {
+			assert.Equal(t, tc.wantDialect, targetProfile.Conn.Sp.Dialect, tc.name)
+		}
+	}
+}
+
+func TestConvertDialectSchema(t *testing.T) {
+	logger.Log = zap.NewNop()
+	statements := []string{"CREATE TABLE t (id INT64 NOT NULL, tags ARRAY<STRING(MAX)>) PRIMARY KEY (id)"}
+	tests := []struct {
+		name        string
+		srcDialect  string
+		dialect     string
+		wantDialect string
+		wantIssues  int
+		wantErr     bool
+	}{
+		{"googlesql source", constants.DIALECT_GOOGLESQL, "", constants.DIALECT_POSTGRESQL, 1, false},
+		{"unspecified dialect source", "database_dialect_unspecified", "", constants.DIALECT_POSTGRESQL, 1, false},
+		{"same dialect", constants.DIALECT_GOOGLESQL, constants.DIALECT_GOOGLESQL, "", 0, true},
+	}
+	for _, tc := range tests {
+		spA := &spanneraccessor.SpannerAccessorMock{
+			GetDatabaseDialectMock: func(ctx context.Context, dbURI string) (string, error) { return tc.srcDialect, nil },
+			GetDatabaseDdlMock:     func(ctx context.Context, dbURI string) ([]string, error) { return statements, nil },
+		}
+		_, targetProfile, err := parseDialectProfiles("instance=i,dbName=src", "instance=i,dbName=dst")
+		assert.Nil(t, err)
+		targetProfile.Conn.Sp.Dialect = tc.dialect
+		conv, srcSchema, _, issues, err := convertDialectSchema(context.Background(), spA, "projects/p/instances/i/databases/src", targetProfile)
+		assert.Equal(t, tc.wantErr, err != nil, tc.name)
+		if tc.wantErr {
+			continue
+		}
+		assert.Equal(t, tc.wantDialect, conv.SpDialect, tc.name)
+		assert.Equal(t, 1, len(srcSchema), tc.name)
+		assert.Equal(t, tc.wantIssues, len(issues), tc.name)
+	}
+}
+
+func TestWriteDialectReport(t *testing.T) {
+	var buf bytes.Buffer
+	writeDialectReport(&buf, constants.DIALECT_POSTGRESQL, nil)
+	assert.Equal(t, "The schema was converted to the postgresql dialect without issues.\n", buf.String())
+	buf.Reset()
+	writeDialectReport(&buf, constants.DIALECT_POSTGRESQL, []spanner.DialectIssue{{Object: "CREATE VIEW v", Issue: "skipped"}, {Table: "t", Object: "column c", Issue: "changed"}})
+	assert.Equal(t, "2 parts of the schema were dropped or changed by the conversion to the postgresql dialect:\n"+
+		"  - CREATE VIEW v: skipped\n"+
+		"  - table t, column c: changed\n", buf.String())
+}
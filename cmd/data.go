@@ -54,6 +54,13 @@ type DataCmd struct {
 	SkipForeignKeys  bool
 	validate         bool
 	dataflowTemplate string
+	checkpoint       bool
+	resume           bool
+	deadLetter       bool
+	adaptiveWrites   bool
+	maxRowsPerSecond float64
+	maxCPUPercent    float64
+	metricsAddress   string
 }
 
 // Name returns the name of operation.
@@ -92,6 +99,13 @@ func (cmd *DataCmd) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&cmd.SkipForeignKeys, "skip-foreign-keys", false, "Skip creating foreign keys after data migration is complete (ddl statements for foreign keys can still be found in the downloaded schema.ddl.txt file and the same can be applied separately)")
 	f.BoolVar(&cmd.validate, "validate", false, "Flag for validating if all the required input parameters are present")
 	f.StringVar(&cmd.dataflowTemplate, "dataflow-template", constants.DEFAULT_TEMPLATE_PATH, "GCS path of the Dataflow template")
+	f.BoolVar(&cmd.checkpoint, "checkpoint", false, "Record the progress of the bulk data migration in a checkpoint file, so that it can be resumed with --resume")
+	f.BoolVar(&cmd.resume, "resume", false, "Resume an interrupted bulk data migration from its checkpoint file, skipping completed tables")
+	f.BoolVar(&cmd.deadLetter, "dead-letter", false, "Write every row that can't be converted or written to Spanner to a dead-letter file, so that it can be replayed with the replay-dlq command")
+	f.BoolVar(&cmd.adaptiveWrites, "adaptive-writes", false, "Tune the batch size and number of parallel writes (up to write-limit) from Spanner commit latency and errors, backing off when Spanner pushes back")
+	f.Float64Var(&cmd.maxRowsPerSecond, "max-rows-per-second", 0, "Optional. Caps the rate at which rows are written to Spanner")
+	f.Float64Var(&cmd.maxCPUPercent, "max-cpu-percent", 0, "Optional. Reduces parallel writes while the high priority CPU utilization of the Spanner instance exceeds this percentage. Requires --adaptive-writes")
+	f.StringVar(&cmd.metricsAddress, "metrics-address", "", "Optional. Serves Prometheus metrics of the migration on /metrics at this address, e.g. \":9090\"")
 }
 
 func (cmd *DataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
@@ -109,6 +123,10 @@ func (cmd *DataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface
 		return subcommands.ExitFailure
 	}
 	defer logger.Log.Sync()
+	err = startMetricsEndpoint(cmd.metricsAddress)
+	if err != nil {
+		return subcommands.ExitUsageError
+	}
 
 	conv := internal.MakeConv()
 	utils.SetDataflowTemplatePath(cmd.dataflowTemplate)
@@ -135,6 +153,7 @@ func (cmd *DataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface
 	conv.Audit.MigrationRequestId = strings.Replace(conv.Audit.MigrationRequestId, "_", "-", -1)
 	conv.Audit.MigrationType = migration.MigrationData_DATA_ONLY.Enum()
 	conv.Audit.SkipMetricsPopulation = os.Getenv("SKIP_METRICS_POPULATION") == "true"
+	conv.Audit.WriteSettings = getWriteSettings(cmd.adaptiveWrites, cmd.maxRowsPerSecond, cmd.maxCPUPercent)
 	dataCoversionStartTime := time.Now()
 
 	if cmd.validate {
@@ -154,12 +173,33 @@ func (cmd *DataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface
 			err = fmt.Errorf("running data migration for Spanner dialect: %v, whereas schema mapping was done for dialect: %v", targetProfile.Conn.Sp.Dialect, conv.SpDialect)
 			return subcommands.ExitUsageError
 		}
+		err = conv.ValidateColumnTransformations()
+		if err != nil {
+			return subcommands.ExitUsageError
+		}
+		err = validateRowFilters(conv, sourceProfile)
+		if err != nil {
+			return subcommands.ExitUsageError
+		}
+	}
+
+	// If filePrefix not explicitly set, use dbName as prefix.
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = targetProfile.Conn.Sp.Dbname
 	}
 
 	var (
 		dbURI string
 	)
 	if !cmd.dryRun {
+		err = initCheckpoint(conv, targetProfile, cmd.filePrefix+checkpointFile, cmd.checkpoint, cmd.resume)
+		if err != nil {
+			return subcommands.ExitUsageError
+		}
+		if cmd.deadLetter {
+			conv.DeadLetter = internal.NewDeadLetterQueue(cmd.filePrefix+deadLetterFile, cmd.resume)
+			defer conv.DeadLetter.Close()
+		}
 		now := time.Now()
 		bw, err = MigrateDatabase(ctx, cmd.project, targetProfile, sourceProfile, dbName, &ioHelper, cmd, conv, nil)
 		if err != nil {
@@ -190,12 +230,13 @@ func (cmd *DataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface
 	dataCoversionDuration := dataCoversionEndTime.Sub(dataCoversionStartTime)
 	conv.Audit.DataConversionDuration = dataCoversionDuration
 
-	// If filePrefix not explicitly set, use dbName as prefix.
-	if cmd.filePrefix == "" {
-		cmd.filePrefix = targetProfile.Conn.Sp.Dbname
-	}
 	reportImpl := conversion.ReportImpl{}
 	reportImpl.GenerateReport(sourceProfile.Driver, bw.DroppedRowsByTable(), ioHelper.BytesRead, banner, conv, cmd.filePrefix, dbName, ioHelper.Out)
+	if conv.DeadLetter != nil {
+		if err := conv.DeadLetter.Close(); err != nil {
+			fmt.Fprintf(ioHelper.Out, "Can't write dead-letter file %s: %v\n", conv.DeadLetter.Path(), err)
+		}
+	}
 	conversion.WriteBadData(bw, conv, banner, cmd.filePrefix+badDataFile, ioHelper.Out)
 	// Cleanup smt tmp data directory.
 	os.RemoveAll(filepath.Join(os.TempDir(), constants.SMT_TMP_DIR))
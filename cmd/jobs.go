@@ -0,0 +1,259 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/dao"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/streaming"
+	"github.com/google/subcommands"
+)
+
+const (
+	jobsList     = "list"
+	jobsDescribe = "describe"
+	jobsHistory  = "history"
+)
+
+// JobsCmd is the command for inspecting the migration jobs stored in the
+// metadata database.
+type JobsCmd struct {
+	targetProfile string
+	format        string
+	logLevel      string
+}
+
+// jobDescription is the output of the jobs describe command.
+type jobDescription struct {
+	Job       dao.Job        `json:"job"`
+	Resources []dao.Resource `json:"resources"`
+}
+
+// jobHistory is the output of the jobs history command.
+type jobHistory struct {
+	Job            dao.Job               `json:"job"`
+	JobStates      []dao.StateTransition `json:"jobStates"`
+	ResourceStates []dao.StateTransition `json:"resourceStates"`
+}
+
+// Name returns the name of operation.
+func (cmd *JobsCmd) Name() string {
+	return "jobs"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *JobsCmd) Synopsis() string {
+	return "list migration jobs and show their resources and state history"
+}
+
+// Usage returns usage info of the command.
+func (cmd *JobsCmd) Usage() string {
+	return fmt.Sprintf(`%v jobs list|describe [jobId]|history [jobId] --target-profile="instance=my-instance" ...
+
+List the migration jobs stored in the metadata database of the Spanner instance,
+describe the resources generated for a job and their current states, or show
+the state transitions of a job and of its resources. The jobs flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *JobsCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying project and instance details of Spanner e.g., \"project=XYZ,instance=ABC\"")
+	f.StringVar(&cmd.format, "format", "text", "Output format (accepted values: `text`, `json`)")
+	f.StringVar(&cmd.logLevel, "log-level", "DEBUG", "Configure the logging level for the command (INFO, DEBUG), defaults to DEBUG")
+}
+
+func (cmd *JobsCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	args := f.Args()
+	if len(args) == 0 {
+		fmt.Println("Please specify one of list, describe or history.")
+		return subcommands.ExitUsageError
+	}
+	action := args[0]
+	args = args[1:]
+	jobId := ""
+	switch action {
+	case jobsList:
+	case jobsDescribe, jobsHistory:
+		if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+			fmt.Printf("Please specify the jobId to %s.\n", action)
+			return subcommands.ExitUsageError
+		}
+		jobId = args[0]
+		args = args[1:]
+	default:
+		fmt.Printf("Unknown jobs action %q, expected one of list, describe or history.\n", action)
+		return subcommands.ExitUsageError
+	}
+	// Allow flags to follow the action and jobId as well.
+	if err := f.Parse(args); err != nil || f.NArg() > 0 {
+		fmt.Println("Unexpected arguments:", args)
+		return subcommands.ExitUsageError
+	}
+	if cmd.format != "text" && cmd.format != "json" {
+		fmt.Printf("Unknown format %q, expected text or json.\n", cmd.format)
+		return subcommands.ExitUsageError
+	}
+	err := logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+	targetProfile, err := profiles.NewTargetProfile(cmd.targetProfile)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Target profile is not properly configured, this is needed for SMT to lookup job details in the metadata database: %v\n", err))
+		return subcommands.ExitUsageError
+	}
+	project, instance, err := streaming.GetInstanceDetails(ctx, targetProfile)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("can't get resource ids: %v\n", err))
+		return subcommands.ExitFailure
+	}
+	dbURI := fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, constants.METADATA_DB)
+	_, err = dao.GetOrCreateClient(ctx, dbURI)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("can't connect to the metadata database %s: %v\n", dbURI, err))
+		return subcommands.ExitFailure
+	}
+	err = runJobsAction(ctx, &dao.DAOImpl{}, action, jobId, cmd.format == "json", os.Stdout)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// runJobsAction reads the jobs data needed by action from the metadata
+// database and writes it to w, as JSON if asJSON is set and as tables otherwise.
+func runJobsAction(ctx context.Context, d dao.DAO, action, jobId string, asJSON bool, w io.Writer) error {
+	var out interface{}
+	switch action {
+	case jobsList:
+		jobs, err := d.ListJobs(ctx)
+		if err != nil {
+			return err
+		}
+		out = jobs
+	case jobsDescribe:
+		job, err := d.GetJob(ctx, jobId)
+		if err != nil {
+			return err
+		}
+		resources, err := d.ListResources(ctx, jobId)
+		if err != nil {
+			return err
+		}
+		out = jobDescription{Job: *job, Resources: resources}
+	case jobsHistory:
+		job, err := d.GetJob(ctx, jobId)
+		if err != nil {
+			return err
+		}
+		jobStates, err := d.GetJobHistory(ctx, jobId)
+		if err != nil {
+			return err
+		}
+		resourceStates, err := d.GetResourceHistory(ctx, jobId)
+		if err != nil {
+			return err
+		}
+		out = jobHistory{Job: *job, JobStates: jobStates, ResourceStates: resourceStates}
+	default:
+		return fmt.Errorf("unknown jobs action %q", action)
+	}
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	switch v := out.(type) {
+	case []dao.Job:
+		writeJobsList(tw, v)
+	case jobDescription:
+		writeJobDescription(tw, v)
+	case jobHistory:
+		writeJobHistory(tw, v)
+	}
+	return tw.Flush()
+}
+
+func writeJobsList(w io.Writer, jobs []dao.Job) {
+	if len(jobs) == 0 {
+		fmt.Fprintln(w, "No migration jobs found.")
+		return
+	}
+	fmt.Fprintln(w, "JOB ID\tNAME\tTYPE\tSTATE\tDATABASE\tCREATED\tUPDATED")
+	for _, job := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", job.JobId, job.JobName, job.JobType, job.State, job.SpannerDatabaseName, formatJobTime(job.CreatedAt), formatJobTime(job.UpdatedAt))
+	}
+}
+
+func writeJobDescription(w io.Writer, d jobDescription) {
+	fmt.Fprintf(w, "Job ID:\t%s\n", d.Job.JobId)
+	fmt.Fprintf(w, "Name:\t%s\n", d.Job.JobName)
+	fmt.Fprintf(w, "Type:\t%s\n", d.Job.JobType)
+	fmt.Fprintf(w, "State:\t%s\n", d.Job.State)
+	fmt.Fprintf(w, "Dialect:\t%s\n", d.Job.Dialect)
+	fmt.Fprintf(w, "Database:\t%s\n", d.Job.SpannerDatabaseName)
+	fmt.Fprintf(w, "Created:\t%s\n", formatJobTime(d.Job.CreatedAt))
+	fmt.Fprintf(w, "Updated:\t%s\n\n", formatJobTime(d.Job.UpdatedAt))
+	if len(d.Resources) == 0 {
+		fmt.Fprintln(w, "No resources found for this job.")
+		return
+	}
+	fmt.Fprintln(w, "SHARD\tTYPE\tNAME\tEXTERNAL ID\tSTATE\tUPDATED")
+	for _, r := range d.Resources {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.DataShardId, r.ResourceType, r.ResourceName, r.ExternalId, r.State, formatJobTime(r.UpdatedAt))
+	}
+}
+
+func writeJobHistory(w io.Writer, h jobHistory) {
+	transitions := make([]dao.StateTransition, 0, len(h.JobStates)+len(h.ResourceStates))
+	transitions = append(transitions, h.JobStates...)
+	transitions = append(transitions, h.ResourceStates...)
+	if len(transitions) == 0 {
+		fmt.Fprintf(w, "No state transitions recorded for job %s, current state: %s\n", h.Job.JobId, h.Job.State)
+		return
+	}
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].CreatedAt.Before(transitions[j].CreatedAt)
+	})
+	fmt.Fprintln(w, "TIME\tOBJECT\tNAME\tSTATE")
+	for _, t := range transitions {
+		object, name := "job", h.Job.JobName
+		if t.ResourceId != "" {
+			object, name = t.ResourceType, t.ResourceName
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatJobTime(t.CreatedAt), object, name, t.State)
+	}
+}
+
+func formatJobTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/dao"
+	"github.com/stretchr/testify/assert"
+)
+
+func jobsDAOMock() *dao.DAOMock {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	job := dao.Job{JobId: "smt-job-1", JobName: "smt-job-1", JobType: "minimalDowntime", State: "RUNNING", Dialect: "google_standard_sql", SpannerDatabaseName: "db1", CreatedAt: created, UpdatedAt: created}
+	return &dao.DAOMock{
+		ListJobsMock: func(ctx context.Context) ([]dao.Job, error) {
+			return []dao.Job{job}, nil
+		},
+		GetJobMock: func(ctx context.Context, jobId string) (*dao.Job, error) {
+			if jobId != job.JobId {
+				return nil, fmt.Errorf("smt job %s not found", jobId)
+			}
+			return &job, nil
+		},
+		ListResourcesMock: func(ctx context.Context, jobId string) ([]dao.Resource, error) {
+			return []dao.Resource{
+				{ResourceId: "r1", JobId: jobId, ExternalId: "stream-1", ResourceName: "stream-1", ResourceType: "datastream", DataShardId: "shard1", State: "CREATED", CreatedAt: created, UpdatedAt: created},
+				{ResourceId: "r2", JobId: jobId, ExternalId: "df-job-1", ResourceName: "df-job-1", ResourceType: "dataflow", DataShardId: "shard1", State: "CREATED", CreatedAt: created, UpdatedAt: created},
+			}, nil
+		},
+		GetJobHistoryMock: func(ctx context.Context, jobId string) ([]dao.StateTransition, error) {
+			return []dao.StateTransition{
+				{State: "CREATING", CreatedAt: created},
+				{State: "RUNNING", CreatedAt: created.Add(2 * time.Minute)},
+			}, nil
+		},
+		GetResourceHistoryMock: func(ctx context.Context, jobId string) ([]dao.StateTransition, error) {
+			return []dao.StateTransition{
+				{ResourceId: "r1", ResourceName: "stream-1", ResourceType: "datastream", State: "CREATED", CreatedAt: created.Add(time.Minute)},
+			}, nil
+		},
+	}
+}
+
+func TestRunJobsAction(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		name         string
+		action       string
+		jobId        string
+		expectError  bool
+		expectedRows []string
+	}{
+		{
+			name:         "list",
+			action:       jobsList,
+			expectedRows: []string{"JOB ID", "smt-job-1  smt-job-1  minimalDowntime  RUNNING  db1"},
+		},
+		{
+			name:         "describe",
+			action:       jobsDescribe,
+			jobId:        "smt-job-1",
+			expectedRows: []string{"State:     RUNNING", "shard1  datastream  stream-1  stream-1     CREATED", "shard1  dataflow    df-job-1  df-job-1     CREATED"},
+		},
+		{
+			name:   "history",
+			action: jobsHistory,
+			jobId:  "smt-job-1",
+			expectedRows: []string{
+				"2024-05-01T10:00:00Z  job         smt-job-1  CREATING",
+				"2024-05-01T10:01:00Z  datastream  stream-1   CREATED",
+				"2024-05-01T10:02:00Z  job         smt-job-1  RUNNING",
+			},
+		},
+		{
+			name:        "describe unknown job",
+			action:      jobsDescribe,
+			jobId:       "smt-job-2",
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		err := runJobsAction(ctx, jobsDAOMock(), tc.action, tc.jobId, false, &out)
+		assert.Equal(t, tc.expectError, err != nil, tc.name)
+		for _, row := range tc.expectedRows {
+			assert.True(t, strings.Contains(out.String(), row), "%s: %q not found in:\n%s", tc.name, row, out.String())
+		}
+	}
+}
+
+func TestRunJobsActionJSON(t *testing.T) {
+	var out bytes.Buffer
+	err := runJobsAction(context.Background(), jobsDAOMock(), jobsDescribe, "smt-job-1", true, &out)
+	assert.Nil(t, err)
+	var got jobDescription
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &got))
+	assert.Equal(t, "RUNNING", got.Job.State)
+	assert.Equal(t, 2, len(got.Resources))
+	assert.Equal(t, "shard1", got.Resources[0].DataShardId)
+}
@@ -0,0 +1,193 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/utils"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/conversion"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
+	"github.com/google/subcommands"
+)
+
+// deadLetterRetryFile holds the rows that still fail when replaying a
+// dead-letter file.
+const deadLetterRetryFile = ".dead_letter.retry.jsonl"
+
+// ReplayDLQCmd is the command for writing the rows of a dead-letter file to
+// Spanner.
+type ReplayDLQCmd struct {
+	sessionJSON   string
+	dlqFile       string
+	targetProfile string
+	filePrefix    string
+	WriteLimit    int64
+	logLevel      string
+}
+
+// Name returns the name of operation.
+func (cmd *ReplayDLQCmd) Name() string {
+	return "replay-dlq"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *ReplayDLQCmd) Synopsis() string {
+	return "replay the rows of a dead-letter file to Spanner"
+}
+
+// Usage returns usage info of the command.
+func (cmd *ReplayDLQCmd) Usage() string {
+	return fmt.Sprintf(`%v replay-dlq --session=[session_file] --dlq-file=[dead_letter_file] --target-profile="dbName=my-db" ...
+
+Re-read the rows rejected by a data migration from its dead-letter file,
+convert them again using the current session file (e.g. after fixing the
+schema, column mappings or the rows themselves) and write them to Spanner.
+Rows that still fail are written to a new dead-letter file. The replay-dlq
+flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *ReplayDLQCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.sessionJSON, "session", "", "Specifies the file with the schema mapping used by the migration")
+	f.StringVar(&cmd.dlqFile, "dlq-file", "", "Specifies the dead-letter file written by the data or schema-and-data commands")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"dialect=postgresql\"")
+	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
+	f.Int64Var(&cmd.WriteLimit, "write-limit", DefaultWritersLimit, "Write limit for writes to spanner")
+	f.StringVar(&cmd.logLevel, "log-level", "DEBUG", "Configure the logging level for the command (INFO, DEBUG), defaults to DEBUG")
+}
+
+func (cmd *ReplayDLQCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	err := logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+	if cmd.sessionJSON == "" || cmd.dlqFile == "" {
+		fmt.Println("Please specify both the session file (--session) and the dead-letter file (--dlq-file).")
+		return subcommands.ExitUsageError
+	}
+	targetProfile, err := profiles.NewTargetProfile(cmd.targetProfile)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Target profile is not properly configured: %v\n", err))
+		return subcommands.ExitUsageError
+	}
+	dbName := targetProfile.Conn.Sp.Dbname
+	if dbName == "" {
+		logger.Log.Error("dbName must be specified in target-profile to replay a dead-letter file")
+		return subcommands.ExitUsageError
+	}
+	conv := internal.MakeConv()
+	if err = conversion.ReadSessionFile(conv, cmd.sessionJSON); err != nil {
+		logger.Log.Error(fmt.Sprintf("Can't read session file %s: %v\n", cmd.sessionJSON, err))
+		return subcommands.ExitUsageError
+	}
+	records, err := internal.ReadDeadLetterFile(cmd.dlqFile)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitUsageError
+	}
+	conv.Audit.SkipMetricsPopulation = os.Getenv("SKIP_METRICS_POPULATION") == "true"
+	ioHelper := utils.IOStreams{In: os.Stdin, Out: os.Stdout}
+	_, client, _, err := CreateDatabaseClient(ctx, targetProfile, conv.Source, dbName, ioHelper)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	defer client.Close()
+
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = dbName
+	}
+	// The rows are all in memory, so it's fine for the retry file to be the
+	// file being replayed.
+	conv.DeadLetter = internal.NewDeadLetterQueue(cmd.filePrefix+deadLetterRetryFile, false)
+	config := writer.BatchWriterConfig{
+		BytesLimit: 100 * 1000 * 1000,
+		WriteLimit: cmd.WriteLimit,
+		RetryLimit: 1000,
+		Verbose:    internal.Verbose(),
+	}
+	bw, err := conversion.ReplayDeadLetters(conv, records, config, client, &conversion.PopulateDataConvImpl{})
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	if err = conv.DeadLetter.Close(); err != nil {
+		logger.Log.Error(fmt.Sprintf("Can't write dead-letter file %s: %v\n", conv.DeadLetter.Path(), err))
+		return subcommands.ExitFailure
+	}
+	var failed []internal.DeadLetterRecord
+	if conv.DeadLetter.Count() > 0 {
+		failed, err = internal.ReadDeadLetterFile(conv.DeadLetter.Path())
+		if err != nil {
+			logger.Log.Error(err.Error())
+			return subcommands.ExitFailure
+		}
+	}
+	written := utils.SumMapValues(bw.WrittenRowsByTable())
+	writeReplaySummary(os.Stdout, len(records), written, failed, conv.DeadLetter.Path())
+	if len(failed) > 0 {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// writeReplaySummary reports how many of the replayed rows were written and
+// breaks down the rows that still fail by stage and error.
+func writeReplaySummary(w io.Writer, replayed int, written int64, failed []internal.DeadLetterRecord, failedPath string) {
+	fmt.Fprintf(w, "Replayed %d rows: %d written to Spanner, %d still failing\n", replayed, written, len(failed))
+	if len(failed) == 0 {
+		return
+	}
+	type failure struct {
+		table, stage, err string
+	}
+	counts := make(map[failure]int)
+	for _, r := range failed {
+		counts[failure{r.Table, r.Stage, r.Error}]++
+	}
+	var failures []failure
+	for k := range counts {
+		failures = append(failures, k)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if counts[failures[i]] != counts[failures[j]] {
+			return counts[failures[i]] > counts[failures[j]]
+		}
+		if failures[i].table != failures[j].table {
+			return failures[i].table < failures[j].table
+		}
+		return failures[i].err < failures[j].err
+	})
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROWS\tTABLE\tSTAGE\tERROR")
+	for _, k := range failures {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", counts[k], k.table, k.stage, k.err)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Rows that still fail were written to '%s'\n", failedPath)
+}
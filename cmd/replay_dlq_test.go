@@ -0,0 +1,46 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteReplaySummary(t *testing.T) {
+	var out bytes.Buffer
+	writeReplaySummary(&out, 5, 5, nil, "db.dead_letter.retry.jsonl")
+	assert.Equal(t, "Replayed 5 rows: 5 written to Spanner, 0 still failing\n", out.String())
+
+	failed := []internal.DeadLetterRecord{
+		{SourceRow: internal.SourceRow{Table: "users"}, Stage: internal.DeadLetterConversion, Error: "bad int"},
+		{SourceRow: internal.SourceRow{Table: "albums"}, Stage: internal.DeadLetterWrite, Error: "already exists"},
+		{SourceRow: internal.SourceRow{Table: "users"}, Stage: internal.DeadLetterConversion, Error: "bad int"},
+	}
+	out.Reset()
+	writeReplaySummary(&out, 10, 7, failed, "db.dead_letter.retry.jsonl")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, []string{
+		"Replayed 10 rows: 7 written to Spanner, 3 still failing",
+		"ROWS  TABLE   STAGE       ERROR",
+		"2     users   conversion  bad int",
+		"1     albums  write       already exists",
+		"Rows that still fail were written to 'db.dead_letter.retry.jsonl'",
+	}, lines)
+}
@@ -0,0 +1,643 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/dataflow/apiv1beta3/dataflowpb"
+	"cloud.google.com/go/spanner"
+	dataflowclient "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/clients/dataflow"
+	storageclient "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/clients/storage"
+	dataflowaccessor "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/dataflow"
+	spanneraccessor "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/spanner"
+	storageaccessor "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/storage"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/utils"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/conversion"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/dao"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/streaming"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/helpers"
+	"github.com/google/subcommands"
+)
+
+const (
+	reverseReplicationReader = "reader"
+	reverseReplicationWriter = "writer"
+	reverseReplicationBoth   = "both"
+
+	reverseReplicationSessionFile = "session.json"
+	reverseReplicationShardsFile  = "shards.json"
+)
+
+// ReverseReplicationCmd is the command for launching the Dataflow jobs that
+// replicate the writes made to Spanner back to the source shards.
+type ReverseReplicationCmd struct {
+	sourceProfile                        string
+	targetProfile                        string
+	sessionJSON                          string
+	dataflowProject                      string
+	dataflowRegion                       string
+	jobNamePrefix                        string
+	changeStreamName                     string
+	metadataInstance                     string
+	metadataDatabase                     string
+	startTimestamp                       string
+	windowDuration                       string
+	gcsPath                              string
+	filtrationMode                       string
+	metadataTableSuffix                  string
+	readerSkipDirectoryName              string
+	sourceDbTimezoneOffset               string
+	readerRunMode                        string
+	writerRunMode                        string
+	machineType                          string
+	vpcNetwork                           string
+	vpcSubnetwork                        string
+	vpcHostProjectId                     string
+	serviceAccountEmail                  string
+	readerWorkers                        int
+	readerMaxWorkers                     int
+	writerWorkers                        int
+	spannerReaderTemplateLocation        string
+	sourceWriterTemplateLocation         string
+	jobsToLaunch                         string
+	skipChangeStreamCreation             bool
+	skipMetadataDatabaseCreation         bool
+	networkTags                          string
+	runIdentifier                        string
+	readerShardingCustomJarPath          string
+	readerShardingCustomClassName        string
+	readerShardingCustomParameters       string
+	writeFilteredEventsToGcs             bool
+	writerTransformationCustomJarPath    string
+	writerTransformationCustomClassName  string
+	writerTransformationCustomParameters string
+	dryRun                               bool
+	logLevel                             string
+}
+
+// reverseReplicationShard is an entry of the source shards file read by the
+// reverse replication Dataflow templates.
+type reverseReplicationShard struct {
+	LogicalShardId string `json:"logicalShardId"`
+	Host           string `json:"host"`
+	User           string `json:"user"`
+	Password       string `json:"password"`
+	Port           string `json:"port"`
+	DbName         string `json:"dbName"`
+}
+
+// reverseReplicationJob is a Dataflow job to launch, either the reader of the
+// Spanner change stream or the writer to the source shards.
+type reverseReplicationJob struct {
+	role    string
+	request *dataflowpb.LaunchFlexTemplateRequest
+}
+
+// reverseReplicationPlan holds everything needed to set up reverse
+// replication, so that a dry run can print it without calling any service.
+type reverseReplicationPlan struct {
+	runId         string
+	dbURI         string
+	metadataDbURI string
+	dialect       string
+	configPath    string
+	sessionData   string
+	shardsData    string
+	jobs          []reverseReplicationJob
+}
+
+// reverseReplicationJobData is the job data stored in SMT_JOB.
+type reverseReplicationJobData struct {
+	RunIdentifier        string
+	ChangeStreamName     string
+	MetadataDatabase     string
+	SessionFilePath      string
+	SourceShardsFilePath string
+}
+
+// reverseReplicationResourceData is the resource data stored in SMT_RESOURCE
+// for each launched Dataflow job.
+type reverseReplicationResourceData struct {
+	DataShardId   string
+	Role          string
+	GcloudCommand string
+}
+
+// reverseReplicationServices are the services called to set up reverse
+// replication.
+type reverseReplicationServices struct {
+	spA       spanneraccessor.SpannerAccessor
+	storageA  storageaccessor.StorageAccessor
+	storageC  storageclient.StorageClient
+	dataflowC dataflowclient.DataflowClient
+	dao       dao.DAO
+}
+
+// Name returns the name of operation.
+func (cmd *ReverseReplicationCmd) Name() string {
+	return "reverse-replication"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *ReverseReplicationCmd) Synopsis() string {
+	return "replicate the writes made to Spanner back to the source shards"
+}
+
+// Usage returns usage info of the command.
+func (cmd *ReverseReplicationCmd) Usage() string {
+	return fmt.Sprintf(`%v reverse-replication --source-profile="config=shards.json" --session=[session_file] --target-profile="instance=my-instance,dbName=my-db" --dataflow-region=us-central1 --gcs-path=gs://bucket/dir ...
+
+Launch the Dataflow jobs that read the changes made to the Spanner database
+from a change stream and write them to the source shards of the migration.
+The shards are read from the same sharded source-profile config and the
+schema mapping from the same session file used by the migration. The change
+stream is validated, or created if missing, and the launched jobs are
+recorded in the metadata database. The reverse-replication flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *ReverseReplicationCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.sourceProfile, "source-profile", "", "Flag for specifying the sharded migration config of the source shards e.g., \"config=shards.json\"")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"instance=my-instance,dbName=my-db\"")
+	f.StringVar(&cmd.sessionJSON, "session", "", "Specifies the file with the schema mapping used by the migration")
+	f.StringVar(&cmd.dataflowProject, "dataflow-project", "", "Project to run the Dataflow jobs in, defaults to the Spanner project")
+	f.StringVar(&cmd.dataflowRegion, "dataflow-region", "", "Region to run the Dataflow jobs in")
+	f.StringVar(&cmd.jobNamePrefix, "job-name-prefix", "smt-reverse-replication", "Name prefix of the Dataflow jobs, converted to lower case")
+	f.StringVar(&cmd.changeStreamName, "change-stream-name", "reverseReplicationStream", "Name of the change stream read by the reader job")
+	f.StringVar(&cmd.metadataInstance, "metadata-instance", "", "Spanner instance to store the change stream metadata in, defaults to the target instance")
+	f.StringVar(&cmd.metadataDatabase, "metadata-database", "rev_repl_metadata", "Spanner database to store the change stream metadata in")
+	f.StringVar(&cmd.startTimestamp, "start-timestamp", "", "Timestamp in RFC 3339 format from which to read the change stream, defaults to the current time")
+	f.StringVar(&cmd.windowDuration, "window-duration", "10s", "Window in which the change stream data is written to Cloud Storage")
+	f.StringVar(&cmd.gcsPath, "gcs-path", "", "Pre-created GCS directory where the change stream data is buffered, e.g. gs://bucket/dir")
+	f.StringVar(&cmd.filtrationMode, "filtration-mode", "forward_migration", "Whether to filter out the forward migrated writes (accepted values: `forward_migration`, `none`)")
+	f.StringVar(&cmd.metadataTableSuffix, "metadata-table-suffix", "", "Suffix of the metadata tables, helpful in case of multiple runs")
+	f.StringVar(&cmd.readerSkipDirectoryName, "reader-skip-directory-name", "skip", "Directory the records skipped from reverse replication are written to")
+	f.StringVar(&cmd.sourceDbTimezoneOffset, "source-db-timezone-offset", "+00:00", "Timezone offset of the source database with respect to UTC")
+	f.StringVar(&cmd.readerRunMode, "reader-run-mode", "regular", "Run mode of the reader job (accepted values: `regular`, `resume`)")
+	f.StringVar(&cmd.writerRunMode, "writer-run-mode", "regular", "Run mode of the writer job (accepted values: `regular`, `reprocess`, `resumeFailed`, `resumeSuccess`, `resumeAll`)")
+	f.StringVar(&cmd.machineType, "machine-type", "n2-standard-4", "Machine type of the Dataflow workers")
+	f.StringVar(&cmd.vpcNetwork, "vpc-network", "", "VPC network of the Dataflow jobs")
+	f.StringVar(&cmd.vpcSubnetwork, "vpc-subnetwork", "", "VPC subnetwork of the Dataflow jobs, in the Dataflow region")
+	f.StringVar(&cmd.vpcHostProjectId, "vpc-host-project-id", "", "Project hosting the VPC subnetwork, defaults to the Dataflow project")
+	f.StringVar(&cmd.serviceAccountEmail, "service-account-email", "", "Service account to run the Dataflow jobs as")
+	f.IntVar(&cmd.readerWorkers, "reader-workers", 5, "Number of workers of the reader job")
+	f.IntVar(&cmd.readerMaxWorkers, "reader-max-workers", 20, "Maximum number of workers of the reader job")
+	f.IntVar(&cmd.writerWorkers, "writer-workers", 5, "Number of workers of the writer job")
+	f.StringVar(&cmd.spannerReaderTemplateLocation, "spanner-reader-template-location", "gs://dataflow-templates-us-east7/2024-05-21-00_RC00/flex/Spanner_Change_Streams_to_Sharded_File_Sink", "Dataflow template of the reader job")
+	f.StringVar(&cmd.sourceWriterTemplateLocation, "source-writer-template-location", "gs://dataflow-templates-us-east7/2024-07-23-00_RC00/flex/GCS_to_Sourcedb", "Dataflow template of the writer job")
+	f.StringVar(&cmd.jobsToLaunch, "jobs-to-launch", reverseReplicationBoth, "Dataflow jobs to launch (accepted values: `both`, `reader`, `writer`)")
+	f.BoolVar(&cmd.skipChangeStreamCreation, "skip-change-stream-creation", false, "Skip validating and creating the change stream")
+	f.BoolVar(&cmd.skipMetadataDatabaseCreation, "skip-metadata-database-creation", false, "Skip creating the change stream metadata database")
+	f.StringVar(&cmd.networkTags, "network-tags", "", "Network tags added to the Dataflow worker and launcher VMs")
+	f.StringVar(&cmd.runIdentifier, "run-identifier", "", "Run identifier of the Dataflow jobs, defaults to the current time")
+	f.StringVar(&cmd.readerShardingCustomJarPath, "reader-sharding-custom-jar-path", "", "GCS path of the jar with the custom sharding logic")
+	f.StringVar(&cmd.readerShardingCustomClassName, "reader-sharding-custom-class-name", "", "Fully qualified class name of the custom sharding logic")
+	f.StringVar(&cmd.readerShardingCustomParameters, "reader-sharding-custom-parameters", "", "Parameters passed to the custom sharding class")
+	f.BoolVar(&cmd.writeFilteredEventsToGcs, "write-filtered-events-to-gcs", false, "Write the events filtered by the custom transformation to GCS")
+	f.StringVar(&cmd.writerTransformationCustomJarPath, "writer-transformation-custom-jar-path", "", "GCS path of the jar with the custom transformation logic")
+	f.StringVar(&cmd.writerTransformationCustomClassName, "writer-transformation-custom-class-name", "", "Fully qualified class name of the custom transformation logic")
+	f.StringVar(&cmd.writerTransformationCustomParameters, "writer-transformation-custom-parameters", "", "Parameters passed to the custom transformation class")
+	f.BoolVar(&cmd.dryRun, "dry-run", false, "Print the Dataflow launch requests without calling any service")
+	f.StringVar(&cmd.logLevel, "log-level", "DEBUG", "Configure the logging level for the command (INFO, DEBUG), defaults to DEBUG")
+}
+
+func (cmd *ReverseReplicationCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	err := logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+	if err = cmd.validateFlags(); err != nil {
+		fmt.Println(err)
+		return subcommands.ExitUsageError
+	}
+	sourceProfile, err := profiles.NewSourceProfile(cmd.sourceProfile, constants.MYSQL, &profiles.NewSourceProfileImpl{})
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Source profile is not properly configured: %v\n", err))
+		return subcommands.ExitUsageError
+	}
+	shards, err := reverseReplicationShards(sourceProfile)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitUsageError
+	}
+	targetProfile, err := profiles.NewTargetProfile(cmd.targetProfile)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Target profile is not properly configured: %v\n", err))
+		return subcommands.ExitUsageError
+	}
+	dbName := targetProfile.Conn.Sp.Dbname
+	if dbName == "" {
+		logger.Log.Error("dbName must be specified in target-profile to set up reverse replication")
+		return subcommands.ExitUsageError
+	}
+	project, instance := targetProfile.Conn.Sp.Project, targetProfile.Conn.Sp.Instance
+	if cmd.dryRun {
+		// Looking up the default project and instance would call a service.
+		if project == "" || instance == "" {
+			logger.Log.Error("project and instance must be specified in target-profile for a dry run")
+			return subcommands.ExitUsageError
+		}
+	} else {
+		project, instance, err = streaming.GetInstanceDetails(ctx, targetProfile)
+		if err != nil {
+			logger.Log.Error(fmt.Sprintf("can't get resource ids: %v\n", err))
+			return subcommands.ExitFailure
+		}
+	}
+	conv := internal.MakeConv()
+	if err = conversion.ReadSessionFile(conv, cmd.sessionJSON); err != nil {
+		logger.Log.Error(fmt.Sprintf("Can't read session file %s: %v\n", cmd.sessionJSON, err))
+		return subcommands.ExitUsageError
+	}
+	session, err := os.ReadFile(cmd.sessionJSON)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Can't read session file %s: %v\n", cmd.sessionJSON, err))
+		return subcommands.ExitUsageError
+	}
+	plan, err := cmd.buildPlan(project, instance, dbName, conv.SpDialect, string(session), shards, time.Now())
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitUsageError
+	}
+	if cmd.dryRun {
+		printReverseReplicationPlan(os.Stdout, cmd, plan)
+		return subcommands.ExitSuccess
+	}
+
+	svc, err := newReverseReplicationServices(ctx, project, instance)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	if err = cmd.run(ctx, plan, svc, os.Stdout); err != nil {
+		logger.Log.Error(err.Error())
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// validateFlags checks the flags, and applies the defaults that depend on
+// other flags.
+func (cmd *ReverseReplicationCmd) validateFlags() error {
+	if cmd.sourceProfile == "" || cmd.sessionJSON == "" {
+		return fmt.Errorf("please specify both the sharded source profile (--source-profile) and the session file (--session)")
+	}
+	if cmd.dataflowRegion == "" {
+		return fmt.Errorf("please specify the region of the Dataflow jobs (--dataflow-region)")
+	}
+	if cmd.jobNamePrefix == "" {
+		return fmt.Errorf("please specify a non-empty job name prefix (--job-name-prefix)")
+	}
+	if !strings.HasPrefix(cmd.gcsPath, "gs://") {
+		return fmt.Errorf("please specify a valid GCS path for --gcs-path, like gs://bucket/dir")
+	}
+	if cmd.changeStreamName == "" {
+		return fmt.Errorf("please specify a valid change stream name (--change-stream-name)")
+	}
+	switch cmd.jobsToLaunch {
+	case reverseReplicationBoth, reverseReplicationReader, reverseReplicationWriter:
+	default:
+		return fmt.Errorf("unknown --jobs-to-launch %q, expected one of both, reader or writer", cmd.jobsToLaunch)
+	}
+	if (cmd.readerShardingCustomJarPath == "") != (cmd.readerShardingCustomClassName == "") {
+		return fmt.Errorf("--reader-sharding-custom-jar-path and --reader-sharding-custom-class-name must be specified together")
+	}
+	if cmd.readerShardingCustomJarPath != "" && !strings.HasPrefix(cmd.readerShardingCustomJarPath, "gs://") {
+		return fmt.Errorf("please specify a valid GCS path for --reader-sharding-custom-jar-path, like gs://bucket/custom.jar")
+	}
+	if (cmd.writerTransformationCustomJarPath == "") != (cmd.writerTransformationCustomClassName == "") {
+		return fmt.Errorf("--writer-transformation-custom-jar-path and --writer-transformation-custom-class-name must be specified together")
+	}
+	if cmd.writerTransformationCustomJarPath != "" && !strings.HasPrefix(cmd.writerTransformationCustomJarPath, "gs://") {
+		return fmt.Errorf("please specify a valid GCS path for --writer-transformation-custom-jar-path, like gs://bucket/custom.jar")
+	}
+	// Dataflow doesn't accept upper case letters in job names, and change
+	// stream names can't contain hyphens.
+	cmd.jobNamePrefix = strings.ToLower(cmd.jobNamePrefix)
+	cmd.changeStreamName = strings.ReplaceAll(cmd.changeStreamName, "-", "_")
+	return nil
+}
+
+// reverseReplicationShards returns the source shards file entries of the
+// shards of a sharded migration config. Writes are routed to a shard by the
+// value of the shard id column, so the logical shard ids are the data shard
+// ids of bulk migrations and the logical shard ids of minimal downtime ones.
+func reverseReplicationShards(sourceProfile profiles.SourceProfile) ([]reverseReplicationShard, error) {
+	conns, err := conversion.ShardConnections(sourceProfile)
+	if err != nil {
+		return nil, fmt.Errorf("reverse replication requires the sharded source-profile config of the migration: %v", err)
+	}
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("the source-profile config has no shards to replicate to")
+	}
+	var shards []reverseReplicationShard
+	for _, c := range conns {
+		shards = append(shards, reverseReplicationShard{
+			LogicalShardId: c.DataShardId,
+			Host:           c.Host,
+			User:           c.User,
+			Password:       c.Password,
+			Port:           c.Port,
+			DbName:         c.DbName,
+		})
+	}
+	return shards, nil
+}
+
+// buildPlan generates the files and the Dataflow launch requests needed to
+// replicate the writes made to dbName of the Spanner instance back to shards.
+func (cmd *ReverseReplicationCmd) buildPlan(project, instance, dbName, dialect, session string, shards []reverseReplicationShard, now time.Time) (*reverseReplicationPlan, error) {
+	runId := cmd.runIdentifier
+	if runId == "" {
+		runId = strings.ToLower(strings.ReplaceAll(now.UTC().Format(time.RFC3339), ":", "-"))
+	}
+	shardsData, err := json.MarshalIndent(shards, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal source shards: %v", err)
+	}
+	metadataInstance := cmd.metadataInstance
+	if metadataInstance == "" {
+		metadataInstance = instance
+	}
+	plan := &reverseReplicationPlan{
+		runId:         runId,
+		dbURI:         fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, dbName),
+		metadataDbURI: fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, metadataInstance, cmd.metadataDatabase),
+		dialect:       dialect,
+		configPath:    fmt.Sprintf("%s/config/%s/", strings.TrimSuffix(cmd.gcsPath, "/"), runId),
+		sessionData:   session,
+		shardsData:    string(shardsData),
+	}
+	dataflowProject := cmd.dataflowProject
+	if dataflowProject == "" {
+		dataflowProject = project
+	}
+	vpcHostProjectId := cmd.vpcHostProjectId
+	if vpcHostProjectId == "" {
+		vpcHostProjectId = dataflowProject
+	}
+	tuning := func(role string, workers int, experiments []string) dataflowaccessor.DataflowTuningConfig {
+		return dataflowaccessor.DataflowTuningConfig{
+			ProjectId:             dataflowProject,
+			JobName:               fmt.Sprintf("%s-%s-%s-%s", cmd.jobNamePrefix, role, runId, utils.GenerateHashStr()),
+			Location:              cmd.dataflowRegion,
+			VpcHostProjectId:      vpcHostProjectId,
+			Network:               cmd.vpcNetwork,
+			Subnetwork:            cmd.vpcSubnetwork,
+			NumWorkers:            int32(workers),
+			ServiceAccountEmail:   cmd.serviceAccountEmail,
+			MachineType:           cmd.machineType,
+			AdditionalExperiments: experiments,
+		}
+	}
+	var networkExperiments []string
+	if cmd.networkTags != "" {
+		networkExperiments = []string{"use_network_tags=" + cmd.networkTags, "use_network_tags_for_flex_templates=" + cmd.networkTags}
+	}
+	sessionFilePath := plan.configPath + reverseReplicationSessionFile
+	shardsFilePath := plan.configPath + reverseReplicationShardsFile
+
+	if cmd.jobsToLaunch == reverseReplicationBoth || cmd.jobsToLaunch == reverseReplicationReader {
+		params := map[string]string{
+			"changeStreamName":     cmd.changeStreamName,
+			"instanceId":           instance,
+			"databaseId":           dbName,
+			"spannerProjectId":     project,
+			"metadataInstance":     metadataInstance,
+			"metadataDatabase":     cmd.metadataDatabase,
+			"startTimestamp":       cmd.startTimestamp,
+			"sessionFilePath":      sessionFilePath,
+			"windowDuration":       cmd.windowDuration,
+			"gcsOutputDirectory":   cmd.gcsPath,
+			"filtrationMode":       cmd.filtrationMode,
+			"sourceShardsFilePath": shardsFilePath,
+			"metadataTableSuffix":  cmd.metadataTableSuffix,
+			"skipDirectoryName":    cmd.readerSkipDirectoryName,
+			"runIdentifier":        runId,
+			"runMode":              cmd.readerRunMode,
+		}
+		// The template expects a GCS path, so the custom sharding parameters
+		// can't be passed empty.
+		if cmd.readerShardingCustomJarPath != "" {
+			params["shardingCustomJarPath"] = cmd.readerShardingCustomJarPath
+			params["shardingCustomClassName"] = cmd.readerShardingCustomClassName
+			params["shardingCustomParameters"] = cmd.readerShardingCustomParameters
+		}
+		cfg := tuning(reverseReplicationReader, cmd.readerWorkers, append([]string{"use_runner_v2"}, networkExperiments...))
+		cfg.MaxWorkers = int32(cmd.readerMaxWorkers)
+		cfg.GcsTemplatePath = cmd.spannerReaderTemplateLocation
+		req, err := dataflowaccessor.GetDataflowLaunchRequest(params, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("can't generate the reader job launch request: %v", err)
+		}
+		plan.jobs = append(plan.jobs, reverseReplicationJob{role: reverseReplicationReader, request: req})
+	}
+	if cmd.jobsToLaunch == reverseReplicationBoth || cmd.jobsToLaunch == reverseReplicationWriter {
+		params := map[string]string{
+			"sourceShardsFilePath":   shardsFilePath,
+			"sessionFilePath":        sessionFilePath,
+			"sourceDbTimezoneOffset": cmd.sourceDbTimezoneOffset,
+			"metadataTableSuffix":    cmd.metadataTableSuffix,
+			"GCSInputDirectoryPath":  cmd.gcsPath,
+			"spannerProjectId":       project,
+			"metadataInstance":       metadataInstance,
+			"metadataDatabase":       cmd.metadataDatabase,
+			"runMode":                cmd.writerRunMode,
+			"runIdentifier":          runId,
+		}
+		if cmd.writerTransformationCustomJarPath != "" {
+			params["transformationJarPath"] = cmd.writerTransformationCustomJarPath
+			params["transformationClassName"] = cmd.writerTransformationCustomClassName
+			params["transformationCustomParameters"] = cmd.writerTransformationCustomParameters
+			params["writeFilteredEventsToGcs"] = strconv.FormatBool(cmd.writeFilteredEventsToGcs)
+		}
+		cfg := tuning(reverseReplicationWriter, cmd.writerWorkers, networkExperiments)
+		cfg.GcsTemplatePath = cmd.sourceWriterTemplateLocation
+		req, err := dataflowaccessor.GetDataflowLaunchRequest(params, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("can't generate the writer job launch request: %v", err)
+		}
+		plan.jobs = append(plan.jobs, reverseReplicationJob{role: reverseReplicationWriter, request: req})
+	}
+	return plan, nil
+}
+
+// printReverseReplicationPlan describes the steps that reverse-replication
+// would take and the equivalent gcloud command of each Dataflow job.
+func printReverseReplicationPlan(w io.Writer, cmd *ReverseReplicationCmd, plan *reverseReplicationPlan) {
+	fmt.Fprintf(w, "Dry run of reverse replication run %s, no service is called.\n\n", plan.runId)
+	if !cmd.skipChangeStreamCreation {
+		fmt.Fprintf(w, "Validate or create change stream %s on %s\n", cmd.changeStreamName, plan.dbURI)
+	}
+	if !cmd.skipMetadataDatabaseCreation {
+		fmt.Fprintf(w, "Create metadata database %s if missing\n", plan.metadataDbURI)
+	}
+	fmt.Fprintf(w, "Write session file to %s%s\n", plan.configPath, reverseReplicationSessionFile)
+	fmt.Fprintf(w, "Write source shards file to %s%s\n", plan.configPath, reverseReplicationShardsFile)
+	for _, job := range plan.jobs {
+		fmt.Fprintf(w, "\nGCLOUD CMD FOR %s JOB:\n%s\n", strings.ToUpper(job.role), dataflowaccessor.GetGcloudDataflowCommandFromRequest(job.request))
+	}
+}
+
+func newReverseReplicationServices(ctx context.Context, project, instance string) (reverseReplicationServices, error) {
+	spA, err := spanneraccessor.NewSpannerAccessorClientImpl(ctx)
+	if err != nil {
+		return reverseReplicationServices{}, err
+	}
+	storageC, err := storageclient.NewStorageClientImpl(ctx)
+	if err != nil {
+		return reverseReplicationServices{}, err
+	}
+	dataflowC, err := dataflowclient.NewDataflowClientImpl(ctx)
+	if err != nil {
+		return reverseReplicationServices{}, err
+	}
+	if !helpers.CheckOrCreateMetadataDb(project, instance) {
+		return reverseReplicationServices{}, fmt.Errorf("can't create the metadata database %s", helpers.GetSpannerUri(project, instance))
+	}
+	if _, err = dao.GetOrCreateClient(ctx, helpers.GetSpannerUri(project, instance)); err != nil {
+		return reverseReplicationServices{}, fmt.Errorf("can't connect to the metadata database: %v", err)
+	}
+	return reverseReplicationServices{
+		spA:       spA,
+		storageA:  &storageaccessor.StorageAccessorImpl{},
+		storageC:  storageC,
+		dataflowC: dataflowC,
+		dao:       &dao.DAOImpl{},
+	}, nil
+}
+
+// run sets up reverse replication as planned: it validates or creates the
+// change stream and the metadata database, writes the session and source
+// shards files to GCS, launches the Dataflow jobs and records them in the
+// metadata database.
+func (cmd *ReverseReplicationCmd) run(ctx context.Context, plan *reverseReplicationPlan, svc reverseReplicationServices, w io.Writer) error {
+	if !cmd.skipChangeStreamCreation {
+		if err := validateOrCreateChangeStream(ctx, svc.spA, cmd.changeStreamName, plan.dbURI, w); err != nil {
+			return err
+		}
+	}
+	if !cmd.skipMetadataDatabaseCreation {
+		exists, err := svc.spA.CheckExistingDb(ctx, plan.metadataDbURI)
+		if err != nil {
+			return fmt.Errorf("can't check metadata database %s: %v", plan.metadataDbURI, err)
+		}
+		if !exists {
+			if err = svc.spA.CreateEmptyDatabase(ctx, plan.metadataDbURI); err != nil {
+				return fmt.Errorf("can't create metadata database %s: %v", plan.metadataDbURI, err)
+			}
+			fmt.Fprintf(w, "Created metadata database %s\n", plan.metadataDbURI)
+		}
+	}
+	if err := svc.storageA.WriteDataToGCS(ctx, svc.storageC, plan.configPath, reverseReplicationSessionFile, plan.sessionData); err != nil {
+		return fmt.Errorf("can't write session file to %s: %v", plan.configPath, err)
+	}
+	if err := svc.storageA.WriteDataToGCS(ctx, svc.storageC, plan.configPath, reverseReplicationShardsFile, plan.shardsData); err != nil {
+		return fmt.Errorf("can't write source shards file to %s: %v", plan.configPath, err)
+	}
+
+	jobId, err := utils.GenerateName("smt-job")
+	if err != nil {
+		return err
+	}
+	jobData, err := json.Marshal(reverseReplicationJobData{
+		RunIdentifier:        plan.runId,
+		ChangeStreamName:     cmd.changeStreamName,
+		MetadataDatabase:     plan.metadataDbURI,
+		SessionFilePath:      plan.configPath + reverseReplicationSessionFile,
+		SourceShardsFilePath: plan.configPath + reverseReplicationShardsFile,
+	})
+	if err != nil {
+		return err
+	}
+	dbName := path.Base(plan.dbURI)
+	if err = svc.dao.InsertJobEntry(ctx, jobId, jobId, constants.REVERSE_REPLICATION, plan.dialect, dbName, spanner.NullJSON{Valid: true, Value: json.RawMessage(jobData)}); err != nil {
+		return err
+	}
+	// Once recorded, the job is marked FAILED on any error, so that its
+	// history doesn't show a job that looks unfinished.
+	failJob := func(err error) error {
+		if stateErr := svc.dao.UpdateJobState(ctx, jobId, "FAILED"); stateErr != nil {
+			logger.Log.Error(stateErr.Error())
+		}
+		return err
+	}
+	for _, job := range plan.jobs {
+		gcloudCmd := dataflowaccessor.GetGcloudDataflowCommandFromRequest(job.request)
+		fmt.Fprintf(w, "\nGCLOUD CMD FOR %s JOB:\n%s\n", strings.ToUpper(job.role), gcloudCmd)
+		resp, err := svc.dataflowC.LaunchFlexTemplate(ctx, job.request)
+		if err != nil {
+			return failJob(fmt.Errorf("unable to launch %s job: %v", job.role, err))
+		}
+		fmt.Fprintf(w, "Launched %s job: %s\n", job.role, resp.Job.Id)
+		resourceId, err := utils.GenerateName("smt-resource")
+		if err != nil {
+			return failJob(err)
+		}
+		resourceData, err := json.Marshal(reverseReplicationResourceData{DataShardId: constants.DEFAULT_SHARD_ID, Role: job.role, GcloudCommand: gcloudCmd})
+		if err != nil {
+			return failJob(err)
+		}
+		err = svc.dao.InsertResourceEntry(ctx, resourceId, jobId, resp.Job.Id, job.request.LaunchParameter.JobName, constants.DATAFLOW_RESOURCE, spanner.NullJSON{Valid: true, Value: json.RawMessage(resourceData)})
+		if err == nil {
+			err = svc.dao.UpdateResourceState(ctx, resourceId, "CREATED")
+		}
+		if err != nil {
+			return failJob(fmt.Errorf("can't record launched %s job %s: %v", job.role, resp.Job.Id, err))
+		}
+	}
+	if err = svc.dao.UpdateJobState(ctx, jobId, "RUNNING"); err != nil {
+		return failJob(err)
+	}
+	fmt.Fprintf(w, "\nReverse replication job %s recorded in the metadata database.\n", jobId)
+	return nil
+}
+
+// validateOrCreateChangeStream creates the change stream read by the reader
+// job, or checks that the existing one captures new rows.
+func validateOrCreateChangeStream(ctx context.Context, spA spanneraccessor.SpannerAccessor, changeStreamName, dbURI string, w io.Writer) error {
+	exists, err := spA.CheckIfChangeStreamExists(ctx, changeStreamName, dbURI)
+	if err != nil {
+		return fmt.Errorf("can't check change stream %s: %v", changeStreamName, err)
+	}
+	if !exists {
+		if err = spA.CreateChangeStream(ctx, changeStreamName, dbURI); err != nil {
+			return fmt.Errorf("can't create change stream %s: %v", changeStreamName, err)
+		}
+		fmt.Fprintf(w, "Created change stream %s\n", changeStreamName)
+		return nil
+	}
+	if err = spA.ValidateChangeStreamOptions(ctx, changeStreamName, dbURI); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Found change stream %s\n", changeStreamName)
+	return nil
+}
@@ -0,0 +1,266 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/dataflow/apiv1beta3/dataflowpb"
+	"cloud.google.com/go/spanner"
+	dataflowclient "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/clients/dataflow"
+	storageclient "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/clients/storage"
+	spanneraccessor "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/spanner"
+	storageaccessor "github.com/GoogleCloudPlatform/spanner-migration-tool/accessors/storage"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/dao"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger.Log = zap.NewNop()
+}
+
+// reverseReplicationTestCmd returns a command with the flags parsed from args
+// on top of the defaults.
+func reverseReplicationTestCmd(t *testing.T, args ...string) *ReverseReplicationCmd {
+	cmd := &ReverseReplicationCmd{}
+	fs := flag.NewFlagSet("reverse-replication", flag.ContinueOnError)
+	cmd.SetFlags(fs)
+	args = append([]string{"-source-profile=config=shards.json", "-session=session.json", "-dataflow-region=us-central1", "-gcs-path=gs://bucket/dir"}, args...)
+	assert.Nil(t, fs.Parse(args))
+	assert.Nil(t, cmd.validateFlags())
+	return cmd
+}
+
+func TestReverseReplicationValidateFlags(t *testing.T) {
+	cmd := reverseReplicationTestCmd(t, "-job-name-prefix=My-Prefix", "-change-stream-name=my-stream")
+	assert.Equal(t, "my-prefix", cmd.jobNamePrefix)
+	assert.Equal(t, "my_stream", cmd.changeStreamName)
+
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{"gcs path isn't a GCS path", []string{"-gcs-path=/tmp/dir"}},
+		{"unknown jobs to launch", []string{"-jobs-to-launch=all"}},
+		{"custom sharding jar without class", []string{"-reader-sharding-custom-jar-path=gs://bucket/custom.jar"}},
+		{"custom transformation class without jar", []string{"-writer-transformation-custom-class-name=com.custom.Class"}},
+	}
+	for _, tc := range testCases {
+		cmd := &ReverseReplicationCmd{}
+		fs := flag.NewFlagSet("reverse-replication", flag.ContinueOnError)
+		cmd.SetFlags(fs)
+		args := append([]string{"-source-profile=config=shards.json", "-session=session.json", "-dataflow-region=us-central1", "-gcs-path=gs://bucket/dir"}, tc.args...)
+		assert.Nil(t, fs.Parse(args), tc.name)
+		assert.NotNil(t, cmd.validateFlags(), tc.name)
+	}
+}
+
+func TestReverseReplicationShards(t *testing.T) {
+	sourceProfile := profiles.SourceProfile{
+		Ty: profiles.SourceProfileTypeConfig,
+		Config: profiles.SourceProfileConfig{
+			ConfigType: constants.BULK_MIGRATION,
+			ShardConfigurationBulk: profiles.ShardConfigurationBulk{
+				DataShards: []profiles.DirectConnectionConfig{{DataShardId: "shard1", Host: "10.0.0.1", User: "root", Password: "pw", Port: "3306", DbName: "db1"}},
+			},
+		},
+	}
+	shards, err := reverseReplicationShards(sourceProfile)
+	assert.Nil(t, err)
+	assert.Equal(t, []reverseReplicationShard{{LogicalShardId: "shard1", Host: "10.0.0.1", User: "root", Password: "pw", Port: "3306", DbName: "db1"}}, shards)
+
+	// Writes can't be routed without the shards of the migration.
+	_, err = reverseReplicationShards(profiles.SourceProfile{Ty: profiles.SourceProfileTypeConnection})
+	assert.NotNil(t, err)
+	sourceProfile.Config.ShardConfigurationBulk.DataShards = nil
+	_, err = reverseReplicationShards(sourceProfile)
+	assert.NotNil(t, err)
+}
+
+func TestReverseReplicationBuildPlan(t *testing.T) {
+	cmd := reverseReplicationTestCmd(t, "-network-tags=tag1", "-writer-transformation-custom-jar-path=gs://bucket/custom.jar", "-writer-transformation-custom-class-name=com.custom.Class")
+	shards := []reverseReplicationShard{{LogicalShardId: "shard1", Host: "10.0.0.1", Port: "3306", DbName: "db1"}}
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	plan, err := cmd.buildPlan("sp-project", "sp-instance", "sp-db", constants.DIALECT_GOOGLESQL, "{}", shards, now)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-05-01t10-30-00z", plan.runId)
+	assert.Equal(t, "projects/sp-project/instances/sp-instance/databases/sp-db", plan.dbURI)
+	assert.Equal(t, "projects/sp-project/instances/sp-instance/databases/rev_repl_metadata", plan.metadataDbURI)
+	assert.Equal(t, "gs://bucket/dir/config/2024-05-01t10-30-00z/", plan.configPath)
+	assert.Contains(t, plan.shardsData, `"logicalShardId": "shard1"`)
+	assert.Equal(t, 2, len(plan.jobs))
+
+	reader := plan.jobs[0].request
+	assert.Equal(t, reverseReplicationReader, plan.jobs[0].role)
+	assert.True(t, strings.HasPrefix(reader.LaunchParameter.JobName, "smt-reverse-replication-reader-2024-05-01t10-30-00z-"))
+	assert.Equal(t, "sp-project", reader.ProjectId)
+	assert.Equal(t, "us-central1", reader.Location)
+	assert.Equal(t, "gs://bucket/dir/config/2024-05-01t10-30-00z/session.json", reader.LaunchParameter.Parameters["sessionFilePath"])
+	assert.Equal(t, "gs://bucket/dir/config/2024-05-01t10-30-00z/shards.json", reader.LaunchParameter.Parameters["sourceShardsFilePath"])
+	assert.Equal(t, "reverseReplicationStream", reader.LaunchParameter.Parameters["changeStreamName"])
+	assert.Equal(t, int32(20), reader.LaunchParameter.Environment.MaxWorkers)
+	assert.Equal(t, []string{"use_runner_v2", "use_network_tags=tag1", "use_network_tags_for_flex_templates=tag1"}, reader.LaunchParameter.Environment.AdditionalExperiments)
+
+	writer := plan.jobs[1].request
+	assert.Equal(t, reverseReplicationWriter, plan.jobs[1].role)
+	assert.Equal(t, "gs://bucket/dir", writer.LaunchParameter.Parameters["GCSInputDirectoryPath"])
+	assert.Equal(t, "com.custom.Class", writer.LaunchParameter.Parameters["transformationClassName"])
+	assert.Equal(t, "false", writer.LaunchParameter.Parameters["writeFilteredEventsToGcs"])
+	assert.Equal(t, []string{"use_network_tags=tag1", "use_network_tags_for_flex_templates=tag1"}, writer.LaunchParameter.Environment.AdditionalExperiments)
+
+	// Only the requested jobs are launched, in the given run.
+	cmd = reverseReplicationTestCmd(t, "-jobs-to-launch=writer", "-run-identifier=run1", "-dataflow-project=df-project", "-metadata-instance=meta-instance")
+	plan, err = cmd.buildPlan("sp-project", "sp-instance", "sp-db", constants.DIALECT_GOOGLESQL, "{}", shards, now)
+	assert.Nil(t, err)
+	assert.Equal(t, "projects/sp-project/instances/meta-instance/databases/rev_repl_metadata", plan.metadataDbURI)
+	assert.Equal(t, 1, len(plan.jobs))
+	assert.Equal(t, reverseReplicationWriter, plan.jobs[0].role)
+	assert.Equal(t, "df-project", plan.jobs[0].request.ProjectId)
+	assert.Equal(t, "run1", plan.jobs[0].request.LaunchParameter.Parameters["runIdentifier"])
+	assert.Equal(t, "sp-project", plan.jobs[0].request.LaunchParameter.Parameters["spannerProjectId"])
+
+	// A dry run prints the launch requests.
+	var out bytes.Buffer
+	printReverseReplicationPlan(&out, cmd, plan)
+	assert.Contains(t, out.String(), "Validate or create change stream reverseReplicationStream on projects/sp-project/instances/sp-instance/databases/sp-db")
+	assert.Contains(t, out.String(), "GCLOUD CMD FOR WRITER JOB:\ngcloud dataflow flex-template run smt-reverse-replication-writer-run1-")
+}
+
+func TestReverseReplicationRun(t *testing.T) {
+	ctx := context.Background()
+	cmd := reverseReplicationTestCmd(t, "-run-identifier=run1")
+	plan, err := cmd.buildPlan("sp-project", "sp-instance", "sp-db", constants.DIALECT_GOOGLESQL, "{}", []reverseReplicationShard{{LogicalShardId: "shard1"}}, time.Now())
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name             string
+		csExists         bool
+		launchErr        error
+		recordErr        error
+		expectError      bool
+		expectedCalls    []string
+		expectedJobState string
+	}{
+		{
+			name:             "change stream created",
+			expectedCalls:    []string{"create change stream", "create metadata db", "write session.json", "write shards.json", "insert job", "launch reader", "launch writer"},
+			expectedJobState: "RUNNING",
+		},
+		{
+			name:             "change stream validated",
+			csExists:         true,
+			expectedCalls:    []string{"validate change stream", "create metadata db", "write session.json", "write shards.json", "insert job", "launch reader", "launch writer"},
+			expectedJobState: "RUNNING",
+		},
+		{
+			name:             "launch failure",
+			launchErr:        fmt.Errorf("quota exceeded"),
+			expectError:      true,
+			expectedCalls:    []string{"create change stream", "create metadata db", "write session.json", "write shards.json", "insert job", "launch reader"},
+			expectedJobState: "FAILED",
+		},
+		{
+			name:             "launched job not recorded",
+			recordErr:        fmt.Errorf("deadline exceeded"),
+			expectError:      true,
+			expectedCalls:    []string{"create change stream", "create metadata db", "write session.json", "write shards.json", "insert job", "launch reader"},
+			expectedJobState: "FAILED",
+		},
+	}
+	for _, tc := range testCases {
+		var calls []string
+		var jobType, jobState string
+		resources := map[string]string{}
+		svc := reverseReplicationServices{
+			spA: &spanneraccessor.SpannerAccessorMock{
+				CheckIfChangeStreamExistsMock: func(ctx context.Context, changeStreamName, dbURI string) (bool, error) {
+					return tc.csExists, nil
+				},
+				ValidateChangeStreamOptionsMock: func(ctx context.Context, changeStreamName, dbURI string) error {
+					calls = append(calls, "validate change stream")
+					return nil
+				},
+				CreateChangeStreamMock: func(ctx context.Context, changeStreamName, dbURI string) error {
+					calls = append(calls, "create change stream")
+					return nil
+				},
+				CheckExistingDbMock: func(ctx context.Context, dbURI string) (bool, error) {
+					return false, nil
+				},
+				CreateEmptyDatabaseMock: func(ctx context.Context, dbURI string) error {
+					calls = append(calls, "create metadata db")
+					return nil
+				},
+			},
+			storageA: &storageaccessor.StorageAccessorMock{
+				WriteDataToGCSMock: func(ctx context.Context, sc storageclient.StorageClient, filePath, fileName, data string) error {
+					calls = append(calls, "write "+fileName)
+					return nil
+				},
+			},
+			dataflowC: &dataflowclient.DataflowClientMock{
+				LaunchFlexTemplateMock: func(ctx context.Context, req *dataflowpb.LaunchFlexTemplateRequest, opts ...gax.CallOption) (*dataflowpb.LaunchFlexTemplateResponse, error) {
+					role := reverseReplicationReader
+					if strings.Contains(req.LaunchParameter.JobName, reverseReplicationWriter) {
+						role = reverseReplicationWriter
+					}
+					calls = append(calls, "launch "+role)
+					if tc.launchErr != nil {
+						return nil, tc.launchErr
+					}
+					return &dataflowpb.LaunchFlexTemplateResponse{Job: &dataflowpb.Job{Id: "df-" + role}}, nil
+				},
+			},
+			dao: &dao.DAOMock{
+				InsertJobEntryMock: func(ctx context.Context, jobId, jobName, jt, dialect, dbName string, jobData spanner.NullJSON) error {
+					calls = append(calls, "insert job")
+					jobType, jobState = jt, "CREATING"
+					return nil
+				},
+				UpdateJobStateMock: func(ctx context.Context, jobId, state string) error {
+					jobState = state
+					return nil
+				},
+				InsertResourceEntryMock: func(ctx context.Context, resourceId, jobId, externalId, resourceName, resourceType string, resourceData spanner.NullJSON) error {
+					resources[externalId] = resourceType
+					return nil
+				},
+				UpdateResourceStateMock: func(ctx context.Context, resourceId, state string) error {
+					return tc.recordErr
+				},
+			},
+		}
+		var out bytes.Buffer
+		err := cmd.run(ctx, plan, svc, &out)
+		assert.Equal(t, tc.expectError, err != nil, tc.name)
+		assert.Equal(t, tc.expectedCalls, calls, tc.name)
+		assert.Equal(t, constants.REVERSE_REPLICATION, jobType, tc.name)
+		assert.Equal(t, tc.expectedJobState, jobState, tc.name)
+		if !tc.expectError {
+			assert.Equal(t, map[string]string{"df-reader": constants.DATAFLOW_RESOURCE, "df-writer": constants.DATAFLOW_RESOURCE}, resources, tc.name)
+		}
+	}
+}
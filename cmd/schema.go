@@ -38,16 +38,21 @@ import (
 
 // SchemaCmd struct with flags.
 type SchemaCmd struct {
-	source        string
-	sourceProfile string
-	target        string
-	targetProfile string
-	filePrefix    string // TODO: move filePrefix to global flags
-	project       string
-	logLevel      string
-	dryRun        bool
-	validate      bool
-	sessionJSON   string
+	source                string
+	sourceProfile         string
+	target                string
+	targetProfile         string
+	filePrefix            string // TODO: move filePrefix to global flags
+	project               string
+	logLevel              string
+	dryRun                bool
+	validate              bool
+	sessionJSON           string
+	rulesFile             string
+	offlineVerification   bool
+	checkShardKeys        bool
+	shardKeySampleSize    int64
+	fixShardKeyCollisions bool
 }
 
 // Name returns the name of operation.
@@ -83,6 +88,11 @@ func (cmd *SchemaCmd) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&cmd.dryRun, "dry-run", false, "Flag for generating DDL and schema conversion report without creating a spanner database")
 	f.BoolVar(&cmd.validate, "validate", false, "Flag for validating if all the required input parameters are present")
 	f.StringVar(&cmd.sessionJSON, "session", "", "Optional. Specifies the file we restore session state from.")
+	f.StringVar(&cmd.rulesFile, "rules", "", "Optional. Specifies a YAML or JSON file with rules that are applied in order to the converted schema")
+	f.BoolVar(&cmd.offlineVerification, "offline-verification", false, "Verify check constraints and default values locally instead of against a staging database in the Spanner instance")
+	f.BoolVar(&cmd.checkShardKeys, "check-shard-keys", false, "Before a sharded migration, read the primary keys of every table from every shard and report the keys found in more than one shard")
+	f.Int64Var(&cmd.shardKeySampleSize, "shard-key-sample-size", DefaultShardKeySampleSize, "Number of primary keys, lowest first, read per table and shard by -check-shard-keys. 0 reads all keys")
+	f.BoolVar(&cmd.fixShardKeyCollisions, "fix-shard-key-collisions", false, "Add the shard id column to the primary keys of all tables if -check-shard-keys finds keys in more than one shard. Implies -check-shard-keys")
 }
 
 func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
@@ -135,7 +145,7 @@ func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interfa
 		if err != nil {
 			return subcommands.ExitFailure
 		}
-		expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessorImpl(context.Background(), targetProfile.Conn.Sp.Project, targetProfile.Conn.Sp.Instance)
+		expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessor(context.Background(), targetProfile.Conn.Sp.Project, targetProfile.Conn.Sp.Instance, cmd.offlineVerification)
 		schemaToSpanner := common.SchemaToSpannerImpl{
 			ExpressionVerificationAccessor: expressionVerificationAccessor,
 		}
@@ -147,13 +157,14 @@ func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interfa
 		}
 	} else {
 		ctx := context.Background()
-		ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, "", "")
+		ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, "", "", cmd.offlineVerification)
 		if err != nil {
 			logger.Log.Error(fmt.Sprintf("error trying create ddl verifier: %v", err))
 			return subcommands.ExitFailure
 		}
 		sfs := &conversion.SchemaFromSourceImpl{
-			DdlVerifier: ddlVerifier,
+			DdlVerifier:         ddlVerifier,
+			OfflineVerification: cmd.offlineVerification,
 		}
 		conv, err = convImpl.SchemaConv(cmd.project, sourceProfile, targetProfile, &ioHelper, sfs)
 		if err != nil {
@@ -164,6 +175,21 @@ func (cmd *SchemaCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interfa
 		logger.Log.Error("Could not initialize conversion context from")
 		return subcommands.ExitFailure
 	}
+	// Flag the same primary key hotspots as the UI, so that they are
+	// reported and can be fixed by fix_hotspot rules.
+	internal.DetectHotspots(conv)
+	if cmd.rulesFile != "" {
+		err = applyRulesFile(conv, cmd.rulesFile)
+		if err != nil {
+			return subcommands.ExitUsageError
+		}
+	}
+	if cmd.checkShardKeys || cmd.fixShardKeyCollisions {
+		err = checkShardKeys(cmd.project, sourceProfile, targetProfile, conv, cmd.shardKeySampleSize, cmd.fixShardKeyCollisions)
+		if err != nil {
+			return subcommands.ExitFailure
+		}
+	}
 	conversion.WriteSchemaFile(conv, schemaConversionStartTime, cmd.filePrefix+schemaFile, ioHelper.Out, sourceProfile.Driver)
 	// We always write the session file to accommodate for a re-run that might change anything.
 	conversion.WriteSessionFile(conv, cmd.filePrefix+sessionFile, ioHelper.Out)
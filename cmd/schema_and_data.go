@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/prommetrics"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/utils"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/conversion"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/expressions_api"
@@ -39,18 +40,31 @@ import (
 
 // SchemaAndDataCmd struct with flags.
 type SchemaAndDataCmd struct {
-	source           string
-	sourceProfile    string
-	target           string
-	targetProfile    string
-	SkipForeignKeys  bool
-	filePrefix       string // TODO: move filePrefix to global flags
-	project          string
-	WriteLimit       int64
-	dryRun           bool
-	logLevel         string
-	validate         bool
-	dataflowTemplate string
+	source                string
+	sourceProfile         string
+	target                string
+	targetProfile         string
+	SkipForeignKeys       bool
+	filePrefix            string // TODO: move filePrefix to global flags
+	project               string
+	WriteLimit            int64
+	dryRun                bool
+	logLevel              string
+	validate              bool
+	dataflowTemplate      string
+	checkpoint            bool
+	resume                bool
+	deadLetter            bool
+	rulesFile             string
+	deferIndexes          bool
+	offlineVerification   bool
+	adaptiveWrites        bool
+	maxRowsPerSecond      float64
+	maxCPUPercent         float64
+	metricsAddress        string
+	checkShardKeys        bool
+	shardKeySampleSize    int64
+	fixShardKeyCollisions bool
 }
 
 // Name returns the name of operation.
@@ -88,6 +102,19 @@ func (cmd *SchemaAndDataCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&cmd.logLevel, "log-level", "DEBUG", "Configure the logging level for the command (INFO, DEBUG), defaults to DEBUG")
 	f.BoolVar(&cmd.validate, "validate", false, "Flag for validating if all the required input parameters are present")
 	f.StringVar(&cmd.dataflowTemplate, "dataflow-template", constants.DEFAULT_TEMPLATE_PATH, "GCS path of the Dataflow template")
+	f.BoolVar(&cmd.checkpoint, "checkpoint", false, "Record the progress of the bulk data migration in a checkpoint file, so that it can be resumed with --resume")
+	f.BoolVar(&cmd.resume, "resume", false, "Resume an interrupted bulk data migration from its checkpoint file, skipping completed tables")
+	f.BoolVar(&cmd.deadLetter, "dead-letter", false, "Write every row that can't be converted or written to Spanner to a dead-letter file, so that it can be replayed with the replay-dlq command")
+	f.StringVar(&cmd.rulesFile, "rules", "", "Optional. Specifies a YAML or JSON file with rules that are applied in order to the converted schema")
+	f.BoolVar(&cmd.deferIndexes, "defer-indexes", false, "Create secondary indexes after data migration is complete instead of along with the tables. Not supported for minimal downtime migrations")
+	f.BoolVar(&cmd.offlineVerification, "offline-verification", false, "Verify check constraints and default values locally instead of against a staging database in the Spanner instance")
+	f.BoolVar(&cmd.adaptiveWrites, "adaptive-writes", false, "Tune the batch size and number of parallel writes (up to write-limit) from Spanner commit latency and errors, backing off when Spanner pushes back")
+	f.Float64Var(&cmd.maxRowsPerSecond, "max-rows-per-second", 0, "Optional. Caps the rate at which rows are written to Spanner")
+	f.Float64Var(&cmd.maxCPUPercent, "max-cpu-percent", 0, "Optional. Reduces parallel writes while the high priority CPU utilization of the Spanner instance exceeds this percentage. Requires --adaptive-writes")
+	f.StringVar(&cmd.metricsAddress, "metrics-address", "", "Optional. Serves Prometheus metrics of the migration on /metrics at this address, e.g. \":9090\"")
+	f.BoolVar(&cmd.checkShardKeys, "check-shard-keys", false, "Before a sharded migration, read the primary keys of every table from every shard and report the keys found in more than one shard")
+	f.Int64Var(&cmd.shardKeySampleSize, "shard-key-sample-size", DefaultShardKeySampleSize, "Number of primary keys, lowest first, read per table and shard by -check-shard-keys. 0 reads all keys")
+	f.BoolVar(&cmd.fixShardKeyCollisions, "fix-shard-key-collisions", false, "Add the shard id column to the primary keys of all tables if -check-shard-keys finds keys in more than one shard. Implies -check-shard-keys")
 }
 
 func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
@@ -105,6 +132,10 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 		return subcommands.ExitFailure
 	}
 	defer logger.Log.Sync()
+	err = startMetricsEndpoint(cmd.metricsAddress)
+	if err != nil {
+		return subcommands.ExitUsageError
+	}
 	utils.SetDataflowTemplatePath(cmd.dataflowTemplate)
 	// validate and parse source-profile, target-profile and source
 	sourceProfile, targetProfile, ioHelper, dbName, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
@@ -137,20 +168,41 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 		dbURI  string
 	)
 	convImpl := &conversion.ConvImpl{}
-	ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, "", "")
+	ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, "", "", cmd.offlineVerification)
 	if err != nil {
 		logger.Log.Error(fmt.Sprintf("error trying create ddl verifier: %v", err))
 		return subcommands.ExitFailure
 	}
 	sfs := &conversion.SchemaFromSourceImpl{
-		DdlVerifier: ddlVerifier,
+		DdlVerifier:         ddlVerifier,
+		OfflineVerification: cmd.offlineVerification,
 	}
 	conv, err = convImpl.SchemaConv(cmd.project, sourceProfile, targetProfile, &ioHelper, sfs)
 	if err != nil {
 		panic(err)
 	}
+	// Flag the same primary key hotspots as the UI, so that they are
+	// reported and can be fixed by fix_hotspot rules.
+	internal.DetectHotspots(conv)
+	if cmd.rulesFile != "" {
+		err = applyRulesFile(conv, cmd.rulesFile)
+		if err != nil {
+			return subcommands.ExitUsageError
+		}
+		err = validateRowFilters(conv, sourceProfile)
+		if err != nil {
+			return subcommands.ExitUsageError
+		}
+	}
+	if cmd.checkShardKeys || cmd.fixShardKeyCollisions {
+		err = checkShardKeys(cmd.project, sourceProfile, targetProfile, conv, cmd.shardKeySampleSize, cmd.fixShardKeyCollisions)
+		if err != nil {
+			return subcommands.ExitFailure
+		}
+	}
 	schemaCoversionEndTime := time.Now()
 	conv.Audit.SchemaConversionDuration = schemaCoversionEndTime.Sub(schemaConversionStartTime)
+	prommetrics.ObservePhase(prommetrics.PhaseSchemaConversion, conv.Audit.SchemaConversionDuration)
 
 	// Populate migration request id and migration type in conv object.
 	conv.Audit.MigrationRequestId, _ = utils.GenerateName("smt-job")
@@ -160,8 +212,24 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 	conversion.WriteSchemaFile(conv, schemaConversionStartTime, cmd.filePrefix+schemaFile, ioHelper.Out, sourceProfile.Driver)
 	conversion.WriteSessionFile(conv, cmd.filePrefix+sessionFile, ioHelper.Out)
 	conv.Audit.SkipMetricsPopulation = os.Getenv("SKIP_METRICS_POPULATION") == "true"
+	conv.Audit.WriteSettings = getWriteSettings(cmd.adaptiveWrites, cmd.maxRowsPerSecond, cmd.maxCPUPercent)
+	if cmd.deferIndexes {
+		if sourceProfile.Config.ConfigType == constants.DATAFLOW_MIGRATION {
+			logger.Log.Warn("Ignoring -defer-indexes flag: secondary indexes are always created along with the tables for minimal downtime migrations")
+		} else {
+			conv.Audit.DeferIndexes = true
+		}
+	}
 	reportImpl := conversion.ReportImpl{}
 	if !cmd.dryRun {
+		err = initCheckpoint(conv, targetProfile, cmd.filePrefix+checkpointFile, cmd.checkpoint, cmd.resume)
+		if err != nil {
+			return subcommands.ExitUsageError
+		}
+		if cmd.deadLetter {
+			conv.DeadLetter = internal.NewDeadLetterQueue(cmd.filePrefix+deadLetterFile, cmd.resume)
+			defer conv.DeadLetter.Close()
+		}
 		reportImpl.GenerateReport(sourceProfile.Driver, nil, ioHelper.BytesRead, "", conv, cmd.filePrefix, dbName, ioHelper.Out)
 		bw, err = MigrateDatabase(ctx, cmd.project, targetProfile, sourceProfile, dbName, &ioHelper, cmd, conv, nil)
 		if err != nil {
@@ -195,6 +263,11 @@ func (cmd *SchemaAndDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...
 		banner = utils.GetBanner(schemaConversionStartTime, dbName)
 	}
 	reportImpl.GenerateReport(sourceProfile.Driver, bw.DroppedRowsByTable(), ioHelper.BytesRead, banner, conv, cmd.filePrefix, dbName, ioHelper.Out)
+	if conv.DeadLetter != nil {
+		if err := conv.DeadLetter.Close(); err != nil {
+			fmt.Fprintf(ioHelper.Out, "Can't write dead-letter file %s: %v\n", conv.DeadLetter.Path(), err)
+		}
+	}
 	conversion.WriteBadData(bw, conv, banner, cmd.filePrefix+badDataFile, ioHelper.Out)
 
 	// Cleanup smt tmp data directory.
@@ -43,6 +43,7 @@ func TestSchemaAndDataSetFlags(t *testing.T) {
                                 SkipForeignKeys:  false,
                                 validate:         false,
                                 dataflowTemplate: constants.DEFAULT_TEMPLATE_PATH,
+                                shardKeySampleSize: DefaultShardKeySampleSize,
                         },
                 },
                 {
@@ -60,6 +61,7 @@ func TestSchemaAndDataSetFlags(t *testing.T) {
                                 SkipForeignKeys:  false,
                                 validate:         false,
                                 dataflowTemplate: constants.DEFAULT_TEMPLATE_PATH,
+                                shardKeySampleSize: DefaultShardKeySampleSize,
                         },
                 },
                 {
@@ -77,6 +79,7 @@ func TestSchemaAndDataSetFlags(t *testing.T) {
                                 SkipForeignKeys:  false,
                                 validate:         false,
                                 dataflowTemplate: constants.DEFAULT_TEMPLATE_PATH,
+                                shardKeySampleSize: DefaultShardKeySampleSize,
                         },
                 },
                 {
@@ -94,6 +97,7 @@ func TestSchemaAndDataSetFlags(t *testing.T) {
                                 SkipForeignKeys:  false,
                                 validate:         false,
                                 dataflowTemplate: constants.DEFAULT_TEMPLATE_PATH,
+                                shardKeySampleSize: DefaultShardKeySampleSize,
                         },
                 },
                 {
@@ -111,6 +115,7 @@ func TestSchemaAndDataSetFlags(t *testing.T) {
                                 SkipForeignKeys:  false,
                                 validate:         false,
                                 dataflowTemplate: constants.DEFAULT_TEMPLATE_PATH,
+                                shardKeySampleSize: DefaultShardKeySampleSize,
                         },
                 },
                 {
@@ -128,6 +133,7 @@ func TestSchemaAndDataSetFlags(t *testing.T) {
                                 SkipForeignKeys:  true,
                                 validate:         true,
                                 dataflowTemplate: constants.DEFAULT_TEMPLATE_PATH,
+                                shardKeySampleSize: DefaultShardKeySampleSize,
                         },
                 },
                 {
@@ -145,6 +151,7 @@ func TestSchemaAndDataSetFlags(t *testing.T) {
                                 SkipForeignKeys:  false,
                                 validate:         false,
                                 dataflowTemplate: "gs://my-bucket/my-template",
+                                shardKeySampleSize: DefaultShardKeySampleSize,
                         },
                 },
                 {
@@ -174,6 +181,7 @@ func TestSchemaAndDataSetFlags(t *testing.T) {
                                 SkipForeignKeys:  true,
                                 validate:         true,
                                 dataflowTemplate: "gs://custom/template",
+                                shardKeySampleSize: DefaultShardKeySampleSize,
                         },
                 },
         }
@@ -0,0 +1,195 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/utils"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/conversion"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/google/subcommands"
+	"go.uber.org/zap"
+)
+
+// SchemaDiffCmd struct with flags.
+type SchemaDiffCmd struct {
+	sessionJSON     string
+	fromSessionJSON string
+	targetProfile   string
+	filePrefix      string
+	logLevel        string
+}
+
+// Name returns the name of operation.
+func (cmd *SchemaDiffCmd) Name() string {
+	return "schema-diff"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *SchemaDiffCmd) Synopsis() string {
+	return "diff the spanner schema of a session against another session or a spanner database"
+}
+
+// Usage returns usage info of the command.
+func (cmd *SchemaDiffCmd) Usage() string {
+	return fmt.Sprintf(`%v schema-diff -session=[new_session_file] -from-session=[old_session_file] ...
+
+Compare the Spanner schema of a session file with the Spanner schema of an older
+session file, or of an existing Spanner database specified in target-profile.
+The structural differences and the DDL statements that change the older schema
+into the schema of the session are written out. The schema-diff flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *SchemaDiffCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.sessionJSON, "session", "", "Specifies the session file with the new schema")
+	f.StringVar(&cmd.fromSessionJSON, "from-session", "", "Specifies the session file with the old schema")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying the Spanner database with the old schema when --from-session isn't set e.g., \"instance=my-instance,dbName=my-db\"")
+	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
+	f.StringVar(&cmd.logLevel, "log-level", "DEBUG", "Configure the logging level for the command (INFO, DEBUG), defaults to DEBUG")
+}
+
+func (cmd *SchemaDiffCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	var err error
+	defer func() {
+		if err != nil {
+			logger.Log.Fatal("FATAL error", zap.Error(err))
+		}
+	}()
+	err = logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+
+	if cmd.sessionJSON == "" {
+		err = fmt.Errorf("cannot leave --session flag empty, please specify session file path e.g., --session=./session.json etc")
+		return subcommands.ExitUsageError
+	}
+	if (cmd.fromSessionJSON == "") == (cmd.targetProfile == "") {
+		err = fmt.Errorf("please specify exactly one of --from-session or --target-profile")
+		return subcommands.ExitUsageError
+	}
+	toConv := internal.MakeConv()
+	err = conversion.ReadSessionFile(toConv, cmd.sessionJSON)
+	if err != nil {
+		return subcommands.ExitUsageError
+	}
+	fromConv := internal.MakeConv()
+	if cmd.fromSessionJSON != "" {
+		err = conversion.ReadSessionFile(fromConv, cmd.fromSessionJSON)
+		if err != nil {
+			return subcommands.ExitUsageError
+		}
+	} else {
+		err = readSpannerDatabaseSchema(ctx, cmd.targetProfile, fromConv)
+		if err != nil {
+			return subcommands.ExitFailure
+		}
+	}
+	if fromConv.SpDialect != toConv.SpDialect {
+		err = fmt.Errorf("spanner dialects don't match: old schema dialect %v, new schema dialect %v", fromConv.SpDialect, toConv.SpDialect)
+		return subcommands.ExitUsageError
+	}
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = "schema"
+	}
+
+	diff := ddl.DiffSchemas(fromConv.SpSchema, toConv.SpSchema, fromConv.SpSequences, toConv.SpSequences)
+	stmts, warnings := diff.Statements(ddl.Config{Comments: true, ProtectIds: true, Tables: true, ForeignKeys: true, SpDialect: toConv.SpDialect, Source: toConv.Source})
+	err = writeSchemaDiff(diff, stmts, cmd.filePrefix)
+	if err != nil {
+		return subcommands.ExitFailure
+	}
+	if diff.IsEmpty() {
+		fmt.Println("Schemas are identical.")
+	} else {
+		fmt.Printf("%d tables added, %d dropped and %d modified; %d DDL statements generated.\n",
+			len(diff.AddedTables), len(diff.DroppedTables), len(diff.ModifiedTables), len(stmts))
+	}
+	for _, w := range warnings {
+		fmt.Printf("WARNING: %s\n", w)
+	}
+	return subcommands.ExitSuccess
+}
+
+// readSpannerDatabaseSchema reads the schema of the Spanner database specified
+// in targetProfileStr into conv.
+func readSpannerDatabaseSchema(ctx context.Context, targetProfileStr string, conv *internal.Conv) error {
+	targetProfile, err := profiles.NewTargetProfile(targetProfileStr)
+	if err != nil {
+		return err
+	}
+	if targetProfile.Conn.Sp.Dbname == "" {
+		return fmt.Errorf("please specify the Spanner database to diff with dbName in --target-profile")
+	}
+	project, instance, dbName, err := targetProfile.GetResourceIds(ctx, time.Now(), "", os.Stdout, &utils.GetUtilInfoImpl{})
+	if err != nil {
+		return err
+	}
+	dbURI := fmt.Sprintf("projects/%s/instances/%s/databases/%s", project, instance, dbName)
+	client, err := utils.GetClient(ctx, dbURI)
+	if err != nil {
+		return fmt.Errorf("can't create client for db %s: %v", dbURI, err)
+	}
+	defer client.Close()
+	conv.SpProjectId, conv.SpInstanceId = project, instance
+	conv.SpDialect = targetProfile.Conn.Sp.Dialect
+	if conv.SpDialect == "" {
+		conv.SpDialect = constants.DIALECT_GOOGLESQL
+	}
+	err = utils.ReadSpannerSchema(ctx, conv, client)
+	if err != nil {
+		return fmt.Errorf("can't read spanner schema: %v", err)
+	}
+	return utils.ReadSpannerSequences(ctx, conv, client)
+}
+
+// writeSchemaDiff writes diff to <prefix>.schema_diff.json and the DDL
+// statements stmts to <prefix>.schema_diff.ddl.txt.
+func writeSchemaDiff(diff ddl.SchemaDiff, stmts []string, prefix string) error {
+	diffFile := prefix + schemaDiffFile
+	b, err := json.MarshalIndent(diff, "", " ")
+	if err != nil {
+		return fmt.Errorf("can't encode schema diff: %v", err)
+	}
+	if err := os.WriteFile(diffFile, b, 0644); err != nil {
+		return fmt.Errorf("can't write out schema diff file %s: %v", diffFile, err)
+	}
+	ddlFile := prefix + schemaDiffDDLFile
+	var ddlText string
+	for _, stmt := range stmts {
+		ddlText += stmt + ";\n\n"
+	}
+	if err := os.WriteFile(ddlFile, []byte(ddlText), 0644); err != nil {
+		return fmt.Errorf("can't write out schema diff DDL file %s: %v", ddlFile, err)
+	}
+	fmt.Printf("Wrote schema diff to file '%s'.\n", diffFile)
+	fmt.Printf("Wrote schema diff DDL statements to file '%s'.\n", ddlFile)
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"time"
 
 	sp "cloud.google.com/go/spanner"
@@ -30,25 +31,34 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/metrics"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/parse"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/prommetrics"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/utils"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/conversion"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/api"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/helpers"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/proto"
 )
 
 var (
-	badDataFile = ".dropped.txt"
-	schemaFile  = ".schema.txt"
-	sessionFile = ".session.json"
+	badDataFile          = ".dropped.txt"
+	schemaFile           = ".schema.txt"
+	sessionFile          = ".session.json"
+	checkpointFile       = ".checkpoint.json"
+	deadLetterFile       = ".dead_letter.jsonl"
+	validationReportFile = ".validation.json"
+	schemaDiffFile       = ".schema_diff.json"
+	schemaDiffDDLFile    = ".schema_diff.ddl.txt"
 )
 
 const (
-	DefaultWritersLimit  = 40
-	completionPercentage = 100
+	DefaultWritersLimit       = 40
+	completionPercentage      = 100
+	DefaultShardKeySampleSize = 10000
 )
 
 func metricsPopulation(ctx context.Context, driver string, conv *internal.Conv) {
@@ -125,6 +135,111 @@ func PrepareMigrationPrerequisites(sourceProfileString, targetProfileString, sou
 	return sourceProfile, targetProfile, ioHelper, dbName, nil
 }
 
+// startMetricsEndpoint serves Prometheus metrics of the migration on
+// /metrics at addr, if set.
+func startMetricsEndpoint(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	a, err := prommetrics.Serve(addr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Serving migration metrics on http://%s/metrics\n", a)
+	return nil
+}
+
+// initCheckpoint enables checkpointing of bulk data migration for conv when
+// checkpoint or resume is set. Checkpointing reads each table in primary key
+// order, so it is off by default. The checkpoint is persisted at path, and
+// when resume is true, the progress recorded there by a previous run is
+// loaded so that completed tables are skipped and partially migrated ones
+// continue from their last committed key.
+func initCheckpoint(conv *internal.Conv, targetProfile profiles.TargetProfile, path string, checkpoint, resume bool) error {
+	if !resume {
+		if checkpoint {
+			conv.Checkpoint = internal.NewCheckpoint(path)
+		}
+		return nil
+	}
+	if targetProfile.Conn.Sp.Dbname == "" {
+		return fmt.Errorf("dbName must be specified in target-profile to resume a migration")
+	}
+	cp, err := internal.LoadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Resuming migration using checkpoint file %s\n", path)
+	conv.Checkpoint = cp
+	return nil
+}
+
+// applyRulesFile applies the rules of the rules file at path to the schema of
+// conv, using the same code paths as the UI.
+func applyRulesFile(conv *internal.Conv, path string) error {
+	rules, err := api.ReadRulesFile(path)
+	if err != nil {
+		return err
+	}
+	if err := api.ApplyFileRules(conv, rules); err != nil {
+		return fmt.Errorf("can't apply rules file %s:\n%v", path, err)
+	}
+	logger.Log.Info(fmt.Sprintf("Applied %d rules of rules file %s", len(rules), path))
+	return nil
+}
+
+// checkShardKeys reads the primary keys of every table from every shard of a
+// sharded migration, at most sampleSize per table and shard unless
+// sampleSize is 0, and warns about the tables with keys found in more than
+// one shard. If fix is set, the shard id column is then added to the primary
+// keys of all tables with an add_shard_id_primary_key rule.
+func checkShardKeys(project string, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, conv *internal.Conv, sampleSize int64, fix bool) error {
+	err := conversion.AnalyzeShardKeys(project, sourceProfile, targetProfile, conv, sampleSize, &conversion.GetInfoImpl{})
+	if err != nil {
+		return fmt.Errorf("can't check primary keys across shards: %v", err)
+	}
+	var tables []string
+	for _, tableId := range conv.CollidingShardKeyTables() {
+		c := conv.ShardKeyCollisions[tableId]
+		tables = append(tables, fmt.Sprintf("%s (%d keys)", conv.SpSchema[tableId].Name, c.Colliding))
+	}
+	if len(tables) == 0 {
+		logger.Log.Info(fmt.Sprintf("No primary key found in more than one shard in %d tables", len(conv.ShardKeyCollisions)))
+		return nil
+	}
+	if !fix {
+		logger.Log.Warn(fmt.Sprintf("Primary keys found in more than one shard in tables %s. Only one row per key can be written to Spanner: "+
+			"add the shard id column to the primary keys with an add_shard_id_primary_key rule or the -fix-shard-key-collisions flag", strings.Join(tables, ", ")))
+		return nil
+	}
+	rule := api.FileRule{Type: constants.AddShardIdPrimaryKey, AddedAtTheStart: true}
+	if err := api.ApplyFileRules(conv, []api.FileRule{rule}); err != nil {
+		return fmt.Errorf("can't add the shard id column to the primary keys: %v", err)
+	}
+	logger.Log.Info(fmt.Sprintf("Added the shard id column to the primary keys of all tables, since primary keys were found in more than one shard in tables %s", strings.Join(tables, ", ")))
+	return nil
+}
+
+// getWriteSettings returns the settings for writing data to Spanner from the
+// values of the write throttling flags.
+func getWriteSettings(adaptive bool, maxRowsPerSecond, maxCPUPercent float64) internal.WriteSettings {
+	if maxCPUPercent > 0 && !adaptive {
+		logger.Log.Warn("Ignoring -max-cpu-percent flag: it requires -adaptive-writes")
+		maxCPUPercent = 0
+	}
+	return internal.WriteSettings{Adaptive: adaptive, MaxRowsPerSecond: maxRowsPerSecond, MaxCPUPercent: maxCPUPercent}
+}
+
+// validateRowFilters checks the row filters of the source tables of conv. Dump
+// files and DynamoDB evaluate the filters client-side. Other sources push the
+// filters down into their queries, where the source database checks them.
+func validateRowFilters(conv *internal.Conv, sourceProfile profiles.SourceProfile) error {
+	if sourceProfile.Ty != profiles.SourceProfileTypeFile && sourceProfile.Driver != constants.DYNAMODB {
+		return conv.ValidatePushDownRowFilters()
+	}
+	return conv.ValidateRowFilters()
+}
+
 // MigrateData creates database and populates data in it.
 func MigrateDatabase(ctx context.Context, migrationProjectId string, targetProfile profiles.TargetProfile, sourceProfile profiles.SourceProfile, dbName string, ioHelper *utils.IOStreams, cmd interface{}, conv *internal.Conv, migrationError *error) (*writer.BatchWriter, error) {
 	var (
@@ -164,11 +279,13 @@ func migrateSchema(ctx context.Context, targetProfile profiles.TargetProfile, so
 	if err != nil {
 		return err
 	}
+	start := time.Now()
 	err = spA.CreateOrUpdateDatabase(ctx, dbURI, sourceProfile.Driver, conv, sourceProfile.Config.ConfigType)
 	if err != nil {
 		err = fmt.Errorf("can't create/update database: %v", err)
 		return err
 	}
+	prommetrics.ObservePhase(prommetrics.PhaseSchemaCreation, time.Since(start))
 	metricsPopulation(ctx, sourceProfile.Driver, conv)
 	conv.Audit.Progress.UpdateProgress("Schema migration complete.", completionPercentage, internal.SchemaMigrationComplete)
 	return nil
@@ -198,19 +315,23 @@ func migrateData(ctx context.Context, migrationProjectId string, targetProfile p
 	}
 
 	c := &conversion.ConvImpl{}
+	start := time.Now()
 	bw, err = c.DataConv(ctx, migrationProjectId, sourceProfile, targetProfile, ioHelper, client, conv, true, cmd.WriteLimit, &conversion.DataFromSourceImpl{})
 
 	if err != nil {
 		err = fmt.Errorf("can't finish data conversion for db %s: %v", dbURI, err)
 		return nil, err
 	}
+	prommetrics.ObservePhase(prommetrics.PhaseData, time.Since(start))
 	conv.Audit.Progress.UpdateProgress("Data migration complete.", completionPercentage, internal.DataMigrationComplete)
 	if !cmd.SkipForeignKeys {
 		spA, err := spanneraccessor.NewSpannerAccessorClientImpl(ctx)
 		if err != nil {
 			return bw, err
 		}
+		start = time.Now()
 		spA.UpdateDDLForeignKeys(ctx, dbURI, conv, sourceProfile.Driver, sourceProfile.Config.ConfigType)
+		prommetrics.ObservePhase(prommetrics.PhaseForeignKeys, time.Since(start))
 	}
 	return bw, nil
 }
@@ -221,10 +342,22 @@ func migrateSchemaAndData(ctx context.Context, migrationProjectId string, target
 	if err != nil {
 		return nil, err
 	}
-	err = spA.CreateOrUpdateDatabase(ctx, dbURI, sourceProfile.Driver, conv, sourceProfile.Config.ConfigType)
-	if err != nil {
-		err = fmt.Errorf("can't create/update database: %v", err)
-		return nil, err
+	if cmd.resume && conv.Checkpoint != nil && len(conv.Checkpoint.Tables) > 0 {
+		// The previous run already created the schema and started loading
+		// data, so only verify that the schema still matches.
+		err = validateExistingDb(ctx, conv.SpDialect, dbURI, adminClient, client, conv)
+		if err != nil {
+			err = fmt.Errorf("error while validating existing database for resuming migration: %v", err)
+			return nil, err
+		}
+	} else {
+		start := time.Now()
+		err = spA.CreateOrUpdateDatabase(ctx, dbURI, sourceProfile.Driver, conv, sourceProfile.Config.ConfigType)
+		if err != nil {
+			err = fmt.Errorf("can't create/update database: %v", err)
+			return nil, err
+		}
+		prommetrics.ObservePhase(prommetrics.PhaseSchemaCreation, time.Since(start))
 	}
 	metricsPopulation(ctx, sourceProfile.Driver, conv)
 	conv.Audit.Progress.UpdateProgress("Schema migration complete.", completionPercentage, internal.SchemaMigrationComplete)
@@ -238,16 +371,25 @@ func migrateSchemaAndData(ctx context.Context, migrationProjectId string, target
 	}
 
 	convImpl := &conversion.ConvImpl{}
+	start := time.Now()
 	bw, err := convImpl.DataConv(ctx, migrationProjectId, sourceProfile, targetProfile, ioHelper, client, conv, true, cmd.WriteLimit, &conversion.DataFromSourceImpl{})
 
 	if err != nil {
 		err = fmt.Errorf("can't finish data conversion for db %s: %v", dbURI, err)
 		return nil, err
 	}
+	prommetrics.ObservePhase(prommetrics.PhaseData, time.Since(start))
 
 	conv.Audit.Progress.UpdateProgress("Data migration complete.", completionPercentage, internal.DataMigrationComplete)
+	if conv.Audit.DeferIndexes {
+		start = time.Now()
+		spA.CreateDeferredIndexes(ctx, dbURI, conv, sourceProfile.Driver)
+		prommetrics.ObservePhase(prommetrics.PhaseIndexes, time.Since(start))
+	}
 	if !cmd.SkipForeignKeys {
+		start = time.Now()
 		spA.UpdateDDLForeignKeys(ctx, dbURI, conv, sourceProfile.Driver, sourceProfile.Config.ConfigType)
+		prommetrics.ObservePhase(prommetrics.PhaseForeignKeys, time.Since(start))
 	}
 	return bw, nil
 }
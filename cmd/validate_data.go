@@ -0,0 +1,181 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/utils"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/conversion"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/validation"
+	"github.com/google/subcommands"
+	"go.uber.org/zap"
+)
+
+// ValidateDataCmd struct with flags.
+type ValidateDataCmd struct {
+	source            string
+	sourceProfile     string
+	targetProfile     string
+	sessionJSON       string
+	filePrefix        string
+	project           string
+	logLevel          string
+	chunks            int
+	maxMismatchedKeys int
+	compareValues     bool
+}
+
+// Name returns the name of operation.
+func (cmd *ValidateDataCmd) Name() string {
+	return "validate-data"
+}
+
+// Synopsis returns summary of operation.
+func (cmd *ValidateDataCmd) Synopsis() string {
+	return "validate the data migrated to target db against source db"
+}
+
+// Usage returns usage info of the command.
+func (cmd *ValidateDataCmd) Usage() string {
+	return fmt.Sprintf(`%v validate-data -session=[session_file] -source=[source] -source-profile=[source_profile] -target-profile="instance=my-instance,dbName=my-db"...
+
+Validate the data migrated to target db against source db. The rows of chunks
+of primary key ranges of every table are counted and hashed in both databases,
+and the chunks whose counts or hashes differ are compared row by row to list
+the keys of the differing rows. Source db must be specified with a direct connection profile.
+The validate-data flags are:
+`, path.Base(os.Args[0]))
+}
+
+// SetFlags sets the flags.
+func (cmd *ValidateDataCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&cmd.source, "source", "", "Flag for specifying source DB, (e.g., `PostgreSQL`, `MySQL`, `DynamoDB`)")
+	f.StringVar(&cmd.sourceProfile, "source-profile", "", "Flag for specifying connection profile for source database e.g., \"host=localhost,port=3306,user=root,dbName=db\"")
+	f.StringVar(&cmd.sessionJSON, "session", "", "Specifies the file we restore session state from")
+	f.StringVar(&cmd.targetProfile, "target-profile", "", "Flag for specifying connection profile for target database e.g., \"instance=my-instance,dbName=my-db\"")
+	f.StringVar(&cmd.filePrefix, "prefix", "", "File prefix for generated files")
+	f.StringVar(&cmd.project, "project", "", "Flag spcifying default project id for all the generated resources for the migration")
+	f.StringVar(&cmd.logLevel, "log-level", "DEBUG", "Configure the logging level for the command (INFO, DEBUG), defaults to DEBUG")
+	f.IntVar(&cmd.chunks, "chunks", validation.DefaultChunks, "Maximum number of primary key range chunks that each table is divided into")
+	f.IntVar(&cmd.maxMismatchedKeys, "max-mismatched-keys", validation.DefaultMaxMismatchedKeys, "Maximum number of mismatched keys listed per table in the validation report")
+	f.BoolVar(&cmd.compareValues, "compare-values", false, "Compare the rows of every chunk to detect differences in columns that can't be hashed in the database, which reads every row of both databases")
+}
+
+func (cmd *ValidateDataCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	var err error
+	defer func() {
+		if err != nil {
+			logger.Log.Fatal("FATAL error", zap.Error(err))
+		}
+	}()
+	err = logger.InitializeLogger(cmd.logLevel)
+	if err != nil {
+		fmt.Println("Error initialising logger, did you specify a valid log-level? [DEBUG, INFO, WARN, ERROR, FATAL]", err)
+		return subcommands.ExitFailure
+	}
+	defer logger.Log.Sync()
+
+	if cmd.sessionJSON == "" {
+		err = fmt.Errorf("cannot leave --session flag empty, please specify session file path e.g., --session=./session.json etc")
+		return subcommands.ExitUsageError
+	}
+	if cmd.chunks < 1 {
+		err = fmt.Errorf("--chunks must be at least 1")
+		return subcommands.ExitUsageError
+	}
+	sourceProfile, targetProfile, ioHelper, dbName, err := PrepareMigrationPrerequisites(cmd.sourceProfile, cmd.targetProfile, cmd.source)
+	if err != nil {
+		err = fmt.Errorf("error while preparing prerequisites for validation: %v", err)
+		return subcommands.ExitUsageError
+	}
+	if sourceProfile.Ty != profiles.SourceProfileTypeConnection {
+		err = fmt.Errorf("data validation requires a direct connection to the source database, please specify connection params in --source-profile")
+		return subcommands.ExitUsageError
+	}
+	if targetProfile.Conn.Sp.Dbname == "" {
+		err = fmt.Errorf("please specify the Spanner database to validate with dbName in --target-profile")
+		return subcommands.ExitUsageError
+	}
+	if cmd.project == "" {
+		getInfo := &utils.GetUtilInfoImpl{}
+		cmd.project, err = getInfo.GetProject()
+		if err != nil {
+			logger.Log.Error("Could not get project id from gcloud environment or --project flag. Either pass the projectId in the --project flag or configure in gcloud CLI using gcloud config set", zap.Error(err))
+			return subcommands.ExitUsageError
+		}
+	}
+
+	conv := internal.MakeConv()
+	err = conversion.ReadSessionFile(conv, cmd.sessionJSON)
+	if err != nil {
+		return subcommands.ExitUsageError
+	}
+	// Source rows are converted like a data migration does, but are passed to
+	// the validator instead of being written.
+	conv.Audit.DryRun = false
+	if cmd.filePrefix == "" {
+		cmd.filePrefix = targetProfile.Conn.Sp.Dbname
+	}
+
+	getInfo := &conversion.GetInfoImpl{}
+	infoSchema, err := getInfo.GetInfoSchema(cmd.project, sourceProfile, targetProfile)
+	if err != nil {
+		err = fmt.Errorf("can't connect to source database: %v", err)
+		return subcommands.ExitFailure
+	}
+	_, client, dbURI, err := CreateDatabaseClient(ctx, targetProfile, sourceProfile.Driver, dbName, ioHelper)
+	if err != nil {
+		err = fmt.Errorf("can't create client for spanner: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer client.Close()
+
+	dv := validation.DataValidator{
+		Conv:              conv,
+		InfoSchema:        infoSchema,
+		Spanner:           &validation.SpannerReaderImpl{Client: client, SpDialect: conv.SpDialect},
+		Chunks:            cmd.chunks,
+		MaxMismatchedKeys: cmd.maxMismatchedKeys,
+		CompareValues:     cmd.compareValues,
+	}
+	report := dv.Validate(ctx, targetProfile.Conn.Sp.Dbname)
+
+	reportFileName := cmd.filePrefix + validationReportFile
+	b, err := json.MarshalIndent(report, "", " ")
+	if err != nil {
+		err = fmt.Errorf("can't encode data validation report: %v", err)
+		return subcommands.ExitFailure
+	}
+	if err = os.WriteFile(reportFileName, b, 0644); err != nil {
+		err = fmt.Errorf("can't write out data validation report file %s: %v", reportFileName, err)
+		return subcommands.ExitFailure
+	}
+	fmt.Fprintf(ioHelper.Out, "Validated %d tables of %s: %d matched, %d mismatched, %d skipped, %d failed.\n",
+		report.Summary.Tables, dbURI, report.Summary.MatchedTables, report.Summary.MismatchedTables, report.Summary.SkippedTables, report.Summary.FailedTables)
+	fmt.Fprintf(ioHelper.Out, "Wrote data validation report to file '%s'.\n", reportFileName)
+	if report.Summary.MismatchedTables > 0 || report.Summary.FailedTables > 0 {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
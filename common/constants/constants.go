@@ -40,10 +40,29 @@ const (
 	// CSV is the driver name when loading data using csv.
 	CSV string = "csv"
 
+	// PARQUET is the driver name when loading data using Parquet files.
+	PARQUET string = "parquet"
+
+	// AVRO is the driver name when loading data using Avro object
+	// container files.
+	AVRO string = "avro"
+
 	// ORACLE is the driver name for Oracle.
 	// This is an experimental driver; implementation in progress.
 	ORACLE string = "oracle"
 
+	// ORACLEDUMP is the driver name for Oracle DDL scripts, e.g. the output
+	// of DBMS_METADATA.GET_DDL or a Data Pump SQLFILE.
+	ORACLEDUMP string = "oracle_dump"
+
+	// SQLSERVERDUMP is the driver name for SQL Server T-SQL scripts, e.g.
+	// the output of SSMS "Generate Scripts".
+	SQLSERVERDUMP string = "sqlserver_dump"
+
+	// SPANNER is the driver name for an existing Spanner database, e.g. the
+	// source of a dialect conversion.
+	SPANNER string = "spanner"
+
 	// Target db for which schema is being generated.
 	// This can be removed once the support for global flags is removed.
 	TargetSpanner              string = "spanner"
@@ -89,6 +108,7 @@ const (
 	METADATA_DB string = "spannermigrationtool_metadata"
 	// Migration types
 	MINIMAL_DOWNTIME_MIGRATION = "minimal_downtime"
+	REVERSE_REPLICATION        = "reverse_replication"
 	// Job Resource Types
 	DATAFLOW_RESOURCE         string = "dataflow"
 	PUBSUB_RESOURCE           string = "pubsub"
@@ -123,11 +143,12 @@ const (
 	DLQ_GCS     string = "dlq"
 
 	// VerifyExpresions API
-	CHECK_EXPRESSION   = "CHECK"
-	DEFAULT_EXPRESSION = "DEFAULT"
-	DEFAULT_GENERATED  = "DEFAULT_GENERATED"
-	TEMP_DB            = "smt-staging-db"
-	DB_URI             = "projects/%s/instances/%s/databases/%s"
+	CHECK_EXPRESSION     = "CHECK"
+	DEFAULT_EXPRESSION   = "DEFAULT"
+	GENERATED_EXPRESSION = "GENERATED"
+	DEFAULT_GENERATED    = "DEFAULT_GENERATED"
+	TEMP_DB              = "smt-staging-db"
+	DB_URI               = "projects/%s/instances/%s/databases/%s"
 
 	// Regex for matching database collation
 	DB_COLLATION_REGEX = `(_[a-zA-Z0-9]+\\|\\)`
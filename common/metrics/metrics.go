@@ -105,6 +105,10 @@ func getMigrationDataSourceDetails(driver string, migrationData *migration.Migra
 		return migration.MigrationData_DB_DUMP.Enum(), migration.MigrationData_POSTGRESQL.Enum()
 	case constants.MYSQLDUMP:
 		return migration.MigrationData_DB_DUMP.Enum(), migration.MigrationData_MYSQL.Enum()
+	case constants.ORACLEDUMP:
+		return migration.MigrationData_DB_DUMP.Enum(), migration.MigrationData_ORACLE.Enum()
+	case constants.SQLSERVERDUMP:
+		return migration.MigrationData_DB_DUMP.Enum(), migration.MigrationData_SQL_SERVER.Enum()
 	case constants.POSTGRES:
 		return migration.MigrationData_DIRECT_CONNECTION.Enum(), migration.MigrationData_POSTGRESQL.Enum()
 	case constants.MYSQL:
@@ -117,6 +121,8 @@ func getMigrationDataSourceDetails(driver string, migrationData *migration.Migra
 		return migration.MigrationData_DIRECT_CONNECTION.Enum(), migration.MigrationData_SQL_SERVER.Enum()
 	case constants.CSV:
 		return migration.MigrationData_FILE.Enum(), migration.MigrationData_CSV.Enum()
+	case constants.PARQUET, constants.AVRO:
+		return migration.MigrationData_FILE.Enum(), migration.MigrationData_SOURCE_UNSPECIFIED.Enum()
 	default:
 		return migration.MigrationData_SOURCE_CONNECTION_MECHANISM_UNSPECIFIED.Enum(), migration.MigrationData_SOURCE_UNSPECIFIED.Enum()
 	}
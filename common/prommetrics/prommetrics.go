@@ -0,0 +1,207 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prommetrics exposes the progress of bulk migrations as Prometheus
+// metrics on an HTTP /metrics endpoint.
+// prommetrics should not import any Spanner migration tool packages, since
+// it is called from the lowest levels of the data path.
+package prommetrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "smt"
+
+// Phases of a migration reported by ObservePhase.
+const (
+	PhaseSchemaConversion = "schema_conversion" // Reading the source schema and mapping it to Spanner.
+	PhaseSchemaCreation   = "schema_creation"   // Creating or updating the Spanner database.
+	PhaseData             = "data"              // Converting and writing data.
+	PhaseIndexes          = "indexes"           // Creating secondary indexes deferred until after the data migration.
+	PhaseForeignKeys      = "foreign_keys"      // Creating foreign keys after the data migration.
+)
+
+var (
+	// enabled is set once the endpoint is started. Metrics are only
+	// recorded when enabled, so that the data path pays nothing otherwise.
+	enabled atomic.Bool
+
+	registry = prometheus.NewRegistry()
+
+	rowsRead = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_read_total",
+		Help:      "Rows read from the source database, by source table.",
+	}, []string{"table"})
+	rowsConverted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_converted_total",
+		Help:      "Rows converted to Spanner values and queued for writing, by source table.",
+	}, []string{"table"})
+	rowsBad = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_bad_total",
+		Help:      "Rows that couldn't be converted, by source table.",
+	}, []string{"table"})
+	rowsFiltered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_filtered_total",
+		Help:      "Rows skipped by row filters, by source table.",
+	}, []string{"table"})
+	rowsWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_written_total",
+		Help:      "Rows durably written to Spanner, by Spanner table.",
+	}, []string{"table"})
+	rowsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rows_dropped_total",
+		Help:      "Rows that couldn't be written to Spanner, by Spanner table.",
+	}, []string{"table"})
+	batchLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "batch_write_duration_seconds",
+		Help:      "Latency of writes of batches of rows to Spanner, by result (ok or error).",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14), // 10ms to ~80s.
+	}, []string{"result"})
+	batchRows = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "batch_write_rows",
+		Help:      "Rows per batch written to Spanner.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 9), // 1 to 65536.
+	})
+	retries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "batch_write_retries_total",
+		Help:      "Batches retried after Spanner returned an error.",
+	})
+	phaseDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "phase_duration_seconds",
+		Help:      "Duration of the completed phases of the migration.",
+	}, []string{"phase"})
+)
+
+func init() {
+	registry.MustRegister(rowsRead, rowsConverted, rowsBad, rowsFiltered, rowsWritten, rowsDropped,
+		batchLatency, batchRows, retries, phaseDuration,
+		collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+// Serve starts an HTTP server exposing the metrics on /metrics at addr
+// (e.g. ":9090") and enables recording of metrics. It returns the address
+// the server listens on.
+func Serve(addr string) (net.Addr, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen on %s for the metrics endpoint: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	go http.Serve(l, mux)
+	enabled.Store(true)
+	return l.Addr(), nil
+}
+
+// Handler returns the HTTP handler serving the metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Enabled reports whether metrics are being recorded.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// RowConverted records a source row that was converted to Spanner values.
+func RowConverted(srcTable string) {
+	if enabled.Load() {
+		rowsRead.WithLabelValues(srcTable).Inc()
+		rowsConverted.WithLabelValues(srcTable).Inc()
+	}
+}
+
+// RowBad records a source row that couldn't be converted.
+func RowBad(srcTable string) {
+	if enabled.Load() {
+		rowsRead.WithLabelValues(srcTable).Inc()
+		rowsBad.WithLabelValues(srcTable).Inc()
+	}
+}
+
+// RowFiltered records a source row that was skipped by a row filter.
+func RowFiltered(srcTable string) {
+	if enabled.Load() {
+		rowsRead.WithLabelValues(srcTable).Inc()
+		rowsFiltered.WithLabelValues(srcTable).Inc()
+	}
+}
+
+// RowsFiltered records n source rows that were skipped by a row filter
+// applied in the source database, and so were never read.
+func RowsFiltered(srcTable string, n int64) {
+	if enabled.Load() {
+		rowsFiltered.WithLabelValues(srcTable).Add(float64(n))
+	}
+}
+
+// RowsWritten records n rows of a Spanner table written to Spanner.
+func RowsWritten(spTable string, n int) {
+	if enabled.Load() {
+		rowsWritten.WithLabelValues(spTable).Add(float64(n))
+	}
+}
+
+// RowsDropped records n rows of a Spanner table that couldn't be written.
+func RowsDropped(spTable string, n int) {
+	if enabled.Load() {
+		rowsDropped.WithLabelValues(spTable).Add(float64(n))
+	}
+}
+
+// ObserveBatch records the outcome of a write of a batch of rows to Spanner.
+func ObserveBatch(rows int, latency time.Duration, err error) {
+	if !enabled.Load() {
+		return
+	}
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	batchLatency.WithLabelValues(result).Observe(latency.Seconds())
+	batchRows.Observe(float64(rows))
+}
+
+// Retry records a retried batch.
+func Retry() {
+	if enabled.Load() {
+		retries.Inc()
+	}
+}
+
+// ObservePhase records the duration of a completed phase of the migration.
+func ObservePhase(phase string, d time.Duration) {
+	if enabled.Load() {
+		phaseDuration.WithLabelValues(phase).Set(d.Seconds())
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prommetrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServe(t *testing.T) {
+	// Nothing is recorded until the endpoint is started.
+	RowConverted("ignored")
+	assert.False(t, Enabled())
+
+	addr, err := Serve("127.0.0.1:0")
+	assert.Nil(t, err)
+	assert.True(t, Enabled())
+	RowConverted("users")
+	RowConverted("users")
+	RowBad("users")
+	RowFiltered("albums")
+	RowsFiltered("albums", 4)
+	RowsWritten("Users", 2)
+	RowsDropped("Users", 1)
+	ObserveBatch(3, 50*time.Millisecond, nil)
+	ObserveBatch(1, 20*time.Millisecond, errors.New("already exists"))
+	Retry()
+	ObservePhase(PhaseSchemaCreation, 90*time.Second)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/metrics", addr))
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	body := string(b)
+	for _, line := range []string{
+		`smt_rows_read_total{table="users"} 3`,
+		`smt_rows_read_total{table="albums"} 1`,
+		`smt_rows_converted_total{table="users"} 2`,
+		`smt_rows_bad_total{table="users"} 1`,
+		`smt_rows_filtered_total{table="albums"} 5`,
+		`smt_rows_written_total{table="Users"} 2`,
+		`smt_rows_dropped_total{table="Users"} 1`,
+		`smt_batch_write_duration_seconds_count{result="ok"} 1`,
+		`smt_batch_write_duration_seconds_count{result="error"} 1`,
+		`smt_batch_write_rows_sum 4`,
+		`smt_batch_write_retries_total 1`,
+		`smt_phase_duration_seconds{phase="schema_creation"} 90`,
+	} {
+		assert.Contains(t, body, line)
+	}
+	assert.NotContains(t, body, "ignored")
+
+	_, err = Serve(addr.String())
+	assert.NotNil(t, err, "address is already in use")
+}
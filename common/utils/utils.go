@@ -65,6 +65,7 @@ type IOStreams struct {
 type ManifestTable struct {
 	Table_name    string   `json:"table_name"`
 	File_patterns []string `json:"file_patterns"`
+	Row_filter    string   `json:"row_filter,omitempty"`
 }
 
 // Interface to fetch spanner details
@@ -78,7 +79,8 @@ type GetUtilInfoInterface interface {
 type GetUtilInfoImpl struct{}
 
 // NewIOStreams returns a new IOStreams struct such that input stream is set
-// to open file descriptor for dumpFile if driver is PGDUMP or MYSQLDUMP.
+// to open file descriptor for dumpFile if driver is a dump driver, e.g.
+// PGDUMP or MYSQLDUMP.
 // Input stream defaults to stdin. Output stream is always set to stdout.
 func NewIOStreams(driver string, dumpFile string) IOStreams {
 	io := IOStreams{In: os.Stdin, Out: os.Stdout}
@@ -87,7 +89,8 @@ func NewIOStreams(driver string, dumpFile string) IOStreams {
 		fmt.Printf("parseFilePath: unable parse file path for dumpfile %s", dumpFile)
 		log.Fatal(err)
 	}
-	if (driver == constants.PGDUMP || driver == constants.MYSQLDUMP) && dumpFile != "" {
+	isDump := driver == constants.PGDUMP || driver == constants.MYSQLDUMP || driver == constants.ORACLEDUMP || driver == constants.SQLSERVERDUMP
+	if isDump && dumpFile != "" {
 		fmt.Printf("\nLoading dump file from path: %s\n", dumpFile)
 		var f *os.File
 		var err error
@@ -424,6 +427,8 @@ func GetValidDrivers() []string {
 		constants.DYNAMODB,
 
 		constants.SQLSERVER,
+		constants.ORACLEDUMP,
+		constants.SQLSERVERDUMP,
 	}
 }
 
@@ -446,8 +451,8 @@ func GetLegacyModeSupportedDrivers() []string {
 func ReadSpannerSchema(ctx context.Context, conv *internal.Conv, client *sp.Client) error {
 	infoSchema := spanner.InfoSchemaImpl{Client: client, Ctx: ctx, SpDialect: conv.SpDialect}
 	processSchema := common.ProcessSchemaImpl{}
-	expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessorImpl(ctx, conv.SpProjectId, conv.SpInstanceId)
-	ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, conv.SpProjectId, conv.SpInstanceId)
+	expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessor(ctx, conv.SpProjectId, conv.SpInstanceId, false)
+	ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, conv.SpProjectId, conv.SpInstanceId, false)
 	if err != nil {
 		return fmt.Errorf("error trying create ddl verifier: %v", err)
 	}
@@ -477,6 +482,17 @@ func ReadSpannerSchema(ctx context.Context, conv *internal.Conv, client *sp.Clie
 	return nil
 }
 
+// ReadSpannerSequences fills the sequences of conv by querying Spanner infoschema.
+func ReadSpannerSequences(ctx context.Context, conv *internal.Conv, client *sp.Client) error {
+	infoSchema := spanner.InfoSchemaImpl{Client: client, Ctx: ctx, SpDialect: conv.SpDialect}
+	sequences, err := infoSchema.GetSequences()
+	if err != nil {
+		return fmt.Errorf("error trying to read spanner sequences: %v", err)
+	}
+	conv.SpSequences = sequences
+	return nil
+}
+
 // CompareSchema compares the spanner schema of two conv objects and returns specific error if they don't match
 func CompareSchema(sessionFileConv, actualSpannerConv *internal.Conv) error {
 	if sessionFileConv.SpDialect != actualSpannerConv.SpDialect {
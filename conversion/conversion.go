@@ -43,6 +43,7 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/csv"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/datafile"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
 )
 
@@ -79,9 +80,15 @@ func (ci *ConvImpl) SchemaConv(migrationProjectId string, sourceProfile profiles
 	switch sourceProfile.Driver {
 	case constants.POSTGRES, constants.MYSQL, constants.DYNAMODB, constants.SQLSERVER, constants.ORACLE:
 		return schemaFromSource.schemaFromDatabase(migrationProjectId, sourceProfile, targetProfile, &GetInfoImpl{}, &common.ProcessSchemaImpl{})
-	case constants.PGDUMP, constants.MYSQLDUMP:
-		expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessorImpl(context.Background(), targetProfile.Conn.Sp.Project, targetProfile.Conn.Sp.Instance)
+	case constants.PGDUMP, constants.MYSQLDUMP, constants.ORACLEDUMP, constants.SQLSERVERDUMP:
+		offline := false
+		if sfs, ok := schemaFromSource.(*SchemaFromSourceImpl); ok {
+			offline = sfs.OfflineVerification
+		}
+		expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessor(context.Background(), targetProfile.Conn.Sp.Project, targetProfile.Conn.Sp.Instance, offline)
 		return schemaFromSource.SchemaFromDump(targetProfile.Conn.Sp.Project, targetProfile.Conn.Sp.Instance, sourceProfile.Driver, targetProfile.Conn.Sp.Dialect, ioHelper, &ProcessDumpByDialectImpl{ExpressionVerificationAccessor: expressionVerificationAccessor})
+	case constants.PARQUET, constants.AVRO:
+		return schemaFromSource.schemaFromDataFile(sourceProfile, targetProfile, &datafile.DataFileImpl{Format: sourceProfile.Driver})
 	default:
 		return nil, fmt.Errorf("schema conversion for driver %s not supported", sourceProfile.Driver)
 	}
@@ -96,6 +103,7 @@ func (ci *ConvImpl) DataConv(ctx context.Context, migrationProjectId string, sou
 		RetryLimit: 1000,
 		Verbose:    internal.Verbose(),
 	}
+	applyWriteSettings(ctx, &config, conv, targetProfile.Conn.Sp.Project, targetProfile.Conn.Sp.Instance)
 	switch sourceProfile.Driver {
 	case constants.POSTGRES, constants.MYSQL, constants.DYNAMODB, constants.SQLSERVER, constants.ORACLE:
 		return dataFromSource.dataFromDatabase(ctx, migrationProjectId, sourceProfile, targetProfile, config, conv, client, &GetInfoImpl{}, &DataFromDatabaseImpl{}, &SnapshotMigrationImpl{})
@@ -106,6 +114,8 @@ func (ci *ConvImpl) DataConv(ctx context.Context, migrationProjectId string, sou
 		return dataFromSource.dataFromDump(sourceProfile.Driver, config, ioHelper, client, conv, dataOnly, &ProcessDumpByDialectImpl{}, &PopulateDataConvImpl{})
 	case constants.CSV:
 		return dataFromSource.dataFromCSV(ctx, sourceProfile, targetProfile, config, conv, client, &PopulateDataConvImpl{}, &csv.CsvImpl{})
+	case constants.PARQUET, constants.AVRO:
+		return dataFromSource.dataFromDataFile(ctx, sourceProfile, targetProfile, config, conv, client, &PopulateDataConvImpl{}, &datafile.DataFileImpl{Format: sourceProfile.Driver})
 	default:
 		return nil, fmt.Errorf("data conversion for driver %s not supported", sourceProfile.Driver)
 	}
@@ -32,6 +32,7 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/csv"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/datafile"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/streaming"
 	"go.uber.org/zap"
@@ -40,16 +41,19 @@ import (
 type SchemaFromSourceInterface interface {
 	schemaFromDatabase(migrationProjectId string, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, getInfo GetInfoInterface, processSchema common.ProcessSchemaInterface) (*internal.Conv, error)
 	SchemaFromDump(SpProjectId string, SpInstanceId string, driver string, spDialect string, ioHelper *utils.IOStreams, processDump ProcessDumpByDialectInterface) (*internal.Conv, error)
+	schemaFromDataFile(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, dataFile datafile.DataFileInterface) (*internal.Conv, error)
 }
 
 type SchemaFromSourceImpl struct {
-	DdlVerifier expressions_api.DDLVerifier
+	DdlVerifier         expressions_api.DDLVerifier
+	OfflineVerification bool // Verify expressions locally instead of against Spanner.
 }
 
 type DataFromSourceInterface interface {
 	dataFromDatabase(ctx context.Context, migrationProjectId string, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, getInfo GetInfoInterface, dataFromDb DataFromDatabaseInterface, snapshotMigration SnapshotMigrationInterface) (*writer.BatchWriter, error)
 	dataFromDump(driver string, config writer.BatchWriterConfig, ioHelper *utils.IOStreams, client *sp.Client, conv *internal.Conv, dataOnly bool, processDump ProcessDumpByDialectInterface, populateDataConv PopulateDataConvInterface) (*writer.BatchWriter, error)
 	dataFromCSV(ctx context.Context, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, populateDataConv PopulateDataConvInterface, csv csv.CsvInterface) (*writer.BatchWriter, error)
+	dataFromDataFile(ctx context.Context, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, populateDataConv PopulateDataConvInterface, dataFile datafile.DataFileInterface) (*writer.BatchWriter, error)
 }
 
 type DataFromSourceImpl struct{}
@@ -104,7 +108,7 @@ func (sads *SchemaFromSourceImpl) schemaFromDatabase(migrationProjectId string,
 	}
 
 	ctx := context.Background()
-	expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessorImpl(ctx, conv.SpProjectId, conv.SpInstanceId)
+	expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessor(ctx, conv.SpProjectId, conv.SpInstanceId, sads.OfflineVerification)
 	schemaToSpanner := common.SchemaToSpannerImpl{
 		DdlV:                           sads.DdlVerifier,
 		ExpressionVerificationAccessor: expressionVerificationAccessor,
@@ -127,6 +131,7 @@ func (sads *SchemaFromSourceImpl) SchemaFromDump(SpProjectId string, SpInstanceI
 	conv.SpInstanceId = SpInstanceId
 	p := internal.NewProgress(n, "Generating schema", internal.Verbose(), false, int(internal.SchemaCreationInProgress))
 	r := internal.NewReader(bufio.NewReader(f), p)
+	r.Path = f.Name()
 	conv.SetSchemaMode() // Build schema and ignore data in dump.
 	conv.SetDataSink(nil)
 	err = processDump.ProcessDump(driver, conv, r)
@@ -138,6 +143,31 @@ func (sads *SchemaFromSourceImpl) SchemaFromDump(SpProjectId string, SpInstanceI
 	return conv, nil
 }
 
+// schemaFromDataFile infers the schema from the Parquet or Avro files listed
+// in the manifest of the source profile and converts it to Spanner.
+func (sads *SchemaFromSourceImpl) schemaFromDataFile(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, dataFile datafile.DataFileInterface) (*internal.Conv, error) {
+	conv := internal.MakeConv()
+	conv.SpDialect = targetProfile.Conn.Sp.Dialect
+	conv.SpProjectId = targetProfile.Conn.Sp.Project
+	conv.SpInstanceId = targetProfile.Conn.Sp.Instance
+	conv.Source = sourceProfile.Driver
+	conv.SetSchemaMode()
+	tables, err := dataFile.GetFiles(conv, sourceProfile)
+	if err != nil {
+		return nil, fmt.Errorf("error finding %s files: %v", sourceProfile.Driver, err)
+	}
+	err = dataFile.SchemaFromFiles(conv, tables)
+	if err != nil {
+		return nil, err
+	}
+	expressionVerificationAccessor, _ := expressions_api.NewExpressionVerificationAccessor(context.Background(), conv.SpProjectId, conv.SpInstanceId, sads.OfflineVerification)
+	schemaToSpanner := common.SchemaToSpannerImpl{
+		DdlV:                           sads.DdlVerifier,
+		ExpressionVerificationAccessor: expressionVerificationAccessor,
+	}
+	return conv, schemaToSpanner.SchemaToSpannerDDL(conv, datafile.ToDdlImpl{}, internal.AdditionalSchemaAttributes{})
+}
+
 func (sads *DataFromSourceImpl) dataFromDump(driver string, config writer.BatchWriterConfig, ioHelper *utils.IOStreams, client *sp.Client, conv *internal.Conv, dataOnly bool, processDump ProcessDumpByDialectInterface, populateDataConv PopulateDataConvInterface) (*writer.BatchWriter, error) {
 	// TODO: refactor of the way we handle getSeekable
 	// to avoid the code duplication here
@@ -162,6 +192,7 @@ func (sads *DataFromSourceImpl) dataFromDump(driver string, config writer.BatchW
 
 	conv.Audit.Progress = *internal.NewProgress(totalRows, "Writing data to Spanner", internal.Verbose(), false, int(internal.DataWriteInProgress))
 	r := internal.NewReader(bufio.NewReader(ioHelper.SeekableIn), nil)
+	r.Path = ioHelper.SeekableIn.Name()
 	batchWriter := populateDataConv.populateDataConv(conv, config, client)
 	processDump.ProcessDump(driver, conv, r)
 	batchWriter.Flush()
@@ -225,6 +256,47 @@ func (sads *DataFromSourceImpl) dataFromCSV(ctx context.Context, sourceProfile p
 	return batchWriter, nil
 }
 
+// dataFromDataFile writes the rows of Parquet or Avro files. The schema is
+// either the one converted from the files by schemaFromDataFile, or when only
+// migrating data, the schema of the existing Spanner database.
+func (sads *DataFromSourceImpl) dataFromDataFile(ctx context.Context, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, populateDataConv PopulateDataConvInterface, dataFile datafile.DataFileInterface) (*writer.BatchWriter, error) {
+	if len(conv.SpSchema) == 0 {
+		if targetProfile.Conn.Sp.Dbname == "" {
+			return nil, fmt.Errorf("dbName is mandatory in target-profile for %s source", sourceProfile.Driver)
+		}
+		conv.SpDialect = targetProfile.Conn.Sp.Dialect
+		conv.SpProjectId = targetProfile.Conn.Sp.Project
+		conv.SpInstanceId = targetProfile.Conn.Sp.Instance
+		conv.Source = sourceProfile.Driver
+		err := utils.ReadSpannerSchema(ctx, conv, client)
+		if err != nil {
+			return nil, fmt.Errorf("error trying to read and convert spanner schema: %v", err)
+		}
+		if len(conv.SpSchema) == 0 {
+			return nil, fmt.Errorf("database %s has no tables: use the schema-and-data subcommand to create them from the schema of the %s files", targetProfile.Conn.Sp.Dbname, sourceProfile.Driver)
+		}
+	}
+	tables, err := dataFile.GetFiles(conv, sourceProfile)
+	if err != nil {
+		return nil, fmt.Errorf("error finding %s files: %v", sourceProfile.Driver, err)
+	}
+	err = dataFile.SetRowStats(conv, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	totalRows := conv.Rows()
+	conv.Audit.Progress = *internal.NewProgress(totalRows, "Writing data to Spanner", internal.Verbose(), false, int(internal.DataWriteInProgress))
+	batchWriter := populateDataConv.populateDataConv(conv, config, client)
+	err = dataFile.ProcessFiles(conv, tables)
+	if err != nil {
+		return nil, fmt.Errorf("can't process %s files: %v", sourceProfile.Driver, err)
+	}
+	batchWriter.Flush()
+	conv.Audit.Progress.Done()
+	return batchWriter, nil
+}
+
 func (sads *DataFromSourceImpl) dataFromDatabase(ctx context.Context, migrationProjectId string, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, getInfo GetInfoInterface, dataFromDb DataFromDatabaseInterface, snapshotMigration SnapshotMigrationInterface) (*writer.BatchWriter, error) {
 	//handle migrating data for sharded migrations differently
 	//sharded migrations are identified via the config= flag, if that flag is not present
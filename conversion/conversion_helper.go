@@ -35,9 +35,12 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/mysql"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/oracle"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/postgres"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/sqlserver"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
 	"github.com/aws/aws-sdk-go/aws"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/proto"
 )
@@ -96,6 +99,10 @@ func (pdd *ProcessDumpByDialectImpl) ProcessDump(driver string, conv *internal.C
 		return common.ProcessDbDump(conv, r, mysql.DbDumpImpl{}, pdd.DdlVerifier, pdd.ExpressionVerificationAccessor)
 	case constants.PGDUMP:
 		return common.ProcessDbDump(conv, r, postgres.DbDumpImpl{}, pdd.DdlVerifier, pdd.ExpressionVerificationAccessor)
+	case constants.ORACLEDUMP:
+		return common.ProcessDbDump(conv, r, oracle.DbDumpImpl{}, pdd.DdlVerifier, pdd.ExpressionVerificationAccessor)
+	case constants.SQLSERVERDUMP:
+		return common.ProcessDbDump(conv, r, sqlserver.DbDumpImpl{}, pdd.DdlVerifier, pdd.ExpressionVerificationAccessor)
 	default:
 		return fmt.Errorf("process dump for driver %s not supported", driver)
 	}
@@ -103,6 +110,7 @@ func (pdd *ProcessDumpByDialectImpl) ProcessDump(driver string, conv *internal.C
 
 func (pdc *PopulateDataConvImpl) populateDataConv(conv *internal.Conv, config writer.BatchWriterConfig, client *sp.Client) *writer.BatchWriter {
 	rows := int64(0)
+	config.IndexedColumns = indexedColumns(conv)
 	config.Write = func(m []*sp.Mutation) error {
 		ctx := context.Background()
 		if !conv.Audit.SkipMetricsPopulation {
@@ -119,12 +127,34 @@ func (pdc *PopulateDataConvImpl) populateDataConv(conv *internal.Conv, config wr
 		conv.Audit.Progress.MaybeReport(atomic.LoadInt64(&rows))
 		return nil
 	}
+	if conv.Checkpoint != nil {
+		config.OnCommit = func(table string, n int64, cols []string, vals []interface{}) {
+			conv.Checkpoint.RecordCommit(conv, table, n, cols, vals)
+		}
+	}
+	config.OnDrop = func(table string, cols []string, vals []interface{}, source interface{}, err error) {
+		if isShardKeyCollision(conv, table, err) {
+			conv.RecordShardKeyDrop(table)
+		}
+		if conv.DeadLetter != nil {
+			conv.DeadLetter.Add(writeDeadLetter(table, cols, vals, source, err))
+		}
+	}
 	batchWriter := writer.NewBatchWriter(config)
+	if config.Adaptive || config.MaxRowsPerSecond > 0 {
+		conv.Audit.Progress.SetDetail(func() string {
+			return batchWriter.Stats().String()
+		})
+	}
 	conv.SetDataMode()
 	if !conv.Audit.DryRun {
-		conv.SetDataSink(
-			func(table string, cols []string, vals []interface{}) {
-				batchWriter.AddRow(table, cols, vals)
+		conv.SetDataSinkWithSource(
+			func(table string, cols []string, vals []interface{}, src *internal.SourceRow) {
+				if conv.DeadLetter != nil && src != nil {
+					batchWriter.AddRowWithSource(table, cols, vals, src)
+				} else {
+					batchWriter.AddRow(table, cols, vals)
+				}
 			})
 		conv.DataFlush = func() {
 			batchWriter.Flush()
@@ -134,6 +164,39 @@ func (pdc *PopulateDataConvImpl) populateDataConv(conv *internal.Conv, config wr
 	return batchWriter
 }
 
+// isShardKeyCollision reports whether a row of Spanner table spTable was
+// rejected because another shard of a sharded migration had already written
+// a row with the same key, i.e. the table has a shard id column that isn't
+// part of its primary key and the row already exists.
+func isShardKeyCollision(conv *internal.Conv, spTable string, err error) bool {
+	if sp.ErrCode(err) != codes.AlreadyExists {
+		return false
+	}
+	tableId, lookupErr := internal.GetTableIdFromSpName(conv.SpSchema, spTable)
+	if lookupErr != nil {
+		return false
+	}
+	table := conv.SpSchema[tableId]
+	return table.ShardIdColumn != "" && !internal.ShardIdInPrimaryKey(table)
+}
+
+// writeDeadLetter returns the dead-letter record for a row that couldn't be
+// written to Spanner. The source row is recorded if known, so that the row
+// can be replayed through the conversion; otherwise we fall back to the
+// Spanner row.
+func writeDeadLetter(table string, cols []string, vals []interface{}, source interface{}, err error) internal.DeadLetterRecord {
+	r := internal.DeadLetterRecord{Error: err.Error(), Stage: internal.DeadLetterWrite}
+	if src, ok := source.(*internal.SourceRow); ok && src != nil {
+		r.SourceRow = *src
+		return r
+	}
+	r.Table, r.Cols = table, cols
+	for _, v := range vals {
+		r.Vals = append(r.Vals, fmt.Sprint(v))
+	}
+	return r
+}
+
 func connectionConfig(sourceProfile profiles.SourceProfile) (interface{}, error) {
 	switch sourceProfile.Driver {
 	// For PG and MYSQL, When called as part of the subcommand flow, host/user/db etc will
@@ -0,0 +1,52 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"context"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
+)
+
+// CopyDialectData copies the rows of the tables of srcSchema, read from a
+// Spanner database of dialect srcDialect using srcClient, to the tables of
+// conv.SpSchema with the same ids, written using client. Parent tables are
+// copied and flushed before their interleaved children.
+func CopyDialectData(ctx context.Context, conv *internal.Conv, srcSchema ddl.Schema, srcDialect string, srcClient *sp.Client, config writer.BatchWriterConfig, client *sp.Client, pdc PopulateDataConvInterface) (*writer.BatchWriter, error) {
+	tableIds := ddl.GetSortedTableIdsBySpName(srcSchema)
+	var total int64
+	for _, tableId := range tableIds {
+		n, err := spanner.CountRows(ctx, srcClient, srcSchema[tableId].Name, srcDialect)
+		if err != nil {
+			return nil, err
+		}
+		conv.Stats.Rows[srcSchema[tableId].Name] += n
+		total += n
+	}
+	conv.Audit.Progress = *internal.NewProgress(total, "Writing data to Spanner", internal.Verbose(), false, int(internal.DataWriteInProgress))
+	batchWriter := pdc.populateDataConv(conv, config, client)
+	for _, tableId := range tableIds {
+		if err := spanner.CopyRows(ctx, conv, srcClient, srcSchema[tableId], conv.SpSchema[tableId]); err != nil {
+			return nil, err
+		}
+		batchWriter.Flush()
+	}
+	conv.Audit.Progress.Done()
+	return batchWriter, nil
+}
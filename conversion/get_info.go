@@ -182,6 +182,7 @@ func (gi *GetInfoImpl) GetInfoSchema(migrationProjectId string, sourceProfile pr
 			DynamoClient:        dydbClient,
 			SampleSize:          profiles.GetSchemaSampleSize(sourceProfile),
 			DynamoStreamsClient: dydbStreamsClient,
+			NormalizeNested:     sourceProfile.Conn.Dydb.NormalizeNested,
 		}, nil
 	case constants.SQLSERVER:
 		db, err := sql.Open(driver, connectionConfig.(string))
@@ -189,7 +190,7 @@ func (gi *GetInfoImpl) GetInfoSchema(migrationProjectId string, sourceProfile pr
 		if err != nil {
 			return nil, err
 		}
-		return sqlserver.InfoSchemaImpl{DbName: dbName, Db: db}, nil
+		return sqlserver.InfoSchemaImpl{DbName: dbName, Db: db, SourceProfile: sourceProfile}, nil
 	case constants.ORACLE:
 		db, err := sql.Open(driver, connectionConfig.(string))
 		dbName := getDbNameFromSQLConnectionStr(driver, connectionConfig.(string))
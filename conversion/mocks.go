@@ -25,6 +25,7 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/csv"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/datafile"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
 	"github.com/stretchr/testify/mock"
 )
@@ -58,6 +59,10 @@ func (msads *MockSchemaFromSource) SchemaFromDump(SpProjectId string, SpInstance
 	args := msads.Called(driver, spDialect, ioHelper, processDump)
 	return args.Get(0).(*internal.Conv), args.Error(1)
 }
+func (msads *MockSchemaFromSource) schemaFromDataFile(sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, dataFile datafile.DataFileInterface) (*internal.Conv, error) {
+	args := msads.Called(sourceProfile, targetProfile, dataFile)
+	return args.Get(0).(*internal.Conv), args.Error(1)
+}
 
 type MockDataFromSource struct {
 	mock.Mock
@@ -75,6 +80,10 @@ func (msads *MockDataFromSource) dataFromCSV(ctx context.Context, sourceProfile
 	args := msads.Called(ctx, sourceProfile, targetProfile, config, conv, client, pdc, csv)
 	return args.Get(0).(*writer.BatchWriter), args.Error(1)
 }
+func (msads *MockDataFromSource) dataFromDataFile(ctx context.Context, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, config writer.BatchWriterConfig, conv *internal.Conv, client *sp.Client, pdc PopulateDataConvInterface, dataFile datafile.DataFileInterface) (*writer.BatchWriter, error) {
+	args := msads.Called(ctx, sourceProfile, targetProfile, config, conv, client, pdc, dataFile)
+	return args.Get(0).(*writer.BatchWriter), args.Error(1)
+}
 
 type MockValidateOrCreateResources struct {
 	mock.Mock
@@ -0,0 +1,118 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"fmt"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/mysql"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/oracle"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/postgres"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/sqlserver"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
+)
+
+// ReplayDeadLetters reapplies the conversion of conv (typically loaded from
+// a session file) to the rows of a dead-letter file, and writes them to
+// Spanner. Rows that still fail are added to conv.DeadLetter, if set.
+// Replay is supported for the sources whose rows are converted from string
+// values, i.e. all database and dump file sources except DynamoDB.
+func ReplayDeadLetters(conv *internal.Conv, records []internal.DeadLetterRecord, config writer.BatchWriterConfig, client *sp.Client, pdc PopulateDataConvInterface) (*writer.BatchWriter, error) {
+	processRow, err := replayFunc(conv.Source)
+	if err != nil {
+		return nil, err
+	}
+	conv.Audit.Progress = *internal.NewProgress(int64(len(records)), "Replaying rows to Spanner", internal.Verbose(), false, int(internal.DataWriteInProgress))
+	batchWriter := pdc.populateDataConv(conv, config, client)
+	for _, r := range records {
+		tableId, srcCols, err := replayColumns(conv, r)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't replay row: %s", err))
+			conv.StatsAddBadRow(r.Table, conv.DataMode())
+			conv.CollectBadRow(r.Table, r.Cols, r.Vals, err)
+			continue
+		}
+		srcTable := conv.SrcSchema[tableId]
+		commonColIds, err := common.PrepareColumns(conv, tableId, srcCols)
+		if err == nil {
+			var vals []string
+			vals, err = common.PrepareValues(conv, tableId, internal.GetSrcColNameIdMap(srcTable), commonColIds, srcCols, r.Vals)
+			if err == nil {
+				processRow(conv, tableId, commonColIds, vals)
+				continue
+			}
+		}
+		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+		conv.StatsAddBadRow(srcTable.Name, conv.DataMode())
+		conv.CollectBadRow(srcTable.Name, srcCols, r.Vals, err)
+	}
+	batchWriter.Flush()
+	conv.Audit.Progress.Done()
+	return batchWriter, nil
+}
+
+// replayFunc returns the function that converts and writes a row of the
+// given source driver.
+func replayFunc(driver string) (func(conv *internal.Conv, tableId string, colIds, vals []string), error) {
+	switch driver {
+	case constants.MYSQL, constants.MYSQLDUMP:
+		return func(conv *internal.Conv, tableId string, colIds, vals []string) {
+			mysql.ProcessDataRow(conv, tableId, colIds, conv.SrcSchema[tableId], conv.SpSchema[tableId], vals, internal.AdditionalDataAttributes{ShardId: ""})
+		}, nil
+	case constants.POSTGRES, constants.PGDUMP:
+		return postgres.ProcessDataRow, nil
+	case constants.SQLSERVER:
+		return func(conv *internal.Conv, tableId string, colIds, vals []string) {
+			sqlserver.ProcessDataRow(conv, tableId, colIds, conv.SrcSchema[tableId], conv.SpSchema[tableId], vals)
+		}, nil
+	case constants.ORACLE:
+		return func(conv *internal.Conv, tableId string, colIds, vals []string) {
+			oracle.ProcessDataRow(conv, tableId, colIds, conv.SrcSchema[tableId], conv.SpSchema[tableId], vals)
+		}, nil
+	default:
+		return nil, fmt.Errorf("replaying dead-letter rows is not supported for driver %s", driver)
+	}
+}
+
+// replayColumns returns the table id and source column names of a
+// dead-letter record. Records normally hold the source row; rows dropped
+// without a known source row hold the Spanner row instead, whose names are
+// mapped back to the source schema.
+func replayColumns(conv *internal.Conv, r internal.DeadLetterRecord) (string, []string, error) {
+	if tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, r.Table); err == nil {
+		return tableId, r.Cols, nil
+	}
+	tableId, err := internal.GetTableIdFromSpName(conv.SpSchema, r.Table)
+	if err != nil {
+		return "", nil, fmt.Errorf("table %s not found in the session", r.Table)
+	}
+	var srcCols []string
+	for _, c := range r.Cols {
+		colId, err := internal.GetColIdFromSpName(conv.SpSchema[tableId].ColDefs, c)
+		if err != nil {
+			return "", nil, fmt.Errorf("column %s not found in table %s of the session", c, r.Table)
+		}
+		srcCol, ok := conv.SrcSchema[tableId].ColDefs[colId]
+		if !ok {
+			return "", nil, fmt.Errorf("column %s of table %s has no source column", c, r.Table)
+		}
+		srcCols = append(srcCols, srcCol.Name)
+	}
+	return tableId, srcCols, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"path/filepath"
+	"testing"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type replayRow struct {
+	table string
+	cols  []string
+	vals  []interface{}
+}
+
+// fakePopulateDataConv collects the rows written to Spanner.
+type fakePopulateDataConv struct {
+	rows []replayRow
+}
+
+func (f *fakePopulateDataConv) populateDataConv(conv *internal.Conv, config writer.BatchWriterConfig, client *sp.Client) *writer.BatchWriter {
+	conv.SetDataMode()
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		f.rows = append(f.rows, replayRow{table, cols, vals})
+	})
+	return writer.NewBatchWriter(config)
+}
+
+func replayTestConv() *internal.Conv {
+	conv := internal.MakeConv()
+	conv.Source = constants.POSTGRES
+	conv.SrcSchema = map[string]schema.Table{
+		"t1": {
+			Name:   "users",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2"},
+			ColDefs: map[string]schema.Column{
+				"c1": {Name: "id", Id: "c1", Type: schema.Type{Name: "bigint"}},
+				"c2": {Name: "name", Id: "c2", Type: schema.Type{Name: "text"}},
+			},
+		},
+	}
+	conv.SpSchema = ddl.Schema{
+		"t1": {
+			Name:   "Users",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "Id", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+				"c2": {Name: "Name", Id: "c2", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "c1", Order: 1}},
+		},
+	}
+	return conv
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+	logger.Log = zap.NewNop()
+	conv := replayTestConv()
+	path := filepath.Join(t.TempDir(), "retry.jsonl")
+	conv.DeadLetter = internal.NewDeadLetterQueue(path, false)
+	records := []internal.DeadLetterRecord{
+		{SourceRow: internal.SourceRow{Table: "users", Cols: []string{"name", "id"}, Vals: []string{"alice", "1"}}, Stage: internal.DeadLetterWrite},
+		{SourceRow: internal.SourceRow{Table: "users", Cols: []string{"id", "name"}, Vals: []string{"one", "bob"}}, Stage: internal.DeadLetterConversion},
+		// Spanner row of a dropped write.
+		{SourceRow: internal.SourceRow{Table: "Users", Cols: []string{"Id", "Name"}, Vals: []string{"3", "carol"}}, Stage: internal.DeadLetterWrite},
+		{SourceRow: internal.SourceRow{Table: "albums", Cols: []string{"id"}, Vals: []string{"4"}}, Stage: internal.DeadLetterConversion},
+	}
+	pdc := &fakePopulateDataConv{}
+	_, err := ReplayDeadLetters(conv, records, writer.BatchWriterConfig{}, nil, pdc)
+	assert.Nil(t, err)
+	assert.Equal(t, []replayRow{
+		{"Users", []string{"Name", "Id"}, []interface{}{"alice", int64(1)}},
+		{"Users", []string{"Id", "Name"}, []interface{}{int64(3), "carol"}},
+	}, pdc.rows)
+	assert.Nil(t, conv.DeadLetter.Close())
+
+	failed, err := internal.ReadDeadLetterFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(failed))
+	assert.Equal(t, "users", failed[0].Table)
+	assert.Equal(t, []string{"one", "bob"}, failed[0].Vals)
+	assert.Equal(t, internal.DeadLetterConversion, failed[0].Stage)
+	assert.Equal(t, "albums", failed[1].Table)
+	assert.Contains(t, failed[1].Error, "table albums not found")
+}
+
+func TestReplayDeadLettersUnsupportedDriver(t *testing.T) {
+	conv := replayTestConv()
+	conv.Source = constants.DYNAMODB
+	_, err := ReplayDeadLetters(conv, nil, writer.BatchWriterConfig{}, nil, &fakePopulateDataConv{})
+	assert.NotNil(t, err)
+}
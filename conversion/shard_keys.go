@@ -0,0 +1,102 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+)
+
+// AnalyzeShardKeys reads the primary keys of the tables of conv from every
+// shard of a sharded migration, and records the keys read from more than one
+// shard in conv.ShardKeyCollisions. At most sampleSize keys, lowest first, are
+// read per table and shard, unless sampleSize is 0. Tables whose primary key
+// includes the shard id column are skipped, since their keys can't collide.
+func AnalyzeShardKeys(migrationProjectId string, sourceProfile profiles.SourceProfile, targetProfile profiles.TargetProfile, conv *internal.Conv, sampleSize int64, gi GetInfoInterface) error {
+	shards, err := ShardConnections(sourceProfile)
+	if err != nil {
+		return err
+	}
+	var readers []common.PrimaryKeyReader
+	for _, shard := range shards {
+		infoSchema, err := gi.getInfoSchemaForShard(migrationProjectId, shard, sourceProfile.Driver, targetProfile, &profiles.SourceProfileDialectImpl{}, &GetInfoImpl{})
+		if err != nil {
+			return fmt.Errorf("can't connect to shard %s: %v", shard.DataShardId, err)
+		}
+		reader, ok := infoSchema.(common.PrimaryKeyReader)
+		if !ok {
+			return fmt.Errorf("shard key analysis isn't supported for source %s", sourceProfile.Driver)
+		}
+		readers = append(readers, reader)
+	}
+	var shardIds []string
+	for _, shard := range shards {
+		shardIds = append(shardIds, shard.DataShardId)
+	}
+	conv.ShardKeyCollisions = make(map[string]internal.ShardKeyCollisions)
+	// Tables are analyzed one at a time, so that only the keys of one table
+	// are held in memory.
+	for _, tableId := range internal.ShardKeyTables(conv) {
+		cols := internal.ShardKeyColumns(conv, tableId)
+		var keys [][][]string
+		for i, reader := range readers {
+			k, err := reader.ReadPrimaryKeys(conv, tableId, cols, sampleSize)
+			if err != nil {
+				return fmt.Errorf("can't read primary keys of table %s from shard %s: %v", conv.SrcSchema[tableId].Name, shardIds[i], err)
+			}
+			keys = append(keys, k)
+		}
+		conv.ShardKeyCollisions[tableId] = internal.FindShardKeyCollisions(shardIds, keys, sampleSize > 0)
+	}
+	return nil
+}
+
+// ShardConnections returns the connection details of every shard of a
+// sharded bulk or minimal downtime migration. For minimal downtime
+// migrations, each logical shard is a database of a physical shard.
+func ShardConnections(sourceProfile profiles.SourceProfile) ([]profiles.DirectConnectionConfig, error) {
+	if sourceProfile.Ty != profiles.SourceProfileTypeConfig {
+		return nil, fmt.Errorf("source profile isn't a sharded migration config")
+	}
+	switch sourceProfile.Config.ConfigType {
+	case constants.BULK_MIGRATION:
+		return sourceProfile.Config.ShardConfigurationBulk.DataShards, nil
+	case constants.DATAFLOW_MIGRATION:
+		var shards []profiles.DirectConnectionConfig
+		for _, dataShard := range sourceProfile.Config.ShardConfigurationDataflow.DataShards {
+			src := dataShard.SrcConnectionProfile
+			if src.Host == "" {
+				return nil, fmt.Errorf("source connection profile of data shard %s has no host", dataShard.DataShardId)
+			}
+			for _, logicalShard := range dataShard.LogicalShards {
+				shards = append(shards, profiles.DirectConnectionConfig{
+					DataShardId: logicalShard.LogicalShardId,
+					Host:        src.Host,
+					User:        src.User,
+					Password:    src.Password,
+					Port:        src.Port,
+					DbName:      logicalShard.DbName,
+				})
+			}
+		}
+		return shards, nil
+	default:
+		return nil, fmt.Errorf("shard connections can't be read for %s migrations", sourceProfile.Config.ConfigType)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/mysql"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+func shardKeyTestConv() *internal.Conv {
+	conv := internal.MakeConv()
+	conv.SrcSchema["t_users"] = schema.Table{
+		Name:        "users",
+		Id:          "t_users",
+		ColIds:      []string{"c_user_id"},
+		ColDefs:     map[string]schema.Column{"c_user_id": {Name: "id", Id: "c_user_id"}},
+		PrimaryKeys: []schema.Key{{ColId: "c_user_id"}},
+	}
+	conv.SpSchema["t_users"] = ddl.CreateTable{
+		Name:   "users",
+		Id:     "t_users",
+		ColIds: []string{"c_user_id", "c_shard"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c_user_id": {Name: "id", Id: "c_user_id", T: ddl.Type{Name: ddl.Int64}},
+			"c_shard":   {Name: internal.ShardIdColumn, Id: "c_shard", T: ddl.Type{Name: ddl.String, Len: 50}},
+		},
+		PrimaryKeys:   []ddl.IndexKey{{ColId: "c_user_id", Order: 1}},
+		ShardIdColumn: "c_shard",
+	}
+	return conv
+}
+
+func TestAnalyzeShardKeys(t *testing.T) {
+	sourceProfile := profiles.SourceProfile{
+		Driver: constants.MYSQL,
+		Ty:     profiles.SourceProfileTypeConfig,
+		Config: profiles.SourceProfileConfig{
+			ConfigType: constants.BULK_MIGRATION,
+			ShardConfigurationBulk: profiles.ShardConfigurationBulk{
+				DataShards: []profiles.DirectConnectionConfig{{DataShardId: "shard1", DbName: "db1"}, {DataShardId: "shard2", DbName: "db2"}},
+			},
+		},
+	}
+	gim := MockGetInfo{}
+	for i, keys := range [][]int64{{1, 2, 3}, {2, 3, 4}} {
+		db, m, err := sqlmock.New()
+		assert.Nil(t, err)
+		rows := sqlmock.NewRows([]string{"id"})
+		for _, k := range keys {
+			rows.AddRow(k)
+		}
+		dbName := fmt.Sprintf("db%d", i+1)
+		m.ExpectQuery("SELECT `users`.`id` FROM `" + dbName + "`.`users` ORDER BY `users`.`id` LIMIT 100;").WillReturnRows(rows)
+		isShard := func(c profiles.DirectConnectionConfig) bool { return c.DbName == dbName }
+		gim.On("getInfoSchemaForShard", "project", mock.MatchedBy(isShard), mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(mysql.InfoSchemaImpl{DbName: dbName, Db: db}, nil)
+	}
+	conv := shardKeyTestConv()
+	assert.Nil(t, AnalyzeShardKeys("project", sourceProfile, profiles.TargetProfile{}, conv, 100, &gim))
+	assert.Equal(t, map[string]internal.ShardKeyCollisions{
+		"t_users": {
+			KeysRead:  6,
+			Sampled:   true,
+			Colliding: 2,
+			Samples: []internal.ShardKeyCollision{
+				{Key: []string{"2"}, ShardIds: []string{"shard1", "shard2"}},
+				{Key: []string{"3"}, ShardIds: []string{"shard1", "shard2"}},
+			},
+		},
+	}, conv.ShardKeyCollisions)
+
+	// Unsharded migrations can't be analyzed.
+	err := AnalyzeShardKeys("project", profiles.SourceProfile{Ty: profiles.SourceProfileTypeConnection}, profiles.TargetProfile{}, conv, 100, &gim)
+	assert.NotNil(t, err)
+}
+
+func TestShardConnections(t *testing.T) {
+	sourceProfile := profiles.SourceProfile{
+		Ty: profiles.SourceProfileTypeConfig,
+		Config: profiles.SourceProfileConfig{
+			ConfigType: constants.DATAFLOW_MIGRATION,
+			ShardConfigurationDataflow: profiles.ShardConfigurationDataflow{
+				DataShards: []*profiles.DataShard{{
+					DataShardId:          "data1",
+					SrcConnectionProfile: profiles.DatastreamConnProfileSource{Host: "10.0.0.1", User: "root", Password: "pw", Port: "3306"},
+					LogicalShards:        []profiles.LogicalShard{{DbName: "db1", LogicalShardId: "l1"}, {DbName: "db2", LogicalShardId: "l2"}},
+				}},
+			},
+		},
+	}
+	shards, err := ShardConnections(sourceProfile)
+	assert.Nil(t, err)
+	assert.Equal(t, []profiles.DirectConnectionConfig{
+		{DataShardId: "l1", Host: "10.0.0.1", User: "root", Password: "pw", Port: "3306", DbName: "db1"},
+		{DataShardId: "l2", Host: "10.0.0.1", User: "root", Password: "pw", Port: "3306", DbName: "db2"},
+	}, shards)
+
+	// Connection profiles without connection details can't be read.
+	sourceProfile.Config.ShardConfigurationDataflow.DataShards[0].SrcConnectionProfile = profiles.DatastreamConnProfileSource{Name: "existing-profile"}
+	_, err = ShardConnections(sourceProfile)
+	assert.NotNil(t, err)
+}
@@ -200,6 +200,9 @@ func WriteBadData(bw *writer.BatchWriter, conv *internal.Conv, banner, name stri
 	}
 
 	fmt.Fprintf(out, "See file '%s' for details of bad rows\n", name)
+	if conv.DeadLetter != nil && conv.DeadLetter.Count() > 0 {
+		fmt.Fprintf(out, "All %d rejected rows were written to '%s'; they can be fixed and written to Spanner with the replay-dlq command\n", conv.DeadLetter.Count(), conv.DeadLetter.Path())
+	}
 }
 
 // writeBadStreamingData writes sample of bad records and dropped records during streaming
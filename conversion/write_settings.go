@@ -0,0 +1,99 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// applyWriteSettings configures the batch writer config according to the
+// write settings of conv.
+func applyWriteSettings(ctx context.Context, config *writer.BatchWriterConfig, conv *internal.Conv, project, instance string) {
+	settings := conv.Audit.WriteSettings
+	config.Adaptive = settings.Adaptive
+	config.MaxRowsPerSecond = settings.MaxRowsPerSecond
+	if settings.Adaptive && settings.MaxCPUPercent > 0 {
+		config.MaxCPUPercent = settings.MaxCPUPercent
+		config.CPUUtilization = spannerCPUUtilization(ctx, project, instance)
+	}
+}
+
+// indexedColumns returns the number of columns of the secondary indexes of
+// each table, keyed by Spanner table name. Indexes created after the data
+// migration don't count.
+func indexedColumns(conv *internal.Conv) map[string]int64 {
+	m := make(map[string]int64)
+	if conv.Audit.DeferIndexes {
+		return m
+	}
+	for _, ct := range conv.SpSchema {
+		for _, index := range ct.Indexes {
+			m[ct.Name] += int64(len(index.Keys) + len(index.StoredColumnIds))
+		}
+	}
+	return m
+}
+
+// spannerCPUUtilization returns a function that reads the latest high
+// priority CPU utilization of a Spanner instance, in percent, from Cloud
+// Monitoring. The monitoring client is created on first use.
+func spannerCPUUtilization(ctx context.Context, project, instance string) func() (float64, error) {
+	var once sync.Once
+	var client *monitoring.MetricClient
+	var clientErr error
+	return func() (float64, error) {
+		once.Do(func() {
+			client, clientErr = monitoring.NewMetricClient(ctx)
+		})
+		if clientErr != nil {
+			return 0, fmt.Errorf("can't create monitoring client: %v", clientErr)
+		}
+		now := time.Now()
+		it := client.ListTimeSeries(ctx, &monitoringpb.ListTimeSeriesRequest{
+			Name: "projects/" + project,
+			Filter: fmt.Sprintf(`metric.type = "spanner.googleapis.com/instance/cpu/utilization_by_priority" AND metric.labels.priority = "high" AND resource.labels.instance_id = %q`,
+				instance),
+			Interval: &monitoringpb.TimeInterval{
+				StartTime: timestamppb.New(now.Add(-5 * time.Minute)),
+				EndTime:   timestamppb.New(now),
+			},
+			Aggregation: &monitoringpb.Aggregation{
+				AlignmentPeriod:    durationpb.New(time.Minute),
+				PerSeriesAligner:   monitoringpb.Aggregation_ALIGN_MEAN,
+				CrossSeriesReducer: monitoringpb.Aggregation_REDUCE_SUM,
+			},
+		})
+		series, err := it.Next()
+		if err == iterator.Done || (err == nil && len(series.Points) == 0) {
+			return 0, fmt.Errorf("no CPU utilization reported for instance %s", instance)
+		}
+		if err != nil {
+			return 0, err
+		}
+		// Points are returned in reverse time order.
+		return series.Points[0].GetValue().GetDoubleValue() * 100, nil
+	}
+}
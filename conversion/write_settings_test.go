@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conversion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexedColumns(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SpSchema = ddl.Schema{
+		"t1": {
+			Name: "Singers",
+			Indexes: []ddl.CreateIndex{
+				{Name: "ByName", Keys: []ddl.IndexKey{{ColId: "c2"}, {ColId: "c3"}}},
+				{Name: "ByAge", Keys: []ddl.IndexKey{{ColId: "c4"}}, StoredColumnIds: []string{"c2"}},
+			},
+		},
+		"t2": {Name: "Albums"},
+	}
+	assert.Equal(t, map[string]int64{"Singers": 4}, indexedColumns(conv))
+
+	conv.Audit.DeferIndexes = true
+	assert.Equal(t, map[string]int64{}, indexedColumns(conv))
+}
+
+func TestApplyWriteSettings(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.Audit.WriteSettings = internal.WriteSettings{Adaptive: true, MaxRowsPerSecond: 500, MaxCPUPercent: 65}
+	config := writer.BatchWriterConfig{WriteLimit: 40}
+	applyWriteSettings(context.Background(), &config, conv, "project", "instance")
+	assert.True(t, config.Adaptive)
+	assert.Equal(t, float64(500), config.MaxRowsPerSecond)
+	assert.Equal(t, float64(65), config.MaxCPUPercent)
+	assert.NotNil(t, config.CPUUtilization)
+
+	conv.Audit.WriteSettings = internal.WriteSettings{}
+	config = writer.BatchWriterConfig{WriteLimit: 40}
+	applyWriteSettings(context.Background(), &config, conv, "project", "instance")
+	assert.False(t, config.Adaptive)
+	assert.Nil(t, config.CPUUtilization)
+}
@@ -16,6 +16,7 @@ package dao
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"cloud.google.com/go/spanner"
 	"google.golang.org/api/iterator"
@@ -25,12 +26,52 @@ type StateData struct {
 	State string `json:"state"`
 }
 
+// Job is a row of the SMT_JOB table, without the job data.
+type Job struct {
+	JobId               string    `json:"jobId"`
+	JobName             string    `json:"jobName"`
+	JobType             string    `json:"jobType"`
+	State               string    `json:"state"`
+	Dialect             string    `json:"dialect"`
+	SpannerDatabaseName string    `json:"spannerDatabaseName"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
+
+// Resource is a row of the SMT_RESOURCE table, without the resource data.
+type Resource struct {
+	ResourceId   string    `json:"resourceId"`
+	JobId        string    `json:"jobId"`
+	ExternalId   string    `json:"externalId"`
+	ResourceName string    `json:"resourceName"`
+	ResourceType string    `json:"resourceType"`
+	DataShardId  string    `json:"dataShardId,omitempty"`
+	State        string    `json:"state"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// StateTransition is a row of the SMT_JOB_HISTORY or SMT_RESOURCE_HISTORY
+// table. ResourceId and ResourceName are empty for job state transitions.
+type StateTransition struct {
+	ResourceId   string    `json:"resourceId,omitempty"`
+	ResourceName string    `json:"resourceName,omitempty"`
+	ResourceType string    `json:"resourceType,omitempty"`
+	State        string    `json:"state"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
 type DAO interface {
 	InsertJobEntry(ctx context.Context, jobId, jobName, jobType, dialect, dbName string, jobData spanner.NullJSON) error
 	UpdateJobState(ctx context.Context, jobId, state string) error
 	InsertResourceEntry(ctx context.Context, resourceId, jobId, externalId, resourceName, resourceType string, resourceData spanner.NullJSON) error
 	UpdateResourceState(ctx context.Context, resourceId, state string) error
 	UpdateResourceExternalId(ctx context.Context, resourceId, externalId string) error
+	ListJobs(ctx context.Context) ([]Job, error)
+	GetJob(ctx context.Context, jobId string) (*Job, error)
+	ListResources(ctx context.Context, jobId string) ([]Resource, error)
+	GetJobHistory(ctx context.Context, jobId string) ([]StateTransition, error)
+	GetResourceHistory(ctx context.Context, jobId string) ([]StateTransition, error)
 }
 
 type DAOImpl struct{}
@@ -188,6 +229,152 @@ func (dao *DAOImpl) UpdateResourceExternalId(ctx context.Context, resourceId, ex
 	return nil
 }
 
+// List all the SMT jobs, most recently created first.
+func (dao *DAOImpl) ListJobs(ctx context.Context) ([]Job, error) {
+	stmt := spanner.Statement{SQL: `
+		SELECT
+			JobId, JobName, JobType, JSON_VALUE(JobStateData, '$.state') AS State, Dialect, SpannerDatabaseName, CreatedAt, UpdatedAt
+		FROM SMT_JOB ORDER BY CreatedAt DESC;`,
+	}
+	jobs := []Job{}
+	err := GetClient().Single().Query(ctx, stmt).Do(func(row *spanner.Row) error {
+		job, err := readJobRow(row)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing smt jobs: %v", err)
+	}
+	return jobs, nil
+}
+
+// Get the SMT job with the given job id.
+func (dao *DAOImpl) GetJob(ctx context.Context, jobId string) (*Job, error) {
+	stmt := spanner.Statement{SQL: `
+		SELECT
+			JobId, JobName, JobType, JSON_VALUE(JobStateData, '$.state') AS State, Dialect, SpannerDatabaseName, CreatedAt, UpdatedAt
+		FROM SMT_JOB WHERE JobId = @jobId;`,
+		Params: map[string]interface{}{"jobId": jobId},
+	}
+	iter := GetClient().Single().Query(ctx, stmt)
+	defer iter.Stop()
+	row, err := iter.Next()
+	if err == iterator.Done {
+		return nil, fmt.Errorf("smt job %s not found", jobId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching smt job: %v", err)
+	}
+	job, err := readJobRow(row)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List the SMT resources generated for the given job id, ordered by data shard.
+func (dao *DAOImpl) ListResources(ctx context.Context, jobId string) ([]Resource, error) {
+	stmt := spanner.Statement{SQL: `
+		SELECT
+			ResourceId, JobId, ExternalId, ResourceName, ResourceType, JSON_VALUE(ResourceData, '$.DataShardId') AS DataShardId,
+			JSON_VALUE(ResourceStateData, '$.state') AS State, CreatedAt, UpdatedAt
+		FROM SMT_RESOURCE WHERE JobId = @jobId ORDER BY DataShardId, ResourceType, CreatedAt;`,
+		Params: map[string]interface{}{"jobId": jobId},
+	}
+	resources := []Resource{}
+	err := GetClient().Single().Query(ctx, stmt).Do(func(row *spanner.Row) error {
+		var resourceId, jobId, resourceName, resourceType string
+		var externalId, dataShardId, state spanner.NullString
+		var createdAt, updatedAt time.Time
+		if err := row.Columns(&resourceId, &jobId, &externalId, &resourceName, &resourceType, &dataShardId, &state, &createdAt, &updatedAt); err != nil {
+			return fmt.Errorf("error reading smt resource row: %v", err)
+		}
+		resources = append(resources, Resource{
+			ResourceId:   resourceId,
+			JobId:        jobId,
+			ExternalId:   externalId.StringVal,
+			ResourceName: resourceName,
+			ResourceType: resourceType,
+			DataShardId:  dataShardId.StringVal,
+			State:        state.StringVal,
+			CreatedAt:    createdAt,
+			UpdatedAt:    updatedAt,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing smt resources: %v", err)
+	}
+	return resources, nil
+}
+
+// Get the state transitions of the given job id, oldest first.
+func (dao *DAOImpl) GetJobHistory(ctx context.Context, jobId string) ([]StateTransition, error) {
+	stmt := spanner.Statement{SQL: `
+		SELECT
+			'' AS ResourceId, '' AS ResourceName, '' AS ResourceType, JSON_VALUE(JobStateData, '$.state') AS State, CreatedAt
+		FROM SMT_JOB_HISTORY WHERE JobId = @jobId ORDER BY CreatedAt;`,
+		Params: map[string]interface{}{"jobId": jobId},
+	}
+	history, err := queryStateTransitions(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching smt job history: %v", err)
+	}
+	return history, nil
+}
+
+// Get the state transitions of all resources of the given job id, oldest first.
+func (dao *DAOImpl) GetResourceHistory(ctx context.Context, jobId string) ([]StateTransition, error) {
+	stmt := spanner.Statement{SQL: `
+		SELECT
+			ResourceId, ResourceName, ResourceType, JSON_VALUE(ResourceStateData, '$.state') AS State, CreatedAt
+		FROM SMT_RESOURCE_HISTORY WHERE JobId = @jobId ORDER BY CreatedAt;`,
+		Params: map[string]interface{}{"jobId": jobId},
+	}
+	history, err := queryStateTransitions(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching smt resource history: %v", err)
+	}
+	return history, nil
+}
+
+func readJobRow(row *spanner.Row) (Job, error) {
+	var jobId, jobName, jobType, dialect, spannerDatabaseName string
+	var state spanner.NullString
+	var createdAt, updatedAt time.Time
+	if err := row.Columns(&jobId, &jobName, &jobType, &state, &dialect, &spannerDatabaseName, &createdAt, &updatedAt); err != nil {
+		return Job{}, fmt.Errorf("error reading smt job row: %v", err)
+	}
+	return Job{
+		JobId:               jobId,
+		JobName:             jobName,
+		JobType:             jobType,
+		State:               state.StringVal,
+		Dialect:             dialect,
+		SpannerDatabaseName: spannerDatabaseName,
+		CreatedAt:           createdAt,
+		UpdatedAt:           updatedAt,
+	}, nil
+}
+
+func queryStateTransitions(ctx context.Context, stmt spanner.Statement) ([]StateTransition, error) {
+	history := []StateTransition{}
+	err := GetClient().Single().Query(ctx, stmt).Do(func(row *spanner.Row) error {
+		var resourceId, resourceName, resourceType string
+		var state spanner.NullString
+		var createdAt time.Time
+		if err := row.Columns(&resourceId, &resourceName, &resourceType, &state, &createdAt); err != nil {
+			return err
+		}
+		history = append(history, StateTransition{ResourceId: resourceId, ResourceName: resourceName, ResourceType: resourceType, State: state.StringVal, CreatedAt: createdAt})
+		return nil
+	})
+	return history, err
+}
+
 func updateJobHistoryWithinTxn(ctx context.Context, txn *spanner.ReadWriteTransaction, jobId string) (int64, error) {
 	// Fetch the newly updated row from SMT_JOB table.
 	stmt := spanner.Statement{SQL: `
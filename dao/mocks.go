@@ -0,0 +1,75 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package dao
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Mock that implements the DAO interface.
+// Pass in unit tests where DAO is an input parameter.
+type DAOMock struct {
+	InsertJobEntryMock           func(ctx context.Context, jobId, jobName, jobType, dialect, dbName string, jobData spanner.NullJSON) error
+	UpdateJobStateMock           func(ctx context.Context, jobId, state string) error
+	InsertResourceEntryMock      func(ctx context.Context, resourceId, jobId, externalId, resourceName, resourceType string, resourceData spanner.NullJSON) error
+	UpdateResourceStateMock      func(ctx context.Context, resourceId, state string) error
+	UpdateResourceExternalIdMock func(ctx context.Context, resourceId, externalId string) error
+	ListJobsMock                 func(ctx context.Context) ([]Job, error)
+	GetJobMock                   func(ctx context.Context, jobId string) (*Job, error)
+	ListResourcesMock            func(ctx context.Context, jobId string) ([]Resource, error)
+	GetJobHistoryMock            func(ctx context.Context, jobId string) ([]StateTransition, error)
+	GetResourceHistoryMock       func(ctx context.Context, jobId string) ([]StateTransition, error)
+}
+
+func (dm *DAOMock) InsertJobEntry(ctx context.Context, jobId, jobName, jobType, dialect, dbName string, jobData spanner.NullJSON) error {
+	return dm.InsertJobEntryMock(ctx, jobId, jobName, jobType, dialect, dbName, jobData)
+}
+
+func (dm *DAOMock) UpdateJobState(ctx context.Context, jobId, state string) error {
+	return dm.UpdateJobStateMock(ctx, jobId, state)
+}
+
+func (dm *DAOMock) InsertResourceEntry(ctx context.Context, resourceId, jobId, externalId, resourceName, resourceType string, resourceData spanner.NullJSON) error {
+	return dm.InsertResourceEntryMock(ctx, resourceId, jobId, externalId, resourceName, resourceType, resourceData)
+}
+
+func (dm *DAOMock) UpdateResourceState(ctx context.Context, resourceId, state string) error {
+	return dm.UpdateResourceStateMock(ctx, resourceId, state)
+}
+
+func (dm *DAOMock) UpdateResourceExternalId(ctx context.Context, resourceId, externalId string) error {
+	return dm.UpdateResourceExternalIdMock(ctx, resourceId, externalId)
+}
+
+func (dm *DAOMock) ListJobs(ctx context.Context) ([]Job, error) {
+	return dm.ListJobsMock(ctx)
+}
+
+func (dm *DAOMock) GetJob(ctx context.Context, jobId string) (*Job, error) {
+	return dm.GetJobMock(ctx, jobId)
+}
+
+func (dm *DAOMock) ListResources(ctx context.Context, jobId string) ([]Resource, error) {
+	return dm.ListResourcesMock(ctx, jobId)
+}
+
+func (dm *DAOMock) GetJobHistory(ctx context.Context, jobId string) ([]StateTransition, error) {
+	return dm.GetJobHistoryMock(ctx, jobId)
+}
+
+func (dm *DAOMock) GetResourceHistory(ctx context.Context, jobId string) ([]StateTransition, error) {
+	return dm.GetResourceHistoryMock(ctx, jobId)
+}
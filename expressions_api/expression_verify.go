@@ -56,8 +56,10 @@ type DDLVerifierImpl struct {
 	Expressions ExpressionVerificationAccessor
 }
 
-func NewDDLVerifierImpl(ctx context.Context, project string, instance string) (*DDLVerifierImpl, error) {
-	expVerifier, err := NewExpressionVerificationAccessorImpl(ctx, project, instance)
+// NewDDLVerifierImpl returns a DDLVerifierImpl which verifies expressions
+// locally when offline is set, see NewExpressionVerificationAccessor.
+func NewDDLVerifierImpl(ctx context.Context, project string, instance string, offline bool) (*DDLVerifierImpl, error) {
+	expVerifier, err := NewExpressionVerificationAccessor(ctx, project, instance, offline)
 	return &DDLVerifierImpl{
 		Expressions: expVerifier,
 	}, err
@@ -124,6 +126,8 @@ func (ev *ExpressionVerificationAccessorImpl) verifyExpressionInternal(expressio
 		sqlStatement = fmt.Sprintf("SELECT 1 from %s where %s;", expressionDetail.ReferenceElement.Name, expressionDetail.Expression)
 	case constants.DEFAULT_EXPRESSION:
 		sqlStatement = fmt.Sprintf("SELECT CAST(%s as %s)", expressionDetail.Expression, expressionDetail.ReferenceElement.Name)
+	case constants.GENERATED_EXPRESSION:
+		sqlStatement = fmt.Sprintf("SELECT %s from %s;", expressionDetail.Expression, expressionDetail.ReferenceElement.Name)
 	default:
 		return task.TaskResult[internal.ExpressionVerificationOutput]{Result: internal.ExpressionVerificationOutput{Result: false, Err: fmt.Errorf("invalid expression type requested")}, Err: nil}
 	}
@@ -132,6 +136,10 @@ func (ev *ExpressionVerificationAccessorImpl) verifyExpressionInternal(expressio
 }
 
 func (ev *ExpressionVerificationAccessorImpl) validateRequest(verifyExpressionsInput internal.VerifyExpressionsInput) error {
+	return validateVerifyExpressionsInput(verifyExpressionsInput)
+}
+
+func validateVerifyExpressionsInput(verifyExpressionsInput internal.VerifyExpressionsInput) error {
 	if verifyExpressionsInput.Conv == nil || verifyExpressionsInput.Source == "" {
 		return fmt.Errorf("one of conv or source is empty. These are mandatory fields = %v", verifyExpressionsInput)
 	}
@@ -166,6 +174,7 @@ func (ev *ExpressionVerificationAccessorImpl) removeExpressions(inputConv *inter
 		for colName, colDef := range table.ColDefs {
 			colDef.AutoGen = ddl.AutoGenCol{}
 			colDef.DefaultValue = ddl.DefaultValue{}
+			colDef.Generated = ddl.GeneratedColumn{}
 			table.ColDefs[colName] = colDef
 		}
 	}
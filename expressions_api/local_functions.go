@@ -0,0 +1,276 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expressions_api
+
+// param reports whether an argument can be passed to a function parameter.
+type param func(arg exprInfo) bool
+
+// signature describes the parameters and the result of a function.
+type signature struct {
+	params   []param
+	optional int  // Number of trailing params that may be omitted.
+	variadic bool // If true, the last param may be repeated.
+	result   func(args []exprInfo) (sqlType, bool)
+}
+
+func (s signature) check(name string, args []exprInfo) (exprInfo, error) {
+	required := len(s.params) - s.optional
+	if len(args) < required || (!s.variadic && len(args) > len(s.params)) {
+		return exprInfo{}, noMatchingFunctionSignature(name, args)
+	}
+	for i, arg := range args {
+		p := s.params[len(s.params)-1]
+		if i < len(s.params) {
+			p = s.params[i]
+		}
+		if !p(arg) {
+			return exprInfo{}, noMatchingFunctionSignature(name, args)
+		}
+	}
+	t, ok := s.result(args)
+	if !ok {
+		return exprInfo{}, noMatchingFunctionSignature(name, args)
+	}
+	return exprInfo{t: t}, nil
+}
+
+func isPseudo(t sqlType) bool {
+	return t == datePartType || t == intervalType || t == sequenceType
+}
+
+var (
+	anyArg           param = func(a exprInfo) bool { return !isPseudo(a.t) }
+	numericArg       param = func(a exprInfo) bool { return a.t.isNull() || a.t.isNumeric() }
+	intArg           param = func(a exprInfo) bool { return a.t.is(int64Type) }
+	boolArg          param = func(a exprInfo) bool { return a.t.is(boolType) }
+	stringArg        param = func(a exprInfo) bool { return a.t.is(stringType) }
+	bytesArg         param = func(a exprInfo) bool { return a.t.is(bytesType) }
+	stringOrBytesArg param = func(a exprInfo) bool { return a.t.is(stringType) || a.t.is(bytesType) }
+	dateArg          param = func(a exprInfo) bool { return a.t.is(dateType) || a.literal }
+	timestampArg     param = func(a exprInfo) bool { return a.t.is(timestampType) || a.literal }
+	jsonOrStringArg  param = func(a exprInfo) bool { return a.t.is(jsonType) || a.t.is(stringType) }
+	arrayArg         param = func(a exprInfo) bool { return a.t.isNull() || a.t.array }
+	intArrayArg      param = func(a exprInfo) bool { return a.t.isNull() || a.t == sqlType{name: int64Type.name, array: true} }
+	stringArrayArg   param = func(a exprInfo) bool { return a.t.isNull() || a.t == sqlType{name: stringType.name, array: true} }
+	datePartArg      param = func(a exprInfo) bool { return a.t == datePartType }
+	intervalArg      param = func(a exprInfo) bool { return a.t == intervalType }
+	sequenceArg      param = func(a exprInfo) bool { return a.t == sequenceType }
+)
+
+func returns(t sqlType) func([]exprInfo) (sqlType, bool) {
+	return func([]exprInfo) (sqlType, bool) { return t, true }
+}
+
+func arrayOf(t sqlType) func([]exprInfo) (sqlType, bool) {
+	return returns(sqlType{name: t.name, array: true})
+}
+
+// sameAsFirst returns the type of the first argument.
+func sameAsFirst(args []exprInfo) (sqlType, bool) {
+	return args[0].t, true
+}
+
+// arrayOfFirst returns an array of the type of the first argument.
+func arrayOfFirst(args []exprInfo) (sqlType, bool) {
+	return sqlType{name: args[0].t.name, array: true}, true
+}
+
+// supertypeOfArgs returns the common supertype of all the arguments.
+func supertypeOfArgs(args []exprInfo) (sqlType, bool) {
+	res, err := unify("", args...)
+	return res.t, err == nil
+}
+
+// numericSupertype returns the common supertype of numeric arguments.
+func numericSupertype(args []exprInfo) (sqlType, bool) {
+	t, ok := supertypeOfArgs(args)
+	return t, ok && (t.isNull() || t.isNumeric())
+}
+
+// roundResult is the result of the rounding functions, which return FLOAT64
+// for INT64 arguments.
+func roundResult(args []exprInfo) (sqlType, bool) {
+	if args[0].t.is(int64Type) {
+		return float64Type, true
+	}
+	return args[0].t, true
+}
+
+func fn(result func([]exprInfo) (sqlType, bool), params ...param) signature {
+	return signature{params: params, result: result}
+}
+
+func fnOptional(optional int, result func([]exprInfo) (sqlType, bool), params ...param) signature {
+	return signature{params: params, optional: optional, result: result}
+}
+
+func fnVariadic(result func([]exprInfo) (sqlType, bool), params ...param) signature {
+	return signature{params: params, variadic: true, result: result}
+}
+
+// functionCatalog contains the scalar functions supported in check
+// constraints and default values.
+// https://cloud.google.com/spanner/docs/reference/standard-sql/functions-all
+var functionCatalog = map[string]signature{
+	// Mathematical functions.
+	"ABS":           fn(sameAsFirst, numericArg),
+	"ACOS":          fn(returns(float64Type), numericArg),
+	"ACOSH":         fn(returns(float64Type), numericArg),
+	"ASIN":          fn(returns(float64Type), numericArg),
+	"ASINH":         fn(returns(float64Type), numericArg),
+	"ATAN":          fn(returns(float64Type), numericArg),
+	"ATAN2":         fn(returns(float64Type), numericArg, numericArg),
+	"ATANH":         fn(returns(float64Type), numericArg),
+	"CEIL":          fn(roundResult, numericArg),
+	"CEILING":       fn(roundResult, numericArg),
+	"COS":           fn(returns(float64Type), numericArg),
+	"COSH":          fn(returns(float64Type), numericArg),
+	"DIV":           fn(numericSupertype, numericArg, numericArg),
+	"EXP":           fn(returns(float64Type), numericArg),
+	"FLOOR":         fn(roundResult, numericArg),
+	"GREATEST":      fnVariadic(supertypeOfArgs, anyArg),
+	"IEEE_DIVIDE":   fn(returns(float64Type), numericArg, numericArg),
+	"IS_INF":        fn(returns(boolType), numericArg),
+	"IS_NAN":        fn(returns(boolType), numericArg),
+	"LEAST":         fnVariadic(supertypeOfArgs, anyArg),
+	"LN":            fn(returns(float64Type), numericArg),
+	"LOG":           fnOptional(1, returns(float64Type), numericArg, numericArg),
+	"LOG10":         fn(returns(float64Type), numericArg),
+	"MOD":           fn(numericSupertype, numericArg, numericArg),
+	"POW":           fn(returns(float64Type), numericArg, numericArg),
+	"POWER":         fn(returns(float64Type), numericArg, numericArg),
+	"ROUND":         fnOptional(1, roundResult, numericArg, intArg),
+	"SAFE_ADD":      fn(numericSupertype, numericArg, numericArg),
+	"SAFE_DIVIDE":   fn(returns(float64Type), numericArg, numericArg),
+	"SAFE_MULTIPLY": fn(numericSupertype, numericArg, numericArg),
+	"SAFE_NEGATE":   fn(sameAsFirst, numericArg),
+	"SAFE_SUBTRACT": fn(numericSupertype, numericArg, numericArg),
+	"SIGN":          fn(sameAsFirst, numericArg),
+	"SIN":           fn(returns(float64Type), numericArg),
+	"SINH":          fn(returns(float64Type), numericArg),
+	"SQRT":          fn(returns(float64Type), numericArg),
+	"TAN":           fn(returns(float64Type), numericArg),
+	"TANH":          fn(returns(float64Type), numericArg),
+	"TRUNC":         fnOptional(1, roundResult, numericArg, intArg),
+
+	// Hash functions.
+	"FARM_FINGERPRINT": fn(returns(int64Type), stringOrBytesArg),
+	"SHA1":             fn(returns(bytesType), stringOrBytesArg),
+	"SHA256":           fn(returns(bytesType), stringOrBytesArg),
+	"SHA512":           fn(returns(bytesType), stringOrBytesArg),
+
+	// String functions.
+	"BYTE_LENGTH":                  fn(returns(int64Type), stringOrBytesArg),
+	"CHAR_LENGTH":                  fn(returns(int64Type), stringArg),
+	"CHARACTER_LENGTH":             fn(returns(int64Type), stringArg),
+	"CODE_POINTS_TO_BYTES":         fn(returns(bytesType), intArrayArg),
+	"CODE_POINTS_TO_STRING":        fn(returns(stringType), intArrayArg),
+	"CONCAT":                       fnVariadic(supertypeOfArgs, stringOrBytesArg),
+	"ENDS_WITH":                    fn(returns(boolType), stringOrBytesArg, stringOrBytesArg),
+	"FORMAT":                       fnVariadic(returns(stringType), stringArg, anyArg),
+	"FROM_BASE32":                  fn(returns(bytesType), stringArg),
+	"FROM_BASE64":                  fn(returns(bytesType), stringArg),
+	"FROM_HEX":                     fn(returns(bytesType), stringArg),
+	"LENGTH":                       fn(returns(int64Type), stringOrBytesArg),
+	"LOWER":                        fn(sameAsFirst, stringOrBytesArg),
+	"LPAD":                         fnOptional(1, sameAsFirst, stringOrBytesArg, intArg, stringOrBytesArg),
+	"LTRIM":                        fnOptional(1, sameAsFirst, stringOrBytesArg, stringOrBytesArg),
+	"REGEXP_CONTAINS":              fn(returns(boolType), stringOrBytesArg, stringOrBytesArg),
+	"REGEXP_EXTRACT":               fn(sameAsFirst, stringOrBytesArg, stringOrBytesArg),
+	"REGEXP_EXTRACT_ALL":           fn(arrayOfFirst, stringOrBytesArg, stringOrBytesArg),
+	"REGEXP_REPLACE":               fn(sameAsFirst, stringOrBytesArg, stringOrBytesArg, stringOrBytesArg),
+	"REPEAT":                       fn(sameAsFirst, stringOrBytesArg, intArg),
+	"REPLACE":                      fn(sameAsFirst, stringOrBytesArg, stringOrBytesArg, stringOrBytesArg),
+	"REVERSE":                      fn(sameAsFirst, stringOrBytesArg),
+	"RPAD":                         fnOptional(1, sameAsFirst, stringOrBytesArg, intArg, stringOrBytesArg),
+	"RTRIM":                        fnOptional(1, sameAsFirst, stringOrBytesArg, stringOrBytesArg),
+	"SAFE_CONVERT_BYTES_TO_STRING": fn(returns(stringType), bytesArg),
+	"SPLIT":                        fnOptional(1, arrayOfFirst, stringOrBytesArg, stringOrBytesArg),
+	"STARTS_WITH":                  fn(returns(boolType), stringOrBytesArg, stringOrBytesArg),
+	"STRPOS":                       fn(returns(int64Type), stringOrBytesArg, stringOrBytesArg),
+	"SUBSTR":                       fnOptional(1, sameAsFirst, stringOrBytesArg, intArg, intArg),
+	"TO_BASE32":                    fn(returns(stringType), bytesArg),
+	"TO_BASE64":                    fn(returns(stringType), bytesArg),
+	"TO_CODE_POINTS":               fn(arrayOf(int64Type), stringOrBytesArg),
+	"TO_HEX":                       fn(returns(stringType), bytesArg),
+	"TRIM":                         fnOptional(1, sameAsFirst, stringOrBytesArg, stringOrBytesArg),
+	"UPPER":                        fn(sameAsFirst, stringOrBytesArg),
+
+	// Array functions.
+	"ARRAY_CONCAT":      fnVariadic(supertypeOfArgs, arrayArg),
+	"ARRAY_IS_DISTINCT": fn(returns(boolType), arrayArg),
+	"ARRAY_LENGTH":      fn(returns(int64Type), arrayArg),
+	"ARRAY_REVERSE":     fn(sameAsFirst, arrayArg),
+	"ARRAY_TO_STRING":   fnOptional(1, returns(stringType), stringArrayArg, stringArg, stringArg),
+	"GENERATE_ARRAY": fnOptional(1, func(args []exprInfo) (sqlType, bool) {
+		t, ok := numericSupertype(args)
+		return sqlType{name: t.name, array: true}, ok
+	}, numericArg, numericArg, numericArg),
+	"GENERATE_DATE_ARRAY": fnOptional(1, arrayOf(dateType), dateArg, dateArg, intervalArg),
+
+	// Date functions.
+	"CURRENT_DATE":        fnOptional(1, returns(dateType), stringArg),
+	"DATE":                fnOptional(2, returns(dateType), anyArg, anyArg, intArg),
+	"DATE_ADD":            fn(returns(dateType), dateArg, intervalArg),
+	"DATE_SUB":            fn(returns(dateType), dateArg, intervalArg),
+	"DATE_DIFF":           fn(returns(int64Type), dateArg, dateArg, datePartArg),
+	"DATE_TRUNC":          fn(returns(dateType), dateArg, datePartArg),
+	"DATE_FROM_UNIX_DATE": fn(returns(dateType), intArg),
+	"FORMAT_DATE":         fn(returns(stringType), stringArg, dateArg),
+	"PARSE_DATE":          fn(returns(dateType), stringArg, stringArg),
+	"UNIX_DATE":           fn(returns(int64Type), dateArg),
+
+	// Timestamp functions.
+	"CURRENT_TIMESTAMP":        fn(returns(timestampType)),
+	"STRING":                   fnOptional(1, returns(stringType), timestampArg, stringArg),
+	"TIMESTAMP":                fnOptional(1, returns(timestampType), anyArg, stringArg),
+	"TIMESTAMP_ADD":            fn(returns(timestampType), timestampArg, intervalArg),
+	"TIMESTAMP_SUB":            fn(returns(timestampType), timestampArg, intervalArg),
+	"TIMESTAMP_DIFF":           fn(returns(int64Type), timestampArg, timestampArg, datePartArg),
+	"TIMESTAMP_TRUNC":          fnOptional(1, returns(timestampType), timestampArg, datePartArg, stringArg),
+	"FORMAT_TIMESTAMP":         fnOptional(1, returns(stringType), stringArg, timestampArg, stringArg),
+	"PARSE_TIMESTAMP":          fnOptional(1, returns(timestampType), stringArg, stringArg, stringArg),
+	"TIMESTAMP_SECONDS":        fn(returns(timestampType), intArg),
+	"TIMESTAMP_MILLIS":         fn(returns(timestampType), intArg),
+	"TIMESTAMP_MICROS":         fn(returns(timestampType), intArg),
+	"UNIX_SECONDS":             fn(returns(int64Type), timestampArg),
+	"UNIX_MILLIS":              fn(returns(int64Type), timestampArg),
+	"UNIX_MICROS":              fn(returns(int64Type), timestampArg),
+	"PENDING_COMMIT_TIMESTAMP": fn(returns(timestampType)),
+
+	// JSON functions.
+	"JSON_QUERY":       fn(sameAsFirst, jsonOrStringArg, stringArg),
+	"JSON_VALUE":       fn(returns(stringType), jsonOrStringArg, stringArg),
+	"JSON_QUERY_ARRAY": fnOptional(1, arrayOfFirst, jsonOrStringArg, stringArg),
+	"JSON_VALUE_ARRAY": fnOptional(1, arrayOf(stringType), jsonOrStringArg, stringArg),
+
+	// Bit functions.
+	"BIT_COUNT":   fn(returns(int64Type), func(a exprInfo) bool { return a.t.is(int64Type) || a.t.is(bytesType) }),
+	"BIT_REVERSE": fn(returns(int64Type), intArg, boolArg),
+
+	// Sequence functions.
+	"GET_NEXT_SEQUENCE_VALUE":     fn(returns(int64Type), sequenceArg),
+	"GET_INTERNAL_SEQUENCE_STATE": fn(returns(int64Type), sequenceArg),
+
+	// Utility functions.
+	"GENERATE_UUID": fn(returns(stringType)),
+}
+
+// aggregateFunctions can't be used in check constraints and default values.
+var aggregateFunctions = map[string]bool{
+	"ANY_VALUE": true, "ARRAY_AGG": true, "ARRAY_CONCAT_AGG": true, "AVG": true, "BIT_AND": true, "BIT_OR": true, "BIT_XOR": true,
+	"COUNT": true, "COUNTIF": true, "LOGICAL_AND": true, "LOGICAL_OR": true, "MAX": true, "MIN": true, "STRING_AGG": true, "SUM": true,
+	"STDDEV": true, "STDDEV_SAMP": true, "VAR_SAMP": true, "VARIANCE": true,
+}
@@ -0,0 +1,483 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expressions_api
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/spanner/spansql"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// exprInfo is the result of type checking an expression. String literals are
+// tracked since they coerce to DATE and TIMESTAMP, and their value is checked
+// when they are cast.
+type exprInfo struct {
+	t       sqlType
+	literal bool
+	str     string
+}
+
+// Pseudo types of the arguments that aren't values.
+var (
+	datePartType = sqlType{name: "DATE_PART"}
+	intervalType = sqlType{name: "INTERVAL"}
+	sequenceType = sqlType{name: "SEQUENCE"}
+)
+
+// exprChecker type checks GoogleSQL expressions against the columns of a table.
+type exprChecker struct {
+	table     string
+	columns   map[string]sqlType // Keyed by lower case column name.
+	sequences map[string]bool    // Keyed by lower case sequence name.
+}
+
+func newExprChecker(conv *internal.Conv, table *ddl.CreateTable) *exprChecker {
+	c := &exprChecker{columns: map[string]sqlType{}, sequences: map[string]bool{}}
+	if table != nil {
+		c.table = table.Name
+		for _, col := range table.ColDefs {
+			c.columns[strings.ToLower(col.Name)] = typeFromDDL(col.T)
+		}
+	}
+	for _, seq := range conv.SpSequences {
+		c.sequences[strings.ToLower(seq.Name)] = true
+	}
+	return c
+}
+
+func (c *exprChecker) eval(e spansql.Expr) (exprInfo, error) {
+	switch e := e.(type) {
+	case spansql.NullLiteral:
+		return exprInfo{t: nullType}, nil
+	case spansql.BoolLiteral:
+		return exprInfo{t: boolType}, nil
+	case spansql.IntegerLiteral:
+		return exprInfo{t: int64Type}, nil
+	case spansql.FloatLiteral:
+		return exprInfo{t: float64Type}, nil
+	case spansql.StringLiteral:
+		return exprInfo{t: stringType, literal: true, str: string(e)}, nil
+	case spansql.BytesLiteral:
+		return exprInfo{t: bytesType}, nil
+	case spansql.DateLiteral:
+		return exprInfo{t: dateType}, nil
+	case spansql.TimestampLiteral:
+		return exprInfo{t: timestampType}, nil
+	case spansql.JSONLiteral:
+		return exprInfo{t: jsonType}, nil
+	case spansql.ID:
+		return c.column(string(e))
+	case spansql.PathExp:
+		if len(e) == 2 && c.table != "" && strings.EqualFold(string(e[0]), c.table) {
+			return c.column(string(e[1]))
+		}
+		return exprInfo{}, fmt.Errorf("Unrecognized name: %s", e[0])
+	case spansql.Paren:
+		return c.eval(e.Expr)
+	case spansql.Array:
+		args, err := c.evalAll(e)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		elem := exprInfo{t: nullType}
+		if len(args) > 0 {
+			if elem, err = unify("ARRAY", args...); err != nil {
+				return exprInfo{}, err
+			}
+		}
+		if elem.t.array {
+			return exprInfo{}, fmt.Errorf("Cannot construct array with element type %s because nested arrays are not supported", elem.t)
+		}
+		return exprInfo{t: sqlType{name: elem.t.name, array: true}}, nil
+	case spansql.ArithOp:
+		return c.evalArith(e)
+	case spansql.LogicalOp:
+		return c.evalLogical(e)
+	case spansql.ComparisonOp:
+		return c.evalComparison(e)
+	case spansql.InOp:
+		lhs, err := c.eval(e.LHS)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		rhs, err := c.evalAll(e.RHS)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		if e.Unnest {
+			if len(rhs) != 1 || !(rhs[0].t.isNull() || (rhs[0].t.array && (lhs.t.isNull() || sqlType{name: rhs[0].t.name}.is(lhs.t)))) {
+				return exprInfo{}, noMatchingSignature("IN UNNEST", append([]exprInfo{lhs}, rhs...))
+			}
+			return exprInfo{t: boolType}, nil
+		}
+		if _, err := unify("IN", append([]exprInfo{lhs}, rhs...)...); err != nil {
+			return exprInfo{}, err
+		}
+		return exprInfo{t: boolType}, nil
+	case spansql.IsOp:
+		lhs, err := c.eval(e.LHS)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		if _, ok := e.RHS.(spansql.BoolLiteral); ok && !lhs.t.is(boolType) {
+			return exprInfo{}, noMatchingSignature("IS", []exprInfo{lhs, {t: boolType}})
+		}
+		return exprInfo{t: boolType}, nil
+	case spansql.Case:
+		return c.evalCase(e)
+	case spansql.Coalesce:
+		args, err := c.evalAll(e.ExprList)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		return unifyResult("COALESCE", args)
+	case spansql.If:
+		args, err := c.evalAll([]spansql.Expr{e.Expr, e.TrueResult, e.ElseResult})
+		if err != nil {
+			return exprInfo{}, err
+		}
+		if !args[0].t.is(boolType) {
+			return exprInfo{}, noMatchingFunctionSignature("IF", args)
+		}
+		return unifyResult("IF", args[1:])
+	case spansql.IfNull:
+		args, err := c.evalAll([]spansql.Expr{e.Expr, e.NullResult})
+		if err != nil {
+			return exprInfo{}, err
+		}
+		return unifyResult("IFNULL", args)
+	case spansql.NullIf:
+		args, err := c.evalAll([]spansql.Expr{e.Expr, e.ExprToMatch})
+		if err != nil {
+			return exprInfo{}, err
+		}
+		if _, err := unify("NULLIF", args...); err != nil {
+			return exprInfo{}, err
+		}
+		return exprInfo{t: args[0].t}, nil
+	case spansql.ExtractExpr:
+		arg, err := c.eval(e.Expr)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		if !arg.t.is(dateType) && !arg.t.is(timestampType) {
+			return exprInfo{}, noMatchingFunctionSignature("EXTRACT", []exprInfo{arg})
+		}
+		if strings.EqualFold(e.Part, "DATE") {
+			return exprInfo{t: dateType}, nil
+		}
+		return exprInfo{t: int64Type}, nil
+	case spansql.IntervalExpr:
+		arg, err := c.eval(e.Expr)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		if !arg.t.is(int64Type) {
+			return exprInfo{}, fmt.Errorf("Interval value must be coercible to INT64 type")
+		}
+		return exprInfo{t: intervalType}, nil
+	case spansql.SequenceExpr:
+		if !c.sequences[strings.ToLower(string(e.Name))] {
+			return exprInfo{}, fmt.Errorf("Sequence not found: %s", e.Name)
+		}
+		return exprInfo{t: sequenceType}, nil
+	case spansql.Func:
+		return c.evalFunc(e)
+	case spansql.Param:
+		return exprInfo{}, fmt.Errorf("Query parameters cannot be used in expressions: @%s", string(e))
+	}
+	return exprInfo{}, fmt.Errorf("Unsupported expression: %s", e.SQL())
+}
+
+func (c *exprChecker) evalAll(exprs []spansql.Expr) ([]exprInfo, error) {
+	var infos []exprInfo
+	for _, e := range exprs {
+		info, err := c.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (c *exprChecker) column(name string) (exprInfo, error) {
+	t, ok := c.columns[strings.ToLower(name)]
+	if !ok {
+		return exprInfo{}, fmt.Errorf("Unrecognized name: %s", name)
+	}
+	return exprInfo{t: t}, nil
+}
+
+var arithOperators = map[spansql.ArithOperator]string{
+	spansql.Neg: "-", spansql.Plus: "+", spansql.BitNot: "~", spansql.Mul: "*", spansql.Div: "/", spansql.Concat: "||",
+	spansql.Add: "+", spansql.Sub: "-", spansql.BitShl: "<<", spansql.BitShr: ">>", spansql.BitAnd: "&", spansql.BitXor: "^", spansql.BitOr: "|",
+}
+
+func (c *exprChecker) evalArith(e spansql.ArithOp) (exprInfo, error) {
+	op := arithOperators[e.Op]
+	rhs, err := c.eval(e.RHS)
+	if err != nil {
+		return exprInfo{}, err
+	}
+	switch e.Op {
+	case spansql.Neg, spansql.Plus:
+		if !rhs.t.isNull() && !rhs.t.isNumeric() {
+			return exprInfo{}, noMatchingSignature(op, []exprInfo{rhs})
+		}
+		return exprInfo{t: rhs.t}, nil
+	case spansql.BitNot:
+		if !rhs.t.is(int64Type) && !rhs.t.is(bytesType) {
+			return exprInfo{}, noMatchingSignature(op, []exprInfo{rhs})
+		}
+		return exprInfo{t: rhs.t}, nil
+	}
+	lhs, err := c.eval(e.LHS)
+	if err != nil {
+		return exprInfo{}, err
+	}
+	args := []exprInfo{lhs, rhs}
+	switch e.Op {
+	case spansql.Add, spansql.Sub:
+		// DATE +/- INT64 and TIMESTAMP +/- INTERVAL are supported too.
+		if (lhs.t == dateType && rhs.t.is(int64Type)) || (lhs.t == timestampType && rhs.t == intervalType) {
+			return exprInfo{t: lhs.t}, nil
+		}
+		if e.Op == spansql.Add && ((rhs.t == dateType && lhs.t.is(int64Type)) || (rhs.t == timestampType && lhs.t == intervalType)) {
+			return exprInfo{t: rhs.t}, nil
+		}
+		fallthrough
+	case spansql.Mul, spansql.Div:
+		res, ok := supertype(lhs, rhs)
+		if !ok || !(res.t.isNull() || res.t.isNumeric()) {
+			return exprInfo{}, noMatchingSignature(op, args)
+		}
+		if e.Op == spansql.Div && (res.t.isNull() || res.t == int64Type) {
+			return exprInfo{t: float64Type}, nil
+		}
+		return exprInfo{t: res.t}, nil
+	case spansql.Concat:
+		res, ok := supertype(lhs, rhs)
+		if !ok || !(res.t.isNull() || res.t.is(stringType) || res.t.is(bytesType) || res.t.array) {
+			return exprInfo{}, noMatchingSignature(op, args)
+		}
+		return exprInfo{t: res.t}, nil
+	case spansql.BitShl, spansql.BitShr:
+		if !(lhs.t.is(int64Type) || lhs.t.is(bytesType)) || !rhs.t.is(int64Type) {
+			return exprInfo{}, noMatchingSignature(op, args)
+		}
+		return exprInfo{t: lhs.t}, nil
+	default:
+		res, ok := supertype(lhs, rhs)
+		if !ok || !(res.t.is(int64Type) || res.t.is(bytesType)) {
+			return exprInfo{}, noMatchingSignature(op, args)
+		}
+		return exprInfo{t: res.t}, nil
+	}
+}
+
+func (c *exprChecker) evalLogical(e spansql.LogicalOp) (exprInfo, error) {
+	op := map[spansql.LogicalOperator]string{spansql.And: "AND", spansql.Or: "OR", spansql.Not: "NOT"}[e.Op]
+	var args []exprInfo
+	for _, operand := range []spansql.BoolExpr{e.LHS, e.RHS} {
+		if operand == nil {
+			continue
+		}
+		info, err := c.eval(operand)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		args = append(args, info)
+	}
+	for _, arg := range args {
+		if !arg.t.is(boolType) {
+			return exprInfo{}, noMatchingSignature(op, args)
+		}
+	}
+	return exprInfo{t: boolType}, nil
+}
+
+var comparisonOperators = map[spansql.ComparisonOperator]string{
+	spansql.Lt: "<", spansql.Le: "<=", spansql.Gt: ">", spansql.Ge: ">=", spansql.Eq: "=", spansql.Ne: "!=",
+	spansql.Like: "LIKE", spansql.NotLike: "NOT LIKE", spansql.Between: "BETWEEN", spansql.NotBetween: "NOT BETWEEN",
+}
+
+func (c *exprChecker) evalComparison(e spansql.ComparisonOp) (exprInfo, error) {
+	op := comparisonOperators[e.Op]
+	operands := []spansql.Expr{e.LHS, e.RHS}
+	if e.RHS2 != nil {
+		operands = append(operands, e.RHS2)
+	}
+	args, err := c.evalAll(operands)
+	if err != nil {
+		return exprInfo{}, err
+	}
+	res, err := unify(op, args...)
+	if err != nil {
+		return exprInfo{}, err
+	}
+	switch {
+	case e.Op == spansql.Like || e.Op == spansql.NotLike:
+		if !res.t.is(stringType) && !res.t.is(bytesType) {
+			return exprInfo{}, noMatchingSignature(op, args)
+		}
+	case res.t.array || res.t == jsonType:
+		return exprInfo{}, noMatchingSignature(op, args)
+	}
+	return exprInfo{t: boolType}, nil
+}
+
+func (c *exprChecker) evalCase(e spansql.Case) (exprInfo, error) {
+	var value *exprInfo
+	if e.Expr != nil {
+		info, err := c.eval(e.Expr)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		value = &info
+	}
+	var results []exprInfo
+	for _, when := range e.WhenClauses {
+		cond, err := c.eval(when.Cond)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		if value != nil {
+			if _, err := unify("CASE", *value, cond); err != nil {
+				return exprInfo{}, err
+			}
+		} else if !cond.t.is(boolType) {
+			return exprInfo{}, noMatchingSignature("CASE", []exprInfo{cond})
+		}
+		result, err := c.eval(when.Result)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		results = append(results, result)
+	}
+	if e.ElseResult != nil {
+		result, err := c.eval(e.ElseResult)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		results = append(results, result)
+	}
+	return unifyResult("CASE", results)
+}
+
+// Position of the date part argument of the functions that take one.
+var datePartArgs = map[string]int{"DATE_DIFF": 2, "DATE_TRUNC": 1, "TIMESTAMP_DIFF": 2, "TIMESTAMP_TRUNC": 1}
+
+var dateParts = map[string]bool{
+	"NANOSECOND": true, "MICROSECOND": true, "MILLISECOND": true, "SECOND": true, "MINUTE": true, "HOUR": true, "DAY": true,
+	"DAYOFWEEK": true, "DAYOFYEAR": true, "WEEK": true, "ISOWEEK": true, "MONTH": true, "QUARTER": true, "YEAR": true, "ISOYEAR": true,
+}
+
+func (c *exprChecker) evalFunc(e spansql.Func) (exprInfo, error) {
+	name := strings.ToUpper(e.Name)
+	if aggregateFunctions[name] {
+		return exprInfo{}, fmt.Errorf("Aggregate function %s not allowed in expressions", name)
+	}
+	if name == "CAST" || name == "SAFE_CAST" {
+		te, ok := e.Args[0].(spansql.TypedExpr)
+		if len(e.Args) != 1 || !ok {
+			return exprInfo{}, fmt.Errorf("Syntax error: invalid %s", name)
+		}
+		arg, err := c.eval(te.Expr)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		to := typeFromSpansql(te.Type)
+		if !canCast(arg.t, to) {
+			return exprInfo{}, fmt.Errorf("Invalid cast from %s to %s", arg.t, to)
+		}
+		if arg.literal && name == "CAST" {
+			if err := checkStringLiteralCast(arg.str, to); err != nil {
+				return exprInfo{}, err
+			}
+		}
+		return exprInfo{t: to}, nil
+	}
+	sig, ok := functionCatalog[name]
+	if !ok {
+		return exprInfo{}, unverifiedf("function %s is not in the local catalog", e.Name)
+	}
+	var args []exprInfo
+	for i, arg := range e.Args {
+		if pos, ok := datePartArgs[name]; ok && pos == i {
+			if id, ok := arg.(spansql.ID); ok && dateParts[strings.ToUpper(string(id))] {
+				args = append(args, exprInfo{t: datePartType})
+				continue
+			}
+			return exprInfo{}, fmt.Errorf("A valid date part name is required but found %s", arg.SQL())
+		}
+		info, err := c.eval(arg)
+		if err != nil {
+			return exprInfo{}, err
+		}
+		args = append(args, info)
+	}
+	return sig.check(name, args)
+}
+
+// unify returns the common supertype of args, which are the operands of op.
+func unify(op string, args ...exprInfo) (exprInfo, error) {
+	res := args[0]
+	for _, arg := range args[1:] {
+		var ok bool
+		if res, ok = supertype(res, arg); !ok {
+			return exprInfo{}, noMatchingSignature(op, args)
+		}
+	}
+	for _, arg := range args {
+		if arg.literal && !res.t.is(stringType) {
+			if err := checkStringLiteralCast(arg.str, res.t); err != nil {
+				return exprInfo{}, fmt.Errorf("Could not cast literal %q to type %s", arg.str, res.t)
+			}
+		}
+	}
+	return res, nil
+}
+
+// unifyResult returns the type of an expression that evaluates to one of args.
+func unifyResult(name string, args []exprInfo) (exprInfo, error) {
+	if len(args) == 0 {
+		return exprInfo{t: nullType}, nil
+	}
+	res, err := unify(name, args...)
+	if err != nil {
+		return exprInfo{}, noMatchingFunctionSignature(name, args)
+	}
+	return exprInfo{t: res.t}, nil
+}
+
+func noMatchingSignature(op string, args []exprInfo) error {
+	return fmt.Errorf("No matching signature for operator %s for argument types: %s", op, argTypes(args))
+}
+
+func noMatchingFunctionSignature(name string, args []exprInfo) error {
+	return fmt.Errorf("No matching signature for function %s for argument types: %s", name, argTypes(args))
+}
+
+func argTypes(args []exprInfo) string {
+	var types []string
+	for _, arg := range args {
+		types = append(types, arg.t.String())
+	}
+	return strings.Join(types, ", ")
+}
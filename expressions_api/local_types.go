@@ -0,0 +1,210 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expressions_api
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner/spansql"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// sqlType is the type of a GoogleSQL expression. An empty name is the type
+// of an untyped NULL, which coerces to any other type.
+type sqlType struct {
+	name  string
+	array bool
+}
+
+func (t sqlType) String() string {
+	name := t.name
+	if name == "" {
+		name = "INT64" // Untyped NULL literals are reported as INT64 by Spanner.
+	}
+	if t.array {
+		return "ARRAY<" + name + ">"
+	}
+	return name
+}
+
+var (
+	nullType      = sqlType{}
+	boolType      = sqlType{name: ddl.Bool}
+	int64Type     = sqlType{name: ddl.Int64}
+	float32Type   = sqlType{name: ddl.Float32}
+	float64Type   = sqlType{name: ddl.Float64}
+	numericType   = sqlType{name: ddl.Numeric}
+	stringType    = sqlType{name: ddl.String}
+	bytesType     = sqlType{name: ddl.Bytes}
+	dateType      = sqlType{name: ddl.Date}
+	timestampType = sqlType{name: ddl.Timestamp}
+	jsonType      = sqlType{name: ddl.JSON}
+)
+
+func (t sqlType) isNull() bool    { return t.name == "" && !t.array }
+func (t sqlType) isNumeric() bool { return !t.array && numericRank[t.name] > 0 }
+func (t sqlType) is(u sqlType) bool {
+	return t.isNull() || t == u
+}
+
+// Ranks of the numeric types, used to find the supertype of two numeric types.
+var numericRank = map[string]int{ddl.Int64: 1, ddl.Numeric: 2, ddl.Float32: 3, ddl.Float64: 4}
+
+// typeFromDDL returns the type of a column of the Spanner schema.
+func typeFromDDL(t ddl.Type) sqlType {
+	name := t.Name
+	if standard, ok := ddl.PGSQL_TO_STANDARD_TYPE_TYPEMAP[name]; ok {
+		name = standard
+	}
+	return sqlType{name: name, array: t.IsArray}
+}
+
+// typeFromName parses a type name such as "INT64" or "ARRAY<STRING(MAX)>",
+// which is how the target type of a default value is passed in
+// ReferenceElement.Name.
+func typeFromName(name string) (sqlType, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	array := false
+	if strings.HasPrefix(name, "ARRAY<") && strings.HasSuffix(name, ">") {
+		array = true
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "ARRAY<"), ">")
+	}
+	if i := strings.Index(name, "("); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	if standard, ok := ddl.PGSQL_TO_STANDARD_TYPE_TYPEMAP[name]; ok {
+		name = standard
+	}
+	switch name {
+	case ddl.Bool, ddl.Int64, ddl.Float32, ddl.Float64, ddl.Numeric, ddl.String, ddl.Bytes, ddl.Date, ddl.Timestamp, ddl.JSON:
+		return sqlType{name: name, array: array}, true
+	}
+	return sqlType{}, false
+}
+
+// typeFromSpansql converts the target type of a CAST.
+func typeFromSpansql(t spansql.Type) sqlType {
+	names := map[spansql.TypeBase]string{
+		spansql.Bool: ddl.Bool, spansql.Int64: ddl.Int64, spansql.Float64: ddl.Float64, spansql.Numeric: ddl.Numeric,
+		spansql.String: ddl.String, spansql.Bytes: ddl.Bytes, spansql.Date: ddl.Date, spansql.Timestamp: ddl.Timestamp, spansql.JSON: ddl.JSON,
+	}
+	return sqlType{name: names[t.Base], array: t.Array}
+}
+
+// supertype returns the common supertype of a and b, following the GoogleSQL
+// coercion rules: NULL coerces to any type, INT64 and NUMERIC coerce to
+// floating point types, and string literals coerce to DATE and TIMESTAMP.
+func supertype(a, b exprInfo) (exprInfo, bool) {
+	switch {
+	case a.t.isNull():
+		return b, true
+	case b.t.isNull():
+		return a, true
+	case a.t == b.t:
+		return exprInfo{t: a.t, literal: a.literal && b.literal}, true
+	case a.t.isNumeric() && b.t.isNumeric():
+		if a.t.name == ddl.Float32 && b.t.name == ddl.Float32 {
+			return exprInfo{t: float32Type}, true
+		}
+		if numericRank[a.t.name] >= numericRank[ddl.Float32] || numericRank[b.t.name] >= numericRank[ddl.Float32] {
+			return exprInfo{t: float64Type}, true
+		}
+		return exprInfo{t: numericType}, true
+	case a.literal && a.t == stringType && (b.t == dateType || b.t == timestampType):
+		return exprInfo{t: b.t}, true
+	case b.literal && b.t == stringType && (a.t == dateType || a.t == timestampType):
+		return exprInfo{t: a.t}, true
+	}
+	return exprInfo{}, false
+}
+
+// canCast reports whether a value of type from can be cast to type to.
+func canCast(from, to sqlType) bool {
+	if from.isNull() || from == to {
+		return true
+	}
+	if from.array != to.array {
+		return false
+	}
+	if from.array {
+		return canCast(sqlType{name: from.name}, sqlType{name: to.name})
+	}
+	switch from.name {
+	case ddl.Int64:
+		return to.isNumeric() || to.name == ddl.Bool || to.name == ddl.String
+	case ddl.Float32, ddl.Float64, ddl.Numeric:
+		return to.isNumeric() || to.name == ddl.String
+	case ddl.Bool:
+		return to.name == ddl.Int64 || to.name == ddl.String
+	case ddl.String:
+		return to.name != ddl.JSON
+	case ddl.Bytes:
+		return to.name == ddl.String
+	case ddl.Date:
+		return to.name == ddl.String || to.name == ddl.Timestamp
+	case ddl.Timestamp:
+		return to.name == ddl.String || to.name == ddl.Date
+	}
+	return false
+}
+
+// checkStringLiteralCast verifies that the value of a string literal can be
+// converted to type to, since Spanner rejects such casts when evaluating them.
+func checkStringLiteralCast(s string, to sqlType) error {
+	if to.array {
+		return nil
+	}
+	var err error
+	switch to.name {
+	case ddl.Int64:
+		_, err = strconv.ParseInt(strings.TrimSpace(s), 0, 64)
+	case ddl.Float32, ddl.Float64:
+		_, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case ddl.Numeric:
+		if _, ok := new(big.Rat).SetString(strings.TrimSpace(s)); !ok {
+			err = fmt.Errorf("invalid numeric")
+		}
+	case ddl.Bool:
+		if !strings.EqualFold(s, "true") && !strings.EqualFold(s, "false") {
+			err = fmt.Errorf("invalid bool")
+		}
+	case ddl.Date:
+		_, err = civil.ParseDate(s)
+	case ddl.Timestamp:
+		err = parseTimestampLiteral(s)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Bad %s value: %s", strings.ToLower(to.name), s)
+	}
+	return nil
+}
+
+func parseTimestampLiteral(s string) error {
+	layouts := []string{"2006-01-02", "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04:05Z07:00", "2006-01-02T15:04:05Z07:00",
+		"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999Z07:00", time.RFC3339Nano}
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid timestamp")
+}
@@ -0,0 +1,233 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expressions_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/spanner/spansql"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// NewExpressionVerificationAccessor returns the local expression verifier when
+// offline is set, and the Spanner backed one otherwise.
+func NewExpressionVerificationAccessor(ctx context.Context, project string, instance string, offline bool) (ExpressionVerificationAccessor, error) {
+	if offline {
+		return &LocalExpressionVerificationAccessorImpl{}, nil
+	}
+	return NewExpressionVerificationAccessorImpl(ctx, project, instance)
+}
+
+// ErrUnverified is wrapped by the errors of the expressions that the local
+// verifier can neither accept nor reject, e.g. because they call functions
+// missing from its catalog.
+var ErrUnverified = errors.New("expression can't be verified offline")
+
+func unverifiedf(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUnverified, fmt.Sprintf(format, a...))
+}
+
+// LocalExpressionVerificationAccessorImpl verifies check constraints and
+// default values without connecting to Spanner. Expressions are parsed with a
+// Spanner dialect SQL parser and type checked against the column types of
+// conv.SpSchema and a catalog of the functions supported by Spanner.
+//
+// The errors returned mirror the ones returned by Spanner, so that callers can
+// classify them in the same way. Since the catalog only covers the commonly
+// used functions, expressions using other functions are unverified: they are
+// accepted, with an error wrapping ErrUnverified, and are only verified by
+// Spanner when the schema is created.
+type LocalExpressionVerificationAccessorImpl struct{}
+
+func (ev *LocalExpressionVerificationAccessorImpl) VerifyExpressions(ctx context.Context, verifyExpressionsInput internal.VerifyExpressionsInput) internal.VerifyExpressionsOutput {
+	err := validateVerifyExpressionsInput(verifyExpressionsInput)
+	if err != nil {
+		return internal.VerifyExpressionsOutput{Err: err}
+	}
+	var verifyExpressionsOutput internal.VerifyExpressionsOutput
+	var errorCount int16 = 0
+	for _, expressionDetail := range verifyExpressionsInput.ExpressionDetailList {
+		err := verifyExpressionLocally(verifyExpressionsInput.Conv, expressionDetail)
+		unverified := errors.Is(err, ErrUnverified)
+		verifyExpressionsOutput.ExpressionVerificationOutputList = append(verifyExpressionsOutput.ExpressionVerificationOutputList, internal.ExpressionVerificationOutput{Result: err == nil || unverified, Err: err, ExpressionDetail: expressionDetail})
+		if err != nil && !unverified {
+			errorCount++
+		}
+	}
+	if errorCount != 0 {
+		verifyExpressionsOutput.Err = fmt.Errorf("%d expressions either failed verification or did not get verified. Please look at the individual errors returned for each expression", errorCount)
+	}
+	return verifyExpressionsOutput
+}
+
+// RefreshSpannerClient is a no-op since no Spanner client is used.
+func (ev *LocalExpressionVerificationAccessorImpl) RefreshSpannerClient(ctx context.Context, project string, instance string) error {
+	return nil
+}
+
+func verifyExpressionLocally(conv *internal.Conv, expressionDetail internal.ExpressionDetail) error {
+	if conv.SpDialect == constants.DIALECT_POSTGRESQL {
+		return verifyPGExpression(conv, expressionDetail)
+	}
+	if err := checkFunctionNames(expressionDetail.Expression); err != nil {
+		return err
+	}
+	switch expressionDetail.Type {
+	case constants.CHECK_EXPRESSION:
+		table, ok := findTable(conv, expressionDetail)
+		if !ok {
+			return fmt.Errorf("Table not found: %s", expressionDetail.ReferenceElement.Name)
+		}
+		q, err := spansql.ParseQuery(fmt.Sprintf("SELECT 1 FROM `%s` WHERE %s", table.Name, expressionDetail.Expression))
+		if err != nil {
+			return fmt.Errorf("Syntax error: %v", err)
+		}
+		if q.Select.Where == nil || len(q.Select.GroupBy) > 0 || len(q.Order) > 0 || q.Limit != nil || q.Offset != nil {
+			return fmt.Errorf("Syntax error: unexpected clause in expression %q", expressionDetail.Expression)
+		}
+		c := newExprChecker(conv, &table)
+		info, err := c.eval(q.Select.Where)
+		if err != nil {
+			return err
+		}
+		if !info.t.is(boolType) {
+			return fmt.Errorf("WHERE clause should return type BOOL, but returns %s", info.t)
+		}
+		return nil
+	case constants.DEFAULT_EXPRESSION:
+		q, err := spansql.ParseQuery("SELECT " + expressionDetail.Expression)
+		if err != nil {
+			return fmt.Errorf("Syntax error: %v", err)
+		}
+		if len(q.Select.List) != 1 || len(q.Select.From) > 0 || q.Select.Where != nil || len(q.Select.GroupBy) > 0 || len(q.Order) > 0 || q.Limit != nil || q.Offset != nil || (len(q.Select.ListAliases) > 0 && q.Select.ListAliases[0] != "") {
+			return fmt.Errorf("Syntax error: unexpected clause in expression %q", expressionDetail.Expression)
+		}
+		c := newExprChecker(conv, nil)
+		info, err := c.eval(q.Select.List[0])
+		if err != nil {
+			return err
+		}
+		to, ok := typeFromName(expressionDetail.ReferenceElement.Name)
+		if !ok {
+			return nil
+		}
+		if !canCast(info.t, to) {
+			return fmt.Errorf("Invalid cast from %s to %s", info.t, to)
+		}
+		if info.literal {
+			return checkStringLiteralCast(info.str, to)
+		}
+		return nil
+	case constants.GENERATED_EXPRESSION:
+		table, ok := findTable(conv, expressionDetail)
+		if !ok {
+			return fmt.Errorf("Table not found: %s", expressionDetail.ReferenceElement.Name)
+		}
+		q, err := spansql.ParseQuery(fmt.Sprintf("SELECT %s FROM `%s`", expressionDetail.Expression, table.Name))
+		if err != nil {
+			return fmt.Errorf("Syntax error: %v", err)
+		}
+		if len(q.Select.List) != 1 || q.Select.Where != nil || len(q.Select.GroupBy) > 0 || len(q.Order) > 0 || q.Limit != nil || q.Offset != nil || (len(q.Select.ListAliases) > 0 && q.Select.ListAliases[0] != "") {
+			return fmt.Errorf("Syntax error: unexpected clause in expression %q", expressionDetail.Expression)
+		}
+		c := newExprChecker(conv, &table)
+		info, err := c.eval(q.Select.List[0])
+		if err != nil {
+			return err
+		}
+		col, ok := table.ColDefs[expressionDetail.Metadata["colId"]]
+		if !ok {
+			return nil
+		}
+		if to := typeFromDDL(col.T); !canCast(info.t, to) {
+			return fmt.Errorf("Invalid cast from %s to %s", info.t, to)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid expression type requested")
+	}
+}
+
+// findTable returns the table a check constraint or generated column is
+// defined on, looking it up
+// by the tableId metadata first and by name otherwise.
+func findTable(conv *internal.Conv, expressionDetail internal.ExpressionDetail) (ddl.CreateTable, bool) {
+	if table, ok := conv.SpSchema[expressionDetail.Metadata["tableId"]]; ok {
+		return table, true
+	}
+	for _, table := range conv.SpSchema {
+		if strings.EqualFold(table.Name, expressionDetail.ReferenceElement.Name) {
+			return table, true
+		}
+	}
+	return ddl.CreateTable{}, false
+}
+
+// Names that may be followed by a parenthesis without being function calls,
+// or that are parsed as special forms rather than catalog functions.
+var specialForms = map[string]bool{
+	"CAST": true, "SAFE_CAST": true, "EXTRACT": true, "COALESCE": true, "IFNULL": true, "NULLIF": true, "SEQUENCE": true,
+}
+
+// checkFunctionNames reports the first function called by expr which is not
+// in the catalog. The parser rejects unknown functions with a generic error,
+// so they are detected up front to report the expression as unverified.
+func checkFunctionNames(expr string) error {
+	for i := 0; i < len(expr); i++ {
+		switch ch := expr[i]; {
+		case ch == '\'' || ch == '"' || ch == '`':
+			// Skip quoted strings and identifiers.
+			for i++; i < len(expr) && expr[i] != ch; i++ {
+				if expr[i] == '\\' {
+					i++
+				}
+			}
+		case ch == '_' || isLetter(ch):
+			start := i
+			for i < len(expr) && (expr[i] == '_' || isLetter(expr[i]) || (expr[i] >= '0' && expr[i] <= '9')) {
+				i++
+			}
+			name := expr[start:i]
+			j := i
+			for j < len(expr) && (expr[j] == ' ' || expr[j] == '\t' || expr[j] == '\n') {
+				j++
+			}
+			i--
+			if j == len(expr) || expr[j] != '(' || (start > 0 && expr[start-1] == '.') {
+				continue
+			}
+			upper := strings.ToUpper(name)
+			if _, ok := functionCatalog[upper]; ok || aggregateFunctions[upper] || specialForms[upper] || spansql.IsKeyword(upper) {
+				continue
+			}
+			return unverifiedf("function %s is not in the local catalog", name)
+		case ch >= '0' && ch <= '9':
+			// Skip numbers so that exponents such as 1e5 aren't read as names.
+			for i+1 < len(expr) && (isLetter(expr[i+1]) || (expr[i+1] >= '0' && expr[i+1] <= '9') || expr[i+1] == '.') {
+				i++
+			}
+		}
+	}
+	return nil
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
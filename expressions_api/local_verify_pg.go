@@ -0,0 +1,138 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expressions_api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+// pgFunctions are the PostgreSQL functions supported by Spanner in check
+// constraints and default values. Functions qualified with the spanner and
+// pg_catalog schemas are accepted as well.
+// https://cloud.google.com/spanner/docs/reference/postgresql/functions-and-operators
+var pgFunctions = map[string]bool{
+	"abs": true, "ceil": true, "ceiling": true, "div": true, "exp": true, "floor": true, "ln": true, "log": true, "mod": true,
+	"power": true, "round": true, "sign": true, "sqrt": true, "trunc": true, "acos": true, "asin": true, "atan": true, "atan2": true,
+	"cos": true, "sin": true, "tan": true,
+	"btrim": true, "char_length": true, "character_length": true, "concat": true, "left": true, "length": true, "lower": true,
+	"lpad": true, "ltrim": true, "octet_length": true, "regexp_match": true, "regexp_replace": true, "regexp_split_to_array": true,
+	"repeat": true, "replace": true, "reverse": true, "right": true, "rpad": true, "rtrim": true, "split_part": true,
+	"starts_with": true, "strpos": true, "substr": true, "substring": true, "textregexne": true, "to_char": true, "to_hex": true,
+	"upper": true, "md5": true, "sha256": true, "sha512": true, "encode": true, "decode": true, "quote_ident": true,
+	"array_cat": true, "array_length": true, "array_to_string": true, "array_upper": true,
+	"current_date": true, "current_timestamp": true, "date_part": true, "date_trunc": true, "extract": true, "make_date": true,
+	"now": true, "to_date": true, "to_number": true, "to_timestamp": true, "timezone": true,
+	"jsonb_array_elements": true, "jsonb_build_array": true, "jsonb_build_object": true, "jsonb_typeof": true, "to_jsonb": true,
+	"nextval": true, "gen_random_uuid": true,
+}
+
+// verifyPGExpression verifies an expression of a PostgreSQL dialect database.
+// Expressions are parsed and their column references and function calls are
+// resolved, but they aren't type checked.
+func verifyPGExpression(conv *internal.Conv, expressionDetail internal.ExpressionDetail) error {
+	var sql string
+	columns := map[string]bool{}
+	switch expressionDetail.Type {
+	case constants.CHECK_EXPRESSION, constants.GENERATED_EXPRESSION:
+		table, ok := findTable(conv, expressionDetail)
+		if !ok {
+			return fmt.Errorf("relation %q does not exist", expressionDetail.ReferenceElement.Name)
+		}
+		for _, col := range table.ColDefs {
+			columns[strings.ToLower(col.Name)] = true
+		}
+		columns[strings.ToLower(table.Name)] = true
+		if expressionDetail.Type == constants.GENERATED_EXPRESSION {
+			sql = fmt.Sprintf("SELECT %s FROM %q", expressionDetail.Expression, table.Name)
+		} else {
+			sql = fmt.Sprintf("SELECT 1 FROM %q WHERE %s", table.Name, expressionDetail.Expression)
+		}
+	case constants.DEFAULT_EXPRESSION:
+		sql = "SELECT " + expressionDetail.Expression
+	default:
+		return fmt.Errorf("invalid expression type requested")
+	}
+	tree, err := pg_query.ParseToJSON(sql)
+	if err != nil {
+		return err
+	}
+	var node interface{}
+	if err := json.Unmarshal([]byte(tree), &node); err != nil {
+		return err
+	}
+	return checkPGNode(node, columns)
+}
+
+// checkPGNode walks the JSON parse tree of an expression and reports the first
+// unknown column. Calls to functions missing from pgFunctions make the
+// expression unverified, unless it refers to an unknown column.
+func checkPGNode(node interface{}, columns map[string]bool) error {
+	var unverified error
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := n["ColumnRef"].(map[string]interface{}); ok {
+			names := pgNames(ref["fields"])
+			if len(names) > 0 && !columns[strings.ToLower(names[len(names)-1])] {
+				return fmt.Errorf("column %q does not exist", strings.Join(names, "."))
+			}
+		}
+		if call, ok := n["FuncCall"].(map[string]interface{}); ok {
+			names := pgNames(call["funcname"])
+			qualified := len(names) > 1 && (names[0] == "spanner" || names[0] == "pg_catalog")
+			if len(names) > 0 && !qualified && !pgFunctions[strings.ToLower(names[len(names)-1])] {
+				unverified = unverifiedf("function %s is not in the local catalog", strings.Join(names, "."))
+			}
+		}
+		for _, child := range n {
+			if err := checkPGNode(child, columns); err != nil {
+				if !errors.Is(err, ErrUnverified) {
+					return err
+				}
+				unverified = err
+			}
+		}
+	case []interface{}:
+		for _, child := range n {
+			if err := checkPGNode(child, columns); err != nil {
+				if !errors.Is(err, ErrUnverified) {
+					return err
+				}
+				unverified = err
+			}
+		}
+	}
+	return unverified
+}
+
+// pgNames returns the names of a list of String nodes.
+func pgNames(node interface{}) []string {
+	var names []string
+	list, _ := node.([]interface{})
+	for _, item := range list {
+		m, _ := item.(map[string]interface{})
+		s, _ := m["String"].(map[string]interface{})
+		if name, ok := s["sval"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
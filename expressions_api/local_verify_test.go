@@ -0,0 +1,128 @@
+package expressions_api_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/expressions_api"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+)
+
+func localVerifyConv(dialect string) *internal.Conv {
+	conv := internal.MakeConv()
+	conv.SpDialect = dialect
+	conv.SpSchema = map[string]ddl.CreateTable{
+		"t1": {
+			Name:   "Books",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2", "c3", "c4", "c5"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "id", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+				"c2": {Name: "title", Id: "c2", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"c3": {Name: "price", Id: "c3", T: ddl.Type{Name: ddl.Numeric}},
+				"c4": {Name: "published", Id: "c4", T: ddl.Type{Name: ddl.Date}},
+				"c5": {Name: "tags", Id: "c5", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength, IsArray: true}},
+			},
+		},
+	}
+	conv.SpSequences = map[string]ddl.Sequence{"s1": {Name: "BookSeq", Id: "s1"}}
+	return conv
+}
+
+func TestLocalVerifyExpressions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		dialect     string
+		expr        string
+		exprType    string
+		refName     string
+		colId       string
+		expectedErr string
+		unverified  bool
+	}{
+		{name: "valid comparison", expr: "id > 10", exprType: constants.CHECK_EXPRESSION, refName: "Books"},
+		{name: "valid function and logical operators", expr: "(UPPER(title) != 'X' AND price BETWEEN 0 AND 100.5) OR id IN (1, 2)", exprType: constants.CHECK_EXPRESSION, refName: "Books"},
+		{name: "valid date comparison with string literal", expr: "published >= '2000-01-01' AND DATE_DIFF(published, DATE '1999-01-01', DAY) > 0", exprType: constants.CHECK_EXPRESSION, refName: "Books"},
+		{name: "valid array function", expr: "ARRAY_LENGTH(tags) < 5", exprType: constants.CHECK_EXPRESSION, refName: "Books"},
+		{name: "type mismatch", expr: "title > 10", exprType: constants.CHECK_EXPRESSION, refName: "Books", expectedErr: "No matching signature for operator > for argument types: STRING, INT64"},
+		{name: "unknown column", expr: "pages > 10", exprType: constants.CHECK_EXPRESSION, refName: "Books", expectedErr: "Unrecognized name: pages"},
+		{name: "unknown function", expr: "foo(id) > 10", exprType: constants.CHECK_EXPRESSION, refName: "Books", expectedErr: "function foo is not in the local catalog", unverified: true},
+		{name: "syntax error", expr: "id >", exprType: constants.CHECK_EXPRESSION, refName: "Books", expectedErr: "Syntax error"},
+		{name: "non bool check", expr: "title", exprType: constants.CHECK_EXPRESSION, refName: "Books", expectedErr: "WHERE clause should return type BOOL, but returns STRING"},
+		{name: "bad date literal", expr: "published > '2000-13-01'", exprType: constants.CHECK_EXPRESSION, refName: "Books", expectedErr: "Could not cast literal"},
+		{name: "valid default", expr: "1 + 2", exprType: constants.DEFAULT_EXPRESSION, refName: "FLOAT64"},
+		{name: "valid default function", expr: "CURRENT_TIMESTAMP()", exprType: constants.DEFAULT_EXPRESSION, refName: "TIMESTAMP"},
+		{name: "valid default sequence", expr: "GET_NEXT_SEQUENCE_VALUE(SEQUENCE BookSeq)", exprType: constants.DEFAULT_EXPRESSION, refName: "INT64"},
+		{name: "valid default string literal", expr: "'42'", exprType: constants.DEFAULT_EXPRESSION, refName: "INT64"},
+		{name: "bad default string literal", expr: "'abc'", exprType: constants.DEFAULT_EXPRESSION, refName: "INT64", expectedErr: "Bad int64 value: abc"},
+		{name: "invalid default cast", expr: "DATE '2020-01-01'", exprType: constants.DEFAULT_EXPRESSION, refName: "INT64", expectedErr: "Invalid cast from DATE to INT64"},
+		{name: "default referencing column", expr: "id", exprType: constants.DEFAULT_EXPRESSION, refName: "INT64", expectedErr: "Unrecognized name: id"},
+		{name: "unknown sequence", expr: "GET_NEXT_SEQUENCE_VALUE(SEQUENCE OtherSeq)", exprType: constants.DEFAULT_EXPRESSION, refName: "INT64", expectedErr: "Sequence not found: OtherSeq"},
+		{name: "valid generated", expr: "CONCAT(UPPER(title), '-', CAST(id AS STRING))", exprType: constants.GENERATED_EXPRESSION, refName: "Books", colId: "c2"},
+		{name: "generated type mismatch", expr: "published", exprType: constants.GENERATED_EXPRESSION, refName: "Books", colId: "c1", expectedErr: "Invalid cast from DATE to INT64"},
+		{name: "generated unknown column", expr: "pages * 2", exprType: constants.GENERATED_EXPRESSION, refName: "Books", colId: "c1", expectedErr: "Unrecognized name: pages"},
+		{name: "generated with clause", expr: "id FROM Books", exprType: constants.GENERATED_EXPRESSION, refName: "Books", colId: "c1", expectedErr: "Syntax error"},
+		{name: "postgres valid check", dialect: constants.DIALECT_POSTGRESQL, expr: "upper(title) <> 'X' AND id > 0", exprType: constants.CHECK_EXPRESSION, refName: "Books"},
+		{name: "postgres unknown column", dialect: constants.DIALECT_POSTGRESQL, expr: "pages > 0", exprType: constants.CHECK_EXPRESSION, refName: "Books", expectedErr: `column "pages" does not exist`},
+		{name: "postgres syntax error", dialect: constants.DIALECT_POSTGRESQL, expr: "id > > 0", exprType: constants.CHECK_EXPRESSION, refName: "Books", expectedErr: "syntax error at or near"},
+		{name: "postgres valid generated", dialect: constants.DIALECT_POSTGRESQL, expr: "price * 2", exprType: constants.GENERATED_EXPRESSION, refName: "Books", colId: "c3"},
+		{name: "postgres generated unknown column", dialect: constants.DIALECT_POSTGRESQL, expr: "pages * 2", exprType: constants.GENERATED_EXPRESSION, refName: "Books", colId: "c3", expectedErr: `column "pages" does not exist`},
+		{name: "postgres unknown function", dialect: constants.DIALECT_POSTGRESQL, expr: "foo()", exprType: constants.DEFAULT_EXPRESSION, refName: "int8", expectedErr: "function foo is not in the local catalog", unverified: true},
+		{name: "postgres unknown function and column", dialect: constants.DIALECT_POSTGRESQL, expr: "foo(pages) > 0", exprType: constants.CHECK_EXPRESSION, refName: "Books", expectedErr: `column "pages" does not exist`},
+	}
+	ev := &expressions_api.LocalExpressionVerificationAccessorImpl{}
+	for _, tc := range testCases {
+		input := internal.VerifyExpressionsInput{
+			Conv:   localVerifyConv(tc.dialect),
+			Source: constants.MYSQL,
+			ExpressionDetailList: []internal.ExpressionDetail{
+				{
+					Expression:       tc.expr,
+					Type:             tc.exprType,
+					ReferenceElement: internal.ReferenceElement{Name: tc.refName},
+					ExpressionId:     "e1",
+					Metadata:         map[string]string{"tableId": "t1", "colId": tc.colId},
+				},
+			},
+		}
+		output := ev.VerifyExpressions(context.Background(), input)
+		assert.Equal(t, 1, len(output.ExpressionVerificationOutputList), tc.name)
+		result := output.ExpressionVerificationOutputList[0]
+		switch {
+		case tc.expectedErr == "":
+			assert.Nil(t, output.Err, tc.name)
+			assert.Nil(t, result.Err, tc.name)
+			assert.True(t, result.Result, tc.name)
+		case tc.unverified:
+			// Unverified expressions are accepted.
+			assert.Nil(t, output.Err, tc.name)
+			assert.True(t, errors.Is(result.Err, expressions_api.ErrUnverified), tc.name)
+			assert.True(t, strings.Contains(result.Err.Error(), tc.expectedErr), "%s: got %q", tc.name, result.Err.Error())
+			assert.True(t, result.Result, tc.name)
+		default:
+			assert.NotNil(t, output.Err, tc.name)
+			if assert.NotNil(t, result.Err, tc.name) {
+				assert.True(t, strings.Contains(result.Err.Error(), tc.expectedErr), "%s: got %q", tc.name, result.Err.Error())
+			}
+			assert.False(t, result.Result, tc.name)
+		}
+	}
+}
+
+func TestLocalVerifyExpressionsInvalidInput(t *testing.T) {
+	ev := &expressions_api.LocalExpressionVerificationAccessorImpl{}
+	output := ev.VerifyExpressions(context.Background(), internal.VerifyExpressionsInput{Source: constants.MYSQL})
+	assert.NotNil(t, output.Err)
+	assert.Nil(t, output.ExpressionVerificationOutputList)
+}
+
+func TestNewExpressionVerificationAccessorOffline(t *testing.T) {
+	ev, err := expressions_api.NewExpressionVerificationAccessor(context.Background(), "", "", true)
+	assert.Nil(t, err)
+	assert.IsType(t, &expressions_api.LocalExpressionVerificationAccessorImpl{}, ev)
+}
@@ -0,0 +1,354 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"go.uber.org/zap"
+)
+
+// checkpointSaveInterval is the minimum time between two writes of the
+// checkpoint file while a table is in progress. The file is always written
+// when a table completes.
+const checkpointSaveInterval = 10 * time.Second
+
+// CheckpointStatus is the migration status of a table in a checkpoint.
+type CheckpointStatus string
+
+const (
+	CheckpointInProgress CheckpointStatus = "IN_PROGRESS"
+	CheckpointComplete   CheckpointStatus = "COMPLETE"
+)
+
+// TableCheckpoint records how far bulk data migration got for a table.
+type TableCheckpoint struct {
+	Status       CheckpointStatus
+	KeyCols      []string  // Source primary key columns the table is read in order of. Empty if the table can't be resumed by key.
+	LastKey      []string  // Source primary key of the last row known to be committed to Spanner.
+	WrittenRows  int64     // Rows committed to Spanner (written or dropped) up to LastKey.
+	GoodRows     int64     // Rows successfully converted, recorded when the table completes.
+	BadRows      int64     // Rows where conversion failed, recorded when the table completes.
+	FilteredRows int64     // Rows skipped by the row filter of the table, recorded when the table completes.
+	UpdatedAt    time.Time // Last time this entry was updated.
+
+	goodRowsAtStart     int64
+	badRowsAtStart      int64
+	filteredRowsAtStart int64
+}
+
+// Checkpoint tracks per-table progress of a bulk data migration so that
+// an interrupted run can be resumed. Tables are keyed by Spanner table name
+// (prefixed with the shard id for sharded migrations), since table ids are
+// regenerated every time schema conversion runs.
+type Checkpoint struct {
+	Tables   map[string]*TableCheckpoint
+	path     string
+	shardId  string
+	lastSave time.Time
+	lock     sync.Mutex
+}
+
+// NewCheckpoint returns an empty checkpoint that is persisted to path.
+func NewCheckpoint(path string) *Checkpoint {
+	return &Checkpoint{Tables: make(map[string]*TableCheckpoint), path: path}
+}
+
+// LoadCheckpoint reads the checkpoint persisted at path. If the file doesn't
+// exist, an empty checkpoint is returned.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	cp := NewCheckpoint(path)
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read checkpoint file %s: %v", path, err)
+	}
+	if err := json.Unmarshal(b, cp); err != nil {
+		return nil, fmt.Errorf("can't parse checkpoint file %s: %v", path, err)
+	}
+	if cp.Tables == nil {
+		cp.Tables = make(map[string]*TableCheckpoint)
+	}
+	return cp, nil
+}
+
+// Save writes the checkpoint to its file.
+func (cp *Checkpoint) Save() error {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	return cp.save()
+}
+
+func (cp *Checkpoint) save() error {
+	b, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename, so that a crash mid-write never
+	// leaves a truncated checkpoint behind.
+	tmp := cp.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return fmt.Errorf("can't write checkpoint file %s: %v", tmp, err)
+	}
+	if err := os.Rename(tmp, cp.path); err != nil {
+		return fmt.Errorf("can't write checkpoint file %s: %v", cp.path, err)
+	}
+	cp.lastSave = time.Now()
+	return nil
+}
+
+// SetShard sets the data shard that subsequent calls refer to. It must be
+// called before processing the tables of each shard in sharded migrations.
+func (cp *Checkpoint) SetShard(shardId string) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	cp.shardId = shardId
+}
+
+func (cp *Checkpoint) key(table string) string {
+	if cp.shardId == "" {
+		return table
+	}
+	return cp.shardId + "/" + table
+}
+
+// Get returns the checkpoint entry for the Spanner table, if there is one.
+func (cp *Checkpoint) Get(table string) (TableCheckpoint, bool) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	tc, ok := cp.Tables[cp.key(table)]
+	if !ok {
+		return TableCheckpoint{}, false
+	}
+	return *tc, true
+}
+
+// IsComplete returns true if all data of the Spanner table was migrated.
+func (cp *Checkpoint) IsComplete(table string) bool {
+	tc, ok := cp.Get(table)
+	return ok && tc.Status == CheckpointComplete
+}
+
+// ResumeKey returns the source key columns and the key of the last row
+// committed for a partially migrated Spanner table. ok is false if the
+// table must be read from the beginning.
+func (cp *Checkpoint) ResumeKey(table string) (cols []string, key []string, ok bool) {
+	tc, found := cp.Get(table)
+	if !found || tc.Status != CheckpointInProgress || len(tc.KeyCols) == 0 || len(tc.LastKey) != len(tc.KeyCols) {
+		return nil, nil, false
+	}
+	return tc.KeyCols, tc.LastKey, true
+}
+
+// MarkInProgress records that migration of the Spanner table has started,
+// or resumed if a previous run recorded a key to resume from.
+func (cp *Checkpoint) MarkInProgress(conv *Conv, tableId string) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	srcTable := conv.SrcSchema[tableId].Name
+	k := cp.key(conv.SpSchema[tableId].Name)
+	keyCols := CheckpointKeyColumns(conv, tableId)
+	tc, ok := cp.Tables[k]
+	if ok && tc.Status == CheckpointInProgress && len(keyCols) != 0 && sameCols(tc.KeyCols, keyCols) && len(tc.LastKey) == len(keyCols) {
+		// Resuming from LastKey: rows committed by the previous run count
+		// as good rows of this one.
+		tc.GoodRows = tc.WrittenRows
+		conv.Stats.GoodRows[srcTable] += tc.WrittenRows
+	} else {
+		// Either a fresh start, or the table has to be read from the
+		// beginning since there is no usable position to resume from.
+		tc = &TableCheckpoint{}
+		cp.Tables[k] = tc
+	}
+	tc.Status = CheckpointInProgress
+	tc.UpdatedAt = time.Now()
+	tc.goodRowsAtStart = conv.Stats.GoodRows[srcTable]
+	tc.badRowsAtStart = conv.Stats.BadRows[srcTable]
+	tc.filteredRowsAtStart = conv.Stats.FilteredRows[srcTable]
+	if err := cp.save(); err != nil {
+		logger.Log.Warn("Couldn't save checkpoint", zap.Error(err))
+	}
+}
+
+// SetKeyCols records that the rows of the Spanner table are read in order of
+// the source key columns keyCols, so that the key of committed rows can be
+// used to resume. Sources that don't read rows in key order never call it,
+// and their tables are migrated from the beginning when resuming.
+func (cp *Checkpoint) SetKeyCols(table string, keyCols []string) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	if tc, ok := cp.Tables[cp.key(table)]; ok {
+		tc.KeyCols = keyCols
+	}
+}
+
+// MarkComplete records that all data of the Spanner table was migrated,
+// along with the row stats collected for it during this run.
+func (cp *Checkpoint) MarkComplete(conv *Conv, tableId string) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	srcTable := conv.SrcSchema[tableId].Name
+	tc, ok := cp.Tables[cp.key(conv.SpSchema[tableId].Name)]
+	if !ok {
+		return
+	}
+	tc.Status = CheckpointComplete
+	tc.UpdatedAt = time.Now()
+	tc.GoodRows += conv.Stats.GoodRows[srcTable] - tc.goodRowsAtStart
+	tc.BadRows += conv.Stats.BadRows[srcTable] - tc.badRowsAtStart
+	tc.FilteredRows += conv.Stats.FilteredRows[srcTable] - tc.filteredRowsAtStart
+	if err := cp.save(); err != nil {
+		logger.Log.Warn("Couldn't save checkpoint", zap.Error(err))
+	}
+}
+
+// RestoreStats adds the row stats recorded for a completed Spanner table to
+// conv, so that the report of a resumed run covers skipped tables.
+func (cp *Checkpoint) RestoreStats(conv *Conv, tableId string) {
+	tc, ok := cp.Get(conv.SpSchema[tableId].Name)
+	if !ok {
+		return
+	}
+	srcTable := conv.SrcSchema[tableId].Name
+	conv.Stats.GoodRows[srcTable] += tc.GoodRows
+	conv.Stats.BadRows[srcTable] += tc.BadRows
+	conv.Stats.FilteredRows[srcTable] += tc.FilteredRows
+}
+
+// RecordCommit records that n more rows of the table were committed to
+// Spanner, the last of which is given as Spanner columns and values. It is
+// meant to be called from BatchWriter's OnCommit callback, which guarantees
+// that all rows before it were committed too.
+func (cp *Checkpoint) RecordCommit(conv *Conv, spTable string, n int64, spCols []string, spVals []interface{}) {
+	tableId, err := GetTableIdFromSpName(conv.SpSchema, spTable)
+	if err != nil {
+		return
+	}
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	tc, ok := cp.Tables[cp.key(spTable)]
+	if !ok || tc.Status != CheckpointInProgress {
+		return
+	}
+	tc.UpdatedAt = time.Now()
+	tc.WrittenRows += n
+	if len(tc.KeyCols) != 0 {
+		key, err := checkpointKey(conv, tableId, tc.KeyCols, spCols, spVals)
+		if err != nil {
+			// Without a key we can only restart this table from scratch.
+			logger.Log.Debug(fmt.Sprintf("Can't record checkpoint for table %s", spTable), zap.Error(err))
+			tc.KeyCols = nil
+			tc.LastKey = nil
+		} else {
+			tc.LastKey = key
+		}
+	}
+	if time.Since(cp.lastSave) > checkpointSaveInterval {
+		if err := cp.save(); err != nil {
+			logger.Log.Warn("Couldn't save checkpoint", zap.Error(err))
+		}
+	}
+}
+
+// CheckpointKeyColumns returns the source primary key columns of a table if
+// every one of them is also migrated to Spanner, which is needed to derive
+// the source key from the rows written to Spanner. Returns nil otherwise.
+func CheckpointKeyColumns(conv *Conv, tableId string) []string {
+	srcTable := conv.SrcSchema[tableId]
+	spTable := conv.SpSchema[tableId]
+	var cols []string
+	for _, k := range srcTable.PrimaryKeys {
+		if _, ok := spTable.ColDefs[k.ColId]; !ok {
+			return nil
+		}
+		cols = append(cols, srcTable.ColDefs[k.ColId].Name)
+	}
+	return cols
+}
+
+// checkpointKey extracts the values of the source key columns from a row of
+// Spanner values and formats them as literals the source database accepts
+// in comparisons with the key columns.
+func checkpointKey(conv *Conv, tableId string, keyCols []string, spCols []string, spVals []interface{}) ([]string, error) {
+	srcTable := conv.SrcSchema[tableId]
+	spTable := conv.SpSchema[tableId]
+	var key []string
+	for _, c := range keyCols {
+		colId, err := GetColIdFromSrcName(srcTable.ColDefs, c)
+		if err != nil {
+			return nil, err
+		}
+		i := indexOf(spCols, spTable.ColDefs[colId].Name)
+		if i < 0 {
+			return nil, fmt.Errorf("key column %s not found in row", c)
+		}
+		v, err := formatKeyValue(conv, spVals[i])
+		if err != nil {
+			return nil, fmt.Errorf("key column %s: %v", c, err)
+		}
+		key = append(key, v)
+	}
+	return key, nil
+}
+
+func formatKeyValue(conv *Conv, v interface{}) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case int64, float64, float32, bool:
+		return fmt.Sprint(x), nil
+	case spanner.PGNumeric:
+		return x.Numeric, nil
+	case *big.Rat:
+		return x.FloatString(9), nil
+	case civil.Date:
+		return x.String(), nil
+	case time.Time:
+		return x.In(conv.Location).Format("2006-01-02 15:04:05.999999999"), nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", v)
+	}
+}
+
+func sameCols(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOf(l []string, s string) int {
+	for i, x := range l {
+		if x == s {
+			return i
+		}
+	}
+	return -1
+}
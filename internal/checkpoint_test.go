@@ -0,0 +1,122 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+func buildCheckpointConv() *Conv {
+	conv := MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Id:     "t1",
+		Name:   "orders",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]schema.Column{
+			"c1": {Id: "c1", Name: "region"},
+			"c2": {Id: "c2", Name: "order_id"},
+			"c3": {Id: "c3", Name: "note"},
+		},
+		PrimaryKeys: []schema.Key{{ColId: "c1"}, {ColId: "c2"}},
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Id:     "t1",
+		Name:   "Orders",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Id: "c1", Name: "Region"},
+			"c2": {Id: "c2", Name: "OrderId"},
+			"c3": {Id: "c3", Name: "Note"},
+		},
+	}
+	return conv
+}
+
+func TestCheckpointKeyColumns(t *testing.T) {
+	conv := buildCheckpointConv()
+	assert.Equal(t, []string{"region", "order_id"}, CheckpointKeyColumns(conv, "t1"))
+
+	// Key columns that aren't migrated can't be recovered from written rows.
+	sp := conv.SpSchema["t1"]
+	delete(sp.ColDefs, "c2")
+	conv.SpSchema["t1"] = sp
+	assert.Nil(t, CheckpointKeyColumns(conv, "t1"))
+}
+
+func TestCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.checkpoint.json")
+	conv := buildCheckpointConv()
+	cp := NewCheckpoint(path)
+	conv.Checkpoint = cp
+
+	cp.MarkInProgress(conv, "t1")
+	_, _, ok := cp.ResumeKey("Orders")
+	assert.False(t, ok)
+	cp.SetKeyCols("Orders", CheckpointKeyColumns(conv, "t1"))
+	cp.RecordCommit(conv, "Orders", 2, []string{"Note", "OrderId", "Region"}, []interface{}{"x", int64(7), "eu"})
+	assert.Nil(t, cp.Save())
+
+	// Simulate a restart.
+	conv = buildCheckpointConv()
+	cp, err := LoadCheckpoint(path)
+	assert.Nil(t, err)
+	assert.False(t, cp.IsComplete("Orders"))
+	cols, key, ok := cp.ResumeKey("Orders")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"region", "order_id"}, cols)
+	assert.Equal(t, []string{"eu", "7"}, key)
+
+	cp.MarkInProgress(conv, "t1")
+	_, _, ok = cp.ResumeKey("Orders")
+	assert.True(t, ok)
+	// Rows committed by the previous run count as good rows.
+	assert.Equal(t, int64(2), conv.Stats.GoodRows["orders"])
+	conv.Stats.GoodRows["orders"] += 3
+	conv.Stats.BadRows["orders"] += 1
+	conv.Stats.FilteredRows["orders"] += 4
+	cp.MarkComplete(conv, "t1")
+
+	conv = buildCheckpointConv()
+	cp, err = LoadCheckpoint(path)
+	assert.Nil(t, err)
+	assert.True(t, cp.IsComplete("Orders"))
+	cp.RestoreStats(conv, "t1")
+	assert.Equal(t, int64(5), conv.Stats.GoodRows["orders"])
+	assert.Equal(t, int64(1), conv.Stats.BadRows["orders"])
+	assert.Equal(t, int64(4), conv.Stats.FilteredRows["orders"])
+}
+
+func TestCheckpointShards(t *testing.T) {
+	conv := buildCheckpointConv()
+	cp := NewCheckpoint(filepath.Join(t.TempDir(), "test.checkpoint.json"))
+	cp.SetShard("shard1")
+	cp.MarkInProgress(conv, "t1")
+	cp.MarkComplete(conv, "t1")
+	assert.True(t, cp.IsComplete("Orders"))
+	cp.SetShard("shard2")
+	assert.False(t, cp.IsComplete("Orders"))
+}
+
+func TestLoadCheckpointMissingFile(t *testing.T) {
+	cp, err := LoadCheckpoint(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Nil(t, err)
+	assert.Empty(t, cp.Tables)
+}
@@ -16,9 +16,11 @@ package internal
 
 import (
 	"fmt"
+	"math/bits"
 	"sync"
 	"time"
 
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/prommetrics"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/proto/migration"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
@@ -38,7 +40,7 @@ type Conv struct {
 	ToSpanner          map[string]NameAndCols       `json:"-"` // Maps from source-DB table name to Spanner name and column mapping.
 	ToSource           map[string]NameAndCols       `json:"-"` // Maps from Spanner table name to source-DB table name and column mapping.
 	UsedNames          map[string]bool              `json:"-"` // Map storing the names that are already assigned to tables, indices or foreign key contraints.
-	dataSink           func(table string, cols []string, values []interface{})
+	dataSink           func(table string, cols []string, values []interface{}, src *SourceRow)
 	DataFlush          func()                  `json:"-"` // Data flush is used to flush out remaining writes and wait for them to complete.
 	Location           *time.Location          // Timezone (for timestamp conversion).
 	sampleBadRows      rowSamples              // Rows that generated errors during conversion.
@@ -55,9 +57,33 @@ type Conv struct {
 	UI                 bool                    // Flag if UI interface was used for migration. ToDo: Remove flag after resource generation is introduced to UI
 	SpSequences        map[string]ddl.Sequence // Maps Spanner Sequences to Sequence Schema
 	SrcSequences       map[string]ddl.Sequence // Maps source-DB Sequences to Sequence schema information
+	SrcObjects         []schema.SourceObject   `json:",omitempty"` // Views, triggers, stored procedures and functions of the source database, which are not converted.
+	SrcRowCounts       map[string]int64        `json:",omitempty"` // Maps source table id to its row count, read during schema conversion for the tables linked by foreign keys.
 	SpProjectId        string                  // Spanner Project Id
 	SpInstanceId       string                  // Spanner Instance Id
 	Source             string                  // Source Database type being migrated
+	Checkpoint         *Checkpoint             `json:"-"` // Tracks bulk data migration progress for resumable runs; nil when checkpointing is disabled.
+	DeadLetter         *DeadLetterQueue        `json:"-"` // Records every rejected row; nil when disabled.
+	// Maps Spanner table id and column id to the transformation applied to
+	// the values of the column during bulk data migration.
+	ColumnTransformations map[string]map[string]ColumnTransformation `json:",omitempty"`
+	transformersOnce      sync.Once
+	rowTransformers       map[string]*rowTransformer // Maps Spanner table name to its compiled column transformations.
+	rowFiltersOnce        sync.Once
+	rowFilters            map[string]*tableRowFilter // Maps Spanner table name to the row filter of its source table.
+	// Maps Spanner table id to the hotspot fix applied to its primary key.
+	KeyRewrites      map[string]KeyRewrite `json:",omitempty"`
+	keyRewritersOnce sync.Once
+	keyRewriters     map[string]*keyRewriter // Maps Spanner table name to the rewriter computing the column added to its key.
+	// Maps Spanner table id to the primary keys read from more than one
+	// shard by the shard key analysis of a sharded migration.
+	ShardKeyCollisions map[string]ShardKeyCollisions `json:"-"`
+	FailedIndexes      []FailedIndex                 `json:"-"` // Secondary indexes that couldn't be created after data migration.
+	shardKeyDrops      shardKeyDrops                 // Rows rejected because another shard wrote the same key.
+	readKeyRanges      map[string]KeyRange           // Maps source table id to the key range its reads are restricted to.
+	// Guards the unexpected conditions and synthetic key sequences, which
+	// are updated by the concurrent readers of a table's key ranges.
+	rowLock sync.Mutex
 }
 
 type InvalidCheckExp struct {
@@ -74,6 +100,14 @@ type AdditionalSchemaAttributes struct {
 	IsSharded bool
 }
 
+// KeyRange is a range of the values of an integer key column of a source
+// table. Start is inclusive and Limit exclusive; nil bounds are unbounded.
+type KeyRange struct {
+	ColId string
+	Start *int64
+	Limit *int64
+}
+
 type AdditionalDataAttributes struct {
 	ShardId string
 }
@@ -148,6 +182,7 @@ const (
 	CheckConstraintFunctionNotFoundError
 	GenericError
 	GenericWarning
+	GeneratedColumn
 )
 
 const (
@@ -188,12 +223,13 @@ type row struct {
 // c) successfully converted, but an error occurs when writing the row to Spanner.
 // d) unsuccessfully converted (we won't try to write such rows to Spanner).
 type stats struct {
-	Rows       map[string]int64          // Count of rows encountered during processing (a + b + c + d), broken down by source table.
-	GoodRows   map[string]int64          // Count of rows successfully converted (b + c), broken down by source table.
-	BadRows    map[string]int64          // Count of rows where conversion failed (d), broken down by source table.
-	Statement  map[string]*statementStat // Count of processed statements, broken down by statement type.
-	Unexpected map[string]int64          // Count of unexpected conditions, broken down by condition description.
-	Reparsed   int64                     // Count of times we re-parse dump data looking for end-of-statement.
+	Rows         map[string]int64          // Count of rows encountered during processing (a + b + c + d), broken down by source table.
+	GoodRows     map[string]int64          // Count of rows successfully converted (b + c), broken down by source table.
+	BadRows      map[string]int64          // Count of rows where conversion failed (d), broken down by source table.
+	FilteredRows map[string]int64          // Count of rows skipped by the row filter of their table (a), broken down by source table.
+	Statement    map[string]*statementStat // Count of processed statements, broken down by statement type.
+	Unexpected   map[string]int64          // Count of unexpected conditions, broken down by condition description.
+	Reparsed     int64                     // Count of times we re-parse dump data looking for end-of-statement.
 }
 
 type statementStat struct {
@@ -214,6 +250,23 @@ type Audit struct {
 	StreamingStats           streamingStats                         `json:"-"` // Stores information related to streaming migration process.
 	Progress                 Progress                               `json:"-"` // Stores information related to progress of the migration progress
 	SkipMetricsPopulation    bool                                   `json:"-"` // Flag to identify if outgoing metrics metadata needs to skipped
+	DeferIndexes             bool                                   `json:"-"` // Flag to identify if secondary indexes are created after data migration.
+	WriteSettings            WriteSettings                          `json:"-"` // Settings for writing data to Spanner in bulk migrations.
+}
+
+// FailedIndex is a secondary index that couldn't be created after data
+// migration (see Audit.DeferIndexes).
+type FailedIndex struct {
+	Name  string
+	Table string // Spanner table name.
+	Error string
+}
+
+// WriteSettings configures how data is written to Spanner in bulk migrations.
+type WriteSettings struct {
+	Adaptive         bool    // If true, batch size and number of in-progress writes are tuned from commit latency and errors.
+	MaxRowsPerSecond float64 // Cap on rows written per second; 0 means no cap.
+	MaxCPUPercent    float64 // Cap on Spanner high priority CPU utilization in adaptive mode; 0 means no cap.
 }
 
 // Stores information related to generated Dataflow Resources.
@@ -352,11 +405,12 @@ func MakeConv() *Conv {
 		Location:       time.Local, // By default, use go's local time, which uses $TZ (when set).
 		sampleBadRows:  rowSamples{bytesLimit: 10 * 1000 * 1000},
 		Stats: stats{
-			Rows:       make(map[string]int64),
-			GoodRows:   make(map[string]int64),
-			BadRows:    make(map[string]int64),
-			Statement:  make(map[string]*statementStat),
-			Unexpected: make(map[string]int64),
+			Rows:         make(map[string]int64),
+			GoodRows:     make(map[string]int64),
+			BadRows:      make(map[string]int64),
+			FilteredRows: make(map[string]int64),
+			Statement:    make(map[string]*statementStat),
+			Unexpected:   make(map[string]int64),
 		},
 		TimezoneOffset: "+00:00", // By default, use +00:00 offset which is equal to UTC timezone
 		UniquePKey:     make(map[string][]string),
@@ -372,19 +426,54 @@ func MakeConv() *Conv {
 
 func (conv *Conv) ResetStats() {
 	conv.Stats = stats{
-		Rows:       make(map[string]int64),
-		GoodRows:   make(map[string]int64),
-		BadRows:    make(map[string]int64),
-		Statement:  make(map[string]*statementStat),
-		Unexpected: make(map[string]int64),
+		Rows:         make(map[string]int64),
+		GoodRows:     make(map[string]int64),
+		BadRows:      make(map[string]int64),
+		FilteredRows: make(map[string]int64),
+		Statement:    make(map[string]*statementStat),
+		Unexpected:   make(map[string]int64),
 	}
 }
 
 // SetDataSink configures conv to use the specified data sink.
 func (conv *Conv) SetDataSink(ds func(table string, cols []string, values []interface{})) {
+	if ds == nil {
+		conv.dataSink = nil
+		return
+	}
+	conv.dataSink = func(table string, cols []string, values []interface{}, _ *SourceRow) {
+		ds(table, cols, values)
+	}
+}
+
+// SetDataSinkWithSource configures conv to use the specified data sink,
+// which is also passed the source row that each row was converted from, if
+// known.
+func (conv *Conv) SetDataSinkWithSource(ds func(table string, cols []string, values []interface{}, src *SourceRow)) {
 	conv.dataSink = ds
 }
 
+// SetReadKeyRange restricts the reads of the rows of source table tableId
+// to key range kr, e.g. to compare a chunk of the table during validation.
+// A nil kr removes the restriction.
+func (conv *Conv) SetReadKeyRange(tableId string, kr *KeyRange) {
+	if kr == nil {
+		delete(conv.readKeyRanges, tableId)
+		return
+	}
+	if conv.readKeyRanges == nil {
+		conv.readKeyRanges = make(map[string]KeyRange)
+	}
+	conv.readKeyRanges[tableId] = *kr
+}
+
+// ReadKeyRange returns the key range that the reads of the rows of source
+// table tableId are restricted to, if any.
+func (conv *Conv) ReadKeyRange(tableId string) (KeyRange, bool) {
+	kr, ok := conv.readKeyRanges[tableId]
+	return kr, ok
+}
+
 // Note on modes.
 // We process the dump output twice. In the first pass (schema mode) we
 // build the schema, and the second pass (data mode) we write data to
@@ -409,6 +498,54 @@ func (conv *Conv) SetDataMode() {
 
 // WriteRow calls dataSink and updates row stats.
 func (conv *Conv) WriteRow(srcTable, spTable string, spCols []string, spVals []interface{}) {
+	conv.writeRow(srcTable, spTable, spCols, spVals, nil)
+}
+
+// WriteRowWithSource is like WriteRow, but also passes srcCols and srcVals,
+// the source row that spVals were converted from, so that the row can be
+// dead lettered if it can't be written to Spanner.
+func (conv *Conv) WriteRowWithSource(srcTable string, srcCols, srcVals []string, spTable string, spCols []string, spVals []interface{}) {
+	var src *SourceRow
+	if conv.DeadLetter != nil {
+		src = &SourceRow{Table: srcTable, Cols: srcCols, Vals: srcVals}
+	}
+	conv.writeRow(srcTable, spTable, spCols, spVals, src)
+}
+
+func (conv *Conv) writeRow(srcTable, spTable string, spCols []string, spVals []interface{}, src *SourceRow) {
+	if rf := conv.getRowFilter(spTable); rf != nil {
+		ok, err := rf.filterRow(spCols, spVals)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't filter row of table %s: %v", spTable, err))
+			conv.StatsAddBadRow(srcTable, conv.DataMode())
+			conv.deadLetter(src, err)
+			return
+		}
+		if !ok {
+			conv.StatsAddFilteredRow(srcTable, conv.DataMode())
+			return
+		}
+	}
+	if rt := conv.getRowTransformer(spTable); rt != nil {
+		vals, err := rt.transform(spCols, spVals)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't transform row of table %s: %v", spTable, err))
+			conv.StatsAddBadRow(srcTable, conv.DataMode())
+			conv.deadLetter(src, err)
+			return
+		}
+		spVals = vals
+	}
+	if kr := conv.getKeyRewriter(spTable); kr != nil {
+		cols, vals, err := kr.rewrite(spCols, spVals)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't rewrite key of row of table %s: %v", spTable, err))
+			conv.StatsAddBadRow(srcTable, conv.DataMode())
+			conv.deadLetter(src, err)
+			return
+		}
+		spCols, spVals = cols, vals
+	}
 	if conv.Audit.DryRun {
 		conv.statsAddGoodRow(srcTable, conv.DataMode())
 	} else if conv.dataSink == nil {
@@ -419,7 +556,7 @@ func (conv *Conv) WriteRow(srcTable, spTable string, spCols []string, spVals []i
 		conv.Unexpected(msg)
 		conv.StatsAddBadRow(srcTable, conv.DataMode())
 	} else {
-		conv.dataSink(spTable, spCols, spVals)
+		conv.dataSink(spTable, spCols, spVals, src)
 		conv.statsAddGoodRow(srcTable, conv.DataMode())
 	}
 }
@@ -443,6 +580,16 @@ func (conv *Conv) BadRows() int64 {
 	return n
 }
 
+// FilteredRows returns the total count of data rows skipped by row
+// filters.
+func (conv *Conv) FilteredRows() int64 {
+	n := int64(0)
+	for _, c := range conv.Stats.FilteredRows {
+		n += c
+	}
+	return n
+}
+
 // Statements returns the total number of statements processed.
 func (conv *Conv) Statements() int64 {
 	n := int64(0)
@@ -468,8 +615,10 @@ func (conv *Conv) Unexpecteds() int64 {
 }
 
 // CollectBadRow updates the list of bad rows, while respecting
-// the byte limit for bad rows.
-func (conv *Conv) CollectBadRow(srcTable string, srcCols, vals []string) {
+// the byte limit for bad rows. The row and the conversion error are also
+// added to the dead-letter file, if enabled.
+func (conv *Conv) CollectBadRow(srcTable string, srcCols, vals []string, err error) {
+	conv.deadLetter(&SourceRow{Table: srcTable, Cols: srcCols, Vals: vals}, err)
 	r := &row{table: srcTable, cols: srcCols, vals: vals}
 	bytes := byteSize(r)
 	// Cap storage used by badRows. Keep at least one bad row.
@@ -479,6 +628,19 @@ func (conv *Conv) CollectBadRow(srcTable string, srcCols, vals []string) {
 	}
 }
 
+// deadLetter adds a row that failed conversion to the dead-letter file, if
+// enabled and the source row is known.
+func (conv *Conv) deadLetter(src *SourceRow, err error) {
+	if conv.DeadLetter == nil || src == nil || conv.mode != dataOnly {
+		return
+	}
+	msg := "unknown error"
+	if err != nil {
+		msg = err.Error()
+	}
+	conv.DeadLetter.Add(DeadLetterRecord{SourceRow: *src, Error: msg, Stage: DeadLetterConversion})
+}
+
 // SampleBadRows returns a string-formatted list of rows that generated errors.
 // Returns at most n rows.
 func (conv *Conv) SampleBadRows(n int) []string {
@@ -599,11 +761,27 @@ func (conv *Conv) Unexpected(u string) {
 
 	// Limit size of unexpected map. If over limit, then only
 	// update existing entries.
+	conv.rowLock.Lock()
+	defer conv.rowLock.Unlock()
 	if _, ok := conv.Stats.Unexpected[u]; ok || len(conv.Stats.Unexpected) < 1000 {
 		conv.Stats.Unexpected[u]++
 	}
 }
 
+// NextSyntheticPKey returns the name of the synthetic primary key column of
+// table tableId and its next value, bit reversed to spread writes, and
+// advances its sequence. Returns false if the table has no synthetic key.
+func (conv *Conv) NextSyntheticPKey(tableId string) (string, string, bool) {
+	conv.rowLock.Lock()
+	defer conv.rowLock.Unlock()
+	aux, ok := conv.SyntheticPKeys[tableId]
+	if !ok {
+		return "", "", false
+	}
+	conv.SyntheticPKeys[tableId] = SyntheticPKey{ColId: aux.ColId, Sequence: aux.Sequence + 1}
+	return conv.SpSchema[tableId].ColDefs[aux.ColId].Name, fmt.Sprintf("%d", int64(bits.Reverse64(uint64(aux.Sequence)))), true
+}
+
 // StatsAddRow increments the count of rows for 'srcTable' if b is
 // true.  The boolean arg 'b' is used to avoid double counting of
 // stats. Specifically, some code paths that report row stats run in
@@ -623,6 +801,7 @@ func (conv *Conv) StatsAddRow(srcTable string, b bool) {
 func (conv *Conv) statsAddGoodRow(srcTable string, b bool) {
 	if b {
 		conv.Stats.GoodRows[srcTable]++
+		prommetrics.RowConverted(srcTable)
 	}
 }
 
@@ -631,9 +810,26 @@ func (conv *Conv) statsAddGoodRow(srcTable string, b bool) {
 func (conv *Conv) StatsAddBadRow(srcTable string, b bool) {
 	if b {
 		conv.Stats.BadRows[srcTable]++
+		prommetrics.RowBad(srcTable)
 	}
 }
 
+// StatsAddFilteredRow increments the filtered-row stats for 'srcTable'
+// if b is true.  See StatsAddRow comments for context.
+func (conv *Conv) StatsAddFilteredRow(srcTable string, b bool) {
+	if b {
+		conv.Stats.FilteredRows[srcTable]++
+		prommetrics.RowFiltered(srcTable)
+	}
+}
+
+// StatsAddFilteredRows adds n rows of 'srcTable' skipped by its row filter
+// in the source database, which were never read.
+func (conv *Conv) StatsAddFilteredRows(srcTable string, n int64) {
+	conv.Stats.FilteredRows[srcTable] += n
+	prommetrics.RowsFiltered(srcTable, n)
+}
+
 func (conv *Conv) getStatementStat(s string) *statementStat {
 	if conv.Stats.Statement[s] == nil {
 		conv.Stats.Statement[s] = &statementStat{}
@@ -79,6 +79,21 @@ func TestUnexpecteds(t *testing.T) {
 	assert.Equal(t, int64(1), conv.Unexpecteds())
 }
 
+func TestNextSyntheticPKey(t *testing.T) {
+	conv := MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{Name: "t", ColDefs: map[string]ddl.ColumnDef{"c1": {Name: "synth_id"}}}
+	conv.SyntheticPKeys["t1"] = SyntheticPKey{ColId: "c1"}
+	col, val, ok := conv.NextSyntheticPKey("t1")
+	assert.True(t, ok)
+	assert.Equal(t, "synth_id", col)
+	assert.Equal(t, "0", val)
+	_, val, _ = conv.NextSyntheticPKey("t1")
+	assert.Equal(t, "-9223372036854775808", val)
+	assert.Equal(t, int64(2), conv.SyntheticPKeys["t1"].Sequence)
+	_, _, ok = conv.NextSyntheticPKey("t2")
+	assert.False(t, ok)
+}
+
 func TestGetBadRows(t *testing.T) {
 	conv := MakeConv()
 	row1 := row{"table", []string{"col1", "col2"}, []string{"a", "1"}}
@@ -0,0 +1,157 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+)
+
+// Stages of the migration at which a row can be rejected.
+const (
+	DeadLetterConversion = "conversion" // The row couldn't be converted to Spanner values.
+	DeadLetterWrite      = "write"      // The row was converted, but couldn't be written to Spanner.
+)
+
+// SourceRow is a row of data as read from the source database.
+type SourceRow struct {
+	Table string   `json:"table"` // Source table name.
+	Cols  []string `json:"cols"`  // Source column names.
+	Vals  []string `json:"vals"`  // Source values, as strings.
+}
+
+// DeadLetterRecord is a rejected row, as stored in the dead-letter file.
+type DeadLetterRecord struct {
+	SourceRow
+	Error string `json:"error"`
+	Stage string `json:"stage"` // DeadLetterConversion or DeadLetterWrite.
+}
+
+// DeadLetterQueue writes every rejected row to a dead-letter file, one JSON
+// record per line. Unlike the sample of bad rows kept in Conv, it isn't
+// capped, so rows can be fixed up and replayed later. The file is only
+// created once the first row is rejected. DeadLetterQueue is safe for
+// concurrent use, since rows are rejected by go routines writing to
+// Spanner too.
+type DeadLetterQueue struct {
+	path  string
+	lock  sync.Mutex
+	f     *os.File
+	w     *bufio.Writer
+	count int64
+	err   error // First error encountered writing the file.
+}
+
+// NewDeadLetterQueue returns a DeadLetterQueue writing to path. Records of
+// previous runs are discarded unless keep is true, e.g. when resuming a
+// migration.
+func NewDeadLetterQueue(path string, keep bool) *DeadLetterQueue {
+	if !keep {
+		os.Remove(path)
+	}
+	return &DeadLetterQueue{path: path}
+}
+
+// Add appends a record to the dead-letter file. Errors writing the file are
+// logged once, and reported by Close.
+func (q *DeadLetterQueue) Add(r DeadLetterRecord) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.err != nil {
+		return
+	}
+	if q.f == nil {
+		q.f, q.err = os.OpenFile(q.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if q.err != nil {
+			logger.Log.Error(fmt.Sprintf("Can't create dead-letter file %s: %v", q.path, q.err))
+			return
+		}
+		q.w = bufio.NewWriter(q.f)
+	}
+	b, err := json.Marshal(r)
+	if err == nil {
+		b = append(b, '\n')
+		_, err = q.w.Write(b)
+	}
+	if err != nil {
+		q.err = err
+		logger.Log.Error(fmt.Sprintf("Can't write to dead-letter file %s: %v", q.path, err))
+		return
+	}
+	q.count++
+}
+
+// Count returns the number of records added to the dead-letter file.
+func (q *DeadLetterQueue) Count() int64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.count
+}
+
+// Path returns the path of the dead-letter file.
+func (q *DeadLetterQueue) Path() string {
+	return q.path
+}
+
+// Close flushes and closes the dead-letter file.
+func (q *DeadLetterQueue) Close() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.f == nil {
+		return q.err
+	}
+	if err := q.w.Flush(); err != nil && q.err == nil {
+		q.err = err
+	}
+	if err := q.f.Close(); err != nil && q.err == nil {
+		q.err = err
+	}
+	q.f = nil
+	return q.err
+}
+
+// ReadDeadLetterFile reads the records of a dead-letter file.
+func ReadDeadLetterFile(path string) ([]DeadLetterRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open dead-letter file %s: %v", path, err)
+	}
+	defer f.Close()
+	var records []DeadLetterRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1<<20), 1<<30)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r DeadLetterRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("can't parse line %d of dead-letter file %s: %v", line, path, err)
+		}
+		if len(r.Cols) != len(r.Vals) {
+			return nil, fmt.Errorf("line %d of dead-letter file %s has %d columns but %d values", line, path, len(r.Cols), len(r.Vals))
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read dead-letter file %s: %v", path, err)
+	}
+	return records, nil
+}
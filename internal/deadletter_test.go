@@ -0,0 +1,81 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetterQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.dead_letter.jsonl")
+	q := NewDeadLetterQueue(path, false)
+	assert.Nil(t, q.Close())
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "file should only be created for the first record")
+
+	r1 := DeadLetterRecord{SourceRow: SourceRow{Table: "t", Cols: []string{"a", "b"}, Vals: []string{"1", "x"}}, Error: "bad", Stage: DeadLetterConversion}
+	r2 := DeadLetterRecord{SourceRow: SourceRow{Table: "t", Cols: []string{"a"}, Vals: []string{"2"}}, Error: "exists", Stage: DeadLetterWrite}
+	q = NewDeadLetterQueue(path, false)
+	q.Add(r1)
+	assert.Nil(t, q.Close())
+
+	// Resumed migrations keep the records of previous runs.
+	q = NewDeadLetterQueue(path, true)
+	q.Add(r2)
+	assert.Nil(t, q.Close())
+	assert.Equal(t, int64(1), q.Count())
+	records, err := ReadDeadLetterFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []DeadLetterRecord{r1, r2}, records)
+
+	NewDeadLetterQueue(path, false)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "records of previous runs should be discarded")
+}
+
+func TestReadDeadLetterFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := ReadDeadLetterFile(filepath.Join(dir, "missing.jsonl"))
+	assert.NotNil(t, err)
+	for i, content := range []string{
+		"not json\n",
+		`{"table":"t","cols":["a","b"],"vals":["1"],"error":"e","stage":"write"}` + "\n",
+	} {
+		path := filepath.Join(dir, fmt.Sprintf("dlq%d.jsonl", i))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+		_, err := ReadDeadLetterFile(path)
+		assert.NotNil(t, err, content)
+	}
+}
+
+func TestCollectBadRowDeadLetter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.dead_letter.jsonl")
+	conv := MakeConv()
+	conv.DeadLetter = NewDeadLetterQueue(path, false)
+	// Rows are only dead lettered when writing data.
+	conv.SetSchemaMode()
+	conv.CollectBadRow("t", []string{"a"}, []string{"1"}, fmt.Errorf("bad"))
+	conv.SetDataMode()
+	conv.CollectBadRow("t", []string{"a"}, []string{"2"}, fmt.Errorf("bad"))
+	assert.Nil(t, conv.DeadLetter.Close())
+	records, err := ReadDeadLetterFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []DeadLetterRecord{{SourceRow: SourceRow{Table: "t", Cols: []string{"a"}, Vals: []string{"2"}}, Error: "bad", Stage: DeadLetterConversion}}, records)
+}
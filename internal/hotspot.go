@@ -0,0 +1,514 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// HotspotFix is a rewrite of the primary key of a table that spreads the
+// writes of monotonically increasing keys (timestamps, auto-increment
+// columns) across splits.
+type HotspotFix string
+
+const (
+	// HotspotFixBitReversedSequence generates the values of an INT64 key
+	// column with a bit-reversed sequence. The migrated rows keep their
+	// values; the sequence is used for the rows inserted afterwards, and
+	// skips the values from 1 to SkipRangeMax so that they don't collide
+	// with the migrated keys.
+	HotspotFixBitReversedSequence HotspotFix = "bit_reversed_sequence"
+	// HotspotFixUUID adds a UUID column, generated with GENERATE_UUID() by
+	// default, in front of the primary key. Migrated rows get a UUID derived
+	// from their key, so that rewriting a row gives it the same UUID.
+	HotspotFixUUID HotspotFix = "uuid"
+	// HotspotFixHashShard adds an INT64 column in front of the primary key,
+	// generated from a hash of the key modulo ShardCount. The column is a
+	// stored generated column, so Spanner sets it for every row written,
+	// including the rows of minimal downtime migrations.
+	HotspotFixHashShard HotspotFix = "hash_shard"
+	// HotspotFixReorderKey moves the hotspot column after the other key
+	// columns, or reorders the key as given by PrimaryKeys.
+	HotspotFixReorderKey HotspotFix = "reorder_primary_key"
+)
+
+const (
+	uuidKeyColumn        = "uuid"
+	hashShardColumn      = "key_shard"
+	defaultHashShards    = 16
+	defaultSkipRangeMax  = math.MaxUint32
+	uuidGenerationType   = "Pre-defined"
+	bitReversedPositive  = "BIT REVERSED POSITIVE"
+	hotspotSequenceAffix = "_seq"
+)
+
+// KeyRewrite is a hotspot fix applied to the primary key of a table.
+type KeyRewrite struct {
+	Fix          HotspotFix
+	ColId        string         // Key column with the hotspot.
+	AddedColId   string         `json:",omitempty"` // Column added in front of the key by HotspotFixUUID and HotspotFixHashShard.
+	ShardCount   int64          `json:",omitempty"` // Number of shards of HotspotFixHashShard.
+	SkipRangeMax int64          `json:",omitempty"` // Largest migrated key of HotspotFixBitReversedSequence, skipped by the sequence.
+	SequenceId   string         `json:",omitempty"` // Sequence created by HotspotFixBitReversedSequence.
+	PrimaryKeys  []ddl.IndexKey `json:",omitempty"` // Primary key set by HotspotFixReorderKey, defaults to the hotspot column last.
+}
+
+// Describe returns a short description of r for the report.
+func (r KeyRewrite) Describe(conv *Conv, tableId string) string {
+	sp := conv.SpSchema[tableId]
+	switch r.Fix {
+	case HotspotFixBitReversedSequence:
+		seq := conv.SpSequences[r.SequenceId]
+		return fmt.Sprintf("values of %s generated by bit-reversed sequence %s, skipping %s to %s", sp.ColDefs[r.ColId].Name, seq.Name, seq.SkipRangeMin, seq.SkipRangeMax)
+	case HotspotFixUUID:
+		return fmt.Sprintf("UUID column %s added in front of the primary key", sp.ColDefs[r.AddedColId].Name)
+	case HotspotFixHashShard:
+		return fmt.Sprintf("column %s added in front of the primary key, set to a hash of (%s) modulo %d",
+			sp.ColDefs[r.AddedColId].Name, colNames(sp, rewriteKeyColIds(sp, r)), r.ShardCount)
+	case HotspotFixReorderKey:
+		var keys []string
+		for _, k := range sortedIndexKeys(sp.PrimaryKeys) {
+			keys = append(keys, k.ColId)
+		}
+		return fmt.Sprintf("primary key reordered to (%s)", colNames(sp, keys))
+	}
+	return string(r.Fix)
+}
+
+// DetectHotspots adds a HotspotTimestamp issue to the TIMESTAMP primary
+// key columns of the Spanner schema, and a HotspotAutoIncrement issue to
+// the primary key columns converted from auto-increment source columns.
+// Issues already present, and issues of columns whose hotspot is fixed by
+// FixHotspot, aren't added again.
+func DetectHotspots(conv *Conv) {
+	for _, sp := range conv.SpSchema {
+		DetectKeyHotspots(conv, sp, sp.PrimaryKeys)
+	}
+}
+
+// DetectKeyHotspots is like DetectHotspots, for the key columns keys of
+// table sp.
+func DetectKeyHotspots(conv *Conv, sp ddl.CreateTable, keys []ddl.IndexKey) {
+	for _, k := range keys {
+		if r, ok := conv.KeyRewrites[sp.Id]; ok && r.ColId == k.ColId {
+			continue
+		}
+		if col, ok := sp.ColDefs[k.ColId]; ok && col.T.Name == ddl.Timestamp {
+			conv.addColumnIssue(sp.Id, k.ColId, HotspotTimestamp)
+		}
+		srcCol, ok := conv.SrcSchema[sp.Id].ColDefs[k.ColId]
+		if ok && (srcCol.Ignored.AutoIncrement || srcCol.AutoGen.GenerationType == constants.AUTO_INCREMENT) {
+			conv.addColumnIssue(sp.Id, k.ColId, HotspotAutoIncrement)
+		}
+	}
+}
+
+func (conv *Conv) addColumnIssue(tableId, colId string, issue SchemaIssue) {
+	if conv.SchemaIssues == nil {
+		conv.SchemaIssues = make(map[string]TableIssues)
+	}
+	tableIssues := conv.SchemaIssues[tableId]
+	if tableIssues.ColumnLevelIssues == nil {
+		tableIssues.ColumnLevelIssues = make(map[string][]SchemaIssue)
+	}
+	if !Contains(tableIssues.ColumnLevelIssues[colId], issue) {
+		tableIssues.ColumnLevelIssues[colId] = append(tableIssues.ColumnLevelIssues[colId], issue)
+	}
+	conv.SchemaIssues[tableId] = tableIssues
+}
+
+func (conv *Conv) removeColumnIssues(tableId, colId string, issues ...SchemaIssue) {
+	tableIssues, ok := conv.SchemaIssues[tableId]
+	if !ok || tableIssues.ColumnLevelIssues == nil {
+		return
+	}
+	var kept []SchemaIssue
+	for _, issue := range tableIssues.ColumnLevelIssues[colId] {
+		if !Contains(issues, issue) {
+			kept = append(kept, issue)
+		}
+	}
+	tableIssues.ColumnLevelIssues[colId] = kept
+	conv.SchemaIssues[tableId] = tableIssues
+}
+
+// hotspotColumn returns the first primary key column of table tableId with
+// a hotspot issue.
+func (conv *Conv) hotspotColumn(tableId string) string {
+	for _, k := range sortedIndexKeys(conv.SpSchema[tableId].PrimaryKeys) {
+		issues := conv.SchemaIssues[tableId].ColumnLevelIssues[k.ColId]
+		if Contains(issues, HotspotTimestamp) || Contains(issues, HotspotAutoIncrement) {
+			return k.ColId
+		}
+	}
+	return ""
+}
+
+// FixHotspot applies the hotspot fix r to the primary key of table tableId
+// and records it in conv, so that the data migration populates the columns
+// it adds. r.ColId defaults to the first key column with a hotspot issue
+// (see DetectHotspots). A table can only have one fix.
+func (conv *Conv) FixHotspot(tableId string, r KeyRewrite) error {
+	sp, ok := conv.SpSchema[tableId]
+	if !ok {
+		return fmt.Errorf("table not found")
+	}
+	if _, ok := conv.KeyRewrites[tableId]; ok {
+		return fmt.Errorf("the primary key of table %s is already rewritten", sp.Name)
+	}
+	if r.ColId == "" {
+		r.ColId = conv.hotspotColumn(tableId)
+		if r.ColId == "" {
+			return fmt.Errorf("table %s has no hotspot in its primary key, specify the column to fix", sp.Name)
+		}
+	}
+	if !isKeyColumn(sp, r.ColId) {
+		return fmt.Errorf("column %s is not part of the primary key of table %s", sp.ColDefs[r.ColId].Name, sp.Name)
+	}
+	if r.Fix != HotspotFixBitReversedSequence {
+		// Changing the key of an interleaved table breaks the prefix shared
+		// with its parent or children.
+		if sp.ParentTable.Id != "" {
+			return fmt.Errorf("table %s is interleaved in %s, remove the interleaving first", sp.Name, conv.SpSchema[sp.ParentTable.Id].Name)
+		}
+		for _, t := range conv.SpSchema {
+			if t.ParentTable.Id == tableId {
+				return fmt.Errorf("table %s is interleaved in table %s, remove the interleaving first", t.Name, sp.Name)
+			}
+		}
+	}
+	switch r.Fix {
+	case HotspotFixBitReversedSequence:
+		col := sp.ColDefs[r.ColId]
+		if col.T.Name != ddl.Int64 || col.T.IsArray {
+			return fmt.Errorf("column %s has type %s, bit-reversed sequences generate INT64 values", col.Name, col.T.PrintColumnDefType())
+		}
+		if r.SkipRangeMax == 0 {
+			r.SkipRangeMax = defaultSkipRangeMax
+		}
+		if r.SkipRangeMax < 0 {
+			return fmt.Errorf("the largest migrated key must be positive")
+		}
+		if col.AutoGen.GenerationType == constants.SEQUENCE {
+			// Spanner sequences, like the ones converted from auto-increment
+			// columns, are bit-reversed.
+			r.SequenceId = conv.sequenceId(col.AutoGen.Name)
+			if r.SequenceId == "" {
+				return fmt.Errorf("sequence %s of column %s not found", col.AutoGen.Name, col.Name)
+			}
+			seq := conv.SpSequences[r.SequenceId]
+			if seq.SkipRangeMin == "" && seq.SkipRangeMax == "" {
+				seq.SkipRangeMin, seq.SkipRangeMax = "1", strconv.FormatInt(r.SkipRangeMax, 10)
+				conv.SpSequences[r.SequenceId] = seq
+			}
+			break
+		}
+		if conv.UsedNames == nil {
+			conv.UsedNames = make(map[string]bool)
+		}
+		if conv.SpSequences == nil {
+			conv.SpSequences = make(map[string]ddl.Sequence)
+		}
+		seq := ddl.Sequence{
+			Id:              GenerateSequenceId(),
+			Name:            getSpannerValidName(conv, sp.Name+"_"+col.Name+hotspotSequenceAffix),
+			SequenceKind:    bitReversedPositive,
+			SkipRangeMin:    "1",
+			SkipRangeMax:    strconv.FormatInt(r.SkipRangeMax, 10),
+			ColumnsUsingSeq: map[string][]string{tableId: {r.ColId}},
+		}
+		conv.SpSequences[seq.Id] = seq
+		col.AutoGen = ddl.AutoGenCol{Name: seq.Name, GenerationType: constants.SEQUENCE}
+		sp.ColDefs[r.ColId] = col
+		r.SequenceId = seq.Id
+	case HotspotFixUUID:
+		r.AddedColId = newColumnId(sp)
+		addKeyPrefixColumn(&sp, ddl.ColumnDef{
+			Name:    uniqueColumnName(sp, uuidKeyColumn),
+			Id:      r.AddedColId,
+			T:       ddl.Type{Name: ddl.String, Len: 36},
+			NotNull: true,
+			AutoGen: ddl.AutoGenCol{Name: constants.UUID, GenerationType: uuidGenerationType},
+		})
+	case HotspotFixHashShard:
+		if r.ShardCount == 0 {
+			r.ShardCount = defaultHashShards
+		}
+		if r.ShardCount < 0 {
+			return fmt.Errorf("the number of shards must be positive")
+		}
+		keyColIds := rewriteKeyColIds(sp, r)
+		r.AddedColId = newColumnId(sp)
+		addKeyPrefixColumn(&sp, ddl.ColumnDef{
+			Name:    uniqueColumnName(sp, hashShardColumn),
+			Id:      r.AddedColId,
+			T:       ddl.Type{Name: ddl.Int64},
+			NotNull: true,
+			Generated: ddl.GeneratedColumn{
+				IsPresent: true,
+				Value:     ddl.Expression{ExpressionId: GenerateExpressionId(), Statement: keyShardExpression(conv.SpDialect, sp, keyColIds, r.ShardCount)},
+				Stored:    true,
+			},
+		})
+	case HotspotFixReorderKey:
+		keys, err := reorderedKey(sp, r)
+		if err != nil {
+			return err
+		}
+		sp.PrimaryKeys = keys
+		r.PrimaryKeys = keys
+	default:
+		return fmt.Errorf("unknown hotspot fix %q", r.Fix)
+	}
+	conv.SpSchema[tableId] = sp
+	conv.removeColumnIssues(tableId, r.ColId, HotspotTimestamp, HotspotAutoIncrement)
+	if conv.KeyRewrites == nil {
+		conv.KeyRewrites = make(map[string]KeyRewrite)
+	}
+	conv.KeyRewrites[tableId] = r
+	return nil
+}
+
+// reorderedKey returns the primary key set by a HotspotFixReorderKey
+// rewrite: r.PrimaryKeys if given, which must have the same columns as the
+// current key, or else the current key with the hotspot column last.
+func reorderedKey(sp ddl.CreateTable, r KeyRewrite) ([]ddl.IndexKey, error) {
+	current := sortedIndexKeys(sp.PrimaryKeys)
+	var keys []ddl.IndexKey
+	if len(r.PrimaryKeys) > 0 {
+		if len(r.PrimaryKeys) != len(current) {
+			return nil, fmt.Errorf("the new primary key of table %s must have the columns of the current one", sp.Name)
+		}
+		for _, k := range r.PrimaryKeys {
+			if !isKeyColumn(sp, k.ColId) {
+				return nil, fmt.Errorf("the new primary key of table %s must have the columns of the current one", sp.Name)
+			}
+		}
+		keys = append(keys, r.PrimaryKeys...)
+	} else {
+		if len(current) < 2 {
+			return nil, fmt.Errorf("the primary key of table %s has a single column, use another fix", sp.Name)
+		}
+		var last ddl.IndexKey
+		for _, k := range current {
+			if k.ColId == r.ColId {
+				last = k
+				continue
+			}
+			keys = append(keys, k)
+		}
+		keys = append(keys, last)
+	}
+	for i := range keys {
+		keys[i].Order = i + 1
+	}
+	if keys[0].ColId == r.ColId {
+		return nil, fmt.Errorf("column %s would still be the first column of the primary key of table %s", sp.ColDefs[r.ColId].Name, sp.Name)
+	}
+	return keys, nil
+}
+
+// addKeyPrefixColumn adds col to sp, in front of its primary key.
+func addKeyPrefixColumn(sp *ddl.CreateTable, col ddl.ColumnDef) {
+	sp.ColIds = append([]string{col.Id}, sp.ColIds...)
+	sp.ColDefs[col.Id] = col
+	keys := []ddl.IndexKey{{ColId: col.Id, Order: 1}}
+	for _, k := range sortedIndexKeys(sp.PrimaryKeys) {
+		k.Order = len(keys) + 1
+		keys = append(keys, k)
+	}
+	sp.PrimaryKeys = keys
+}
+
+// newColumnId returns a new column id, not used by the columns of sp.
+func newColumnId(sp ddl.CreateTable) string {
+	for {
+		id := GenerateColumnId()
+		if _, ok := sp.ColDefs[id]; !ok {
+			return id
+		}
+	}
+}
+
+func uniqueColumnName(sp ddl.CreateTable, name string) string {
+	used := make(map[string]bool)
+	for _, c := range sp.ColDefs {
+		used[strings.ToLower(c.Name)] = true
+	}
+	unique := name
+	for i := 1; used[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	return unique
+}
+
+func isKeyColumn(sp ddl.CreateTable, colId string) bool {
+	for _, k := range sp.PrimaryKeys {
+		if k.ColId == colId {
+			return true
+		}
+	}
+	return false
+}
+
+func (conv *Conv) sequenceId(name string) string {
+	for id, seq := range conv.SpSequences {
+		if seq.Name == name {
+			return id
+		}
+	}
+	return ""
+}
+
+// rewriteKeyColIds returns the key columns whose values derive the column
+// added by r.
+func rewriteKeyColIds(sp ddl.CreateTable, r KeyRewrite) []string {
+	var colIds []string
+	for _, k := range sortedIndexKeys(sp.PrimaryKeys) {
+		if k.ColId != r.AddedColId {
+			colIds = append(colIds, k.ColId)
+		}
+	}
+	return colIds
+}
+
+// keyRewriter computes the value of the column added to the primary key of
+// a table from the key values of each row.
+type keyRewriter struct {
+	r       KeyRewrite
+	table   string
+	col     string   // Spanner name of the added column.
+	keyCols []string // Spanner names of the key columns deriving it.
+}
+
+// rewrite appends the added column to a row of the table, unless the row
+// already has it.
+func (kr *keyRewriter) rewrite(cols []string, vals []interface{}) ([]string, []interface{}, error) {
+	pos := make(map[string]int)
+	for i, c := range cols {
+		pos[c] = i
+	}
+	if _, ok := pos[kr.col]; ok {
+		return cols, vals, nil
+	}
+	var key []string
+	for _, c := range kr.keyCols {
+		i, ok := pos[c]
+		if !ok {
+			return nil, nil, fmt.Errorf("can't compute %s: key column %s is missing", kr.col, c)
+		}
+		key = append(key, fmt.Sprint(vals[i]))
+	}
+	v := KeyUUID(kr.table, key)
+	return append(cols[:len(cols):len(cols)], kr.col), append(vals[:len(vals):len(vals)], v), nil
+}
+
+// KeyUUID returns the UUID of the row of a table with the given key values,
+// for rows migrated to a table rewritten by HotspotFixUUID. The UUID is a
+// name-based (version 5) UUID of the table name and key.
+func KeyUUID(table string, key []string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(table+"\x00"+strings.Join(key, "\x00"))).String()
+}
+
+// keyShardExpression returns the expression of the column added by
+// HotspotFixHashShard to table sp: the FARM_FINGERPRINT hash of the key
+// columns colIds, converted to strings and separated by commas, modulo
+// shards. The modulo is taken before ABS, which fails for the smallest
+// INT64.
+func keyShardExpression(dialect string, sp ddl.CreateTable, colIds []string, shards int64) string {
+	pg := dialect == constants.DIALECT_POSTGRESQL
+	var parts []string
+	for _, colId := range colIds {
+		col := sp.ColDefs[colId]
+		var s string
+		switch {
+		case col.T.Name == ddl.String:
+			s = col.Name
+		case col.T.Name == ddl.Bytes && pg:
+			s = fmt.Sprintf("encode(%s, 'base64')", col.Name)
+		case col.T.Name == ddl.Bytes:
+			s = fmt.Sprintf("TO_BASE64(%s)", col.Name)
+		case pg:
+			s = fmt.Sprintf("CAST(%s AS text)", col.Name)
+		default:
+			s = fmt.Sprintf("CAST(%s AS STRING)", col.Name)
+		}
+		if !col.NotNull {
+			if pg {
+				s = fmt.Sprintf("COALESCE(%s, '')", s)
+			} else {
+				s = fmt.Sprintf("IFNULL(%s, '')", s)
+			}
+		}
+		parts = append(parts, s)
+	}
+	if pg {
+		return fmt.Sprintf("abs(mod(spanner.farm_fingerprint(%s), %d))", strings.Join(parts, " || ',' || "), shards)
+	}
+	key := parts[0]
+	if len(parts) > 1 {
+		key = "CONCAT(" + strings.Join(parts, ", ',', ") + ")"
+	}
+	return fmt.Sprintf("ABS(MOD(FARM_FINGERPRINT(%s), %d))", key, shards)
+}
+
+// getKeyRewriter returns the key rewriter of the Spanner table spTable, or
+// nil if the table has no key rewrite adding a column.
+func (conv *Conv) getKeyRewriter(spTable string) *keyRewriter {
+	conv.keyRewritersOnce.Do(func() {
+		conv.keyRewriters = make(map[string]*keyRewriter)
+		for tableId, r := range conv.KeyRewrites {
+			sp, ok := conv.SpSchema[tableId]
+			if !ok || r.AddedColId == "" {
+				continue
+			}
+			// Generated columns, like the one added by HotspotFixHashShard,
+			// are set by Spanner.
+			if col, ok := sp.ColDefs[r.AddedColId]; !ok || col.Generated.IsPresent {
+				continue
+			}
+			kr := &keyRewriter{r: r, table: sp.Name, col: sp.ColDefs[r.AddedColId].Name}
+			for _, colId := range rewriteKeyColIds(sp, r) {
+				kr.keyCols = append(kr.keyCols, sp.ColDefs[colId].Name)
+			}
+			conv.keyRewriters[sp.Name] = kr
+		}
+	})
+	return conv.keyRewriters[spTable]
+}
+
+// KeyRewriteTables returns the ids of the tables with a key rewrite, sorted
+// by Spanner table name.
+func (conv *Conv) KeyRewriteTables() []string {
+	var tableIds []string
+	for tableId := range conv.KeyRewrites {
+		if _, ok := conv.SpSchema[tableId]; ok {
+			tableIds = append(tableIds, tableId)
+		}
+	}
+	sort.Slice(tableIds, func(i, j int) bool {
+		return conv.SpSchema[tableIds[i]].Name < conv.SpSchema[tableIds[j]].Name
+	})
+	return tableIds
+}
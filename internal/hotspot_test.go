@@ -0,0 +1,194 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// hotspotTestConv returns a conv with table events, keyed by a timestamp
+// and an auto-increment id, and table logs, keyed by an auto-increment id.
+func hotspotTestConv() *Conv {
+	conv := MakeConv()
+	addInterleaveTestTable(conv, "t1", "events",
+		[]interleaveTestCol{{"c1", "created", ddl.Type{Name: ddl.Timestamp}}, {"c2", "id", ddl.Type{Name: ddl.Int64}}, {"c3", "uuid", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}}},
+		[]string{"c1", "c2"}, nil)
+	addInterleaveTestTable(conv, "t2", "logs", []interleaveTestCol{{"c4", "id", ddl.Type{Name: ddl.Int64}}}, []string{"c4"}, nil)
+	src := conv.SrcSchema["t2"]
+	src.ColDefs["c4"] = schema.Column{Name: "id", Id: "c4", Ignored: schema.Ignored{AutoIncrement: true}}
+	conv.SrcSchema["t2"] = src
+	src = conv.SrcSchema["t1"]
+	src.ColDefs["c2"] = schema.Column{Name: "id", Id: "c2", AutoGen: ddl.AutoGenCol{GenerationType: constants.AUTO_INCREMENT}}
+	conv.SrcSchema["t1"] = src
+	return conv
+}
+
+func TestDetectHotspots(t *testing.T) {
+	conv := hotspotTestConv()
+	DetectHotspots(conv)
+	DetectHotspots(conv)
+	assert.Equal(t, []SchemaIssue{HotspotTimestamp}, conv.SchemaIssues["t1"].ColumnLevelIssues["c1"])
+	assert.Equal(t, []SchemaIssue{HotspotAutoIncrement}, conv.SchemaIssues["t1"].ColumnLevelIssues["c2"])
+	assert.Equal(t, []SchemaIssue{HotspotAutoIncrement}, conv.SchemaIssues["t2"].ColumnLevelIssues["c4"])
+
+	// Fixed hotspots aren't reported again.
+	assert.Nil(t, conv.FixHotspot("t2", KeyRewrite{Fix: HotspotFixBitReversedSequence}))
+	assert.Empty(t, conv.SchemaIssues["t2"].ColumnLevelIssues["c4"])
+	DetectHotspots(conv)
+	assert.Empty(t, conv.SchemaIssues["t2"].ColumnLevelIssues["c4"])
+}
+
+func TestFixHotspotSequence(t *testing.T) {
+	conv := hotspotTestConv()
+	DetectHotspots(conv)
+	assert.Nil(t, conv.FixHotspot("t2", KeyRewrite{Fix: HotspotFixBitReversedSequence}))
+	r := conv.KeyRewrites["t2"]
+	assert.Equal(t, "c4", r.ColId)
+	seq := conv.SpSequences[r.SequenceId]
+	assert.Equal(t, "logs_id_seq", seq.Name)
+	assert.Equal(t, "BIT REVERSED POSITIVE", seq.SequenceKind)
+	// The sequence skips the values of the migrated keys.
+	assert.Equal(t, "1", seq.SkipRangeMin)
+	assert.Equal(t, "4294967295", seq.SkipRangeMax)
+	assert.Equal(t, map[string][]string{"t2": {"c4"}}, seq.ColumnsUsingSeq)
+	assert.True(t, conv.UsedNames["logs_id_seq"])
+	assert.Equal(t, ddl.AutoGenCol{Name: "logs_id_seq", GenerationType: constants.SEQUENCE}, conv.SpSchema["t2"].ColDefs["c4"].AutoGen)
+	assert.Equal(t, "values of id generated by bit-reversed sequence logs_id_seq, skipping 1 to 4294967295", r.Describe(conv, "t2"))
+
+	// Timestamps can't be generated by sequences.
+	assert.NotNil(t, conv.FixHotspot("t1", KeyRewrite{Fix: HotspotFixBitReversedSequence}))
+	assert.NotNil(t, conv.FixHotspot("t1", KeyRewrite{Fix: HotspotFixBitReversedSequence, ColId: "c2", SkipRangeMax: -1}))
+
+	// Columns already generated by a sequence keep it, with the skip range
+	// added unless it has one.
+	conv.SpSequences["s1"] = ddl.Sequence{Id: "s1", Name: "events_seq", SequenceKind: "BIT REVERSED POSITIVE"}
+	sp := conv.SpSchema["t1"]
+	col := sp.ColDefs["c2"]
+	col.AutoGen = ddl.AutoGenCol{Name: "events_seq", GenerationType: constants.SEQUENCE}
+	sp.ColDefs["c2"] = col
+	assert.Nil(t, conv.FixHotspot("t1", KeyRewrite{Fix: HotspotFixBitReversedSequence, ColId: "c2", SkipRangeMax: 1000}))
+	assert.Equal(t, "s1", conv.KeyRewrites["t1"].SequenceId)
+	assert.Equal(t, "1", conv.SpSequences["s1"].SkipRangeMin)
+	assert.Equal(t, "1000", conv.SpSequences["s1"].SkipRangeMax)
+}
+
+func TestFixHotspotUUID(t *testing.T) {
+	conv := hotspotTestConv()
+	DetectHotspots(conv)
+	assert.Nil(t, conv.FixHotspot("t1", KeyRewrite{Fix: HotspotFixUUID}))
+	r := conv.KeyRewrites["t1"]
+	assert.Equal(t, "c1", r.ColId)
+	sp := conv.SpSchema["t1"]
+	col := sp.ColDefs[r.AddedColId]
+	// The table already has a column named uuid.
+	assert.Equal(t, "uuid_1", col.Name)
+	assert.Equal(t, ddl.Type{Name: ddl.String, Len: 36}, col.T)
+	assert.True(t, col.NotNull)
+	assert.Equal(t, constants.UUID, col.AutoGen.Name)
+	assert.Equal(t, r.AddedColId, sp.ColIds[0])
+	assert.Equal(t, []ddl.IndexKey{{ColId: r.AddedColId, Order: 1}, {ColId: "c1", Order: 2}, {ColId: "c2", Order: 3}}, sp.PrimaryKeys)
+	assert.Equal(t, "UUID column uuid_1 added in front of the primary key", r.Describe(conv, "t1"))
+
+	var rows [][]interface{}
+	conv.SetDataMode()
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		assert.Equal(t, []string{"created", "id", "uuid", "uuid_1"}, cols)
+		rows = append(rows, vals)
+	})
+	cols := []string{"created", "id", "uuid"}
+	conv.WriteRow("events", "events", cols, []interface{}{"2024-01-01T00:00:00Z", int64(7), "x"})
+	conv.WriteRow("events", "events", cols, []interface{}{"2024-01-01T00:00:00Z", int64(7), "y"})
+	assert.Equal(t, 2, len(rows))
+	// The UUID only depends on the key, so rewriting a row keeps its UUID.
+	assert.Equal(t, KeyUUID("events", []string{"2024-01-01T00:00:00Z", "7"}), rows[0][3])
+	assert.Equal(t, rows[0][3], rows[1][3])
+
+	// Rows without the key columns are bad rows.
+	conv.WriteRow("events", "events", []string{"created"}, []interface{}{"2024-01-01T00:00:00Z"})
+	assert.Equal(t, int64(1), conv.BadRows())
+}
+
+func TestFixHotspotHashShard(t *testing.T) {
+	conv := hotspotTestConv()
+	assert.Nil(t, conv.FixHotspot("t2", KeyRewrite{Fix: HotspotFixHashShard, ColId: "c4", ShardCount: 4}))
+	r := conv.KeyRewrites["t2"]
+	sp := conv.SpSchema["t2"]
+	assert.Equal(t, "key_shard", sp.ColDefs[r.AddedColId].Name)
+	assert.Equal(t, ddl.Type{Name: ddl.Int64}, sp.ColDefs[r.AddedColId].T)
+	assert.Equal(t, []ddl.IndexKey{{ColId: r.AddedColId, Order: 1}, {ColId: "c4", Order: 2}}, sp.PrimaryKeys)
+	assert.Equal(t, "column key_shard added in front of the primary key, set to a hash of (id) modulo 4", r.Describe(conv, "t2"))
+	// The column is generated by Spanner, so rows are written as is.
+	assert.Equal(t, ddl.GeneratedColumn{IsPresent: true, Value: ddl.Expression{ExpressionId: sp.ColDefs[r.AddedColId].Generated.Value.ExpressionId, Statement: "ABS(MOD(FARM_FINGERPRINT(IFNULL(CAST(id AS STRING), '')), 4))"}, Stored: true}, sp.ColDefs[r.AddedColId].Generated)
+	assert.Nil(t, conv.getKeyRewriter("logs"))
+
+	assert.NotNil(t, conv.FixHotspot("t2", KeyRewrite{Fix: HotspotFixHashShard}))
+}
+
+func TestKeyShardExpression(t *testing.T) {
+	sp := ddl.CreateTable{ColDefs: map[string]ddl.ColumnDef{
+		"c1": {Name: "created", T: ddl.Type{Name: ddl.Timestamp}, NotNull: true},
+		"c2": {Name: "name", T: ddl.Type{Name: ddl.String, Len: 10}, NotNull: true},
+		"c3": {Name: "hash", T: ddl.Type{Name: ddl.Bytes, Len: 16}},
+	}}
+	colIds := []string{"c1", "c2", "c3"}
+	assert.Equal(t, "ABS(MOD(FARM_FINGERPRINT(CONCAT(CAST(created AS STRING), ',', name, ',', IFNULL(TO_BASE64(hash), ''))), 8))",
+		keyShardExpression(constants.DIALECT_GOOGLESQL, sp, colIds, 8))
+	assert.Equal(t, "abs(mod(spanner.farm_fingerprint(CAST(created AS text) || ',' || name || ',' || COALESCE(encode(hash, 'base64'), '')), 8))",
+		keyShardExpression(constants.DIALECT_POSTGRESQL, sp, colIds, 8))
+}
+
+func TestFixHotspotReorderKey(t *testing.T) {
+	conv := hotspotTestConv()
+	DetectHotspots(conv)
+	assert.Nil(t, conv.FixHotspot("t1", KeyRewrite{Fix: HotspotFixReorderKey}))
+	assert.Equal(t, []ddl.IndexKey{{ColId: "c2", Order: 1}, {ColId: "c1", Order: 2}}, conv.SpSchema["t1"].PrimaryKeys)
+	assert.Equal(t, "primary key reordered to (id, created)", conv.KeyRewrites["t1"].Describe(conv, "t1"))
+	// No column is added, so rows are written as is.
+	assert.Nil(t, conv.getKeyRewriter("events"))
+
+	// A single column key can't be reordered.
+	assert.NotNil(t, conv.FixHotspot("t2", KeyRewrite{Fix: HotspotFixReorderKey}))
+}
+
+func TestFixHotspotErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		tableId string
+		r       KeyRewrite
+	}{
+		{"unknown table", "t9", KeyRewrite{Fix: HotspotFixUUID}},
+		{"no hotspot", "t1", KeyRewrite{Fix: HotspotFixUUID}},
+		{"not a key column", "t1", KeyRewrite{Fix: HotspotFixUUID, ColId: "c3"}},
+		{"unknown fix", "t1", KeyRewrite{Fix: "salt", ColId: "c1"}},
+		{"negative shards", "t1", KeyRewrite{Fix: HotspotFixHashShard, ColId: "c1", ShardCount: -1}},
+		{"hotspot still first", "t1", KeyRewrite{Fix: HotspotFixReorderKey, ColId: "c1", PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}, {ColId: "c2"}}}},
+		{"different key columns", "t1", KeyRewrite{Fix: HotspotFixReorderKey, ColId: "c1", PrimaryKeys: []ddl.IndexKey{{ColId: "c3"}, {ColId: "c1"}}}},
+		{"interleaved", "t3", KeyRewrite{Fix: HotspotFixUUID, ColId: "c5"}},
+	} {
+		conv := hotspotTestConv()
+		addInterleaveTestTable(conv, "t3", "events_data", []interleaveTestCol{{"c5", "created", ddl.Type{Name: ddl.Timestamp}}}, []string{"c5"}, nil)
+		sp := conv.SpSchema["t3"]
+		sp.ParentTable = ddl.InterleavedParent{Id: "t1"}
+		conv.SpSchema["t3"] = sp
+		assert.NotNil(t, conv.FixHotspot(tc.tableId, tc.r), tc.name)
+		assert.Empty(t, conv.KeyRewrites, tc.name)
+	}
+}
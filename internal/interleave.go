@@ -0,0 +1,381 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// MaxInterleaveDepth is the maximum number of levels of interleaving
+// supported by Spanner.
+const MaxInterleaveDepth = 7
+
+// Estimated benefit of an interleave, from the number of child rows per
+// parent row.
+const (
+	InterleaveBenefitHigh    = "high"
+	InterleaveBenefitMedium  = "medium"
+	InterleaveBenefitLow     = "low"
+	InterleaveBenefitUnknown = "unknown" // Row counts are not known.
+)
+
+var interleaveBenefitRank = map[string]int{
+	InterleaveBenefitHigh:    0,
+	InterleaveBenefitMedium:  1,
+	InterleaveBenefitUnknown: 2,
+	InterleaveBenefitLow:     3,
+}
+
+// InterleaveSuggestion is a proposal to interleave a Spanner table in the
+// table referenced by one of its source foreign keys. The interleave can
+// only be applied once all of its Blockers are addressed.
+type InterleaveSuggestion struct {
+	TableId           string
+	Table             string // Spanner name of the child table.
+	ParentTableId     string
+	ParentTable       string // Spanner name of the parent table.
+	ForeignKey        string // Source foreign key replaced by the interleave.
+	ForeignKeyId      string
+	PrimaryKeys       []ddl.IndexKey // Proposed primary key of the child table, the parent key first.
+	PrimaryKey        []string       // Column names of PrimaryKeys.
+	ReorderPrimaryKey bool           // Whether PrimaryKeys reorders the current primary key.
+	Parent            ddl.InterleavedParent
+	Chain             []string // Tables from the root of the interleave hierarchy down to Table.
+	Rows              int64    // Rows of the child table, 0 if unknown.
+	ParentRows        int64    // Rows of the parent table, 0 if unknown.
+	Benefit           string
+	Blockers          []string
+}
+
+// Applicable reports whether nothing blocks the interleave.
+func (s InterleaveSuggestion) Applicable() bool {
+	return len(s.Blockers) == 0
+}
+
+// AnalyzeInterleaving walks the foreign keys of the source schema and
+// proposes to interleave each table in the table referenced by a foreign key
+// covering the whole primary key of the referenced table, so that the
+// child's primary key can be prefixed by the parent's. Tables already
+// interleaved in the referenced table are skipped.
+//
+// The benefit of each interleave is estimated from the number of child rows
+// per parent row, see tableRows. A table can only be interleaved in one
+// parent, so when several are possible, the one with the highest benefit is
+// proposed and the others are reported as blocked. Suggestions are ordered
+// with the applicable ones first, then by benefit.
+func AnalyzeInterleaving(conv *Conv) []InterleaveSuggestion {
+	var suggestions []InterleaveSuggestion
+	for tableId, srcTable := range conv.SrcSchema {
+		child, ok := conv.SpSchema[tableId]
+		if !ok {
+			continue
+		}
+		for _, fk := range srcTable.ForeignKeys {
+			if fk.ReferTableId == tableId || child.ParentTable.Id == fk.ReferTableId {
+				continue
+			}
+			if s, ok := analyzeForeignKey(conv, tableId, fk, sortedIndexKeys(conv.SpSchema[fk.ReferTableId].PrimaryKeys)); ok {
+				suggestions = append(suggestions, s)
+			}
+		}
+	}
+	sortInterleaveSuggestions(suggestions)
+
+	// Pick a parent for each table, without creating cycles.
+	parentOf := make(map[string]string)
+	for tableId, t := range conv.SpSchema {
+		if t.ParentTable.Id != "" {
+			parentOf[tableId] = t.ParentTable.Id
+		}
+	}
+	proposed := make(map[string]string)
+	for i := range suggestions {
+		s := &suggestions[i]
+		if parent, ok := proposed[s.TableId]; ok {
+			s.Blockers = append(s.Blockers, fmt.Sprintf("%s can only be interleaved in one table, and interleaving it in %s is proposed instead", s.Table, conv.SpSchema[parent].Name))
+			continue
+		}
+		if isAncestor(parentOf, s.TableId, s.ParentTableId) {
+			s.Blockers = append(s.Blockers, fmt.Sprintf("interleaving %s in %s would create a cycle, as %s is an ancestor of %s", s.Table, s.ParentTable, s.Table, s.ParentTable))
+			continue
+		}
+		proposed[s.TableId] = s.ParentTableId
+		if s.Applicable() {
+			parentOf[s.TableId] = s.ParentTableId
+		}
+	}
+
+	// The key of the parent prefixes the proposed keys of its children, so
+	// proposals that reorder the key of a table reorder the keys of its
+	// descendants too.
+	accepted := make(map[string]*InterleaveSuggestion)
+	for i := range suggestions {
+		if s := &suggestions[i]; s.Applicable() {
+			accepted[s.TableId] = s
+		}
+	}
+	resolved := make(map[string]bool)
+	var proposedKeys func(tableId string) []ddl.IndexKey
+	proposeKeys := func(s *InterleaveSuggestion) {
+		fk, err := GetSrcFkFromId(conv.SrcSchema[s.TableId].ForeignKeys, s.ForeignKeyId)
+		if err != nil {
+			return
+		}
+		if r, ok := analyzeForeignKey(conv, s.TableId, fk, proposedKeys(s.ParentTableId)); ok {
+			s.PrimaryKeys, s.PrimaryKey, s.ReorderPrimaryKey = r.PrimaryKeys, r.PrimaryKey, r.ReorderPrimaryKey
+		}
+	}
+	proposedKeys = func(tableId string) []ddl.IndexKey {
+		s, ok := accepted[tableId]
+		if !ok {
+			return sortedIndexKeys(conv.SpSchema[tableId].PrimaryKeys)
+		}
+		if !resolved[tableId] {
+			resolved[tableId] = true
+			proposeKeys(s)
+		}
+		return s.PrimaryKeys
+	}
+	for i := range suggestions {
+		if s := &suggestions[i]; accepted[s.TableId] == s {
+			proposedKeys(s.TableId)
+		} else {
+			proposeKeys(s)
+		}
+	}
+
+	for i := range suggestions {
+		s := &suggestions[i]
+		s.Chain = []string{s.Table}
+		for t := s.ParentTableId; t != "" && len(s.Chain) <= MaxInterleaveDepth+1; t = parentOf[t] {
+			s.Chain = append([]string{conv.SpSchema[t].Name}, s.Chain...)
+		}
+		if len(s.Chain)-1 > MaxInterleaveDepth {
+			s.Blockers = append(s.Blockers, fmt.Sprintf("%s would be interleaved more than %d levels deep", s.Table, MaxInterleaveDepth))
+		}
+	}
+	sortInterleaveSuggestions(suggestions)
+	return suggestions
+}
+
+// ApplyInterleaveSuggestion reorders the primary key of the child table of s
+// and interleaves it in its parent, in place of the foreign key of s.
+func ApplyInterleaveSuggestion(conv *Conv, s InterleaveSuggestion) error {
+	if !s.Applicable() {
+		return fmt.Errorf("can't interleave %s in %s: %s", s.Table, s.ParentTable, strings.Join(s.Blockers, "; "))
+	}
+	child, ok := conv.SpSchema[s.TableId]
+	if !ok {
+		return fmt.Errorf("table %s not found", s.Table)
+	}
+	parent, ok := conv.SpSchema[s.ParentTableId]
+	if !ok {
+		return fmt.Errorf("table %s not found", s.ParentTable)
+	}
+	for i, k := range sortedIndexKeys(parent.PrimaryKeys) {
+		if i >= len(s.PrimaryKey) || parent.ColDefs[k.ColId].Name != s.PrimaryKey[i] {
+			return fmt.Errorf("can't interleave %s in %s: the primary key of %s must be reordered first, by applying its proposed interleave", s.Table, s.ParentTable, s.ParentTable)
+		}
+	}
+	child.PrimaryKeys = s.PrimaryKeys
+	child.ParentTable = s.Parent
+	var fks []ddl.Foreignkey
+	for _, fk := range child.ForeignKeys {
+		if fk.Id == s.ForeignKeyId {
+			delete(conv.UsedNames, strings.ToLower(fk.Name))
+			continue
+		}
+		fks = append(fks, fk)
+	}
+	child.ForeignKeys = fks
+	conv.SpSchema[s.TableId] = child
+	return nil
+}
+
+// analyzeForeignKey checks whether the child table can be interleaved in
+// the table referenced by fk, whose primary key is parentPks. It returns
+// false if fk doesn't cover the primary key of the referenced table.
+func analyzeForeignKey(conv *Conv, tableId string, fk schema.ForeignKey, parentPks []ddl.IndexKey) (InterleaveSuggestion, bool) {
+	child := conv.SpSchema[tableId]
+	parent, ok := conv.SpSchema[fk.ReferTableId]
+	if !ok || len(parentPks) == 0 || len(fk.ColIds) != len(fk.ReferColumnIds) {
+		return InterleaveSuggestion{}, false
+	}
+	referencing := make(map[string]string)
+	for i, colId := range fk.ReferColumnIds {
+		referencing[colId] = fk.ColIds[i]
+	}
+	// In sharded migrations, the shard id column is added to the primary
+	// keys but isn't part of the source foreign keys.
+	if parent.ShardIdColumn != "" && child.ShardIdColumn != "" {
+		referencing[parent.ShardIdColumn] = child.ShardIdColumn
+	}
+	if len(referencing) != len(parentPks) {
+		return InterleaveSuggestion{}, false
+	}
+	for _, k := range parentPks {
+		if _, ok := referencing[k.ColId]; !ok {
+			return InterleaveSuggestion{}, false
+		}
+	}
+
+	s := InterleaveSuggestion{
+		TableId:       tableId,
+		Table:         child.Name,
+		ParentTableId: fk.ReferTableId,
+		ParentTable:   parent.Name,
+		ForeignKey:    fk.Name,
+		ForeignKeyId:  fk.Id,
+		Parent:        ddl.InterleavedParent{Id: fk.ReferTableId, OnDelete: interleaveOnDelete(fk.OnDelete)},
+		Rows:          tableRows(conv, tableId),
+		ParentRows:    tableRows(conv, fk.ReferTableId),
+	}
+	s.Benefit = interleaveBenefit(s.Rows, s.ParentRows)
+	if child.ParentTable.Id != "" {
+		s.Blockers = append(s.Blockers, fmt.Sprintf("%s is already interleaved in %s", child.Name, conv.SpSchema[child.ParentTable.Id].Name))
+	}
+	if _, ok := conv.SyntheticPKeys[tableId]; ok {
+		s.Blockers = append(s.Blockers, fmt.Sprintf("%s has a synthetic primary key; add %s to its primary key instead", child.Name, colNames(child, fk.ColIds)))
+	}
+
+	childPks := sortedIndexKeys(child.PrimaryKeys)
+	inPk := make(map[string]ddl.IndexKey)
+	for _, k := range childPks {
+		inPk[k.ColId] = k
+	}
+	prefix := make(map[string]bool)
+	for _, pk := range parentPks {
+		colId := referencing[pk.ColId]
+		childCol, parentCol := child.ColDefs[colId], parent.ColDefs[pk.ColId]
+		k, ok := inPk[colId]
+		if !ok {
+			if _, synth := conv.SyntheticPKeys[tableId]; !synth {
+				s.Blockers = append(s.Blockers, fmt.Sprintf("column %s.%s must be added to the primary key of %s", child.Name, childCol.Name, child.Name))
+			}
+			k = ddl.IndexKey{ColId: colId, Desc: pk.Desc}
+		}
+		if childCol.Name != parentCol.Name {
+			s.Blockers = append(s.Blockers, fmt.Sprintf("column %s.%s must be renamed to %s to match the primary key of %s", child.Name, childCol.Name, parentCol.Name, parent.Name))
+		}
+		if childCol.T != parentCol.T {
+			s.Blockers = append(s.Blockers, fmt.Sprintf("column %s.%s has type %s but %s.%s has type %s", child.Name, childCol.Name, childCol.T.PrintColumnDefType(), parent.Name, parentCol.Name, parentCol.T.PrintColumnDefType()))
+		}
+		prefix[colId] = true
+		s.PrimaryKeys = append(s.PrimaryKeys, ddl.IndexKey{ColId: colId, Desc: k.Desc})
+	}
+	for _, k := range childPks {
+		if !prefix[k.ColId] {
+			s.PrimaryKeys = append(s.PrimaryKeys, ddl.IndexKey{ColId: k.ColId, Desc: k.Desc})
+		}
+	}
+	for i := range s.PrimaryKeys {
+		s.PrimaryKeys[i].Order = i + 1
+		s.PrimaryKey = append(s.PrimaryKey, child.ColDefs[s.PrimaryKeys[i].ColId].Name)
+		if i < len(childPks) && childPks[i].ColId != s.PrimaryKeys[i].ColId {
+			s.ReorderPrimaryKey = true
+		}
+	}
+	return s, true
+}
+
+// interleaveOnDelete maps the delete rule of a source foreign key to the
+// delete rule of an interleave: rows are deleted with their parent only if
+// the foreign key cascades deletes.
+func interleaveOnDelete(srcDeleteRule string) string {
+	if strings.ToUpper(srcDeleteRule) == constants.FK_CASCADE {
+		return constants.FK_CASCADE
+	}
+	return constants.FK_NO_ACTION
+}
+
+// tableRows returns the number of rows of a source table: the rows read by
+// the data migration once they are known, else the rows counted during
+// schema conversion, or 0 if neither is known.
+func tableRows(conv *Conv, tableId string) int64 {
+	if n := conv.Stats.Rows[conv.SrcSchema[tableId].Name]; n > 0 {
+		return n
+	}
+	return conv.SrcRowCounts[tableId]
+}
+
+// interleaveBenefit estimates the benefit of interleaving a table in
+// another from their row counts. Interleaving stores the child rows with
+// their parent row, so it pays off most when each parent has many children
+// that are read or written together with it.
+func interleaveBenefit(rows, parentRows int64) string {
+	if rows == 0 || parentRows == 0 {
+		return InterleaveBenefitUnknown
+	}
+	perParent := float64(rows) / float64(parentRows)
+	switch {
+	case perParent >= 10:
+		return InterleaveBenefitHigh
+	case perParent >= 1:
+		return InterleaveBenefitMedium
+	default:
+		return InterleaveBenefitLow
+	}
+}
+
+func sortInterleaveSuggestions(suggestions []InterleaveSuggestion) {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Applicable() != b.Applicable() {
+			return a.Applicable()
+		}
+		if interleaveBenefitRank[a.Benefit] != interleaveBenefitRank[b.Benefit] {
+			return interleaveBenefitRank[a.Benefit] < interleaveBenefitRank[b.Benefit]
+		}
+		if a.Rows != b.Rows {
+			return a.Rows > b.Rows
+		}
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.ParentTable < b.ParentTable
+	})
+}
+
+// isAncestor reports whether ancestor is tableId or one of its ancestors in
+// the interleave hierarchy given by parentOf.
+func isAncestor(parentOf map[string]string, ancestor, tableId string) bool {
+	for seen := make(map[string]bool); tableId != "" && !seen[tableId]; tableId = parentOf[tableId] {
+		if tableId == ancestor {
+			return true
+		}
+		seen[tableId] = true
+	}
+	return false
+}
+
+func sortedIndexKeys(keys []ddl.IndexKey) []ddl.IndexKey {
+	sorted := append([]ddl.IndexKey{}, keys...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+	return sorted
+}
+
+func colNames(t ddl.CreateTable, colIds []string) string {
+	var names []string
+	for _, colId := range colIds {
+		names = append(names, t.ColDefs[colId].Name)
+	}
+	return strings.Join(names, ", ")
+}
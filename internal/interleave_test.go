@@ -0,0 +1,181 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+type interleaveTestCol struct {
+	id, name string
+	t        ddl.Type
+}
+
+// addInterleaveTestTable adds a table with the same source and Spanner
+// names to conv.
+func addInterleaveTestTable(conv *Conv, id, name string, cols []interleaveTestCol, pks []string, fks []schema.ForeignKey) {
+	src := schema.Table{Name: name, Id: id, ColDefs: map[string]schema.Column{}, ForeignKeys: fks}
+	sp := ddl.CreateTable{Name: name, Id: id, ColDefs: map[string]ddl.ColumnDef{}}
+	for _, c := range cols {
+		src.ColIds = append(src.ColIds, c.id)
+		src.ColDefs[c.id] = schema.Column{Name: c.name, Id: c.id}
+		sp.ColIds = append(sp.ColIds, c.id)
+		sp.ColDefs[c.id] = ddl.ColumnDef{Name: c.name, Id: c.id, T: c.t}
+	}
+	for i, pk := range pks {
+		src.PrimaryKeys = append(src.PrimaryKeys, schema.Key{ColId: pk, Order: i + 1})
+		sp.PrimaryKeys = append(sp.PrimaryKeys, ddl.IndexKey{ColId: pk, Order: i + 1})
+	}
+	for _, fk := range fks {
+		sp.ForeignKeys = append(sp.ForeignKeys, ddl.Foreignkey{Name: fk.Name, Id: fk.Id, ColIds: fk.ColIds, ReferTableId: fk.ReferTableId, ReferColumnIds: fk.ReferColumnIds})
+	}
+	conv.SrcSchema[id] = src
+	conv.SpSchema[id] = sp
+}
+
+func interleaveTestConv() *Conv {
+	conv := MakeConv()
+	int64Type := ddl.Type{Name: ddl.Int64}
+	addInterleaveTestTable(conv, "t1", "Singers", []interleaveTestCol{{"c1", "SingerId", int64Type}}, []string{"c1"}, nil)
+	addInterleaveTestTable(conv, "t2", "Albums",
+		[]interleaveTestCol{{"c2", "AlbumId", int64Type}, {"c3", "SingerId", int64Type}},
+		[]string{"c2", "c3"},
+		[]schema.ForeignKey{{Name: "fk_albums", Id: "f1", ColIds: []string{"c3"}, ReferTableId: "t1", ReferColumnIds: []string{"c1"}, OnDelete: "cascade"}})
+	addInterleaveTestTable(conv, "t3", "Songs",
+		[]interleaveTestCol{{"c4", "SingerId", int64Type}, {"c5", "AlbumId", int64Type}, {"c6", "SongId", int64Type}},
+		[]string{"c5", "c4", "c6"},
+		[]schema.ForeignKey{{Name: "fk_songs", Id: "f2", ColIds: []string{"c4", "c5"}, ReferTableId: "t2", ReferColumnIds: []string{"c3", "c2"}}})
+	addInterleaveTestTable(conv, "t4", "Reviews",
+		[]interleaveTestCol{{"c7", "ReviewId", int64Type}, {"c8", "Singer", ddl.Type{Name: ddl.String, Len: ddl.MaxLength}}, {"c9", "Label", int64Type}},
+		[]string{"c7"},
+		[]schema.ForeignKey{
+			{Name: "fk_reviews", Id: "f3", ColIds: []string{"c8"}, ReferTableId: "t1", ReferColumnIds: []string{"c1"}},
+			// Doesn't reference the primary key of Labels.
+			{Name: "fk_labels", Id: "f4", ColIds: []string{"c9"}, ReferTableId: "t5", ReferColumnIds: []string{"c11"}},
+		})
+	addInterleaveTestTable(conv, "t5", "Labels", []interleaveTestCol{{"c10", "LabelId", int64Type}, {"c11", "Code", int64Type}}, []string{"c10"}, nil)
+	conv.Stats.Rows = map[string]int64{"Singers": 100, "Albums": 1000, "Songs": 500}
+	return conv
+}
+
+func TestAnalyzeInterleaving(t *testing.T) {
+	conv := interleaveTestConv()
+	suggestions := AnalyzeInterleaving(conv)
+	assert.Equal(t, 3, len(suggestions))
+
+	albums := suggestions[0]
+	assert.Equal(t, "Albums", albums.Table)
+	assert.Equal(t, "Singers", albums.ParentTable)
+	assert.Equal(t, "fk_albums", albums.ForeignKey)
+	assert.Equal(t, []ddl.IndexKey{{ColId: "c3", Order: 1}, {ColId: "c2", Order: 2}}, albums.PrimaryKeys)
+	assert.Equal(t, []string{"SingerId", "AlbumId"}, albums.PrimaryKey)
+	assert.True(t, albums.ReorderPrimaryKey)
+	assert.Equal(t, ddl.InterleavedParent{Id: "t1", OnDelete: constants.FK_CASCADE}, albums.Parent)
+	assert.Equal(t, []string{"Singers", "Albums"}, albums.Chain)
+	assert.Equal(t, InterleaveBenefitHigh, albums.Benefit)
+	assert.Empty(t, albums.Blockers)
+
+	// The key of Songs follows the proposed key of Albums.
+	songs := suggestions[1]
+	assert.Equal(t, "Songs", songs.Table)
+	assert.Equal(t, []string{"SingerId", "AlbumId", "SongId"}, songs.PrimaryKey)
+	assert.True(t, songs.ReorderPrimaryKey)
+	assert.Equal(t, constants.FK_NO_ACTION, songs.Parent.OnDelete)
+	assert.Equal(t, []string{"Singers", "Albums", "Songs"}, songs.Chain)
+	assert.Equal(t, InterleaveBenefitLow, songs.Benefit)
+	assert.Empty(t, songs.Blockers)
+
+	reviews := suggestions[2]
+	assert.Equal(t, "Reviews", reviews.Table)
+	assert.Equal(t, InterleaveBenefitUnknown, reviews.Benefit)
+	assert.Equal(t, []string{
+		"column Reviews.Singer must be added to the primary key of Reviews",
+		"column Reviews.Singer must be renamed to SingerId to match the primary key of Singers",
+		"column Reviews.Singer has type STRING(MAX) but Singers.SingerId has type INT64",
+	}, reviews.Blockers)
+}
+
+func TestAnalyzeInterleavingSrcRowCounts(t *testing.T) {
+	conv := interleaveTestConv()
+	// Row counts read during schema conversion are used until the data
+	// migration has counted the rows.
+	conv.Stats.Rows = map[string]int64{"Albums": 300}
+	conv.SrcRowCounts = map[string]int64{"t1": 100, "t2": 150}
+	suggestions := AnalyzeInterleaving(conv)
+	albums := suggestions[0]
+	assert.Equal(t, "Albums", albums.Table)
+	assert.Equal(t, int64(300), albums.Rows)
+	assert.Equal(t, int64(100), albums.ParentRows)
+	assert.Equal(t, InterleaveBenefitMedium, albums.Benefit)
+}
+
+func TestAnalyzeInterleavingOneParent(t *testing.T) {
+	conv := MakeConv()
+	int64Type := ddl.Type{Name: ddl.Int64}
+	addInterleaveTestTable(conv, "t1", "A", []interleaveTestCol{{"c1", "Id", int64Type}}, []string{"c1"},
+		[]schema.ForeignKey{{Name: "fk_a", Id: "f1", ColIds: []string{"c1"}, ReferTableId: "t2", ReferColumnIds: []string{"c2"}}})
+	addInterleaveTestTable(conv, "t2", "B", []interleaveTestCol{{"c2", "Id", int64Type}}, []string{"c2"},
+		[]schema.ForeignKey{{Name: "fk_b", Id: "f2", ColIds: []string{"c2"}, ReferTableId: "t1", ReferColumnIds: []string{"c1"}}})
+	addInterleaveTestTable(conv, "t3", "C", []interleaveTestCol{{"c3", "Id", int64Type}}, []string{"c3"},
+		[]schema.ForeignKey{
+			{Name: "fk_c_a", Id: "f3", ColIds: []string{"c3"}, ReferTableId: "t1", ReferColumnIds: []string{"c1"}},
+			{Name: "fk_c_b", Id: "f4", ColIds: []string{"c3"}, ReferTableId: "t2", ReferColumnIds: []string{"c2"}},
+		})
+	conv.Stats.Rows = map[string]int64{"A": 10, "B": 10, "C": 20}
+
+	suggestions := AnalyzeInterleaving(conv)
+	assert.Equal(t, 4, len(suggestions))
+	var blockers [][]string
+	for _, s := range suggestions {
+		blockers = append(blockers, s.Blockers)
+	}
+	assert.Equal(t, [][]string{
+		nil,
+		nil,
+		{"C can only be interleaved in one table, and interleaving it in A is proposed instead"},
+		{"interleaving B in A would create a cycle, as B is an ancestor of A"},
+	}, blockers)
+	assert.Equal(t, "C", suggestions[0].Table)
+	assert.Equal(t, []string{"B", "A", "C"}, suggestions[0].Chain)
+	assert.Equal(t, "A", suggestions[1].Table)
+}
+
+func TestApplyInterleaveSuggestion(t *testing.T) {
+	conv := interleaveTestConv()
+	conv.UsedNames = map[string]bool{"fk_albums": true, "fk_songs": true}
+	suggestions := AnalyzeInterleaving(conv)
+
+	// Songs can only be interleaved once the key of Albums is reordered.
+	assert.NotNil(t, ApplyInterleaveSuggestion(conv, suggestions[1]))
+	assert.NotNil(t, ApplyInterleaveSuggestion(conv, suggestions[2]))
+
+	assert.Nil(t, ApplyInterleaveSuggestion(conv, suggestions[0]))
+	albums := conv.SpSchema["t2"]
+	assert.Equal(t, []ddl.IndexKey{{ColId: "c3", Order: 1}, {ColId: "c2", Order: 2}}, albums.PrimaryKeys)
+	assert.Equal(t, ddl.InterleavedParent{Id: "t1", OnDelete: constants.FK_CASCADE}, albums.ParentTable)
+	assert.Empty(t, albums.ForeignKeys)
+	assert.False(t, conv.UsedNames["fk_albums"])
+
+	suggestions = AnalyzeInterleaving(conv)
+	assert.Equal(t, "Songs", suggestions[0].Table)
+	assert.Nil(t, ApplyInterleaveSuggestion(conv, suggestions[0]))
+	assert.Equal(t, "t2", conv.SpSchema["t3"].ParentTable.Id)
+}
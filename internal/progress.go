@@ -36,6 +36,8 @@ type Progress struct {
 	verbose    bool   // If true, print detailed info about each progress step.
 	fractional bool   // If true, report progress in fractions instead of percentages.
 	ProgressStatus
+	detail  func() string // If set, returns details printed after the percentage.
+	lineLen int           // Length of the last line printed with details.
 }
 
 // ProgressStatus specifies a stage of migration.
@@ -50,11 +52,13 @@ const (
 	DataWriteInProgress
 	ForeignKeyUpdateInProgress
 	ForeignKeyUpdateComplete
+	IndexCreationInProgress
+	IndexCreationComplete
 )
 
 // NewProgress creates and returns a Progress instance.
 func NewProgress(total int64, message string, verbose, fractional bool, progressStatus int) *Progress {
-	p := &Progress{total, 0, 0, message, verbose, fractional, ProgressStatus(progressStatus)}
+	p := &Progress{total: total, message: message, verbose: verbose, fractional: fractional, ProgressStatus: ProgressStatus(progressStatus)}
 	if total == 0 {
 		p.pct = 100
 	}
@@ -100,7 +104,17 @@ func (p *Progress) Done() {
 	p.MaybeReport(p.total)
 }
 
+// SetDetail sets a function returning details, e.g. throughput, that are
+// printed after the percentage each time progress is reported.
+func (p *Progress) SetDetail(detail func() string) {
+	p.detail = detail
+}
+
 func (p *Progress) reportPct(firstCall bool) {
+	if p.detail != nil {
+		p.reportPctWithDetail()
+		return
+	}
 	if p.verbose {
 		fmt.Printf("%s: %2d%%\n", p.message, p.pct)
 		return
@@ -116,6 +130,23 @@ func (p *Progress) reportPct(firstCall bool) {
 	}
 }
 
+// reportPctWithDetail rewrites the whole line, since the length of the
+// details varies between calls.
+func (p *Progress) reportPctWithDetail() {
+	detail := p.detail()
+	line := fmt.Sprintf("%s: %2d%% [%s]", p.message, p.pct, detail)
+	if p.verbose {
+		fmt.Println(line)
+		return
+	}
+	logger.Log.Debug(p.message, zap.Int("Progress", p.pct), zap.String("Detail", detail))
+	fmt.Printf("\r%-*s", p.lineLen, line)
+	p.lineLen = len(line)
+	if p.pct == 100 {
+		fmt.Printf("\n")
+	}
+}
+
 func (p *Progress) reportFraction(firstCall bool) {
 	if p.verbose {
 		fmt.Printf("%s: %d/%d\n", p.message, p.progress, p.total)
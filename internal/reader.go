@@ -27,6 +27,7 @@ type Reader struct {
 	LineNumber int // Starting at line 1
 	Offset     int // Character offset from start of input. Starts with character 1.
 	EOF        bool
+	Path       string // Path of the input, if known. Directory dumps (pg_dump -Fd) are read from it.
 	r          *bufio.Reader
 	progress   *Progress
 }
@@ -58,3 +59,27 @@ func (r *Reader) ReadLine() []byte {
 	}
 	return b
 }
+
+// Read reads up to len(p) bytes of input, for dump formats that aren't
+// line based. It implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.EOF {
+		return 0, io.EOF
+	}
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		r.EOF = true
+	}
+	r.Offset += n
+	if r.progress != nil {
+		r.progress.MaybeReport(int64(r.Offset - 1))
+	}
+	return n, err
+}
+
+// Peek returns the next n bytes of input without consuming them, or fewer
+// bytes if the input is shorter.
+func (r *Reader) Peek(n int) []byte {
+	b, _ := r.r.Peek(n)
+	return b
+}
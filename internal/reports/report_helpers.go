@@ -497,6 +497,12 @@ func buildTableReportBody(conv *internal.Conv, tableId string, issues map[string
 					}
 					l = append(l, toAppend)
 
+				case internal.GeneratedColumn:
+					toAppend := Issue{
+						Category:    IssueDB[i].Category,
+						Description: fmt.Sprintf("Table '%s': Column '%s': %s", conv.SpSchema[tableId].Name, spColName, IssueDB[i].Brief),
+					}
+					l = append(l, toAppend)
 				case internal.DefaultValueError:
 					toAppend := Issue{
 						Category:    IssueDB[i].Category,
@@ -583,12 +589,14 @@ func getPkOrderForReport(pks []ddl.IndexKey, colId string) (int, error) {
 }
 
 func fillRowStats(conv *internal.Conv, srcTable string, badWrites map[string]int64, tr *tableReport) {
-	rows := conv.Stats.Rows[srcTable]
+	filteredRows := conv.Stats.FilteredRows[srcTable]
+	rows := conv.Stats.Rows[srcTable] - filteredRows
 	goodConvRows := conv.Stats.GoodRows[srcTable]
 	badConvRows := conv.Stats.BadRows[srcTable]
 	badRowWrites := badWrites[srcTable]
 	// Note on rows:
-	// rows: all rows we encountered during processing.
+	// rows: all rows we encountered during processing, except the ones
+	// skipped by the row filter of the table (filteredRows).
 	// goodConvRows: rows we successfully converted.
 	// badConvRows: rows we failed to convert.
 	// badRowWrites: rows we converted, but could not write to Spanner.
@@ -597,6 +605,7 @@ func fillRowStats(conv *internal.Conv, srcTable string, badWrites map[string]int
 	}
 	tr.rows = rows
 	tr.badRows = badConvRows + badRowWrites
+	tr.filteredRows = filteredRows
 }
 
 // IssueDB provides a description and severity for each schema issue.
@@ -672,6 +681,7 @@ var IssueDB = map[internal.SchemaIssue]struct {
 	internal.ForeignKeyOnUpdate:           {Brief: "Spanner supports only ON UPDATE NO ACTION", Severity: warning, Category: "FOREIGN_KEY_ACTIONS"},
 	internal.ForeignKeyActionNotSupported: {Brief: "Spanner supports foreign key action migration only for MySQL and PostgreSQL", Severity: warning, Category: "FOREIGN_KEY_ACTIONS"},
 	internal.NumericPKNotSupported:        {Brief: "Spanner PostgreSQL does not support numeric primary keys / unique indices", Severity: warning, Category: "NUMERIC_PK_NOT_SUPPORTED"},
+	internal.GeneratedColumn:              {Brief: "The expression of this generated column couldn't be converted to Spanner. The column is migrated as a plain column holding the source values", Severity: warning, Category: "GENERATED_COLUMN_NOT_CONVERTED"},
 	internal.DefaultValueError:            {Brief: "Some columns have default value expressions not supported by Spanner. Please fix them to continue migration.", Severity: Errors, batch: true, Category: "INCOMPATIBLE_DEFAULT_VALUE_CONSTRAINTS"},
 }
 
@@ -819,7 +829,7 @@ func GenerateSummary(conv *internal.Conv, r []tableReport, badWrites map[string]
 	// provides per-table stats for each table in the schema i.e. it omits
 	// rows for tables not in the schema. To handle this corner-case, use
 	// the source of truth for row stats: conv.Stats.
-	rows := conv.Rows()
+	rows := conv.Rows() - conv.FilteredRows()
 	badRows := conv.BadRows() // Bad rows encountered during data conversion.
 	// Add in bad rows while writing to Spanner.
 	for _, n := range badWrites {
@@ -42,6 +42,12 @@ func (r *ReportImpl) GenerateTextReport(structuredReport StructuredReport, w *bu
 		writeStatementStats(structuredReport, w)
 	}
 	writeNameChanges(structuredReport, w)
+	writeColumnTransformations(structuredReport, w)
+	writeKeyRewrites(structuredReport, w)
+	writeUnconvertedObjects(structuredReport, w)
+	writeInterleaveSuggestions(structuredReport, w)
+	writeShardKeyCollisions(structuredReport, w)
+	writeFailedIndexes(structuredReport, w)
 	writeTableReports(structuredReport, w)
 	writeUnexpectedConditionsv2(structuredReport, w)
 
@@ -129,6 +135,9 @@ func writeTableReports(structuredReport StructuredReport, w *bufio.Writer) {
 			}
 			s := fmt.Sprintf(" (%s%% of %d rows %s to Spanner)", pct(tableReport.DataReport.TotalRows, tableReport.DataReport.BadRows), tableReport.DataReport.TotalRows, dataRatingText)
 			dataRatingText = tableReport.DataReport.Rating + s
+			if tableReport.DataReport.FilteredRows > 0 {
+				dataRatingText += fmt.Sprintf(", %d rows skipped by the row filter", tableReport.DataReport.FilteredRows)
+			}
 			rate = rate + fmt.Sprintf("Data conversion: %s.\n", dataRatingText)
 		}
 		w.WriteString(rate)
@@ -159,6 +168,168 @@ func writeNameChanges(structuredReport StructuredReport, w *bufio.Writer) {
 	}
 }
 
+// writeColumnTransformations lists the columns whose values are transformed
+// during data migration. Nothing is written if there are none.
+func writeColumnTransformations(structuredReport StructuredReport, w *bufio.Writer) {
+	if len(structuredReport.ColumnTransformations) == 0 {
+		return
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	w.WriteString("Column Transformations in Data Migration\n")
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	fmt.Fprintf(w, "%25s %25s   %s\n", "Spanner Table", "Spanner Column", "Transformation")
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	for _, t := range structuredReport.ColumnTransformations {
+		fmt.Fprintf(w, "%25s %25s   %s\n", t.SpannerTable, t.SpannerColumn, t.Transformation)
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n\n\n")
+}
+
+// writeKeyRewrites lists the primary keys rewritten to avoid hotspots.
+// Nothing is written if there are none.
+func writeKeyRewrites(structuredReport StructuredReport, w *bufio.Writer) {
+	if len(structuredReport.KeyRewrites) == 0 {
+		return
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	w.WriteString("Primary Key Rewrites\n")
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	fmt.Fprintf(w, "%25s %25s   %s\n", "Spanner Table", "Hotspot Column", "Rewrite")
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	for _, r := range structuredReport.KeyRewrites {
+		fmt.Fprintf(w, "%25s %25s   %s\n", r.SpannerTable, r.SpannerColumn, r.Description)
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n\n\n")
+}
+
+// writeUnconvertedObjects lists the views, triggers, stored procedures and
+// functions of the source database, followed by guidance for each type of
+// object. Nothing is written if there are none.
+func writeUnconvertedObjects(structuredReport StructuredReport, w *bufio.Writer) {
+	if len(structuredReport.UnconvertedObjects) == 0 {
+		return
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	w.WriteString("Unconverted Views, Triggers, Stored Procedures and Functions\n")
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	fmt.Fprintf(w, "%10s %25s %25s %6s   %s\n", "Type", "Name", "Table (Event)", "Lines", "Referenced Tables")
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	var types []string
+	guidance := map[string]string{}
+	for _, o := range structuredReport.UnconvertedObjects {
+		table := o.Table
+		if o.Event != "" {
+			table = fmt.Sprintf("%s (%s)", o.Table, o.Event)
+		}
+		fmt.Fprintf(w, "%10s %25s %25s %6d   %s\n", o.ObjectType, o.Name, table, o.LinesOfCode, strings.Join(o.ReferencedTables, ", "))
+		if _, ok := guidance[o.ObjectType]; !ok {
+			types = append(types, o.ObjectType)
+			guidance[o.ObjectType] = o.Guidance
+		}
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	for _, t := range types {
+		justifyLines(w, fmt.Sprintf("%s: %s", t, guidance[t]), 80, 2)
+		w.WriteString("\n")
+	}
+	w.WriteString("\n\n")
+}
+
+// writeInterleaveSuggestions lists the proposed interleaves, with the
+// primary key changes they need and the issues that block them. Nothing is
+// written if there are none.
+// writeFailedIndexes lists the secondary indexes that couldn't be created
+// after data migration.
+func writeFailedIndexes(structuredReport StructuredReport, w *bufio.Writer) {
+	if len(structuredReport.FailedIndexes) == 0 {
+		return
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	w.WriteString("Failed Secondary Indexes\n")
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	justifyLines(w, "These secondary indexes couldn't be created after data migration. "+
+		"The data was migrated, but queries can't use the indexes until they are created, e.g. with the CREATE INDEX statements of the schema file.", 80, 0)
+	w.WriteString("\n")
+	for _, f := range structuredReport.FailedIndexes {
+		fmt.Fprintf(w, "\n%s on table %s\n", f.IndexName, f.SpannerTable)
+		fmt.Fprintf(w, "    Error: %s\n", f.Error)
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n\n\n")
+}
+
+// writeShardKeyCollisions lists the tables with primary keys found in more
+// than one shard. Nothing is written if there are none.
+func writeShardKeyCollisions(structuredReport StructuredReport, w *bufio.Writer) {
+	if len(structuredReport.ShardKeyCollisions) == 0 {
+		return
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	w.WriteString("Cross-Shard Key Collisions\n")
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	justifyLines(w, "These tables have primary keys found in more than one shard. "+
+		"Only one row per key can be written to Spanner, the rows of the other shards are rejected. "+
+		"Add the shard id column to the primary keys with an add_shard_id_primary_key rule to keep the rows of all shards.", 80, 0)
+	w.WriteString("\n")
+	for _, c := range structuredReport.ShardKeyCollisions {
+		status := "not fixed"
+		if c.Fixed {
+			status = "fixed, shard id column added to the primary key"
+		}
+		fmt.Fprintf(w, "\n%s (%s)\n", c.SpannerTable, status)
+		if c.KeysRead > 0 {
+			scope := "all keys"
+			if c.Sampled {
+				scope = "first keys of each shard"
+			}
+			fmt.Fprintf(w, "    Colliding keys: %d of %d keys read (%s)\n", c.CollidingKeys, c.KeysRead, scope)
+		}
+		for _, e := range c.Examples {
+			fmt.Fprintf(w, "    Example: %s\n", e)
+		}
+		if c.RejectedRows > 0 {
+			fmt.Fprintf(w, "    Rows rejected during data migration: %d\n", c.RejectedRows)
+		}
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n\n\n")
+}
+
+func writeInterleaveSuggestions(structuredReport StructuredReport, w *bufio.Writer) {
+	if len(structuredReport.InterleaveSuggestions) == 0 {
+		return
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	w.WriteString("Interleaving Suggestions\n")
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n")
+	justifyLines(w, "Interleaving a table in its parent stores the child rows with their parent row, "+
+		"which makes reads and writes of a parent with its children faster. "+
+		"The benefit is estimated from the number of child rows per parent row, when row counts are known.", 80, 0)
+	w.WriteString("\n")
+	for _, s := range structuredReport.InterleaveSuggestions {
+		status := "possible"
+		if len(s.Blockers) > 0 {
+			status = "blocked"
+		}
+		fmt.Fprintf(w, "\n%s in %s (%s, benefit: %s)\n", s.Table, s.ParentTable, status, s.Benefit)
+		fmt.Fprintf(w, "    Hierarchy: %s\n", strings.Join(s.Chain, " > "))
+		if s.ForeignKey != "" {
+			fmt.Fprintf(w, "    Replaces foreign key: %s\n", s.ForeignKey)
+		}
+		if s.Rows > 0 && s.ParentRows > 0 {
+			fmt.Fprintf(w, "    Rows: %d in %s, %d in %s\n", s.Rows, s.Table, s.ParentRows, s.ParentTable)
+		}
+		pk := strings.Join(s.PrimaryKey, ", ")
+		if s.ReorderPrimaryKey {
+			pk += " (reordered)"
+		}
+		fmt.Fprintf(w, "    Primary key: %s\n", pk)
+		fmt.Fprintf(w, "    DDL: INTERLEAVE IN PARENT %s ON DELETE %s\n", s.ParentTable, s.OnDelete)
+		for _, b := range s.Blockers {
+			fmt.Fprintf(w, "    Blocked: %s\n", b)
+		}
+	}
+	w.WriteString("-----------------------------------------------------------------------------------------------------\n\n\n")
+}
+
 func writeStatementStats(structuredReport StructuredReport, w *bufio.Writer) {
 	type stat struct {
 		statement string
@@ -16,10 +16,13 @@
 package reports
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/proto/migration"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
 )
 
 // A report consists of the following parts:
@@ -30,8 +33,11 @@ import (
 // 4. Migration Type
 // 5. Statement stats (in case of dumps)
 // 6. Name changes
-// 7. Individual table reports (Detailed + Quality of conversion for each)
-// 8. Unexpected conditions
+// 7. Column transformations (if any)
+// 8. Unconverted views, triggers, stored procedures and functions (if any)
+// 9. Primary keys found in more than one shard (if any)
+// 10. Individual table reports (Detailed + Quality of conversion for each)
+// 11. Unexpected conditions
 //
 // This method the RAW structured report in JSON format. Several utilities can be built on top of
 // this raw, nested JSON data to output the reports in different user and machine friendly formats
@@ -67,15 +73,27 @@ func (r *ReportImpl) GenerateStructuredReport(driverName string, dbName string,
 		smtReport.StatementStats.StatementStats = fetchStatementStats(driverName, conv)
 	}
 
-	//7. Name changes
+	//7. Name changes, column transformations and primary key rewrites
 	smtReport.NameChanges = fetchNameChanges(conv)
+	smtReport.ColumnTransformations = fetchColumnTransformations(conv)
+	smtReport.KeyRewrites = fetchKeyRewrites(conv)
 
-	//8. Table Reports
+	//8. Unconverted views, triggers, stored procedures and functions
+	smtReport.UnconvertedObjects = fetchUnconvertedObjects(conv)
+
+	//9. Interleaving suggestions
+	smtReport.InterleaveSuggestions = fetchInterleaveSuggestions(conv)
+
+	//10. Primary keys shared by several shards
+	smtReport.ShardKeyCollisions = fetchShardKeyCollisions(conv)
+	smtReport.FailedIndexes = fetchFailedIndexes(conv)
+
+	//11. Table Reports
 	if printTableReports {
 		smtReport.TableReports = fetchTableReports(tableReports, conv)
 	}
 
-	//9. Unexpected Conditions
+	//12. Unexpected Conditions
 	if printUnexpecteds {
 		smtReport.UnexpectedConditions = fetchUnexceptedConditions(driverName, conv)
 	}
@@ -123,6 +141,149 @@ func fetchStatementStats(driverName string, conv *internal.Conv) (statementStats
 	return statementStats
 }
 
+func fetchColumnTransformations(conv *internal.Conv) (transformations []ColumnTransformation) {
+	for tableId, cols := range conv.ColumnTransformations {
+		spTable, ok := conv.SpSchema[tableId]
+		if !ok {
+			continue
+		}
+		for colId, t := range cols {
+			spCol, ok := spTable.ColDefs[colId]
+			if !ok {
+				continue
+			}
+			transformations = append(transformations, ColumnTransformation{SpannerTable: spTable.Name, SpannerColumn: spCol.Name, Transformation: t.Describe(conv, tableId)})
+		}
+	}
+	sort.Slice(transformations, func(i, j int) bool {
+		if transformations[i].SpannerTable != transformations[j].SpannerTable {
+			return transformations[i].SpannerTable < transformations[j].SpannerTable
+		}
+		return transformations[i].SpannerColumn < transformations[j].SpannerColumn
+	})
+	return transformations
+}
+
+func fetchKeyRewrites(conv *internal.Conv) (rewrites []KeyRewrite) {
+	for _, tableId := range conv.KeyRewriteTables() {
+		r := conv.KeyRewrites[tableId]
+		spTable := conv.SpSchema[tableId]
+		rewrites = append(rewrites, KeyRewrite{SpannerTable: spTable.Name, SpannerColumn: spTable.ColDefs[r.ColId].Name, Fix: string(r.Fix), Description: r.Describe(conv, tableId)})
+	}
+	return rewrites
+}
+
+// unconvertedObjectGuidance maps the kinds of source objects to guidance on
+// migrating them by hand.
+var unconvertedObjectGuidance = map[string]string{
+	schema.View: "Recreate the view with CREATE VIEW ... SQL SECURITY INVOKER, " +
+		"rewriting its query in the dialect of the Spanner database.",
+	schema.Trigger: "Spanner doesn't support triggers. Move the logic to the " +
+		"application, in the transactions that write the table, or use change " +
+		"streams for logic that can run asynchronously.",
+	schema.Procedure: "Spanner doesn't support stored procedures. Move the " +
+		"logic to the application.",
+	schema.Function: "Spanner doesn't support user-defined functions. Rewrite " +
+		"the queries that use the function with built-in functions, or move the " +
+		"logic to the application.",
+}
+
+// unconvertedObjectOrder is the order of the kinds of source objects in the
+// report.
+var unconvertedObjectOrder = map[string]int{schema.View: 0, schema.Trigger: 1, schema.Procedure: 2, schema.Function: 3}
+
+func fetchUnconvertedObjects(conv *internal.Conv) (objects []UnconvertedObject) {
+	for _, o := range conv.SrcObjects {
+		lines := 0
+		if body := strings.TrimSpace(o.Body); body != "" {
+			lines = strings.Count(body, "\n") + 1
+		}
+		objects = append(objects, UnconvertedObject{
+			ObjectType:       o.Kind,
+			Name:             o.Name,
+			Table:            o.Table,
+			Event:            o.Event,
+			ReferencedTables: o.ReferencedTables,
+			LinesOfCode:      lines,
+			Guidance:         unconvertedObjectGuidance[o.Kind],
+		})
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		if objects[i].ObjectType != objects[j].ObjectType {
+			return unconvertedObjectOrder[objects[i].ObjectType] < unconvertedObjectOrder[objects[j].ObjectType]
+		}
+		return objects[i].Name < objects[j].Name
+	})
+	return objects
+}
+
+func fetchInterleaveSuggestions(conv *internal.Conv) (suggestions []InterleaveSuggestion) {
+	for _, s := range internal.AnalyzeInterleaving(conv) {
+		suggestions = append(suggestions, InterleaveSuggestion{
+			Table:             s.Table,
+			ParentTable:       s.ParentTable,
+			ForeignKey:        s.ForeignKey,
+			PrimaryKey:        s.PrimaryKey,
+			ReorderPrimaryKey: s.ReorderPrimaryKey,
+			OnDelete:          s.Parent.OnDelete,
+			Chain:             s.Chain,
+			Rows:              s.Rows,
+			ParentRows:        s.ParentRows,
+			Benefit:           s.Benefit,
+			Blockers:          s.Blockers,
+		})
+	}
+	return suggestions
+}
+
+// fetchFailedIndexes lists the secondary indexes that couldn't be created
+// after data migration, by table and index name.
+func fetchFailedIndexes(conv *internal.Conv) (failed []FailedIndex) {
+	for _, f := range conv.FailedIndexes {
+		failed = append(failed, FailedIndex{IndexName: f.Name, SpannerTable: f.Table, Error: f.Error})
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		if failed[i].SpannerTable != failed[j].SpannerTable {
+			return failed[i].SpannerTable < failed[j].SpannerTable
+		}
+		return failed[i].IndexName < failed[j].IndexName
+	})
+	return failed
+}
+
+// fetchShardKeyCollisions lists the tables with primary keys found in more
+// than one shard, either by the shard key analysis or because Spanner
+// rejected rows of another shard with the same key during data migration.
+func fetchShardKeyCollisions(conv *internal.Conv) (collisions []ShardKeyCollision) {
+	drops := conv.ShardKeyDrops()
+	for _, tableId := range conv.CollidingShardKeyTables() {
+		c := conv.ShardKeyCollisions[tableId]
+		spTable := conv.SpSchema[tableId]
+		sc := ShardKeyCollision{
+			SpannerTable:  spTable.Name,
+			KeysRead:      c.KeysRead,
+			Sampled:       c.Sampled,
+			CollidingKeys: c.Colliding,
+			RejectedRows:  drops[spTable.Name],
+			Fixed:         internal.ShardIdInPrimaryKey(spTable),
+		}
+		for _, k := range c.Samples {
+			sc.Examples = append(sc.Examples, fmt.Sprintf("(%s) in shards %s", strings.Join(k.Key, ", "), strings.Join(k.ShardIds, ", ")))
+		}
+		collisions = append(collisions, sc)
+		delete(drops, spTable.Name)
+	}
+	var tables []string
+	for t := range drops {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		collisions = append(collisions, ShardKeyCollision{SpannerTable: t, RejectedRows: drops[t]})
+	}
+	return collisions
+}
+
 func fetchNameChanges(conv *internal.Conv) (nameChanges []NameChange) {
 	for tableId, spTable := range conv.SpSchema {
 		srcTable := conv.SrcSchema[tableId]
@@ -180,6 +341,7 @@ func fetchTableReports(inputTableReports []tableReport, conv *internal.Conv) (ta
 		schemaOnly := conv.SchemaMode()
 		if !schemaOnly {
 			tableReport.DataReport = getDataReport(t.rows, t.badRows, conv.Audit.DryRun)
+			tableReport.DataReport.FilteredRows = t.filteredRows
 		}
 		//4. Issues
 		for _, x := range t.Body {
@@ -15,6 +15,7 @@ type tableReport struct {
 	SpTable       string
 	rows          int64
 	badRows       int64
+	filteredRows  int64
 	Cols          int64
 	Warnings      int64
 	Errors        int64
@@ -64,6 +65,70 @@ type NameChange struct {
 	NewName        string `json:"newName"`
 }
 
+// ColumnTransformation describes a transformation applied to the values of a
+// column during bulk data migration.
+type ColumnTransformation struct {
+	SpannerTable   string `json:"spannerTable"`
+	SpannerColumn  string `json:"spannerColumn"`
+	Transformation string `json:"transformation"`
+}
+
+// KeyRewrite describes a hotspot fix applied to the primary key of a table.
+type KeyRewrite struct {
+	SpannerTable  string `json:"spannerTable"`
+	SpannerColumn string `json:"spannerColumn"` // Key column with the hotspot.
+	Fix           string `json:"fix"`
+	Description   string `json:"description"`
+}
+
+// UnconvertedObject is a view, trigger, stored procedure or function of the
+// source database. These objects are not converted to Spanner.
+type UnconvertedObject struct {
+	ObjectType       string   `json:"objectType"`
+	Name             string   `json:"name"`
+	Table            string   `json:"table,omitempty"` // For triggers.
+	Event            string   `json:"event,omitempty"` // For triggers.
+	ReferencedTables []string `json:"referencedTables"`
+	LinesOfCode      int      `json:"linesOfCode"`
+	Guidance         string   `json:"guidance"`
+}
+
+// InterleaveSuggestion proposes to interleave a Spanner table in the table
+// referenced by one of its foreign keys, with the issues blocking it.
+type InterleaveSuggestion struct {
+	Table             string   `json:"table"`
+	ParentTable       string   `json:"parentTable"`
+	ForeignKey        string   `json:"foreignKey"`
+	PrimaryKey        []string `json:"primaryKey"`
+	ReorderPrimaryKey bool     `json:"reorderPrimaryKey"`
+	OnDelete          string   `json:"onDelete"`
+	Chain             []string `json:"chain"`
+	Rows              int64    `json:"rows,omitempty"`
+	ParentRows        int64    `json:"parentRows,omitempty"`
+	Benefit           string   `json:"benefit"`
+	Blockers          []string `json:"blockers,omitempty"`
+}
+
+// ShardKeyCollision describes the primary keys of a Spanner table that were
+// found in more than one shard of a sharded migration.
+// FailedIndex is a secondary index that couldn't be created after data
+// migration.
+type FailedIndex struct {
+	IndexName    string `json:"indexName"`
+	SpannerTable string `json:"spannerTable"`
+	Error        string `json:"error"`
+}
+
+type ShardKeyCollision struct {
+	SpannerTable  string   `json:"spannerTable"`
+	KeysRead      int64    `json:"keysRead,omitempty"`
+	Sampled       bool     `json:"sampled,omitempty"` // Whether only the first keys of each shard were read.
+	CollidingKeys int64    `json:"collidingKeys,omitempty"`
+	Examples      []string `json:"examples,omitempty"`
+	RejectedRows  int64    `json:"rejectedRows,omitempty"` // Rows rejected during data migration because another shard wrote the same key.
+	Fixed         bool     `json:"fixed"`                  // Whether the shard id column is now part of the primary key.
+}
+
 type Issues struct {
 	IssueType string  `json:"issueType"`
 	IssueList []Issue `json:"issueList"`
@@ -83,10 +148,11 @@ type SchemaReport struct {
 }
 
 type DataReport struct {
-	Rating    string `json:"rating"`
-	BadRows   int64  `json:"badRows"`
-	TotalRows int64  `json:"totalRows"`
-	DryRun    bool   `json:"dryRun"`
+	Rating       string `json:"rating"`
+	BadRows      int64  `json:"badRows"`
+	TotalRows    int64  `json:"totalRows"`
+	FilteredRows int64  `json:"filteredRows,omitempty"`
+	DryRun       bool   `json:"dryRun"`
 }
 
 type TableReport struct {
@@ -108,16 +174,22 @@ type UnexpectedConditions struct {
 }
 
 type StructuredReport struct {
-	Summary              Summary              `json:"summary"`
-	IsSharded            bool                 `json:"isSharded"`
-	IgnoredStatements    []IgnoredStatement   `json:"ignoredStatements"`
-	ConversionMetadata   []ConversionMetadata `json:"conversionMetadata"`
-	MigrationType        string               `json:"migrationType"`
-	StatementStats       StatementStats       `json:"statementStats"`
-	NameChanges          []NameChange         `json:"nameChanges"`
-	TableReports         []TableReport        `json:"tableReports"`
-	UnexpectedConditions UnexpectedConditions `json:"unexpectedConditions"`
-	SchemaOnly           bool                 `json:"-"`
+	Summary               Summary                `json:"summary"`
+	IsSharded             bool                   `json:"isSharded"`
+	IgnoredStatements     []IgnoredStatement     `json:"ignoredStatements"`
+	ConversionMetadata    []ConversionMetadata   `json:"conversionMetadata"`
+	MigrationType         string                 `json:"migrationType"`
+	StatementStats        StatementStats         `json:"statementStats"`
+	NameChanges           []NameChange           `json:"nameChanges"`
+	ColumnTransformations []ColumnTransformation `json:"columnTransformations,omitempty"`
+	KeyRewrites           []KeyRewrite           `json:"keyRewrites,omitempty"`
+	UnconvertedObjects    []UnconvertedObject    `json:"unconvertedObjects,omitempty"`
+	InterleaveSuggestions []InterleaveSuggestion `json:"interleaveSuggestions,omitempty"`
+	ShardKeyCollisions    []ShardKeyCollision    `json:"shardKeyCollisions,omitempty"`
+	FailedIndexes         []FailedIndex          `json:"failedIndexes,omitempty"`
+	TableReports          []TableReport          `json:"tableReports"`
+	UnexpectedConditions  UnexpectedConditions   `json:"unexpectedConditions"`
+	SchemaOnly            bool                   `json:"-"`
 }
 
 type ReportInterface interface {
@@ -125,4 +197,44 @@ type ReportInterface interface {
 	GenerateTextReport(structuredReport StructuredReport, w *bufio.Writer)
 }
 
-type ReportImpl struct {}
\ No newline at end of file
+type ReportImpl struct {}
+// DataValidationReport is the result of validating the data migrated to
+// Spanner against the source database.
+type DataValidationReport struct {
+	DbName  string                `json:"dbName"`
+	Summary DataValidationSummary `json:"summary"`
+	Tables  []TableValidation     `json:"tables"`
+}
+
+type DataValidationSummary struct {
+	Tables           int `json:"tables"`
+	MatchedTables    int `json:"matchedTables"`
+	MismatchedTables int `json:"mismatchedTables"`
+	SkippedTables    int `json:"skippedTables"`
+	FailedTables     int `json:"failedTables"`
+}
+
+type TableValidation struct {
+	SrcTableName       string          `json:"srcTableName"`
+	SpTableName        string          `json:"spTableName"`
+	Status             string          `json:"status"` // One of MATCH, MISMATCH, SKIPPED or ERROR.
+	Reason             string          `json:"reason,omitempty"`
+	SrcRowCount        int64           `json:"srcRowCount"`
+	SpRowCount         int64           `json:"spRowCount"`
+	Chunks             int             `json:"chunks"`
+	MismatchedChunks   []ChunkMismatch `json:"mismatchedChunks"`
+	MismatchedKeyCount int64           `json:"mismatchedKeyCount"`
+	MismatchedKeys     []KeyMismatch   `json:"mismatchedKeys"`            // Capped, see MismatchedKeyCount for the total.
+	UnhashedColumns    []string        `json:"unhashedColumns,omitempty"` // Spanner columns not hashed in the databases, only compared with --compare-values.
+}
+
+type ChunkMismatch struct {
+	Chunk   string `json:"chunk"`
+	SrcRows int64  `json:"srcRows"`
+	SpRows  int64  `json:"spRows"`
+}
+
+type KeyMismatch struct {
+	Key  []string `json:"key"`
+	Kind string   `json:"kind"` // One of MISSING_IN_SPANNER, EXTRA_IN_SPANNER or VALUE_MISMATCH.
+}
@@ -0,0 +1,757 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// Row filters restrict the rows of a source table that are migrated. A row
+// filter is a boolean expression in the style of a SQL WHERE clause, set in
+// the RowFilter field of the source table. SQL sources push the filter down
+// into the query that reads the rows of the table, so any expression of the
+// source database is allowed. For the other sources (dump files, CSV files
+// and DynamoDB), the filter is evaluated on each row after it is converted to
+// Spanner values, and supports the following subset of SQL:
+//
+//	comparisons: col = 1, col <> 'a', col != 'a', col < 1.5, col <= 2, col > 3, col >= 4
+//	col [NOT] IN ('a', 'b'), col [NOT] LIKE 'a%', col [NOT] BETWEEN 1 AND 10
+//	col IS [NOT] NULL, combined with AND, OR, NOT and parentheses
+//
+// Columns are referred to by their source names, optionally quoted with
+// double quotes or backticks. Literals are numbers, single quoted strings,
+// TRUE, FALSE and NULL. Comparisons with NULL are never true, like in SQL.
+//
+// RowFilter is a row filter parsed for client-side evaluation.
+type RowFilter struct {
+	expr filterExpr
+	cols []string // Columns referenced by the filter.
+}
+
+// ParseRowFilter parses the row filter s for client-side evaluation.
+func ParseRowFilter(s string) (*RowFilter, error) {
+	toks, err := lexFilter(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid row filter %q: %v", s, err)
+	}
+	p := &filterParser{toks: toks, cols: map[string]bool{}}
+	expr, err := p.parseOr()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid row filter %q: %v", s, err)
+	}
+	return &RowFilter{expr: expr, cols: sortedKeys(p.cols)}, nil
+}
+
+// Columns returns the names of the columns referenced by f, sorted.
+func (f *RowFilter) Columns() []string {
+	return f.cols
+}
+
+// Match reports whether the row with the given column values satisfies f.
+// row maps source column names to converted values; missing columns are
+// NULL.
+func (f *RowFilter) Match(row map[string]interface{}) bool {
+	return f.expr.eval(row) == triTrue
+}
+
+// tri is a value of SQL's three-valued logic.
+type tri int8
+
+const (
+	triFalse tri = iota
+	triTrue
+	triUnknown
+)
+
+func triOf(b bool) tri {
+	if b {
+		return triTrue
+	}
+	return triFalse
+}
+
+func (t tri) not() tri {
+	switch t {
+	case triTrue:
+		return triFalse
+	case triFalse:
+		return triTrue
+	}
+	return triUnknown
+}
+
+type filterExpr interface {
+	eval(row map[string]interface{}) tri
+}
+
+type andExpr struct{ l, r filterExpr }
+
+func (e andExpr) eval(row map[string]interface{}) tri {
+	l, r := e.l.eval(row), e.r.eval(row)
+	switch {
+	case l == triFalse || r == triFalse:
+		return triFalse
+	case l == triTrue && r == triTrue:
+		return triTrue
+	}
+	return triUnknown
+}
+
+type orExpr struct{ l, r filterExpr }
+
+func (e orExpr) eval(row map[string]interface{}) tri {
+	l, r := e.l.eval(row), e.r.eval(row)
+	switch {
+	case l == triTrue || r == triTrue:
+		return triTrue
+	case l == triFalse && r == triFalse:
+		return triFalse
+	}
+	return triUnknown
+}
+
+type notExpr struct{ e filterExpr }
+
+func (e notExpr) eval(row map[string]interface{}) tri {
+	return e.e.eval(row).not()
+}
+
+type cmpExpr struct {
+	op   string
+	l, r operand
+}
+
+func (e cmpExpr) eval(row map[string]interface{}) tri {
+	c, ok := compareValues(e.l.value(row), e.r.value(row))
+	if !ok {
+		return triUnknown
+	}
+	switch e.op {
+	case "=":
+		return triOf(c == 0)
+	case "<>", "!=":
+		return triOf(c != 0)
+	case "<":
+		return triOf(c < 0)
+	case "<=":
+		return triOf(c <= 0)
+	case ">":
+		return triOf(c > 0)
+	case ">=":
+		return triOf(c >= 0)
+	}
+	return triUnknown
+}
+
+type isNullExpr struct {
+	o   operand
+	not bool
+}
+
+func (e isNullExpr) eval(row map[string]interface{}) tri {
+	return triOf(e.o.value(row).null != e.not)
+}
+
+type inExpr struct {
+	o    operand
+	list []operand
+	not  bool
+}
+
+func (e inExpr) eval(row map[string]interface{}) tri {
+	v := e.o.value(row)
+	res := triFalse
+	for _, o := range e.list {
+		c, ok := compareValues(v, o.value(row))
+		if !ok {
+			res = triUnknown
+		} else if c == 0 {
+			res = triTrue
+			break
+		}
+	}
+	if e.not {
+		return res.not()
+	}
+	return res
+}
+
+type likeExpr struct {
+	o   operand
+	re  *regexp.Regexp
+	not bool
+}
+
+func (e likeExpr) eval(row map[string]interface{}) tri {
+	v := e.o.value(row)
+	if v.null {
+		return triUnknown
+	}
+	return triOf(e.re.MatchString(v.String()) != e.not)
+}
+
+type betweenExpr struct {
+	o, lo, hi operand
+	not       bool
+}
+
+func (e betweenExpr) eval(row map[string]interface{}) tri {
+	v := e.o.value(row)
+	res := andExpr{cmpExpr{">=", constOperand{v}, e.lo}, cmpExpr{"<=", constOperand{v}, e.hi}}.eval(row)
+	if e.not {
+		return res.not()
+	}
+	return res
+}
+
+// filterValue is a value in a row filter: NULL, a number, a string or a bool.
+type filterValue struct {
+	null  bool
+	num   *big.Rat
+	str   string
+	isStr bool
+	b     bool
+}
+
+func (v filterValue) String() string {
+	switch {
+	case v.num != nil:
+		return ratString(v.num)
+	case v.isStr:
+		return v.str
+	}
+	return strconv.FormatBool(v.b)
+}
+
+// toFilterValue converts a column value to a filterValue.
+func toFilterValue(x interface{}) filterValue {
+	switch x := x.(type) {
+	case int64:
+		return filterValue{num: new(big.Rat).SetInt64(x)}
+	case float64:
+		if r, ok := new(big.Rat).SetString(strconv.FormatFloat(x, 'g', -1, 64)); ok {
+			return filterValue{num: r}
+		}
+	case big.Rat:
+		return filterValue{num: &x}
+	case *big.Rat:
+		if x != nil {
+			return filterValue{num: x}
+		}
+	case bool:
+		return filterValue{b: x}
+	}
+	s, ok := valueString(x)
+	if !ok {
+		return filterValue{null: true}
+	}
+	return filterValue{str: s, isStr: true}
+}
+
+// compareValues compares a and b, converting strings to numbers or bools
+// when compared to one. Returns false if the values can't be compared, e.g.
+// because one of them is NULL.
+func compareValues(a, b filterValue) (int, bool) {
+	if a.null || b.null {
+		return 0, false
+	}
+	switch {
+	case a.num != nil && b.num != nil:
+		return a.num.Cmp(b.num), true
+	case a.isStr && b.isStr:
+		return strings.Compare(a.str, b.str), true
+	case a.isStr:
+		c, ok := compareValues(b, a)
+		return -c, ok
+	case a.num != nil && b.isStr:
+		r, ok := new(big.Rat).SetString(strings.TrimSpace(b.str))
+		if !ok {
+			return strings.Compare(a.String(), b.str), true
+		}
+		return a.num.Cmp(r), true
+	case a.num == nil && b.num == nil && !b.isStr:
+		return compareBools(a.b, b.b), true
+	case a.num == nil && b.isStr:
+		bv, err := strconv.ParseBool(b.str)
+		if err != nil {
+			return 0, false
+		}
+		return compareBools(a.b, bv), true
+	}
+	return 0, false
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+type operand interface {
+	value(row map[string]interface{}) filterValue
+}
+
+type columnOperand struct{ name string }
+
+func (o columnOperand) value(row map[string]interface{}) filterValue {
+	return toFilterValue(row[o.name])
+}
+
+type constOperand struct{ v filterValue }
+
+func (o constOperand) value(map[string]interface{}) filterValue {
+	return o.v
+}
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokQuotedIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+}
+
+func lexFilter(s string) ([]filterToken, error) {
+	var toks []filterToken
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			// Quotes are escaped by doubling them, like in SQL.
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(r); j++ {
+				if r[j] == c {
+					if j+1 < len(r) && r[j+1] == c {
+						b.WriteRune(c)
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteRune(r[j])
+			}
+			if j >= len(r) {
+				return nil, fmt.Errorf("unterminated quote %c", c)
+			}
+			kind := tokQuotedIdent
+			if c == '\'' {
+				kind = tokString
+			}
+			toks = append(toks, filterToken{kind, b.String()})
+			i = j + 1
+		case unicode.IsDigit(c) || (c == '.' || c == '-') && i+1 < len(r) && unicode.IsDigit(r[i+1]):
+			j := i + 1
+			for j < len(r) && (unicode.IsDigit(r[j]) || r[j] == '.' || r[j] == 'e' || r[j] == 'E' ||
+				(r[j] == '-' || r[j] == '+') && (r[j-1] == 'e' || r[j-1] == 'E')) {
+				j++
+			}
+			toks = append(toks, filterToken{tokNumber, string(r[i:j])})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(r) && (unicode.IsLetter(r[j]) || unicode.IsDigit(r[j]) || r[j] == '_' || r[j] == '$') {
+				j++
+			}
+			toks = append(toks, filterToken{tokIdent, string(r[i:j])})
+			i = j
+		case strings.ContainsRune("(),", c):
+			toks = append(toks, filterToken{tokOp, string(c)})
+			i++
+		case strings.ContainsRune("=<>!", c):
+			j := i + 1
+			if j < len(r) && (r[j] == '=' || c == '<' && r[j] == '>') {
+				j++
+			}
+			op := string(r[i:j])
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected %q", op)
+			}
+			toks = append(toks, filterToken{tokOp, op})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return toks, nil
+}
+
+type filterParser struct {
+	toks []filterToken
+	pos  int
+	cols map[string]bool
+}
+
+// keyword reports whether the next token is the keyword kw, and consumes it
+// if so.
+func (p *filterParser) keyword(kw string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos].kind == tokIdent && strings.EqualFold(p.toks[p.pos].text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// op reports whether the next token is the operator op, and consumes it if
+// so.
+func (p *filterParser) op(op string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos].kind == tokOp && p.toks[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expect(op string) error {
+	if !p.op(op) {
+		return p.unexpected(fmt.Sprintf("%q", op))
+	}
+	return nil
+}
+
+func (p *filterParser) unexpected(want string) error {
+	if p.pos >= len(p.toks) {
+		return fmt.Errorf("expected %s at end of filter", want)
+	}
+	return fmt.Errorf("expected %s, found %q", want, p.toks[p.pos].text)
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	l, err := p.parseAnd()
+	for err == nil && p.keyword("OR") {
+		var r filterExpr
+		r, err = p.parseAnd()
+		l = orExpr{l, r}
+	}
+	return l, err
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	l, err := p.parseNot()
+	for err == nil && p.keyword("AND") {
+		var r filterExpr
+		r, err = p.parseNot()
+		l = andExpr{l, r}
+	}
+	return l, err
+}
+
+func (p *filterParser) parseNot() (filterExpr, error) {
+	if p.keyword("NOT") {
+		e, err := p.parseNot()
+		return notExpr{e}, err
+	}
+	return p.parsePredicate()
+}
+
+func (p *filterParser) parsePredicate() (filterExpr, error) {
+	if p.op("(") {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	}
+	o, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("IS") {
+		not := p.keyword("NOT")
+		if !p.keyword("NULL") {
+			return nil, p.unexpected("NULL")
+		}
+		return isNullExpr{o, not}, nil
+	}
+	not := p.keyword("NOT")
+	switch {
+	case p.keyword("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		e := inExpr{o: o, not: not}
+		for {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			e.list = append(e.list, item)
+			if !p.op(",") {
+				break
+			}
+		}
+		return e, p.expect(")")
+	case p.keyword("LIKE"):
+		pattern, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		c, ok := pattern.(constOperand)
+		if !ok || !c.v.isStr {
+			return nil, fmt.Errorf("LIKE pattern must be a string")
+		}
+		return likeExpr{o, likeRegexp(c.v.str), not}, nil
+	case p.keyword("BETWEEN"):
+		lo, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, p.unexpected("AND")
+		}
+		hi, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenExpr{o, lo, hi, not}, nil
+	case not:
+		return nil, p.unexpected("IN, LIKE or BETWEEN")
+	}
+	for _, op := range []string{"=", "<>", "!=", "<=", ">=", "<", ">"} {
+		if p.op(op) {
+			r, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return cmpExpr{op, o, r}, nil
+		}
+	}
+	return nil, p.unexpected("comparison")
+}
+
+func (p *filterParser) parseOperand() (operand, error) {
+	if p.pos >= len(p.toks) {
+		return nil, p.unexpected("column or value")
+	}
+	t := p.toks[p.pos]
+	switch t.kind {
+	case tokString:
+		p.pos++
+		return constOperand{filterValue{str: t.text, isStr: true}}, nil
+	case tokNumber:
+		r, ok := new(big.Rat).SetString(t.text)
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		p.pos++
+		return constOperand{filterValue{num: r}}, nil
+	case tokQuotedIdent:
+		p.pos++
+		p.cols[t.text] = true
+		return columnOperand{t.text}, nil
+	case tokIdent:
+		switch {
+		case p.keyword("NULL"):
+			return constOperand{filterValue{null: true}}, nil
+		case p.keyword("TRUE"):
+			return constOperand{filterValue{b: true}}, nil
+		case p.keyword("FALSE"):
+			return constOperand{filterValue{b: false}}, nil
+		}
+		for _, kw := range []string{"AND", "OR", "NOT", "IS", "IN", "LIKE", "BETWEEN"} {
+			if strings.EqualFold(t.text, kw) {
+				return nil, p.unexpected("column or value")
+			}
+		}
+		p.pos++
+		p.cols[t.text] = true
+		return columnOperand{t.text}, nil
+	}
+	return nil, p.unexpected("column or value")
+}
+
+// likeRegexp converts a LIKE pattern to a regular expression.
+func likeRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, c := range pattern {
+		switch c {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// tableRowFilter is the row filter of a source table, compiled for
+// client-side evaluation.
+type tableRowFilter struct {
+	tableId    string
+	filter     *RowFilter
+	err        error             // Error parsing the filter.
+	srcNames   map[string]string // Maps Spanner column names to source column names.
+	pushedDown atomic.Bool       // Set when the filter is evaluated by the source database.
+}
+
+// getRowFilter returns the row filter of the table migrated to the Spanner
+// table spTable, or nil if the table has no row filter.
+func (conv *Conv) getRowFilter(spTable string) *tableRowFilter {
+	conv.rowFiltersOnce.Do(func() {
+		conv.rowFilters = make(map[string]*tableRowFilter)
+		for tableId, srcTable := range conv.SrcSchema {
+			sp, ok := conv.SpSchema[tableId]
+			if srcTable.RowFilter == "" || !ok {
+				continue
+			}
+			rf := &tableRowFilter{tableId: tableId, srcNames: make(map[string]string)}
+			rf.filter, rf.err = ParseRowFilter(srcTable.RowFilter)
+			for colId, col := range sp.ColDefs {
+				if srcCol, ok := srcTable.ColDefs[colId]; ok {
+					rf.srcNames[col.Name] = srcCol.Name
+				}
+			}
+			conv.rowFilters[sp.Name] = rf
+		}
+	})
+	return conv.rowFilters[spTable]
+}
+
+// PushDownRowFilter returns the row filter of source table tableId, for a SQL
+// source to add to the WHERE clause of the query that reads the rows of the
+// table. The rows of the table are then no longer filtered client-side.
+// Returns an empty string if the table has no row filter.
+func (conv *Conv) PushDownRowFilter(tableId string) string {
+	if rf := conv.getRowFilter(conv.SpSchema[tableId].Name); rf != nil {
+		rf.pushedDown.Store(true)
+	}
+	return conv.SrcSchema[tableId].RowFilter
+}
+
+// ValidateRowFilters checks that the row filters of the source tables can be
+// evaluated client-side, i.e. that they parse and only refer to migrated
+// columns. It's not needed for sources that push row filters down.
+func (conv *Conv) ValidateRowFilters() error {
+	for _, tableId := range sortedKeys(conv.SrcSchema) {
+		srcTable := conv.SrcSchema[tableId]
+		if srcTable.RowFilter == "" {
+			continue
+		}
+		f, err := ParseRowFilter(srcTable.RowFilter)
+		if err != nil {
+			return fmt.Errorf("table %s: %v", srcTable.Name, err)
+		}
+		colNameIdMap := GetSrcColNameIdMap(srcTable)
+		for _, c := range f.Columns() {
+			colId, ok := colNameIdMap[c]
+			if !ok {
+				return fmt.Errorf("row filter of table %s refers to unknown column %s", srcTable.Name, c)
+			}
+			if _, ok := conv.SpSchema[tableId].ColDefs[colId]; !ok {
+				return fmt.Errorf("row filter of table %s refers to column %s, which isn't migrated to Spanner", srcTable.Name, c)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePushDownRowFilters checks that the row filters of the source tables
+// can be added to the WHERE clause of the queries that read the tables, for
+// the sources that push row filters down. The filters are SQL expressions of
+// the source database, which checks them, but they must not end the
+// expression they are embedded in: their parentheses must be balanced, and
+// they can't contain statement separators or comments outside of quotes.
+func (conv *Conv) ValidatePushDownRowFilters() error {
+	for _, tableId := range sortedKeys(conv.SrcSchema) {
+		srcTable := conv.SrcSchema[tableId]
+		if srcTable.RowFilter == "" {
+			continue
+		}
+		if err := checkPushDownRowFilter(srcTable.RowFilter); err != nil {
+			return fmt.Errorf("row filter of table %s: %v", srcTable.Name, err)
+		}
+	}
+	return nil
+}
+
+func checkPushDownRowFilter(s string) error {
+	r := []rune(s)
+	depth := 0
+	for i := 0; i < len(r); i++ {
+		switch c := r[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// Doubled quotes inside quotes are handled as two quoted strings.
+			j := i + 1
+			for j < len(r) && r[j] != c {
+				j++
+			}
+			if j >= len(r) {
+				return fmt.Errorf("unterminated quote %c", c)
+			}
+			i = j
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced parentheses")
+			}
+		case c == ';':
+			return fmt.Errorf("unexpected %q", c)
+		case c == '-' && i+1 < len(r) && r[i+1] == '-', c == '/' && i+1 < len(r) && r[i+1] == '*':
+			return fmt.Errorf("comments are not supported")
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("unbalanced parentheses")
+	}
+	return nil
+}
+
+// filterRow reports whether the row of spTable with the Spanner columns
+// spCols and values spVals passes the row filter of its table. Rows of
+// tables whose filter is pushed down into the source query always pass.
+func (rf *tableRowFilter) filterRow(spCols []string, spVals []interface{}) (bool, error) {
+	if rf.pushedDown.Load() {
+		return true, nil
+	}
+	if rf.err != nil {
+		return false, rf.err
+	}
+	row := make(map[string]interface{}, len(spCols))
+	for i, c := range spCols {
+		if name, ok := rf.srcNames[c]; ok {
+			row[name] = spVals[i]
+		}
+	}
+	return rf.filter.Match(row), nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+func TestRowFilterMatch(t *testing.T) {
+	row := map[string]interface{}{
+		"tenant_id":  int64(7),
+		"price":      *big.NewRat(25, 2),
+		"name":       "O'Brien",
+		"active":     true,
+		"created_on": civil.Date{Year: 2024, Month: 3, Day: 1},
+		"updated_at": time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		"note":       nil,
+	}
+	for _, tc := range []struct {
+		filter string
+		match  bool
+	}{
+		{"tenant_id = 7", true},
+		{"tenant_id <> 7", false},
+		{"`tenant_id` != '8'", true},
+		{`"tenant_id" IN (1, 7)`, true},
+		{"tenant_id NOT IN (1, 7)", false},
+		{"price > 12.4 AND price <= 12.5", true},
+		{"price BETWEEN 1 AND 10", false},
+		{"price NOT BETWEEN 1 AND 10", true},
+		{"name = 'O''Brien'", true},
+		{"name LIKE 'O%n' and active = TRUE", true},
+		{"name NOT LIKE 'O_B%'", false},
+		{"created_on >= '2024-01-01'", true},
+		{"updated_at < '2024-03-01T12:00:00Z'", true},
+		{"note = 'x' OR tenant_id = 7", true},
+		{"note = 'x'", false},
+		{"NOT note = 'x'", false},
+		{"note IS NULL AND name IS NOT NULL", true},
+		{"note NOT IN ('a') OR NOT (tenant_id < -1)", true},
+		{"missing IS NULL", true},
+	} {
+		f, err := ParseRowFilter(tc.filter)
+		assert.Nil(t, err, tc.filter)
+		assert.Equal(t, tc.match, f.Match(row), tc.filter)
+	}
+}
+
+func TestParseRowFilterErrors(t *testing.T) {
+	for _, filter := range []string{
+		"",
+		"tenant_id",
+		"tenant_id = ",
+		"tenant_id = 7 AND",
+		"(tenant_id = 7",
+		"tenant_id = 7)",
+		"name = 'abc",
+		"tenant_id ! 7",
+		"tenant_id NOT = 7",
+		"name LIKE tenant_id",
+		"lower(name) = 'a'",
+	} {
+		_, err := ParseRowFilter(filter)
+		assert.NotNil(t, err, filter)
+	}
+	f, err := ParseRowFilter("b = 1 OR (a IS NULL AND b > a)")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, f.Columns())
+}
+
+func buildRowFilterConv(filter string) *Conv {
+	conv := MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Id:        "t1",
+		Name:      "orders",
+		ColIds:    []string{"c1", "c2", "c3"},
+		ColDefs:   map[string]schema.Column{"c1": {Id: "c1", Name: "id"}, "c2": {Id: "c2", Name: "tenant"}, "c3": {Id: "c3", Name: "legacy"}},
+		RowFilter: filter,
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Id:     "t1",
+		Name:   "Orders",
+		ColIds: []string{"c1", "c2"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Id: "c1", Name: "Id", T: ddl.Type{Name: ddl.Int64}},
+			"c2": {Id: "c2", Name: "Tenant", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		},
+	}
+	return conv
+}
+
+func TestWriteRowFilter(t *testing.T) {
+	conv := buildRowFilterConv("tenant = 'acme'")
+	assert.Nil(t, conv.ValidateRowFilters())
+	var ids []interface{}
+	conv.SetDataMode()
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		ids = append(ids, vals[0])
+	})
+	cols := []string{"Id", "Tenant"}
+	conv.WriteRow("orders", "Orders", cols, []interface{}{int64(1), "acme"})
+	conv.WriteRow("orders", "Orders", cols, []interface{}{int64(2), "globex"})
+	conv.WriteRow("orders", "Orders", cols, []interface{}{int64(3), nil})
+	assert.Equal(t, []interface{}{int64(1)}, ids)
+	assert.Equal(t, int64(1), conv.Stats.GoodRows["orders"])
+	assert.Equal(t, int64(2), conv.Stats.FilteredRows["orders"])
+	assert.Equal(t, int64(0), conv.BadRows())
+
+	// Rows of tables whose filter is pushed down aren't filtered again.
+	assert.Equal(t, "tenant = 'acme'", conv.PushDownRowFilter("t1"))
+	conv.WriteRow("orders", "Orders", cols, []interface{}{int64(4), "globex"})
+	assert.Equal(t, []interface{}{int64(1), int64(4)}, ids)
+}
+
+func TestValidateRowFilters(t *testing.T) {
+	assert.Nil(t, buildRowFilterConv("").ValidateRowFilters())
+	assert.NotNil(t, buildRowFilterConv("tenant = ").ValidateRowFilters())
+	assert.NotNil(t, buildRowFilterConv("customer = 1").ValidateRowFilters())
+	assert.NotNil(t, buildRowFilterConv("legacy = 1").ValidateRowFilters())
+}
+
+func TestValidatePushDownRowFilters(t *testing.T) {
+	for _, filter := range []string{
+		"",
+		"tenant = 'acme'",
+		"lower(tenant) IN ('a;b', 'it''s') AND (id % 2 = 0)",
+		`"weird)name" = '--'`,
+	} {
+		assert.Nil(t, buildRowFilterConv(filter).ValidatePushDownRowFilters(), filter)
+	}
+	for _, filter := range []string{
+		"id = 1; DROP TABLE orders",
+		"id = 1) OR (1 = 1",
+		"(id = 1",
+		"id = 1 -- comment",
+		"id = 1 /* comment */",
+		"tenant = 'acme",
+	} {
+		assert.NotNil(t, buildRowFilterConv(filter).ValidatePushDownRowFilters(), filter)
+	}
+}
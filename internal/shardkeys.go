@@ -0,0 +1,182 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// maxShardKeySamples is the number of colliding keys kept per table as
+// examples for the report.
+const maxShardKeySamples = 10
+
+// ShardKeyCollisions describes the primary keys of a table that were read
+// from more than one shard of a sharded migration. Unless the shard id column
+// is part of the primary key, only one of the rows with such a key can be
+// written to Spanner.
+type ShardKeyCollisions struct {
+	KeysRead  int64               // Number of keys read from all shards.
+	Sampled   bool                // Whether only the first keys of each shard were read.
+	Colliding int64               // Number of distinct keys read from more than one shard.
+	Samples   []ShardKeyCollision // Up to maxShardKeySamples colliding keys.
+}
+
+// ShardKeyCollision is a primary key read from more than one shard.
+type ShardKeyCollision struct {
+	Key      []string // Values of the key columns.
+	ShardIds []string // Shards the key was read from, in the order they were read.
+}
+
+// shardKeyDrops counts the rows rejected by Spanner during a sharded data
+// migration because another shard had already written a row with the same
+// key. Rows are dropped concurrently by the writers, hence the lock.
+type shardKeyDrops struct {
+	lock sync.Mutex
+	rows map[string]int64 // Broken down by Spanner table name.
+}
+
+// ShardIdInPrimaryKey reports whether the shard id column of a table is part
+// of its primary key.
+func ShardIdInPrimaryKey(table ddl.CreateTable) bool {
+	for _, k := range table.PrimaryKeys {
+		if k.ColId == table.ShardIdColumn {
+			return true
+		}
+	}
+	return false
+}
+
+// ShardKeyColumns returns the source columns of the primary key of a table,
+// whose values can collide across the shards of a sharded migration. Returns
+// nil if the key can't collide because it includes the shard id column, or if
+// it can't be read from the source because some key column, e.g. a synthetic
+// key, isn't a source column.
+func ShardKeyColumns(conv *Conv, tableId string) []string {
+	spTable := conv.SpSchema[tableId]
+	if spTable.ShardIdColumn == "" || ShardIdInPrimaryKey(spTable) {
+		return nil
+	}
+	srcTable := conv.SrcSchema[tableId]
+	var cols []string
+	for _, k := range spTable.PrimaryKeys {
+		col, ok := srcTable.ColDefs[k.ColId]
+		if !ok {
+			return nil
+		}
+		cols = append(cols, col.Name)
+	}
+	return cols
+}
+
+// ShardKeyTables returns the ids of the tables whose primary keys can collide
+// across shards, sorted by Spanner table name.
+func ShardKeyTables(conv *Conv) []string {
+	var tableIds []string
+	for tableId := range conv.SpSchema {
+		if ShardKeyColumns(conv, tableId) != nil {
+			tableIds = append(tableIds, tableId)
+		}
+	}
+	sort.Slice(tableIds, func(i, j int) bool {
+		return conv.SpSchema[tableIds[i]].Name < conv.SpSchema[tableIds[j]].Name
+	})
+	return tableIds
+}
+
+// FindShardKeyCollisions finds the keys that were read from more than one
+// shard, given the keys read from each shard of shardIds. sampled records
+// whether only the first keys of each shard were read, in which case keys
+// beyond those can collide too.
+func FindShardKeyCollisions(shardIds []string, keys [][][]string, sampled bool) ShardKeyCollisions {
+	c := ShardKeyCollisions{Sampled: sampled}
+	firstShard := make(map[string]string)
+	// Maps colliding keys to their index in c.Samples, or -1 if not sampled.
+	colliding := make(map[string]int)
+	for i, shardId := range shardIds {
+		for _, key := range keys[i] {
+			c.KeysRead++
+			k := strings.Join(key, "\x00")
+			first, ok := firstShard[k]
+			if !ok {
+				firstShard[k] = shardId
+				continue
+			}
+			if first == shardId {
+				continue
+			}
+			j, ok := colliding[k]
+			if !ok {
+				c.Colliding++
+				j = -1
+				if len(c.Samples) < maxShardKeySamples {
+					j = len(c.Samples)
+					c.Samples = append(c.Samples, ShardKeyCollision{Key: key, ShardIds: []string{first}})
+				}
+				colliding[k] = j
+			}
+			if j >= 0 {
+				s := &c.Samples[j]
+				if s.ShardIds[len(s.ShardIds)-1] != shardId {
+					s.ShardIds = append(s.ShardIds, shardId)
+				}
+			}
+		}
+	}
+	return c
+}
+
+// CollidingShardKeyTables returns the ids of the tables for which keys were
+// read from more than one shard, sorted by Spanner table name.
+func (conv *Conv) CollidingShardKeyTables() []string {
+	var tableIds []string
+	for tableId, c := range conv.ShardKeyCollisions {
+		if c.Colliding > 0 {
+			tableIds = append(tableIds, tableId)
+		}
+	}
+	sort.Slice(tableIds, func(i, j int) bool {
+		return conv.SpSchema[tableIds[i]].Name < conv.SpSchema[tableIds[j]].Name
+	})
+	return tableIds
+}
+
+// RecordShardKeyDrop counts a row of Spanner table spTable that was rejected
+// because another shard had already written a row with the same key. It can
+// be called concurrently.
+func (conv *Conv) RecordShardKeyDrop(spTable string) {
+	conv.shardKeyDrops.lock.Lock()
+	defer conv.shardKeyDrops.lock.Unlock()
+	if conv.shardKeyDrops.rows == nil {
+		conv.shardKeyDrops.rows = make(map[string]int64)
+	}
+	conv.shardKeyDrops.rows[spTable]++
+}
+
+// ShardKeyDrops returns the number of rows rejected because another shard
+// had already written a row with the same key, broken down by Spanner table
+// name.
+func (conv *Conv) ShardKeyDrops() map[string]int64 {
+	conv.shardKeyDrops.lock.Lock()
+	defer conv.shardKeyDrops.lock.Unlock()
+	drops := make(map[string]int64, len(conv.shardKeyDrops.rows))
+	for t, n := range conv.shardKeyDrops.rows {
+		drops[t] = n
+	}
+	return drops
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// shardKeyTestConv returns a conv with tables orders, keyed by (region, id),
+// and users, keyed by id, which both have a shard id column outside of their
+// keys.
+func shardKeyTestConv() *Conv {
+	conv := MakeConv()
+	int64Type := ddl.Type{Name: ddl.Int64}
+	addInterleaveTestTable(conv, "t_orders", "orders",
+		[]interleaveTestCol{{"c_region", "region", ddl.Type{Name: ddl.String, Len: 10}}, {"c_order_id", "id", int64Type}},
+		[]string{"c_region", "c_order_id"}, nil)
+	addInterleaveTestTable(conv, "t_users", "users", []interleaveTestCol{{"c_user_id", "id", int64Type}}, []string{"c_user_id"}, nil)
+	for _, tableId := range []string{"t_orders", "t_users"} {
+		sp := conv.SpSchema[tableId]
+		colId := "c_shard_" + tableId
+		sp.ColIds = append(sp.ColIds, colId)
+		sp.ColDefs[colId] = ddl.ColumnDef{Name: ShardIdColumn, Id: colId, T: ddl.Type{Name: ddl.String, Len: 50}}
+		sp.ShardIdColumn = colId
+		conv.SpSchema[tableId] = sp
+	}
+	return conv
+}
+
+func TestShardKeyColumns(t *testing.T) {
+	conv := shardKeyTestConv()
+	assert.Equal(t, []string{"region", "id"}, ShardKeyColumns(conv, "t_orders"))
+	assert.Equal(t, []string{"t_orders", "t_users"}, ShardKeyTables(conv))
+
+	// Keys including the shard id column can't collide.
+	sp := conv.SpSchema["t_users"]
+	sp.PrimaryKeys = append([]ddl.IndexKey{{ColId: sp.ShardIdColumn, Order: 1}}, sp.PrimaryKeys...)
+	conv.SpSchema["t_users"] = sp
+	assert.True(t, ShardIdInPrimaryKey(sp))
+	assert.Nil(t, ShardKeyColumns(conv, "t_users"))
+
+	// Synthetic keys aren't read from the source.
+	sp = conv.SpSchema["t_orders"]
+	sp.ColDefs["c_synth"] = ddl.ColumnDef{Name: "synth_id", Id: "c_synth"}
+	sp.PrimaryKeys = []ddl.IndexKey{{ColId: "c_synth", Order: 1}}
+	conv.SpSchema["t_orders"] = sp
+	assert.Nil(t, ShardKeyColumns(conv, "t_orders"))
+	assert.Empty(t, ShardKeyTables(conv))
+
+	// Tables of unsharded migrations have no shard id column.
+	conv = MakeConv()
+	addInterleaveTestTable(conv, "t_users", "users", []interleaveTestCol{{"c_user_id", "id", ddl.Type{Name: ddl.Int64}}}, []string{"c_user_id"}, nil)
+	assert.Nil(t, ShardKeyColumns(conv, "t_users"))
+}
+
+func TestFindShardKeyCollisions(t *testing.T) {
+	keys := [][][]string{
+		{{"eu", "1"}, {"eu", "2"}, {"us", "1"}},
+		{{"eu", "1"}, {"us", "2"}},
+		{{"eu", "1"}, {"us", "2"}, {"us", "3"}},
+	}
+	c := FindShardKeyCollisions([]string{"shard1", "shard2", "shard3"}, keys, true)
+	assert.Equal(t, ShardKeyCollisions{
+		KeysRead:  8,
+		Sampled:   true,
+		Colliding: 2,
+		Samples: []ShardKeyCollision{
+			{Key: []string{"eu", "1"}, ShardIds: []string{"shard1", "shard2", "shard3"}},
+			{Key: []string{"us", "2"}, ShardIds: []string{"shard2", "shard3"}},
+		},
+	}, c)
+
+	// Only the first colliding keys are kept as samples.
+	var ids [][]string
+	for i := 0; i < 2*maxShardKeySamples; i++ {
+		ids = append(ids, []string{fmt.Sprint(i)})
+	}
+	c = FindShardKeyCollisions([]string{"shard1", "shard2"}, [][][]string{ids, ids}, false)
+	assert.Equal(t, int64(2*maxShardKeySamples), c.Colliding)
+	assert.Equal(t, maxShardKeySamples, len(c.Samples))
+	assert.Equal(t, []string{"0"}, c.Samples[0].Key)
+	assert.False(t, c.Sampled)
+}
+
+func TestRecordShardKeyDrop(t *testing.T) {
+	conv := shardKeyTestConv()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conv.RecordShardKeyDrop("orders")
+		}()
+	}
+	wg.Wait()
+	conv.RecordShardKeyDrop("users")
+	assert.Equal(t, map[string]int64{"orders": 10, "users": 1}, conv.ShardKeyDrops())
+
+	conv.ShardKeyCollisions = map[string]ShardKeyCollisions{
+		"t_users":  {KeysRead: 4, Colliding: 1},
+		"t_orders": {KeysRead: 4},
+	}
+	assert.Equal(t, []string{"t_users"}, conv.CollidingShardKeyTables())
+}
@@ -0,0 +1,452 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// TransformationKind is the kind of function a ColumnTransformation applies.
+type TransformationKind string
+
+const (
+	// TransformHash replaces a value with the hex encoded SHA-256 hash of Salt
+	// followed by the value.
+	TransformHash TransformationKind = "hash"
+	// TransformMask replaces all but the first KeepFirst and last KeepLast
+	// characters of a value with MaskChar.
+	TransformMask TransformationKind = "mask"
+	// TransformSubstring keeps Length characters of a value starting at the
+	// 0-based Start. A Length of 0 keeps the rest of the value.
+	TransformSubstring TransformationKind = "substring"
+	// TransformRegexReplace replaces the matches of Pattern in a value with
+	// Replacement, which can refer to submatches as $1, $2 etc.
+	TransformRegexReplace TransformationKind = "regex_replace"
+	// TransformConstant replaces every value with Value.
+	TransformConstant TransformationKind = "constant"
+	// TransformLookup replaces a value with its entry in Lookup. Values that
+	// aren't in Lookup are kept.
+	TransformLookup TransformationKind = "lookup"
+	// TransformConcat replaces a value with the values of the columns ColIds
+	// of the same table, joined by Separator. NULL values are skipped.
+	TransformConcat TransformationKind = "concat"
+	// TransformNullIf replaces a value equal to Value with NULL.
+	TransformNullIf TransformationKind = "null_if"
+)
+
+// ColumnTransformation is a function applied to the values of a Spanner column
+// during bulk data migration, after they are converted to the Spanner type of
+// the column. Only the fields used by Kind are set. Transformations see the
+// values of the other columns of the row before they are transformed.
+type ColumnTransformation struct {
+	Kind        TransformationKind
+	Salt        string            `json:",omitempty"`
+	MaskChar    string            `json:",omitempty"`
+	KeepFirst   int               `json:",omitempty"`
+	KeepLast    int               `json:",omitempty"`
+	Start       int               `json:",omitempty"`
+	Length      int               `json:",omitempty"`
+	Pattern     string            `json:",omitempty"`
+	Replacement string            `json:",omitempty"`
+	Value       string            `json:",omitempty"`
+	Lookup      map[string]string `json:",omitempty"`
+	ColIds      []string          `json:",omitempty"`
+	Separator   string            `json:",omitempty"`
+}
+
+// SetColumnTransformation validates t for the Spanner column colId of table
+// tableId and records it in conv. It replaces any previous transformation of
+// the column.
+func (conv *Conv) SetColumnTransformation(tableId, colId string, t ColumnTransformation) error {
+	if _, err := newColumnTransformer(conv, tableId, colId, t); err != nil {
+		return err
+	}
+	if conv.ColumnTransformations == nil {
+		conv.ColumnTransformations = make(map[string]map[string]ColumnTransformation)
+	}
+	if conv.ColumnTransformations[tableId] == nil {
+		conv.ColumnTransformations[tableId] = make(map[string]ColumnTransformation)
+	}
+	conv.ColumnTransformations[tableId][colId] = t
+	return nil
+}
+
+// ValidateColumnTransformations checks that the column transformations of
+// conv, e.g. the ones edited in a session file, can be applied to the current
+// Spanner schema.
+func (conv *Conv) ValidateColumnTransformations() error {
+	for _, tableId := range sortedKeys(conv.ColumnTransformations) {
+		for _, colId := range sortedKeys(conv.ColumnTransformations[tableId]) {
+			if _, err := newColumnTransformer(conv, tableId, colId, conv.ColumnTransformations[tableId][colId]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Describe returns a short human readable description of t, applied to a
+// column of table tableId.
+func (t ColumnTransformation) Describe(conv *Conv, tableId string) string {
+	switch t.Kind {
+	case TransformHash:
+		if t.Salt != "" {
+			return "hash (salted SHA-256)"
+		}
+		return "hash (SHA-256)"
+	case TransformMask:
+		return fmt.Sprintf("mask (keep first %d and last %d characters)", t.KeepFirst, t.KeepLast)
+	case TransformSubstring:
+		if t.Length == 0 {
+			return fmt.Sprintf("substring (from %d)", t.Start)
+		}
+		return fmt.Sprintf("substring (from %d, length %d)", t.Start, t.Length)
+	case TransformRegexReplace:
+		return fmt.Sprintf("regex replace (%s with %s)", t.Pattern, t.Replacement)
+	case TransformConstant:
+		return fmt.Sprintf("constant (%s)", t.Value)
+	case TransformLookup:
+		return fmt.Sprintf("lookup (%d values)", len(t.Lookup))
+	case TransformConcat:
+		var names []string
+		for _, colId := range t.ColIds {
+			names = append(names, conv.SpSchema[tableId].ColDefs[colId].Name)
+		}
+		return fmt.Sprintf("concat (%s)", strings.Join(names, ", "))
+	case TransformNullIf:
+		return fmt.Sprintf("null if (%s)", t.Value)
+	}
+	return string(t.Kind)
+}
+
+// keyColumnUse describes how column colId of table tableId is used as a key:
+// as part of the primary key, which includes the keys interleaved tables share
+// with their parent, or of a foreign key, either referencing or referenced. It
+// returns "" for columns that aren't keys.
+func keyColumnUse(conv *Conv, tableId, colId string) string {
+	sp := conv.SpSchema[tableId]
+	for _, pk := range sp.PrimaryKeys {
+		if pk.ColId != colId {
+			continue
+		}
+		if sp.ParentTable.Id != "" {
+			return "part of the primary key of an interleaved table"
+		}
+		for _, id := range sortedKeys(conv.SpSchema) {
+			if conv.SpSchema[id].ParentTable.Id == tableId {
+				return fmt.Sprintf("part of the primary key of the interleave parent of table %s", conv.SpSchema[id].Name)
+			}
+		}
+		return "part of the primary key"
+	}
+	for _, fk := range sp.ForeignKeys {
+		for _, id := range fk.ColIds {
+			if id == colId {
+				return fmt.Sprintf("part of foreign key %s", fk.Name)
+			}
+		}
+	}
+	for _, id := range sortedKeys(conv.SpSchema) {
+		for _, fk := range conv.SpSchema[id].ForeignKeys {
+			if fk.ReferTableId != tableId {
+				continue
+			}
+			for _, referColId := range fk.ReferColumnIds {
+				if referColId == colId {
+					return fmt.Sprintf("referenced by foreign key %s of table %s", fk.Name, conv.SpSchema[id].Name)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// columnTransformer is a ColumnTransformation compiled for a Spanner column.
+type columnTransformer struct {
+	t          ColumnTransformation
+	re         *regexp.Regexp
+	constant   interface{}
+	concatCols []string // Spanner names of the concatenated columns.
+}
+
+func newColumnTransformer(conv *Conv, tableId, colId string, t ColumnTransformation) (*columnTransformer, error) {
+	sp, ok := conv.SpSchema[tableId]
+	if !ok {
+		return nil, fmt.Errorf("can't transform column: table id %s not found", tableId)
+	}
+	col, ok := sp.ColDefs[colId]
+	if !ok {
+		return nil, fmt.Errorf("can't transform column: column id %s not found in table %s", colId, sp.Name)
+	}
+	errorf := func(format string, a ...interface{}) error {
+		return fmt.Errorf("invalid %s transformation of column %s.%s: %s", t.Kind, sp.Name, col.Name, fmt.Sprintf(format, a...))
+	}
+	// Checkpoints record the key values of the rows written to Spanner, and
+	// the keys of related tables must keep matching, so key columns are never
+	// transformed.
+	if use := keyColumnUse(conv, tableId, colId); use != "" {
+		return nil, errorf("the column is %s", use)
+	}
+	isString := col.T.Name == ddl.String && !col.T.IsArray
+	ct := &columnTransformer{t: t}
+	switch t.Kind {
+	case TransformHash, TransformMask, TransformSubstring, TransformRegexReplace, TransformLookup, TransformConcat:
+		if !isString {
+			return nil, errorf("column type is %s, but the transformation produces %s values", col.T.PrintColumnDefType(), ddl.String)
+		}
+	case TransformConstant, TransformNullIf:
+		if col.T.IsArray {
+			return nil, errorf("array columns are not supported")
+		}
+	default:
+		return nil, fmt.Errorf("invalid transformation of column %s.%s: unknown kind %q", sp.Name, col.Name, t.Kind)
+	}
+	switch t.Kind {
+	case TransformMask:
+		if t.KeepFirst < 0 || t.KeepLast < 0 {
+			return nil, errorf("the number of characters to keep can't be negative")
+		}
+		if len([]rune(t.MaskChar)) > 1 {
+			return nil, errorf("mask character %q is not a single character", t.MaskChar)
+		}
+	case TransformSubstring:
+		if t.Start < 0 || t.Length < 0 {
+			return nil, errorf("start and length can't be negative")
+		}
+	case TransformRegexReplace:
+		re, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return nil, errorf("%v", err)
+		}
+		ct.re = re
+	case TransformConstant:
+		v, err := parseConstant(col.T.Name, t.Value)
+		if err != nil {
+			return nil, errorf("%v", err)
+		}
+		ct.constant = v
+	case TransformConcat:
+		if len(t.ColIds) == 0 {
+			return nil, errorf("no columns to concatenate")
+		}
+		for _, id := range t.ColIds {
+			c, ok := sp.ColDefs[id]
+			if !ok {
+				return nil, errorf("column id %s not found", id)
+			}
+			ct.concatCols = append(ct.concatCols, c.Name)
+		}
+	}
+	return ct, nil
+}
+
+// parseConstant parses s as a value of the Spanner type typeName.
+func parseConstant(typeName, s string) (interface{}, error) {
+	switch typeName {
+	case ddl.String:
+		return s, nil
+	case ddl.Int64:
+		return strconv.ParseInt(s, 10, 64)
+	case ddl.Float64:
+		return strconv.ParseFloat(s, 64)
+	case ddl.Bool:
+		return strconv.ParseBool(s)
+	}
+	return nil, fmt.Errorf("constants of type %s are not supported", typeName)
+}
+
+// apply returns the transformed value of a column. row maps the Spanner column
+// names of the row to their values before transformation.
+func (ct *columnTransformer) apply(v interface{}, row map[string]interface{}) (interface{}, error) {
+	t := ct.t
+	switch t.Kind {
+	case TransformConstant:
+		return ct.constant, nil
+	case TransformNullIf:
+		if s, ok := valueString(v); ok && s == t.Value {
+			return nil, nil
+		}
+		return v, nil
+	case TransformConcat:
+		var parts []string
+		for _, c := range ct.concatCols {
+			if s, ok := valueString(row[c]); ok {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, t.Separator), nil
+	}
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("can't apply %s transformation to non-string value %v", t.Kind, v)
+	}
+	switch t.Kind {
+	case TransformHash:
+		h := sha256.Sum256([]byte(t.Salt + s))
+		return hex.EncodeToString(h[:]), nil
+	case TransformMask:
+		maskChar := t.MaskChar
+		if maskChar == "" {
+			maskChar = "*"
+		}
+		r := []rune(s)
+		var b strings.Builder
+		for i, c := range r {
+			if i < t.KeepFirst || i >= len(r)-t.KeepLast {
+				b.WriteRune(c)
+			} else {
+				b.WriteString(maskChar)
+			}
+		}
+		return b.String(), nil
+	case TransformSubstring:
+		r := []rune(s)
+		if t.Start >= len(r) {
+			return "", nil
+		}
+		end := len(r)
+		if t.Length > 0 && t.Start+t.Length < end {
+			end = t.Start + t.Length
+		}
+		return string(r[t.Start:end]), nil
+	case TransformRegexReplace:
+		return ct.re.ReplaceAllString(s, t.Replacement), nil
+	case TransformLookup:
+		if mapped, ok := t.Lookup[s]; ok {
+			return mapped, nil
+		}
+		return s, nil
+	}
+	return v, nil
+}
+
+// valueString returns the string form of a converted value, and false if the
+// value is NULL.
+func valueString(v interface{}) (string, bool) {
+	if n, ok := v.(interface{ IsNull() bool }); ok && n.IsNull() {
+		return "", false
+	}
+	switch x := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return x, true
+	case []byte:
+		return base64.StdEncoding.EncodeToString(x), true
+	case int64:
+		return strconv.FormatInt(x, 10), true
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), true
+	case bool:
+		return strconv.FormatBool(x), true
+	case big.Rat:
+		return ratString(&x), true
+	case *big.Rat:
+		return ratString(x), true
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano), true
+	case civil.Date:
+		return x.String(), true
+	case fmt.Stringer:
+		return x.String(), true
+	}
+	return fmt.Sprint(v), true
+}
+
+// ratString formats r as a decimal number without trailing zeros.
+func ratString(r *big.Rat) string {
+	s := r.FloatString(9)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// rowTransformer applies the column transformations of a Spanner table to
+// its rows.
+type rowTransformer struct {
+	cols map[string]*columnTransformer // Keyed by Spanner column name.
+}
+
+func (rt *rowTransformer) transform(cols []string, vals []interface{}) ([]interface{}, error) {
+	row := make(map[string]interface{}, len(cols))
+	for i, c := range cols {
+		row[c] = vals[i]
+	}
+	out := make([]interface{}, len(vals))
+	for i, c := range cols {
+		ct, ok := rt.cols[c]
+		if !ok {
+			out[i] = vals[i]
+			continue
+		}
+		v, err := ct.apply(vals[i], row)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %v", c, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// getRowTransformer returns the row transformer of the Spanner table spTable,
+// or nil if none of its columns are transformed. Row transformers are built
+// the first time they are needed; invalid transformations are skipped here
+// since they are reported by ValidateColumnTransformations.
+func (conv *Conv) getRowTransformer(spTable string) *rowTransformer {
+	conv.transformersOnce.Do(func() {
+		conv.rowTransformers = make(map[string]*rowTransformer)
+		for tableId, cols := range conv.ColumnTransformations {
+			sp, ok := conv.SpSchema[tableId]
+			if !ok {
+				continue
+			}
+			rt := &rowTransformer{cols: make(map[string]*columnTransformer)}
+			for colId, t := range cols {
+				ct, err := newColumnTransformer(conv, tableId, colId, t)
+				if err != nil {
+					continue
+				}
+				rt.cols[sp.ColDefs[colId].Name] = ct
+			}
+			if len(rt.cols) > 0 {
+				conv.rowTransformers[sp.Name] = rt
+			}
+		}
+	})
+	return conv.rowTransformers[spTable]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+func buildTransformConv() *Conv {
+	conv := MakeConv()
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Id:     "t1",
+		Name:   "users",
+		ColIds: []string{"c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8", "c9"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Id: "c1", Name: "id", T: ddl.Type{Name: ddl.Int64}},
+			"c2": {Id: "c2", Name: "email", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c3": {Id: "c3", Name: "card", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c4": {Id: "c4", Name: "zip", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c5": {Id: "c5", Name: "phone", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c6": {Id: "c6", Name: "score", T: ddl.Type{Name: ddl.Int64}},
+			"c7": {Id: "c7", Name: "country", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c8": {Id: "c8", Name: "label", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c9": {Id: "c9", Name: "note", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+	}
+	return conv
+}
+
+func TestWriteRowTransformations(t *testing.T) {
+	conv := buildTransformConv()
+	for colId, ct := range map[string]ColumnTransformation{
+		"c2": {Kind: TransformHash, Salt: "s"},
+		"c3": {Kind: TransformMask, KeepLast: 4},
+		"c4": {Kind: TransformSubstring, Length: 3},
+		"c5": {Kind: TransformRegexReplace, Pattern: `[^0-9]`},
+		"c6": {Kind: TransformConstant, Value: "0"},
+		"c7": {Kind: TransformLookup, Lookup: map[string]string{"DE": "Germany"}},
+		"c8": {Kind: TransformConcat, ColIds: []string{"c1", "c7"}, Separator: "-"},
+		"c9": {Kind: TransformNullIf, Value: "n/a"},
+	} {
+		assert.Nil(t, conv.SetColumnTransformation("t1", colId, ct), colId)
+	}
+	assert.Nil(t, conv.ValidateColumnTransformations())
+
+	var rows [][]interface{}
+	conv.SetDataMode()
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		rows = append(rows, vals)
+	})
+	cols := []string{"id", "email", "card", "zip", "phone", "score", "country", "label", "note"}
+	conv.WriteRow("users", "users", cols, []interface{}{int64(7), "a@b.c", "4111222233334444", "94043-1351", "(555) 010-2030", int64(42), "DE", "x", "n/a"})
+	conv.WriteRow("users", "users", cols, []interface{}{int64(8), nil, "12", "9", "", nil, "FR", nil, "ok"})
+	assert.Equal(t, [][]interface{}{
+		{int64(7), "f49190c156c96778ee00b1b8162dd363750a94d82855a60076756098676ca522", "************4444", "940", "5550102030", int64(0), "Germany", "7-DE", nil},
+		{int64(8), nil, "12", "9", "", int64(0), "FR", "8-FR", "ok"},
+	}, rows)
+	assert.Equal(t, "concat (id, country)", conv.ColumnTransformations["t1"]["c8"].Describe(conv, "t1"))
+}
+
+func TestSetColumnTransformationErrors(t *testing.T) {
+	conv := buildTransformConv()
+	for _, tc := range []struct {
+		name  string
+		colId string
+		t     ColumnTransformation
+	}{
+		{"unknown kind", "c2", ColumnTransformation{Kind: "encrypt"}},
+		{"hash of int column", "c1", ColumnTransformation{Kind: TransformHash}},
+		{"bad constant", "c6", ColumnTransformation{Kind: TransformConstant, Value: "abc"}},
+		{"bad pattern", "c5", ColumnTransformation{Kind: TransformRegexReplace, Pattern: "("}},
+		{"unknown concat column", "c8", ColumnTransformation{Kind: TransformConcat, ColIds: []string{"c42"}}},
+		{"unknown column", "c42", ColumnTransformation{Kind: TransformHash}},
+	} {
+		assert.NotNil(t, conv.SetColumnTransformation("t1", tc.colId, tc.t), tc.name)
+	}
+	assert.Empty(t, conv.ColumnTransformations)
+}
+
+func TestSetColumnTransformationOfKeys(t *testing.T) {
+	conv := buildTransformConv()
+	conv.SpSchema["t2"] = ddl.CreateTable{
+		Id:     "t2",
+		Name:   "orders",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Id: "c1", Name: "id", T: ddl.Type{Name: ddl.Int64}},
+			"c2": {Id: "c2", Name: "user_email", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			"c3": {Id: "c3", Name: "note", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+		},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+		ForeignKeys: []ddl.Foreignkey{{Name: "fk_user", ColIds: []string{"c2"}, ReferTableId: "t1", ReferColumnIds: []string{"c2"}}},
+	}
+	conv.SpSchema["t3"] = ddl.CreateTable{
+		Id:     "t3",
+		Name:   "logins",
+		ColIds: []string{"c1", "c2"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Id: "c1", Name: "id", T: ddl.Type{Name: ddl.Int64}},
+			"c2": {Id: "c2", Name: "at", T: ddl.Type{Name: ddl.Int64}},
+		},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}, {ColId: "c2"}},
+		ParentTable: ddl.InterleavedParent{Id: "t1"},
+	}
+	for _, tc := range []struct {
+		tableId, colId string
+		expected       string
+	}{
+		{"t1", "c1", "invalid constant transformation of column users.id: the column is part of the primary key of the interleave parent of table logins"},
+		{"t1", "c2", "invalid constant transformation of column users.email: the column is referenced by foreign key fk_user of table orders"},
+		{"t2", "c1", "invalid constant transformation of column orders.id: the column is part of the primary key"},
+		{"t2", "c2", "invalid constant transformation of column orders.user_email: the column is part of foreign key fk_user"},
+		{"t3", "c2", "invalid constant transformation of column logins.at: the column is part of the primary key of an interleaved table"},
+	} {
+		err := conv.SetColumnTransformation(tc.tableId, tc.colId, ColumnTransformation{Kind: TransformConstant, Value: "1"})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, tc.expected, err.Error())
+		}
+	}
+	assert.Nil(t, conv.SetColumnTransformation("t2", "c3", ColumnTransformation{Kind: TransformConstant, Value: "1"}))
+}
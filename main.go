@@ -52,7 +52,13 @@ func main() {
 	subcommands.Register(&cmd.SchemaCmd{}, "")
 	subcommands.Register(&cmd.DataCmd{}, "")
 	subcommands.Register(&cmd.SchemaAndDataCmd{}, "")
+	subcommands.Register(&cmd.ValidateDataCmd{}, "")
+	subcommands.Register(&cmd.SchemaDiffCmd{}, "")
 	subcommands.Register(&cmd.CleanupCmd{}, "")
+	subcommands.Register(&cmd.JobsCmd{}, "")
+	subcommands.Register(&cmd.ReplayDLQCmd{}, "")
+	subcommands.Register(&cmd.ReverseReplicationCmd{}, "")
+	subcommands.Register(&cmd.ConvertDialectCmd{}, "")
 	subcommands.Register(&cmd.AssessmentCmd{}, "")
 	subcommands.Register(&webv2.WebCmd{DistDir: distDir}, "")
 	flag.Parse()
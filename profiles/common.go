@@ -162,6 +162,40 @@ func GetSchemaSampleSize(sourceProfile SourceProfile) int64 {
 	return schemaSampleSize
 }
 
+// GetReadParallelism returns the number of concurrent key-range reads to use
+// when migrating the data of source table 'table'. Defaults to 1, i.e. the
+// table is read with a single query.
+func GetReadParallelism(sourceProfile SourceProfile, table string) int {
+	if sourceProfile.Ty == SourceProfileTypeConnection {
+		if n, ok := sourceProfile.Conn.ReadParallelism[table]; ok {
+			return n
+		}
+	}
+	return 1
+}
+
+// parseReadParallelism parses the read-parallelism source profile param,
+// which is of the form "table1:n1;table2:n2;...".
+func parseReadParallelism(s string) (map[string]int, error) {
+	readParallelism := make(map[string]int)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid read-parallelism entry (expected format: table:n): %v", entry)
+		}
+		n, err := strconv.Atoi(entry[i+1:])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid read-parallelism for table %s: %v", entry[:i], entry[i+1:])
+		}
+		readParallelism[entry[:i]] = n
+	}
+	return readParallelism, nil
+}
+
 func getORACLEConnectionStr(server, port, user, password, dbName string) string {
 	portNumber, _ := strconv.Atoi(port)
 	return go_ora.BuildUrl(server, portNumber, dbName, user, password, nil)
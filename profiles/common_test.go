@@ -209,4 +209,18 @@ func TestGetSchemaSampleSize(t *testing.T) {
 		res := GetSchemaSampleSize(tc.inputSourceProfile)
 		assert.Equal(t, tc.expectedOutput, res, tc.name)
 	}
-}
\ No newline at end of file
+}
+func TestGetReadParallelism(t *testing.T) {
+	readParallelism, err := parseReadParallelism("orders:8; line:items:16;")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"orders": 8, "line:items": 16}, readParallelism)
+
+	sourceProfile := SourceProfile{Ty: SourceProfileTypeConnection, Conn: SourceProfileConnection{Ty: SourceProfileConnectionTypeMySQL, ReadParallelism: readParallelism}}
+	assert.Equal(t, 8, GetReadParallelism(sourceProfile, "orders"))
+	assert.Equal(t, 1, GetReadParallelism(sourceProfile, "customers"))
+
+	for _, s := range []string{"orders", "orders:", ":8", "orders:x", "orders:-1"} {
+		_, err := parseReadParallelism(s)
+		assert.NotNil(t, err, s)
+	}
+}
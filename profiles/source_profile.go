@@ -35,6 +35,7 @@ const (
 	SourceProfileTypeConfig
 	SourceProfileTypeCsv
 	SourceProfileTypeCloudSQL
+	SourceProfileTypeDataFile
 )
 
 type SourceProfileFile struct {
@@ -369,6 +370,7 @@ type SourceProfileConnectionDynamoDB struct {
 	AwsRegion          string // Same as AWS_REGION environment variable
 	DydbEndpoint       string // Same as DYNAMODB_ENDPOINT_OVERRIDE environment variable
 	SchemaSampleSize   int64  // Number of rows to use for inferring schema (default 100,000)
+	NormalizeNested    bool   // Normalize nested maps into columns and lists of maps into interleaved tables
 	enableStreaming    string // Used for confirming streaming migration (valid options: `yes`,`no`,`true`,`false`)
 }
 
@@ -381,6 +383,13 @@ func (spd *SourceProfileDialectImpl) NewSourceProfileConnectionDynamoDB(params m
 		}
 		dydb.SchemaSampleSize = int64(schemaSampleSizeInt)
 	}
+	if normalizeNested, ok := params["normalize-nested"]; ok {
+		normalizeNestedBool, err := strconv.ParseBool(normalizeNested)
+		if err != nil {
+			return dydb, fmt.Errorf("could not parse normalize-nested = %v as a valid bool", normalizeNested)
+		}
+		dydb.NormalizeNested = normalizeNestedBool
+	}
 	// For DynamoDB, the preferred way to provide connection params is through env variables.
 	// Unlike postgres and mysql, there may not be deprecation of env variables, hence it
 	// is better to override env variables optionally via source profile params.
@@ -457,13 +466,14 @@ func (spd *SourceProfileDialectImpl) NewSourceProfileConnectionOracle(params map
 }
 
 type SourceProfileConnection struct {
-	Ty        SourceProfileConnectionType
-	Streaming bool
-	Mysql     SourceProfileConnectionMySQL
-	Pg        SourceProfileConnectionPostgreSQL
-	Dydb      SourceProfileConnectionDynamoDB
-	SqlServer SourceProfileConnectionSqlServer
-	Oracle    SourceProfileConnectionOracle
+	Ty              SourceProfileConnectionType
+	Streaming       bool
+	Mysql           SourceProfileConnectionMySQL
+	Pg              SourceProfileConnectionPostgreSQL
+	Dydb            SourceProfileConnectionDynamoDB
+	SqlServer       SourceProfileConnectionSqlServer
+	Oracle          SourceProfileConnectionOracle
+	ReadParallelism map[string]int // Number of concurrent key-range reads per source table during bulk data migration.
 }
 
 type SourceProfileConnectionCloudSQL struct {
@@ -532,6 +542,15 @@ func (nsp *NewSourceProfileImpl) NewSourceProfileConnection(source string, param
 	default:
 		return conn, fmt.Errorf("please specify a valid source database using -source flag, received source = %v", source)
 	}
+	if readParallelism, ok := params["read-parallelism"]; ok {
+		if conn.Ty == SourceProfileConnectionTypeDynamoDB {
+			return conn, fmt.Errorf("read-parallelism is not supported for DynamoDB")
+		}
+		conn.ReadParallelism, err = parseReadParallelism(readParallelism)
+		if err != nil {
+			return conn, err
+		}
+	}
 	return conn, nil
 }
 
@@ -691,6 +710,16 @@ func NewSourceProfileCsv(params map[string]string) SourceProfileCsv {
 	return csvProfile
 }
 
+// SourceProfileDataFile is the source profile of Parquet and Avro files,
+// which are listed in a manifest with the same format as for CSV files.
+type SourceProfileDataFile struct {
+	Manifest string
+}
+
+func NewSourceProfileDataFile(params map[string]string) SourceProfileDataFile {
+	return SourceProfileDataFile{Manifest: params["manifest"]}
+}
+
 type SourceProfile struct {
 	Driver       string
 	Ty           SourceProfileType
@@ -699,12 +728,14 @@ type SourceProfile struct {
 	ConnCloudSQL SourceProfileConnectionCloudSQL
 	Config       SourceProfileConfig
 	Csv          SourceProfileCsv
+	DataFile     SourceProfileDataFile
 }
 
 // UseTargetSchema returns true if the driver expects an existing schema
-// to use in the target database.
+// to use in the target database. Parquet and Avro files also have a schema
+// of their own, which the schema and schema-and-data subcommands convert.
 func (src SourceProfile) UseTargetSchema() bool {
-	return (src.Driver == constants.CSV)
+	return (src.Driver == constants.CSV || src.Driver == constants.PARQUET || src.Driver == constants.AVRO)
 }
 
 // ToLegacyDriver converts source-profile to equivalent legacy global flags
@@ -720,6 +751,10 @@ func (src SourceProfile) ToLegacyDriver(source string) (string, error) {
 				return constants.MYSQLDUMP, nil
 			case "postgresql", "postgres", "pg":
 				return constants.PGDUMP, nil
+			case "sqlserver", "mssql":
+				return constants.SQLSERVERDUMP, nil
+			case "oracle":
+				return constants.ORACLEDUMP, nil
 			case "dynamodb":
 				return "", fmt.Errorf("dump files are not supported with DynamoDB")
 			default:
@@ -766,6 +801,8 @@ func (src SourceProfile) ToLegacyDriver(source string) (string, error) {
 		}
 	case SourceProfileTypeCsv:
 		return constants.CSV, nil
+	case SourceProfileTypeDataFile:
+		return strings.ToLower(source), nil
 	default:
 		return "", fmt.Errorf("invalid source-profile, could not infer type")
 	}
@@ -798,6 +835,9 @@ func NewSourceProfile(s string, source string, n NewSourceProfileInterface) (Sou
 	if strings.ToLower(source) == constants.CSV {
 		return SourceProfile{Ty: SourceProfileTypeCsv, Csv: NewSourceProfileCsv(params)}, nil
 	}
+	if strings.ToLower(source) == constants.PARQUET || strings.ToLower(source) == constants.AVRO {
+		return SourceProfile{Ty: SourceProfileTypeDataFile, DataFile: NewSourceProfileDataFile(params)}, nil
+	}
 
 	if _, ok := params["file"]; ok || filePipedToStdin() {
 		profile := n.NewSourceProfileFile(params)
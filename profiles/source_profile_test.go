@@ -387,6 +387,16 @@ func TestNewSourceProfileConnectionDynamoDB(t *testing.T) {
 			params:        map[string]string{"schema-sample-size": "a"},
 			errorExpected: true,
 		},
+		{
+			name:          "valid normalize nested",
+			params:        map[string]string{"normalize-nested": "true"},
+			errorExpected: false,
+		},
+		{
+			name:          "invalid normalize nested",
+			params:        map[string]string{"normalize-nested": "maybe"},
+			errorExpected: true,
+		},
 		{
 			name:          "valid aws access key id ",
 			params:        map[string]string{"aws-access-key-id": "hdsjg"},
@@ -750,6 +760,30 @@ func TestNewSourceProfileConnection(t *testing.T) {
 			returnConnProfile: nil,
 			errorExpected:     true,
 		},
+		{
+			name:              "read parallelism",
+			source:            "mysql",
+			params:            map[string]string{"read-parallelism": "orders:8;line_items:16"},
+			function:          "NewSourceProfileConnectionMySQL",
+			returnConnProfile: SourceProfileConnectionMySQL{},
+			errorExpected:     false,
+		},
+		{
+			name:              "invalid read parallelism",
+			source:            "postgresql",
+			params:            map[string]string{"read-parallelism": "orders:0"},
+			function:          "NewSourceProfileConnectionPostgreSQL",
+			returnConnProfile: SourceProfileConnectionPostgreSQL{},
+			errorExpected:     true,
+		},
+		{
+			name:              "read parallelism for dynamodb",
+			source:            "dynamodb",
+			params:            map[string]string{"read-parallelism": "orders:8"},
+			function:          "NewSourceProfileConnectionDynamoDB",
+			returnConnProfile: SourceProfileConnectionDynamoDB{},
+			errorExpected:     true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -868,6 +902,11 @@ func TestUseTargetSchema(t *testing.T) {
 			srcDriver:     "csv",
 			returnBoolean: true,
 		},
+		{
+			name:          "parquet as source driver",
+			srcDriver:     "parquet",
+			returnBoolean: true,
+		},
 		{
 			name:          "not csv as source driver",
 			srcDriver:     "cfg",
@@ -893,6 +932,7 @@ func TestToLegacyDriver(t *testing.T) {
 		SourceProfileTypeConfig
 		SourceProfileTypeCsv
 		SourceProfileTypeCloudSQL
+		SourceProfileTypeDataFile
 		InvalidType
 	)
 	testCases := []struct {
@@ -916,6 +956,20 @@ func TestToLegacyDriver(t *testing.T) {
 			returnConstant: constants.PGDUMP,
 			errorExpected:  false,
 		},
+		{
+			name:           "source profile type FILE and source sqlserver",
+			srcDriver:      SourceProfile{Ty: SourceProfileTypeFile},
+			source:         "sqlserver",
+			returnConstant: constants.SQLSERVERDUMP,
+			errorExpected:  false,
+		},
+		{
+			name:           "source profile type FILE and source oracle",
+			srcDriver:      SourceProfile{Ty: SourceProfileTypeFile},
+			source:         "oracle",
+			returnConstant: constants.ORACLEDUMP,
+			errorExpected:  false,
+		},
 		{
 			name:           "source profile type FILE and source dynamodb",
 			srcDriver:      SourceProfile{Ty: SourceProfileTypeFile},
@@ -1014,6 +1068,13 @@ func TestToLegacyDriver(t *testing.T) {
 			returnConstant: constants.CSV,
 			errorExpected:  false,
 		},
+		{
+			name:           "source profile type DATAFILE and source avro",
+			srcDriver:      SourceProfile{Ty: SourceProfileTypeDataFile},
+			source:         "Avro",
+			returnConstant: constants.AVRO,
+			errorExpected:  false,
+		},
 		{
 			name:           "source profile type CONFIG and source invalid",
 			srcDriver:      SourceProfile{Ty: InvalidType},
@@ -1040,6 +1101,7 @@ func TestNewSourceProfile(t *testing.T) {
 		SourceProfileTypeConfig
 		SourceProfileTypeCsv
 		SourceProfileTypeCloudSQL
+		SourceProfileTypeDataFile
 	)
 	testCases := []struct {
 		name          string
@@ -1095,6 +1157,15 @@ func TestNewSourceProfile(t *testing.T) {
 			returnTy:      SourceProfileTypeCsv,
 			errorExpected: false,
 		},
+		{
+			name:          "source profile for parquet",
+			params:        "manifest=manifest.json",
+			source:        "parquet",
+			function:      "",
+			mockReturn:    SourceProfile{},
+			returnTy:      SourceProfileTypeDataFile,
+			errorExpected: false,
+		},
 		{
 			name:          "unset source profile params",
 			params:        "",
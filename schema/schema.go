@@ -45,6 +45,20 @@ type Table struct {
 	CheckConstraints []CheckConstraint
 	Indexes          []Index
 	Id               string
+	RowFilter        string      `json:",omitempty"` // WHERE-style filter on the rows migrated by bulk data migration, see internal.RowFilter.
+	Nested           *NestedList `json:",omitempty"` // Set for tables normalized from lists nested in the documents of another table.
+}
+
+// NestedList describes a table normalized from the lists of documents nested
+// in the documents of its parent table (e.g. a DynamoDB list of maps). Each
+// element of a list is a row, keyed by the primary key of the document the
+// list is nested in, followed by the position of the element in the list.
+// Such tables have no source table of their own: their rows are read from
+// the documents of the root table.
+type NestedList struct {
+	ParentId     string   // Id of the table of the documents the lists are nested in.
+	Path         []string // Attribute path of the lists in the documents of the parent table.
+	OrdinalColId string   // Id of the column with the position of the element in its list.
 }
 
 // Column represents a database column.
@@ -57,6 +71,12 @@ type Column struct {
 	Id           string
 	AutoGen      ddl.AutoGenCol
 	DefaultValue ddl.DefaultValue
+	// Expression of the column if it is a generated (computed) column.
+	Generated ddl.GeneratedColumn
+	// Attribute path of the value of the column in nested documents, e.g.
+	// [address city] for a column flattened from the city attribute of the
+	// address map. Empty for top-level attributes.
+	Path []string `json:",omitempty"`
 }
 
 // ForeignKey represents a foreign key.
@@ -113,6 +133,28 @@ type Index struct {
 	StoredColumnIds []string
 }
 
+// Kinds of source objects.
+const (
+	View      = "VIEW"
+	Trigger   = "TRIGGER"
+	Procedure = "PROCEDURE"
+	Function  = "FUNCTION"
+)
+
+// SourceObject represents a view, trigger, stored procedure or function.
+// These objects are not converted, but we keep an inventory of them for
+// reporting: they often contain business logic that has to be moved to the
+// application or rewritten by hand.
+type SourceObject struct {
+	Kind             string // One of View, Trigger, Procedure or Function.
+	Schema           string
+	Name             string
+	Body             string   // Definition of the object, as returned by the source database.
+	ReferencedTables []string // Source tables referenced in Body, sorted.
+	Table            string   // For triggers, the table the trigger is defined on.
+	Event            string   // For triggers, the operations that fire the trigger e.g. "INSERT, UPDATE".
+}
+
 // Type represents the type of a column.
 type Type struct {
 	Name        string
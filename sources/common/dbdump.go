@@ -47,6 +47,7 @@ func ProcessDbDump(conv *internal.Conv, r *internal.Reader, dbDump DbDump, ddlVe
 			return err
 		}
 		conv.AddPrimaryKeys()
+		SetReferencedTables(conv)
 	}
 	return nil
 }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/task"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
 )
@@ -41,11 +42,32 @@ type InfoSchema interface {
 	GetConstraints(conv *internal.Conv, table SchemaAndName) ([]string, []schema.CheckConstraint, map[string][]string, error)
 	GetForeignKeys(conv *internal.Conv, table SchemaAndName) (foreignKeys []schema.ForeignKey, err error)
 	GetIndexes(conv *internal.Conv, table SchemaAndName, colNameIdMp map[string]string) ([]schema.Index, error)
+	GetSourceObjects(conv *internal.Conv) ([]schema.SourceObject, error)
 	ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, spCols []string, spSchema ddl.CreateTable, additionalAttributes internal.AdditionalDataAttributes) error
 	StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error)
 	StartStreamingMigration(ctx context.Context, migrationProjectId string, client *sp.Client, conv *internal.Conv, streamInfo map[string]interface{}) (internal.DataflowOutput, error)
 }
 
+// NestedDocumentsNormalizer is implemented by the InfoSchema of sources of
+// nested documents that can normalize them into additional columns and
+// tables, once the schema of the source tables has been read.
+type NestedDocumentsNormalizer interface {
+	// NormalizeNestedDocuments updates conv.SrcSchema with the columns and
+	// tables normalized from nested documents, and returns the number of
+	// tables added.
+	NormalizeNestedDocuments(conv *internal.Conv) (int, error)
+}
+
+// PrimaryKeyReader is implemented by InfoSchemas that can read the primary
+// keys of a table without reading its rows, which is used to find the keys
+// shared by several shards of a sharded migration.
+type PrimaryKeyReader interface {
+	// ReadPrimaryKeys returns the values of the source columns cols of the
+	// rows of a table, in the order of cols. At most limit keys are read,
+	// lowest first, unless limit is 0.
+	ReadPrimaryKeys(conv *internal.Conv, tableId string, cols []string, limit int64) ([][]string, error)
+}
+
 // SchemaAndName contains the schema and name for a table
 type SchemaAndName struct {
 	Schema string
@@ -112,10 +134,12 @@ func (is *InfoSchemaImpl) GenerateSrcSchema(conv *internal.Conv, infoSchema Info
 		numWorkers = DefaultWorkers
 	}
 
+	tableIds := make(map[SchemaAndName]string)
 	asyncProcessTable := func(t SchemaAndName, mutex *sync.Mutex) task.TaskResult[SchemaAndName] {
 		table, e := is.processTable(conv, t, infoSchema)
 		mutex.Lock()
 		conv.SrcSchema[table.Id] = table
+		tableIds[t] = table.Id
 		mutex.Unlock()
 		res := task.TaskResult[SchemaAndName]{t, e}
 		return res
@@ -129,7 +153,27 @@ func (is *InfoSchemaImpl) GenerateSrcSchema(conv *internal.Conv, infoSchema Info
 	}
 
 	internal.ResolveForeignKeyIds(conv.SrcSchema)
-	return len(tables), nil
+	setSrcRowCounts(conv, infoSchema, tables, tableIds)
+
+	tableCount := len(tables)
+	if n, ok := infoSchema.(NestedDocumentsNormalizer); ok {
+		added, err := n.NormalizeNestedDocuments(conv)
+		if err != nil {
+			return 0, err
+		}
+		tableCount += added
+	}
+
+	// Views, triggers, stored procedures and functions are not converted, but
+	// are listed in the report. Failing to read them doesn't fail the schema
+	// conversion.
+	objects, err := infoSchema.GetSourceObjects(conv)
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Couldn't read views, triggers, stored procedures and functions: %s", err))
+	}
+	conv.SrcObjects = objects
+	SetReferencedTables(conv)
+	return tableCount, nil
 }
 
 // ProcessData performs data conversion for source database
@@ -141,9 +185,17 @@ func (is *InfoSchemaImpl) ProcessData(conv *internal.Conv, infoSchema InfoSchema
 	// Tables are ordered in alphabetical order with one exception: interleaved
 	// tables appear after the population of their parent table.
 	tableIds := ddl.GetSortedTableIdsBySpName(conv.SpSchema)
+	if conv.Checkpoint != nil {
+		conv.Checkpoint.SetShard(additionalAttributes.ShardId)
+	}
 
 	for _, tableId := range tableIds {
 		srcSchema := conv.SrcSchema[tableId]
+		// The rows of nested tables are written by infoSchema.ProcessData for
+		// the table they are nested in.
+		if srcSchema.Nested != nil {
+			continue
+		}
 		spSchema, ok := conv.SpSchema[tableId]
 		if !ok {
 			conv.Stats.BadRows[srcSchema.Name] += conv.Stats.Rows[srcSchema.Name]
@@ -151,16 +203,53 @@ func (is *InfoSchemaImpl) ProcessData(conv *internal.Conv, infoSchema InfoSchema
 				srcSchema.Name, ok))
 			continue
 		}
+		var nestedIds []string
+		for _, id := range NestedTableIds(conv, tableId) {
+			if _, ok := conv.SpSchema[id]; ok {
+				nestedIds = append(nestedIds, id)
+			}
+		}
+		if conv.Checkpoint != nil {
+			if conv.Checkpoint.IsComplete(spSchema.Name) {
+				fmt.Printf("Skipping table %s: data migration already completed by a previous run\n", spSchema.Name)
+				for _, id := range append([]string{tableId}, nestedIds...) {
+					conv.Checkpoint.RestoreStats(conv, id)
+				}
+				continue
+			}
+			_, started := conv.Checkpoint.Get(spSchema.Name)
+			for _, id := range append([]string{tableId}, nestedIds...) {
+				conv.Checkpoint.MarkInProgress(conv, id)
+			}
+			if _, _, ok := conv.Checkpoint.ResumeKey(spSchema.Name); ok {
+				fmt.Printf("Resuming data migration of table %s from the last committed key\n", spSchema.Name)
+			} else if started {
+				logger.Log.Warn(fmt.Sprintf("Data migration of table %s can't be resumed from a key and will restart from the beginning; rows written by the previous run will be reported as dropped", spSchema.Name))
+			}
+		}
 		// Extract common spColds. We get column ids common to both source and
-		// spanner table so that we can read these records from source
-		colIds := GetCommonColumnIds(conv, tableId, spSchema.ColIds)
+		// spanner table so that we can read these records from source.
+		// Generated columns are computed by Spanner and aren't read.
+		colIds := GetCommonColumnIds(conv, tableId, WritableColumnIds(conv, tableId, spSchema.ColIds))
 		err := infoSchema.ProcessData(conv, tableId, srcSchema, colIds, spSchema, additionalAttributes)
 		if err != nil {
 			return
 		}
+		if c, ok := infoSchema.(FilteredRowCounter); ok && srcSchema.RowFilter != "" {
+			n, err := c.CountFilteredRows(conv, tableId)
+			if err != nil {
+				conv.Unexpected(err.Error())
+			}
+			conv.StatsAddFilteredRows(srcSchema.Name, n)
+		}
 		if conv.DataFlush != nil {
 			conv.DataFlush()
 		}
+		if conv.Checkpoint != nil {
+			for _, id := range append([]string{tableId}, nestedIds...) {
+				conv.Checkpoint.MarkComplete(conv, id)
+			}
+		}
 	}
 }
 
@@ -182,6 +271,37 @@ func (is *InfoSchemaImpl) SetRowStats(conv *internal.Conv, infoSchema InfoSchema
 	}
 }
 
+// setSrcRowCounts counts the rows of the source tables linked by foreign
+// keys, so that the benefit of interleaving them can be estimated before any
+// data is migrated (see internal.AnalyzeInterleaving). Tables whose rows
+// can't be counted are left out.
+func setSrcRowCounts(conv *internal.Conv, infoSchema InfoSchema, tables []SchemaAndName, tableIds map[SchemaAndName]string) {
+	linked := make(map[string]bool)
+	for tableId, t := range conv.SrcSchema {
+		for _, fk := range t.ForeignKeys {
+			if fk.ReferTableId != "" && fk.ReferTableId != tableId {
+				linked[tableId] = true
+				linked[fk.ReferTableId] = true
+			}
+		}
+	}
+	for _, t := range tables {
+		tableId := tableIds[t]
+		if !linked[tableId] {
+			continue
+		}
+		count, err := infoSchema.GetRowCount(t)
+		if err != nil {
+			logger.Log.Debug(fmt.Sprintf("Couldn't get number of rows for table %s: %s", conv.SrcSchema[tableId].Name, err))
+			continue
+		}
+		if conv.SrcRowCounts == nil {
+			conv.SrcRowCounts = make(map[string]int64)
+		}
+		conv.SrcRowCounts[tableId] = count
+	}
+}
+
 func (is *InfoSchemaImpl) processTable(conv *internal.Conv, table SchemaAndName, infoSchema InfoSchema) (schema.Table, error) {
 	var t schema.Table
 	fmt.Println("processing schema for table", table)
@@ -0,0 +1,498 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/task"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// QueryDialect describes how a SQL source database quotes column references
+// and numbers query parameters.
+type QueryDialect struct {
+	QuoteCol    func(col string) string        // Returns a reference to the column that can be used in WHERE and ORDER BY clauses.
+	Placeholder func(i int) string             // Returns the placeholder for the i-th (1-based) query parameter.
+	IsInteger   func(srcType schema.Type) bool // Reports whether a source column type only holds integers. Required for key-range reads.
+	// Returns the text of the values of column col (see QuoteCol) of source
+	// type srcType migrated to Spanner type spType, as they're hashed by data
+	// validation, or false if they can't be hashed in the database. Nil if
+	// the source doesn't hash values.
+	HashText func(col string, srcType schema.Type, spType ddl.Type) (string, bool)
+	Hash     HashDialect
+}
+
+// ReadClause returns the clauses to append to the SELECT statement that
+// reads the rows of a table, along with its query arguments. The row filter
+// of the table, if any, is pushed down into the WHERE clause. When data
+// migration is checkpointed, the rows are read in primary key order, and
+// when resuming a partially migrated table, only rows after the last
+// committed key are read. Reads restricted to a key range (see
+// Conv.SetReadKeyRange) only select the rows of that range. Returns an empty
+// clause otherwise.
+func ReadClause(conv *internal.Conv, tableId string, d QueryDialect) (string, []interface{}) {
+	var conds []string
+	if filter := conv.PushDownRowFilter(tableId); filter != "" {
+		conds = append(conds, "("+filter+")")
+	}
+	var orderBy string
+	var args []interface{}
+	var keyCols []string
+	if conv.Checkpoint != nil {
+		keyCols = internal.CheckpointKeyColumns(conv, tableId)
+	}
+	if len(keyCols) > 0 {
+		var cols []string
+		for _, c := range keyCols {
+			cols = append(cols, d.QuoteCol(c))
+		}
+		orderBy = " ORDER BY " + strings.Join(cols, ", ")
+		conv.Checkpoint.SetKeyCols(conv.SpSchema[tableId].Name, keyCols)
+		if resumeCols, key, ok := conv.Checkpoint.ResumeKey(conv.SpSchema[tableId].Name); ok {
+			var where string
+			where, args = keyAfterPredicate(resumeCols, key, d)
+			conds = append(conds, where)
+		}
+	}
+	if kr, ok := conv.ReadKeyRange(tableId); ok {
+		col := d.QuoteCol(conv.SrcSchema[tableId].ColDefs[kr.ColId].Name)
+		if kr.Start != nil {
+			args = append(args, *kr.Start)
+			conds = append(conds, fmt.Sprintf("%s >= %s", col, d.Placeholder(len(args))))
+		}
+		if kr.Limit != nil {
+			args = append(args, *kr.Limit)
+			conds = append(conds, fmt.Sprintf("%s < %s", col, d.Placeholder(len(args))))
+		}
+	}
+	if len(conds) == 0 {
+		return orderBy, args
+	}
+	return " WHERE " + strings.Join(conds, " AND ") + orderBy, args
+}
+
+// keyAfterPredicate builds a predicate selecting the rows whose key sorts
+// after key, e.g. for key columns (a, b): (a > ?) OR (a = ? AND b > ?).
+// Row value comparisons like (a, b) > (?, ?) would be shorter, but aren't
+// supported by all sources.
+func keyAfterPredicate(cols []string, key []string, d QueryDialect) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	for i := range cols {
+		var conds []string
+		for j := 0; j < i; j++ {
+			args = append(args, key[j])
+			conds = append(conds, fmt.Sprintf("%s = %s", d.QuoteCol(cols[j]), d.Placeholder(len(args))))
+		}
+		args = append(args, key[i])
+		conds = append(conds, fmt.Sprintf("%s > %s", d.QuoteCol(cols[i]), d.Placeholder(len(args))))
+		terms = append(terms, "("+strings.Join(conds, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// keyRange is a range of values of the split column of a table, as a WHERE
+// clause and its query arguments.
+type keyRange struct {
+	where string
+	args  []interface{}
+}
+
+// SplitColumn returns the source column on which a table can be split into
+// key ranges: its first primary key column, if that column holds integers
+// that are migrated to INT64.
+func SplitColumn(conv *internal.Conv, tableId string, d QueryDialect) (string, bool) {
+	srcTable := conv.SrcSchema[tableId]
+	if len(srcTable.PrimaryKeys) == 0 || d.IsInteger == nil {
+		return "", false
+	}
+	colId := srcTable.PrimaryKeys[0].ColId
+	spCol, ok := conv.SpSchema[tableId].ColDefs[colId]
+	if !ok || spCol.T.Name != ddl.Int64 || !d.IsInteger(srcTable.ColDefs[colId].Type) {
+		return "", false
+	}
+	return srcTable.ColDefs[colId].Name, true
+}
+
+// ReadKeyRanges reads the rows of a table with up to parallelism concurrent
+// queries, each over a range of values of its split column (see SplitColumn).
+// The ranges are derived from the minimum and maximum values of the column,
+// read from 'table' (the quoted table name). query runs the SELECT statement
+// for the table's rows with the given WHERE clause appended, and process
+// consumes its rows; process must hold mutex while updating conv.
+//
+// Returns false if the table can't be read in key ranges, in which case the
+// caller should read it with a single query. This includes partially
+// migrated tables that can be resumed from a checkpoint, since resuming
+// relies on rows being read in key order, and reads restricted to a single
+// key range.
+func ReadKeyRanges(conv *internal.Conv, tableId string, parallelism int, db *sql.DB, table string, d QueryDialect,
+	query func(where string, args []interface{}) (*sql.Rows, error), process func(rows *sql.Rows, mutex *sync.Mutex)) (bool, error) {
+	srcTableName := conv.SrcSchema[tableId].Name
+	col, ok := SplitColumn(conv, tableId, d)
+	if !ok {
+		logger.Log.Warn(fmt.Sprintf("Table %s can't be split into key ranges: its first primary key column must be an integer column. Reading it with a single query.", srcTableName))
+		return false, nil
+	}
+	if conv.Checkpoint != nil {
+		if _, _, ok := conv.Checkpoint.ResumeKey(conv.SpSchema[tableId].Name); ok {
+			logger.Log.Info(fmt.Sprintf("Resuming table %s with a single query from its last committed key", srcTableName))
+			return false, nil
+		}
+	}
+	if _, ok := conv.ReadKeyRange(tableId); ok {
+		return false, nil
+	}
+	var filterWhere string
+	if filter := conv.PushDownRowFilter(tableId); filter != "" {
+		filterWhere = "(" + filter + ")"
+	}
+	// Drivers return integers in different forms (e.g. strings, floats), so
+	// we scan them as strings.
+	var minStr, maxStr sql.NullString
+	q := fmt.Sprintf("SELECT MIN(%s), MAX(%s) FROM %s", d.QuoteCol(col), d.QuoteCol(col), table)
+	if filterWhere != "" {
+		q += " WHERE " + filterWhere
+	}
+	if err := db.QueryRow(q).Scan(&minStr, &maxStr); err != nil {
+		return true, fmt.Errorf("couldn't get key range of table %s: %w", srcTableName, err)
+	}
+	if !minStr.Valid || !maxStr.Valid {
+		// The table is empty.
+		return false, nil
+	}
+	min, err1 := parseKeyBound(minStr.String)
+	max, err2 := parseKeyBound(maxStr.String)
+	if err1 != nil || err2 != nil {
+		logger.Log.Warn(fmt.Sprintf("Couldn't parse key range [%s, %s] of table %s. Reading it with a single query.", minStr.String, maxStr.String, srcTableName))
+		return false, nil
+	}
+	ranges := splitKeyRange(min, max, parallelism, d.QuoteCol(col), d)
+	if len(ranges) < 2 {
+		return false, nil
+	}
+	logger.Log.Info(fmt.Sprintf("Reading table %s in %d key ranges", srcTableName, len(ranges)))
+	readRange := func(r keyRange, mutex *sync.Mutex) task.TaskResult[keyRange] {
+		where := r.where
+		switch {
+		case filterWhere != "" && where == "":
+			where = " WHERE " + filterWhere
+		case filterWhere != "":
+			where += " AND " + filterWhere
+		}
+		rows, err := query(where, r.args)
+		if err != nil {
+			return task.TaskResult[keyRange]{Result: r, Err: err}
+		}
+		defer rows.Close()
+		process(rows, mutex)
+		return task.TaskResult[keyRange]{Result: r, Err: rows.Err()}
+	}
+	rpt := task.RunParallelTasksImpl[keyRange, keyRange]{}
+	res, _ := rpt.RunParallelTasks(ranges, len(ranges), readRange, false)
+	for _, r := range res {
+		if r.Err != nil {
+			return true, fmt.Errorf("couldn't read key range%s of table %s: %w", r.Result.where, srcTableName, r.Err)
+		}
+	}
+	return true, nil
+}
+
+// KeyRangeSummary is the number of rows in a key range of a table and the
+// sum of the hashes of their values (see RowHashSum).
+type KeyRangeSummary struct {
+	Rows int64
+	Hash int64
+}
+
+// KeyRangeHasher is implemented by the InfoSchemas of sources that can
+// count and hash the rows of a table by key range in the database.
+type KeyRangeHasher interface {
+	// HashableColumns returns the columns of colIds of source table tableId
+	// whose values can be hashed in the database.
+	HashableColumns(conv *internal.Conv, tableId string, colIds []string) []string
+	// HashKeyRanges returns the summary of the rows of source table tableId
+	// in each of the ranges of values of its integer column colId delimited
+	// by bounds (see KeyRangeBounds), hashing the values of columns
+	// hashColIds with the primary key columns keyColIds. Returns false if
+	// colId isn't an integer column. With no bounds, all rows of the table
+	// are summarized, and with no hashColIds, rows are only counted.
+	HashKeyRanges(conv *internal.Conv, tableId, colId string, bounds []int64, keyColIds, hashColIds []string) ([]KeyRangeSummary, bool, error)
+}
+
+// HashDialect describes how a database hashes the values of rows, see
+// RowHashSum.
+type HashDialect struct {
+	Concat func(exprs ...string) string // Concatenates two or more text expressions.
+	Hash   func(text string) string     // Returns the first 24 bits of the MD5 hash of text as an integer.
+}
+
+// RowHashSum returns an aggregate expression summing the hashes of the
+// values of the rows of a table, which is 0 for no rows. texts are the
+// expressions for the text of the hashed values, and keyTexts those of the
+// primary key of the row. Each value is hashed with the key of its row and
+// the position of its column, so that values moved to another row or column
+// change the sum, and NULLs are hashed differently from empty strings.
+func RowHashSum(d HashDialect, keyTexts, texts []string) string {
+	var keyParts []string
+	for i, k := range keyTexts {
+		if i > 0 {
+			keyParts = append(keyParts, "'|'")
+		}
+		keyParts = append(keyParts, fmt.Sprintf("COALESCE(%s, '')", k))
+	}
+	key := keyParts[0]
+	if len(keyParts) > 1 {
+		key = d.Concat(keyParts...)
+	}
+	var hashes []string
+	for i, t := range texts {
+		value := fmt.Sprintf("CASE WHEN %s IS NULL THEN 'n' ELSE %s END", t, d.Concat("'v'", t))
+		hashes = append(hashes, d.Hash(d.Concat(key, fmt.Sprintf("'|%d:'", i), value)))
+	}
+	return fmt.Sprintf("COALESCE(SUM(%s), 0)", strings.Join(hashes, " + "))
+}
+
+// ConcatFunction concatenates text expressions with the CONCAT function.
+func ConcatFunction(exprs ...string) string {
+	return "CONCAT(" + strings.Join(exprs, ", ") + ")"
+}
+
+// ConcatOperator concatenates text expressions with the || operator.
+func ConcatOperator(exprs ...string) string {
+	return "(" + strings.Join(exprs, " || ") + ")"
+}
+
+// HashableColumns implements KeyRangeHasher.HashableColumns for SQL sources.
+func HashableColumns(conv *internal.Conv, tableId string, colIds []string, d QueryDialect) []string {
+	if d.HashText == nil {
+		return nil
+	}
+	var hashable []string
+	for _, colId := range colIds {
+		if _, ok := hashText(conv, tableId, colId, d); ok {
+			hashable = append(hashable, colId)
+		}
+	}
+	return hashable
+}
+
+// hashText returns the text of the values of a column as hashed in the
+// source. Values changed by a column transformation can't be hashed.
+func hashText(conv *internal.Conv, tableId, colId string, d QueryDialect) (string, bool) {
+	if _, ok := conv.ColumnTransformations[tableId][colId]; ok {
+		return "", false
+	}
+	srcCol, ok := conv.SrcSchema[tableId].ColDefs[colId]
+	if !ok {
+		return "", false
+	}
+	spCol, ok := conv.SpSchema[tableId].ColDefs[colId]
+	if !ok {
+		return "", false
+	}
+	return d.HashText(d.QuoteCol(srcCol.Name), srcCol.Type, spCol.T)
+}
+
+// HashKeyRanges implements KeyRangeHasher for SQL sources, with one query
+// per key range on 'table' (the quoted table name). Rows excluded by the row
+// filter of the table aren't summarized.
+func HashKeyRanges(conv *internal.Conv, tableId, colId string, bounds []int64, keyColIds, hashColIds []string, db *sql.DB, table string, d QueryDialect) ([]KeyRangeSummary, bool, error) {
+	srcTable := conv.SrcSchema[tableId]
+	ranges := []keyRange{{}}
+	if len(bounds) > 0 {
+		srcCol, ok := srcTable.ColDefs[colId]
+		if !ok || d.IsInteger == nil || !d.IsInteger(srcCol.Type) {
+			return nil, false, nil
+		}
+		ranges = keyRangesOf(bounds, d.QuoteCol(srcCol.Name), d)
+	}
+	sel := "COUNT(*)"
+	if len(hashColIds) > 0 {
+		if d.HashText == nil {
+			return nil, true, fmt.Errorf("values of table %s can't be hashed", srcTable.Name)
+		}
+		texts := func(colIds []string) ([]string, error) {
+			var l []string
+			for _, colId := range colIds {
+				t, ok := hashText(conv, tableId, colId, d)
+				if !ok {
+					return nil, fmt.Errorf("values of column %s of table %s can't be hashed", srcTable.ColDefs[colId].Name, srcTable.Name)
+				}
+				l = append(l, t)
+			}
+			return l, nil
+		}
+		keyTexts, err := texts(keyColIds)
+		if err != nil {
+			return nil, true, err
+		}
+		valueTexts, err := texts(hashColIds)
+		if err != nil {
+			return nil, true, err
+		}
+		sel += ", " + RowHashSum(d.Hash, keyTexts, valueTexts)
+	}
+	filter := conv.PushDownRowFilter(tableId)
+	summaries := make([]KeyRangeSummary, len(ranges))
+	for i, r := range ranges {
+		q := "SELECT " + sel + " FROM " + table + r.where
+		switch {
+		case filter != "" && r.where == "":
+			q += " WHERE (" + filter + ")"
+		case filter != "":
+			q += " AND (" + filter + ")"
+		}
+		row := db.QueryRow(q, r.args...)
+		var err error
+		if len(hashColIds) == 0 {
+			err = row.Scan(&summaries[i].Rows)
+		} else {
+			// Drivers return sums in different forms (e.g. decimal strings).
+			var hash string
+			if err = row.Scan(&summaries[i].Rows, &hash); err == nil {
+				summaries[i].Hash, err = parseSum(hash)
+			}
+		}
+		if err != nil {
+			return nil, true, fmt.Errorf("couldn't summarize rows of key range%s of table %s: %w", r.where, srcTable.Name, err)
+		}
+	}
+	return summaries, true, nil
+}
+
+// FilteredRowCounter is implemented by the InfoSchemas of sources that push
+// row filters down into their queries. The rows skipped by these filters are
+// never read, so they are counted in the database.
+type FilteredRowCounter interface {
+	// CountFilteredRows returns the number of rows of source table tableId
+	// that are skipped by its row filter.
+	CountFilteredRows(conv *internal.Conv, tableId string) (int64, error)
+}
+
+// CountFilteredRows implements FilteredRowCounter for SQL sources, with a
+// COUNT query on 'table' (the quoted table name). Like the WHERE clause of the
+// queries that read the table, it counts rows for which the filter is NULL as
+// skipped.
+func CountFilteredRows(conv *internal.Conv, tableId string, db *sql.DB, table string) (int64, error) {
+	filter := conv.PushDownRowFilter(tableId)
+	if filter == "" {
+		return 0, nil
+	}
+	var n int64
+	q := fmt.Sprintf("SELECT COUNT(*) - COUNT(CASE WHEN (%s) THEN 1 END) FROM %s", filter, table)
+	if err := db.QueryRow(q).Scan(&n); err != nil {
+		return 0, fmt.Errorf("couldn't count rows skipped by the row filter of table %s: %w", conv.SrcSchema[tableId].Name, err)
+	}
+	return n, nil
+}
+
+// parseKeyBound parses the minimum or maximum value of a split column. Values
+// in floating point notation are approximated, which only affects the widths
+// of the key ranges.
+func parseKeyBound(s string) (int64, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case f >= math.MaxInt64:
+		return math.MaxInt64, nil
+	case f <= math.MinInt64:
+		return math.MinInt64, nil
+	}
+	return int64(f), nil
+}
+
+// parseSum parses the sum of the hashes of a key range.
+func parseSum(s string) (int64, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	f, _, err := big.ParseFloat(s, 10, 128, big.ToNearestEven)
+	if err != nil {
+		return 0, err
+	}
+	i, acc := f.Int64()
+	if acc != big.Exact {
+		return 0, fmt.Errorf("invalid sum %s", s)
+	}
+	return i, nil
+}
+
+// KeyRangeBounds splits the integer values [min, max] into at most n
+// contiguous ranges of roughly equal width, and returns the n-1 (or fewer)
+// boundaries between them in increasing order.
+func KeyRangeBounds(min, max int64, n int) []int64 {
+	// Unsigned arithmetic, since max - min can overflow int64.
+	span := uint64(max) - uint64(min)
+	if n < 1 {
+		n = 1
+	}
+	if span < uint64(n) {
+		n = int(span) + 1
+	}
+	step := span / uint64(n)
+	if span%uint64(n) != 0 || step == 0 {
+		step++
+	}
+	var bounds []int64
+	for i := 1; i < n; i++ {
+		off := uint64(i) * step
+		if off > span {
+			break
+		}
+		bounds = append(bounds, min+int64(off))
+	}
+	return bounds
+}
+
+// splitKeyRange splits the values [min, max] of column col into at most n
+// key ranges (see KeyRangeBounds). The first and last ranges are unbounded,
+// so that together the ranges cover all rows of the table.
+func splitKeyRange(min, max int64, n int, col string, d QueryDialect) []keyRange {
+	return keyRangesOf(KeyRangeBounds(min, max, n), col, d)
+}
+
+// keyRangesOf returns the key ranges of column col delimited by bounds.
+func keyRangesOf(bounds []int64, col string, d QueryDialect) []keyRange {
+	var ranges []keyRange
+	for i := 0; i <= len(bounds); i++ {
+		switch {
+		case len(bounds) == 0:
+			ranges = append(ranges, keyRange{})
+		case i == 0:
+			ranges = append(ranges, keyRange{fmt.Sprintf(" WHERE %s < %s", col, d.Placeholder(1)), []interface{}{bounds[0]}})
+		case i == len(bounds):
+			ranges = append(ranges, keyRange{fmt.Sprintf(" WHERE %s >= %s", col, d.Placeholder(1)), []interface{}{bounds[i-1]}})
+		default:
+			ranges = append(ranges, keyRange{fmt.Sprintf(" WHERE %s >= %s AND %s < %s", col, d.Placeholder(1), col, d.Placeholder(2)), []interface{}{bounds[i-1], bounds[i]}})
+		}
+	}
+	return ranges
+}
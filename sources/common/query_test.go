@@ -0,0 +1,322 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+func TestReadClause(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Id:          "t1",
+		Name:        "orders",
+		ColIds:      []string{"c1", "c2"},
+		ColDefs:     map[string]schema.Column{"c1": {Id: "c1", Name: "a"}, "c2": {Id: "c2", Name: "b"}},
+		PrimaryKeys: []schema.Key{{ColId: "c1"}, {ColId: "c2"}},
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Id:      "t1",
+		Name:    "orders",
+		ColIds:  []string{"c1", "c2"},
+		ColDefs: map[string]ddl.ColumnDef{"c1": {Id: "c1", Name: "a"}, "c2": {Id: "c2", Name: "b"}},
+	}
+	d := QueryDialect{
+		QuoteCol:    func(col string) string { return fmt.Sprintf(`"%s"`, col) },
+		Placeholder: func(i int) string { return fmt.Sprintf("$%d", i) },
+	}
+
+	// No checkpointing.
+	clause, args := ReadClause(conv, "t1", d)
+	assert.Equal(t, "", clause)
+	assert.Nil(t, args)
+
+	// Checkpointing, nothing committed yet.
+	conv.Checkpoint = internal.NewCheckpoint(filepath.Join(t.TempDir(), "test.checkpoint.json"))
+	conv.Checkpoint.MarkInProgress(conv, "t1")
+	clause, args = ReadClause(conv, "t1", d)
+	assert.Equal(t, ` ORDER BY "a", "b"`, clause)
+	assert.Nil(t, args)
+
+	// Resuming after a committed key.
+	conv.Checkpoint.RecordCommit(conv, "orders", 1, []string{"a", "b"}, []interface{}{int64(1), "x"})
+	clause, args = ReadClause(conv, "t1", d)
+	assert.Equal(t, ` WHERE (("a" > $1) OR ("a" = $2 AND "b" > $3)) ORDER BY "a", "b"`, clause)
+	assert.Equal(t, []interface{}{"1", "1", "x"}, args)
+
+	// Row filters are pushed down.
+	orders := conv.SrcSchema["t1"]
+	orders.RowFilter = "tenant_id = 7"
+	conv.SrcSchema["t1"] = orders
+	clause, args = ReadClause(conv, "t1", d)
+	assert.Equal(t, ` WHERE (tenant_id = 7) AND (("a" > $1) OR ("a" = $2 AND "b" > $3)) ORDER BY "a", "b"`, clause)
+	assert.Equal(t, []interface{}{"1", "1", "x"}, args)
+	conv.Checkpoint = nil
+	clause, args = ReadClause(conv, "t1", d)
+	assert.Equal(t, ` WHERE (tenant_id = 7)`, clause)
+	assert.Nil(t, args)
+
+	// Reads restricted to a key range.
+	start, limit := int64(10), int64(20)
+	conv.SetReadKeyRange("t1", &internal.KeyRange{ColId: "c1", Start: &start, Limit: &limit})
+	clause, args = ReadClause(conv, "t1", d)
+	assert.Equal(t, ` WHERE (tenant_id = 7) AND "a" >= $1 AND "a" < $2`, clause)
+	assert.Equal(t, []interface{}{int64(10), int64(20)}, args)
+	conv.SetReadKeyRange("t1", &internal.KeyRange{ColId: "c1", Limit: &limit})
+	clause, args = ReadClause(conv, "t1", d)
+	assert.Equal(t, ` WHERE (tenant_id = 7) AND "a" < $1`, clause)
+	assert.Equal(t, []interface{}{int64(20)}, args)
+	conv.SetReadKeyRange("t1", nil)
+	clause, _ = ReadClause(conv, "t1", d)
+	assert.Equal(t, ` WHERE (tenant_id = 7)`, clause)
+}
+
+func TestSplitKeyRange(t *testing.T) {
+	d := QueryDialect{Placeholder: func(i int) string { return "?" }}
+	ranges := splitKeyRange(1, 100, 4, "id", d)
+	assert.Equal(t, []keyRange{
+		{" WHERE id < ?", []interface{}{int64(26)}},
+		{" WHERE id >= ? AND id < ?", []interface{}{int64(26), int64(51)}},
+		{" WHERE id >= ? AND id < ?", []interface{}{int64(51), int64(76)}},
+		{" WHERE id >= ?", []interface{}{int64(76)}},
+	}, ranges)
+
+	// No more ranges than values.
+	ranges = splitKeyRange(5, 6, 8, "id", d)
+	assert.Equal(t, []keyRange{
+		{" WHERE id < ?", []interface{}{int64(6)}},
+		{" WHERE id >= ?", []interface{}{int64(6)}},
+	}, ranges)
+	assert.Len(t, splitKeyRange(7, 7, 8, "id", d), 1)
+
+	// The full int64 range doesn't overflow.
+	ranges = splitKeyRange(math.MinInt64, math.MaxInt64, 2, "id", d)
+	assert.Equal(t, []keyRange{
+		{" WHERE id < ?", []interface{}{int64(0)}},
+		{" WHERE id >= ?", []interface{}{int64(0)}},
+	}, ranges)
+}
+
+func TestParseKeyBound(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		expected int64
+	}{
+		{"42", 42},
+		{"-7", -7},
+		{"1e+06", 1000000},
+		{"18446744073709551615", math.MaxInt64},
+	} {
+		v, err := parseKeyBound(tc.s)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, v)
+	}
+	_, err := parseKeyBound("abc")
+	assert.NotNil(t, err)
+}
+
+func buildKeyRangeConv(srcType string) *internal.Conv {
+	conv := internal.MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Id:          "t1",
+		Name:        "orders",
+		ColIds:      []string{"c1", "c2"},
+		ColDefs:     map[string]schema.Column{"c1": {Id: "c1", Name: "id", Type: schema.Type{Name: srcType}}, "c2": {Id: "c2", Name: "note", Type: schema.Type{Name: "text"}}},
+		PrimaryKeys: []schema.Key{{ColId: "c1"}},
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Id:      "t1",
+		Name:    "orders",
+		ColIds:  []string{"c1", "c2"},
+		ColDefs: map[string]ddl.ColumnDef{"c1": {Id: "c1", Name: "id", T: ddl.Type{Name: ddl.Int64}}, "c2": {Id: "c2", Name: "note", T: ddl.Type{Name: ddl.String}}},
+	}
+	return conv
+}
+
+var keyRangeDialect = QueryDialect{
+	QuoteCol:    func(col string) string { return col },
+	Placeholder: func(i int) string { return fmt.Sprintf("$%d", i) },
+	IsInteger:   func(srcType schema.Type) bool { return srcType.Name == "bigint" },
+	HashText: func(col string, srcType schema.Type, spType ddl.Type) (string, bool) {
+		switch srcType.Name {
+		case "bigint":
+			return "TEXT(" + col + ")", true
+		case "text":
+			return col, true
+		}
+		return "", false
+	},
+	Hash: HashDialect{
+		Concat: ConcatOperator,
+		Hash:   func(text string) string { return "H(" + text + ")" },
+	},
+}
+
+func TestSplitColumn(t *testing.T) {
+	col, ok := SplitColumn(buildKeyRangeConv("bigint"), "t1", keyRangeDialect)
+	assert.True(t, ok)
+	assert.Equal(t, "id", col)
+
+	_, ok = SplitColumn(buildKeyRangeConv("varchar"), "t1", keyRangeDialect)
+	assert.False(t, ok)
+
+	conv := buildKeyRangeConv("bigint")
+	sp := conv.SpSchema["t1"]
+	sp.ColDefs["c1"] = ddl.ColumnDef{Id: "c1", Name: "id", T: ddl.Type{Name: ddl.String}}
+	_, ok = SplitColumn(conv, "t1", keyRangeDialect)
+	assert.False(t, ok)
+}
+
+func TestReadKeyRanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT MIN(id), MAX(id) FROM orders`)).
+		WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow("1", "9"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM orders WHERE id < $1`)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM orders WHERE id >= $1 AND id < $2`)).WithArgs(int64(4), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM orders WHERE id >= $1`)).WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7).AddRow(9))
+
+	conv := buildKeyRangeConv("bigint")
+	query := func(where string, args []interface{}) (*sql.Rows, error) {
+		return db.Query("SELECT * FROM orders"+where, args...)
+	}
+	var ids []int64
+	process := func(rows *sql.Rows, mutex *sync.Mutex) {
+		for rows.Next() {
+			var id int64
+			assert.Nil(t, rows.Scan(&id))
+			mutex.Lock()
+			ids = append(ids, id)
+			mutex.Unlock()
+		}
+	}
+	ok, err := ReadKeyRanges(conv, "t1", 3, db, "orders", keyRangeDialect, query, process)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []int64{1, 3, 5, 7, 9}, ids)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// Tables that can't be split are read by the caller.
+	ok, err = ReadKeyRanges(buildKeyRangeConv("varchar"), "t1", 3, db, "orders", keyRangeDialect, query, process)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestRowHashSum(t *testing.T) {
+	d := HashDialect{Concat: ConcatFunction, Hash: func(text string) string { return "H(" + text + ")" }}
+	assert.Equal(t,
+		"COALESCE(SUM(H(CONCAT(COALESCE(k, ''), '|0:', CASE WHEN k IS NULL THEN 'n' ELSE CONCAT('v', k) END)) + "+
+			"H(CONCAT(COALESCE(k, ''), '|1:', CASE WHEN v IS NULL THEN 'n' ELSE CONCAT('v', v) END))), 0)",
+		RowHashSum(d, []string{"k"}, []string{"k", "v"}))
+	assert.Equal(t,
+		"COALESCE(SUM(H(CONCAT(CONCAT(COALESCE(k1, ''), '|', COALESCE(k2, '')), '|0:', CASE WHEN v IS NULL THEN 'n' ELSE CONCAT('v', v) END))), 0)",
+		RowHashSum(d, []string{"k1", "k2"}, []string{"v"}))
+}
+
+func TestHashableColumns(t *testing.T) {
+	conv := buildKeyRangeConv("bigint")
+	assert.Equal(t, []string{"c1", "c2"}, HashableColumns(conv, "t1", []string{"c1", "c2"}, keyRangeDialect))
+	assert.Equal(t, []string{"c2"}, HashableColumns(buildKeyRangeConv("decimal"), "t1", []string{"c1", "c2"}, keyRangeDialect))
+	// Transformed values differ from the source values.
+	conv.ColumnTransformations = map[string]map[string]internal.ColumnTransformation{"t1": {"c2": {}}}
+	assert.Equal(t, []string{"c1"}, HashableColumns(conv, "t1", []string{"c1", "c2"}, keyRangeDialect))
+}
+
+func TestHashKeyRanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM orders WHERE id < $1 AND (note <> '')`)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM orders WHERE id >= $1 AND (note <> '')`)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM orders WHERE (note <> '')`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	sum := "COALESCE(SUM(H((COALESCE(TEXT(id), '') || '|0:' || CASE WHEN note IS NULL THEN 'n' ELSE ('v' || note) END))), 0)"
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*), ` + sum + ` FROM orders WHERE id < $1 AND (note <> '')`)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "hash"}).AddRow(2, "30000000"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*), ` + sum + ` FROM orders WHERE id >= $1 AND (note <> '')`)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "hash"}).AddRow(3, "4.5E7"))
+
+	conv := buildKeyRangeConv("bigint")
+	orders := conv.SrcSchema["t1"]
+	orders.RowFilter = "note <> ''"
+	conv.SrcSchema["t1"] = orders
+	sums, ok, err := HashKeyRanges(conv, "t1", "c1", []int64{4}, nil, nil, db, "orders", keyRangeDialect)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, []KeyRangeSummary{{Rows: 2}, {Rows: 3}}, sums)
+
+	// Without bounds, the whole table is summarized.
+	sums, ok, err = HashKeyRanges(conv, "t1", "", nil, nil, nil, db, "orders", keyRangeDialect)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, []KeyRangeSummary{{Rows: 5}}, sums)
+
+	sums, ok, err = HashKeyRanges(conv, "t1", "c1", []int64{4}, []string{"c1"}, []string{"c2"}, db, "orders", keyRangeDialect)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, []KeyRangeSummary{{Rows: 2, Hash: 30000000}, {Rows: 3, Hash: 45000000}}, sums)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// Non-integer columns can't be split.
+	_, ok, err = HashKeyRanges(buildKeyRangeConv("varchar"), "t1", "c1", []int64{4}, nil, nil, db, "orders", keyRangeDialect)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	// Columns that can't be hashed are an error.
+	_, ok, err = HashKeyRanges(buildKeyRangeConv("decimal"), "t1", "", nil, []string{"c1"}, []string{"c2"}, db, "orders", keyRangeDialect)
+	assert.True(t, ok)
+	assert.NotNil(t, err)
+}
+
+func TestCountFilteredRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) - COUNT(CASE WHEN (note <> '') THEN 1 END) FROM orders`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	conv := buildKeyRangeConv("bigint")
+	n, err := CountFilteredRows(conv, "t1", db, "orders")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+
+	orders := conv.SrcSchema["t1"]
+	orders.RowFilter = "note <> ''"
+	conv.SrcSchema["t1"] = orders
+	n, err = CountFilteredRows(conv, "t1", db, "orders")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,787 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// ScriptDialect describes the parts of the DDL of a source database that
+// differ between databases, for building the source schema from a SQL
+// script (e.g. an Oracle or SQL Server DDL script) with ProcessScriptStatement.
+type ScriptDialect interface {
+	// Ident returns the name of an identifier token, e.g. with unquoted
+	// identifiers converted to upper case for Oracle.
+	Ident(t ScriptToken) string
+	// TableName returns the name of a table in the source schema, given its
+	// schema name (empty if unqualified) and its name.
+	TableName(schemaName, name string) string
+	// ColumnType parses the data type of a column definition.
+	ColumnType(p *ScriptParser) (schema.Type, error)
+	// JSONCheck reports whether a check constraint expression enforces that
+	// a column holds JSON documents.
+	JSONCheck(expr []ScriptToken) bool
+}
+
+// ProcessScriptStatement updates the source schema of conv with a CREATE
+// TABLE, ALTER TABLE or CREATE INDEX statement of a SQL script. Statements
+// of other types are skipped. Errors are recorded as unexpected conditions.
+func ProcessScriptStatement(conv *internal.Conv, toks []ScriptToken, d ScriptDialect) {
+	stmtType := ScriptStatementType(toks)
+	p := NewScriptParser(toks)
+	var err error
+	switch stmtType {
+	case "CREATE TABLE":
+		err = processScriptCreateTable(conv, p, d)
+	case "ALTER TABLE":
+		err = processScriptAlterTable(conv, p, d)
+	case "CREATE INDEX":
+		err = processScriptCreateIndex(conv, p, d)
+	default:
+		conv.SkipStatement(stmtType)
+		return
+	}
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Processing %s statement: %s", stmtType, err))
+		conv.ErrorInStatement(stmtType)
+		return
+	}
+	conv.SchemaStatement(stmtType)
+}
+
+// ResolveScriptForeignKeys resolves the tables and columns referenced by the
+// foreign keys of a source schema built with ProcessScriptStatement. Foreign
+// keys that don't list the referenced columns reference the primary key of
+// the referenced table.
+func ResolveScriptForeignKeys(conv *internal.Conv) {
+	for _, tbl := range conv.SrcSchema {
+		for i, fk := range tbl.ForeignKeys {
+			if len(fk.ReferColumnNames) > 0 {
+				continue
+			}
+			if refTbl, ok := internal.GetSrcTableByName(conv.SrcSchema, fk.ReferTableName); ok {
+				for _, k := range refTbl.PrimaryKeys {
+					tbl.ForeignKeys[i].ReferColumnNames = append(tbl.ForeignKeys[i].ReferColumnNames, refTbl.ColDefs[k.ColId].Name)
+				}
+			}
+		}
+	}
+	internal.ResolveForeignKeyIds(conv.SrcSchema)
+}
+
+// scriptKey is a column of a key or index.
+type scriptKey struct {
+	col  string
+	desc bool
+}
+
+// scriptConstraint is a table constraint (or an inline index) of a CREATE
+// TABLE or ALTER TABLE statement.
+type scriptConstraint struct {
+	name   string
+	kind   string // PRIMARY KEY, UNIQUE, FOREIGN KEY, CHECK or INDEX.
+	keys   []scriptKey
+	fk     schema.ForeignKey
+	check  []ScriptToken
+	stored []string
+}
+
+// isScriptConstraint reports whether an element of a CREATE TABLE or ALTER
+// TABLE ADD statement is a table constraint rather than a column.
+func isScriptConstraint(p *ScriptParser) bool {
+	t := p.Peek()
+	for _, w := range []string{"CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "INDEX"} {
+		if t.IsWord(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseScriptName parses a possibly qualified object name, e.g.
+// "HR"."EMPLOYEES" or [dbo].[Orders], and returns its schema and name.
+func parseScriptName(p *ScriptParser, d ScriptDialect) (string, string, error) {
+	var parts []string
+	for {
+		t := p.Next()
+		if !t.IsIdent() {
+			return "", "", fmt.Errorf("expected a name, found '%s'", t.Text)
+		}
+		parts = append(parts, d.Ident(t))
+		if !p.AcceptPunct(".") {
+			break
+		}
+	}
+	if len(parts) == 1 {
+		return "", parts[0], nil
+	}
+	return parts[len(parts)-2], parts[len(parts)-1], nil
+}
+
+// parseScriptKeys parses a parenthesized list of key columns, each with an
+// optional ASC or DESC.
+func parseScriptKeys(p *ScriptParser, d ScriptDialect) ([]scriptKey, error) {
+	g, ok := p.Group()
+	if !ok {
+		return nil, fmt.Errorf("expected a list of columns, found '%s'", p.Peek().Text)
+	}
+	var keys []scriptKey
+	for _, item := range g.List() {
+		t := item.Next()
+		if !t.IsIdent() {
+			return nil, fmt.Errorf("expected a column name, found '%s'", t.Text)
+		}
+		k := scriptKey{col: d.Ident(t)}
+		if item.Accept("DESC") {
+			k.desc = true
+		} else {
+			item.Accept("ASC")
+		}
+		if !item.Done() {
+			return nil, fmt.Errorf("expressions in keys are not supported")
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// parseScriptReferences parses the target of a foreign key, after its
+// REFERENCES keyword.
+func parseScriptReferences(p *ScriptParser, d ScriptDialect, cols []string) (schema.ForeignKey, error) {
+	refSchema, refTable, err := parseScriptName(p, d)
+	if err != nil {
+		return schema.ForeignKey{}, err
+	}
+	fk := schema.ForeignKey{
+		Id:             internal.GenerateForeignkeyId(),
+		ColumnNames:    cols,
+		ReferTableName: d.TableName(refSchema, refTable),
+		OnDelete:       constants.FK_NO_ACTION,
+		OnUpdate:       constants.FK_NO_ACTION,
+	}
+	if p.Peek().IsPunct("(") {
+		keys, err := parseScriptKeys(p, d)
+		if err != nil {
+			return schema.ForeignKey{}, err
+		}
+		for _, k := range keys {
+			fk.ReferColumnNames = append(fk.ReferColumnNames, k.col)
+		}
+		if len(fk.ReferColumnNames) != len(cols) {
+			return schema.ForeignKey{}, fmt.Errorf("foreign key references %d columns for %d columns", len(fk.ReferColumnNames), len(cols))
+		}
+	}
+	for {
+		var rule *string
+		switch {
+		case p.Accept("ON", "DELETE"):
+			rule = &fk.OnDelete
+		case p.Accept("ON", "UPDATE"):
+			rule = &fk.OnUpdate
+		default:
+			return fk, nil
+		}
+		switch {
+		case p.Accept("CASCADE"):
+			*rule = constants.FK_CASCADE
+		case p.Accept("SET", "NULL"):
+			*rule = constants.FK_SET_NULL
+		case p.Accept("SET", "DEFAULT"):
+			*rule = constants.FK_SET_DEFAULT
+		case p.Accept("RESTRICT"):
+			*rule = constants.FK_RESTRICT
+		case p.Accept("NO", "ACTION"):
+			*rule = constants.FK_NO_ACTION
+		}
+	}
+}
+
+// parseScriptConstraint parses a table constraint, or an inline index of a
+// T-SQL CREATE TABLE statement. Options like Oracle's USING INDEX or T-SQL's
+// WITH (...) are ignored.
+func parseScriptConstraint(p *ScriptParser, d ScriptDialect) (scriptConstraint, error) {
+	var c scriptConstraint
+	if p.Accept("CONSTRAINT") {
+		t := p.Next()
+		if !t.IsIdent() {
+			return c, fmt.Errorf("expected a constraint name, found '%s'", t.Text)
+		}
+		c.name = d.Ident(t)
+	}
+	var err error
+	switch {
+	case p.Accept("PRIMARY", "KEY"):
+		c.kind = "PRIMARY KEY"
+		_ = p.Accept("CLUSTERED") || p.Accept("NONCLUSTERED")
+		c.keys, err = parseScriptKeys(p, d)
+	case p.Accept("UNIQUE"):
+		c.kind = "UNIQUE"
+		_ = p.Accept("CLUSTERED") || p.Accept("NONCLUSTERED")
+		c.keys, err = parseScriptKeys(p, d)
+	case p.Accept("INDEX"):
+		c.kind = "INDEX"
+		t := p.Next()
+		if !t.IsIdent() {
+			return c, fmt.Errorf("expected an index name, found '%s'", t.Text)
+		}
+		c.name = d.Ident(t)
+		if p.Accept("UNIQUE") {
+			c.kind = "UNIQUE"
+		}
+		_ = p.Accept("CLUSTERED") || p.Accept("NONCLUSTERED")
+		c.keys, err = parseScriptKeys(p, d)
+	case p.Accept("FOREIGN", "KEY"):
+		c.kind = "FOREIGN KEY"
+		var cols []scriptKey
+		if cols, err = parseScriptKeys(p, d); err != nil {
+			return c, err
+		}
+		if !p.Accept("REFERENCES") {
+			return c, fmt.Errorf("expected REFERENCES, found '%s'", p.Peek().Text)
+		}
+		var names []string
+		for _, k := range cols {
+			names = append(names, k.col)
+		}
+		c.fk, err = parseScriptReferences(p, d, names)
+		c.fk.Name = c.name
+	case p.Accept("CHECK"):
+		c.kind = "CHECK"
+		p.Accept("NOT", "FOR", "REPLICATION")
+		g, ok := p.Group()
+		if !ok {
+			return c, fmt.Errorf("expected a check expression, found '%s'", p.Peek().Text)
+		}
+		c.check = g.Rest()
+	default:
+		return c, fmt.Errorf("unsupported constraint '%s'", p.Peek().Text)
+	}
+	return c, err
+}
+
+// parseScriptColumn parses a column definition. Inline constraints other than
+// NOT NULL are returned as table constraints on the column.
+func parseScriptColumn(p *ScriptParser, d ScriptDialect) (schema.Column, []scriptConstraint, error) {
+	t := p.Next()
+	if !t.IsIdent() {
+		return schema.Column{}, nil, fmt.Errorf("expected a column name, found '%s'", t.Text)
+	}
+	col := schema.Column{Name: d.Ident(t)}
+	var err error
+	if p.Peek().IsWord("AS") || p.Peek().IsWord("GENERATED") {
+		// Computed columns without a data type are only converted when
+		// their expression is a cast.
+		var ok bool
+		if col.Type, ok = scriptCastType(NewScriptParser(p.Rest()), d); !ok {
+			return col, nil, fmt.Errorf("computed column %s has no data type", col.Name)
+		}
+	} else if col.Type, err = d.ColumnType(p); err != nil {
+		return col, nil, fmt.Errorf("column %s: %w", col.Name, err)
+	}
+	var cs []scriptConstraint
+	var name string
+	for !p.Done() {
+		if expr, ok := acceptScriptGenerated(p); ok {
+			col.Generated = ddl.GeneratedColumn{
+				IsPresent: true,
+				Value:     ddl.Expression{ExpressionId: internal.GenerateExpressionId(), Statement: scriptExprText(expr.Rest())},
+				Stored:    p.Accept("PERSISTED") || p.Accept("STORED"),
+			}
+			p.Accept("VIRTUAL")
+			name = ""
+			continue
+		}
+		switch {
+		case p.Accept("CONSTRAINT"):
+			name = d.Ident(p.Next())
+			continue
+		case p.Accept("NOT", "NULL"):
+			col.NotNull = true
+		case p.Accept("DEFAULT"):
+			col.Ignored.Default = true
+		case p.Accept("IDENTITY") || p.Accept("AS", "IDENTITY"):
+			col.Ignored.Identity = true
+		case p.Accept("PRIMARY", "KEY"):
+			cs = append(cs, scriptConstraint{name: name, kind: "PRIMARY KEY", keys: []scriptKey{{col: col.Name}}})
+		case p.Accept("UNIQUE"):
+			cs = append(cs, scriptConstraint{name: name, kind: "UNIQUE", keys: []scriptKey{{col: col.Name}}})
+		case p.Accept("FOREIGN", "KEY") || p.Accept("REFERENCES"):
+			p.Accept("REFERENCES")
+			fk, err := parseScriptReferences(p, d, []string{col.Name})
+			if err != nil {
+				return col, nil, err
+			}
+			fk.Name = name
+			cs = append(cs, scriptConstraint{name: name, kind: "FOREIGN KEY", fk: fk})
+		case p.Accept("CHECK"):
+			g, ok := p.Group()
+			if !ok {
+				return col, nil, fmt.Errorf("expected a check expression, found '%s'", p.Peek().Text)
+			}
+			col.Ignored.Check = true
+			if d.JSONCheck(g.Rest()) {
+				col.Type = schema.Type{Name: "JSON"}
+			}
+		default:
+			p.Skip()
+			continue
+		}
+		name = ""
+	}
+	return col, cs, nil
+}
+
+// isScriptComputedColumn reports whether a column definition is a computed
+// column without a data type, e.g. T-SQL's 'total AS (price * qty)'.
+func isScriptComputedColumn(p *ScriptParser) bool {
+	rest := p.Rest()
+	return len(rest) > 1 && (rest[1].IsWord("AS") || rest[1].IsWord("GENERATED"))
+}
+
+// isScriptCastColumn reports whether a computed column without a data type
+// has a cast expression, from which scriptCastType infers its data type.
+func isScriptCastColumn(p *ScriptParser, d ScriptDialect) bool {
+	q := NewScriptParser(p.Rest())
+	q.Next()
+	_, ok := scriptCastType(q, d)
+	return ok
+}
+
+// acceptScriptGenerated consumes the expression of a computed column,
+// 'GENERATED ALWAYS AS (expr)' or 'AS (expr)', and returns a parser over the
+// tokens of expr. Returns false, without consuming anything, if the next
+// tokens aren't such an expression, e.g. for 'AS IDENTITY'.
+func acceptScriptGenerated(p *ScriptParser) (*ScriptParser, bool) {
+	start := p.pos
+	p.Accept("GENERATED", "ALWAYS")
+	if p.Accept("AS") {
+		if g, ok := p.Group(); ok {
+			return g, true
+		}
+	}
+	p.pos = start
+	return nil, false
+}
+
+// scriptCastType returns the data type of a computed column whose expression
+// is a cast, e.g. T-SQL's 'AS (CONVERT([decimal](10,2),[price]*(2)))' or
+// 'AS (CAST(price * 2 AS int))'. Returns false for other expressions.
+func scriptCastType(p *ScriptParser, d ScriptDialect) (schema.Type, bool) {
+	g, ok := acceptScriptGenerated(p)
+	if !ok {
+		return schema.Type{}, false
+	}
+	// Skip redundant parentheses around the expression.
+	for {
+		inner := NewScriptParser(g.Rest())
+		if ig, ok := inner.Group(); ok && inner.Done() {
+			g = ig
+			continue
+		}
+		break
+	}
+	switch {
+	case g.Accept("CAST"):
+		args, ok := g.Group()
+		if !ok || !g.Done() {
+			return schema.Type{}, false
+		}
+		for !args.Done() && !args.Accept("AS") {
+			args.Skip()
+		}
+		if args.Done() {
+			return schema.Type{}, false
+		}
+		t, err := d.ColumnType(args)
+		return t, err == nil && args.Done()
+	case g.Accept("CONVERT"):
+		args, ok := g.Group()
+		if !ok || !g.Done() {
+			return schema.Type{}, false
+		}
+		t, err := d.ColumnType(args)
+		return t, err == nil && args.AcceptPunct(",")
+	}
+	return schema.Type{}, false
+}
+
+// scriptExprText returns the SQL text of an expression from its tokens, with
+// identifiers quoted as in the ANSI standard.
+func scriptExprText(toks []ScriptToken) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 {
+			prev := toks[i-1]
+			switch {
+			case prev.IsPunct("(") || prev.IsPunct(".") || t.IsPunct(")") || t.IsPunct(",") || t.IsPunct("."):
+			case t.IsPunct("(") && prev.IsIdent():
+				// Function call.
+			default:
+				b.WriteString(" ")
+			}
+		}
+		switch t.Kind {
+		case QuotedIdentToken:
+			b.WriteString(`"` + strings.ReplaceAll(t.Text, `"`, `""`) + `"`)
+		case StringToken:
+			b.WriteString("'" + strings.ReplaceAll(t.Text, "'", "''") + "'")
+		default:
+			b.WriteString(t.Text)
+		}
+	}
+	return b.String()
+}
+
+// addScriptColumn adds a column to a table of the source schema.
+func addScriptColumn(tbl *schema.Table, col schema.Column) error {
+	if _, ok := tbl.ColNameIdMap[col.Name]; ok {
+		return fmt.Errorf("duplicate column %s", col.Name)
+	}
+	col.Id = internal.GenerateColumnId()
+	tbl.ColIds = append(tbl.ColIds, col.Id)
+	tbl.ColDefs[col.Id] = col
+	tbl.ColNameIdMap[col.Name] = col.Id
+	return nil
+}
+
+// scriptKeys converts the columns of a key to schema keys.
+func scriptKeys(tbl *schema.Table, keys []scriptKey) ([]schema.Key, error) {
+	var sk []schema.Key
+	for _, k := range keys {
+		colId, ok := tbl.ColNameIdMap[k.col]
+		if !ok {
+			return nil, fmt.Errorf("table %s has no column %s", tbl.Name, k.col)
+		}
+		sk = append(sk, schema.Key{ColId: colId, Desc: k.desc})
+	}
+	return sk, nil
+}
+
+// addScriptIndex adds an index to a table of the source schema, unless the
+// table already has an index of the same name, or the index is the index
+// that backs the primary key. Scripts often contain both the index that
+// backs a constraint and the constraint itself.
+func addScriptIndex(tbl *schema.Table, name string, unique bool, keys []scriptKey, stored []string) error {
+	sk, err := scriptKeys(tbl, keys)
+	if err != nil {
+		return err
+	}
+	for i, idx := range tbl.Indexes {
+		if name != "" && idx.Name == name {
+			tbl.Indexes[i].Unique = idx.Unique || unique
+			return nil
+		}
+	}
+	if sameScriptKeys(sk, tbl.PrimaryKeys) && unique {
+		return nil
+	}
+	idx := schema.Index{Id: internal.GenerateIndexesId(), Name: name, Unique: unique, Keys: sk}
+	for _, col := range stored {
+		colId, ok := tbl.ColNameIdMap[col]
+		if !ok {
+			return fmt.Errorf("table %s has no column %s", tbl.Name, col)
+		}
+		idx.StoredColumnIds = append(idx.StoredColumnIds, colId)
+	}
+	tbl.Indexes = append(tbl.Indexes, idx)
+	return nil
+}
+
+func sameScriptKeys(a, b []schema.Key) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ColId != b[i].ColId {
+			return false
+		}
+	}
+	return true
+}
+
+// applyScriptConstraint adds a constraint to a table of the source schema.
+func applyScriptConstraint(tbl *schema.Table, c scriptConstraint, d ScriptDialect) error {
+	switch c.kind {
+	case "PRIMARY KEY":
+		pk, err := scriptKeys(tbl, c.keys)
+		if err != nil {
+			return err
+		}
+		tbl.PrimaryKeys = pk
+		// Primary key columns are implicitly NOT NULL.
+		for _, k := range pk {
+			col := tbl.ColDefs[k.ColId]
+			col.NotNull = true
+			tbl.ColDefs[k.ColId] = col
+		}
+		// Drop the index that backs the primary key, if it was created first.
+		var indexes []schema.Index
+		for _, idx := range tbl.Indexes {
+			if !(idx.Unique && sameScriptKeys(idx.Keys, pk)) {
+				indexes = append(indexes, idx)
+			}
+		}
+		tbl.Indexes = indexes
+	case "UNIQUE", "INDEX":
+		return addScriptIndex(tbl, c.name, c.kind == "UNIQUE", c.keys, c.stored)
+	case "FOREIGN KEY":
+		for _, col := range c.fk.ColumnNames {
+			if _, ok := tbl.ColNameIdMap[col]; !ok {
+				return fmt.Errorf("table %s has no column %s", tbl.Name, col)
+			}
+		}
+		tbl.ForeignKeys = append(tbl.ForeignKeys, c.fk)
+	case "CHECK":
+		// Like the information schema based conversion, we record which
+		// columns have check constraints, but don't convert them.
+		json := d.JSONCheck(c.check)
+		for _, t := range c.check {
+			if !t.IsIdent() {
+				continue
+			}
+			if colId, ok := tbl.ColNameIdMap[d.Ident(t)]; ok {
+				col := tbl.ColDefs[colId]
+				col.Ignored.Check = true
+				if json {
+					col.Type = schema.Type{Name: "JSON"}
+				}
+				tbl.ColDefs[colId] = col
+			}
+		}
+	}
+	return nil
+}
+
+// scriptTable looks up a table of the source schema by its (possibly
+// qualified) name.
+func scriptTable(conv *internal.Conv, p *ScriptParser, d ScriptDialect) (*schema.Table, error) {
+	schemaName, name, err := parseScriptName(p, d)
+	if err != nil {
+		return nil, err
+	}
+	tbl, ok := internal.GetSrcTableByName(conv.SrcSchema, d.TableName(schemaName, name))
+	if !ok {
+		return nil, fmt.Errorf("table %s not found", d.TableName(schemaName, name))
+	}
+	return tbl, nil
+}
+
+func processScriptCreateTable(conv *internal.Conv, p *ScriptParser, d ScriptDialect) error {
+	for !p.Done() && !p.Accept("TABLE") {
+		p.Next()
+	}
+	schemaName, name, err := parseScriptName(p, d)
+	if err != nil {
+		return err
+	}
+	tableName := d.TableName(schemaName, name)
+	if _, ok := internal.GetSrcTableByName(conv.SrcSchema, tableName); ok {
+		return fmt.Errorf("table %s is defined more than once", tableName)
+	}
+	g, ok := p.Group()
+	if !ok {
+		return fmt.Errorf("table %s has no column definitions", tableName)
+	}
+	tbl := schema.Table{
+		Id:           internal.GenerateTableId(),
+		Name:         tableName,
+		Schema:       schemaName,
+		ColDefs:      make(map[string]schema.Column),
+		ColNameIdMap: make(map[string]string),
+	}
+	var cs []scriptConstraint
+	for _, item := range g.List() {
+		switch {
+		case isScriptConstraint(item):
+			c, err := parseScriptConstraint(item, d)
+			if err != nil {
+				return err
+			}
+			cs = append(cs, c)
+		case item.Accept("SUPPLEMENTAL", "LOG"), item.Accept("PERIOD", "FOR"):
+		case isScriptComputedColumn(item) && !isScriptCastColumn(item, d):
+			// The data type of computed columns isn't part of their
+			// definition, so we can only convert those whose expression
+			// is a cast.
+			conv.Unexpected(fmt.Sprintf("Skipping computed column %s of table %s", d.Ident(item.Peek()), tableName))
+		default:
+			col, colCs, err := parseScriptColumn(item, d)
+			if err != nil {
+				return err
+			}
+			if err := addScriptColumn(&tbl, col); err != nil {
+				return err
+			}
+			cs = append(cs, colCs...)
+		}
+	}
+	for _, c := range cs {
+		if err := applyScriptConstraint(&tbl, c, d); err != nil {
+			return err
+		}
+	}
+	conv.SrcSchema[tbl.Id] = tbl
+	return nil
+}
+
+func processScriptAlterTable(conv *internal.Conv, p *ScriptParser, d ScriptDialect) error {
+	p.Accept("ALTER", "TABLE")
+	tbl, err := scriptTable(conv, p, d)
+	if err != nil {
+		return err
+	}
+	_ = p.Accept("WITH", "CHECK") || p.Accept("WITH", "NOCHECK")
+	switch {
+	case p.Accept("ADD"):
+		if p.Peek().IsWord("SUPPLEMENTAL") {
+			break
+		}
+		var items []*ScriptParser
+		if g, ok := p.Group(); ok {
+			items = g.List()
+		} else {
+			items = p.List()
+		}
+		for _, item := range items {
+			if err := alterScriptTableAdd(tbl, item, d); err != nil {
+				return err
+			}
+		}
+	case p.Accept("MODIFY"):
+		items := []*ScriptParser{p}
+		if g, ok := p.Group(); ok {
+			items = g.List()
+		}
+		for _, item := range items {
+			colId, ok := tbl.ColNameIdMap[d.Ident(item.Next())]
+			if !ok {
+				return fmt.Errorf("table %s has no such column", tbl.Name)
+			}
+			col := tbl.ColDefs[colId]
+			for !item.Done() {
+				switch {
+				case item.Accept("NOT", "NULL"):
+					col.NotNull = true
+				case item.Accept("DEFAULT"):
+					col.Ignored.Default = true
+				default:
+					item.Skip()
+				}
+			}
+			tbl.ColDefs[colId] = col
+		}
+	case p.Accept("CHECK", "CONSTRAINT"), p.Accept("NOCHECK", "CONSTRAINT"), p.Accept("ENABLE"), p.Accept("DISABLE"):
+		// Enabling or disabling constraints doesn't affect the schema.
+	default:
+		return fmt.Errorf("unsupported ALTER TABLE action '%s'", p.Peek().Text)
+	}
+	conv.SrcSchema[tbl.Id] = *tbl
+	return nil
+}
+
+// alterScriptTableAdd adds a column or constraint to a table. It also handles
+// T-SQL's ADD [CONSTRAINT name] DEFAULT expr FOR column.
+func alterScriptTableAdd(tbl *schema.Table, p *ScriptParser, d ScriptDialect) error {
+	start := p.pos
+	if p.Accept("CONSTRAINT") {
+		p.Next()
+	}
+	if p.Accept("DEFAULT") {
+		for !p.Done() && !p.Accept("FOR") {
+			p.Skip()
+		}
+		colId, ok := tbl.ColNameIdMap[d.Ident(p.Next())]
+		if !ok {
+			return fmt.Errorf("table %s has no such column", tbl.Name)
+		}
+		col := tbl.ColDefs[colId]
+		col.Ignored.Default = true
+		tbl.ColDefs[colId] = col
+		return nil
+	}
+	p.pos = start
+	var cs []scriptConstraint
+	if isScriptConstraint(p) {
+		c, err := parseScriptConstraint(p, d)
+		if err != nil {
+			return err
+		}
+		cs = append(cs, c)
+	} else {
+		col, colCs, err := parseScriptColumn(p, d)
+		if err != nil {
+			return err
+		}
+		if err := addScriptColumn(tbl, col); err != nil {
+			return err
+		}
+		cs = colCs
+	}
+	for _, c := range cs {
+		if err := applyScriptConstraint(tbl, c, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func processScriptCreateIndex(conv *internal.Conv, p *ScriptParser, d ScriptDialect) error {
+	p.Accept("CREATE")
+	unique := false
+	for !p.Done() && !p.Accept("INDEX") {
+		switch t := p.Next(); {
+		case t.IsWord("UNIQUE"):
+			unique = true
+		case t.IsWord("COLUMNSTORE"), t.IsWord("SPATIAL"), t.IsWord("XML"), t.IsWord("FULLTEXT"):
+			return fmt.Errorf("%s indexes are not supported", t.Text)
+		}
+	}
+	_, name, err := parseScriptName(p, d)
+	if err != nil {
+		return err
+	}
+	if !p.Accept("ON") {
+		return fmt.Errorf("expected ON, found '%s'", p.Peek().Text)
+	}
+	tbl, err := scriptTable(conv, p, d)
+	if err != nil {
+		return err
+	}
+	keys, err := parseScriptKeys(p, d)
+	if err != nil {
+		return fmt.Errorf("index %s: %w", name, err)
+	}
+	var stored []string
+	if p.Accept("INCLUDE") {
+		cols, err := parseScriptKeys(p, d)
+		if err != nil {
+			return fmt.Errorf("index %s: %w", name, err)
+		}
+		for _, k := range cols {
+			stored = append(stored, k.col)
+		}
+	}
+	if err := addScriptIndex(tbl, name, unique, keys, stored); err != nil {
+		return err
+	}
+	conv.SrcSchema[tbl.Id] = *tbl
+	return nil
+}
@@ -0,0 +1,305 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScriptTokenKind is the kind of a token of a SQL script.
+type ScriptTokenKind int
+
+const (
+	WordToken        ScriptTokenKind = iota // Keyword or unquoted identifier.
+	QuotedIdentToken                        // Quoted identifier, e.g. "name" or [name].
+	StringToken                             // String literal.
+	NumberToken                             // Numeric literal.
+	PunctToken                              // Any other single character, e.g. '(' or ','.
+)
+
+// ScriptToken is a token of a SQL script. The Text of quoted identifiers
+// and string literals has its quotes removed and its escapes resolved.
+type ScriptToken struct {
+	Kind ScriptTokenKind
+	Text string
+}
+
+// IsWord reports whether t is the keyword (or unquoted identifier) w,
+// ignoring case.
+func (t ScriptToken) IsWord(w string) bool {
+	return t.Kind == WordToken && strings.EqualFold(t.Text, w)
+}
+
+// IsPunct reports whether t is the punctuation character s.
+func (t ScriptToken) IsPunct(s string) bool {
+	return t.Kind == PunctToken && t.Text == s
+}
+
+// IsIdent reports whether t can name a database object.
+func (t ScriptToken) IsIdent() bool {
+	return t.Kind == WordToken || t.Kind == QuotedIdentToken
+}
+
+// TokenizeScript splits a SQL statement into tokens, skipping whitespace and
+// comments. Identifiers can be quoted with double quotes, and, if brackets
+// is set, with square brackets as in T-SQL. Returns an error if the
+// statement ends inside a string literal, quoted identifier or comment.
+func TokenizeScript(stmt string, brackets bool) ([]ScriptToken, error) {
+	var toks []ScriptToken
+	s := []rune(stmt)
+	isWordChar := func(c rune) bool {
+		return c == '_' || c == '$' || c == '#' || c == '@' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c > 127
+	}
+	isDigit := func(c rune) bool { return c >= '0' && c <= '9' }
+	// quoted reads a literal that starts at s[i] and ends with close, where
+	// close is escaped by doubling it.
+	quoted := func(i int, close rune) (string, int, error) {
+		var b strings.Builder
+		for j := i + 1; j < len(s); j++ {
+			if s[j] != close {
+				b.WriteRune(s[j])
+				continue
+			}
+			if j+1 < len(s) && s[j+1] == close {
+				b.WriteRune(close)
+				j++
+				continue
+			}
+			return b.String(), j + 1, nil
+		}
+		return "", 0, fmt.Errorf("unterminated %c", s[i])
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && i+1 < len(s) && s[i+1] == '-':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			j := i + 2
+			for j+1 < len(s) && !(s[j] == '*' && s[j+1] == '/') {
+				j++
+			}
+			if j+1 >= len(s) {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i = j + 2
+		case c == '\'' || (c == 'N' || c == 'n') && i+1 < len(s) && s[i+1] == '\'':
+			if c != '\'' {
+				i++
+			}
+			text, next, err := quoted(i, '\'')
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, ScriptToken{Kind: StringToken, Text: text})
+			i = next
+		case c == '"' || brackets && c == '[':
+			close := '"'
+			if c == '[' {
+				close = ']'
+			}
+			text, next, err := quoted(i, close)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, ScriptToken{Kind: QuotedIdentToken, Text: text})
+			i = next
+		case isDigit(c) || c == '.' && i+1 < len(s) && isDigit(s[i+1]):
+			j := i + 1
+			for j < len(s) && (isDigit(s[j]) || s[j] == '.') {
+				j++
+			}
+			if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+				j++
+				if j < len(s) && (s[j] == '+' || s[j] == '-') {
+					j++
+				}
+				for j < len(s) && isDigit(s[j]) {
+					j++
+				}
+			}
+			toks = append(toks, ScriptToken{Kind: NumberToken, Text: string(s[i:j])})
+			i = j
+		case isWordChar(c):
+			j := i + 1
+			for j < len(s) && isWordChar(s[j]) {
+				j++
+			}
+			toks = append(toks, ScriptToken{Kind: WordToken, Text: string(s[i:j])})
+			i = j
+		default:
+			toks = append(toks, ScriptToken{Kind: PunctToken, Text: string(c)})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+// ScriptParser is a cursor over the tokens of a SQL statement.
+type ScriptParser struct {
+	toks []ScriptToken
+	pos  int
+}
+
+// NewScriptParser returns a parser positioned at the first of toks.
+func NewScriptParser(toks []ScriptToken) *ScriptParser {
+	return &ScriptParser{toks: toks}
+}
+
+// Done reports whether all tokens have been consumed.
+func (p *ScriptParser) Done() bool {
+	return p.pos >= len(p.toks)
+}
+
+// Peek returns the next token without consuming it, or an empty token if
+// all tokens have been consumed.
+func (p *ScriptParser) Peek() ScriptToken {
+	if p.Done() {
+		return ScriptToken{Kind: PunctToken}
+	}
+	return p.toks[p.pos]
+}
+
+// Next consumes and returns the next token.
+func (p *ScriptParser) Next() ScriptToken {
+	t := p.Peek()
+	if !p.Done() {
+		p.pos++
+	}
+	return t
+}
+
+// Accept consumes the given sequence of keywords if the next tokens match
+// it, and reports whether they did.
+func (p *ScriptParser) Accept(words ...string) bool {
+	if p.pos+len(words) > len(p.toks) {
+		return false
+	}
+	for i, w := range words {
+		if !p.toks[p.pos+i].IsWord(w) {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+// AcceptPunct consumes the next token if it is the punctuation character s.
+func (p *ScriptParser) AcceptPunct(s string) bool {
+	if p.Peek().IsPunct(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// Group consumes a parenthesized group of tokens, and returns a parser over
+// the tokens inside the parentheses. Returns false, without consuming
+// anything, if the next token isn't an opening parenthesis.
+func (p *ScriptParser) Group() (*ScriptParser, bool) {
+	if !p.Peek().IsPunct("(") {
+		return nil, false
+	}
+	depth := 0
+	for i := p.pos; i < len(p.toks); i++ {
+		switch {
+		case p.toks[i].IsPunct("("):
+			depth++
+		case p.toks[i].IsPunct(")"):
+			depth--
+			if depth == 0 {
+				g := NewScriptParser(p.toks[p.pos+1 : i])
+				p.pos = i + 1
+				return g, true
+			}
+		}
+	}
+	// Unbalanced parentheses: the group extends to the end of the statement.
+	g := NewScriptParser(p.toks[p.pos+1:])
+	p.pos = len(p.toks)
+	return g, true
+}
+
+// Skip consumes the next token, or the next parenthesized group.
+func (p *ScriptParser) Skip() {
+	if _, ok := p.Group(); !ok {
+		p.Next()
+	}
+}
+
+// List splits the remaining tokens into the items of a comma separated list,
+// ignoring commas inside parentheses, and consumes them.
+func (p *ScriptParser) List() []*ScriptParser {
+	var items []*ScriptParser
+	start, depth := p.pos, 0
+	for ; p.pos < len(p.toks); p.pos++ {
+		switch t := p.toks[p.pos]; {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case t.IsPunct(",") && depth == 0:
+			items = append(items, NewScriptParser(p.toks[start:p.pos]))
+			start = p.pos + 1
+		}
+	}
+	if start < len(p.toks) {
+		items = append(items, NewScriptParser(p.toks[start:]))
+	}
+	return items
+}
+
+// Rest returns the tokens that haven't been consumed yet.
+func (p *ScriptParser) Rest() []ScriptToken {
+	return p.toks[p.pos:]
+}
+
+// scriptObjectKinds lists the kinds of objects created, altered or dropped
+// by the statements of a SQL script.
+var scriptObjectKinds = map[string]bool{
+	"TABLE": true, "INDEX": true, "VIEW": true, "PROCEDURE": true, "PROC": true, "FUNCTION": true,
+	"TRIGGER": true, "SEQUENCE": true, "PACKAGE": true, "TYPE": true, "SYNONYM": true, "SCHEMA": true,
+	"USER": true, "ROLE": true, "DATABASE": true, "SESSION": true, "SYSTEM": true, "LIBRARY": true,
+	"JAVA": true, "DIRECTORY": true, "CONTEXT": true, "CLUSTER": true, "TABLESPACE": true, "LOGIN": true,
+	"STATISTICS": true, "DEFAULT": true, "PARTITION": true, "FULLTEXT": true, "XML": true, "ASSEMBLY": true,
+}
+
+// ScriptStatementType returns the type of a SQL statement for the statement
+// stats of the conversion report, e.g. "CREATE TABLE" for
+// 'CREATE GLOBAL TEMPORARY TABLE ...' or "SET" for 'SET ANSI_NULLS ON'.
+func ScriptStatementType(toks []ScriptToken) string {
+	if len(toks) == 0 || toks[0].Kind != WordToken {
+		return "UNKNOWN"
+	}
+	verb := strings.ToUpper(toks[0].Text)
+	switch verb {
+	case "CREATE", "ALTER", "DROP":
+		// Skip modifiers like OR REPLACE, UNIQUE or NONCLUSTERED.
+		for i := 1; i < len(toks) && i < 6 && toks[i].Kind == WordToken; i++ {
+			if kind := strings.ToUpper(toks[i].Text); scriptObjectKinds[kind] {
+				if kind == "PROC" {
+					kind = "PROCEDURE"
+				}
+				return verb + " " + kind
+			}
+		}
+	}
+	return verb
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizeScript(t *testing.T) {
+	toks, err := TokenizeScript(`CREATE TABLE [dbo].[t] ( "a""b" int DEFAULT N'it''s', -- comment
+		c NUMBER(8,2) /* multi
+		line */ CHECK (c > -1.5e3) );`, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []ScriptToken{
+		{WordToken, "CREATE"}, {WordToken, "TABLE"}, {QuotedIdentToken, "dbo"}, {PunctToken, "."}, {QuotedIdentToken, "t"},
+		{PunctToken, "("}, {QuotedIdentToken, `a"b`}, {WordToken, "int"}, {WordToken, "DEFAULT"}, {StringToken, "it's"}, {PunctToken, ","},
+		{WordToken, "c"}, {WordToken, "NUMBER"}, {PunctToken, "("}, {NumberToken, "8"}, {PunctToken, ","}, {NumberToken, "2"}, {PunctToken, ")"},
+		{WordToken, "CHECK"}, {PunctToken, "("}, {WordToken, "c"}, {PunctToken, ">"}, {PunctToken, "-"}, {NumberToken, "1.5e3"}, {PunctToken, ")"},
+		{PunctToken, ")"}, {PunctToken, ";"},
+	}, toks)
+
+	for _, stmt := range []string{"SELECT 'abc", `SELECT "abc`, "SELECT 1 /* abc"} {
+		_, err := TokenizeScript(stmt, false)
+		assert.NotNil(t, err, stmt)
+	}
+	// Brackets only quote identifiers in T-SQL.
+	toks, err = TokenizeScript("[a]", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(toks))
+}
+
+func TestScriptStatementType(t *testing.T) {
+	for stmt, want := range map[string]string{
+		"CREATE GLOBAL TEMPORARY TABLE t (a int)":      "CREATE TABLE",
+		"create unique nonclustered index i on t (a)":  "CREATE INDEX",
+		"CREATE OR REPLACE EDITIONABLE PROCEDURE p IS": "CREATE PROCEDURE",
+		"CREATE PROC p AS SELECT 1":                    "CREATE PROCEDURE",
+		"ALTER SESSION SET EVENTS 'x'":                 "ALTER SESSION",
+		"SET ANSI_NULLS ON":                            "SET",
+		"(SELECT 1)":                                   "UNKNOWN",
+	} {
+		toks, err := TokenizeScript(stmt, true)
+		assert.Nil(t, err)
+		assert.Equal(t, want, ScriptStatementType(toks), stmt)
+	}
+}
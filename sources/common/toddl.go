@@ -91,7 +91,7 @@ func (ss *SchemaToSpannerImpl) SchemaToSpannerDDL(conv *internal.Conv, toddl ToD
 		conv.AddShardIdColumn()
 	}
 
-	if conv.Source == constants.MYSQL && conv.SpProjectId != "" && conv.SpInstanceId != "" {
+	if conv.Source == constants.MYSQL && ss.canVerifyExpressions(conv) {
 		// Process and verify Spanner DDL expressions for MYSQL
 		expressionDetails := ss.DdlV.GetSourceExpressionDetails(conv, tableIds)
 		expressions, err := ss.DdlV.VerifySpannerDDL(conv, expressionDetails)
@@ -101,7 +101,7 @@ func (ss *SchemaToSpannerImpl) SchemaToSpannerDDL(conv *internal.Conv, toddl ToD
 		spannerSchemaApplyExpressions(conv, expressions)
 	}
 
-	if (conv.Source == constants.MYSQL || conv.Source == constants.MYSQLDUMP) && conv.SpProjectId != "" && conv.SpInstanceId != "" {
+	if (conv.Source == constants.MYSQL || conv.Source == constants.MYSQLDUMP) && ss.canVerifyExpressions(conv) {
 		// Process and verify Check constraints for MySQL and MySQLDump flow only
 		err := ss.VerifyExpressions(conv)
 		if err != nil {
@@ -109,10 +109,25 @@ func (ss *SchemaToSpannerImpl) SchemaToSpannerDDL(conv *internal.Conv, toddl ToD
 		}
 	}
 
+	if ss.canVerifyExpressions(conv) {
+		if err := ss.verifyGeneratedColumns(conv); err != nil {
+			return err
+		}
+	}
+
 	internal.ResolveRefs(conv)
 	return nil
 }
 
+// canVerifyExpressions reports whether the expressions of the schema can be
+// verified, which needs a Spanner instance unless they are verified offline.
+func (ss *SchemaToSpannerImpl) canVerifyExpressions(conv *internal.Conv) bool {
+	if _, ok := ss.ExpressionVerificationAccessor.(*expressions_api.LocalExpressionVerificationAccessorImpl); ok {
+		return true
+	}
+	return conv.SpProjectId != "" && conv.SpInstanceId != ""
+}
+
 // GenerateExpressionDetailList it will generate the expression detail list which is used in verify expression method as a input
 func GenerateExpressionDetailList(spschema ddl.Schema) []internal.ExpressionDetail {
 	expressionDetailList := []internal.ExpressionDetail{}
@@ -306,6 +321,57 @@ func (ss *SchemaToSpannerImpl) VerifyExpressions(conv *internal.Conv) error {
 	return nil
 }
 
+// verifyGeneratedColumns uses expression_api to verify the expressions of the
+// generated columns of the Spanner schema. Columns whose expression is not
+// valid in Spanner are converted to plain columns holding the source values.
+func (ss *SchemaToSpannerImpl) verifyGeneratedColumns(conv *internal.Conv) error {
+	var expressionDetailList []internal.ExpressionDetail
+	for _, sp := range conv.SpSchema {
+		for _, colId := range sp.ColIds {
+			col := sp.ColDefs[colId]
+			if !col.Generated.IsPresent {
+				continue
+			}
+			expressionDetailList = append(expressionDetailList, internal.ExpressionDetail{
+				Expression:       col.Generated.Value.Statement,
+				Type:             constants.GENERATED_EXPRESSION,
+				ReferenceElement: internal.ReferenceElement{Name: sp.Name},
+				ExpressionId:     col.Generated.Value.ExpressionId,
+				Metadata:         map[string]string{"tableId": sp.Id, "colId": colId},
+			})
+		}
+	}
+	if len(expressionDetailList) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	ss.ExpressionVerificationAccessor.RefreshSpannerClient(ctx, conv.SpProjectId, conv.SpInstanceId)
+	result := ss.ExpressionVerificationAccessor.VerifyExpressions(ctx, internal.VerifyExpressionsInput{
+		Conv:                 conv,
+		Source:               conv.Source,
+		ExpressionDetailList: expressionDetailList,
+	})
+	if result.ExpressionVerificationOutputList == nil {
+		return result.Err
+	}
+	for _, ev := range result.ExpressionVerificationOutputList {
+		if ev.Result {
+			continue
+		}
+		tableId, colId := ev.ExpressionDetail.Metadata["tableId"], ev.ExpressionDetail.Metadata["colId"]
+		col := conv.SpSchema[tableId].ColDefs[colId]
+		col.Generated = ddl.GeneratedColumn{}
+		conv.SpSchema[tableId].ColDefs[colId] = col
+		tableIssues := conv.SchemaIssues[tableId]
+		if tableIssues.ColumnLevelIssues == nil {
+			tableIssues.ColumnLevelIssues = map[string][]internal.SchemaIssue{}
+		}
+		tableIssues.ColumnLevelIssues[colId] = append(tableIssues.ColumnLevelIssues[colId], internal.GeneratedColumn)
+		conv.SchemaIssues[tableId] = tableIssues
+	}
+	return nil
+}
+
 // IsSchemaIssuePresent checks if issue is present in the given schemaissue list.
 func IsSchemaIssuePresent(schemaissue []internal.SchemaIssue, issue internal.SchemaIssue) bool {
 
@@ -400,6 +466,24 @@ func (ss *SchemaToSpannerImpl) SchemaToSpannerDDLHelper(conv *internal.Conv, tod
 			totalNonKeyColumnSize += getColumnSize(ty.Name, ty.Len)
 		}
 	}
+	// Generated columns can reference any column of the table, their
+	// expression is converted once all columns are mapped.
+	for _, colId := range spColIds {
+		srcGenerated := srcTable.ColDefs[colId].Generated
+		if !srcGenerated.IsPresent {
+			continue
+		}
+		colDef := spColDef[colId]
+		colDef.Generated = ddl.GeneratedColumn{
+			IsPresent: true,
+			Value: ddl.Expression{
+				ExpressionId: srcGenerated.Value.ExpressionId,
+				Statement:    cvtGeneratedExpression(conv, srcTable, spColDef, srcGenerated.Value.Statement),
+			},
+			Stored: srcGenerated.Stored,
+		}
+		spColDef[colId] = colDef
+	}
 	if totalNonKeyColumnSize > ddl.MaxNonKeyColumnLength {
 		tableLevelIssues = append(tableLevelIssues, internal.RowLimitExceeded)
 	}
@@ -413,6 +497,7 @@ func (ss *SchemaToSpannerImpl) SchemaToSpannerDDLHelper(conv *internal.Conv, tod
 		ColIds:           spColIds,
 		ColDefs:          spColDef,
 		PrimaryKeys:      cvtPrimaryKeys(srcTable.PrimaryKeys),
+		ParentTable:      cvtNestedParent(srcTable),
 		ForeignKeys:      cvtForeignKeys(conv, spTableName, srcTable.Id, srcTable.ForeignKeys, isRestore),
 		CheckConstraints: cvtCheckConstraint(conv, srcTable.CheckConstraints),
 		Indexes:          cvtIndexes(conv, srcTable.Id, srcTable.Indexes, spColIds, spColDef),
@@ -422,6 +507,16 @@ func (ss *SchemaToSpannerImpl) SchemaToSpannerDDLHelper(conv *internal.Conv, tod
 	return nil
 }
 
+// cvtNestedParent interleaves tables normalized from nested lists in the
+// table of the documents the lists are nested in, so that the elements of a
+// list are deleted with its document.
+func cvtNestedParent(srcTable schema.Table) ddl.InterleavedParent {
+	if srcTable.Nested == nil {
+		return ddl.InterleavedParent{}
+	}
+	return ddl.InterleavedParent{Id: srcTable.Nested.ParentId, OnDelete: constants.FK_CASCADE}
+}
+
 func (ss *SchemaToSpannerImpl) SchemaToSpannerSequenceHelper(conv *internal.Conv, srcSequence ddl.Sequence) error {
 	switch srcSequence.SequenceKind {
 	case constants.AUTO_INCREMENT:
@@ -493,6 +588,116 @@ func cvtCheckConstraint(conv *internal.Conv, srcKeys []schema.CheckConstraint) [
 	return spcc
 }
 
+// cvtGeneratedExpression converts the expression of a source generated
+// column to Spanner by replacing the references to the columns of srcTable
+// with their quoted Spanner names. Literals, function names and other
+// identifiers are kept as is, expressions that aren't valid in Spanner are
+// caught by verifyGeneratedColumns.
+func cvtGeneratedExpression(conv *internal.Conv, srcTable schema.Table, spColDef map[string]ddl.ColumnDef, expr string) string {
+	quote := func(name string) string {
+		if conv.SpDialect == constants.DIALECT_POSTGRESQL {
+			return `"` + name + `"`
+		}
+		return "`" + name + "`"
+	}
+	// column returns the quoted Spanner name of a source column, preferring
+	// an exact match of the name.
+	column := func(name string) (string, bool) {
+		var found string
+		for _, colId := range srcTable.ColIds {
+			spCol, ok := spColDef[colId]
+			if !ok {
+				continue
+			}
+			if srcTable.ColDefs[colId].Name == name {
+				return quote(spCol.Name), true
+			}
+			if found == "" && strings.EqualFold(srcTable.ColDefs[colId].Name, name) {
+				found = quote(spCol.Name)
+			}
+		}
+		return found, found != ""
+	}
+	isIdentRune := func(r rune) bool {
+		return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	// MySQL strings may be double quoted and use backslash escapes, double
+	// quotes delimit identifiers in other databases.
+	mysqlStrings := conv.Source == constants.MYSQL || conv.Source == constants.MYSQLDUMP
+	rs := []rune(expr)
+	var sb strings.Builder
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case r == '\'' || (r == '"' && mysqlStrings):
+			j := i + 1
+			for j < len(rs) {
+				if rs[j] == '\\' && mysqlStrings && j+1 < len(rs) {
+					j += 2
+					continue
+				}
+				if rs[j] == r {
+					if j+1 < len(rs) && rs[j+1] == r {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(rs))
+			sb.WriteString(string(rs[i:j]))
+			i = j
+		case r == '`' || r == '"' || (r == '[' && conv.Source == constants.SQLSERVER):
+			end := r
+			if r == '[' {
+				end = ']'
+			}
+			j := i + 1
+			for j < len(rs) && rs[j] != end {
+				j++
+			}
+			name := string(rs[i+1 : j])
+			if spName, ok := column(name); ok {
+				sb.WriteString(spName)
+			} else {
+				sb.WriteString(quote(name))
+			}
+			i = min(j+1, len(rs))
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(rs) && (isIdentRune(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			sb.WriteString(string(rs[i:j]))
+			i = j
+		case isIdentRune(r):
+			j := i
+			for j < len(rs) && isIdentRune(rs[j]) {
+				j++
+			}
+			word := string(rs[i:j])
+			next := j
+			for next < len(rs) && unicode.IsSpace(rs[next]) {
+				next++
+			}
+			// Function names and qualified names are kept as is.
+			isFunction := next < len(rs) && rs[next] == '('
+			isQualified := (i > 0 && rs[i-1] == '.') || (j < len(rs) && rs[j] == '.')
+			if spName, ok := column(word); ok && !isFunction && !isQualified {
+				sb.WriteString(spName)
+			} else {
+				sb.WriteString(word)
+			}
+			i = j
+		default:
+			sb.WriteRune(r)
+			i++
+		}
+	}
+	return sb.String()
+}
+
 func CvtForeignKeysHelper(conv *internal.Conv, spTableName string, srcTableId string, srcKey schema.ForeignKey, isRestore bool) (ddl.Foreignkey, error) {
 	if len(srcKey.ColIds) != len(srcKey.ReferColumnIds) {
 		conv.Unexpected(fmt.Sprintf("ConvertForeignKeys: ColIds and referColumns don't have the same lengths: len(columns)=%d, len(referColumns)=%d for source tableId: %s, referenced table: %s", len(srcKey.ColIds), len(srcKey.ReferColumnIds), srcTableId, srcKey.ReferTableId))
@@ -17,6 +17,7 @@ package common
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -673,6 +674,22 @@ func TestVerifyCheckConstraintExpressions(t *testing.T) {
 			},
 			expectedResponse: true,
 		},
+		{
+			name: "UnverifiedFunction",
+			expressions: []ddl.CheckConstraint{
+				{Expr: "(col1 > 0)", ExprId: "expr1", Name: "check1"},
+				{Expr: "(foo(col1) > 18)", ExprId: "expr2", Name: "check2"},
+			},
+			expectedResults: []internal.ExpressionVerificationOutput{
+				{Result: true, Err: nil, ExpressionDetail: internal.ExpressionDetail{Expression: "(col1 > 0)", Type: "CHECK", Metadata: map[string]string{"tableId": "t1"}, ExpressionId: "expr1"}},
+				{Result: true, Err: fmt.Errorf("%w: function foo is not in the local catalog", expressions_api.ErrUnverified), ExpressionDetail: internal.ExpressionDetail{Expression: "(foo(col1) > 18)", Type: "CHECK", Metadata: map[string]string{"tableId": "t1"}, ExpressionId: "expr2"}},
+			},
+			expectedCheckConstraint: []ddl.CheckConstraint{
+				{Expr: "(col1 > 0)", ExprId: "expr1", Name: "check1"},
+				{Expr: "(foo(col1) > 18)", ExprId: "expr2", Name: "check2"},
+			},
+			expectedResponse: false,
+		},
 		{
 			name: "GenericError",
 			expressions: []ddl.CheckConstraint{
@@ -722,3 +739,78 @@ func TestVerifyCheckConstraintExpressions(t *testing.T) {
 		})
 	}
 }
+
+func Test_cvtGeneratedExpression(t *testing.T) {
+	srcTable := schema.Table{
+		Name:   "orders",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]schema.Column{
+			"c1": {Name: "price", Id: "c1"},
+			"c2": {Name: "Qty", Id: "c2"},
+			"c3": {Name: "order id", Id: "c3"},
+		},
+	}
+	spColDef := map[string]ddl.ColumnDef{
+		"c1": {Name: "price", Id: "c1"},
+		"c2": {Name: "Qty", Id: "c2"},
+		"c3": {Name: "order_id", Id: "c3"},
+	}
+	tests := []struct {
+		name      string
+		source    string
+		spDialect string
+		expr      string
+		want      string
+	}{
+		{"mysql columns", constants.MYSQL, constants.DIALECT_GOOGLESQL, "(`price` * `qty`)", "(`price` * `Qty`)"},
+		{"mysql strings and functions", constants.MYSQL, constants.DIALECT_GOOGLESQL, `concat(price, "qty", 'it\'s', qty)`, "concat(`price`, \"qty\", 'it\\'s', `Qty`)"},
+		{"renamed column", constants.MYSQL, constants.DIALECT_GOOGLESQL, "`order id` + 1.5e2", "`order_id` + 1.5e2"},
+		{"postgres columns", constants.POSTGRES, constants.DIALECT_POSTGRESQL, `(price * ("Qty")::numeric)`, `("price" * ("Qty")::numeric)`},
+		{"postgres qualified names", constants.POSTGRES, constants.DIALECT_GOOGLESQL, "price::public.price", "`price`::public.price"},
+		{"sqlserver brackets", constants.SQLSERVER, constants.DIALECT_GOOGLESQL, "([price]*[Qty])", "(`price`*`Qty`)"},
+		{"unknown identifier", constants.ORACLE, constants.DIALECT_GOOGLESQL, `"OTHER" + PRICE`, "`OTHER` + `price`"},
+	}
+	for _, tc := range tests {
+		conv := internal.MakeConv()
+		conv.Source = tc.source
+		conv.SpDialect = tc.spDialect
+		assert.Equal(t, tc.want, cvtGeneratedExpression(conv, srcTable, spColDef, tc.expr), tc.name)
+	}
+}
+
+func TestVerifyGeneratedColumns(t *testing.T) {
+	mockAccessor := new(mocks.MockExpressionVerificationAccessor)
+	handler := &SchemaToSpannerImpl{ExpressionVerificationAccessor: mockAccessor}
+	conv := internal.MakeConv()
+	generated := func(expr string) ddl.GeneratedColumn {
+		return ddl.GeneratedColumn{IsPresent: true, Value: ddl.Expression{ExpressionId: expr, Statement: expr}, Stored: true}
+	}
+	conv.SpSchema = map[string]ddl.CreateTable{
+		"t1": {
+			Name:   "table1",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2", "c3"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "col1", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+				"c2": {Name: "col2", Id: "c2", T: ddl.Type{Name: ddl.Int64}, Generated: generated("col1 + 1")},
+				"c3": {Name: "col3", Id: "c3", T: ddl.Type{Name: ddl.Int64}, Generated: generated("unknown(col1)")},
+			},
+		},
+	}
+	conv.SchemaIssues = map[string]internal.TableIssues{"t1": {ColumnLevelIssues: map[string][]internal.SchemaIssue{}}}
+	ctx := context.Background()
+	mockAccessor.On("RefreshSpannerClient", ctx, mock.Anything, mock.Anything).Return(nil)
+	mockAccessor.On("VerifyExpressions", ctx, mock.MatchedBy(func(input internal.VerifyExpressionsInput) bool {
+		return len(input.ExpressionDetailList) == 2 && input.ExpressionDetailList[0].Type == constants.GENERATED_EXPRESSION
+	})).Return(internal.VerifyExpressionsOutput{
+		ExpressionVerificationOutputList: []internal.ExpressionVerificationOutput{
+			{Result: true, ExpressionDetail: internal.ExpressionDetail{Expression: "col1 + 1", Metadata: map[string]string{"tableId": "t1", "colId": "c2"}}},
+			{Result: false, Err: errors.New("Function not found: unknown"), ExpressionDetail: internal.ExpressionDetail{Expression: "unknown(col1)", Metadata: map[string]string{"tableId": "t1", "colId": "c3"}}},
+		},
+	})
+	assert.Nil(t, handler.verifyGeneratedColumns(conv))
+	assert.Equal(t, generated("col1 + 1"), conv.SpSchema["t1"].ColDefs["c2"].Generated)
+	assert.Equal(t, ddl.GeneratedColumn{}, conv.SpSchema["t1"].ColDefs["c3"].Generated)
+	assert.Equal(t, []internal.SchemaIssue{internal.GeneratedColumn}, conv.SchemaIssues["t1"].ColumnLevelIssues["c3"])
+	assert.Nil(t, conv.SchemaIssues["t1"].ColumnLevelIssues["c2"])
+}
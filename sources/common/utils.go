@@ -16,7 +16,9 @@ package common
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
@@ -134,8 +136,39 @@ func GetCommonColumnIds(conv *internal.Conv, tableId string, colIds []string) []
 	return commonColIds
 }
 
+// NestedTableIds returns the ids of the tables nested in table tableId (see
+// schema.NestedList), at any depth, with parents before their children. The
+// rows of nested tables are read with the rows of the table they are nested
+// in.
+func NestedTableIds(conv *internal.Conv, tableId string) []string {
+	var ids []string
+	for _, t := range conv.SrcSchema {
+		if t.Nested != nil && t.Nested.ParentId == tableId {
+			ids = append(ids, t.Id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return conv.SrcSchema[ids[i]].Name < conv.SrcSchema[ids[j]].Name })
+	for _, id := range append([]string{}, ids...) {
+		ids = append(ids, NestedTableIds(conv, id)...)
+	}
+	return ids
+}
+
+// WritableColumnIds returns the ids of colIds that can be written to Spanner
+// table tableId, i.e. all but the generated columns, whose values are computed
+// by Spanner.
+func WritableColumnIds(conv *internal.Conv, tableId string, colIds []string) []string {
+	var writableColIds []string
+	for _, colId := range colIds {
+		if !conv.SpSchema[tableId].ColDefs[colId].Generated.IsPresent {
+			writableColIds = append(writableColIds, colId)
+		}
+	}
+	return writableColIds
+}
+
 func PrepareColumns(conv *internal.Conv, tableId string, srcCols []string) ([]string, error) {
-	spColIds := conv.SpSchema[tableId].ColIds
+	spColIds := WritableColumnIds(conv, tableId, conv.SpSchema[tableId].ColIds)
 	srcColIds := []string{}
 	for _, colName := range srcCols {
 		colId, err := internal.GetColIdFromSrcName(conv.SrcSchema[tableId].ColDefs, colName)
@@ -268,3 +301,35 @@ func findSchemaIssue(schemaissue []internal.SchemaIssue, issue internal.SchemaIs
 	}
 	return ind
 }
+
+var identifierRegexp = regexp.MustCompile(`[\pL_][\pL\pN_$#]*`)
+
+// SetReferencedTables sets the tables referenced by each of conv.SrcObjects
+// to the source tables whose name appears as an identifier in its body.
+// Names are matched case-insensitively, and schema-qualified names match on
+// the table name only, so the list may contain false positives e.g. for a
+// column named like a table.
+func SetReferencedTables(conv *internal.Conv) {
+	names := map[string][]string{}
+	for _, t := range conv.SrcSchema {
+		name := t.Name
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		names[strings.ToLower(name)] = append(names[strings.ToLower(name)], t.Name)
+	}
+	for i, o := range conv.SrcObjects {
+		found := map[string]bool{}
+		for _, id := range identifierRegexp.FindAllString(o.Body, -1) {
+			for _, t := range names[strings.ToLower(id)] {
+				found[t] = true
+			}
+		}
+		var tables []string
+		for t := range found {
+			tables = append(tables, t)
+		}
+		sort.Strings(tables)
+		conv.SrcObjects[i].ReferencedTables = tables
+	}
+}
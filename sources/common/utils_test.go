@@ -183,6 +183,34 @@ func TestPrepareColumns(t *testing.T) {
 			srcCols:        []string{"a", "b"},
 			expectedColIds: []string{"c1"},
 		},
+		{
+			name: "when a column is generated on spanner table",
+			conv: &internal.Conv{
+				SpSchema: map[string]ddl.CreateTable{
+					"t1": {
+						Name:   "t1",
+						ColIds: []string{"c1", "c2"},
+						ColDefs: map[string]ddl.ColumnDef{
+							"c1": {Name: "a", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+							"c2": {Name: "b", Id: "c2", T: ddl.Type{Name: ddl.Int64}, Generated: ddl.GeneratedColumn{IsPresent: true, Value: ddl.Expression{Statement: "`a` + 1"}, Stored: true}},
+						},
+						PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+					}},
+				SrcSchema: map[string]schema.Table{
+					"t1": {
+						Name:   "t1",
+						ColIds: []string{"c1", "c2"},
+						ColDefs: map[string]schema.Column{
+							"c1": {Name: "a", Id: "c1", Type: schema.Type{Name: "bigint", Mods: []int64{}}},
+							"c2": {Name: "b", Id: "c2", Type: schema.Type{Name: "bigint", Mods: []int64{}}},
+						},
+						PrimaryKeys: []schema.Key{{ColId: "c1"}},
+					}},
+			},
+			tableId:        "t1",
+			srcCols:        []string{"a", "b"},
+			expectedColIds: []string{"c1"},
+		},
 	}
 	for _, tc := range tc {
 		res, err := PrepareColumns(tc.conv, tc.tableId, tc.srcCols)
@@ -85,9 +85,34 @@ func loadManifest(conv *internal.Conv, manifestFile string) ([]utils.ManifestTab
 	if err != nil {
 		return nil, fmt.Errorf("manifest is incomplete: %v", err)
 	}
+	err = SetRowFilters(conv, tables)
+	if err != nil {
+		return nil, err
+	}
 	return tables, nil
 }
 
+// SetRowFilters sets the row filters of the tables of the manifest, which are
+// evaluated on each row of their CSV files.
+func SetRowFilters(conv *internal.Conv, tables []utils.ManifestTable) error {
+	for _, table := range tables {
+		if table.Row_filter == "" {
+			continue
+		}
+		tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, table.Table_name)
+		if err != nil {
+			return err
+		}
+		srcTable := conv.SrcSchema[tableId]
+		srcTable.RowFilter = table.Row_filter
+		conv.SrcSchema[tableId] = srcTable
+	}
+	if err := conv.ValidateRowFilters(); err != nil {
+		return fmt.Errorf("invalid row filter in manifest: %v", err)
+	}
+	return nil
+}
+
 // VerifyManifest performs certain prechecks on the structure of the manifest while populating the conv with
 // the ddl types. Also checks on valid file paths and empty CSVs are handled as conv.Unexpected errors later during processing.
 func VerifyManifest(conv *internal.Conv, tables []utils.ManifestTable) error {
@@ -200,7 +225,7 @@ func (c *CsvImpl) ProcessCSV(conv *internal.Conv, tables []utils.ManifestTable,
 	}
 	orderedTables := []utils.ManifestTable{}
 	for _, id := range tableIds {
-		orderedTables = append(orderedTables, utils.ManifestTable{conv.SpSchema[id].Name, nameToFiles[conv.SpSchema[id].Name]})
+		orderedTables = append(orderedTables, utils.ManifestTable{Table_name: conv.SpSchema[id].Name, File_patterns: nameToFiles[conv.SpSchema[id].Name]})
 	}
 
 	for _, table := range orderedTables {
@@ -264,9 +289,9 @@ func processDataRow(conv *internal.Conv, nullStr, tableName string, srcCols []st
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(tableName, conv.DataMode())
-		conv.CollectBadRow(tableName, srcCols, values)
+		conv.CollectBadRow(tableName, srcCols, values, err)
 	} else {
-		conv.WriteRow(tableName, tableName, cvtCols, cvtVals)
+		conv.WriteRowWithSource(tableName, srcCols, values, tableName, cvtCols, cvtVals)
 	}
 }
 
@@ -0,0 +1,297 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datafile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/linkedin/goavro/v2"
+)
+
+// avroColumn is a field of the top-level record of an Avro file.
+type avroColumn struct {
+	fileColumn
+	// nullable is set for fields of union type ["null", T], whose values
+	// are wrapped in a map keyed by the name of T.
+	nullable bool
+	// nullableItems is set for arrays of such unions.
+	nullableItems bool
+}
+
+// avroReader reads the records of an Avro object container file.
+type avroReader struct {
+	file    *os.File
+	ocfr    *goavro.OCFReader
+	cols    []avroColumn
+	skipped []string
+}
+
+func newAvroReader(path string) (*avroReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	ocfr, err := goavro.NewOCFReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("can't read avro header: %v", err)
+	}
+	r := &avroReader{file: f, ocfr: ocfr}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(ocfr.Codec().Schema()), &record); err != nil || record["type"] != "record" {
+		f.Close()
+		return nil, fmt.Errorf("avro schema must be a record")
+	}
+	named := map[string]string{}
+	fields, _ := record["fields"].([]interface{})
+	for _, field := range fields {
+		m, _ := field.(map[string]interface{})
+		name, _ := m["name"].(string)
+		col, ok := avroFieldColumn(name, m["type"], named)
+		if !ok {
+			r.skipped = append(r.skipped, name)
+			continue
+		}
+		r.cols = append(r.cols, col)
+	}
+	return r, nil
+}
+
+// avroFieldColumn maps the Avro type of a field to a column. Named types
+// defined by the field are recorded in named, so that later fields can
+// refer to them.
+func avroFieldColumn(name string, t interface{}, named map[string]string) (avroColumn, bool) {
+	col := avroColumn{fileColumn: fileColumn{Name: name, NotNull: true}}
+	if u, ok := t.([]interface{}); ok {
+		// Only unions of null and a single type are supported.
+		branch, ok := nullableBranch(u)
+		if !ok {
+			return col, false
+		}
+		t = branch
+		col.NotNull = false
+		col.nullable = true
+	}
+	if m, ok := t.(map[string]interface{}); ok && m["type"] == "array" {
+		items := m["items"]
+		if u, ok := items.([]interface{}); ok {
+			branch, ok := nullableBranch(u)
+			if !ok {
+				return col, false
+			}
+			items = branch
+			col.nullableItems = true
+		}
+		ty := avroType(items, named)
+		if ty == typeJSON {
+			// Arrays of complex types are stored as JSON.
+			col.Type = schema.Type{Name: typeJSON}
+			return col, true
+		}
+		col.Type = schema.Type{Name: ty, ArrayBounds: []int64{-1}}
+		return col, true
+	}
+	col.Type = schema.Type{Name: avroType(t, named)}
+	if col.Type.Name == typeDecimal {
+		m := t.(map[string]interface{})
+		precision, _ := m["precision"].(float64)
+		scale, _ := m["scale"].(float64)
+		col.Type.Mods = []int64{int64(precision), int64(scale)}
+	}
+	return col, true
+}
+
+// nullableBranch returns the non-null branch of a union of null and a single
+// type.
+func nullableBranch(u []interface{}) (interface{}, bool) {
+	var branches []interface{}
+	for _, b := range u {
+		if b != "null" {
+			branches = append(branches, b)
+		}
+	}
+	if len(branches) != 1 {
+		return nil, false
+	}
+	return branches[0], true
+}
+
+// avroType maps an Avro type to a source type. Records, maps and nested
+// arrays map to JSON.
+func avroType(t interface{}, named map[string]string) string {
+	switch x := t.(type) {
+	case string:
+		switch x {
+		case "boolean":
+			return typeBool
+		case "int":
+			return typeInt32
+		case "long":
+			return typeInt64
+		case "float":
+			return typeFloat
+		case "double":
+			return typeDouble
+		case "bytes":
+			return typeBytes
+		case "string":
+			return typeString
+		}
+		if ty, ok := named[x]; ok {
+			return ty
+		}
+		return typeJSON
+	case map[string]interface{}:
+		base, _ := x["type"].(string)
+		var ty string
+		switch base {
+		case "enum":
+			ty = typeString
+		case "fixed":
+			ty = typeBytes
+		case "record", "map", "array":
+			ty = typeJSON
+		default:
+			ty = avroType(base, named)
+		}
+		switch x["logicalType"] {
+		case "decimal":
+			if base == "bytes" || base == "fixed" {
+				ty = typeDecimal
+			}
+		case "date":
+			if base == "int" {
+				ty = typeDate
+			}
+		case "time-millis", "time-micros":
+			if base == "int" || base == "long" {
+				ty = typeTime
+			}
+		case "timestamp-millis", "timestamp-micros":
+			if base == "long" {
+				ty = typeTimestamp
+			}
+		}
+		if name, ok := x["name"].(string); ok && (base == "enum" || base == "fixed" || base == "record") {
+			named[name] = ty
+			if ns, ok := x["namespace"].(string); ok {
+				named[ns+"."+name] = ty
+			}
+		}
+		return ty
+	}
+	return typeJSON
+}
+
+func (r *avroReader) columns() []fileColumn {
+	var cols []fileColumn
+	for _, c := range r.cols {
+		cols = append(cols, c.fileColumn)
+	}
+	return cols
+}
+
+func (r *avroReader) unsupported() []string {
+	return r.skipped
+}
+
+// rowCount returns the number of records of the file from the counts of its
+// blocks, without decoding them.
+func (r *avroReader) rowCount() (int64, error) {
+	var n int64
+	for r.ocfr.Scan() {
+		n += r.ocfr.RemainingBlockItems()
+		r.ocfr.SkipThisBlockAndReset()
+	}
+	return n, r.ocfr.Err()
+}
+
+func (r *avroReader) read() ([]interface{}, error) {
+	if !r.ocfr.Scan() {
+		if err := r.ocfr.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	datum, err := r.ocfr.Read()
+	if err != nil {
+		return nil, err
+	}
+	record, ok := datum.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a record, found %T", datum)
+	}
+	row := make([]interface{}, len(r.cols))
+	for i, c := range r.cols {
+		v := record[c.Name]
+		if c.nullable {
+			v = unwrapUnion(v)
+		}
+		if v == nil {
+			continue
+		}
+		if c.Type.Name == typeJSON {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("can't encode value of column %s as json: %v", c.Name, err)
+			}
+			row[i] = string(b)
+			continue
+		}
+		if a, ok := v.([]interface{}); ok {
+			items := make([]interface{}, len(a))
+			for j, item := range a {
+				if c.nullableItems {
+					item = unwrapUnion(item)
+				}
+				items[j] = decodeAvro(c.Type.Name, item)
+			}
+			row[i] = items
+			continue
+		}
+		row[i] = decodeAvro(c.Type.Name, v)
+	}
+	return row, nil
+}
+
+// unwrapUnion returns the value of a union, which goavro wraps in a map keyed
+// by the name of its type unless it is null.
+func unwrapUnion(v interface{}) interface{} {
+	if m, ok := v.(map[string]interface{}); ok && len(m) == 1 {
+		for _, x := range m {
+			return x
+		}
+	}
+	return v
+}
+
+// decodeAvro converts the values goavro decodes for logical types to the Go
+// values of the source type.
+func decodeAvro(ty string, v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok && ty == typeDate {
+		return civil.DateOf(t)
+	}
+	return v
+}
+
+func (r *avroReader) close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,260 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datafile
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// convValue converts a value decoded from a file to a value of the Spanner
+// type of its column. Values are converted directly from their Go type: only
+// STRING and JSON columns format values of other types as strings.
+func convValue(conv *internal.Conv, spannerType ddl.Type, v interface{}) (interface{}, error) {
+	if spannerType.IsArray {
+		a, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("can't convert %T to array", v)
+		}
+		return convArray(conv, spannerType, a)
+	}
+	return convScalar(conv, spannerType, v)
+}
+
+func convScalar(conv *internal.Conv, spannerType ddl.Type, v interface{}) (interface{}, error) {
+	switch spannerType.Name {
+	case ddl.Bool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case ddl.Int64:
+		switch x := v.(type) {
+		case int32:
+			return int64(x), nil
+		case int64:
+			return x, nil
+		}
+	case ddl.Float32:
+		switch x := v.(type) {
+		case float32:
+			return x, nil
+		case int32:
+			return float32(x), nil
+		}
+	case ddl.Float64:
+		switch x := v.(type) {
+		case float32:
+			return float64(x), nil
+		case float64:
+			return x, nil
+		case int32:
+			return float64(x), nil
+		case int64:
+			return float64(x), nil
+		}
+	case ddl.Numeric:
+		r, err := convNumeric(v)
+		if err != nil {
+			return nil, err
+		}
+		if conv.SpDialect == constants.DIALECT_POSTGRESQL {
+			return spanner.PGNumeric{Numeric: spanner.NumericString(r), Valid: true}, nil
+		}
+		return *r, nil
+	case ddl.String:
+		return convString(v)
+	case ddl.JSON:
+		if s, ok := v.(string); ok {
+			if !json.Valid([]byte(s)) {
+				return nil, fmt.Errorf("invalid json value %q", s)
+			}
+			return s, nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert to json: %v", err)
+		}
+		return string(b), nil
+	case ddl.Bytes:
+		switch x := v.(type) {
+		case []byte:
+			return x, nil
+		case string:
+			return []byte(x), nil
+		}
+	case ddl.Date:
+		switch x := v.(type) {
+		case civil.Date:
+			return x, nil
+		case time.Time:
+			return civil.DateOf(x), nil
+		}
+	case ddl.Timestamp:
+		switch x := v.(type) {
+		case time.Time:
+			return x, nil
+		case civil.Date:
+			return x.In(time.UTC), nil
+		}
+	default:
+		return nil, fmt.Errorf("data conversion not implemented for type %v", spannerType.Name)
+	}
+	return nil, fmt.Errorf("can't convert %T to %s", v, spannerType.Name)
+}
+
+// convNumeric converts integers and decimals to a big.Rat. Floats are
+// converted exactly, except for NaN and infinities which Spanner doesn't
+// support.
+func convNumeric(v interface{}) (*big.Rat, error) {
+	switch x := v.(type) {
+	case *big.Rat:
+		return x, nil
+	case int32:
+		return new(big.Rat).SetInt64(int64(x)), nil
+	case int64:
+		return new(big.Rat).SetInt64(x), nil
+	case float32:
+		return convNumeric(float64(x))
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("can't convert %v to numeric", x)
+		}
+		return new(big.Rat).SetFloat64(x), nil
+	}
+	return nil, fmt.Errorf("can't convert %T to %s", v, ddl.Numeric)
+}
+
+// convString formats values of any type as strings, e.g. for columns mapped
+// to STRING by rules.
+func convString(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case *big.Rat:
+		return x.RatString(), nil
+	case time.Time:
+		return x.Format(time.RFC3339Nano), nil
+	case time.Duration:
+		// Times of day.
+		return civil.TimeOf(time.Unix(0, 0).UTC().Add(x)).String(), nil
+	case []interface{}, map[string]interface{}:
+		b, err := json.Marshal(x)
+		return string(b), err
+	}
+	return fmt.Sprint(v), nil
+}
+
+// convArray converts the elements of an array. The Spanner client for go
+// does not accept []interface{} for arrays, so the elements are converted to
+// slices of a specific type.
+func convArray(conv *internal.Conv, spannerType ddl.Type, a []interface{}) (interface{}, error) {
+	elemType := ddl.Type{Name: spannerType.Name, Len: spannerType.Len}
+	elems := make([]interface{}, len(a))
+	for i, v := range a {
+		if v == nil {
+			continue
+		}
+		x, err := convScalar(conv, elemType, v)
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = x
+	}
+	switch spannerType.Name {
+	case ddl.Bool:
+		r := []spanner.NullBool{}
+		for _, e := range elems {
+			b, ok := e.(bool)
+			r = append(r, spanner.NullBool{Bool: b, Valid: ok})
+		}
+		return r, nil
+	case ddl.Int64:
+		r := []spanner.NullInt64{}
+		for _, e := range elems {
+			i, ok := e.(int64)
+			r = append(r, spanner.NullInt64{Int64: i, Valid: ok})
+		}
+		return r, nil
+	case ddl.Float32:
+		r := []spanner.NullFloat32{}
+		for _, e := range elems {
+			f, ok := e.(float32)
+			r = append(r, spanner.NullFloat32{Float32: f, Valid: ok})
+		}
+		return r, nil
+	case ddl.Float64:
+		r := []spanner.NullFloat64{}
+		for _, e := range elems {
+			f, ok := e.(float64)
+			r = append(r, spanner.NullFloat64{Float64: f, Valid: ok})
+		}
+		return r, nil
+	case ddl.Numeric:
+		if conv.SpDialect == constants.DIALECT_POSTGRESQL {
+			r := []spanner.PGNumeric{}
+			for _, e := range elems {
+				n, ok := e.(spanner.PGNumeric)
+				r = append(r, spanner.PGNumeric{Numeric: n.Numeric, Valid: ok})
+			}
+			return r, nil
+		}
+		r := []spanner.NullNumeric{}
+		for _, e := range elems {
+			n, ok := e.(big.Rat)
+			r = append(r, spanner.NullNumeric{Numeric: n, Valid: ok})
+		}
+		return r, nil
+	case ddl.String:
+		r := []spanner.NullString{}
+		for _, e := range elems {
+			s, ok := e.(string)
+			r = append(r, spanner.NullString{StringVal: s, Valid: ok})
+		}
+		return r, nil
+	case ddl.Bytes:
+		r := [][]byte{}
+		for _, e := range elems {
+			b, _ := e.([]byte)
+			r = append(r, b)
+		}
+		return r, nil
+	case ddl.Date:
+		r := []spanner.NullDate{}
+		for _, e := range elems {
+			d, ok := e.(civil.Date)
+			r = append(r, spanner.NullDate{Date: d, Valid: ok})
+		}
+		return r, nil
+	case ddl.Timestamp:
+		r := []spanner.NullTime{}
+		for _, e := range elems {
+			t, ok := e.(time.Time)
+			r = append(r, spanner.NullTime{Time: t, Valid: ok})
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("array type conversion not implemented for type []%v", spannerType.Name)
+}
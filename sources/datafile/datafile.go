@@ -0,0 +1,312 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package datafile handles schema and data migrations from Parquet and Avro
+// files. The files of each table are listed in a manifest with the same
+// format as for CSV files.
+package datafile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/utils"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/csv"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// Source types of the columns of Parquet and Avro files. Both formats map
+// their physical and logical types to these types.
+const (
+	typeBool      = "BOOL"
+	typeInt32     = "INT32"
+	typeInt64     = "INT64"
+	typeFloat     = "FLOAT"
+	typeDouble    = "DOUBLE"
+	typeDecimal   = "DECIMAL"
+	typeString    = "STRING"
+	typeBytes     = "BYTES"
+	typeDate      = "DATE"
+	typeTime      = "TIME"
+	typeTimestamp = "TIMESTAMP"
+	typeJSON      = "JSON"
+)
+
+// fileColumn is a column of a Parquet or Avro file.
+type fileColumn struct {
+	Name    string
+	Type    schema.Type
+	NotNull bool
+}
+
+// fileReader reads the rows of a Parquet or Avro file. Values are decoded
+// into Go values: bool, int32, int64, float32, float64, *big.Rat for
+// decimals, string, []byte, civil.Date, time.Duration for times, time.Time
+// and []interface{} for arrays. Null values are nil.
+type fileReader interface {
+	// columns returns the supported columns of the file.
+	columns() []fileColumn
+	// unsupported returns the names of the columns that are skipped.
+	unsupported() []string
+	// rowCount returns the number of rows of the file, without reading them.
+	rowCount() (int64, error)
+	// read returns the values of the next row, in the order of columns, or
+	// io.EOF at the end of the file.
+	read() ([]interface{}, error)
+	close() error
+}
+
+func openFile(format, path string) (fileReader, error) {
+	switch format {
+	case constants.PARQUET:
+		return newParquetReader(path)
+	case constants.AVRO:
+		return newAvroReader(path)
+	}
+	return nil, fmt.Errorf("unsupported file format %s", format)
+}
+
+type DataFileInterface interface {
+	GetFiles(conv *internal.Conv, sourceProfile profiles.SourceProfile) (tables []utils.ManifestTable, err error)
+	SchemaFromFiles(conv *internal.Conv, tables []utils.ManifestTable) error
+	SetRowStats(conv *internal.Conv, tables []utils.ManifestTable) error
+	ProcessFiles(conv *internal.Conv, tables []utils.ManifestTable) error
+}
+
+// DataFileImpl reads files of Format, which is either constants.PARQUET or
+// constants.AVRO.
+type DataFileImpl struct {
+	Format string
+}
+
+// GetFiles finds the files of each table and downloads gcs files if any.
+// When conv already has a schema, the manifest is verified against it and
+// its row filters are set.
+func (d *DataFileImpl) GetFiles(conv *internal.Conv, sourceProfile profiles.SourceProfile) (tables []utils.ManifestTable, err error) {
+	if sourceProfile.DataFile.Manifest == "" {
+		if len(conv.SpSchema) == 0 {
+			return nil, fmt.Errorf("a manifest file is required to infer the schema from %s files", d.Format)
+		}
+		// Without a manifest, we assume the files exist in the current
+		// directory in table_name.<format> format.
+		fmt.Printf("Manifest file not provided, checking for files named `[table_name].%s` in current working directory...\n", d.Format)
+		for _, t := range conv.SpSchema {
+			tables = append(tables, utils.ManifestTable{Table_name: t.Name, File_patterns: []string{fmt.Sprintf("%s.%s", t.Name, d.Format)}})
+		}
+	} else {
+		manifest, err := os.ReadFile(sourceProfile.DataFile.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("can't read manifest file due to: %v", err)
+		}
+		if err := json.Unmarshal(manifest, &tables); err != nil {
+			return nil, fmt.Errorf("unable to unmarshall json due to: %v", err)
+		}
+		if len(tables) == 0 {
+			return nil, fmt.Errorf("manifest is incomplete: no tables found")
+		}
+	}
+	if len(conv.SrcSchema) > 0 {
+		if err := csv.VerifyManifest(conv, tables); err != nil {
+			return nil, fmt.Errorf("manifest is incomplete: %v", err)
+		}
+		if err := csv.SetRowFilters(conv, tables); err != nil {
+			return nil, err
+		}
+	}
+	tables, err = utils.PreloadGCSFiles(tables)
+	if err != nil {
+		return nil, fmt.Errorf("gcs file download error: %v", err)
+	}
+	return tables, nil
+}
+
+// SchemaFromFiles infers the source schema of conv from the schemas of the
+// files of each table. All the files of a table must have the same columns.
+// Files have no primary keys, so the tables get synthetic primary keys when
+// converted to Spanner.
+func (d *DataFileImpl) SchemaFromFiles(conv *internal.Conv, tables []utils.ManifestTable) error {
+	for _, table := range tables {
+		if table.Table_name == "" || len(table.File_patterns) == 0 {
+			return fmt.Errorf("manifest is incomplete: each table needs a name and at least one file")
+		}
+		if _, err := internal.GetTableIdFromSrcName(conv.SrcSchema, table.Table_name); err == nil {
+			return fmt.Errorf("table %s appears more than once in the manifest", table.Table_name)
+		}
+		var cols []fileColumn
+		for i, path := range table.File_patterns {
+			r, err := openFile(d.Format, path)
+			if err != nil {
+				return fmt.Errorf("can't read file %s for table %s: %v", path, table.Table_name, err)
+			}
+			fileCols := r.columns()
+			if i == 0 {
+				cols = fileCols
+				for _, name := range r.unsupported() {
+					conv.Unexpected(fmt.Sprintf("Skipped column %s of table %s: its %s type is not supported", name, table.Table_name, d.Format))
+				}
+			}
+			r.close()
+			if !reflect.DeepEqual(cols, fileCols) {
+				return fmt.Errorf("file %s of table %s has different columns than file %s", path, table.Table_name, table.File_patterns[0])
+			}
+		}
+		tbl := schema.Table{
+			Id:           internal.GenerateTableId(),
+			Name:         table.Table_name,
+			ColNameIdMap: map[string]string{},
+			ColDefs:      map[string]schema.Column{},
+		}
+		for _, c := range cols {
+			col := schema.Column{Id: internal.GenerateColumnId(), Name: c.Name, Type: c.Type, NotNull: c.NotNull}
+			tbl.ColIds = append(tbl.ColIds, col.Id)
+			tbl.ColDefs[col.Id] = col
+			tbl.ColNameIdMap[col.Name] = col.Id
+		}
+		conv.SrcSchema[tbl.Id] = tbl
+	}
+	return nil
+}
+
+// SetRowStats calculates the number of rows per table.
+func (d *DataFileImpl) SetRowStats(conv *internal.Conv, tables []utils.ManifestTable) error {
+	for _, table := range tables {
+		for _, path := range table.File_patterns {
+			r, err := openFile(d.Format, path)
+			if err != nil {
+				return fmt.Errorf("can't read file %s for table %s: %v", path, table.Table_name, err)
+			}
+			count, err := r.rowCount()
+			r.close()
+			if err != nil {
+				return fmt.Errorf("error reading file %s for table %s: %v", path, table.Table_name, err)
+			}
+			if count == 0 {
+				conv.Unexpected(fmt.Sprintf("error processing table %s: file %s is empty.", table.Table_name, path))
+				continue
+			}
+			conv.Stats.Rows[table.Table_name] += count
+		}
+	}
+	return nil
+}
+
+// ProcessFiles writes the rows of the files of each table, parent tables
+// first.
+func (d *DataFileImpl) ProcessFiles(conv *internal.Conv, tables []utils.ManifestTable) error {
+	nameToFiles := map[string][]string{}
+	for _, table := range tables {
+		nameToFiles[table.Table_name] = table.File_patterns
+	}
+	for _, spTableId := range ddl.GetSortedTableIdsBySpName(conv.SpSchema) {
+		srcTable, ok := conv.SrcSchema[spTableId]
+		if !ok {
+			continue
+		}
+		for _, path := range nameToFiles[srcTable.Name] {
+			if err := d.processFile(conv, spTableId, path); err != nil {
+				return err
+			}
+		}
+		if conv.DataFlush != nil {
+			conv.DataFlush()
+		}
+	}
+	return nil
+}
+
+// processFile writes the rows of a file of the table with id tableId.
+func (d *DataFileImpl) processFile(conv *internal.Conv, tableId, path string) error {
+	srcTable := conv.SrcSchema[tableId]
+	spTable := conv.SpSchema[tableId]
+	r, err := openFile(d.Format, path)
+	if err != nil {
+		return fmt.Errorf("can't read file %s for table %s: %v", path, srcTable.Name, err)
+	}
+	defer r.close()
+	// Map the columns of the file to the columns of the table. Columns
+	// dropped from the Spanner schema are skipped.
+	fileCols := r.columns()
+	colIds := make([]string, len(fileCols))
+	srcCols := make([]string, len(fileCols))
+	for i, c := range fileCols {
+		colId, err := internal.GetColIdFromSrcName(srcTable.ColDefs, c.Name)
+		if err != nil {
+			return fmt.Errorf("column %s of file %s not found in table %s", c.Name, path, srcTable.Name)
+		}
+		if _, ok := spTable.ColDefs[colId]; ok {
+			colIds[i] = colId
+		}
+		srcCols[i] = c.Name
+	}
+	for {
+		values, err := r.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("can't read row of file %s for table %s: %v", path, srcTable.Name, err)
+		}
+		processDataRow(conv, tableId, colIds, srcCols, values)
+	}
+}
+
+// processDataRow converts a row to Spanner values and writes it.
+func processDataRow(conv *internal.Conv, tableId string, colIds, srcCols []string, values []interface{}) {
+	srcTable := conv.SrcSchema[tableId]
+	spTable := conv.SpSchema[tableId]
+	var cols []string
+	var vals []interface{}
+	var err error
+	for i, v := range values {
+		if v == nil || colIds[i] == "" {
+			continue
+		}
+		spColDef := spTable.ColDefs[colIds[i]]
+		var x interface{}
+		x, err = convValue(conv, spColDef.T, v)
+		if err != nil {
+			err = fmt.Errorf("column %s: %v", srcCols[i], err)
+			break
+		}
+		cols = append(cols, spColDef.Name)
+		vals = append(vals, x)
+	}
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+		conv.StatsAddBadRow(srcTable.Name, conv.DataMode())
+		conv.CollectBadRow(srcTable.Name, srcCols, toStrings(values), err)
+		return
+	}
+	if col, val, ok := conv.NextSyntheticPKey(tableId); ok {
+		cols = append(cols, col)
+		vals = append(vals, val)
+	}
+	conv.WriteRowWithSource(srcTable.Name, srcCols, toStrings(values), spTable.Name, cols, vals)
+}
+
+// toStrings formats the values of a row read from a data file as strings.
+func toStrings(values []interface{}) []string {
+	var strVals []string
+	for _, v := range values {
+		strVals = append(strVals, fmt.Sprint(v))
+	}
+	return strVals
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datafile
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/xitongsys/parquet-go/writer"
+	"go.uber.org/zap"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/utils"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/expressions_api"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/mocks"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+)
+
+func init() {
+	logger.Log = zap.NewNop()
+}
+
+type parquetSinger struct {
+	Id      int64    `parquet:"name=id, type=INT64"`
+	Name    *string  `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Fee     int64    `parquet:"name=fee, type=INT64, convertedtype=DECIMAL, scale=2, precision=10"`
+	Born    int32    `parquet:"name=born, type=INT32, convertedtype=DATE"`
+	Updated int64    `parquet:"name=updated, type=INT64, convertedtype=TIMESTAMP_MICROS"`
+	Score   *float64 `parquet:"name=score, type=DOUBLE, repetitiontype=OPTIONAL"`
+	Tags    []string `parquet:"name=tags, type=LIST, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+}
+
+func writeParquet(t *testing.T, path string, rows []parquetSinger) {
+	f, err := localFile{}.Create(path)
+	assert.Nil(t, err)
+	pw, err := writer.NewParquetWriter(f, new(parquetSinger), 1)
+	assert.Nil(t, err)
+	for _, row := range rows {
+		assert.Nil(t, pw.Write(row))
+	}
+	assert.Nil(t, pw.WriteStop())
+	assert.Nil(t, f.Close())
+}
+
+const avroSchema = `{
+  "type": "record", "name": "album", "fields": [
+    {"name": "id", "type": "int"},
+    {"name": "title", "type": ["null", "string"]},
+    {"name": "price", "type": {"type": "bytes", "logicalType": "decimal", "precision": 6, "scale": 2}},
+    {"name": "released", "type": {"type": "int", "logicalType": "date"}},
+    {"name": "ratings", "type": {"type": "array", "items": ["null", "long"]}},
+    {"name": "format", "type": {"type": "enum", "name": "format", "symbols": ["CD", "VINYL"]}},
+    {"name": "label", "type": ["null", {"type": "record", "name": "label", "fields": [{"name": "name", "type": "string"}]}]},
+    {"name": "choice", "type": ["null", "string", "long"]}
+  ]
+}`
+
+func writeAvro(t *testing.T, path string, records []map[string]interface{}) {
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{W: f, Schema: avroSchema})
+	assert.Nil(t, err)
+	assert.Nil(t, w.Append(records))
+	assert.Nil(t, f.Close())
+}
+
+func writeFiles(t *testing.T) []utils.ManifestTable {
+	dir := t.TempDir()
+	name, score := "Alice", 4.5
+	born := int32(time.Date(1990, 3, 1, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+	updated := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC).UnixMicro()
+	writeParquet(t, filepath.Join(dir, "singers_1.parquet"), []parquetSinger{
+		{Id: 1, Name: &name, Fee: 12345, Born: born, Updated: updated, Score: &score, Tags: []string{"a"}},
+		{Id: 2, Fee: -5, Born: born, Updated: updated},
+	})
+	writeParquet(t, filepath.Join(dir, "singers_2.parquet"), []parquetSinger{
+		{Id: 3, Name: &name, Fee: 0, Born: born, Updated: updated},
+	})
+	title := "Blue"
+	writeAvro(t, filepath.Join(dir, "albums.avro"), []map[string]interface{}{
+		{
+			"id": 1, "title": goavro.Union("string", title), "price": big.NewRat(1999, 100),
+			"released": time.Date(2001, 5, 6, 0, 0, 0, 0, time.UTC), "ratings": []interface{}{goavro.Union("long", 5), nil},
+			"format": "CD", "label": goavro.Union("label", map[string]interface{}{"name": "Indie"}), "choice": nil,
+		},
+		{
+			"id": 2, "title": nil, "price": big.NewRat(-1, 4), "released": time.Date(2001, 5, 7, 0, 0, 0, 0, time.UTC),
+			"ratings": []interface{}{}, "format": "VINYL", "label": nil, "choice": nil,
+		},
+	})
+	return []utils.ManifestTable{
+		{Table_name: "singers", File_patterns: []string{filepath.Join(dir, "singers_1.parquet"), filepath.Join(dir, "singers_2.parquet")}},
+		{Table_name: "albums", File_patterns: []string{filepath.Join(dir, "albums.avro")}},
+	}
+}
+
+func colTypes(tbl schema.Table) map[string]schema.Type {
+	m := map[string]schema.Type{}
+	for _, c := range tbl.ColDefs {
+		m[c.Name] = c.Type
+	}
+	return m
+}
+
+func TestSchemaFromFiles(t *testing.T) {
+	tables := writeFiles(t)
+	conv := internal.MakeConv()
+	conv.SetSchemaMode()
+	assert.Nil(t, (&DataFileImpl{Format: constants.PARQUET}).SchemaFromFiles(conv, tables[:1]))
+	assert.Nil(t, (&DataFileImpl{Format: constants.AVRO}).SchemaFromFiles(conv, tables[1:]))
+	assert.Len(t, conv.SrcSchema, 2)
+
+	singersId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "singers")
+	assert.Nil(t, err)
+	singers := conv.SrcSchema[singersId]
+	// The nested list column is skipped.
+	assert.Equal(t, map[string]schema.Type{
+		"id":      {Name: typeInt64},
+		"name":    {Name: typeString},
+		"fee":     {Name: typeDecimal, Mods: []int64{10, 2}},
+		"born":    {Name: typeDate},
+		"updated": {Name: typeTimestamp},
+		"score":   {Name: typeDouble},
+	}, colTypes(singers))
+	assert.True(t, singers.ColDefs[singers.ColNameIdMap["id"]].NotNull)
+	assert.False(t, singers.ColDefs[singers.ColNameIdMap["name"]].NotNull)
+
+	albumsId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "albums")
+	assert.Nil(t, err)
+	albums := conv.SrcSchema[albumsId]
+	// The union of several types is skipped.
+	assert.Equal(t, map[string]schema.Type{
+		"id":       {Name: typeInt32},
+		"title":    {Name: typeString},
+		"price":    {Name: typeDecimal, Mods: []int64{6, 2}},
+		"released": {Name: typeDate},
+		"ratings":  {Name: typeInt64, ArrayBounds: []int64{-1}},
+		"format":   {Name: typeString},
+		"label":    {Name: typeJSON},
+	}, colTypes(albums))
+	assert.False(t, albums.ColDefs[albums.ColNameIdMap["title"]].NotNull)
+	assert.True(t, albums.ColDefs[albums.ColNameIdMap["format"]].NotNull)
+	// One skipped column per table.
+	assert.Equal(t, int64(2), conv.Unexpecteds())
+
+	// Files of a table must have the same columns.
+	conv = internal.MakeConv()
+	mixed := []utils.ManifestTable{{Table_name: "t", File_patterns: []string{tables[0].File_patterns[0], tables[1].File_patterns[0]}}}
+	assert.NotNil(t, (&DataFileImpl{Format: constants.PARQUET}).SchemaFromFiles(conv, mixed))
+}
+
+type writtenRow struct {
+	table string
+	cols  []string
+	vals  []interface{}
+}
+
+func TestProcessFiles(t *testing.T) {
+	tables := writeFiles(t)
+	conv := internal.MakeConv()
+	conv.SetSchemaMode()
+	assert.Nil(t, (&DataFileImpl{Format: constants.PARQUET}).SchemaFromFiles(conv, tables[:1]))
+	assert.Nil(t, (&DataFileImpl{Format: constants.AVRO}).SchemaFromFiles(conv, tables[1:]))
+	mockAccessor := new(mocks.MockExpressionVerificationAccessor)
+	mockAccessor.On("VerifyExpressions", context.Background(), mock.Anything).Return(internal.VerifyExpressionsOutput{})
+	schemaToSpanner := &common.SchemaToSpannerImpl{
+		ExpressionVerificationAccessor: mockAccessor,
+		DdlV:                           &expressions_api.MockDDLVerifier{},
+	}
+	assert.Nil(t, schemaToSpanner.SchemaToSpannerDDL(conv, ToDdlImpl{}, internal.AdditionalSchemaAttributes{}))
+
+	conv.SetDataMode()
+	var rows []writtenRow
+	conv.SetDataSink(func(table string, cols []string, vals []interface{}) {
+		rows = append(rows, writtenRow{table, cols, vals})
+	})
+	parquetFiles := &DataFileImpl{Format: constants.PARQUET}
+	assert.Nil(t, parquetFiles.SetRowStats(conv, tables[:1]))
+	assert.Equal(t, int64(3), conv.Stats.Rows["singers"])
+	assert.Nil(t, parquetFiles.ProcessFiles(conv, tables[:1]))
+	avroFiles := &DataFileImpl{Format: constants.AVRO}
+	assert.Nil(t, avroFiles.SetRowStats(conv, tables[1:]))
+	assert.Equal(t, int64(2), conv.Stats.Rows["albums"])
+	assert.Nil(t, avroFiles.ProcessFiles(conv, tables[1:]))
+	assert.Equal(t, int64(0), conv.BadRows())
+
+	assert.Len(t, rows, 5)
+	fee, _ := new(big.Rat).SetString("123.45")
+	assert.Equal(t, writtenRow{"singers",
+		[]string{"id", "name", "fee", "born", "updated", "score", "synth_id"},
+		[]interface{}{int64(1), "Alice", *fee, civil.Date{Year: 1990, Month: 3, Day: 1}, time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC), 4.5, "0"}}, rows[0])
+	// Null values are not written.
+	assert.Equal(t, []string{"id", "fee", "born", "updated", "synth_id"}, rows[1].cols)
+	assert.Equal(t, "singers", rows[2].table)
+	assert.Equal(t, int64(3), rows[2].vals[0])
+
+	price := big.NewRat(1999, 100)
+	assert.Equal(t, writtenRow{"albums",
+		[]string{"id", "title", "price", "released", "ratings", "format", "label", "synth_id"},
+		[]interface{}{int64(1), "Blue", *price, civil.Date{Year: 2001, Month: 5, Day: 6},
+			[]spanner.NullInt64{{Int64: 5, Valid: true}, {}}, "CD", `{"name":"Indie"}`, "0"}}, rows[3])
+	assert.Equal(t, []string{"id", "price", "released", "ratings", "format", "synth_id"}, rows[4].cols)
+	assert.Equal(t, []spanner.NullInt64{}, rows[4].vals[3])
+}
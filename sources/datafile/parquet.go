@@ -0,0 +1,326 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datafile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/google/uuid"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// parquetBatchSize is the number of rows read from each column at a time.
+const parquetBatchSize = 1000
+
+// localFile implements source.ParquetFile for local files. The reader opens
+// the file again for each column, with an empty name.
+type localFile struct {
+	*os.File
+	name string
+}
+
+func (f localFile) Open(name string) (source.ParquetFile, error) {
+	if name == "" {
+		name = f.name
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return localFile{file, name}, nil
+}
+
+func (f localFile) Create(name string) (source.ParquetFile, error) {
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return localFile{file, name}, nil
+}
+
+// parquetColumn is a top-level primitive column of a Parquet file.
+type parquetColumn struct {
+	fileColumn
+	path   string
+	decode func(v interface{}) (interface{}, error)
+}
+
+// parquetReader reads the rows of a Parquet file column by column, a batch
+// at a time.
+type parquetReader struct {
+	file    localFile
+	pr      *reader.ParquetReader
+	cols    []parquetColumn
+	skipped []string
+	rows    int64
+	// batch holds the values of the current batch, by column.
+	batch [][]interface{}
+	pos   int
+}
+
+func newParquetReader(path string) (*parquetReader, error) {
+	f, err := localFile{}.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := reader.NewParquetColumnReader(f, 1)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("can't read parquet footer: %v", err)
+	}
+	r := &parquetReader{file: f.(localFile), pr: pr, rows: pr.GetNumRows()}
+	sh := pr.SchemaHandler
+	// The schema elements are flattened depth-first, starting with the root.
+	// Only the primitive columns directly under the root are supported.
+	for i := 1; i < len(sh.SchemaElements); i++ {
+		el := sh.SchemaElements[i]
+		name := sh.Infos[i].ExName
+		if el.GetNumChildren() > 0 || el.GetRepetitionType() == parquet.FieldRepetitionType_REPEATED {
+			r.skipped = append(r.skipped, name)
+			i += countDescendants(sh.SchemaElements, i)
+			continue
+		}
+		ty, decode, err := parquetType(el)
+		if err != nil {
+			r.skipped = append(r.skipped, name)
+			continue
+		}
+		r.cols = append(r.cols, parquetColumn{
+			fileColumn: fileColumn{Name: name, Type: ty, NotNull: el.GetRepetitionType() == parquet.FieldRepetitionType_REQUIRED},
+			path:       sh.IndexMap[int32(i)],
+			decode:     decode,
+		})
+	}
+	return r, nil
+}
+
+// countDescendants returns the number of schema elements in the subtree of
+// the element at index i, excluding the element itself.
+func countDescendants(els []*parquet.SchemaElement, i int) int {
+	n := 0
+	for c := int32(0); c < els[i].GetNumChildren(); c++ {
+		n += 1 + countDescendants(els, i+n+1)
+	}
+	return n
+}
+
+// parquetType maps the physical and logical type of a primitive Parquet
+// column to a source type, and returns the function that decodes its values
+// into Go values.
+func parquetType(el *parquet.SchemaElement) (schema.Type, func(interface{}) (interface{}, error), error) {
+	identity := func(v interface{}) (interface{}, error) { return v, nil }
+	lt := el.GetLogicalType()
+	ct := el.ConvertedType
+	if (lt != nil && lt.IsSetDECIMAL()) || (ct != nil && *ct == parquet.ConvertedType_DECIMAL) {
+		precision, scale := el.GetPrecision(), el.GetScale()
+		if lt != nil && lt.IsSetDECIMAL() {
+			precision, scale = lt.DECIMAL.GetPrecision(), lt.DECIMAL.GetScale()
+		}
+		return schema.Type{Name: typeDecimal, Mods: []int64{int64(precision), int64(scale)}},
+			func(v interface{}) (interface{}, error) { return decodeDecimal(v, scale) }, nil
+	}
+	switch el.GetType() {
+	case parquet.Type_BOOLEAN:
+		return schema.Type{Name: typeBool}, identity, nil
+	case parquet.Type_INT32:
+		switch {
+		case (lt != nil && lt.IsSetDATE()) || (ct != nil && *ct == parquet.ConvertedType_DATE):
+			return schema.Type{Name: typeDate}, func(v interface{}) (interface{}, error) {
+				return civil.DateOf(time.Unix(int64(v.(int32))*24*60*60, 0).UTC()), nil
+			}, nil
+		case (lt != nil && lt.IsSetTIME()) || (ct != nil && *ct == parquet.ConvertedType_TIME_MILLIS):
+			return schema.Type{Name: typeTime}, func(v interface{}) (interface{}, error) {
+				return time.Duration(v.(int32)) * time.Millisecond, nil
+			}, nil
+		case ct != nil && *ct == parquet.ConvertedType_UINT_32:
+			return schema.Type{Name: typeInt64}, func(v interface{}) (interface{}, error) {
+				return int64(uint32(v.(int32))), nil
+			}, nil
+		}
+		return schema.Type{Name: typeInt32}, identity, nil
+	case parquet.Type_INT64:
+		switch {
+		case lt != nil && lt.IsSetTIMESTAMP():
+			unit := lt.TIMESTAMP.GetUnit()
+			return schema.Type{Name: typeTimestamp}, func(v interface{}) (interface{}, error) {
+				switch {
+				case unit.IsSetMILLIS():
+					return time.UnixMilli(v.(int64)).UTC(), nil
+				case unit.IsSetMICROS():
+					return time.UnixMicro(v.(int64)).UTC(), nil
+				}
+				return time.Unix(0, v.(int64)).UTC(), nil
+			}, nil
+		case ct != nil && *ct == parquet.ConvertedType_TIMESTAMP_MILLIS:
+			return schema.Type{Name: typeTimestamp}, func(v interface{}) (interface{}, error) {
+				return time.UnixMilli(v.(int64)).UTC(), nil
+			}, nil
+		case ct != nil && *ct == parquet.ConvertedType_TIMESTAMP_MICROS:
+			return schema.Type{Name: typeTimestamp}, func(v interface{}) (interface{}, error) {
+				return time.UnixMicro(v.(int64)).UTC(), nil
+			}, nil
+		case (lt != nil && lt.IsSetTIME()) || (ct != nil && *ct == parquet.ConvertedType_TIME_MICROS):
+			return schema.Type{Name: typeTime}, func(v interface{}) (interface{}, error) {
+				return time.Duration(v.(int64)) * time.Microsecond, nil
+			}, nil
+		}
+		return schema.Type{Name: typeInt64}, identity, nil
+	case parquet.Type_INT96:
+		// Legacy timestamps written by Impala, Hive and Spark.
+		return schema.Type{Name: typeTimestamp}, func(v interface{}) (interface{}, error) {
+			return decodeInt96(v.(string))
+		}, nil
+	case parquet.Type_FLOAT:
+		return schema.Type{Name: typeFloat}, identity, nil
+	case parquet.Type_DOUBLE:
+		return schema.Type{Name: typeDouble}, identity, nil
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		switch {
+		case (lt != nil && lt.IsSetJSON()) || (ct != nil && *ct == parquet.ConvertedType_JSON):
+			return schema.Type{Name: typeJSON}, identity, nil
+		case (lt != nil && (lt.IsSetSTRING() || lt.IsSetENUM())) || (ct != nil && (*ct == parquet.ConvertedType_UTF8 || *ct == parquet.ConvertedType_ENUM)):
+			return schema.Type{Name: typeString}, identity, nil
+		case lt != nil && lt.IsSetUUID():
+			return schema.Type{Name: typeString}, func(v interface{}) (interface{}, error) {
+				u, err := uuid.FromBytes([]byte(v.(string)))
+				if err != nil {
+					return nil, err
+				}
+				return u.String(), nil
+			}, nil
+		}
+		return schema.Type{Name: typeBytes}, func(v interface{}) (interface{}, error) {
+			return []byte(v.(string)), nil
+		}, nil
+	}
+	return schema.Type{}, nil, fmt.Errorf("unsupported parquet type %v", el.GetType())
+}
+
+// decodeDecimal decodes the unscaled value of a Parquet decimal, stored as
+// an integer or as big-endian two's complement bytes.
+func decodeDecimal(v interface{}, scale int32) (interface{}, error) {
+	unscaled := new(big.Int)
+	switch x := v.(type) {
+	case int32:
+		unscaled.SetInt64(int64(x))
+	case int64:
+		unscaled.SetInt64(x)
+	case string:
+		unscaled.SetBytes([]byte(x))
+		if len(x) > 0 && x[0]&0x80 != 0 {
+			unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(x))))
+		}
+	default:
+		return nil, fmt.Errorf("unexpected decimal value %v of type %T", v, v)
+	}
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return new(big.Rat).SetFrac(unscaled, denom), nil
+}
+
+// julianUnixEpoch is the Julian day number of the Unix epoch.
+const julianUnixEpoch = 2440588
+
+// decodeInt96 decodes an INT96 timestamp: the nanoseconds of the day as a
+// little-endian int64, followed by the Julian day as a little-endian int32.
+func decodeInt96(s string) (time.Time, error) {
+	if len(s) != 12 {
+		return time.Time{}, fmt.Errorf("invalid int96 timestamp of length %d", len(s))
+	}
+	b := []byte(s)
+	nanos := int64(binary.LittleEndian.Uint64(b[:8]))
+	days := int64(binary.LittleEndian.Uint32(b[8:])) - julianUnixEpoch
+	return time.Unix(days*24*60*60, nanos).UTC(), nil
+}
+
+func (r *parquetReader) columns() []fileColumn {
+	var cols []fileColumn
+	for _, c := range r.cols {
+		cols = append(cols, c.fileColumn)
+	}
+	return cols
+}
+
+func (r *parquetReader) unsupported() []string {
+	return r.skipped
+}
+
+func (r *parquetReader) rowCount() (int64, error) {
+	return r.rows, nil
+}
+
+func (r *parquetReader) read() ([]interface{}, error) {
+	if r.batch == nil || r.pos >= len(r.batch[0]) {
+		if r.rows <= 0 {
+			return nil, io.EOF
+		}
+		if err := r.readBatch(); err != nil {
+			return nil, err
+		}
+	}
+	row := make([]interface{}, len(r.cols))
+	for i, c := range r.cols {
+		v := r.batch[i][r.pos]
+		if v == nil {
+			continue
+		}
+		x, err := c.decode(v)
+		if err != nil {
+			return nil, fmt.Errorf("can't decode value of column %s: %v", c.Name, err)
+		}
+		row[i] = x
+	}
+	r.pos++
+	return row, nil
+}
+
+// readBatch reads the next batch of values of all columns.
+func (r *parquetReader) readBatch() error {
+	n := int64(parquetBatchSize)
+	if r.rows < n {
+		n = r.rows
+	}
+	r.batch = make([][]interface{}, len(r.cols))
+	for i, c := range r.cols {
+		values, _, _, err := r.pr.ReadColumnByPath(c.path, n)
+		if err != nil {
+			return fmt.Errorf("can't read column %s: %v", c.Name, err)
+		}
+		if int64(len(values)) != n {
+			return fmt.Errorf("read %d values of column %s, expected %d", len(values), c.Name, n)
+		}
+		r.batch[i] = values
+	}
+	if len(r.cols) == 0 {
+		// Files without supported columns still have rows.
+		r.batch = [][]interface{}{make([]interface{}, n)}
+	}
+	r.rows -= n
+	r.pos = 0
+	return nil
+}
+
+func (r *parquetReader) close() error {
+	r.pr.ReadStop()
+	return r.file.Close()
+}
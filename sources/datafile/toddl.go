@@ -0,0 +1,84 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datafile
+
+import (
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// ToDdl implementation for Parquet and Avro files.
+type ToDdlImpl struct {
+}
+
+// Functions below implement the common.ToDdl interface
+// toSpannerType maps a scalar source schema type (defined by id and
+// mods) into a Spanner type. This is the core source-to-Spanner type
+// mapping.  toSpannerType returns the Spanner type and a list of type
+// conversion issues encountered.
+func (tdi ToDdlImpl) ToSpannerType(conv *internal.Conv, spType string, srcType schema.Type, isPk bool) (ddl.Type, []internal.SchemaIssue) {
+	ty, issues := toSpannerTypeInternal(srcType)
+	if len(srcType.ArrayBounds) > 0 {
+		ty.IsArray = true
+	}
+	if conv.SpDialect == constants.DIALECT_POSTGRESQL {
+		var pg_issues []internal.SchemaIssue
+		ty, pg_issues = common.ToPGDialectType(ty, isPk)
+		issues = append(issues, pg_issues...)
+	}
+	return ty, issues
+}
+
+func (tdi ToDdlImpl) GetColumnAutoGen(conv *internal.Conv, autoGenCol ddl.AutoGenCol, colId string, tableId string) (*ddl.AutoGenCol, error) {
+	return nil, nil
+}
+
+func toSpannerTypeInternal(srcType schema.Type) (ddl.Type, []internal.SchemaIssue) {
+	switch srcType.Name {
+	case typeBool:
+		return ddl.Type{Name: ddl.Bool}, nil
+	case typeInt32:
+		return ddl.Type{Name: ddl.Int64}, []internal.SchemaIssue{internal.Widened}
+	case typeInt64:
+		return ddl.Type{Name: ddl.Int64}, nil
+	case typeFloat:
+		return ddl.Type{Name: ddl.Float32}, nil
+	case typeDouble:
+		return ddl.Type{Name: ddl.Float64}, nil
+	case typeDecimal:
+		// Spanner NUMERIC has a precision of 38 and a scale of 9.
+		if len(srcType.Mods) == 2 && (srcType.Mods[0]-srcType.Mods[1] > 29 || srcType.Mods[1] > 9) {
+			return ddl.Type{Name: ddl.Numeric}, []internal.SchemaIssue{internal.Decimal}
+		}
+		return ddl.Type{Name: ddl.Numeric}, nil
+	case typeString:
+		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
+	case typeBytes:
+		return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
+	case typeDate:
+		return ddl.Type{Name: ddl.Date}, nil
+	case typeTime:
+		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.Time}
+	case typeTimestamp:
+		return ddl.Type{Name: ddl.Timestamp}, nil
+	case typeJSON:
+		return ddl.Type{Name: ddl.JSON}, nil
+	default:
+		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, []internal.SchemaIssue{internal.NoGoodType}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
@@ -25,8 +26,26 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
 )
 
+// ProcessDataRow converts item m of a table to the row(s) of table tableId
+// and writes them. Tables nested in the table of the item have a row for
+// each element of their lists in the item.
 func ProcessDataRow(m map[string]*dynamodb.AttributeValue, conv *internal.Conv, tableId string, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable) {
-	spVals, badCols, srcStrVals := cvtRow(m, srcSchema, spSchema, colIds)
+	if srcSchema.Nested == nil {
+		processDocument(document{attrs: m}, conv, srcSchema, colIds, spSchema)
+		return
+	}
+	docs, skipped := nestedDocuments(conv, srcSchema, m)
+	if skipped > 0 {
+		conv.Unexpected(fmt.Sprintf("Skipped %d list element(s) that aren't maps for table %s", skipped, srcSchema.Name))
+	}
+	for _, doc := range docs {
+		conv.StatsAddRow(srcSchema.Name, conv.DataMode())
+		processDocument(doc, conv, srcSchema, colIds, spSchema)
+	}
+}
+
+func processDocument(doc document, conv *internal.Conv, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable) {
+	spVals, badCols, srcStrVals := cvtDocument(doc, srcSchema, spSchema, colIds)
 	srcTableName := srcSchema.Name
 	spTableName := spSchema.Name
 	spColNames := []string{}
@@ -36,39 +55,44 @@ func ProcessDataRow(m map[string]*dynamodb.AttributeValue, conv *internal.Conv,
 		spColNames = append(spColNames, spSchema.ColDefs[colId].Name)
 	}
 	if len(badCols) == 0 {
-		conv.WriteRow(srcTableName, spTableName, spColNames, spVals)
+		conv.WriteRowWithSource(srcTableName, srcColNames, srcStrVals, spTableName, spColNames, spVals)
 	} else {
 		conv.Unexpected(fmt.Sprintf("Data conversion error for table %s in column(s) %s\n", srcTableName, badCols))
 		conv.StatsAddBadRow(srcTableName, conv.DataMode())
-		conv.CollectBadRow(srcTableName, srcColNames, srcStrVals)
+		conv.CollectBadRow(srcTableName, srcColNames, srcStrVals, fmt.Errorf("data conversion error in column(s) %s", badCols))
 	}
 }
 
 func cvtRow(attrsMap map[string]*dynamodb.AttributeValue, srcSchema schema.Table, spSchema ddl.CreateTable, colIds []string) ([]interface{}, []string, []string) {
+	return cvtDocument(document{attrs: attrsMap}, srcSchema, spSchema, colIds)
+}
+
+func cvtDocument(doc document, srcSchema schema.Table, spSchema ddl.CreateTable, colIds []string) ([]interface{}, []string, []string) {
 	var err error
 	var srcStrVals []string
 	var spVals []interface{}
 	var badCols []string
 	for _, colId := range colIds {
-		srcColName := srcSchema.ColDefs[colId].Name
+		srcColDef := srcSchema.ColDefs[colId]
+		srcColName := srcColDef.Name
+		attrVal := doc.value(srcColDef)
 		var spVal interface{}
 		var srcStrVal string
-		if attrsMap[srcColName] == nil {
+		if attrVal == nil {
 			spVal = nil
 			srcStrVal = "null"
 		} else {
 			// Convert data to the target type.
 			spColDef := spSchema.ColDefs[colId]
-			srcColDef := srcSchema.ColDefs[colId]
 			if spColDef.T.IsArray {
-				spVal, err = convArray(attrsMap[srcColName], srcColDef.Type.Name, spColDef.T.Name)
+				spVal, err = convArray(attrVal, srcColDef.Type.Name, spColDef.T.Name)
 			} else {
-				spVal, err = convScalar(attrsMap[srcColName], srcColDef.Type.Name, spColDef.T.Name)
+				spVal, err = convScalar(attrVal, srcColDef.Type.Name, spColDef.T.Name)
 			}
 			if err != nil {
 				badCols = append(badCols, srcColName)
 			}
-			srcStrVal = attrsMap[srcColName].GoString()
+			srcStrVal = attrVal.GoString()
 		}
 		srcStrVals = append(srcStrVals, srcStrVal)
 		spVals = append(spVals, spVal)
@@ -131,7 +155,7 @@ func convScalar(attrVal *dynamodb.AttributeValue, srcType string, spType string)
 		switch srcType {
 		case typeString:
 			return *attrVal.S, nil
-		case typeNumberString:
+		case typeNumberString, typeOrdinal:
 			return *attrVal.N, nil
 		case typeMap, typeList, typeStringSet, typeNumberStringSet, typeNumberSet, typeBinarySet:
 			// For typeMap and typeList, attrVal is a very verbose data
@@ -152,6 +176,15 @@ func convScalar(attrVal *dynamodb.AttributeValue, srcType string, spType string)
 			}
 			return string(b), nil
 		}
+	case ddl.Int64:
+		switch srcType {
+		case typeOrdinal:
+			val, err := strconv.ParseInt(*attrVal.N, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert '%v' to an INT64 type", *attrVal.N)
+			}
+			return val, nil
+		}
 	case ddl.Numeric:
 		switch srcType {
 		case typeNumber:
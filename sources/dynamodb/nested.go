@@ -0,0 +1,411 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+)
+
+// pathSep separates the attribute names of a path in the keys of docStats.
+// Attribute names can contain dots, so the dotted path used for column
+// names can't be used as a key.
+const pathSep = "\x00"
+
+// docStats are the statistics of the attributes of a sample of documents
+// (items, or elements of lists of maps), used to normalize nested maps into
+// columns and nested lists of maps into tables.
+type docStats struct {
+	count int64
+	// Count of each data type of the attributes, by attribute path.
+	types map[string]map[string]int64
+	// Number of lists of maps, by attribute path.
+	listsOfMaps map[string]int64
+	// Stats of the elements of the lists of maps, by attribute path.
+	elements map[string]*docStats
+}
+
+func newDocStats() *docStats {
+	return &docStats{
+		types:       make(map[string]map[string]int64),
+		listsOfMaps: make(map[string]int64),
+		elements:    make(map[string]*docStats),
+	}
+}
+
+// add adds the attributes of a document, nested under path, to s.
+func (s *docStats) add(attrs map[string]*dynamodb.AttributeValue, path []string) {
+	for name, attr := range attrs {
+		p := append(append([]string{}, path...), name)
+		key := strings.Join(p, pathSep)
+		if _, ok := s.types[key]; !ok {
+			s.types[key] = make(map[string]int64)
+		}
+		incTypeCount(name, attr, s.types[key])
+		switch {
+		case isListOfMaps(attr):
+			s.listsOfMaps[key]++
+			if _, ok := s.elements[key]; !ok {
+				s.elements[key] = newDocStats()
+			}
+			for _, e := range attr.L {
+				s.elements[key].add(e.M, nil)
+				s.elements[key].count++
+			}
+		case len(attr.M) != 0:
+			s.add(attr.M, p)
+		}
+	}
+}
+
+func isListOfMaps(attr *dynamodb.AttributeValue) bool {
+	for _, e := range attr.L {
+		if len(e.M) == 0 {
+			return false
+		}
+	}
+	return len(attr.L) != 0
+}
+
+// nestedList is a list of maps of a document that is normalized into a
+// nested table.
+type nestedList struct {
+	path  []string
+	stats *docStats
+}
+
+// inferDocument infers the columns of the documents described by stats,
+// with nested maps flattened into a column per attribute, and the lists of
+// maps normalized into nested tables. Attributes are only flattened if all
+// the maps they are nested in are. Lists of maps nested deeper than depth
+// levels can't be interleaved and are kept as columns. The columns are
+// sorted by name.
+func inferDocument(stats *docStats, primaryKeys []string, depth int) ([]schema.Column, []nestedList, error) {
+	colDefs, _, err := inferDataTypes(stats.types, stats.count, primaryKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	byKey := make(map[string]schema.Column)
+	for _, c := range colDefs {
+		byKey[c.Name] = c
+	}
+	var cols []schema.Column
+	var lists []nestedList
+	for key, c := range byKey {
+		path := strings.Split(key, pathSep)
+		flattened := true
+		for i := 1; i < len(path); i++ {
+			if byKey[strings.Join(path[:i], pathSep)].Type.Name != typeMap {
+				flattened = false
+				break
+			}
+		}
+		switch {
+		case !flattened, c.Type.Name == typeMap:
+		case c.Type.Name == typeList && stats.listsOfMaps[key] == stats.types[key][typeList] && depth < internal.MaxInterleaveDepth:
+			lists = append(lists, nestedList{path: path, stats: stats.elements[key]})
+		default:
+			c.Name = strings.Join(path, ".")
+			c.Path = path
+			cols = append(cols, c)
+		}
+	}
+	sort.Slice(cols, func(i, j int) bool { return cols[i].Name < cols[j].Name })
+	sort.Slice(lists, func(i, j int) bool {
+		return strings.Join(lists[i].path, pathSep) < strings.Join(lists[j].path, pathSep)
+	})
+	return cols, lists, nil
+}
+
+// NormalizeNestedDocuments normalizes the nested maps of the items of the
+// tables in conv into columns, one for each attribute of the maps, named by
+// the dotted path of the attribute, and the lists of maps into tables
+// interleaved in the table of the items, one row per element, keyed by the
+// key of the item and the position of the element in the list. Lists of maps
+// nested in elements are normalized into tables interleaved in turn. It
+// returns the number of tables added, and does nothing unless the
+// NormalizeNested option is set.
+func (isi InfoSchemaImpl) NormalizeNestedDocuments(conv *internal.Conv) (int, error) {
+	if !isi.NormalizeNested {
+		return 0, nil
+	}
+	var tableIds []string
+	for id, t := range conv.SrcSchema {
+		if t.Nested == nil && hasNestedColumns(t) {
+			tableIds = append(tableIds, id)
+		}
+	}
+	sort.Slice(tableIds, func(i, j int) bool {
+		return conv.SrcSchema[tableIds[i]].Name < conv.SrcSchema[tableIds[j]].Name
+	})
+	added := 0
+	for _, tableId := range tableIds {
+		t := conv.SrcSchema[tableId]
+		stats, err := scanDocumentStats(isi.DynamoClient, isi.SampleSize, t.Name)
+		if err != nil {
+			return 0, err
+		}
+		n, err := normalizeTable(conv, tableId, stats)
+		if err != nil {
+			return 0, err
+		}
+		added += n
+	}
+	return added, nil
+}
+
+func hasNestedColumns(t schema.Table) bool {
+	for _, c := range t.ColDefs {
+		if c.Type.Name == typeMap || c.Type.Name == typeList {
+			return true
+		}
+	}
+	return false
+}
+
+// scanDocumentStats collects the docStats of the first sampleSize items of
+// table.
+func scanDocumentStats(client dynamodbiface.DynamoDBAPI, sampleSize int64, table string) (*docStats, error) {
+	stats := newDocStats()
+	params := &dynamodb.ScanInput{
+		TableName: aws.String(table),
+	}
+	for {
+		result, err := client.Scan(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make Query API call for table %v: %v", table, err)
+		}
+		for _, attrsMap := range result.Items {
+			stats.add(attrsMap, nil)
+			stats.count++
+			if stats.count >= sampleSize {
+				return stats, nil
+			}
+		}
+		if result.LastEvaluatedKey == nil {
+			return stats, nil
+		}
+		params.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// normalizeTable replaces the map columns of table tableId with the columns
+// of their attributes and its list of maps columns with nested tables. It
+// returns the number of tables added.
+func normalizeTable(conv *internal.Conv, tableId string, stats *docStats) (int, error) {
+	t := conv.SrcSchema[tableId]
+	var primaryKeys []string
+	for _, pk := range t.PrimaryKeys {
+		primaryKeys = append(primaryKeys, t.ColDefs[pk.ColId].Name)
+	}
+	cols, lists, err := inferDocument(stats, primaryKeys, 0)
+	if err != nil {
+		return 0, err
+	}
+	flattened := make(map[string][]schema.Column)
+	for _, c := range cols {
+		if len(c.Path) > 1 {
+			flattened[c.Path[0]] = append(flattened[c.Path[0]], c)
+		}
+	}
+	normalized := make(map[string]bool)
+	for _, l := range lists {
+		normalized[l.path[0]] = true
+	}
+	names := make(map[string]bool)
+	for _, c := range t.ColDefs {
+		names[c.Name] = true
+	}
+	replaced := make(map[string]bool)
+	var colIds []string
+	for _, colId := range t.ColIds {
+		c := t.ColDefs[colId]
+		switch {
+		case c.Type.Name == typeMap && len(flattened[c.Name]) > 0:
+			delete(t.ColDefs, colId)
+			replaced[c.Name] = true
+			for _, fc := range flattened[c.Name] {
+				fc.Name = uniqueName(names, fc.Name)
+				t.ColDefs[fc.Id] = fc
+				colIds = append(colIds, fc.Id)
+			}
+		case c.Type.Name == typeList && normalized[c.Name]:
+			delete(t.ColDefs, colId)
+			replaced[c.Name] = true
+		default:
+			colIds = append(colIds, colId)
+		}
+	}
+	t.ColIds = colIds
+	t.ColNameIdMap = colNameIdMap(t)
+	conv.SrcSchema[tableId] = t
+
+	added := 0
+	for _, l := range lists {
+		// The sample may have changed since the columns of the table were
+		// inferred: lists are only normalized if the column they are in was
+		// replaced.
+		if !replaced[l.path[0]] {
+			continue
+		}
+		n, err := addNestedTable(conv, tableId, l, 1)
+		if err != nil {
+			return 0, err
+		}
+		added += n
+	}
+	return added, nil
+}
+
+// addNestedTable adds the table normalized from list l of the documents of
+// table parentId, and the tables of the lists nested in its elements, at
+// depth levels of interleaving. It returns the number of tables added.
+func addNestedTable(conv *internal.Conv, parentId string, l nestedList, depth int) (int, error) {
+	parent := conv.SrcSchema[parentId]
+	t := schema.Table{
+		Id:      internal.GenerateTableId(),
+		Name:    parent.Name + "." + strings.Join(l.path, "."),
+		ColDefs: make(map[string]schema.Column),
+		Nested:  &schema.NestedList{ParentId: parentId, Path: l.path},
+	}
+	names := make(map[string]bool)
+	addKey := func(c schema.Column) {
+		c.Id = internal.GenerateColumnId()
+		c.Name = uniqueName(names, c.Name)
+		c.NotNull = true
+		t.ColIds = append(t.ColIds, c.Id)
+		t.ColDefs[c.Id] = c
+		t.PrimaryKeys = append(t.PrimaryKeys, schema.Key{ColId: c.Id, Order: len(t.PrimaryKeys) + 1})
+	}
+	// Rows are keyed by the key of the parent document, with the same
+	// column names and types, as required for interleaving.
+	for _, pk := range parent.PrimaryKeys {
+		pc := parent.ColDefs[pk.ColId]
+		addKey(schema.Column{Name: pc.Name, Type: pc.Type})
+	}
+	addKey(schema.Column{Name: l.path[len(l.path)-1] + "_ordinal", Type: schema.Type{Name: typeOrdinal}})
+	t.Nested.OrdinalColId = t.ColIds[len(t.ColIds)-1]
+
+	cols, lists, err := inferDocument(l.stats, nil, depth)
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range cols {
+		c.Name = uniqueName(names, c.Name)
+		t.ColIds = append(t.ColIds, c.Id)
+		t.ColDefs[c.Id] = c
+	}
+	t.ColNameIdMap = colNameIdMap(t)
+	conv.SrcSchema[t.Id] = t
+
+	added := 1
+	for _, nl := range lists {
+		n, err := addNestedTable(conv, t.Id, nl, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		added += n
+	}
+	return added, nil
+}
+
+// uniqueName returns name, with a numeric suffix if it's already in names,
+// and adds it to names.
+func uniqueName(names map[string]bool, name string) string {
+	unique := name
+	for i := 1; names[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	names[unique] = true
+	return unique
+}
+
+func colNameIdMap(t schema.Table) map[string]string {
+	m := make(map[string]string)
+	for _, c := range t.ColDefs {
+		m[c.Name] = c.Id
+	}
+	return m
+}
+
+// document is an item of a table, or an element of a list normalized into
+// a nested table along with the values of the key columns of its row.
+type document struct {
+	attrs map[string]*dynamodb.AttributeValue
+	keys  map[string]*dynamodb.AttributeValue
+}
+
+// value returns the value of column col in d, or nil if it isn't set.
+func (d document) value(col schema.Column) *dynamodb.AttributeValue {
+	if len(col.Path) == 0 {
+		if v, ok := d.keys[col.Name]; ok {
+			return v
+		}
+		return d.attrs[col.Name]
+	}
+	attrs := d.attrs
+	for _, name := range col.Path[:len(col.Path)-1] {
+		v := attrs[name]
+		if v == nil || v.M == nil {
+			return nil
+		}
+		attrs = v.M
+	}
+	return attrs[col.Path[len(col.Path)-1]]
+}
+
+// nestedDocuments returns the documents of the rows of table t in item, an
+// item of its root table. The rows of a nested table are the elements of
+// its lists in the documents of its parent table: their keys are the key of
+// the parent document, in the order of the key of the parent table, and the
+// position of the element in the list. Elements that aren't maps can't be
+// converted, and are counted in skipped.
+func nestedDocuments(conv *internal.Conv, t schema.Table, item map[string]*dynamodb.AttributeValue) (docs []document, skipped int) {
+	if t.Nested == nil {
+		return []document{{attrs: item}}, 0
+	}
+	parent := conv.SrcSchema[t.Nested.ParentId]
+	parentDocs, skipped := nestedDocuments(conv, parent, item)
+	for _, p := range parentDocs {
+		list := p.value(schema.Column{Path: t.Nested.Path})
+		if list == nil {
+			continue
+		}
+		for i, e := range list.L {
+			if e.M == nil {
+				skipped++
+				continue
+			}
+			keys := make(map[string]*dynamodb.AttributeValue)
+			for k, pk := range parent.PrimaryKeys {
+				if k < len(t.PrimaryKeys) {
+					keys[t.ColDefs[t.PrimaryKeys[k].ColId].Name] = p.value(parent.ColDefs[pk.ColId])
+				}
+			}
+			keys[t.ColDefs[t.Nested.OrdinalColId].Name] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(i))}
+			docs = append(docs, document{attrs: e.M, keys: keys})
+		}
+	}
+	return docs, skipped
+}
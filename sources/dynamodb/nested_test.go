@@ -0,0 +1,248 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dynamodb
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/expressions_api"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/mocks"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+func str(s string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{S: aws.String(s)} }
+func num(n string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{N: aws.String(n)} }
+
+func rat(s string) big.Rat {
+	r, _ := (&big.Rat{}).SetString(s)
+	return *r
+}
+
+// nestedTestItems returns orders with an address map and a list of items,
+// with a list of parts nested in the first item.
+func nestedTestItems() []map[string]*dynamodb.AttributeValue {
+	return []map[string]*dynamodb.AttributeValue{
+		{
+			"id":      str("o1"),
+			"address": {M: map[string]*dynamodb.AttributeValue{"city": str("Paris"), "zip": str("75001")}},
+			"items": {L: []*dynamodb.AttributeValue{
+				{M: map[string]*dynamodb.AttributeValue{
+					"sku":   str("a"),
+					"qty":   num("1"),
+					"parts": {L: []*dynamodb.AttributeValue{{M: map[string]*dynamodb.AttributeValue{"n": str("p1")}}}},
+				}},
+				{M: map[string]*dynamodb.AttributeValue{"sku": str("b"), "qty": num("2")}},
+			}},
+		},
+		{
+			"id":      str("o2"),
+			"address": {M: map[string]*dynamodb.AttributeValue{"city": str("Rome")}},
+			"items":   {L: []*dynamodb.AttributeValue{{M: map[string]*dynamodb.AttributeValue{"sku": str("c"), "qty": num("3")}}}},
+		},
+	}
+}
+
+// nestedTestConv converts the schema of table orders, with nested documents
+// normalized. The client has scans left for dataScans data migrations of
+// the table.
+func nestedTestConv(t *testing.T, dataScans int) (*internal.Conv, *mockDynamoClient) {
+	tableName := "orders"
+	describeTableOutput := dynamodb.DescribeTableOutput{
+		Table: &dynamodb.TableDescription{
+			TableName: aws.String(tableName),
+			KeySchema: []*dynamodb.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: aws.String("HASH")}},
+		},
+	}
+	client := &mockDynamoClient{
+		listTableOutputs:     []dynamodb.ListTablesOutput{{TableNames: []*string{aws.String(tableName)}}},
+		describeTableOutputs: []dynamodb.DescribeTableOutput{describeTableOutput, describeTableOutput},
+	}
+	// Columns are inferred, then nested documents are normalized, from a
+	// scan of the table.
+	for i := 0; i < 2+dataScans; i++ {
+		client.scanOutputs = append(client.scanOutputs, dynamodb.ScanOutput{Items: nestedTestItems()})
+	}
+	mockAccessor := new(mocks.MockExpressionVerificationAccessor)
+	mockAccessor.On("VerifyExpressions", context.Background(), mock.Anything).Return(internal.VerifyExpressionsOutput{})
+	schemaToSpanner := &common.SchemaToSpannerImpl{
+		ExpressionVerificationAccessor: mockAccessor,
+		DdlV:                           &expressions_api.MockDDLVerifier{},
+	}
+	conv := internal.MakeConv()
+	processSchema := common.ProcessSchemaImpl{}
+	isi := InfoSchemaImpl{DynamoClient: client, SampleSize: 100, NormalizeNested: true}
+	err := processSchema.ProcessSchema(conv, isi, 1, internal.AdditionalSchemaAttributes{}, schemaToSpanner, &common.UtilsOrderImpl{}, &common.InfoSchemaImpl{})
+	assert.Nil(t, err)
+	return conv, client
+}
+
+func tableIdByName(conv *internal.Conv, name string) string {
+	for id, t := range conv.SrcSchema {
+		if t.Name == name {
+			return id
+		}
+	}
+	return ""
+}
+
+func colNames(t schema.Table) []string {
+	var names []string
+	for _, colId := range t.ColIds {
+		names = append(names, t.ColDefs[colId].Name)
+	}
+	return names
+}
+
+// ordersRow returns the Spanner columns of table orders, which are in no
+// particular order, and the values of row.
+func ordersRow(conv *internal.Conv, row map[string]interface{}) ([]string, []interface{}) {
+	spTable := conv.SpSchema[tableIdByName(conv, "orders")]
+	var cols []string
+	var vals []interface{}
+	for _, colId := range spTable.ColIds {
+		cols = append(cols, spTable.ColDefs[colId].Name)
+		vals = append(vals, row[spTable.ColDefs[colId].Name])
+	}
+	return cols, vals
+}
+
+func TestNormalizeNestedDocuments(t *testing.T) {
+	conv, _ := nestedTestConv(t, 0)
+	assert.Equal(t, 3, len(conv.SrcSchema))
+	assert.Equal(t, 3, len(conv.SpSchema))
+
+	ordersId := tableIdByName(conv, "orders")
+	orders := conv.SrcSchema[ordersId]
+	assert.Nil(t, orders.Nested)
+	assert.ElementsMatch(t, []string{"id", "address.city", "address.zip"}, colNames(orders))
+	city := orders.ColDefs[orders.ColNameIdMap["address.city"]]
+	assert.Equal(t, []string{"address", "city"}, city.Path)
+	assert.True(t, city.NotNull)
+	assert.False(t, orders.ColDefs[orders.ColNameIdMap["address.zip"]].NotNull)
+	assert.Equal(t, "address_city", conv.SpSchema[ordersId].ColDefs[city.Id].Name)
+
+	itemsId := tableIdByName(conv, "orders.items")
+	items := conv.SrcSchema[itemsId]
+	assert.Equal(t, []string{"id", "items_ordinal", "qty", "sku"}, colNames(items))
+	assert.Equal(t, &schema.NestedList{ParentId: ordersId, Path: []string{"items"}, OrdinalColId: items.ColIds[1]}, items.Nested)
+	assert.Equal(t, []schema.Key{{ColId: items.ColIds[0], Order: 1}, {ColId: items.ColIds[1], Order: 2}}, items.PrimaryKeys)
+	assert.Equal(t, []string{"sku"}, items.ColDefs[items.ColNameIdMap["sku"]].Path)
+	itemsSp := conv.SpSchema[itemsId]
+	assert.Equal(t, "orders_items", itemsSp.Name)
+	assert.Equal(t, ddl.InterleavedParent{Id: ordersId, OnDelete: constants.FK_CASCADE}, itemsSp.ParentTable)
+	assert.Equal(t, ddl.Type{Name: ddl.Int64}, itemsSp.ColDefs[items.Nested.OrdinalColId].T)
+	assert.Equal(t, conv.SpSchema[ordersId].ColDefs[orders.ColNameIdMap["id"]].T, itemsSp.ColDefs[items.ColIds[0]].T)
+
+	partsId := tableIdByName(conv, "orders.items.parts")
+	parts := conv.SrcSchema[partsId]
+	assert.Equal(t, []string{"id", "items_ordinal", "parts_ordinal", "n"}, colNames(parts))
+	assert.Equal(t, itemsId, parts.Nested.ParentId)
+	assert.Equal(t, 3, len(parts.PrimaryKeys))
+	assert.Equal(t, itemsId, conv.SpSchema[partsId].ParentTable.Id)
+}
+
+func TestNormalizeNestedDocumentsDisabled(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{Name: "orders", Id: "t1", ColIds: []string{"c_items"},
+		ColDefs: map[string]schema.Column{"c_items": {Name: "items", Id: "c_items", Type: schema.Type{Name: typeList}}}}
+	n, err := InfoSchemaImpl{DynamoClient: &mockDynamoClient{}}.NormalizeNestedDocuments(conv)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, []string{"c_items"}, conv.SrcSchema["t1"].ColIds)
+}
+
+func TestProcessDataNested(t *testing.T) {
+	// The nested tables are populated from the scan of the orders table.
+	conv, client := nestedTestConv(t, 1)
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+	commonInfoSchema := common.InfoSchemaImpl{}
+	commonInfoSchema.ProcessData(conv, InfoSchemaImpl{DynamoClient: client, SampleSize: 100, NormalizeNested: true}, internal.AdditionalDataAttributes{})
+
+	ordersCols, o1 := ordersRow(conv, map[string]interface{}{"id": "o1", "address_city": "Paris", "address_zip": "75001"})
+	_, o2 := ordersRow(conv, map[string]interface{}{"id": "o2", "address_city": "Rome"})
+	itemsCols := []string{"id", "items_ordinal", "qty", "sku"}
+	assert.Equal(t, []spannerData{
+		{table: "orders", cols: ordersCols, vals: o1},
+		{table: "orders", cols: ordersCols, vals: o2},
+		{table: "orders_items", cols: itemsCols, vals: []interface{}{"o1", int64(0), rat("1"), "a"}},
+		{table: "orders_items", cols: itemsCols, vals: []interface{}{"o1", int64(1), rat("2"), "b"}},
+		{table: "orders_items", cols: itemsCols, vals: []interface{}{"o2", int64(0), rat("3"), "c"}},
+		{table: "orders_items_parts", cols: []string{"id", "items_ordinal", "parts_ordinal", "n"}, vals: []interface{}{"o1", int64(0), int64(0), "p1"}},
+	}, rows)
+	assert.Equal(t, int64(3), conv.Stats.Rows["orders.items"])
+	assert.Equal(t, int64(3), conv.Stats.GoodRows["orders.items"])
+	assert.Equal(t, int64(1), conv.Stats.GoodRows["orders.items.parts"])
+	assert.Equal(t, int64(0), conv.Unexpecteds())
+	assert.Equal(t, len(client.scanOutputs), client.scanCallCount)
+}
+
+func TestProcessRecordNested(t *testing.T) {
+	conv, _ := nestedTestConv(t, 0)
+	streamInfo := MakeStreamingInfo()
+	streamInfo.makeRecordMaps("orders")
+	var batches [][]*sp.Mutation
+	streamInfo.write = func(ms []*sp.Mutation) error {
+		batches = append(batches, ms)
+		return nil
+	}
+
+	item := nestedTestItems()[0]
+	ProcessRecord(conv, streamInfo, &dynamodbstreams.Record{
+		Dynamodb:  &dynamodbstreams.StreamRecord{NewImage: item},
+		EventName: aws.String("MODIFY"),
+	}, "orders")
+	ordersCols, o1 := ordersRow(conv, map[string]interface{}{"id": "o1", "address_city": "Paris", "address_zip": "75001"})
+	itemsCols := []string{"id", "items_ordinal", "qty", "sku"}
+	key := sp.Key{"o1"}
+	assert.Equal(t, [][]*sp.Mutation{
+		{sp.InsertOrUpdate("orders", ordersCols, o1)},
+		// Elements removed from the lists of the item are deleted, in the
+		// same transaction as the nested rows are written again.
+		{
+			sp.Delete("orders_items", sp.KeyRange{Start: key, End: key, Kind: sp.ClosedClosed}),
+			sp.InsertOrUpdate("orders_items", itemsCols, []interface{}{"o1", int64(0), rat("1"), "a"}),
+			sp.InsertOrUpdate("orders_items", itemsCols, []interface{}{"o1", int64(1), rat("2"), "b"}),
+			sp.InsertOrUpdate("orders_items_parts", []string{"id", "items_ordinal", "parts_ordinal", "n"}, []interface{}{"o1", int64(0), int64(0), "p1"}),
+		},
+	}, batches)
+
+	// Nested rows are deleted with the item.
+	batches = nil
+	ProcessRecord(conv, streamInfo, &dynamodbstreams.Record{
+		Dynamodb:  &dynamodbstreams.StreamRecord{Keys: map[string]*dynamodb.AttributeValue{"id": str("o1")}},
+		EventName: aws.String("REMOVE"),
+	}, "orders")
+	assert.Equal(t, [][]*sp.Mutation{{sp.Delete("orders", sp.Key{"o1"})}}, batches)
+	assert.Equal(t, int64(0), sumNestedMapValues(streamInfo.BadRecords))
+	assert.Equal(t, int64(0), sumNestedMapValues(streamInfo.DroppedRecords))
+}
@@ -46,6 +46,9 @@ const (
 	typeNumberSet       = "NumberSet"
 	typeNumberStringSet = "NumberStringSet"
 	typeBinarySet       = "BinarySet"
+	// Position of an element in the list of maps normalized into a nested
+	// table, see NormalizeNestedDocuments.
+	typeOrdinal = "Ordinal"
 
 	errThreshold      = float64(0.001)
 	conflictThreshold = float64(0.05)
@@ -55,6 +58,9 @@ type InfoSchemaImpl struct {
 	DynamoClient        dynamodbiface.DynamoDBAPI
 	DynamoStreamsClient dynamodbstreamsiface.DynamoDBStreamsAPI
 	SampleSize          int64
+	// Normalize nested maps into columns and lists of maps into
+	// interleaved tables, see NormalizeNestedDocuments.
+	NormalizeNested bool
 }
 
 func (isi InfoSchemaImpl) GetToDdl() common.ToDdl {
@@ -149,6 +155,12 @@ func (isi InfoSchemaImpl) GetForeignKeys(conv *internal.Conv, table common.Schem
 	return foreignKeys, err
 }
 
+// GetSourceObjects returns nil: DynamoDB has no views, triggers or stored
+// procedures.
+func (isi InfoSchemaImpl) GetSourceObjects(conv *internal.Conv) ([]schema.SourceObject, error) {
+	return nil, nil
+}
+
 func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAndName, colNameIdMap map[string]string) (indexes []schema.Index, err error) {
 	input := &dynamodb.DescribeTableInput{
 		TableName: aws.String(table.Name),
@@ -180,17 +192,34 @@ func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAnd
 // we extract data using Scan requests, convert the data to Spanner data (based
 // on the source and Spanner schemas), and write it to Spanner. If we can't
 // get/process data for a table, we skip that table and process the remaining
-// tables.
+// tables. The tables nested in the table are populated from the same Scan.
 func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable, additionalAttributes internal.AdditionalDataAttributes) error {
 	rows, err := isi.GetRowsFromTable(conv, tableId)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", conv.SrcSchema[tableId].Name, err))
 		return err
 	}
+	items := rows.([]map[string]*dynamodb.AttributeValue)
 	// Iterate the items returned.
-	for _, attrsMap := range rows.([]map[string]*dynamodb.AttributeValue) {
+	for _, attrsMap := range items {
 		ProcessDataRow(attrsMap, conv, tableId, srcSchema, colIds, spSchema)
 	}
+	// The rows of the tables nested in the table are read from the same
+	// items. Like interleaved tables, each table is written once the rows of
+	// its parent are flushed.
+	for _, id := range common.NestedTableIds(conv, tableId) {
+		nestedSpSchema, ok := conv.SpSchema[id]
+		if !ok {
+			continue
+		}
+		if conv.DataFlush != nil {
+			conv.DataFlush()
+		}
+		nestedColIds := common.GetCommonColumnIds(conv, id, common.WritableColumnIds(conv, id, nestedSpSchema.ColIds))
+		for _, attrsMap := range items {
+			ProcessDataRow(attrsMap, conv, id, conv.SrcSchema[id], nestedColIds, nestedSpSchema)
+		}
+	}
 	return nil
 }
 
@@ -203,6 +232,10 @@ func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *inte
 	tableIds := ddl.GetSortedTableIdsBySpName(conv.SpSchema)
 
 	for _, tableId := range tableIds {
+		// Changes to nested tables are streamed with their root table.
+		if conv.SrcSchema[tableId].Nested != nil {
+			continue
+		}
 		srcTable := conv.SrcSchema[tableId].Name
 		streamArn, err := NewDynamoDBStream(isi.DynamoClient, srcTable)
 		if err != nil {
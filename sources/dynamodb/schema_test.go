@@ -203,7 +203,7 @@ func TestProcessSchema(t *testing.T) {
 		ExpressionVerificationAccessor: mockAccessor,
 		DdlV:                           &expressions_api.MockDDLVerifier{},
 	}
-	err := processSchema.ProcessSchema(conv, InfoSchemaImpl{client, nil, sampleSize}, 1, internal.AdditionalSchemaAttributes{}, schemaToSpanner, &common.UtilsOrderImpl{}, &common.InfoSchemaImpl{})
+	err := processSchema.ProcessSchema(conv, InfoSchemaImpl{client, nil, sampleSize, false}, 1, internal.AdditionalSchemaAttributes{}, schemaToSpanner, &common.UtilsOrderImpl{}, &common.InfoSchemaImpl{})
 
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
@@ -315,7 +315,7 @@ func TestProcessSchema_FullDataTypes(t *testing.T) {
 		ExpressionVerificationAccessor: mockAccessor,
 		DdlV:                           &expressions_api.MockDDLVerifier{},
 	}
-	err := processSchema.ProcessSchema(conv, InfoSchemaImpl{client, nil, sampleSize}, 1, internal.AdditionalSchemaAttributes{}, schemaToSpanner, &common.UtilsOrderImpl{}, &common.InfoSchemaImpl{})
+	err := processSchema.ProcessSchema(conv, InfoSchemaImpl{client, nil, sampleSize, false}, 1, internal.AdditionalSchemaAttributes{}, schemaToSpanner, &common.UtilsOrderImpl{}, &common.InfoSchemaImpl{})
 
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
@@ -399,7 +399,7 @@ func TestProcessData(t *testing.T) {
 			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
 		})
 	commonInfoSchema := common.InfoSchemaImpl{}
-	commonInfoSchema.ProcessData(conv, InfoSchemaImpl{client, nil, 10}, internal.AdditionalDataAttributes{})
+	commonInfoSchema.ProcessData(conv, InfoSchemaImpl{client, nil, 10, false}, internal.AdditionalDataAttributes{})
 	assert.Equal(t,
 		[]spannerData{
 			{
@@ -597,7 +597,7 @@ func TestInfoSchemaImpl_GetIndexes(t *testing.T) {
 
 	dySchema := common.SchemaAndName{Name: "test"}
 	conv := internal.MakeConv()
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{client, nil, 10, false}
 	colNameToId := map[string]string{attrNameC: "c1", attrNameD: "c2"}
 	indexes, err := isi.GetIndexes(conv, dySchema, colNameToId)
 	assert.Nil(t, err)
@@ -657,7 +657,7 @@ func TestInfoSchemaImpl_GetConstraints(t *testing.T) {
 
 	dySchema := common.SchemaAndName{Name: "test"}
 	conv := internal.MakeConv()
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{client, nil, 10, false}
 	primaryKeys, _, constraints, err := isi.GetConstraints(conv, dySchema)
 	assert.Nil(t, err)
 
@@ -678,7 +678,7 @@ func TestInfoSchemaImpl_GetTables(t *testing.T) {
 	client := &mockDynamoClient{
 		listTableOutputs: listTableOutputs,
 	}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{client, nil, 10, false}
 	tables, err := isi.GetTables()
 	assert.Nil(t, err)
 	assert.Equal(t, []common.SchemaAndName{{Schema: "", Name: "table-a", Id: ""}, {Schema: "", Name: "table-b", Id: ""}}, tables)
@@ -688,7 +688,7 @@ func TestInfoSchemaImpl_GetTableName(t *testing.T) {
 	tableNameA := "table-a"
 
 	client := &mockDynamoClient{}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{client, nil, 10, false}
 	table := isi.GetTableName("", tableNameA)
 	assert.Equal(t, tableNameA, table)
 }
@@ -732,7 +732,7 @@ func TestInfoSchemaImpl_GetColumns(t *testing.T) {
 	}
 	dySchema := common.SchemaAndName{Name: "test", Id: "t1"}
 
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{client, nil, 10, false}
 
 	colDefs, _, err := isi.GetColumns(conv, dySchema, nil, nil)
 	assert.Nil(t, err)
@@ -760,7 +760,7 @@ func TestInfoSchemaImpl_GetForeignKeys(t *testing.T) {
 	dySchema := common.SchemaAndName{Name: "test"}
 	conv := internal.MakeConv()
 	client := &mockDynamoClient{}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{client, nil, 10, false}
 	fk, err := isi.GetForeignKeys(conv, dySchema)
 	assert.Nil(t, err)
 	assert.Nil(t, fk)
@@ -783,7 +783,7 @@ func TestInfoSchemaImpl_GetRowCount(t *testing.T) {
 		describeTableOutputs: describeTableOutputs,
 	}
 
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{client, nil, 10, false}
 	dySchema := common.SchemaAndName{Name: tableNameA}
 
 	rowCount, err := isi.GetRowCount(dySchema)
@@ -815,7 +815,7 @@ func TestInfoSchemaImpl_GetRowsFromTable(t *testing.T) {
 		scanOutputs: scanOutputs,
 	}
 	tableName := "testtable"
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{client, nil, 10, false}
 
 	rows, err := isi.GetRowsFromTable(conv, tableName)
 	assert.Nil(t, err)
@@ -851,7 +851,7 @@ func TestInfoSchemaImpl_ProcessData(t *testing.T) {
 	client := &mockDynamoClient{
 		scanOutputs: scanOutputs,
 	}
-	isi := InfoSchemaImpl{client, nil, 10}
+	isi := InfoSchemaImpl{client, nil, 10, false}
 
 	tableName := "cart"
 	tableId := "t1"
@@ -991,7 +991,7 @@ func TestSetRowStats(t *testing.T) {
 	}
 
 	commonInfoSchema := common.InfoSchemaImpl{}
-	commonInfoSchema.SetRowStats(conv, InfoSchemaImpl{client, nil, 10})
+	commonInfoSchema.SetRowStats(conv, InfoSchemaImpl{client, nil, 10, false})
 
 	assert.Equal(t, tableItemCountA, conv.Stats.Rows[tableNameA])
 	assert.Equal(t, tableItemCountB, conv.Stats.Rows[tableNameB])
@@ -39,6 +39,7 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
 )
 
 const (
@@ -374,6 +375,11 @@ func ProcessRecord(conv *internal.Conv, streamInfo *StreamingInfo, record *dynam
 	spVals, badCols, srcStrVals := cvtRow(srcImage, srcSchema, spSchema, commonIds)
 	if len(badCols) == 0 {
 		writeRecord(streamInfo, srcTable, spTable, eventName, spCols, spVals, srcSchema)
+		// Rows of nested tables are deleted with the item by the cascading
+		// delete of their interleaved parent.
+		if eventName != "REMOVE" {
+			processNestedRecords(conv, streamInfo, eventName, tableId, srcImage, spSchemaKey(spSchema, commonIds, spVals))
+		}
 	} else {
 		streamInfo.StatsAddBadRecord(srcTable, eventName)
 		streamInfo.CollectBadRecord(eventName, srcTable, srcCols, srcStrVals)
@@ -381,6 +387,95 @@ func ProcessRecord(conv *internal.Conv, streamInfo *StreamingInfo, record *dynam
 	streamInfo.StatsAddRecordProcessed()
 }
 
+// nestedRecord is a mutation of the rows nested in an item, with the row it
+// writes for the dropped records.
+type nestedRecord struct {
+	m       *sp.Mutation
+	spTable string
+	spCols  []string
+	spVals  []interface{}
+}
+
+// processNestedRecords writes the rows of the tables nested in table tableId
+// for a record of the table with image srcImage and Spanner key key. The
+// rows nested in a modified item are replaced, as elements may have been
+// removed from its lists. The rows are deleted and written again in a single
+// transaction, so that readers never see the item without its nested rows.
+func processNestedRecords(conv *internal.Conv, streamInfo *StreamingInfo, eventName, tableId string, srcImage map[string]*dynamodb.AttributeValue, key sp.Key) {
+	srcTable := conv.SrcSchema[tableId].Name
+	nestedIds := common.NestedTableIds(conv, tableId)
+	var records []nestedRecord
+	if eventName == "MODIFY" {
+		for _, id := range nestedIds {
+			spSchema, ok := conv.SpSchema[id]
+			if !ok || conv.SrcSchema[id].Nested.ParentId != tableId {
+				continue
+			}
+			m := sp.Delete(spSchema.Name, sp.KeyRange{Start: key, End: key, Kind: sp.ClosedClosed})
+			records = append(records, nestedRecord{m: m, spTable: spSchema.Name, spVals: key})
+		}
+	}
+	for _, id := range nestedIds {
+		srcSchema := conv.SrcSchema[id]
+		spSchema, ok := conv.SpSchema[id]
+		if !ok {
+			continue
+		}
+		colIds := common.GetCommonColumnIds(conv, id, spSchema.ColIds)
+		var spCols, srcCols []string
+		for _, colId := range colIds {
+			spCols = append(spCols, spSchema.ColDefs[colId].Name)
+			srcCols = append(srcCols, srcSchema.ColDefs[colId].Name)
+		}
+		docs, skipped := nestedDocuments(conv, srcSchema, srcImage)
+		if skipped > 0 {
+			streamInfo.Unexpected(fmt.Sprintf("Skipped %d list element(s) that aren't maps for table %s", skipped, srcSchema.Name))
+		}
+		for _, doc := range docs {
+			spVals, badCols, srcStrVals := cvtDocument(doc, srcSchema, spSchema, colIds)
+			if len(badCols) == 0 {
+				m := getMutation(eventName, srcTable, spSchema.Name, spCols, spVals, srcSchema)
+				records = append(records, nestedRecord{m: m, spTable: spSchema.Name, spCols: spCols, spVals: spVals})
+			} else {
+				streamInfo.StatsAddBadRecord(srcTable, eventName)
+				streamInfo.CollectBadRecord(eventName, srcSchema.Name, srcCols, srcStrVals)
+			}
+		}
+	}
+	if len(records) == 0 {
+		return
+	}
+	if streamInfo.write == nil {
+		streamInfo.StatsAddBadRecord(srcTable, eventName)
+		streamInfo.Unexpected("Internal error: processNestedRecords called but writer not configured")
+		return
+	}
+	var ms []*sp.Mutation
+	for _, r := range records {
+		ms = append(ms, r.m)
+	}
+	if err := writeMutations(ms, streamInfo); err != nil {
+		for _, r := range records {
+			streamInfo.StatsAddDroppedRecord(srcTable, eventName)
+			streamInfo.CollectDroppedRecord(eventName, r.spTable, r.spCols, r.spVals, err)
+		}
+	}
+}
+
+// spSchemaKey returns the Spanner key of a row of spSchema with values
+// spVals for columns colIds.
+func spSchemaKey(spSchema ddl.CreateTable, colIds []string, spVals []interface{}) sp.Key {
+	var key sp.Key
+	for _, pk := range spSchema.PrimaryKeys {
+		for i, colId := range colIds {
+			if colId == pk.ColId {
+				key = append(key, spVals[i])
+			}
+		}
+	}
+	return key
+}
+
 // writeRecord handles creation and processing of mutation from the converted data to Cloud Spanner.
 // If the writer which writes mutations to Cloud Spanner is not configured then it treats the record
 // as a bad record.
@@ -447,10 +542,16 @@ func parentDataMissingError(err error) bool {
 // writeMutation handles writing of a mutation to Cloud Spanner. To handle insertions failing
 // because of missing parent data, a retryLimit is set.
 func writeMutation(m *sp.Mutation, streamInfo *StreamingInfo) error {
+	return writeMutations([]*sp.Mutation{m}, streamInfo)
+}
+
+// writeMutations is like writeMutation, for mutations that are applied
+// together.
+func writeMutations(ms []*sp.Mutation, streamInfo *StreamingInfo) error {
 	var err error
 	tryNum := 0
 	for tryNum < retryLimit {
-		err = streamInfo.write(m)
+		err = streamInfo.write(ms)
 		if err == nil || !parentDataMissingError(err) {
 			break
 		}
@@ -462,11 +563,11 @@ func writeMutation(m *sp.Mutation, streamInfo *StreamingInfo) error {
 
 // setWriter initializes the write function used to write mutations to Cloud Spanner.
 func setWriter(streamInfo *StreamingInfo, client *sp.Client, conv *internal.Conv) {
-	streamInfo.write = func(m *sp.Mutation) error {
+	streamInfo.write = func(ms []*sp.Mutation) error {
 		migrationData := metrics.GetMigrationData(conv, "", constants.DataConv)
 		serializedMigrationData, _ := proto.Marshal(migrationData)
 		migrationMetadataValue := base64.StdEncoding.EncodeToString(serializedMigrationData)
-		_, err := client.Apply(metadata.AppendToOutgoingContext(context.Background(), constants.MigrationMetadataKey, migrationMetadataValue), []*sp.Mutation{m})
+		_, err := client.Apply(metadata.AppendToOutgoingContext(context.Background(), constants.MigrationMetadataKey, migrationMetadataValue), ms)
 		return err
 	}
 }
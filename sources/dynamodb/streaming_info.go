@@ -31,7 +31,7 @@ type StreamingInfo struct {
 	ShardProcessed   map[string]bool             // Processing status of a shard, (default false i.e. unprocessed).
 	UserExit         bool                        // Flag confirming if customer wants to exit or not, (false until user presses Ctrl+C).
 	Unexpecteds      map[string]int64            // Count of unexpected conditions, broken down by condition description.
-	write            func(m *sp.Mutation) error  // Writes a given mutation to Cloud Spanner.
+	write            func(ms []*sp.Mutation) error // Writes the given mutations to Cloud Spanner in a single transaction.
 	SampleBadRecords []string                    // Records that generated errors during conversion.
 	SampleBadWrites  []string                    // Records that faced errors while writing to Cloud Spanner.
 	lock             sync.Mutex
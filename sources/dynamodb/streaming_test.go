@@ -379,9 +379,9 @@ func TestProcessRecord(t *testing.T) {
 	streamInfo := MakeStreamingInfo()
 	streamInfo.Records[tableName] = make(map[string]int64)
 	writes := 0
-	streamInfo.write = func(m *sp.Mutation) error {
+	streamInfo.write = func(ms []*sp.Mutation) error {
 		writes++
-		assert.Equal(t, m, sp.Insert(tableName, cols, []interface{}{valA, *numVal}))
+		assert.Equal(t, []*sp.Mutation{sp.Insert(tableName, cols, []interface{}{valA, *numVal})}, ms)
 		return nil
 	}
 	ProcessRecord(conv, streamInfo, record, tableName)
@@ -538,14 +538,16 @@ func Test_writeRecord(t *testing.T) {
 	var mutationsWritten []*sp.Mutation
 	var mutationsFailed []*sp.Mutation
 
-	streamInfo.write = func(m *sp.Mutation) error {
+	streamInfo.write = func(ms []*sp.Mutation) error {
 		var err error
 		writeCount++
-		if intersect(m, badMutations) {
-			err = errors.New("record not processed")
-			mutationsFailed = append(mutationsFailed, m)
-		} else {
-			mutationsWritten = append(mutationsWritten, m)
+		for _, m := range ms {
+			if intersect(m, badMutations) {
+				err = errors.New("record not processed")
+				mutationsFailed = append(mutationsFailed, m)
+			} else {
+				mutationsWritten = append(mutationsWritten, m)
+			}
 		}
 		time.Sleep(20 * time.Millisecond)
 		return err
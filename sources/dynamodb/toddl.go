@@ -54,6 +54,8 @@ func toSpannerTypeInternal(conv *internal.Conv, srcType schema.Type) (ddl.Type,
 		return ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, nil
 	case typeBool:
 		return ddl.Type{Name: ddl.Bool}, nil
+	case typeOrdinal:
+		return ddl.Type{Name: ddl.Int64}, nil
 	case typeBinary:
 		return ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, nil
 	case typeStringSet, typeNumberStringSet:
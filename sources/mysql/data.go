@@ -17,7 +17,6 @@ package mysql
 import (
 	"fmt"
 	"math/big"
-	"math/bits"
 	"strconv"
 	"strings"
 	"time"
@@ -35,18 +34,25 @@ import (
 // and vals contains string data to be converted to appropriate types
 // to send to Spanner. ProcessDataRow is only called in DataMode.
 func ProcessDataRow(conv *internal.Conv, tableId string, colIds []string, srcSchema schema.Table, spSchema ddl.CreateTable, vals []string, additionalAttributes internal.AdditionalDataAttributes) {
+	spTableName, cvtCols, cvtVals, err := ConvertData(conv, tableId, colIds, srcSchema, spSchema, vals, additionalAttributes)
+	writeDataRow(conv, colIds, srcSchema, vals, spTableName, cvtCols, cvtVals, err)
+}
+
+// writeDataRow writes a row converted by ConvertData to Spanner, or records
+// it as a bad row if its conversion failed with err. Unlike the conversion,
+// it updates conv, so concurrent readers of a table must serialize it.
+func writeDataRow(conv *internal.Conv, colIds []string, srcSchema schema.Table, vals []string, spTableName string, cvtCols []string, cvtVals []interface{}, err error) {
 	srcTableName := srcSchema.Name
 	srcCols := []string{}
 	for _, colId := range colIds {
 		srcCols = append(srcCols, srcSchema.ColDefs[colId].Name)
 	}
-	spTableName, cvtCols, cvtVals, err := ConvertData(conv, tableId, colIds, srcSchema, spSchema, vals, additionalAttributes)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(srcTableName, conv.DataMode())
-		conv.CollectBadRow(srcTableName, srcCols, vals)
+		conv.CollectBadRow(srcTableName, srcCols, vals, err)
 	} else {
-		conv.WriteRow(srcTableName, spTableName, cvtCols, cvtVals)
+		conv.WriteRowWithSource(srcTableName, srcCols, vals, spTableName, cvtCols, cvtVals)
 	}
 }
 
@@ -89,11 +95,9 @@ func ConvertData(conv *internal.Conv, tableId string, colIds []string, srcSchema
 		v = append(v, x)
 		c = append(c, spCol)
 	}
-	if aux, ok := conv.SyntheticPKeys[tableId]; ok {
-		c = append(c, conv.SpSchema[tableId].ColDefs[aux.ColId].Name)
-		v = append(v, fmt.Sprintf("%d", int64(bits.Reverse64(uint64(aux.Sequence)))))
-		aux.Sequence++
-		conv.SyntheticPKeys[tableId] = aux
+	if col, val, ok := conv.NextSyntheticPKey(tableId); ok {
+		c = append(c, col)
+		v = append(v, val)
 	}
 	colId := conv.SpSchema[tableId].ShardIdColumn
 	if colId != "" {
@@ -21,6 +21,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	sp "cloud.google.com/go/spanner"
 	_ "github.com/go-sql-driver/mysql" // The driver should be used via the database/sql package.
@@ -72,11 +73,84 @@ func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string)
 	// Ideally we would pass schema/name as a query parameter,
 	// but MySQL doesn't support this. So we quote it instead.
 	colNameList := buildColNameList(srcSchema, srcCols)
-	q := fmt.Sprintf("SELECT %s FROM `%s`.`%s`;", colNameList, isi.DbName, srcSchema.Name)
-	rows, err := isi.Db.Query(q)
+	clause, args := common.ReadClause(conv, tableId, queryDialect(srcSchema))
+	q := fmt.Sprintf("SELECT %s FROM `%s`.`%s`%s;", colNameList, isi.DbName, srcSchema.Name, clause)
+	rows, err := isi.Db.Query(q, args...)
 	return rows, err
 }
 
+// ReadPrimaryKeys implements the common.PrimaryKeyReader interface. The row
+// filter of the table, if any, is applied, so that the keys of filtered rows
+// aren't reported.
+func (isi InfoSchemaImpl) ReadPrimaryKeys(conv *internal.Conv, tableId string, cols []string, limit int64) ([][]string, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	d := queryDialect(srcSchema)
+	var quoted []string
+	for _, c := range cols {
+		quoted = append(quoted, d.QuoteCol(c))
+	}
+	colList := strings.Join(quoted, ", ")
+	q := fmt.Sprintf("SELECT %s FROM `%s`.`%s`", colList, isi.DbName, srcSchema.Name)
+	if srcSchema.RowFilter != "" {
+		q += " WHERE (" + srcSchema.RowFilter + ")"
+	}
+	q += " ORDER BY " + colList
+	if limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := isi.Db.Query(q + ";")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	v, iv := buildVals(len(cols))
+	var keys [][]string
+	for rows.Next() {
+		if err := rows.Scan(iv...); err != nil {
+			return nil, err
+		}
+		keys = append(keys, valsToStrings(v))
+	}
+	return keys, rows.Err()
+}
+
+// queryDialect returns the dialect for building clauses over the rows of
+// srcSchema. Key columns are qualified with the table name, so that ORDER BY
+// doesn't pick up aliases of converted columns from the select list.
+func queryDialect(srcSchema schema.Table) common.QueryDialect {
+	return common.QueryDialect{
+		QuoteCol:    func(col string) string { return fmt.Sprintf("`%s`.`%s`", srcSchema.Name, col) },
+		Placeholder: func(i int) string { return "?" },
+		IsInteger: func(srcType schema.Type) bool {
+			switch strings.ToLower(srcType.Name) {
+			case "tinyint", "smallint", "mediumint", "integer", "int", "bigint":
+				return true
+			}
+			return false
+		},
+		HashText: func(col string, srcType schema.Type, spType ddl.Type) (string, bool) {
+			if len(srcType.ArrayBounds) > 0 || spType.IsArray {
+				return "", false
+			}
+			switch t := strings.ToLower(srcType.Name); {
+			case spType.Name == ddl.Int64 && (t == "tinyint" || t == "smallint" || t == "mediumint" || t == "integer" || t == "int" || t == "bigint"):
+				return "CAST(" + col + " AS CHAR)", true
+			case spType.Name == ddl.String && (t == "varchar" || t == "tinytext" || t == "text" || t == "mediumtext" || t == "longtext"):
+				return col, true
+			case spType.Name == ddl.Date && t == "date":
+				return "DATE_FORMAT(" + col + ", '%Y-%m-%d')", true
+			}
+			return "", false
+		},
+		Hash: common.HashDialect{
+			Concat: common.ConcatFunction,
+			Hash: func(text string) string {
+				return "CAST(CONV(SUBSTRING(MD5(CONVERT(" + text + " USING utf8mb4)), 1, 6), 16, 10) AS UNSIGNED)"
+			},
+		},
+	}
+}
+
 // Building list of column names to support mysql spatial datatypes instead of
 // using 'SELECT *' because spatial columns will be fetched using ST_AsText(colName).
 func buildColNameList(srcSchema schema.Table, srcColName []string) string {
@@ -100,6 +174,58 @@ func buildColNameList(srcSchema schema.Table, srcColName []string) string {
 // ProcessData performs data conversion for source database.
 func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable, additionalAttributes internal.AdditionalDataAttributes) error {
 	srcTableName := conv.SrcSchema[tableId].Name
+	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
+	processRows := func(rows *sql.Rows, mutex *sync.Mutex) {
+		srcCols, _ := rows.Columns()
+		v, scanArgs := buildVals(len(srcCols))
+		for rows.Next() {
+			// get RawBytes from data.
+			err := rows.Scan(scanArgs...)
+			if err != nil {
+				mutex.Lock()
+				conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
+				// Scan failed, so we don't have any data to add to bad rows.
+				conv.StatsAddBadRow(srcTableName, conv.DataMode())
+				mutex.Unlock()
+				continue
+			}
+			values := valsToStrings(v)
+			newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
+			if err != nil {
+				mutex.Lock()
+				conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+				conv.StatsAddBadRow(srcTableName, conv.DataMode())
+				conv.CollectBadRow(srcTableName, srcCols, values, err)
+				mutex.Unlock()
+				continue
+			}
+			// Convert the row without holding the lock, so that the readers
+			// of a table's key ranges only serialize their updates of conv.
+			spTableName, cvtCols, cvtVals, err := ConvertData(conv, tableId, commonColIds, srcSchema, spSchema, newValues, additionalAttributes)
+			mutex.Lock()
+			writeDataRow(conv, commonColIds, srcSchema, newValues, spTableName, cvtCols, cvtVals, err)
+			mutex.Unlock()
+		}
+	}
+	// If a read parallelism is configured for the table in the source
+	// profile, read it with concurrent queries over ranges of its primary key.
+	if parallelism := profiles.GetReadParallelism(isi.SourceProfile, srcTableName); parallelism > 1 {
+		table := fmt.Sprintf("`%s`.`%s`", isi.DbName, srcSchema.Name)
+		query := func(where string, args []interface{}) (*sql.Rows, error) {
+			var srcCols []string
+			for _, srcColId := range srcSchema.ColIds {
+				srcCols = append(srcCols, srcSchema.ColDefs[srcColId].Name)
+			}
+			q := fmt.Sprintf("SELECT %s FROM %s%s;", buildColNameList(srcSchema, srcCols), table, where)
+			return isi.Db.Query(q, args...)
+		}
+		if ok, err := common.ReadKeyRanges(conv, tableId, parallelism, isi.Db, table, queryDialect(srcSchema), query, processRows); ok {
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
+			}
+			return err
+		}
+	}
 	rowsInterface, err := isi.GetRowsFromTable(conv, tableId)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
@@ -107,31 +233,29 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSc
 	}
 	rows := rowsInterface.(*sql.Rows)
 	defer rows.Close()
-	srcCols, _ := rows.Columns()
-	v, scanArgs := buildVals(len(srcCols))
-	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
-	for rows.Next() {
-		// get RawBytes from data.
-		err := rows.Scan(scanArgs...)
-		if err != nil {
-			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
-			// Scan failed, so we don't have any data to add to bad rows.
-			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			continue
-		}
-		values := valsToStrings(v)
+	processRows(rows, &sync.Mutex{})
+	return nil
+}
 
-		newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
-		if err != nil {
-			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
-			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, values)
-			continue
-		}
+// HashableColumns returns the columns whose values can be hashed in the
+// database (see common.KeyRangeHasher).
+func (isi InfoSchemaImpl) HashableColumns(conv *internal.Conv, tableId string, colIds []string) []string {
+	return common.HashableColumns(conv, tableId, colIds, queryDialect(conv.SrcSchema[tableId]))
+}
 
-		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues, additionalAttributes)
-	}
-	return nil
+// HashKeyRanges counts and hashes the rows of a table in ranges of an
+// integer column in the database (see common.KeyRangeHasher).
+func (isi InfoSchemaImpl) HashKeyRanges(conv *internal.Conv, tableId, colId string, bounds []int64, keyColIds, hashColIds []string) ([]common.KeyRangeSummary, bool, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	table := fmt.Sprintf("`%s`.`%s`", isi.DbName, srcSchema.Name)
+	return common.HashKeyRanges(conv, tableId, colId, bounds, keyColIds, hashColIds, isi.Db, table, queryDialect(srcSchema))
+}
+
+// CountFilteredRows counts the rows of a table skipped by its row filter in
+// the database (see common.FilteredRowCounter).
+func (isi InfoSchemaImpl) CountFilteredRows(conv *internal.Conv, tableId string) (int64, error) {
+	table := fmt.Sprintf("`%s`.`%s`", isi.DbName, conv.SrcSchema[tableId].Name)
+	return common.CountFilteredRows(conv, tableId, isi.Db, table)
 }
 
 // GetRowCount with number of rows in each table.
@@ -176,9 +300,10 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 
 // GetColumns returns a list of Column objects and names// ProcessColumns
 func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
-	q := `SELECT c.column_name, c.data_type, c.column_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale, c.extra
-              FROM information_schema.COLUMNS c
-              where table_schema = ? and table_name = ? ORDER BY c.ordinal_position;`
+	q, err := isi.getColumnsDQL()
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get schema for table %s.%s: %s", table.Schema, table.Name, err)
+	}
 	cols, err := isi.Db.Query(q, table.Schema, table.Name)
 	if err != nil {
 		return nil, nil, fmt.Errorf("couldn't get schema for table %s.%s: %s", table.Schema, table.Name, err)
@@ -187,11 +312,11 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 	colDefs := make(map[string]schema.Column)
 	var colIds []string
 	var colName, dataType, isNullable, columnType string
-	var colDefault, colExtra sql.NullString
+	var colDefault, colExtra, colGeneration sql.NullString
 	var charMaxLen, numericPrecision, numericScale sql.NullInt64
 	var colAutoGen ddl.AutoGenCol
 	for cols.Next() {
-		err := cols.Scan(&colName, &dataType, &columnType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &colExtra)
+		err := cols.Scan(&colName, &dataType, &columnType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &colExtra, &colGeneration)
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
 			continue
@@ -228,6 +353,20 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 			}
 		}
 
+		// Generated columns have "STORED GENERATED" or "VIRTUAL GENERATED"
+		// extra and their expression in generation_expression.
+		var generated ddl.GeneratedColumn
+		if strings.HasSuffix(colExtra.String, " GENERATED") && colGeneration.String != "" {
+			generated = ddl.GeneratedColumn{
+				IsPresent: true,
+				Value: ddl.Expression{
+					ExpressionId: internal.GenerateExpressionId(),
+					Statement:    common.SanitizeDefaultValue(colGeneration.String, dataType, true),
+				},
+				Stored: strings.HasPrefix(colExtra.String, "STORED"),
+			}
+		}
+
 		c := schema.Column{
 			Id:           colId,
 			Name:         colName,
@@ -236,6 +375,7 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 			Ignored:      ignored,
 			AutoGen:      colAutoGen,
 			DefaultValue: defaultVal,
+			Generated:    generated,
 		}
 		colDefs[colId] = c
 		colIds = append(colIds, colId)
@@ -243,6 +383,25 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 	return colDefs, colIds, nil
 }
 
+func (isi InfoSchemaImpl) getColumnsDQL() (string, error) {
+	var columnExistsCount int
+	// check if the generation_expression column exists.
+	checkQuery := `SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`
+	err := isi.Db.QueryRow(checkQuery).Scan(&columnExistsCount)
+	if err != nil {
+		return "", err
+	}
+	// mysql version 5.7 and above has generated columns. Older versions have
+	// none, so their generation expression is always NULL.
+	generationExpression := "NULL"
+	if columnExistsCount > 0 {
+		generationExpression = "c.generation_expression"
+	}
+	return fmt.Sprintf(`SELECT c.column_name, c.data_type, c.column_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale, c.extra, %s
+              FROM information_schema.COLUMNS c
+              where table_schema = ? and table_name = ? ORDER BY c.ordinal_position;`, generationExpression), nil
+}
+
 // GetConstraints returns a list of primary keys and by-column map of
 // other constraints.  Note: we need to preserve ordinal order of
 // columns in primary key constraints.
@@ -475,6 +634,36 @@ func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAnd
 	return indexes, nil
 }
 
+// GetSourceObjects returns the views, triggers, stored procedures and
+// functions of the database.
+func (isi InfoSchemaImpl) GetSourceObjects(conv *internal.Conv) ([]schema.SourceObject, error) {
+	q := `SELECT 'VIEW', TABLE_NAME, VIEW_DEFINITION, '', ''
+			FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA = ?
+		UNION ALL
+		SELECT 'TRIGGER', TRIGGER_NAME, ACTION_STATEMENT, EVENT_OBJECT_TABLE, EVENT_MANIPULATION
+			FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA = ?
+		UNION ALL
+		SELECT ROUTINE_TYPE, ROUTINE_NAME, ROUTINE_DEFINITION, '', ''
+			FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA = ?;`
+	rows, err := isi.Db.Query(q, isi.DbName, isi.DbName, isi.DbName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var kind, name, table, event string
+	// Bodies are NULL when the user lacks the privileges to read them.
+	var body sql.NullString
+	var objects []schema.SourceObject
+	for rows.Next() {
+		if err := rows.Scan(&kind, &name, &body, &table, &event); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		objects = append(objects, schema.SourceObject{Kind: kind, Schema: isi.DbName, Name: name, Body: body.String, Table: table, Event: event})
+	}
+	return objects, nil
+}
+
 // StartChangeDataCapture is used for automatic triggering of Datastream job when
 // performing a streaming migration.
 func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
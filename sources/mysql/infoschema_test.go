@@ -81,14 +81,22 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 				{"test", "ref", "id", "fk_test", constants.FK_SET_NULL, constants.FK_CASCADE},
 			},
 		},
+		{
+			query: regexp.QuoteMeta(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`),
+			args:  nil,
+			cols:  []string{"count"},
+			rows: [][]driver.Value{
+				{int64(1)},
+			},
+		},
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "user"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "generation_expression"},
 			rows: [][]driver.Value{
-				{"user_id", "text", "text", "NO", "uuid()", nil, nil, nil, constants.DEFAULT_GENERATED},
-				{"name", "text", "text", "NO", "default_name", nil, nil, nil, nil},
-				{"ref", "bigint", "bigint", "NO", nil, nil, nil, nil, nil}},
+				{"user_id", "text", "text", "NO", "uuid()", nil, nil, nil, constants.DEFAULT_GENERATED, nil},
+				{"name", "text", "text", "NO", "default_name", nil, nil, nil, nil, nil},
+				{"ref", "bigint", "bigint", "NO", nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -122,14 +130,22 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 				{"user", "userid", "user_id", "fk_test3", constants.FK_RESTRICT, constants.FK_SET_NULL},
 			},
 		},
+		{
+			query: regexp.QuoteMeta(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`),
+			args:  nil,
+			cols:  []string{"count"},
+			rows: [][]driver.Value{
+				{int64(1)},
+			},
+		},
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "cart"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "generation_expression"},
 			rows: [][]driver.Value{
-				{"productid", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"userid", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"quantity", "bigint", "bigint", "YES", nil, nil, 64, 0, nil},
+				{"productid", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"userid", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"quantity", "bigint", "bigint", "YES", nil, nil, 64, 0, nil, nil},
 			},
 		},
 		// db call to fetch index happens after fetching of column
@@ -166,13 +182,21 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 			args:  []driver.Value{"test", "product"},
 			cols:  []string{"REFERENCED_TABLE_NAME", "COLUMN_NAME", "REFERENCED_COLUMN_NAME", "CONSTRAINT_NAME", "DELETE_RULE", "UPDATE_RULE"},
 		},
+		{
+			query: regexp.QuoteMeta(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`),
+			args:  nil,
+			cols:  []string{"count"},
+			rows: [][]driver.Value{
+				{int64(1)},
+			},
+		},
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "product"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "generation_expression"},
 			rows: [][]driver.Value{
-				{"product_id", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"product_name", "text", "text", "NO", nil, nil, nil, nil, nil},
+				{"product_id", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"product_name", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
 			},
 		},
 		// db call to fetch index happens after fetching of column
@@ -204,31 +228,39 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 				{"test_ref", "txt", "ref_txt", "fk_test4", constants.FK_CASCADE, constants.FK_RESTRICT},
 			},
 		},
+		{
+			query: regexp.QuoteMeta(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`),
+			args:  nil,
+			cols:  []string{"count"},
+			rows: [][]driver.Value{
+				{int64(1)},
+			},
+		},
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "test"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
-			rows: [][]driver.Value{
-				{"id", "bigint", "bigint", "NO", nil, nil, 64, 0, nil},
-				{"s", "set", "set", "YES", nil, nil, nil, nil, nil},
-				{"txt", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"b", "boolean", "boolean", "YES", nil, nil, nil, nil, nil},
-				{"bs", "bigint", "bigint", "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0, nil},
-				{"bl", "blob", "blob", "YES", nil, nil, nil, nil, nil},
-				{"c", "char", "char(1)", "YES", nil, 1, nil, nil, nil},
-				{"c8", "char", "char(8)", "YES", nil, 8, nil, nil, nil},
-				{"d", "date", "date", "YES", nil, nil, nil, nil, nil},
-				{"dec", "decimal", "decimal(20,5)", "YES", nil, nil, 20, 5, nil},
-				{"f8", "double", "double", "YES", nil, nil, 53, nil, nil},
-				{"f4", "float", "float", "YES", nil, nil, 24, nil, nil},
-				{"i8", "bigint", "bigint", "YES", nil, nil, 64, 0, nil},
-				{"i4", "integer", "integer", "YES", nil, nil, 32, 0, "auto_increment"},
-				{"i2", "smallint", "smallint", "YES", nil, nil, 16, 0, nil},
-				{"si", "integer", "integer", "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0, nil},
-				{"ts", "datetime", "datetime", "YES", nil, nil, nil, nil, nil},
-				{"tz", "timestamp", "timestamp", "YES", nil, nil, nil, nil, nil},
-				{"vc", "varchar", "varchar", "YES", nil, nil, nil, nil, nil},
-				{"vc6", "varchar", "varchar(6)", "YES", nil, 6, nil, nil, nil},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "generation_expression"},
+			rows: [][]driver.Value{
+				{"id", "bigint", "bigint", "NO", nil, nil, 64, 0, nil, nil},
+				{"s", "set", "set", "YES", nil, nil, nil, nil, nil, nil},
+				{"txt", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"b", "boolean", "boolean", "YES", nil, nil, nil, nil, nil, nil},
+				{"bs", "bigint", "bigint", "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0, nil, nil},
+				{"bl", "blob", "blob", "YES", nil, nil, nil, nil, nil, nil},
+				{"c", "char", "char(1)", "YES", nil, 1, nil, nil, nil, nil},
+				{"c8", "char", "char(8)", "YES", nil, 8, nil, nil, nil, nil},
+				{"d", "date", "date", "YES", nil, nil, nil, nil, nil, nil},
+				{"dec", "decimal", "decimal(20,5)", "YES", nil, nil, 20, 5, nil, nil},
+				{"f8", "double", "double", "YES", nil, nil, 53, nil, nil, nil},
+				{"f4", "float", "float", "YES", nil, nil, 24, nil, nil, nil},
+				{"i8", "bigint", "bigint", "YES", nil, nil, 64, 0, nil, nil},
+				{"i4", "integer", "integer", "YES", nil, nil, 32, 0, "auto_increment", nil},
+				{"i2", "smallint", "smallint", "YES", nil, nil, 16, 0, nil, nil},
+				{"si", "integer", "integer", "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0, nil, nil},
+				{"ts", "datetime", "datetime", "YES", nil, nil, nil, nil, nil, nil},
+				{"tz", "timestamp", "timestamp", "YES", nil, nil, nil, nil, nil, nil},
+				{"vc", "varchar", "varchar", "YES", nil, nil, nil, nil, nil, nil},
+				{"vc6", "varchar", "varchar(6)", "YES", nil, 6, nil, nil, nil, nil},
 			},
 		},
 		// db call to fetch index happens after fetching of column
@@ -259,14 +291,22 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 			args:  []driver.Value{"test", "test_ref"},
 			cols:  []string{"REFERENCED_TABLE_NAME", "COLUMN_NAME", "REFERENCED_COLUMN_NAME", "CONSTRAINT_NAME", "DELETE_RULE", "UPDATE_RULE"},
 		},
+		{
+			query: regexp.QuoteMeta(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`),
+			args:  nil,
+			cols:  []string{"count"},
+			rows: [][]driver.Value{
+				{int64(1)},
+			},
+		},
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "test_ref"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "generation_expression"},
 			rows: [][]driver.Value{
-				{"ref_id", "bigint", "bigint", "NO", nil, nil, 64, 0, nil},
-				{"ref_txt", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"abc", "text", "text", "NO", nil, nil, nil, nil, nil},
+				{"ref_id", "bigint", "bigint", "NO", nil, nil, 64, 0, nil, nil},
+				{"ref_txt", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"abc", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
 			},
 		},
 		// db call to fetch index happens after fetching of column
@@ -275,6 +315,42 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 			args:  []driver.Value{"test", "test_ref"},
 			cols:  []string{"INDEX_NAME", "COLUMN_NAME", "SEQ_IN_INDEX", "COLLATION", "NON_UNIQUE"},
 		},
+		// Row counts of the tables linked by foreign keys.
+		{
+			query: "SELECT COUNT[(][*][)] FROM `test`.`user`",
+			cols:  []string{"count"},
+			rows:  [][]driver.Value{{10}},
+		},
+		{
+			query: "SELECT COUNT[(][*][)] FROM `test`.`cart`",
+			cols:  []string{"count"},
+			rows:  [][]driver.Value{{50}},
+		},
+		{
+			query: "SELECT COUNT[(][*][)] FROM `test`.`product`",
+			cols:  []string{"count"},
+			rows:  [][]driver.Value{{20}},
+		},
+		{
+			query: "SELECT COUNT[(][*][)] FROM `test`.`test`",
+			cols:  []string{"count"},
+			rows:  [][]driver.Value{{5}},
+		},
+		{
+			query: "SELECT COUNT[(][*][)] FROM `test`.`test_ref`",
+			cols:  []string{"count"},
+			rows:  [][]driver.Value{{3}},
+		},
+		{
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.VIEWS (.+) UNION ALL (.+) FROM INFORMATION_SCHEMA.TRIGGERS (.+) UNION ALL (.+) FROM INFORMATION_SCHEMA.ROUTINES (.+)",
+			args:  []driver.Value{"test", "test", "test"},
+			cols:  []string{"kind", "name", "body", "table", "event"},
+			rows: [][]driver.Value{
+				{"VIEW", "user_carts", "select `test`.`user`.`name` from `test`.`user` join `test`.`cart`", "", ""},
+				{"TRIGGER", "cart_audit", "INSERT INTO test_ref VALUES (NEW.quantity)", "cart", "INSERT"},
+				{"PROCEDURE", "clear_cart", "BEGIN\n  DELETE FROM cart WHERE userid = uid;\nEND", "", ""},
+			},
+		},
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
@@ -341,6 +417,16 @@ func TestProcessSchemaMYSQL(t *testing.T) {
 			ForeignKeys: []schema.ForeignKey{schema.ForeignKey{Name: "fk_test", ColIds: []string{"ref"}, ReferTableId: "test", ReferColumnIds: []string{"id"}, OnUpdate: constants.FK_CASCADE, OnDelete: constants.FK_SET_NULL, Id: ""}},
 			Indexes:     []schema.Index(nil), Id: ""}}
 	internal.AssertSrcSchema(t, conv, expectedSchema, conv.SrcSchema)
+	assert.Equal(t, []schema.SourceObject{
+		{Kind: schema.View, Schema: "test", Name: "user_carts", Body: "select `test`.`user`.`name` from `test`.`user` join `test`.`cart`", ReferencedTables: []string{"cart", "test", "user"}},
+		{Kind: schema.Trigger, Schema: "test", Name: "cart_audit", Body: "INSERT INTO test_ref VALUES (NEW.quantity)", ReferencedTables: []string{"test_ref"}, Table: "cart", Event: "INSERT"},
+		{Kind: schema.Procedure, Schema: "test", Name: "clear_cart", Body: "BEGIN\n  DELETE FROM cart WHERE userid = uid;\nEND", ReferencedTables: []string{"cart"}},
+	}, conv.SrcObjects)
+	rowCounts := make(map[string]int64)
+	for tableId, n := range conv.SrcRowCounts {
+		rowCounts[conv.SrcSchema[tableId].Name] = n
+	}
+	assert.Equal(t, map[string]int64{"user": 10, "cart": 50, "product": 20, "test": 5, "test_ref": 3}, rowCounts)
 	assert.Equal(t, int64(0), conv.Unexpecteds())
 }
 
@@ -437,14 +523,22 @@ func TestProcessData_MultiCol(t *testing.T) {
 			args:  []driver.Value{"test", "test"},
 			cols:  []string{"REFERENCED_TABLE_NAME", "COLUMN_NAME", "REFERENCED_COLUMN_NAME", "CONSTRAINT_NAME", "DELETE_RULE", "UPDATE_RULE"},
 		},
+		{
+			query: regexp.QuoteMeta(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`),
+			args:  nil,
+			cols:  []string{"count"},
+			rows: [][]driver.Value{
+				{int64(1)},
+			},
+		},
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "test"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "generation_expression"},
 			rows: [][]driver.Value{
-				{"a", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"b", "double", "double", "YES", nil, nil, 53, nil, nil},
-				{"c", "bigint", "bigint", "YES", nil, nil, 64, 0, nil},
+				{"a", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"b", "double", "double", "YES", nil, nil, 53, nil, nil, nil},
+				{"c", "bigint", "bigint", "YES", nil, nil, 64, 0, nil, nil},
 			},
 		},
 		{
@@ -452,6 +546,11 @@ func TestProcessData_MultiCol(t *testing.T) {
 			args:  []driver.Value{"test", "test"},
 			cols:  []string{"INDEX_NAME", "COLUMN_NAME", "SEQ_IN_INDEX", "COLLATION", "NON_UNIQUE"},
 		},
+		{
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.VIEWS (.+) UNION ALL (.+) FROM INFORMATION_SCHEMA.TRIGGERS (.+) UNION ALL (.+) FROM INFORMATION_SCHEMA.ROUTINES (.+)",
+			args:  []driver.Value{"test", "test", "test"},
+			cols:  []string{"kind", "name", "body", "table", "event"},
+		},
 		{
 			query: "SELECT (.+) FROM `test`.`test`",
 			cols:  []string{"a", "b", "c"},
@@ -553,14 +652,22 @@ func TestProcessSchema_Sharded(t *testing.T) {
 			args:  []driver.Value{"test", "test"},
 			cols:  []string{"REFERENCED_TABLE_NAME", "COLUMN_NAME", "REFERENCED_COLUMN_NAME", "CONSTRAINT_NAME", "DELETE_RULE", "UPDATE_RULE"},
 		},
+		{
+			query: regexp.QuoteMeta(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`),
+			args:  nil,
+			cols:  []string{"count"},
+			rows: [][]driver.Value{
+				{int64(1)},
+			},
+		},
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"test", "test"},
-			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "generation_expression"},
 			rows: [][]driver.Value{
-				{"a", "text", "text", "NO", nil, nil, nil, nil, nil},
-				{"b", "double", "double", "YES", nil, nil, 53, nil, nil},
-				{"c", "bigint", "bigint", "YES", nil, nil, 64, 0, nil},
+				{"a", "text", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"b", "double", "double", "YES", nil, nil, 53, nil, nil, nil},
+				{"c", "bigint", "bigint", "YES", nil, nil, 64, 0, nil, nil},
 			},
 		},
 		{
@@ -568,6 +675,11 @@ func TestProcessSchema_Sharded(t *testing.T) {
 			args:  []driver.Value{"test", "test"},
 			cols:  []string{"INDEX_NAME", "COLUMN_NAME", "SEQ_IN_INDEX", "COLLATION", "NON_UNIQUE"},
 		},
+		{
+			query: "SELECT (.+) FROM INFORMATION_SCHEMA.VIEWS (.+) UNION ALL (.+) FROM INFORMATION_SCHEMA.TRIGGERS (.+) UNION ALL (.+) FROM INFORMATION_SCHEMA.ROUTINES (.+)",
+			args:  []driver.Value{"test", "test", "test"},
+			cols:  []string{"kind", "name", "body", "table", "event"},
+		},
 		{
 			query: "SELECT (.+) FROM `test`.`test`",
 			cols:  []string{"a", "b", "c"},
@@ -708,3 +820,84 @@ func TestGetConstraints_CheckConstraintsTableAbsent(t *testing.T) {
 	_, _, _, err := isi.GetConstraints(conv, common.SchemaAndName{Schema: "your_schema", Name: "your_table"})
 	assert.Error(t, err)
 }
+
+func TestGetColumns_GeneratedColumns(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: regexp.QuoteMeta(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`),
+			args:  nil,
+			cols:  []string{"count"},
+			rows: [][]driver.Value{
+				{int64(1)},
+			},
+		},
+		{
+			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
+			args:  []driver.Value{"test", "orders"},
+			cols:  []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "generation_expression"},
+			rows: [][]driver.Value{
+				{"price", "bigint", "bigint", "NO", nil, nil, 64, 0, "", ""},
+				{"total", "bigint", "bigint", "YES", nil, nil, 64, 0, "STORED GENERATED", "(`price` * 2)"},
+				{"label", "varchar", "varchar(20)", "YES", nil, 20, nil, nil, "VIRTUAL GENERATED", "concat(_utf8mb4\\'#\\',`price`)"},
+			},
+		},
+	}
+	db := mkMockDB(t, ms)
+	isi := InfoSchemaImpl{Db: db}
+	conv := internal.MakeConv()
+	colDefs, colIds, err := isi.GetColumns(conv, common.SchemaAndName{Schema: "test", Name: "orders"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(colIds))
+	assert.False(t, colDefs[colIds[0]].Generated.IsPresent)
+	total := colDefs[colIds[1]]
+	assert.Equal(t, "(`price` * 2)", total.Generated.Value.Statement)
+	assert.True(t, total.Generated.Stored)
+	assert.False(t, total.DefaultValue.IsPresent)
+	label := colDefs[colIds[2]].Generated
+	assert.Equal(t, "concat('#',`price`)", label.Value.Statement)
+	assert.False(t, label.Stored)
+}
+
+func TestGetColumns_GenerationExpressionAbsent(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: regexp.QuoteMeta(`SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE (TABLE_SCHEMA = 'information_schema' OR TABLE_SCHEMA = 'INFORMATION_SCHEMA') AND TABLE_NAME = 'COLUMNS' AND COLUMN_NAME = 'GENERATION_EXPRESSION';`),
+			cols:  []string{"count"},
+			rows:  [][]driver.Value{{int64(0)}},
+		},
+		{
+			query: regexp.QuoteMeta(`SELECT c.column_name, c.data_type, c.column_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale, c.extra, NULL
+              FROM information_schema.COLUMNS c`),
+			args: []driver.Value{"test", "orders"},
+			cols: []string{"column_name", "data_type", "column_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "extra", "NULL"},
+			rows: [][]driver.Value{
+				{"price", "bigint", "bigint", "NO", nil, nil, 64, 0, "", nil},
+			},
+		},
+	}
+	db := mkMockDB(t, ms)
+	isi := InfoSchemaImpl{Db: db}
+	conv := internal.MakeConv()
+	colDefs, colIds, err := isi.GetColumns(conv, common.SchemaAndName{Schema: "test", Name: "orders"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(colIds))
+	assert.Equal(t, "price", colDefs[colIds[0]].Name)
+	assert.False(t, colDefs[colIds[0]].Generated.IsPresent)
+}
+
+func TestReadPrimaryKeys(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: regexp.QuoteMeta("SELECT `orders`.`region`, `orders`.`id` FROM `test`.`orders` WHERE (region <> 'test') ORDER BY `orders`.`region`, `orders`.`id`;"),
+			cols:  []string{"region", "id"},
+			rows:  [][]driver.Value{{"eu", 1}, {"us", 1}},
+		},
+	}
+	db := mkMockDB(t, ms)
+	conv := internal.MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{Name: "orders", Id: "t1", RowFilter: "region <> 'test'"}
+	isi := InfoSchemaImpl{DbName: "test", Db: db}
+	keys, err := isi.ReadPrimaryKeys(conv, "t1", []string{"region", "id"}, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"eu", "1"}, {"us", "1"}}, keys)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
 	"github.com/pingcap/tidb/parser"
 	"github.com/pingcap/tidb/parser/ast"
 	"github.com/pingcap/tidb/parser/format"
@@ -37,6 +38,9 @@ import (
 var valuesRegexp = regexp.MustCompile("\\((.*?)\\)")
 var insertRegexp = regexp.MustCompile("INSERT\\sINTO\\s(.*?)\\sVALUES\\s")
 var unsupportedRegexp = regexp.MustCompile("function|procedure|trigger")
+var versionCommentRegexp = regexp.MustCompile(`/\*!\d*\s*|\s*\*/`)
+var storedProgramRegexp = regexp.MustCompile("(?is)\\bcreate\\b.*?\\b(trigger|procedure|function)\\s+(?:(?:`[^`]+`|\\w+)\\.)?(`[^`]+`|\\w+)")
+var triggerEventRegexp = regexp.MustCompile("(?is)\\b(?:before|after)\\s+(insert|update|delete)\\s+on\\s+(?:(?:`[^`]+`|\\w+)\\.)?(`[^`]+`|\\w+)")
 var dbcollationRegex = regexp.MustCompile("_[_A-Za-z0-9]+('([^']*)')")
 
 // MysqlSpatialDataTypes is an array of all MySQL spatial data types.
@@ -566,6 +570,17 @@ func updateColsByOption(conv *internal.Conv, tableName string, col *ast.ColumnDe
 			cc.isUniqueKey = true
 		case ast.ColumnOptionCheck:
 			column.Ignored.Check = true
+		case ast.ColumnOptionGenerated:
+			if expr := expressionToString(elem.Expr); expr != "" {
+				column.Generated = ddl.GeneratedColumn{
+					IsPresent: true,
+					Value: ddl.Expression{
+						ExpressionId: internal.GenerateExpressionId(),
+						Statement:    dbcollationRegex.ReplaceAllString(expr, "$1"),
+					},
+					Stored: elem.Stored,
+				}
+			}
 		case ast.ColumnOptionReference:
 			column := col.Name.String()
 			referTable, err := getTableName(elem.Refer.Table)
@@ -642,7 +657,13 @@ func handleParseError(conv *internal.Conv, chunk string, err error, l [][]byte)
 		if strings.Count(strings.ToLower(chunk), "delimiter") == 1 {
 			return nil, false
 		}
-		return nil, skipUnsupported(conv, strings.ToLower(chunk))
+		if !skipUnsupported(conv, strings.ToLower(chunk)) {
+			return nil, false
+		}
+		if conv.SchemaMode() {
+			addStoredProgram(conv, chunk)
+		}
+		return nil, true
 	}
 	// Check if error is due to Insert statement.
 	insertStmtPrefix := insertRegexp.FindString(chunk)
@@ -742,6 +763,38 @@ func skipUnsupported(conv *internal.Conv, chunk string) bool {
 	return true
 }
 
+// addStoredProgram adds the trigger, procedure or function created by chunk
+// to the source objects of conv. mysqldump wraps parts of these statements
+// in version comments e.g.
+// /*!50003 CREATE*/ /*!50017 DEFINER=`root`@`%`*/ /*!50003 TRIGGER ...*/;;
+// which are removed from the body.
+func addStoredProgram(conv *internal.Conv, chunk string) {
+	body := versionCommentRegexp.ReplaceAllString(chunk, "")
+	m := storedProgramRegexp.FindStringSubmatch(body)
+	if m == nil {
+		// E.g. DROP TRIGGER.
+		return
+	}
+	var lines []string
+	for _, l := range strings.Split(body, "\n") {
+		if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(l)), "delimiter") {
+			lines = append(lines, l)
+		}
+	}
+	o := schema.SourceObject{
+		Kind: strings.ToUpper(m[1]),
+		Name: strings.Trim(m[2], "`"),
+		Body: strings.TrimSpace(strings.TrimRight(strings.TrimSpace(strings.Join(lines, "\n")), ";")),
+	}
+	if o.Kind == schema.Trigger {
+		if e := triggerEventRegexp.FindStringSubmatch(body); e != nil {
+			o.Event = strings.ToUpper(e[1])
+			o.Table = strings.Trim(e[2], "`")
+		}
+	}
+	conv.SrcObjects = append(conv.SrcObjects, o)
+}
+
 // getArrayBounds calculate array bound for only set data type
 // and we do not expect multidimensional array.
 func getArrayBounds(ft string, elem []string) []int64 {
@@ -800,7 +853,7 @@ func processInsertStmt(conv *internal.Conv, stmt *ast.InsertStmt) {
 		logStmtError(conv, stmt, fmt.Errorf("can't get column values"))
 		return
 	}
-	commonColIds := common.IntersectionOfTwoStringSlices(conv.SpSchema[tableId].ColIds, srcColIds)
+	commonColIds := common.IntersectionOfTwoStringSlices(common.WritableColumnIds(conv, tableId, conv.SpSchema[tableId].ColIds), srcColIds)
 	spSchema := conv.SpSchema[tableId]
 	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
 	for _, row := range stmt.Lists {
@@ -810,7 +863,7 @@ func processInsertStmt(conv *internal.Conv, stmt *ast.InsertStmt) {
 		if err2 != nil {
 			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 			conv.StatsAddBadRow(srcSchema.Name, conv.DataMode())
-			conv.CollectBadRow(srcSchema.Name, srcCols, values)
+			conv.CollectBadRow(srcSchema.Name, srcCols, values, err2)
 			continue
 		}
 		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues, internal.AdditionalDataAttributes{ShardId: ""})
@@ -28,6 +28,7 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/expressions_api"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/mocks"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
 	"github.com/stretchr/testify/assert"
@@ -798,6 +799,27 @@ func TestProcessMySQLDump_TimeZone(t *testing.T) {
 	assert.Equal(t, conv.TimezoneOffset, "+02:30", "Set timezone")
 }
 
+func TestProcessMySQLDump_GeneratedColumns(t *testing.T) {
+	conv, rows := runProcessMySQLDump("CREATE TABLE test (id bigint PRIMARY KEY, price bigint, " +
+		"total bigint GENERATED ALWAYS AS (price * 2) STORED, label varchar(10) AS (concat('#', `price`)) VIRTUAL);\n" +
+		"INSERT INTO test (id, price) VALUES (1, 5);\n")
+	noIssues(conv, t, "Generated columns")
+	tableId, err := internal.GetTableIdFromSpName(conv.SpSchema, "test")
+	assert.Nil(t, err)
+	generated := map[string]ddl.GeneratedColumn{}
+	for _, col := range conv.SpSchema[tableId].ColDefs {
+		col.Generated.Value.ExpressionId = ""
+		generated[col.Name] = col.Generated
+	}
+	assert.Equal(t, map[string]ddl.GeneratedColumn{
+		"id":    {},
+		"price": {},
+		"total": {IsPresent: true, Value: ddl.Expression{Statement: "`price`*2"}, Stored: true},
+		"label": {IsPresent: true, Value: ddl.Expression{Statement: "CONCAT('#', `price`)"}},
+	}, generated)
+	assert.Equal(t, []spannerData{{table: "test", cols: []string{"id", "price"}, vals: []interface{}{int64(1), int64(5)}}}, rows)
+}
+
 func TestProcessMySQLDump_DataError(t *testing.T) {
 	// Finally test data conversion errors.
 	dataErrorTests := []struct {
@@ -928,6 +950,29 @@ func TestProcessMySQLDump_AddPrimaryKeys(t *testing.T) {
 	}
 }
 
+func TestProcessMySQLDump_StoredPrograms(t *testing.T) {
+	conv, _ := runProcessMySQLDump(`
+CREATE TABLE MyTable (id int PRIMARY KEY, n int);
+CREATE TABLE audit (id int PRIMARY KEY);
+
+DELIMITER ;;
+CREATE DEFINER=` + "`root`@`localhost`" + ` PROCEDURE ` + "`clear_audit`" + `()
+BEGIN
+  DELETE FROM audit;
+END ;;
+DELIMITER ;
+
+DELIMITER ;;
+/*!50003 CREATE*/ /*!50017 DEFINER=` + "`root`@`localhost`" + `*/ /*!50003 TRIGGER ` + "`my_trigger`" + ` AFTER UPDATE ON ` + "`MyTable`" + ` FOR EACH ROW INSERT INTO audit VALUES (NEW.id) */;;
+DELIMITER ;
+`)
+	assert.Equal(t, []schema.SourceObject{
+		{Kind: schema.Procedure, Name: "clear_audit", Body: "CREATE DEFINER=`root`@`localhost` PROCEDURE `clear_audit`()\nBEGIN\n  DELETE FROM audit;\nEND", ReferencedTables: []string{"audit"}},
+		{Kind: schema.Trigger, Name: "my_trigger", Body: "CREATE DEFINER=`root`@`localhost` TRIGGER `my_trigger` AFTER UPDATE ON `MyTable` FOR EACH ROW INSERT INTO audit VALUES (NEW.id)",
+			ReferencedTables: []string{"MyTable", "audit"}, Table: "MyTable", Event: "UPDATE"},
+	}, conv.SrcObjects)
+}
+
 func runProcessMySQLDump(s string) (*internal.Conv, []spannerData) {
 	conv := internal.MakeConv()
 	conv.SetLocation(time.UTC)
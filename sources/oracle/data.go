@@ -18,7 +18,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
-	"math/bits"
 	"strconv"
 	"strings"
 	"time"
@@ -34,6 +33,13 @@ import (
 
 func ProcessDataRow(conv *internal.Conv, tableId string, colIds []string, srcSchema schema.Table, spSchema ddl.CreateTable, vals []string) {
 	spTableName, cvtCols, cvtVals, err := convertData(conv, tableId, colIds, srcSchema, spSchema, vals)
+	writeDataRow(conv, colIds, srcSchema, vals, spTableName, cvtCols, cvtVals, err)
+}
+
+// writeDataRow writes a row converted by ConvertData to Spanner, or records
+// it as a bad row if its conversion failed with err. Unlike the conversion,
+// it updates conv, so concurrent readers of a table must serialize it.
+func writeDataRow(conv *internal.Conv, colIds []string, srcSchema schema.Table, vals []string, spTableName string, cvtCols []string, cvtVals []interface{}, err error) {
 	srcTableName := srcSchema.Name
 	srcCols := []string{}
 	for _, colId := range colIds {
@@ -42,9 +48,9 @@ func ProcessDataRow(conv *internal.Conv, tableId string, colIds []string, srcSch
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(srcTableName, conv.DataMode())
-		conv.CollectBadRow(srcTableName, srcCols, vals)
+		conv.CollectBadRow(srcTableName, srcCols, vals, err)
 	} else {
-		conv.WriteRow(srcTableName, spTableName, cvtCols, cvtVals)
+		conv.WriteRowWithSource(srcTableName, srcCols, vals, spTableName, cvtCols, cvtVals)
 	}
 }
 
@@ -84,11 +90,9 @@ func convertData(conv *internal.Conv, tableId string, colIds []string, srcSchema
 		v = append(v, x)
 		c = append(c, spColDef.Name)
 	}
-	if aux, ok := conv.SyntheticPKeys[tableId]; ok {
-		c = append(c, conv.SpSchema[tableId].ColDefs[aux.ColId].Name)
-		v = append(v, fmt.Sprintf("%d", int64(bits.Reverse64(uint64(aux.Sequence)))))
-		aux.Sequence++
-		conv.SyntheticPKeys[tableId] = aux
+	if col, val, ok := conv.NextSyntheticPKey(tableId); ok {
+		c = append(c, col)
+		v = append(v, val)
 	}
 	return spSchema.Name, c, v, nil
 }
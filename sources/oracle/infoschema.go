@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
 	sp "cloud.google.com/go/spanner"
 
@@ -59,10 +60,47 @@ func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string)
 		return nil, nil
 	}
 	q := getSelectQuery(isi.DbName, tbl.Schema, tbl.Name, tbl.ColIds, tbl.ColDefs)
-	rows, err := isi.Db.Query(q)
+	clause, args := common.ReadClause(conv, tableId, queryDialect(tbl))
+	rows, err := isi.Db.Query(q+clause, args...)
 	return rows, err
 }
 
+// queryDialect returns the dialect for building clauses over the rows of
+// tbl. Key columns are qualified with the table name, so that ORDER BY
+// doesn't pick up aliases of converted columns (e.g. TO_CHAR of numbers)
+// from the select list.
+func queryDialect(tbl schema.Table) common.QueryDialect {
+	return common.QueryDialect{
+		QuoteCol:    func(col string) string { return fmt.Sprintf(`"%s"."%s"."%s"`, tbl.Schema, tbl.Name, col) },
+		Placeholder: func(i int) string { return fmt.Sprintf(":%d", i) },
+		IsInteger: func(srcType schema.Type) bool {
+			// NUMBER(p) and NUMBER(p, 0) hold integers.
+			return srcType.Name == "NUMBER" && (len(srcType.Mods) == 1 || (len(srcType.Mods) == 2 && srcType.Mods[1] == 0))
+		},
+		HashText: func(col string, srcType schema.Type, spType ddl.Type) (string, bool) {
+			if len(srcType.ArrayBounds) > 0 || spType.IsArray {
+				return "", false
+			}
+			switch {
+			case spType.Name == ddl.Int64 && srcType.Name == "NUMBER" && (len(srcType.Mods) == 1 || (len(srcType.Mods) == 2 && srcType.Mods[1] == 0)):
+				return "TO_CHAR(" + col + ")", true
+			case spType.Name == ddl.String && srcType.Name == "VARCHAR2" && len(srcType.Mods) == 1 && srcType.Mods[0] <= 2000:
+				// Longer values could exceed the maximum length of the hashed text.
+				return col, true
+			case spType.Name == ddl.Date && srcType.Name == "DATE":
+				return "TO_CHAR(" + col + ", 'YYYY-MM-DD')", true
+			}
+			return "", false
+		},
+		Hash: common.HashDialect{
+			Concat: common.ConcatOperator,
+			Hash: func(text string) string {
+				return "TO_NUMBER(SUBSTR(RAWTOHEX(STANDARD_HASH(" + text + ", 'MD5')), 1, 6), 'XXXXXX')"
+			},
+		},
+	}
+}
+
 func getSelectQuery(srcDb string, schemaName string, tableName string, colIds []string, colDefs map[string]schema.Column) string {
 	var selects = make([]string, len(colIds))
 
@@ -104,6 +142,54 @@ func getSelectQuery(srcDb string, schemaName string, tableName string, colIds []
 // ProcessData performs data conversion for source database.
 func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable, additionalAttributes internal.AdditionalDataAttributes) error {
 	srcTableName := conv.SrcSchema[tableId].Name
+	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
+	processRows := func(rows *sql.Rows, mutex *sync.Mutex) {
+		srcCols, _ := rows.Columns()
+		v, scanArgs := buildVals(len(srcCols))
+		for rows.Next() {
+			// get RawBytes from data.
+			err := rows.Scan(scanArgs...)
+			if err != nil {
+				mutex.Lock()
+				conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
+				// Scan failed, so we don't have any data to add to bad rows.
+				conv.StatsAddBadRow(srcTableName, conv.DataMode())
+				mutex.Unlock()
+				continue
+			}
+			values := valsToStrings(v)
+			newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
+			if err != nil {
+				mutex.Lock()
+				conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+				conv.StatsAddBadRow(srcTableName, conv.DataMode())
+				conv.CollectBadRow(srcTableName, srcCols, values, err)
+				mutex.Unlock()
+				continue
+			}
+			// Convert the row without holding the lock, so that the readers
+			// of a table's key ranges only serialize their updates of conv.
+			spTableName, cvtCols, cvtVals, err := convertData(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
+			mutex.Lock()
+			writeDataRow(conv, commonColIds, srcSchema, newValues, spTableName, cvtCols, cvtVals, err)
+			mutex.Unlock()
+		}
+	}
+	// If a read parallelism is configured for the table in the source
+	// profile, read it with concurrent queries over ranges of its primary key.
+	if parallelism := profiles.GetReadParallelism(isi.SourceProfile, srcTableName); parallelism > 1 {
+		table := fmt.Sprintf(`"%s"."%s"`, srcSchema.Schema, srcSchema.Name)
+		query := func(where string, args []interface{}) (*sql.Rows, error) {
+			q := getSelectQuery(isi.DbName, srcSchema.Schema, srcSchema.Name, srcSchema.ColIds, srcSchema.ColDefs)
+			return isi.Db.Query(q+where, args...)
+		}
+		if ok, err := common.ReadKeyRanges(conv, tableId, parallelism, isi.Db, table, queryDialect(srcSchema), query, processRows); ok {
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
+			}
+			return err
+		}
+	}
 	rowsInterface, err := isi.GetRowsFromTable(conv, tableId)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
@@ -111,31 +197,32 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSc
 	}
 	rows := rowsInterface.(*sql.Rows)
 	defer rows.Close()
-	srcCols, _ := rows.Columns()
-	v, scanArgs := buildVals(len(srcCols))
-	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
-	for rows.Next() {
-		// get RawBytes from data.
-		err := rows.Scan(scanArgs...)
-		if err != nil {
-			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
-			// Scan failed, so we don't have any data to add to bad rows.
-			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			continue
-		}
-		values := valsToStrings(v)
-		newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
-		if err != nil {
-			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
-			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, values)
-			continue
-		}
-		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
-	}
+	processRows(rows, &sync.Mutex{})
 	return nil
 }
 
+// HashableColumns returns the columns whose values can be hashed in the
+// database (see common.KeyRangeHasher).
+func (isi InfoSchemaImpl) HashableColumns(conv *internal.Conv, tableId string, colIds []string) []string {
+	return common.HashableColumns(conv, tableId, colIds, queryDialect(conv.SrcSchema[tableId]))
+}
+
+// HashKeyRanges counts and hashes the rows of a table in ranges of an
+// integer column in the database (see common.KeyRangeHasher).
+func (isi InfoSchemaImpl) HashKeyRanges(conv *internal.Conv, tableId, colId string, bounds []int64, keyColIds, hashColIds []string) ([]common.KeyRangeSummary, bool, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	table := fmt.Sprintf(`"%s"."%s"`, srcSchema.Schema, srcSchema.Name)
+	return common.HashKeyRanges(conv, tableId, colId, bounds, keyColIds, hashColIds, isi.Db, table, queryDialect(srcSchema))
+}
+
+// CountFilteredRows counts the rows of a table skipped by its row filter in
+// the database (see common.FilteredRowCounter).
+func (isi InfoSchemaImpl) CountFilteredRows(conv *internal.Conv, tableId string) (int64, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	table := fmt.Sprintf(`"%s"."%s"`, srcSchema.Schema, srcSchema.Name)
+	return common.CountFilteredRows(conv, tableId, isi.Db, table)
+}
+
 // GetRowCount with number of rows in each table.
 func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
 	q := fmt.Sprintf(`SELECT count(*) FROM "%s"`, table.Name)
@@ -183,10 +270,12 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 						act.elem_type_name,
 						act.length,
 						act.precision,
-						act.scale
+						act.scale,
+						vc.virtual_column
 					FROM all_tab_columns atc
 					LEFT JOIN all_types at ON atc.data_type=at.type_name AND atc.owner = at.owner
 					LEFT JOIN all_coll_types act ON atc.data_type=act.type_name AND atc.owner = at.owner
+					LEFT JOIN all_tab_cols vc ON atc.owner = vc.owner AND atc.table_name = vc.table_name AND atc.column_name = vc.column_name
 					WHERE atc.owner = '%s' AND atc.table_name = '%s'
 					`, table.Schema, table.Name)
 	cols, err := isi.Db.Query(q)
@@ -198,10 +287,10 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 	var colIds []string
 	var colName, dataType string
 	var isNullable string
-	var colDefault, typecode, elementDataType sql.NullString
+	var colDefault, typecode, elementDataType, virtualColumn sql.NullString
 	var charMaxLen, numericPrecision, numericScale, elementCharMaxLen, elementNumericPrecision, elementNumericScale sql.NullInt64
 	for cols.Next() {
-		err := cols.Scan(&colName, &dataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &typecode, &elementDataType, &elementCharMaxLen, &elementNumericPrecision, &elementNumericScale)
+		err := cols.Scan(&colName, &dataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &typecode, &elementDataType, &elementCharMaxLen, &elementNumericPrecision, &elementNumericScale, &virtualColumn)
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
 			continue
@@ -228,14 +317,25 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 			charMaxLen.Valid = false
 		}
 
+		// The data_default of a virtual column is its expression, not a
+		// default value. Oracle virtual columns are never stored.
+		var generated ddl.GeneratedColumn
+		if virtualColumn.String == "YES" && colDefault.Valid {
+			generated = ddl.GeneratedColumn{
+				IsPresent: true,
+				Value:     ddl.Expression{ExpressionId: internal.GenerateExpressionId(), Statement: strings.TrimSpace(colDefault.String)},
+			}
+			colDefault.Valid = false
+		}
 		ignored.Default = colDefault.Valid
 		colId := internal.GenerateColumnId()
 		c := schema.Column{
-			Id:      colId,
-			Name:    colName,
-			Type:    toType(dataType, typecode, elementDataType, charMaxLen, numericPrecision, numericScale, elementCharMaxLen, elementNumericPrecision, elementNumericScale),
-			NotNull: strings.ToUpper(isNullable) == "N",
-			Ignored: ignored,
+			Id:        colId,
+			Name:      colName,
+			Type:      toType(dataType, typecode, elementDataType, charMaxLen, numericPrecision, numericScale, elementCharMaxLen, elementNumericPrecision, elementNumericScale),
+			NotNull:   strings.ToUpper(isNullable) == "N",
+			Ignored:   ignored,
+			Generated: generated,
 		}
 		colDefs[colId] = c
 		colIds = append(colIds, colId)
@@ -424,6 +524,68 @@ func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAnd
 	return indexes, nil
 }
 
+// GetSourceObjects returns the views, triggers, stored procedures and
+// functions of the schema. Package bodies are returned as stored procedures.
+// The text of views and triggers is stored in LONG columns, which can't be
+// combined in a single query.
+func (isi InfoSchemaImpl) GetSourceObjects(conv *internal.Conv) ([]schema.SourceObject, error) {
+	var objects []schema.SourceObject
+	rows, err := isi.Db.Query(fmt.Sprintf("SELECT view_name, text FROM all_views WHERE owner = '%s'", isi.DbName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var name, body string
+	for rows.Next() {
+		if err := rows.Scan(&name, &body); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		objects = append(objects, schema.SourceObject{Kind: schema.View, Schema: isi.DbName, Name: name, Body: body})
+	}
+
+	rows, err = isi.Db.Query(fmt.Sprintf("SELECT trigger_name, table_name, triggering_event, trigger_body FROM all_triggers WHERE owner = '%s'", isi.DbName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var table, event sql.NullString
+	for rows.Next() {
+		if err := rows.Scan(&name, &table, &event, &body); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		objects = append(objects, schema.SourceObject{Kind: schema.Trigger, Schema: isi.DbName, Name: name, Body: body, Table: table.String,
+			Event: strings.ReplaceAll(event.String, " OR ", ", ")})
+	}
+
+	// The source of stored programs is stored line by line.
+	rows, err = isi.Db.Query(fmt.Sprintf(`SELECT name, type, text FROM all_source
+		WHERE owner = '%s' AND type IN ('PROCEDURE', 'FUNCTION', 'PACKAGE BODY')
+		ORDER BY name, type, line`, isi.DbName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ty, line string
+	for rows.Next() {
+		if err := rows.Scan(&name, &ty, &line); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		kind := schema.Procedure
+		if ty == "FUNCTION" {
+			kind = schema.Function
+		}
+		if n := len(objects) - 1; n >= 0 && objects[n].Kind == kind && objects[n].Name == name {
+			objects[n].Body += line
+			continue
+		}
+		objects = append(objects, schema.SourceObject{Kind: kind, Schema: isi.DbName, Name: name, Body: line})
+	}
+	return objects, nil
+}
+
 // StartChangeDataCapture is used for automatic triggering of Datastream job when
 // performing a streaming migration.
 func (isi InfoSchemaImpl) StartChangeDataCapture(ctx context.Context, conv *internal.Conv) (map[string]interface{}, error) {
@@ -29,6 +29,7 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/mocks"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
 )
@@ -72,11 +73,11 @@ func TestProcessSchemaOracle(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM all_tab_columns (.+)",
 			args:  []driver.Value{},
-			cols:  []string{"column_name", "data_type", "nullable", "data_default", "data_length", "data_precision", "data_scale", "typecode", "element_type", "element_length", "element_precision", "element_scale"},
+			cols:  []string{"column_name", "data_type", "nullable", "data_default", "data_length", "data_precision", "data_scale", "typecode", "element_type", "element_length", "element_precision", "element_scale", "virtual_column"},
 			rows: [][]driver.Value{
-				{"USER_ID", "VARCHAR2", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil},
-				{"NAME", "VARCHAR2", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil},
-				{"REF", "NUMBER", "Y", nil, nil, nil, nil, nil, nil, nil, nil, nil}},
+				{"USER_ID", "VARCHAR2", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"NAME", "VARCHAR2", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"REF", "NUMBER", "Y", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -110,9 +111,9 @@ func TestProcessSchemaOracle(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM all_tab_columns (.+)",
 			args:  []driver.Value{},
-			cols:  []string{"column_name", "data_type", "nullable", "data_default", "data_length", "data_precision", "data_scale", "typecode", "element_type", "element_length", "element_precision", "element_scale"},
+			cols:  []string{"column_name", "data_type", "nullable", "data_default", "data_length", "data_precision", "data_scale", "typecode", "element_type", "element_length", "element_precision", "element_scale", "virtual_column"},
 			rows: [][]driver.Value{
-				{"ID", "NUMBER", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil}},
+				{"ID", "NUMBER", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -141,19 +142,19 @@ func TestProcessSchemaOracle(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM all_tab_columns (.+)",
 			args:  []driver.Value{},
-			cols:  []string{"column_name", "data_type", "nullable", "data_default", "data_length", "data_precision", "data_scale", "typecode", "element_type", "element_length", "element_precision", "element_scale"},
+			cols:  []string{"column_name", "data_type", "nullable", "data_default", "data_length", "data_precision", "data_scale", "typecode", "element_type", "element_length", "element_precision", "element_scale", "virtual_column"},
 			rows: [][]driver.Value{
-				{"ID", "NUMBER", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil},
-				{"JSON", "VARCHAR2", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil},
-				{"REALJSON", "JSON", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil},
-				{"ARRAY_NUM", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "NUMBER", nil, 10, 5},
-				{"ARRAY_FLOAT", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "FLOAT", nil, nil, nil},
-				{"ARRAY_STRING", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "VARCHAR2", 15, nil, nil},
-				{"ARRAY_DATE", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "DATE", nil, nil, nil},
-				{"ARRAY_INT", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "NUMBER", nil, 10, 0},
-				{"OBJECT", "CONTACTS", "N", nil, nil, nil, nil, "OBJECT", nil, nil, nil, nil},
-				{"BINARY_FLOAT", "BINARY_FLOAT", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil},
-				{"ARRAY_BINARY_FLOAT", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "BINARY_FLOAT", nil, nil, nil}},
+				{"ID", "NUMBER", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"JSON", "VARCHAR2", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"REALJSON", "JSON", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"ARRAY_NUM", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "NUMBER", nil, 10, 5, nil},
+				{"ARRAY_FLOAT", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "FLOAT", nil, nil, nil, nil},
+				{"ARRAY_STRING", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "VARCHAR2", 15, nil, nil, nil},
+				{"ARRAY_DATE", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "DATE", nil, nil, nil, nil},
+				{"ARRAY_INT", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "NUMBER", nil, 10, 0, nil},
+				{"OBJECT", "CONTACTS", "N", nil, nil, nil, nil, "OBJECT", nil, nil, nil, nil, nil},
+				{"BINARY_FLOAT", "BINARY_FLOAT", "N", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+				{"ARRAY_BINARY_FLOAT", "STUDENT", "N", nil, nil, nil, nil, "COLLECTION", "BINARY_FLOAT", nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -162,6 +163,24 @@ func TestProcessSchemaOracle(t *testing.T) {
 			cols:  []string{"name", "column_name", "column_position", "descend", "uniqueness", "column_expression", "index_type"},
 			rows:  [][]driver.Value{},
 		},
+		{
+			query: "SELECT view_name, text FROM all_views (.+)",
+			cols:  []string{"view_name", "text"},
+			rows:  [][]driver.Value{{"USER_REFS", "SELECT u.name FROM \"USER\" u JOIN test t ON u.ref = t.id"}},
+		},
+		{
+			query: "SELECT (.+) FROM all_triggers (.+)",
+			cols:  []string{"trigger_name", "table_name", "triggering_event", "trigger_body"},
+			rows:  [][]driver.Value{{"TEST_BI", "TEST", "INSERT OR UPDATE", "BEGIN :new.id := test_seq.nextval; END;"}},
+		},
+		{
+			query: "SELECT (.+) FROM all_source (.+)",
+			cols:  []string{"name", "type", "text"},
+			rows: [][]driver.Value{
+				{"ADD_USER", "PROCEDURE", "PROCEDURE add_user(n VARCHAR2) IS\n"},
+				{"ADD_USER", "PROCEDURE", "BEGIN INSERT INTO \"USER\"(name) VALUES (n); END;\n"},
+			},
+		},
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
@@ -239,12 +258,45 @@ func TestProcessSchemaOracle(t *testing.T) {
 	assert.Equal(t, len(conv.SchemaIssues[userTableId].ColumnLevelIssues), 0)
 	assert.Equal(t, len(conv.SchemaIssues[testTableId].ColumnLevelIssues), 0)
 	assert.Equal(t, len(conv.SchemaIssues[test2TableId].ColumnLevelIssues), 6)
+	assert.Equal(t, []schema.SourceObject{
+		{Kind: schema.View, Schema: "test", Name: "USER_REFS", Body: "SELECT u.name FROM \"USER\" u JOIN test t ON u.ref = t.id", ReferencedTables: []string{"TEST", "USER"}},
+		{Kind: schema.Trigger, Schema: "test", Name: "TEST_BI", Body: "BEGIN :new.id := test_seq.nextval; END;", Table: "TEST", Event: "INSERT, UPDATE"},
+		{Kind: schema.Procedure, Schema: "test", Name: "ADD_USER", Body: "PROCEDURE add_user(n VARCHAR2) IS\nBEGIN INSERT INTO \"USER\"(name) VALUES (n); END;\n", ReferencedTables: []string{"USER"}},
+	}, conv.SrcObjects)
 	assert.Equal(t, int64(0), conv.Unexpecteds())
 }
 
 // stripSchemaComments returns a schema with all comments removed.
 // We mostly ignore schema comments in testing since schema comments
 // are often changed and are not a core part of conversion functionality.
+func TestGetColumns_VirtualColumns(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT (.+) FROM all_tab_columns (.+)",
+			args:  []driver.Value{},
+			cols:  []string{"column_name", "data_type", "nullable", "data_default", "data_length", "data_precision", "data_scale", "typecode", "element_type", "element_length", "element_precision", "element_scale", "virtual_column"},
+			rows: [][]driver.Value{
+				{"PRICE", "NUMBER", "N", "0 ", nil, 10, 0, nil, nil, nil, nil, nil, "NO"},
+				{"TOTAL", "NUMBER", "Y", "\"PRICE\"*2", nil, nil, nil, nil, nil, nil, nil, nil, "YES"},
+			},
+		},
+	}
+	db := mkMockDB(t, ms)
+	isi := InfoSchemaImpl{Db: db}
+	conv := internal.MakeConv()
+	colDefs, colIds, err := isi.GetColumns(conv, common.SchemaAndName{Schema: "TEST", Name: "ORDERS"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(colIds))
+	price := colDefs[colIds[0]]
+	assert.False(t, price.Generated.IsPresent)
+	assert.True(t, price.Ignored.Default)
+	total := colDefs[colIds[1]]
+	assert.True(t, total.Generated.IsPresent)
+	assert.Equal(t, "\"PRICE\"*2", total.Generated.Value.Statement)
+	assert.False(t, total.Generated.Stored)
+	assert.False(t, total.Ignored.Default)
+}
+
 func stripSchemaComments(spSchema map[string]ddl.CreateTable) map[string]ddl.CreateTable {
 	for t, ct := range spSchema {
 		for c, cd := range ct.ColDefs {
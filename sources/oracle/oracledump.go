@@ -0,0 +1,244 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oracle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+)
+
+// DbDumpImpl Oracle specific implementation for DdlDumpImpl. It reads DDL
+// scripts, e.g. the output of DBMS_METADATA.GET_DDL (with the
+// SQLTERMINATOR transform parameter set) or a Data Pump SQLFILE.
+type DbDumpImpl struct {
+}
+
+// GetToDdl function below implement the common.DbDump interface.
+func (ddi DbDumpImpl) GetToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+// ProcessDump processes an Oracle DDL script. Scripts only contain the
+// schema, so they can't be used for data conversion.
+func (ddi DbDumpImpl) ProcessDump(conv *internal.Conv, r *internal.Reader) error {
+	if !conv.SchemaMode() {
+		return fmt.Errorf("Oracle DDL scripts don't contain data: use a direct connection to migrate data")
+	}
+	for {
+		startLine := r.LineNumber
+		toks, err := readStatement(r)
+		if err != nil {
+			return fmt.Errorf("error reading statement at line %d: %w", startLine, err)
+		}
+		if len(toks) > 0 {
+			logger.Log.Debug(fmt.Sprintf("Parsed %s statement at line=%d", common.ScriptStatementType(toks), startLine))
+			common.ProcessScriptStatement(conv, toks, scriptDialect{})
+		}
+		if r.EOF {
+			break
+		}
+	}
+	common.ResolveScriptForeignKeys(conv)
+	return nil
+}
+
+// sqlPlusCommands are the SQL*Plus commands that can appear in DDL scripts.
+// They take up a single line and have no terminator.
+var sqlPlusCommands = map[string]bool{
+	"SET": true, "PROMPT": true, "REM": true, "REMARK": true, "SPOOL": true,
+	"WHENEVER": true, "CONNECT": true, "EXIT": true, "QUIT": true, "SHOW": true,
+}
+
+// plsqlStatements are the types of statements that have PL/SQL bodies. Such
+// statements contain semicolons and are terminated by a line containing a
+// single slash.
+var plsqlStatements = map[string]bool{
+	"CREATE PROCEDURE": true, "CREATE FUNCTION": true, "CREATE PACKAGE": true, "CREATE TRIGGER": true,
+	"CREATE TYPE": true, "CREATE LIBRARY": true, "CREATE JAVA": true, "BEGIN": true, "DECLARE": true,
+}
+
+// readStatement reads the next statement of an Oracle DDL script and returns
+// its tokens, without its terminator. Statements are terminated by a
+// semicolon or by a line containing a single slash. SQL*Plus commands are
+// skipped.
+func readStatement(r *internal.Reader) ([]common.ScriptToken, error) {
+	var b strings.Builder
+	for {
+		line := string(r.ReadLine())
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "/":
+			return common.TokenizeScript(b.String(), false)
+		case isSQLPlusCommand(trimmed) && isBlank(b.String()):
+			b.Reset()
+		default:
+			b.WriteString(line)
+		}
+		if strings.Contains(line, ";") || r.EOF {
+			toks, err := common.TokenizeScript(b.String(), false)
+			switch {
+			case err != nil && r.EOF:
+				return nil, err
+			case err != nil, plsqlStatements[common.ScriptStatementType(toks)] && !r.EOF:
+				// The semicolon is inside a string, a comment or a PL/SQL body.
+				continue
+			case len(toks) > 0 && toks[len(toks)-1].IsPunct(";"):
+				return toks[:len(toks)-1], nil
+			case r.EOF:
+				return toks, nil
+			}
+		}
+	}
+}
+
+func isSQLPlusCommand(line string) bool {
+	word := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+	return sqlPlusCommands[word] || strings.HasPrefix(line, "@")
+}
+
+// isBlank reports whether s only contains whitespace and comments.
+func isBlank(s string) bool {
+	toks, err := common.TokenizeScript(s, false)
+	return err == nil && len(toks) == 0
+}
+
+// scriptDialect implements common.ScriptDialect for Oracle.
+type scriptDialect struct{}
+
+// Ident returns the name of an identifier. Oracle stores the names of
+// unquoted identifiers in upper case.
+func (scriptDialect) Ident(t common.ScriptToken) string {
+	if t.Kind == common.WordToken {
+		return strings.ToUpper(t.Text)
+	}
+	return t.Text
+}
+
+// TableName drops the schema of a table, like InfoSchemaImpl.GetTableName.
+func (scriptDialect) TableName(schemaName, name string) string {
+	return name
+}
+
+// oracleTypeSynonyms maps ANSI data types to the Oracle data types they are
+// stored as.
+var oracleTypeSynonyms = map[string]string{
+	"INTEGER": "NUMBER", "INT": "NUMBER", "SMALLINT": "NUMBER", "DECIMAL": "NUMBER", "DEC": "NUMBER",
+	"NUMERIC": "NUMBER", "REAL": "FLOAT", "CHARACTER": "CHAR",
+}
+
+// ColumnType parses an Oracle data type into the same schema.Type that
+// InfoSchemaImpl.GetColumns builds from the data dictionary, e.g.
+// TIMESTAMP(6) WITH TIME ZONE or NUMBER(10).
+func (scriptDialect) ColumnType(p *common.ScriptParser) (schema.Type, error) {
+	t := p.Next()
+	if t.Kind == common.QuotedIdentToken {
+		// A user-defined type, possibly qualified by its schema.
+		name := t.Text
+		for p.AcceptPunct(".") {
+			name = p.Next().Text
+		}
+		return schema.Type{Name: name}, nil
+	}
+	if t.Kind != common.WordToken {
+		return schema.Type{}, fmt.Errorf("expected a data type, found '%s'", t.Text)
+	}
+	name := strings.ToUpper(t.Text)
+	switch name {
+	case "TIMESTAMP":
+		name = fmt.Sprintf("TIMESTAMP(%d)", typePrecision(p, 6))
+		switch {
+		case p.Accept("WITH", "TIME", "ZONE"):
+			name += " WITH TIME ZONE"
+		case p.Accept("WITH", "LOCAL", "TIME", "ZONE"):
+			name += " WITH LOCAL TIME ZONE"
+		}
+		return schema.Type{Name: name}, nil
+	case "INTERVAL":
+		switch {
+		case p.Accept("YEAR"):
+			name = fmt.Sprintf("INTERVAL YEAR(%d) TO MONTH", typePrecision(p, 2))
+			p.Accept("TO", "MONTH")
+		case p.Accept("DAY"):
+			precision := typePrecision(p, 2)
+			p.Accept("TO", "SECOND")
+			name = fmt.Sprintf("INTERVAL DAY(%d) TO SECOND(%d)", precision, typePrecision(p, 6))
+		}
+		return schema.Type{Name: name}, nil
+	case "LONG":
+		if p.Accept("RAW") {
+			name = "LONG RAW"
+		}
+	case "DOUBLE":
+		p.Accept("PRECISION")
+		name = "FLOAT"
+	case "CHARACTER", "CHAR":
+		if p.Accept("VARYING") {
+			name = "VARCHAR2"
+		}
+	case "NCHAR":
+		if p.Accept("VARYING") {
+			name = "NVARCHAR2"
+		}
+	}
+	if synonym, ok := oracleTypeSynonyms[name]; ok {
+		name = synonym
+	}
+	ty := schema.Type{Name: name}
+	if g, ok := p.Group(); ok {
+		for _, item := range g.List() {
+			// Length semantics (BYTE or CHAR) don't matter for Spanner.
+			if n, err := strconv.ParseInt(item.Peek().Text, 10, 64); err == nil {
+				ty.Mods = append(ty.Mods, n)
+			} else if item.Peek().IsPunct("*") && name == "NUMBER" {
+				// NUMBER(*, s): the data dictionary has no precision.
+				return schema.Type{Name: name}, nil
+			}
+		}
+	}
+	// The data dictionary has a scale of 0 for integers, which
+	// InfoSchemaImpl.GetColumns drops.
+	if name == "NUMBER" && len(ty.Mods) == 2 && ty.Mods[1] == 0 {
+		ty.Mods = ty.Mods[:1]
+	}
+	return ty, nil
+}
+
+// typePrecision parses the optional precision of a data type, e.g. the 3 of
+// TIMESTAMP(3).
+func typePrecision(p *common.ScriptParser, def int64) int64 {
+	if g, ok := p.Group(); ok {
+		if n, err := strconv.ParseInt(g.Peek().Text, 10, 64); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// JSONCheck reports whether a check constraint is an IS JSON condition,
+// which InfoSchemaImpl.GetColumns also converts to the JSON type.
+func (scriptDialect) JSONCheck(expr []common.ScriptToken) bool {
+	for i := 0; i+1 < len(expr); i++ {
+		if expr[i].IsWord("IS") && expr[i+1].IsWord("JSON") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oracle
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+)
+
+const oracleDDLScript = `
+-- CONNECT HR
+ALTER SESSION SET EVENTS '10150 TRACE NAME CONTEXT FOREVER, LEVEL 1';
+SET DEFINE OFF
+
+  CREATE TABLE "HR"."DEPARTMENTS"
+   (	"DEPARTMENT_ID" NUMBER(4,0),
+	"DEPARTMENT_NAME" VARCHAR2(30 BYTE) CONSTRAINT "DEPT_NAME_NN" NOT NULL ENABLE,
+	 CONSTRAINT "DEPT_ID_PK" PRIMARY KEY ("DEPARTMENT_ID")
+  USING INDEX PCTFREE 10 INITRANS 2 MAXTRANS 255 TABLESPACE "USERS"  ENABLE
+   ) SEGMENT CREATION IMMEDIATE
+  PCTFREE 10 PCTUSED 40 INITRANS 1 MAXTRANS 255
+  TABLESPACE "USERS" ;
+
+  CREATE TABLE "HR"."EMPLOYEES"
+   (	"EMPLOYEE_ID" NUMBER(6,0),
+	"EMAIL" VARCHAR2(25 BYTE) CONSTRAINT "EMP_EMAIL_NN" NOT NULL ENABLE,
+	"HIRE_DATE" DATE DEFAULT SYSDATE,
+	"UPDATED_AT" TIMESTAMP (6) WITH TIME ZONE,
+	"SALARY" NUMBER(8,2),
+	"ANNUAL_SALARY" NUMBER(10,2) GENERATED ALWAYS AS ("SALARY"*12) VIRTUAL ,
+	"BONUS" NUMBER(*,0),
+	"PROFILE" CLOB,
+	"NOTES" VARCHAR2(100 CHAR) DEFAULT 'n/a; none',
+	"DEPARTMENT_ID" NUMBER(4,0),
+	 CONSTRAINT "EMP_SALARY_MIN" CHECK (salary > 0) ENABLE,
+	 CONSTRAINT "EMP_PROFILE_JSON" CHECK ("PROFILE" IS JSON) ENABLE,
+	 SUPPLEMENTAL LOG DATA (ALL) COLUMNS
+   ) TABLESPACE "USERS" ;
+
+  CREATE UNIQUE INDEX "HR"."EMP_EMP_ID_PK" ON "HR"."EMPLOYEES" ("EMPLOYEE_ID")
+  PCTFREE 10 TABLESPACE "USERS" ;
+  CREATE UNIQUE INDEX "HR"."EMP_EMAIL_UK" ON "HR"."EMPLOYEES" ("EMAIL");
+  CREATE INDEX "HR"."EMP_HIRE_IX" ON "HR"."EMPLOYEES" ("HIRE_DATE" DESC, "EMPLOYEE_ID");
+  CREATE INDEX "HR"."EMP_UPPER_IX" ON "HR"."EMPLOYEES" (UPPER("EMAIL"));
+
+  ALTER TABLE "HR"."EMPLOYEES" ADD CONSTRAINT "EMP_EMP_ID_PK" PRIMARY KEY ("EMPLOYEE_ID")
+  USING INDEX "HR"."EMP_EMP_ID_PK"  ENABLE;
+  ALTER TABLE "HR"."EMPLOYEES" ADD CONSTRAINT "EMP_EMAIL_UK" UNIQUE ("EMAIL")
+  USING INDEX "HR"."EMP_EMAIL_UK"  ENABLE;
+  ALTER TABLE "HR"."EMPLOYEES" ADD CONSTRAINT "EMP_DEPT_FK" FOREIGN KEY ("DEPARTMENT_ID")
+	  REFERENCES "HR"."DEPARTMENTS" ON DELETE CASCADE ENABLE;
+
+  CREATE OR REPLACE EDITIONABLE TRIGGER "HR"."EMP_TRG"
+  BEFORE INSERT ON employees FOR EACH ROW
+BEGIN
+  :new.hire_date := SYSDATE;
+END;
+/
+create table audit_log (id integer primary key, msg varchar2(200))
+/
+`
+
+func TestProcessDump(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SetSchemaMode()
+	r := internal.NewReader(bufio.NewReader(strings.NewReader(oracleDDLScript)), nil)
+	assert.Nil(t, DbDumpImpl{}.ProcessDump(conv, r))
+	assert.Len(t, conv.SrcSchema, 3)
+
+	dept, ok := internal.GetSrcTableByName(conv.SrcSchema, "DEPARTMENTS")
+	assert.True(t, ok)
+	assert.Equal(t, "HR", dept.Schema)
+	assert.Equal(t, []schema.Key{{ColId: dept.ColNameIdMap["DEPARTMENT_ID"]}}, dept.PrimaryKeys)
+	assert.True(t, dept.ColDefs[dept.ColNameIdMap["DEPARTMENT_ID"]].NotNull)
+
+	emp, ok := internal.GetSrcTableByName(conv.SrcSchema, "EMPLOYEES")
+	assert.True(t, ok)
+	col := func(name string) schema.Column { return emp.ColDefs[emp.ColNameIdMap[name]] }
+	assert.Equal(t, schema.Type{Name: "NUMBER", Mods: []int64{6}}, col("EMPLOYEE_ID").Type)
+	assert.Equal(t, schema.Type{Name: "VARCHAR2", Mods: []int64{25}}, col("EMAIL").Type)
+	assert.True(t, col("EMAIL").NotNull)
+	assert.Equal(t, schema.Type{Name: "DATE"}, col("HIRE_DATE").Type)
+	assert.True(t, col("HIRE_DATE").Ignored.Default)
+	assert.Equal(t, schema.Type{Name: "TIMESTAMP(6) WITH TIME ZONE"}, col("UPDATED_AT").Type)
+	assert.Equal(t, schema.Type{Name: "NUMBER", Mods: []int64{8, 2}}, col("SALARY").Type)
+	assert.True(t, col("SALARY").Ignored.Check)
+	assert.Equal(t, schema.Type{Name: "NUMBER", Mods: []int64{10, 2}}, col("ANNUAL_SALARY").Type)
+	assert.Equal(t, `"SALARY" * 12`, col("ANNUAL_SALARY").Generated.Value.Statement)
+	assert.False(t, col("ANNUAL_SALARY").Generated.Stored)
+	assert.False(t, col("SALARY").Generated.IsPresent)
+	assert.Equal(t, schema.Type{Name: "NUMBER"}, col("BONUS").Type)
+	assert.Equal(t, schema.Type{Name: "JSON"}, col("PROFILE").Type)
+	assert.Equal(t, schema.Type{Name: "VARCHAR2", Mods: []int64{100}}, col("NOTES").Type)
+
+	assert.Equal(t, []schema.Key{{ColId: emp.ColNameIdMap["EMPLOYEE_ID"]}}, emp.PrimaryKeys)
+	// The index backing the primary key is dropped, the function-based index
+	// is skipped.
+	assert.Equal(t, 2, len(emp.Indexes))
+	assert.Equal(t, "EMP_EMAIL_UK", emp.Indexes[0].Name)
+	assert.True(t, emp.Indexes[0].Unique)
+	assert.Equal(t, []schema.Key{{ColId: emp.ColNameIdMap["HIRE_DATE"], Desc: true}, {ColId: emp.ColNameIdMap["EMPLOYEE_ID"]}}, emp.Indexes[1].Keys)
+	assert.Equal(t, 1, len(emp.ForeignKeys))
+	assert.Equal(t, dept.Id, emp.ForeignKeys[0].ReferTableId)
+	assert.Equal(t, []string{emp.ColNameIdMap["DEPARTMENT_ID"]}, emp.ForeignKeys[0].ColIds)
+	assert.Equal(t, []string{dept.ColNameIdMap["DEPARTMENT_ID"]}, emp.ForeignKeys[0].ReferColumnIds)
+	assert.Equal(t, constants.FK_CASCADE, emp.ForeignKeys[0].OnDelete)
+
+	audit, ok := internal.GetSrcTableByName(conv.SrcSchema, "AUDIT_LOG")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"ID", "MSG"}, []string{audit.ColDefs[audit.ColIds[0]].Name, audit.ColDefs[audit.ColIds[1]].Name})
+	assert.Equal(t, schema.Type{Name: "NUMBER"}, audit.ColDefs[audit.ColIds[0]].Type)
+	assert.Equal(t, 1, len(audit.PrimaryKeys))
+
+	assert.Equal(t, int64(1), conv.Stats.Statement["CREATE INDEX"].Error)
+	assert.Equal(t, int64(1), conv.Stats.Statement["CREATE TRIGGER"].Skip)
+	assert.Equal(t, int64(1), conv.Stats.Statement["ALTER SESSION"].Skip)
+}
+
+func TestProcessDumpDataMode(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SetDataMode()
+	r := internal.NewReader(bufio.NewReader(strings.NewReader(oracleDDLScript)), nil)
+	assert.NotNil(t, DbDumpImpl{}.ProcessDump(conv, r))
+}
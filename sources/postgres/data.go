@@ -18,7 +18,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
-	"math/bits"
 	"reflect"
 	"strconv"
 	"strings"
@@ -46,9 +45,9 @@ func ProcessDataRow(conv *internal.Conv, tableId string, colIds, vals []string)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 		conv.StatsAddBadRow(srcTableName, conv.DataMode())
-		conv.CollectBadRow(srcTableName, srcCols, vals)
+		conv.CollectBadRow(srcTableName, srcCols, vals, err)
 	} else {
-		conv.WriteRow(srcTableName, spTableName, spCols, spVals)
+		conv.WriteRowWithSource(srcTableName, srcCols, vals, spTableName, spCols, spVals)
 	}
 }
 
@@ -97,11 +96,9 @@ func ConvertData(conv *internal.Conv, tableId string, colIds []string, vals []st
 		v = append(v, x)
 		c = append(c, spColDef.Name)
 	}
-	if aux, ok := conv.SyntheticPKeys[tableId]; ok {
-		c = append(c, conv.SpSchema[tableId].ColDefs[aux.ColId].Name)
-		v = append(v, fmt.Sprintf("%d", int64(bits.Reverse64(uint64(aux.Sequence)))))
-		aux.Sequence++
-		conv.SyntheticPKeys[tableId] = aux
+	if col, val, ok := conv.NextSyntheticPKey(tableId); ok {
+		c = append(c, col)
+		v = append(v, val)
 	}
 	return spSchema.Name, c, v, nil
 }
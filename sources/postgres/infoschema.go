@@ -17,12 +17,13 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
-	"math/bits"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/civil"
@@ -125,22 +126,63 @@ func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
 
 // GetRowsFromTable returns a sql Rows object for a table.
 func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string) (interface{}, error) {
+	clause, args := common.ReadClause(conv, tableId, pgQueryDialect)
+	q := fmt.Sprintf(`SELECT * FROM %s%s;`, quotedTableName(conv.SrcSchema[tableId]), clause)
+	rows, err := isi.Db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, err
+}
+
+// quotedTableName returns the quoted, schema qualified name of a table.
+func quotedTableName(srcSchema schema.Table) string {
 	// PostgreSQL schema and name can be arbitrary strings.
 	// Ideally we would pass schema/name as a query parameter,
 	// but PostgreSQL doesn't support this. So we quote it instead.
-	isSchemaNamePrefixed := strings.HasPrefix(conv.SrcSchema[tableId].Name, conv.SrcSchema[tableId].Schema+".")
+	isSchemaNamePrefixed := strings.HasPrefix(srcSchema.Name, srcSchema.Schema+".")
 	var tableName string
 	if isSchemaNamePrefixed {
-		tableName = strings.TrimPrefix(conv.SrcSchema[tableId].Name, conv.SrcSchema[tableId].Schema+".")
+		tableName = strings.TrimPrefix(srcSchema.Name, srcSchema.Schema+".")
 	} else {
-		tableName = conv.SrcSchema[tableId].Name
+		tableName = srcSchema.Name
 	}
-	q := fmt.Sprintf(`SELECT * FROM "%s"."%s";`, conv.SrcSchema[tableId].Schema, tableName)
-	rows, err := isi.Db.Query(q)
-	if err != nil {
-		return nil, err
-	}
-	return rows, err
+	return fmt.Sprintf(`"%s"."%s"`, srcSchema.Schema, tableName)
+}
+
+// pgQueryDialect is the dialect for building clauses over the rows of a table.
+var pgQueryDialect = common.QueryDialect{
+	QuoteCol:    func(col string) string { return fmt.Sprintf(`"%s"`, col) },
+	Placeholder: func(i int) string { return fmt.Sprintf("$%d", i) },
+	IsInteger: func(srcType schema.Type) bool {
+		switch strings.ToLower(srcType.Name) {
+		case "int2", "smallint", "int4", "integer", "int8", "bigint":
+			return true
+		}
+		return false
+	},
+	HashText: func(col string, srcType schema.Type, spType ddl.Type) (string, bool) {
+		if len(srcType.ArrayBounds) > 0 || spType.IsArray {
+			return "", false
+		}
+		switch t := strings.ToLower(srcType.Name); {
+		case spType.Name == ddl.Int64 && (t == "int2" || t == "smallint" || t == "int4" || t == "integer" || t == "int8" || t == "bigint"):
+			return col + "::text", true
+		case spType.Name == ddl.String && (t == "varchar" || t == "character varying" || t == "text"):
+			return col + "::text", true
+		case spType.Name == ddl.Date && t == "date":
+			return "to_char(" + col + ", 'YYYY-MM-DD')", true
+		case spType.Name == ddl.Bool && (t == "bool" || t == "boolean"):
+			return "CASE " + col + " WHEN TRUE THEN 'true' WHEN FALSE THEN 'false' END", true
+		}
+		return "", false
+	},
+	Hash: common.HashDialect{
+		Concat: common.ConcatOperator,
+		Hash: func(text string) string {
+			return "('x' || substr(md5(" + text + "), 1, 6))::bit(24)::int::bigint"
+		},
+	},
 }
 
 // ProcessDataRows performs data conversion for source database
@@ -164,6 +206,59 @@ func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string)
 // *interface{} parameters to row.Scan.
 func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable, additionalAttributes internal.AdditionalDataAttributes) error {
 	srcTableName := conv.SrcSchema[tableId].Name
+	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
+	processRows := func(rows *sql.Rows, mutex *sync.Mutex) {
+		srcCols, _ := rows.Columns()
+		v, iv := buildVals(len(srcCols))
+		for rows.Next() {
+			err := rows.Scan(iv...)
+			if err != nil {
+				mutex.Lock()
+				conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
+				// Scan failed, so we don't have any data to add to bad rows.
+				conv.StatsAddBadRow(srcTableName, conv.DataMode())
+				mutex.Unlock()
+				continue
+			}
+			// Convert the row without holding the lock, so that the readers
+			// of a table's key ranges only serialize their updates of conv.
+			var cvtCols []string
+			var cvtVals []interface{}
+			newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, colIds, srcCols, v)
+			if err == nil {
+				cvtCols, cvtVals, err = convertSQLRow(conv, tableId, colIds, srcSchema, spSchema, newValues)
+			}
+			// The source values are only formatted if they may be needed
+			// for the dead-letter file.
+			var srcVals []string
+			if err != nil || conv.DeadLetter != nil {
+				srcVals = pgTextValues(srcSchema, colNameIdMap, srcCols, v)
+			}
+			mutex.Lock()
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
+				conv.StatsAddBadRow(srcTableName, conv.DataMode())
+				conv.CollectBadRow(srcTableName, srcCols, srcVals, err)
+			} else {
+				conv.WriteRowWithSource(srcTableName, srcCols, srcVals, conv.SpSchema[tableId].Name, cvtCols, cvtVals)
+			}
+			mutex.Unlock()
+		}
+	}
+	// If a read parallelism is configured for the table in the source
+	// profile, read it with concurrent queries over ranges of its primary key.
+	if parallelism := profiles.GetReadParallelism(isi.SourceProfile, srcTableName); parallelism > 1 {
+		table := quotedTableName(srcSchema)
+		query := func(where string, args []interface{}) (*sql.Rows, error) {
+			return isi.Db.Query(fmt.Sprintf(`SELECT * FROM %s%s;`, table, where), args...)
+		}
+		if ok, err := common.ReadKeyRanges(conv, tableId, parallelism, isi.Db, table, pgQueryDialect, query, processRows); ok {
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
+			}
+			return err
+		}
+	}
 	rowsInterface, err := isi.GetRowsFromTable(conv, tableId)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
@@ -171,27 +266,7 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSc
 	}
 	rows := rowsInterface.(*sql.Rows)
 	defer rows.Close()
-	srcCols, _ := rows.Columns()
-	v, iv := buildVals(len(srcCols))
-	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
-	for rows.Next() {
-		err := rows.Scan(iv...)
-		if err != nil {
-			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
-			// Scan failed, so we don't have any data to add to bad rows.
-			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			continue
-		}
-		newValues, err1 := common.PrepareValues(conv, tableId, colNameIdMap, colIds, srcCols, v)
-		cvtCols, cvtVals, err2 := convertSQLRow(conv, tableId, colIds, srcSchema, spSchema, newValues)
-		if err1 != nil || err2 != nil {
-			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
-			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, valsToStrings(v))
-			continue
-		}
-		conv.WriteRow(srcTableName, conv.SpSchema[tableId].Name, cvtCols, cvtVals)
-	}
+	processRows(rows, &sync.Mutex{})
 	return nil
 }
 
@@ -226,15 +301,31 @@ func convertSQLRow(conv *internal.Conv, tableId string, colIds []string, srcSche
 		vs = append(vs, spVal)
 		cs = append(cs, spCd.Name)
 	}
-	if aux, ok := conv.SyntheticPKeys[tableId]; ok {
-		cs = append(cs, conv.SpSchema[tableId].ColDefs[aux.ColId].Name)
-		vs = append(vs, fmt.Sprintf("%d", int64(bits.Reverse64(uint64(aux.Sequence)))))
-		aux.Sequence++
-		conv.SyntheticPKeys[tableId] = aux
+	if col, val, ok := conv.NextSyntheticPKey(tableId); ok {
+		cs = append(cs, col)
+		vs = append(vs, val)
 	}
 	return cs, vs, nil
 }
 
+// HashableColumns returns the columns whose values can be hashed in the
+// database (see common.KeyRangeHasher).
+func (isi InfoSchemaImpl) HashableColumns(conv *internal.Conv, tableId string, colIds []string) []string {
+	return common.HashableColumns(conv, tableId, colIds, pgQueryDialect)
+}
+
+// HashKeyRanges counts and hashes the rows of a table in ranges of an
+// integer column in the database (see common.KeyRangeHasher).
+func (isi InfoSchemaImpl) HashKeyRanges(conv *internal.Conv, tableId, colId string, bounds []int64, keyColIds, hashColIds []string) ([]common.KeyRangeSummary, bool, error) {
+	return common.HashKeyRanges(conv, tableId, colId, bounds, keyColIds, hashColIds, isi.Db, quotedTableName(conv.SrcSchema[tableId]), pgQueryDialect)
+}
+
+// CountFilteredRows counts the rows of a table skipped by its row filter in
+// the database (see common.FilteredRowCounter).
+func (isi InfoSchemaImpl) CountFilteredRows(conv *internal.Conv, tableId string) (int64, error) {
+	return common.CountFilteredRows(conv, tableId, isi.Db, quotedTableName(conv.SrcSchema[tableId]))
+}
+
 // GetRowCount with number of rows in each table.
 func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
 	// PostgreSQL schema and name can be arbitrary strings.
@@ -285,7 +376,7 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 
 // GetColumns returns a list of Column objects and names
 func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
-	q := `SELECT c.column_name, c.data_type, e.data_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale
+	q := `SELECT c.column_name, c.data_type, e.data_type, c.is_nullable, c.column_default, c.character_maximum_length, c.numeric_precision, c.numeric_scale, c.is_generated, c.generation_expression
               FROM information_schema.COLUMNS c LEFT JOIN information_schema.element_types e
                  ON ((c.table_catalog, c.table_schema, c.table_name, 'TABLE', c.dtd_identifier)
                      = (e.object_catalog, e.object_schema, e.object_name, e.object_type, e.collection_type_identifier))
@@ -298,10 +389,10 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 	colDefs := make(map[string]schema.Column)
 	var colIds []string
 	var colName, dataType, isNullable string
-	var colDefault, elementDataType sql.NullString
+	var colDefault, elementDataType, isGenerated, generationExpr sql.NullString
 	var charMaxLen, numericPrecision, numericScale sql.NullInt64
 	for cols.Next() {
-		err := cols.Scan(&colName, &dataType, &elementDataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale)
+		err := cols.Scan(&colName, &dataType, &elementDataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &isGenerated, &generationExpr)
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
 			continue
@@ -320,12 +411,22 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 		}
 		ignored.Default = colDefault.Valid
 		colId := internal.GenerateColumnId()
+		// PostgreSQL generated columns are always stored.
+		var generated ddl.GeneratedColumn
+		if isGenerated.String == "ALWAYS" && generationExpr.Valid {
+			generated = ddl.GeneratedColumn{
+				IsPresent: true,
+				Value:     ddl.Expression{ExpressionId: internal.GenerateExpressionId(), Statement: generationExpr.String},
+				Stored:    true,
+			}
+		}
 		c := schema.Column{
-			Id:      colId,
-			Name:    colName,
-			Type:    toType(dataType, elementDataType, charMaxLen, numericPrecision, numericScale),
-			NotNull: common.ToNotNull(conv, isNullable),
-			Ignored: ignored,
+			Id:        colId,
+			Name:      colName,
+			Type:      toType(dataType, elementDataType, charMaxLen, numericPrecision, numericScale),
+			NotNull:   common.ToNotNull(conv, isNullable),
+			Ignored:   ignored,
+			Generated: generated,
 		}
 		colDefs[colId] = c
 		colIds = append(colIds, colId)
@@ -508,6 +609,44 @@ func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAnd
 	return indexes, nil
 }
 
+// GetSourceObjects returns the views, triggers, procedures and functions of
+// the user schemas. Functions written in C, e.g. by extensions, are skipped.
+func (isi InfoSchemaImpl) GetSourceObjects(conv *internal.Conv) ([]schema.SourceObject, error) {
+	q := `SELECT 'VIEW'::text, table_schema::text, table_name::text, COALESCE(view_definition, '')::text, ''::text, ''::text, ''::text
+			FROM information_schema.views
+			WHERE table_schema NOT IN ('information_schema', 'pg_catalog')
+		UNION ALL
+		SELECT 'TRIGGER', trigger_schema, trigger_name, action_statement, event_object_schema, event_object_table,
+				string_agg(event_manipulation, ', ' ORDER BY event_manipulation)
+			FROM information_schema.triggers
+			GROUP BY trigger_schema, trigger_name, action_statement, event_object_schema, event_object_table
+		UNION ALL
+		SELECT routine_type, routine_schema, routine_name, COALESCE(routine_definition, ''), '', '', ''
+			FROM information_schema.routines
+			WHERE routine_schema NOT IN ('information_schema', 'pg_catalog')
+				AND routine_type IN ('PROCEDURE', 'FUNCTION')
+				AND external_language NOT IN ('C', 'INTERNAL');`
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var kind, objSchema, name, body, tableSchema, table, event string
+	var objects []schema.SourceObject
+	for rows.Next() {
+		if err := rows.Scan(&kind, &objSchema, &name, &body, &tableSchema, &table, &event); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		o := schema.SourceObject{Kind: kind, Schema: objSchema, Name: isi.GetTableName(objSchema, name), Body: body, Event: event}
+		if table != "" {
+			o.Table = isi.GetTableName(tableSchema, table)
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
 func toType(dataType string, elementDataType sql.NullString, charLen sql.NullInt64, numericPrecision, numericScale sql.NullInt64) schema.Type {
 	switch {
 	case dataType == "ARRAY" && elementDataType.Valid:
@@ -667,20 +806,57 @@ func buildVals(n int) (v []interface{}, iv []interface{}) {
 	return v, iv
 }
 
-func valsToStrings(vals []interface{}) []string {
-	toString := func(val interface{}) string {
-		if val == nil {
-			return "NULL"
-		}
-		switch v := val.(type) {
-		case *interface{}:
+// pgTextValues formats the values of a row returned by the driver the way
+// pg_dump does, e.g. bytea values as \x followed by hex digits and NULL as
+// \N, so that rows written to the dead-letter file can be replayed through
+// ProcessDataRow.
+func pgTextValues(srcSchema schema.Table, colNameIdMap map[string]string, srcCols []string, vals []interface{}) []string {
+	var s []string
+	for i, val := range vals {
+		if v, ok := val.(*interface{}); ok {
 			val = *v
 		}
-		return fmt.Sprintf("%v", val)
-	}
-	var s []string
-	for _, v := range vals {
-		s = append(s, toString(v))
+		var typeName string
+		if colId, ok := colNameIdMap[srcCols[i]]; ok {
+			typeName = srcSchema.ColDefs[colId].Type.Name
+		}
+		s = append(s, pgText(typeName, val))
 	}
 	return s
 }
+
+func pgText(typeName string, val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return `\N`
+	case []byte:
+		if typeName == "bytea" {
+			return `\x` + hex.EncodeToString(v)
+		}
+		return string(v)
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		switch typeName {
+		case "date":
+			return v.Format("2006-01-02")
+		case "time", "time without time zone":
+			return v.Format("15:04:05.999999")
+		case "timetz", "time with time zone":
+			return v.Format("15:04:05.999999Z07:00")
+		case "timestamptz", "timestamp with time zone":
+			return v.Format("2006-01-02 15:04:05.999999Z07:00")
+		}
+		return v.Format("2006-01-02 15:04:05.999999")
+	}
+	return fmt.Sprintf("%v", val)
+}
@@ -80,11 +80,11 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "user"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_generated", "generation_expression"},
 			rows: [][]driver.Value{
-				{"user_id", "text", nil, "NO", nil, nil, nil, nil},
-				{"name", "text", nil, "NO", nil, nil, nil, nil},
-				{"ref", "bigint", nil, "YES", nil, nil, nil, nil}},
+				{"user_id", "text", nil, "NO", nil, nil, nil, nil, nil, nil},
+				{"name", "text", nil, "NO", nil, nil, nil, nil, nil, nil},
+				{"ref", "bigint", nil, "YES", nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -112,11 +112,11 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "cart"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_generated", "generation_expression"},
 			rows: [][]driver.Value{
-				{"productid", "text", nil, "NO", nil, nil, nil, nil},
-				{"userid", "text", nil, "NO", nil, nil, nil, nil},
-				{"quantity", "bigint", nil, "YES", nil, nil, 64, 0}},
+				{"productid", "text", nil, "NO", nil, nil, nil, nil, nil, nil},
+				{"userid", "text", nil, "NO", nil, nil, nil, nil, nil, nil},
+				{"quantity", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -145,10 +145,10 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "product"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_generated", "generation_expression"},
 			rows: [][]driver.Value{
-				{"product_id", "text", nil, "NO", nil, nil, nil, nil},
-				{"product_name", "text", nil, "NO", nil, nil, nil, nil}},
+				{"product_id", "text", nil, "NO", nil, nil, nil, nil, nil, nil},
+				{"product_name", "text", nil, "NO", nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -173,29 +173,29 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "test"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_generated", "generation_expression"},
 			rows: [][]driver.Value{
-				{"id", "bigint", nil, "NO", nil, nil, 64, 0},
-				{"aint", "ARRAY", "integer", "YES", nil, nil, nil, nil},
-				{"atext", "ARRAY", "text", "YES", nil, nil, nil, nil},
-				{"b", "boolean", nil, "YES", nil, nil, nil, nil},
-				{"bs", "bigint", nil, "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0},
-				{"by", "bytea", nil, "YES", nil, nil, nil, nil},
-				{"c", "character", nil, "YES", nil, 1, nil, nil},
-				{"c_8", "character", nil, "YES", nil, 8, nil, nil},
-				{"d", "date", nil, "YES", nil, nil, nil, nil},
-				{"f8", "double precision", nil, "YES", nil, nil, 53, nil},
-				{"f4", "real", nil, "YES", nil, nil, 24, nil},
-				{"i8", "bigint", nil, "YES", nil, nil, 64, 0},
-				{"i4", "integer", nil, "YES", nil, nil, 32, 0},
-				{"i2", "smallint", nil, "YES", nil, nil, 16, 0},
-				{"num", "numeric", nil, "YES", nil, nil, nil, nil},
-				{"s", "integer", nil, "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0},
-				{"ts", "timestamp without time zone", nil, "YES", nil, nil, nil, nil},
-				{"tz", "timestamp with time zone", nil, "YES", nil, nil, nil, nil},
-				{"txt", "text", nil, "NO", nil, nil, nil, nil},
-				{"vc", "character varying", nil, "YES", nil, nil, nil, nil},
-				{"vc6", "character varying", nil, "YES", nil, 6, nil, nil}},
+				{"id", "bigint", nil, "NO", nil, nil, 64, 0, nil, nil},
+				{"aint", "ARRAY", "integer", "YES", nil, nil, nil, nil, nil, nil},
+				{"atext", "ARRAY", "text", "YES", nil, nil, nil, nil, nil, nil},
+				{"b", "boolean", nil, "YES", nil, nil, nil, nil, nil, nil},
+				{"bs", "bigint", nil, "NO", "nextval('test11_bs_seq'::regclass)", nil, 64, 0, nil, nil},
+				{"by", "bytea", nil, "YES", nil, nil, nil, nil, nil, nil},
+				{"c", "character", nil, "YES", nil, 1, nil, nil, nil, nil},
+				{"c_8", "character", nil, "YES", nil, 8, nil, nil, nil, nil},
+				{"d", "date", nil, "YES", nil, nil, nil, nil, nil, nil},
+				{"f8", "double precision", nil, "YES", nil, nil, 53, nil, nil, nil},
+				{"f4", "real", nil, "YES", nil, nil, 24, nil, nil, nil},
+				{"i8", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil},
+				{"i4", "integer", nil, "YES", nil, nil, 32, 0, nil, nil},
+				{"i2", "smallint", nil, "YES", nil, nil, 16, 0, nil, nil},
+				{"num", "numeric", nil, "YES", nil, nil, nil, nil, nil, nil},
+				{"s", "integer", nil, "NO", "nextval('test11_s_seq'::regclass)", nil, 32, 0, nil, nil},
+				{"ts", "timestamp without time zone", nil, "YES", nil, nil, nil, nil, nil, nil},
+				{"tz", "timestamp with time zone", nil, "YES", nil, nil, nil, nil, nil, nil},
+				{"txt", "text", nil, "NO", nil, nil, nil, nil, nil, nil},
+				{"vc", "character varying", nil, "YES", nil, nil, nil, nil, nil, nil},
+				{"vc6", "character varying", nil, "YES", nil, 6, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -219,11 +219,11 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "test_ref"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_generated", "generation_expression"},
 			rows: [][]driver.Value{
-				{"ref_id", "bigint", nil, "NO", nil, nil, 64, 0},
-				{"ref_txt", "text", nil, "NO", nil, nil, nil, nil},
-				{"abc", "text", nil, "NO", nil, nil, nil, nil}},
+				{"ref_id", "bigint", nil, "NO", nil, nil, 64, 0, nil, nil},
+				{"ref_txt", "text", nil, "NO", nil, nil, nil, nil, nil, nil},
+				{"abc", "text", nil, "NO", nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -231,6 +231,14 @@ func TestProcessSchema(t *testing.T) {
 			args:  []driver.Value{"public", "test_ref"},
 			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
 		},
+		{
+			query: "SELECT (.+) FROM information_schema.views (.+) UNION ALL (.+) FROM information_schema.triggers (.+) UNION ALL (.+) FROM information_schema.routines (.+)",
+			cols:  []string{"kind", "schema", "name", "body", "table_schema", "table", "event"},
+			rows: [][]driver.Value{
+				{"TRIGGER", "public", "cart_audit", "EXECUTE FUNCTION audit_cart()", "public", "cart", "DELETE, INSERT"},
+				{"FUNCTION", "public", "audit_cart", "BEGIN INSERT INTO test_ref SELECT NEW.*; RETURN NEW; END", "", "", ""},
+			},
+		},
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
@@ -335,6 +343,10 @@ func TestProcessSchema(t *testing.T) {
 	testTableId, err := internal.GetTableIdFromSpName(conv.SpSchema, "test")
 	assert.Equal(t, nil, err)
 	internal.AssertTableIssues(conv, t, testTableId, expectedIssues, conv.SchemaIssues[testTableId].ColumnLevelIssues)
+	assert.Equal(t, []schema.SourceObject{
+		{Kind: schema.Trigger, Schema: "public", Name: "cart_audit", Body: "EXECUTE FUNCTION audit_cart()", Table: "cart", Event: "DELETE, INSERT"},
+		{Kind: schema.Function, Schema: "public", Name: "audit_cart", Body: "BEGIN INSERT INTO test_ref SELECT NEW.*; RETURN NEW; END", ReferencedTables: []string{"test_ref"}},
+	}, conv.SrcObjects)
 	assert.Equal(t, int64(0), conv.Unexpecteds())
 }
 
@@ -394,6 +406,31 @@ func TestProcessData(t *testing.T) {
 	assert.Equal(t, int64(1), conv.Unexpecteds()) // Bad row generates an entry in unexpected.
 }
 
+func TestGetColumns_GeneratedColumns(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
+			args:  []driver.Value{"public", "cart"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_generated", "generation_expression"},
+			rows: [][]driver.Value{
+				{"quantity", "bigint", nil, "YES", nil, nil, 64, 0, "NEVER", nil},
+				{"total", "bigint", nil, "YES", nil, nil, 64, 0, "ALWAYS", "(quantity * 2)"},
+			},
+		},
+	}
+	db := mkMockDB(t, ms)
+	isi := InfoSchemaImpl{Db: db}
+	conv := internal.MakeConv()
+	colDefs, colIds, err := isi.GetColumns(conv, common.SchemaAndName{Schema: "public", Name: "cart"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(colIds))
+	assert.False(t, colDefs[colIds[0]].Generated.IsPresent)
+	total := colDefs[colIds[1]].Generated
+	assert.True(t, total.IsPresent)
+	assert.Equal(t, "(quantity * 2)", total.Value.Statement)
+	assert.True(t, total.Stored)
+}
+
 func TestConvertSqlRow_SingleCol(t *testing.T) {
 	tDate, _ := time.Parse("2006-01-02", "2019-10-29")
 	tc := []struct {
@@ -483,6 +520,43 @@ func TestConvertSqlRow_SingleCol(t *testing.T) {
 	}
 }
 
+func TestPGTextValues(t *testing.T) {
+	// Values written to the dead-letter file must convert to the same
+	// Spanner values as the driver values they were formatted from.
+	ist := time.FixedZone("", 5*3600+1800)
+	tc := []struct {
+		name    string
+		srcType string
+		spType  ddl.Type
+		in      interface{}
+		text    string
+	}{
+		{name: "bool", srcType: "bool", spType: ddl.Type{Name: ddl.Bool}, in: true, text: "t"},
+		{name: "bytes", srcType: "bytea", spType: ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, in: []byte{0x0, 0x1, 0xbe, 0xef}, text: `\x0001beef`},
+		{name: "date", srcType: "date", spType: ddl.Type{Name: ddl.Date}, in: time.Date(2019, 10, 29, 0, 0, 0, 0, time.UTC), text: "2019-10-29"},
+		{name: "int64", srcType: "bigint", spType: ddl.Type{Name: ddl.Int64}, in: int64(42), text: "42"},
+		{name: "float64", srcType: "float8", spType: ddl.Type{Name: ddl.Float64}, in: 42.5, text: "42.5"},
+		{name: "numeric", srcType: "numeric", spType: ddl.Type{Name: ddl.Numeric}, in: []byte("1.25"), text: "1.25"},
+		{name: "string", srcType: "text", spType: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}, in: []byte(`a\b`), text: `a\b`},
+		{name: "timestamp", srcType: "timestamp", spType: ddl.Type{Name: ddl.Timestamp}, in: time.Date(2019, 10, 29, 5, 30, 0, 123000, time.UTC), text: "2019-10-29 05:30:00.000123"},
+		{name: "timestamptz", srcType: "timestamptz", spType: ddl.Type{Name: ddl.Timestamp}, in: time.Date(2019, 10, 29, 5, 30, 0, 0, ist), text: "2019-10-29 05:30:00+05:30"},
+	}
+	for _, tc := range tc {
+		srcSchema := schema.Table{Name: "t", Id: "t1", ColIds: []string{"c1"}, ColDefs: map[string]schema.Column{"c1": {Type: schema.Type{Name: tc.srcType}, Name: "a", Id: "c1"}}}
+		spSchema := ddl.CreateTable{Name: "t", Id: "t1", ColIds: []string{"c1"}, ColDefs: map[string]ddl.ColumnDef{"c1": {Name: "a", Id: "c1", T: tc.spType}}}
+		conv := buildConv(spSchema, srcSchema)
+		conv.SetLocation(time.UTC)
+		text := pgTextValues(srcSchema, map[string]string{"a": "c1"}, []string{"a"}, []interface{}{tc.in})
+		assert.Equal(t, []string{tc.text}, text, tc.name)
+		_, expected, err := convertSQLRow(conv, "t1", []string{"c1"}, srcSchema, spSchema, []interface{}{tc.in})
+		assert.Nil(t, err, tc.name)
+		_, _, actual, err := ConvertData(conv, "t1", []string{"c1"}, text)
+		assert.Nil(t, err, tc.name)
+		assert.Equal(t, expected, actual, tc.name)
+	}
+	assert.Equal(t, []string{`\N`}, pgTextValues(schema.Table{}, nil, []string{"a"}, []interface{}{nil}))
+}
+
 func TestConvertSqlRow_MultiCol(t *testing.T) {
 	// Tests multi-column behavior of ConvertSqlRow (including
 	// handling of null ColIds and synthetic keys). Also tests
@@ -508,11 +582,11 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"public", "test"},
-			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "is_generated", "generation_expression"},
 			rows: [][]driver.Value{
-				{"a", "text", nil, "NO", nil, nil, nil, nil},
-				{"b", "double precision", nil, "YES", nil, nil, 53, nil},
-				{"c", "bigint", nil, "YES", nil, nil, 64, 0}},
+				{"a", "text", nil, "NO", nil, nil, nil, nil, nil, nil},
+				{"b", "double precision", nil, "YES", nil, nil, 53, nil, nil, nil},
+				{"c", "bigint", nil, "YES", nil, nil, 64, 0, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -520,6 +594,10 @@ func TestConvertSqlRow_MultiCol(t *testing.T) {
 			args:  []driver.Value{"public", "test"},
 			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order"},
 		},
+		{
+			query: "SELECT (.+) FROM information_schema.views (.+) UNION ALL (.+) FROM information_schema.triggers (.+) UNION ALL (.+) FROM information_schema.routines (.+)",
+			cols:  []string{"kind", "schema", "name", "body", "table_schema", "table", "event"},
+		},
 		{
 			query: `SELECT [*] FROM "public"."test"`, // query is a regexp!
 			cols:  []string{"a", "b", "c"},
@@ -28,6 +28,7 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
 )
 
 // DbDumpImpl Postgres specific implementation for DdlDumpImpl.
@@ -63,7 +64,12 @@ func (ddi DbDumpImpl) ProcessDump(conv *internal.Conv, r *internal.Reader) error
 // In schema mode, ProcessPgDump incrementally builds a schema (updating conv).
 // In data mode, ProcessPgDump uses this schema to convert PostgreSQL data
 // and writes it to Spanner, using the data sink specified in conv.
+// Custom (pg_dump -Fc) and directory (pg_dump -Fd) archives are read
+// by processPgArchive.
 func processPgDump(conv *internal.Conv, r *internal.Reader) error {
+	if isPgArchive(r) {
+		return processPgArchive(conv, r)
+	}
 	for {
 		startLine := r.LineNumber
 		startOffset := r.Offset
@@ -107,7 +113,7 @@ func processPgDump(conv *internal.Conv, r *internal.Reader) error {
 						srcTableName := conv.SrcSchema[ci.table].Name
 						conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
 						conv.StatsAddBadRow(srcTableName, conv.DataMode())
-						conv.CollectBadRow(srcTableName, colNames, vals)
+						conv.CollectBadRow(srcTableName, colNames, vals, err)
 						continue
 					}
 					ProcessDataRow(conv, ci.table, commonColIds, newVals)
@@ -159,12 +165,12 @@ func readAndParseChunk(conv *internal.Conv, r *internal.Reader) ([]byte, []*pg_q
 }
 
 func processCopyBlock(conv *internal.Conv, tableId string, commonColIds, srcCols []string, r *internal.Reader) {
-	srcTableName := conv.SrcSchema[tableId].Name
 	internal.VerbosePrintf("Parsing COPY-FROM stdin block starting at line=%d/fpos=%d\n", r.LineNumber, r.Offset)
 	logger.Log.Debug(fmt.Sprintf("Parsing COPY-FROM stdin block starting at line=%d/fpos=%d\n", r.LineNumber, r.Offset))
+	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
 	for {
 		b := r.ReadLine()
-		if string(b) == "\\.\n" || string(b) == "\\.\r\n" {
+		if isEndOfCopyData(b) {
 			internal.VerbosePrintf("Parsed COPY-FROM stdin block ending at line=%d/fpos=%d\n", r.LineNumber, r.Offset)
 			logger.Log.Debug(fmt.Sprintf("Parsed COPY-FROM stdin block ending at line=%d/fpos=%d\n", r.LineNumber, r.Offset))
 			return
@@ -173,33 +179,44 @@ func processCopyBlock(conv *internal.Conv, tableId string, commonColIds, srcCols
 			conv.Unexpected("Reached eof while parsing copy-block")
 			return
 		}
-		conv.StatsAddRow(srcTableName, conv.SchemaMode())
-		// We have to read the copy-block data so that we can process the remaining
-		// pg_dump content. However, if we don't want the data, stop here.
-		// In particular, avoid the strings.Split and ProcessDataRow calls below, which
-		// will be expensive for huge datasets.
-		if !conv.DataMode() {
-			continue
-		}
-		// pg_dump escapes backslash in copy-block statements. For example:
-		// a) a\"b becomes a\\"b in COPY-BLOCK (but 'a\"b' in INSERT-INTO)
-		// b) {"a\"b"} becomes {"a\\"b"} in COPY-BLOCK (but '{"a\"b"}' in INSERT-INTO)
-		// Note: a'b and {a'b} are unchanged in COPY-BLOCK and INSERT-INTO.
-		s := strings.ReplaceAll(string(b), `\\`, `\`)
-		// COPY-FROM blocks use tabs to separate data items. Note that space within data
-		// items is significant e.g. if a table row contains data items "a ", " b "
-		// it will be shown in the COPY-FROM block as "a \t b ".
-		values := strings.Split(strings.Trim(s, "\r\n"), "\t")
-		colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
-		newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
-		if err != nil {
-			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
-			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, values)
-			continue
-		}
-		ProcessDataRow(conv, tableId, commonColIds, newValues)
+		processCopyRow(conv, tableId, commonColIds, srcCols, colNameIdMap, b)
+	}
+}
+
+// isEndOfCopyData returns whether line b is the end-of-data marker of a
+// COPY-FROM block.
+func isEndOfCopyData(b []byte) bool {
+	return string(b) == "\\.\n" || string(b) == "\\.\r\n"
+}
+
+// processCopyRow processes line b of the COPY-FROM block of table tableId.
+func processCopyRow(conv *internal.Conv, tableId string, commonColIds, srcCols []string, colNameIdMap map[string]string, b []byte) {
+	srcTableName := conv.SrcSchema[tableId].Name
+	conv.StatsAddRow(srcTableName, conv.SchemaMode())
+	// We have to read the copy-block data so that we can process the remaining
+	// pg_dump content. However, if we don't want the data, stop here.
+	// In particular, avoid the strings.Split and ProcessDataRow calls below, which
+	// will be expensive for huge datasets.
+	if !conv.DataMode() {
+		return
+	}
+	// pg_dump escapes backslash in copy-block statements. For example:
+	// a) a\"b becomes a\\"b in COPY-BLOCK (but 'a\"b' in INSERT-INTO)
+	// b) {"a\"b"} becomes {"a\\"b"} in COPY-BLOCK (but '{"a\"b"}' in INSERT-INTO)
+	// Note: a'b and {a'b} are unchanged in COPY-BLOCK and INSERT-INTO.
+	s := strings.ReplaceAll(string(b), `\\`, `\`)
+	// COPY-FROM blocks use tabs to separate data items. Note that space within data
+	// items is significant e.g. if a table row contains data items "a ", " b "
+	// it will be shown in the COPY-FROM block as "a \t b ".
+	values := strings.Split(strings.Trim(s, "\r\n"), "\t")
+	newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+		conv.StatsAddBadRow(srcTableName, conv.DataMode())
+		conv.CollectBadRow(srcTableName, srcCols, values, err)
+		return
 	}
+	ProcessDataRow(conv, tableId, commonColIds, newValues)
 }
 
 // processStatements extracts schema information and data from PostgreSQL
@@ -589,6 +606,7 @@ type constraint struct {
 	referTable string
 	onDelete   string
 	onUpdate   string
+	expr       string // Used for GENERATED columns.
 }
 
 // extractConstraints traverses a list of nodes (expecting them to be
@@ -600,8 +618,16 @@ func extractConstraints(conv *internal.Conv, stmtType, table string, l []*pg_que
 			c := d.Constraint
 			var cols, referCols []string
 			var referTable, onDelete, onUpdate string
-			var conName string
+			var conName, expr string
 			switch c.Contype {
+			case pg_query.ConstrType_CONSTR_GENERATED:
+				e, err := deparseExpr(c.RawExpr)
+				if err != nil {
+					conv.Unexpected(fmt.Sprintf("Processing %v statement: error processing generated column of table %s: %s", stmtType, table, err.Error()))
+					conv.ErrorInStatement(printNodeType(d))
+					continue
+				}
+				expr = e
 			case pg_query.ConstrType_CONSTR_FOREIGN:
 				t, err := getTableName(conv, c.Pktable)
 				if err != nil {
@@ -681,7 +707,7 @@ func extractConstraints(conv *internal.Conv, stmtType, table string, l []*pg_que
 					cols = append(cols, k)
 				}
 			}
-			cs = append(cs, constraint{ct: c.Contype, cols: cols, name: conName, referCols: referCols, referTable: referTable, onDelete: onDelete, onUpdate: onUpdate})
+			cs = append(cs, constraint{ct: c.Contype, cols: cols, name: conName, referCols: referCols, referTable: referTable, onDelete: onDelete, onUpdate: onUpdate, expr: expr})
 		default:
 			conv.Unexpected(fmt.Sprintf("Processing %v statement: found %s node while processing constraints\n", stmtType, printNodeType(d)))
 		}
@@ -689,6 +715,21 @@ func extractConstraints(conv *internal.Conv, stmtType, table string, l []*pg_que
 	return cs
 }
 
+// deparseExpr returns the SQL text of expression node n.
+func deparseExpr(n *pg_query.Node) (string, error) {
+	if n == nil {
+		return "", fmt.Errorf("expression is empty")
+	}
+	stmt := &pg_query.Node{Node: &pg_query.Node_SelectStmt{SelectStmt: &pg_query.SelectStmt{
+		TargetList: []*pg_query.Node{pg_query.MakeResTargetNodeWithVal(n, 0)},
+	}}}
+	s, err := pg_query.Deparse(&pg_query.ParseResult{Stmts: []*pg_query.RawStmt{{Stmt: stmt}}})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(s, "SELECT "), nil
+}
+
 // analyzeColDefConstraints is like extractConstraints, but is specifially for
 // ColDef constraints. These constraints don't specify a key since they
 // are constraints for the column defined by ColDef.
@@ -735,6 +776,22 @@ func updateSchema(conv *internal.Conv, tableId string, cs []constraint, stmtType
 			ct := conv.SrcSchema[tableId]
 			ct.Indexes = append(ct.Indexes, schema.Index{Name: c.name, Unique: true, Keys: toSchemaKeys(conv, tableId, c.cols, colNameIdMap)})
 			conv.SrcSchema[tableId] = ct
+		case pg_query.ConstrType_CONSTR_GENERATED:
+			// PostgreSQL generated columns are always stored.
+			ct := conv.SrcSchema[tableId]
+			for _, cn := range c.cols {
+				cd := ct.ColDefs[colNameIdMap[cn]]
+				cd.Generated = ddl.GeneratedColumn{
+					IsPresent: true,
+					Value: ddl.Expression{
+						ExpressionId: internal.GenerateExpressionId(),
+						Statement:    c.expr,
+					},
+					Stored: true,
+				}
+				ct.ColDefs[colNameIdMap[cn]] = cd
+			}
+			conv.SrcSchema[tableId] = ct
 		default:
 			ct := conv.SrcSchema[tableId]
 			updateCols(c.ct, c.cols, ct.ColDefs, colNameIdMap)
@@ -0,0 +1,563 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/task"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+)
+
+// pg_dump archives (pg_dump -Fc and -Fd) start with a header and a table of
+// contents (TOC) listing the dumped objects, written by pg_backup_archiver.c.
+// Each TOC entry holds the SQL definition of an object and, for table data,
+// the COPY statement of its data. Custom archives append the data of each
+// table as a block of length-prefixed chunks, directory archives store it in
+// one file per table next to the toc.dat file.
+const (
+	archiveMagic = "PGDMP"
+	archiveToc   = "toc.dat"
+
+	archCustom    = 1
+	archTar       = 3
+	archDirectory = 5
+
+	// Data block types of custom archives.
+	blkData  = 1
+	blkBlobs = 3
+
+	// Data offset states of the TOC entries of custom archives.
+	offsetNoData = 3
+
+	// Compression algorithms of archive versions 1.15 and later.
+	compressionNone = 0
+	compressionGzip = 1
+
+	minArchiveVersion = 1<<16 | 12<<8 // 1.12, written by PostgreSQL 9.0 and later.
+	maxArchiveVersion = 1<<16 | 16<<8 // 1.16, written by PostgreSQL 17.
+)
+
+// ArchiveReadWorkers is the number of table data files of directory archives
+// that are read in parallel.
+var ArchiveReadWorkers = common.DefaultWorkers
+
+// pgArchive is a pg_dump custom or directory archive.
+type pgArchive struct {
+	format     int
+	version    int // Major, minor and revision, one byte each.
+	intSize    int
+	offSize    int
+	compressed bool
+	entries    []tocEntry
+	dir        string // Directory of directory archives.
+}
+
+// tocEntry is an entry of the table of contents of an archive.
+type tocEntry struct {
+	dumpId   int
+	desc     string // Kind of object, e.g. TABLE, TABLE DATA or INDEX.
+	tag      string
+	defn     string
+	copyStmt string
+	hasData  bool
+	filename string // Data file of directory archives.
+}
+
+// archiveReader decodes the integers and strings of an archive.
+type archiveReader struct {
+	r io.Reader
+	a *pgArchive
+}
+
+func (ar *archiveReader) readByte() (int, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(ar.r, b[:]); err != nil {
+		return 0, err
+	}
+	return int(b[0]), nil
+}
+
+// readInt reads a sign byte followed by intSize bytes of magnitude, least
+// significant first.
+func (ar *archiveReader) readInt() (int, error) {
+	b := make([]byte, ar.a.intSize+1)
+	if _, err := io.ReadFull(ar.r, b); err != nil {
+		return 0, err
+	}
+	v := 0
+	for i := ar.a.intSize; i > 0; i-- {
+		v = v<<8 | int(b[i])
+	}
+	if b[0] != 0 {
+		v = -v
+	}
+	return v, nil
+}
+
+// readStr reads a length-prefixed string. A length of -1 is a NULL string,
+// returned as "".
+func (ar *archiveReader) readStr() (string, error) {
+	n, err := ar.readInt()
+	if err != nil || n <= 0 {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(ar.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readOffset reads the data offset of a TOC entry of a custom archive, and
+// returns whether the entry has data.
+func (ar *archiveReader) readOffset() (bool, error) {
+	state, err := ar.readByte()
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.ReadFull(ar.r, make([]byte, ar.a.offSize)); err != nil {
+		return false, err
+	}
+	return state != offsetNoData, nil
+}
+
+// isPgArchive returns whether r is a custom archive or a directory archive.
+func isPgArchive(r *internal.Reader) bool {
+	if string(r.Peek(len(archiveMagic))) == archiveMagic {
+		return true
+	}
+	if r.Path == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(r.Path, archiveToc))
+	return err == nil
+}
+
+// readPgArchive reads the header and the TOC of the archive r. The data of
+// custom archives follows the TOC in r.
+func readPgArchive(r *internal.Reader) (*pgArchive, error) {
+	if string(r.Peek(len(archiveMagic))) == archiveMagic {
+		a, err := readArchiveHeader(r)
+		if err != nil {
+			return nil, err
+		}
+		if a.format != archCustom {
+			return nil, fmt.Errorf("pg_dump archive format %d is not supported, only the custom (-Fc) and directory (-Fd) formats are", a.format)
+		}
+		return a, nil
+	}
+	f, err := os.Open(filepath.Join(r.Path, archiveToc))
+	if err != nil {
+		return nil, fmt.Errorf("can't open pg_dump directory archive: %v", err)
+	}
+	defer f.Close()
+	a, err := readArchiveHeader(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	if a.format != archDirectory {
+		return nil, fmt.Errorf("%s is not the TOC of a directory archive", filepath.Join(r.Path, archiveToc))
+	}
+	a.dir = r.Path
+	return a, nil
+}
+
+func readArchiveHeader(r io.Reader) (*pgArchive, error) {
+	magic := make([]byte, len(archiveMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != archiveMagic {
+		return nil, fmt.Errorf("not a pg_dump archive")
+	}
+	a := &pgArchive{}
+	ar := &archiveReader{r: r, a: a}
+	var v [3]int
+	for i := range v {
+		b, err := ar.readByte()
+		if err != nil {
+			return nil, fmt.Errorf("can't read archive header: %v", err)
+		}
+		v[i] = b
+	}
+	a.version = v[0]<<16 | v[1]<<8 | v[2]
+	if a.version < minArchiveVersion || a.version >= maxArchiveVersion+1<<8 {
+		return nil, fmt.Errorf("pg_dump archive version %d.%d is not supported", v[0], v[1])
+	}
+	fields := []*int{&a.intSize, &a.offSize, &a.format}
+	for _, field := range fields {
+		b, err := ar.readByte()
+		if err != nil {
+			return nil, fmt.Errorf("can't read archive header: %v", err)
+		}
+		*field = b
+	}
+	if a.version >= 1<<16|15<<8 {
+		algorithm, err := ar.readByte()
+		if err != nil {
+			return nil, fmt.Errorf("can't read archive header: %v", err)
+		}
+		switch algorithm {
+		case compressionNone:
+		case compressionGzip:
+			a.compressed = true
+		default:
+			return nil, fmt.Errorf("pg_dump archive compression %d is not supported, only gzip compression is", algorithm)
+		}
+	} else {
+		level, err := ar.readInt()
+		if err != nil {
+			return nil, fmt.Errorf("can't read archive header: %v", err)
+		}
+		a.compressed = level != 0
+	}
+	// Creation time (7 ints), database name, server and pg_dump versions.
+	for i := 0; i < 7; i++ {
+		if _, err := ar.readInt(); err != nil {
+			return nil, fmt.Errorf("can't read archive header: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := ar.readStr(); err != nil {
+			return nil, fmt.Errorf("can't read archive header: %v", err)
+		}
+	}
+	if err := ar.readToc(); err != nil {
+		return nil, fmt.Errorf("can't read archive TOC: %v", err)
+	}
+	return a, nil
+}
+
+func (ar *archiveReader) readToc() error {
+	a := ar.a
+	n, err := ar.readInt()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		var te tocEntry
+		if te.dumpId, err = ar.readInt(); err != nil {
+			return err
+		}
+		// Fields are read in order; the ones that aren't needed are
+		// discarded.
+		var discard string
+		var discardInt int
+		fields := []interface{}{
+			&discardInt,  // Had dumper.
+			&discard,     // Table OID.
+			&discard,     // OID.
+			&te.tag,      //
+			&te.desc,     //
+			&discardInt,  // Section.
+			&te.defn,     //
+			&discard,     // Drop statement.
+			&te.copyStmt, //
+			&discard,     // Namespace.
+			&discard,     // Tablespace.
+		}
+		if a.version >= 1<<16|14<<8 {
+			fields = append(fields, &discard) // Table access method.
+		}
+		if a.version >= 1<<16|16<<8 {
+			fields = append(fields, &discardInt) // Relation kind.
+		}
+		fields = append(fields, &discard, &discard) // Owner, with OIDs.
+		for _, field := range fields {
+			switch f := field.(type) {
+			case *int:
+				*f, err = ar.readInt()
+			case *string:
+				*f, err = ar.readStr()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		// Dependencies, terminated by a NULL string.
+		for {
+			dep, err := ar.readInt()
+			if err != nil {
+				return err
+			}
+			if dep < 0 {
+				break
+			}
+			if _, err := io.ReadFull(ar.r, make([]byte, dep)); err != nil {
+				return err
+			}
+		}
+		switch a.format {
+		case archCustom:
+			te.hasData, err = ar.readOffset()
+		case archDirectory:
+			te.filename, err = ar.readStr()
+			te.hasData = te.filename != ""
+		}
+		if err != nil {
+			return err
+		}
+		a.entries = append(a.entries, te)
+	}
+	return nil
+}
+
+// processPgArchive does schema or data conversion of the archive read from
+// r, like processPgDump for plain dumps. The definitions of the TOC entries
+// are processed in TOC order, then the data of the tables: sequentially for
+// custom archives, and in parallel for directory archives.
+func processPgArchive(conv *internal.Conv, r *internal.Reader) error {
+	a, err := readPgArchive(r)
+	if err != nil {
+		return err
+	}
+	var data []tableData
+	for _, te := range a.entries {
+		if te.defn != "" {
+			processArchiveStatement(conv, te.defn)
+		}
+		if !te.hasData || te.copyStmt == "" {
+			continue
+		}
+		ci := processArchiveStatement(conv, te.copyStmt)
+		if ci == nil || ci.stmt != copyFrom {
+			continue
+		}
+		commonColIds, err := common.PrepareColumns(conv, ci.table, ci.cols)
+		if err != nil && !conv.SchemaMode() {
+			return err
+		}
+		data = append(data, tableData{entry: te, tableId: ci.table, commonColIds: commonColIds, srcCols: ci.cols})
+	}
+	switch a.format {
+	case archCustom:
+		err = processCustomArchiveData(conv, a, r, data)
+	case archDirectory:
+		err = processDirectoryArchiveData(conv, a, data)
+	}
+	if err != nil {
+		return err
+	}
+	internal.ResolveForeignKeyIds(conv.SrcSchema)
+	return nil
+}
+
+// tableData is a table whose data is in an archive.
+type tableData struct {
+	entry        tocEntry
+	tableId      string
+	commonColIds []string
+	srcCols      []string
+}
+
+func processArchiveStatement(conv *internal.Conv, stmt string) *copyOrInsert {
+	tree, err := pg_query.Parse(stmt)
+	if err != nil {
+		conv.Unexpected(fmt.Sprintf("Can't parse pg_dump archive statement %q: %v", strings.TrimSpace(stmt), err))
+		return nil
+	}
+	logger.Log.Debug(fmt.Sprintf("Parsed pg_dump archive statement: %d stmts (%d bytes)", len(tree.Stmts), len(stmt)))
+	return processStatements(conv, tree.Stmts)
+}
+
+// processCustomArchiveData reads the data blocks of a custom archive, which
+// follow the TOC in r in the order of the TOC.
+func processCustomArchiveData(conv *internal.Conv, a *pgArchive, r *internal.Reader, data []tableData) error {
+	tables := make(map[int]tableData)
+	for _, td := range data {
+		tables[td.entry.dumpId] = td
+	}
+	ar := &archiveReader{r: r, a: a}
+	for len(tables) > 0 {
+		blkType, err := ar.readByte()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("can't read archive data block: %v", err)
+		}
+		dumpId, err := ar.readInt()
+		if err != nil {
+			return fmt.Errorf("can't read archive data block: %v", err)
+		}
+		switch blkType {
+		case blkData:
+			cr := &chunkReader{ar: ar}
+			if td, ok := tables[dumpId]; ok {
+				delete(tables, dumpId)
+				if err := processArchiveCopyData(conv, td, cr, a.compressed, zlibReader); err != nil {
+					return err
+				}
+			}
+			if _, err := io.Copy(io.Discard, cr); err != nil {
+				return fmt.Errorf("can't read archive data block: %v", err)
+			}
+		case blkBlobs:
+			// Large objects aren't migrated: skip the chunks of each
+			// object, up to the terminating OID 0.
+			for {
+				oid, err := ar.readInt()
+				if err != nil {
+					return fmt.Errorf("can't read archive data block: %v", err)
+				}
+				if oid == 0 {
+					break
+				}
+				if _, err := io.Copy(io.Discard, &chunkReader{ar: ar}); err != nil {
+					return fmt.Errorf("can't read archive data block: %v", err)
+				}
+			}
+		default:
+			return fmt.Errorf("unknown archive data block type %d", blkType)
+		}
+	}
+	for _, td := range tables {
+		conv.Unexpected(fmt.Sprintf("Data of table %s not found in pg_dump archive", td.entry.tag))
+	}
+	return nil
+}
+
+// chunkReader reads the chunks of a data block of a custom archive. Each
+// chunk is prefixed with its length, and a chunk of length 0 ends the block.
+type chunkReader struct {
+	ar   *archiveReader
+	left int
+	done bool
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	for cr.left == 0 {
+		if cr.done {
+			return 0, io.EOF
+		}
+		n, err := cr.ar.readInt()
+		if err != nil {
+			return 0, err
+		}
+		cr.left = n
+		cr.done = n == 0
+	}
+	if len(p) > cr.left {
+		p = p[:cr.left]
+	}
+	n, err := cr.ar.r.Read(p)
+	cr.left -= n
+	if err == io.EOF && cr.left > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func zlibReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+func gzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// processArchiveCopyData processes the COPY data of table td read from r,
+// using processCopyBlock. The data of archives has no end-of-data marker,
+// so one is appended.
+func processArchiveCopyData(conv *internal.Conv, td tableData, r io.Reader, compressed bool, decompress func(io.Reader) (io.ReadCloser, error)) error {
+	if compressed {
+		dr, err := decompress(r)
+		if err != nil {
+			return fmt.Errorf("can't decompress data of table %s: %v", td.entry.tag, err)
+		}
+		defer dr.Close()
+		r = dr
+	}
+	cr := internal.NewReader(bufio.NewReader(io.MultiReader(r, strings.NewReader("\\.\n"))), nil)
+	processCopyBlock(conv, td.tableId, td.commonColIds, td.srcCols, cr)
+	return nil
+}
+
+// processDirectoryArchiveData reads the data files of a directory archive
+// with ArchiveReadWorkers workers. Rows are converted and written under a
+// shared lock, since conv isn't safe for concurrent use.
+func processDirectoryArchiveData(conv *internal.Conv, a *pgArchive, data []tableData) error {
+	readTable := func(td tableData, mutex *sync.Mutex) task.TaskResult[tableData] {
+		f, compressed, err := openArchiveDataFile(a.dir, td.entry.filename)
+		if err != nil {
+			return task.TaskResult[tableData]{Result: td, Err: err}
+		}
+		defer f.Close()
+		var r io.Reader = f
+		if compressed {
+			gr, err := gzip.NewReader(f)
+			if err != nil {
+				return task.TaskResult[tableData]{Result: td, Err: fmt.Errorf("can't decompress data of table %s: %v", td.entry.tag, err)}
+			}
+			defer gr.Close()
+			r = gr
+		}
+		dr := internal.NewReader(bufio.NewReader(r), nil)
+		colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[td.tableId])
+		for {
+			b := dr.ReadLine()
+			if isEndOfCopyData(b) || (dr.EOF && len(b) == 0) {
+				break
+			}
+			mutex.Lock()
+			processCopyRow(conv, td.tableId, td.commonColIds, td.srcCols, colNameIdMap, b)
+			mutex.Unlock()
+			if dr.EOF {
+				break
+			}
+		}
+		return task.TaskResult[tableData]{Result: td}
+	}
+	rpt := task.RunParallelTasksImpl[tableData, tableData]{}
+	res, _ := rpt.RunParallelTasks(data, ArchiveReadWorkers, readTable, false)
+	for _, r := range res {
+		if r.Err != nil {
+			return fmt.Errorf("can't read data of table %s: %w", r.Result.entry.tag, r.Err)
+		}
+	}
+	return nil
+}
+
+// openArchiveDataFile opens a data file of a directory archive. The TOC
+// names the uncompressed file; compressed files have a .gz suffix.
+func openArchiveDataFile(dir, filename string) (*os.File, bool, error) {
+	name := filepath.Join(dir, filename)
+	f, err := os.Open(name)
+	if err == nil {
+		return f, false, nil
+	}
+	if f, err := os.Open(name + ".gz"); err == nil {
+		return f, true, nil
+	}
+	for _, suffix := range []string{".lz4", ".zst"} {
+		if _, err := os.Stat(name + suffix); err == nil {
+			return nil, false, fmt.Errorf("compression of %s is not supported, only gzip compression is", name+suffix)
+		}
+	}
+	return nil, false, err
+}
@@ -0,0 +1,303 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/expressions_api"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/mocks"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testArchiveEntry is an entry of an archive written by writeTestArchive.
+type testArchiveEntry struct {
+	desc     string
+	tag      string
+	defn     string
+	copyStmt string
+	data     string // COPY data of TABLE DATA entries.
+}
+
+var testArchiveEntries = []testArchiveEntry{
+	{desc: "ENCODING", tag: "ENCODING", defn: "SET client_encoding = 'UTF8';\n"},
+	{desc: "TABLE", tag: "cart", defn: "CREATE TABLE public.cart (\n    productid text NOT NULL,\n    userid text NOT NULL,\n    quantity bigint\n);\n"},
+	{desc: "TABLE", tag: "product", defn: "CREATE TABLE public.product (\n    productid text NOT NULL,\n    name text\n);\n"},
+	{desc: "TABLE DATA", tag: "cart", copyStmt: "COPY public.cart (productid, userid, quantity) FROM stdin;\n", data: "901e-a6cfc2b502dc\tabc-123\t1\n9dc7-e8a0c8a8e5f5\txyz-789\t\\N\n"},
+	{desc: "TABLE DATA", tag: "product", copyStmt: "COPY public.product (productid, name) FROM stdin;\n", data: "901e-a6cfc2b502dc\ta\\\\b\n"},
+	{desc: "CONSTRAINT", tag: "cart cart_pkey", defn: "ALTER TABLE ONLY public.cart\n    ADD CONSTRAINT cart_pkey PRIMARY KEY (productid, userid);\n"},
+	{desc: "CONSTRAINT", tag: "product product_pkey", defn: "ALTER TABLE ONLY public.product\n    ADD CONSTRAINT product_pkey PRIMARY KEY (productid);\n"},
+	{desc: "INDEX", tag: "idx", defn: "CREATE INDEX idx ON public.cart USING btree (quantity);\n"},
+	{desc: "FK CONSTRAINT", tag: "cart cart_fkey", defn: "ALTER TABLE ONLY public.cart\n    ADD CONSTRAINT cart_fkey FOREIGN KEY (productid) REFERENCES public.product(productid);\n"},
+}
+
+// testArchivePlainDump is the plain dump equivalent of testArchiveEntries.
+func testArchivePlainDump() string {
+	var s strings.Builder
+	for _, e := range testArchiveEntries {
+		s.WriteString(e.defn)
+		if e.copyStmt != "" {
+			s.WriteString(e.copyStmt + e.data + "\\.\n")
+		}
+	}
+	return s.String()
+}
+
+// testArchiveWriter writes the integers and strings of an archive, with
+// 4-byte integers and 8-byte offsets.
+type testArchiveWriter struct {
+	bytes.Buffer
+}
+
+func (w *testArchiveWriter) writeInt(v int) {
+	if v < 0 {
+		w.WriteByte(1)
+		v = -v
+	} else {
+		w.WriteByte(0)
+	}
+	for i := 0; i < 4; i++ {
+		w.WriteByte(byte(v >> (8 * i)))
+	}
+}
+
+func (w *testArchiveWriter) writeStr(s string) {
+	if s == "" {
+		w.writeInt(-1)
+		return
+	}
+	w.writeInt(len(s))
+	w.WriteString(s)
+}
+
+// writeTestArchive returns the header and TOC of an archive of
+// testArchiveEntries. TABLE DATA entries have dump ids 4 and 5.
+func writeTestArchive(vmin, format int, compressed bool) *testArchiveWriter {
+	w := &testArchiveWriter{}
+	w.WriteString(archiveMagic)
+	w.Write([]byte{1, byte(vmin), 0, 4, 8, byte(format)})
+	if vmin >= 15 {
+		if compressed {
+			w.WriteByte(compressionGzip)
+		} else {
+			w.WriteByte(compressionNone)
+		}
+	} else if compressed {
+		w.writeInt(-1)
+	} else {
+		w.writeInt(0)
+	}
+	for _, v := range []int{0, 0, 12, 1, 0, 124, 0} {
+		w.writeInt(v)
+	}
+	w.writeStr("test")
+	w.writeStr("16.1")
+	w.writeStr("16.1")
+	w.writeInt(len(testArchiveEntries))
+	for i, e := range testArchiveEntries {
+		w.writeInt(i + 1)
+		w.writeInt(1)
+		w.writeStr("0")
+		w.writeStr("0")
+		w.writeStr(e.tag)
+		w.writeStr(e.desc)
+		w.writeInt(2)
+		w.writeStr(e.defn)
+		w.writeStr("")
+		w.writeStr(e.copyStmt)
+		w.writeStr("public")
+		w.writeStr("")
+		if vmin >= 14 {
+			w.writeStr("heap")
+		}
+		if vmin >= 16 {
+			w.writeInt(int('r'))
+		}
+		w.writeStr("postgres")
+		w.writeStr("false")
+		w.writeStr("2")
+		w.writeStr("")
+		switch format {
+		case archDirectory:
+			if e.data != "" {
+				w.writeStr(fmt.Sprintf("%d.dat", i+1))
+			} else {
+				w.writeStr("")
+			}
+		default:
+			if e.data != "" {
+				w.WriteByte(1)
+			} else {
+				w.WriteByte(offsetNoData)
+			}
+			w.Write(make([]byte, 8))
+		}
+	}
+	return w
+}
+
+// writeTestCustomArchive returns a custom archive of testArchiveEntries,
+// whose data blocks are written in the reverse order of the TOC and are
+// followed by a blobs block.
+func writeTestCustomArchive(vmin int, compressed bool) []byte {
+	w := writeTestArchive(vmin, archCustom, compressed)
+	for i := len(testArchiveEntries) - 1; i >= 0; i-- {
+		e := testArchiveEntries[i]
+		if e.data == "" {
+			continue
+		}
+		w.WriteByte(blkData)
+		w.writeInt(i + 1)
+		data := []byte(e.data)
+		if compressed {
+			var b bytes.Buffer
+			zw := zlib.NewWriter(&b)
+			zw.Write(data)
+			zw.Close()
+			data = b.Bytes()
+		}
+		// Write the data in two chunks.
+		w.writeInt(len(data) / 2)
+		w.Write(data[:len(data)/2])
+		w.writeInt(len(data) - len(data)/2)
+		w.Write(data[len(data)/2:])
+		w.writeInt(0)
+	}
+	w.WriteByte(blkBlobs)
+	w.writeInt(len(testArchiveEntries) + 1)
+	w.writeInt(16385)
+	w.writeInt(3)
+	w.WriteString("abc")
+	w.writeInt(0)
+	w.writeInt(0)
+	return w.Bytes()
+}
+
+// writeTestDirectoryArchive writes a directory archive of
+// testArchiveEntries to a new directory. The data file of the first table
+// is gzip compressed.
+func writeTestDirectoryArchive(t *testing.T) string {
+	dir := t.TempDir()
+	w := writeTestArchive(16, archDirectory, false)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, archiveToc), w.Bytes(), 0644))
+	compressed := true
+	for i, e := range testArchiveEntries {
+		if e.data == "" {
+			continue
+		}
+		name := filepath.Join(dir, fmt.Sprintf("%d.dat", i+1))
+		data := []byte(e.data)
+		if compressed {
+			var b bytes.Buffer
+			gw := gzip.NewWriter(&b)
+			gw.Write(data)
+			gw.Close()
+			name, data = name+".gz", b.Bytes()
+			compressed = false
+		}
+		assert.Nil(t, os.WriteFile(name, data, 0644))
+	}
+	return dir
+}
+
+// runProcessPgArchive does schema and data conversion of the archive read
+// from the readers returned by newReader.
+func runProcessPgArchive(newReader func() *internal.Reader) (*internal.Conv, []spannerData, error) {
+	conv := internal.MakeConv()
+	conv.SetLocation(time.UTC)
+	conv.SetSchemaMode()
+	mockAccessor := new(mocks.MockExpressionVerificationAccessor)
+	mockAccessor.On("VerifyExpressions", context.Background(), mock.Anything).Return(internal.VerifyExpressionsOutput{})
+	pgDump := DbDumpImpl{}
+	if err := common.ProcessDbDump(conv, newReader(), pgDump, &expressions_api.MockDDLVerifier{}, mockAccessor); err != nil {
+		return nil, nil, err
+	}
+	conv.SetDataMode()
+	var rows []spannerData
+	conv.SetDataSink(
+		func(table string, cols []string, vals []interface{}) {
+			rows = append(rows, spannerData{table: table, cols: cols, vals: vals})
+		})
+	err := common.ProcessDbDump(conv, newReader(), pgDump, &expressions_api.MockDDLVerifier{}, mockAccessor)
+	return conv, rows, err
+}
+
+func TestProcessPgArchive(t *testing.T) {
+	customArchive := func(vmin int, compressed bool) func() *internal.Reader {
+		b := writeTestCustomArchive(vmin, compressed)
+		return func() *internal.Reader {
+			return internal.NewReader(bufio.NewReader(bytes.NewReader(b)), nil)
+		}
+	}
+	dir := writeTestDirectoryArchive(t)
+	directoryArchive := func() *internal.Reader {
+		f, err := os.Open(dir)
+		assert.Nil(t, err)
+		r := internal.NewReader(bufio.NewReader(f), nil)
+		r.Path = dir
+		return r
+	}
+	wantConv, wantRows := runProcessPgDump(testArchivePlainDump())
+	wantDdl := ddl.GetDDL(ddl.Config{Tables: true, ForeignKeys: true, Source: constants.POSTGRES}, wantConv.SpSchema, wantConv.SpSequences)
+	tests := []struct {
+		name      string
+		newReader func() *internal.Reader
+	}{
+		{"custom archive", customArchive(14, false)},
+		{"custom archive with zlib compression", customArchive(12, true)},
+		{"custom archive with gzip algorithm", customArchive(16, true)},
+		{"directory archive", directoryArchive},
+	}
+	for _, tc := range tests {
+		conv, rows, err := runProcessPgArchive(tc.newReader)
+		assert.Nil(t, err, tc.name)
+		noIssues(conv, t, tc.name)
+		assert.Equal(t, wantDdl, ddl.GetDDL(ddl.Config{Tables: true, ForeignKeys: true, Source: constants.POSTGRES}, conv.SpSchema, conv.SpSequences), tc.name)
+		assert.ElementsMatch(t, wantRows, rows, tc.name)
+		assert.Equal(t, int64(3), conv.Rows(), tc.name)
+	}
+}
+
+func TestProcessPgArchive_Unsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{"tar archive", writeTestArchive(16, archTar, false).Bytes()},
+		{"lz4 compression", append([]byte(archiveMagic), 1, 16, 0, 4, 8, archCustom, 2)},
+		{"unknown version", append([]byte(archiveMagic), 1, 17, 0, 4, 8, archCustom, 0)},
+	}
+	for _, tc := range tests {
+		_, _, err := runProcessPgArchive(func() *internal.Reader {
+			return internal.NewReader(bufio.NewReader(bytes.NewReader(tc.archive)), nil)
+		})
+		assert.NotNil(t, err, tc.name)
+	}
+}
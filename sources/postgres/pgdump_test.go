@@ -1446,6 +1446,29 @@ func TestProcessPgDump_GetPGDDL(t *testing.T) {
 	assert.Equal(t, expected, strings.Join(ddl.GetDDL(c, conv.SpSchema, conv.SpSequences), " "))
 }
 
+func TestProcessPgDump_GeneratedColumns(t *testing.T) {
+	conv, rows := runProcessPgDump("CREATE TABLE cart (productid text PRIMARY KEY, quantity bigint, total bigint GENERATED ALWAYS AS (quantity * 2) STORED);\n" +
+		"INSERT INTO cart (productid, quantity) VALUES ('a42', 2);")
+	noIssues(conv, t, "generated columns")
+	tableId, err := internal.GetTableIdFromSrcName(conv.SrcSchema, "cart")
+	assert.Nil(t, err)
+	colId, err := internal.GetColIdFromSrcName(conv.SrcSchema[tableId].ColDefs, "total")
+	assert.Nil(t, err)
+	generated := conv.SrcSchema[tableId].ColDefs[colId].Generated
+	assert.True(t, generated.IsPresent)
+	assert.True(t, generated.Stored)
+	assert.Equal(t, "quantity * 2", generated.Value.Statement)
+	expected :=
+		"CREATE TABLE cart (\n" +
+			"	productid STRING(MAX) NOT NULL ,\n" +
+			"	quantity INT64,\n" +
+			"	total INT64 AS (`quantity` * 2) STORED,\n" +
+			") PRIMARY KEY (productid)"
+	c := ddl.Config{Tables: true}
+	assert.Equal(t, expected, strings.Join(ddl.GetDDL(c, conv.SpSchema, conv.SpSequences), " "))
+	assert.Equal(t, []spannerData{{table: "cart", cols: []string{"productid", "quantity"}, vals: []interface{}{"a42", int64(2)}}}, rows)
+}
+
 func TestProcessPgDump_Rows(t *testing.T) {
 	conv, _ := runProcessPgDump("CREATE TABLE cart (a text, n bigint);\n" +
 		"INSERT INTO cart (a, n) VALUES ('a42', 2);")
@@ -0,0 +1,239 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// Limits of the NUMERIC type of the GoogleSQL dialect, which is narrower
+// than the PostgreSQL dialect numeric.
+const (
+	maxNumericIntDigits  = 29
+	maxNumericFracDigits = 9
+)
+
+var typeCodes = map[string]sppb.TypeCode{
+	ddl.Bool:      sppb.TypeCode_BOOL,
+	ddl.Bytes:     sppb.TypeCode_BYTES,
+	ddl.Date:      sppb.TypeCode_DATE,
+	ddl.Float32:   sppb.TypeCode_FLOAT32,
+	ddl.Float64:   sppb.TypeCode_FLOAT64,
+	ddl.Int64:     sppb.TypeCode_INT64,
+	ddl.JSON:      sppb.TypeCode_JSON,
+	ddl.Numeric:   sppb.TypeCode_NUMERIC,
+	ddl.String:    sppb.TypeCode_STRING,
+	ddl.Timestamp: sppb.TypeCode_TIMESTAMP,
+}
+
+// ConvertValue converts value v read from a Spanner database to a value of
+// type t of a database of the given dialect. Values keep their encoding,
+// except for arrays stored in string columns, which are encoded as JSON
+// arrays.
+func ConvertValue(v spanner.GenericColumnValue, t ddl.Type, dialect string) (spanner.GenericColumnValue, error) {
+	target, err := spannerType(t, dialect)
+	if err != nil {
+		return spanner.GenericColumnValue{}, err
+	}
+	if _, isNull := v.Value.GetKind().(*structpb.Value_NullValue); isNull {
+		return spanner.GenericColumnValue{Type: target, Value: v.Value}, nil
+	}
+	if v.Type.GetCode() == sppb.TypeCode_ARRAY && target.Code != sppb.TypeCode_ARRAY {
+		if target.Code != sppb.TypeCode_STRING {
+			return spanner.GenericColumnValue{}, fmt.Errorf("can't convert an array to %s", t.Name)
+		}
+		s, err := arrayToJSON(v.Value.GetListValue(), v.Type.GetArrayElementType().GetCode())
+		if err != nil {
+			return spanner.GenericColumnValue{}, err
+		}
+		return spanner.GenericColumnValue{Type: target, Value: structpb.NewStringValue(s)}, nil
+	}
+	if dialect == constants.DIALECT_GOOGLESQL && target.Code == sppb.TypeCode_NUMERIC {
+		if err := checkNumeric(v.Value.GetStringValue()); err != nil {
+			return spanner.GenericColumnValue{}, err
+		}
+	}
+	if dialect == constants.DIALECT_GOOGLESQL && target.Code == sppb.TypeCode_ARRAY &&
+		target.ArrayElementType.Code == sppb.TypeCode_NUMERIC {
+		for _, elem := range v.Value.GetListValue().GetValues() {
+			if _, isNull := elem.GetKind().(*structpb.Value_NullValue); isNull {
+				continue
+			}
+			if err := checkNumeric(elem.GetStringValue()); err != nil {
+				return spanner.GenericColumnValue{}, err
+			}
+		}
+	}
+	return spanner.GenericColumnValue{Type: target, Value: v.Value}, nil
+}
+
+// spannerType returns the Spanner type of a column of type t in a database
+// of the given dialect.
+func spannerType(t ddl.Type, dialect string) (*sppb.Type, error) {
+	code, ok := typeCodes[t.Name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %s", t.Name)
+	}
+	ty := &sppb.Type{Code: code}
+	if dialect == constants.DIALECT_POSTGRESQL {
+		switch code {
+		case sppb.TypeCode_NUMERIC:
+			ty.TypeAnnotation = sppb.TypeAnnotationCode_PG_NUMERIC
+		case sppb.TypeCode_JSON:
+			ty.TypeAnnotation = sppb.TypeAnnotationCode_PG_JSONB
+		}
+	}
+	if t.IsArray {
+		return &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: ty}, nil
+	}
+	return ty, nil
+}
+
+// arrayToJSON encodes the elements of an array of type elemCode as a JSON
+// array. INT64 and NUMERIC elements, which are encoded as strings, are
+// written as JSON numbers.
+func arrayToJSON(list *structpb.ListValue, elemCode sppb.TypeCode) (string, error) {
+	elems := make([]string, 0, len(list.GetValues()))
+	for _, elem := range list.GetValues() {
+		switch k := elem.GetKind().(type) {
+		case *structpb.Value_NullValue:
+			elems = append(elems, "null")
+		case *structpb.Value_StringValue:
+			if elemCode == sppb.TypeCode_INT64 || elemCode == sppb.TypeCode_NUMERIC {
+				elems = append(elems, k.StringValue)
+				continue
+			}
+			b, err := json.Marshal(k.StringValue)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, string(b))
+		default:
+			b, err := elem.MarshalJSON()
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, string(b))
+		}
+	}
+	return "[" + strings.Join(elems, ",") + "]", nil
+}
+
+// checkNumeric returns an error if numeric value s doesn't fit in the NUMERIC
+// type of the GoogleSQL dialect.
+func checkNumeric(s string) error {
+	if strings.EqualFold(s, "NaN") {
+		return fmt.Errorf("NaN is not a valid NUMERIC value")
+	}
+	digits := strings.TrimLeft(s, "+-")
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	intPart = strings.TrimLeft(intPart, "0")
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(intPart) > maxNumericIntDigits || len(fracPart) > maxNumericFracDigits {
+		return fmt.Errorf("%s is out of the range of NUMERIC values", s)
+	}
+	return nil
+}
+
+// CountRows returns the number of rows of table in a database of the given
+// dialect.
+func CountRows(ctx context.Context, client *spanner.Client, table, dialect string) (int64, error) {
+	quoted := "`" + table + "`"
+	if dialect == constants.DIALECT_POSTGRESQL {
+		quoted = `"` + table + `"`
+	}
+	iter := client.Single().Query(ctx, spanner.Statement{SQL: "SELECT COUNT(*) FROM " + quoted})
+	defer iter.Stop()
+	row, err := iter.Next()
+	if err != nil {
+		return 0, fmt.Errorf("can't count rows of table %s: %v", table, err)
+	}
+	var count int64
+	if err := row.Columns(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CopyRows reads all rows of table srcTable of the source database and
+// writes them to table spTable of conv.SpSchema using conv.WriteRow. Rows
+// that can't be converted are recorded as bad rows.
+func CopyRows(ctx context.Context, conv *internal.Conv, client *spanner.Client, srcTable, spTable ddl.CreateTable) error {
+	var srcCols, spCols []string
+	var types []ddl.Type
+	for _, colId := range srcTable.ColIds {
+		// Generated columns are computed by the target database.
+		if spTable.ColDefs[colId].Generated.IsPresent {
+			continue
+		}
+		srcCols = append(srcCols, srcTable.ColDefs[colId].Name)
+		spCols = append(spCols, spTable.ColDefs[colId].Name)
+		types = append(types, spTable.ColDefs[colId].T)
+	}
+	iter := client.Single().Read(ctx, srcTable.Name, spanner.AllKeys(), srcCols)
+	defer iter.Stop()
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("can't read rows of table %s: %v", srcTable.Name, err)
+		}
+		vals := make([]interface{}, len(srcCols))
+		var convErr error
+		for i := range srcCols {
+			var v spanner.GenericColumnValue
+			if err := row.Column(i, &v); err != nil {
+				return err
+			}
+			vals[i], err = ConvertValue(v, types[i], conv.SpDialect)
+			if err != nil && convErr == nil {
+				convErr = fmt.Errorf("column %s: %v", srcCols[i], err)
+			}
+		}
+		srcVals := rowStrings(row)
+		if convErr != nil {
+			conv.Unexpected(fmt.Sprintf("Can't convert row of table %s: %v", srcTable.Name, convErr))
+			conv.StatsAddBadRow(srcTable.Name, conv.DataMode())
+			conv.CollectBadRow(srcTable.Name, srcCols, srcVals, convErr)
+			continue
+		}
+		conv.WriteRowWithSource(srcTable.Name, srcCols, srcVals, spTable.Name, spCols, vals)
+	}
+}
+
+func rowStrings(row *spanner.Row) []string {
+	vals := make([]string, row.Size())
+	for i := range vals {
+		var v spanner.GenericColumnValue
+		if err := row.Column(i, &v); err == nil {
+			vals[i] = fmt.Sprint(v.Value.AsInterface())
+		}
+	}
+	return vals
+}
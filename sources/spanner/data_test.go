@@ -0,0 +1,136 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanner
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestConvertValue(t *testing.T) {
+	list := func(vals ...*structpb.Value) *structpb.Value {
+		return structpb.NewListValue(&structpb.ListValue{Values: vals})
+	}
+	arrayOf := func(code sppb.TypeCode) *sppb.Type {
+		return &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: &sppb.Type{Code: code}}
+	}
+	tests := []struct {
+		name    string
+		v       spanner.GenericColumnValue
+		t       ddl.Type
+		dialect string
+		want    spanner.GenericColumnValue
+		wantErr bool
+	}{
+		{
+			name:    "int64 to postgresql",
+			v:       spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_INT64}, Value: structpb.NewStringValue("42")},
+			t:       ddl.Type{Name: ddl.Int64},
+			dialect: constants.DIALECT_POSTGRESQL,
+			want:    spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_INT64}, Value: structpb.NewStringValue("42")},
+		},
+		{
+			name:    "numeric to postgresql",
+			v:       spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_NUMERIC}, Value: structpb.NewStringValue("1.5")},
+			t:       ddl.Type{Name: ddl.Numeric},
+			dialect: constants.DIALECT_POSTGRESQL,
+			want:    spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_NUMERIC, TypeAnnotation: sppb.TypeAnnotationCode_PG_NUMERIC}, Value: structpb.NewStringValue("1.5")},
+		},
+		{
+			name:    "numeric key to postgresql varchar",
+			v:       spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_NUMERIC}, Value: structpb.NewStringValue("1.5")},
+			t:       ddl.Type{Name: ddl.String, Len: ddl.MaxLength},
+			dialect: constants.DIALECT_POSTGRESQL,
+			want:    spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_STRING}, Value: structpb.NewStringValue("1.5")},
+		},
+		{
+			name:    "json to postgresql",
+			v:       spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_JSON}, Value: structpb.NewStringValue(`{"a":1}`)},
+			t:       ddl.Type{Name: ddl.JSON},
+			dialect: constants.DIALECT_POSTGRESQL,
+			want:    spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_JSON, TypeAnnotation: sppb.TypeAnnotationCode_PG_JSONB}, Value: structpb.NewStringValue(`{"a":1}`)},
+		},
+		{
+			name:    "array to postgresql varchar",
+			v:       spanner.GenericColumnValue{Type: arrayOf(sppb.TypeCode_INT64), Value: list(structpb.NewStringValue("1"), structpb.NewNullValue(), structpb.NewStringValue("3"))},
+			t:       ddl.Type{Name: ddl.String, Len: ddl.MaxLength},
+			dialect: constants.DIALECT_POSTGRESQL,
+			want:    spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_STRING}, Value: structpb.NewStringValue("[1,null,3]")},
+		},
+		{
+			name:    "string array to postgresql varchar",
+			v:       spanner.GenericColumnValue{Type: arrayOf(sppb.TypeCode_STRING), Value: list(structpb.NewStringValue(`a"b`), structpb.NewBoolValue(true))},
+			t:       ddl.Type{Name: ddl.String, Len: ddl.MaxLength},
+			dialect: constants.DIALECT_POSTGRESQL,
+			want:    spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_STRING}, Value: structpb.NewStringValue(`["a\"b",true]`)},
+		},
+		{
+			name:    "null array to postgresql varchar",
+			v:       spanner.GenericColumnValue{Type: arrayOf(sppb.TypeCode_INT64), Value: structpb.NewNullValue()},
+			t:       ddl.Type{Name: ddl.String, Len: ddl.MaxLength},
+			dialect: constants.DIALECT_POSTGRESQL,
+			want:    spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_STRING}, Value: structpb.NewNullValue()},
+		},
+		{
+			name:    "numeric to googlesql",
+			v:       spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_NUMERIC, TypeAnnotation: sppb.TypeAnnotationCode_PG_NUMERIC}, Value: structpb.NewStringValue("12.500000000000")},
+			t:       ddl.Type{Name: ddl.Numeric},
+			dialect: constants.DIALECT_GOOGLESQL,
+			want:    spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_NUMERIC}, Value: structpb.NewStringValue("12.500000000000")},
+		},
+		{
+			name:    "NaN to googlesql",
+			v:       spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_NUMERIC, TypeAnnotation: sppb.TypeAnnotationCode_PG_NUMERIC}, Value: structpb.NewStringValue("NaN")},
+			t:       ddl.Type{Name: ddl.Numeric},
+			dialect: constants.DIALECT_GOOGLESQL,
+			wantErr: true,
+		},
+		{
+			name:    "numeric out of range to googlesql",
+			v:       spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_NUMERIC, TypeAnnotation: sppb.TypeAnnotationCode_PG_NUMERIC}, Value: structpb.NewStringValue("0.1234567891")},
+			t:       ddl.Type{Name: ddl.Numeric},
+			dialect: constants.DIALECT_GOOGLESQL,
+			wantErr: true,
+		},
+		{
+			name:    "numeric array out of range to googlesql",
+			v:       spanner.GenericColumnValue{Type: arrayOf(sppb.TypeCode_NUMERIC), Value: list(structpb.NewStringValue("1"), structpb.NewStringValue("1000000000000000000000000000000"))},
+			t:       ddl.Type{Name: ddl.Numeric, IsArray: true},
+			dialect: constants.DIALECT_GOOGLESQL,
+			wantErr: true,
+		},
+		{
+			name:    "array to bool",
+			v:       spanner.GenericColumnValue{Type: arrayOf(sppb.TypeCode_BOOL), Value: list(structpb.NewBoolValue(true))},
+			t:       ddl.Type{Name: ddl.Bool},
+			dialect: constants.DIALECT_POSTGRESQL,
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		got, err := ConvertValue(tc.v, tc.t, tc.dialect)
+		assert.Equal(t, tc.wantErr, err != nil, tc.name)
+		if !tc.wantErr {
+			assert.Equal(t, tc.want.Type.String(), got.Type.String(), tc.name)
+			assert.Equal(t, tc.want.Value.AsInterface(), got.Value.AsInterface(), tc.name)
+		}
+	}
+}
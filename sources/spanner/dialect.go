@@ -0,0 +1,224 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+// DialectIssue describes a part of the schema of a Spanner database that is
+// dropped or changed when the database is converted to the other dialect.
+type DialectIssue struct {
+	Table  string // Empty for statements that don't belong to a table.
+	Object string // Column, index, constraint or statement affected.
+	Issue  string
+}
+
+// String returns a one line description of the issue.
+func (di DialectIssue) String() string {
+	if di.Table == "" {
+		return fmt.Sprintf("%s: %s", di.Object, di.Issue)
+	}
+	return fmt.Sprintf("table %s, %s: %s", di.Table, di.Object, di.Issue)
+}
+
+var (
+	createTableRe       = regexp.MustCompile(`(?is)^CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)`)
+	createIndexRe       = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?(NULL_FILTERED\s+)?INDEX\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)\s+ON\s+([^\s(]+)`)
+	createSequenceRe    = regexp.MustCompile(`(?is)^CREATE\s+SEQUENCE\b`)
+	alterTableAddRe     = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+\S+\s+ADD\s+(?:CONSTRAINT|FOREIGN|CHECK)\b`)
+	commitTimestampRe   = regexp.MustCompile("(?is)(`[^`]+`|[A-Za-z_][A-Za-z0-9_]*)\\s+TIMESTAMP\\b[^,]*?OPTIONS\\s*\\(\\s*allow_commit_timestamp\\s*=\\s*true\\s*\\)")
+	pgCommitTimestampRe = regexp.MustCompile(`(?i)("[^"]+"|[A-Za-z_][A-Za-z0-9_]*)\s+spanner\.commit_timestamp\b`)
+	rowDeletionPolicyRe = regexp.MustCompile(`(?i)\bROW\s+DELETION\s+POLICY\b`)
+	pgTTLRe             = regexp.MustCompile(`(?is)\bTTL\s+INTERVAL\s+'[^']*'\s+ON\s+\S+`)
+	indexInterleaveRe   = regexp.MustCompile(`(?i)\bINTERLEAVE\s+IN\b`)
+	indexWhereRe        = regexp.MustCompile(`(?i)\bWHERE\b`)
+	indexWhereClauseRe  = regexp.MustCompile(`(?is)\s+WHERE\s.*?(,\s*INTERLEAVE\s+IN\s|$)`)
+	indexUsingRe        = regexp.MustCompile(`(?i)\s+USING\s+btree\b`)
+)
+
+// ConvertDialect parses the DDL statements of a Spanner database of dialect
+// srcDialect, as returned by GetDatabaseDdl, and sets conv.SpSchema and
+// conv.SpSequences to the equivalent schema in dialect conv.SpDialect.
+// It returns the schema of the source database, whose table and column ids
+// are those of conv.SpSchema, and the parts of the schema that are dropped
+// or changed by the conversion.
+func ConvertDialect(conv *internal.Conv, statements []string, srcDialect string) (ddl.Schema, []DialectIssue, error) {
+	stmts, issues := filterStatements(statements, srcDialect)
+	srcSchema, sequences, err := ddl.ParseDDL(strings.Join(stmts, ";\n"), srcDialect, internal.GenerateId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't parse schema of the source database: %v", err)
+	}
+	conv.SpSchema = ddl.NewSchema()
+	for _, tableId := range ddl.GetSortedTableIdsBySpName(srcSchema) {
+		ct, tableIssues := convertTable(srcSchema[tableId], srcDialect, conv.SpDialect)
+		conv.SpSchema[tableId] = ct
+		issues = append(issues, tableIssues...)
+	}
+	conv.SpSequences = make(map[string]ddl.Sequence)
+	for id, seq := range sequences {
+		// Bit reversed positive is the only kind of sequence, but it may be
+		// left implicit in the source database.
+		if seq.SequenceKind == "" {
+			seq.SequenceKind = "BIT REVERSED POSITIVE"
+		}
+		conv.SpSequences[id] = seq
+	}
+	return srcSchema, issues, nil
+}
+
+// filterStatements returns the statements that define tables, indexes,
+// sequences and constraints, with the clauses ddl.ParseDDL doesn't support
+// removed, and reports the other statements and the clauses that are
+// dropped.
+func filterStatements(statements []string, dialect string) ([]string, []DialectIssue) {
+	var stmts []string
+	var issues []DialectIssue
+	for _, stmt := range statements {
+		stmt = strings.TrimSpace(stmt)
+		switch {
+		case createTableRe.MatchString(stmt):
+			table := unquoteName(createTableRe.FindStringSubmatch(stmt)[1])
+			for _, m := range commitTimestampRe.FindAllStringSubmatch(stmt, -1) {
+				issues = append(issues, DialectIssue{Table: table, Object: "column " + unquoteName(m[1]),
+					Issue: "the allow_commit_timestamp option is dropped, the column can't be set to the commit timestamp"})
+			}
+			for _, m := range pgCommitTimestampRe.FindAllStringSubmatch(stmt, -1) {
+				issues = append(issues, DialectIssue{Table: table, Object: "column " + unquoteName(m[1]),
+					Issue: "spanner.commit_timestamp is converted to a timestamp column that can't be set to the commit timestamp"})
+			}
+			stmt = pgCommitTimestampRe.ReplaceAllString(stmt, "$1 timestamptz")
+			if rowDeletionPolicyRe.MatchString(stmt) || pgTTLRe.MatchString(stmt) {
+				issues = append(issues, DialectIssue{Table: table, Object: "row deletion policy", Issue: "the row deletion policy is dropped"})
+				stmt = pgTTLRe.ReplaceAllString(stmt, "")
+			}
+		case createIndexRe.MatchString(stmt):
+			m := createIndexRe.FindStringSubmatch(stmt)
+			table, object := unquoteName(m[3]), "index "+unquoteName(m[2])
+			if m[1] != "" {
+				issues = append(issues, DialectIssue{Table: table, Object: object, Issue: "NULL_FILTERED is dropped, rows with NULL keys are indexed"})
+			}
+			if indexWhereRe.MatchString(stmt) {
+				issues = append(issues, DialectIssue{Table: table, Object: object, Issue: "the WHERE filter is dropped, rows with NULL keys are indexed"})
+				// spansql doesn't parse the filters of GoogleSQL indexes.
+				if dialect != constants.DIALECT_POSTGRESQL {
+					stmt = indexWhereClauseRe.ReplaceAllString(stmt, "$1")
+				}
+			}
+			if indexInterleaveRe.MatchString(stmt) {
+				issues = append(issues, DialectIssue{Table: table, Object: object, Issue: "the index is no longer interleaved"})
+			}
+			// btree is the only index method of PostgreSQL databases.
+			stmt = indexUsingRe.ReplaceAllString(stmt, "")
+		case createSequenceRe.MatchString(stmt), alterTableAddRe.MatchString(stmt):
+		default:
+			issues = append(issues, DialectIssue{Object: statementHead(stmt),
+				Issue: "only tables, indexes, sequences and constraints are converted, the statement is skipped"})
+			continue
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts, issues
+}
+
+// convertTable converts the types, default values and check constraints of
+// table src to dialect. The schema issues of ToPGDialectType are applied to
+// PostgreSQL dialect tables, like for other sources.
+func convertTable(src ddl.CreateTable, srcDialect, dialect string) (ddl.CreateTable, []DialectIssue) {
+	ct := src
+	ct.ColDefs = make(map[string]ddl.ColumnDef, len(src.ColDefs))
+	var issues []DialectIssue
+	for _, colId := range src.ColIds {
+		col := src.ColDefs[colId]
+		object := "column " + col.Name
+		if dialect == constants.DIALECT_POSTGRESQL {
+			if col.T.Name == ddl.Bytes && col.T.Len != ddl.MaxLength {
+				issues = append(issues, DialectIssue{Table: src.Name, Object: object, Issue: fmt.Sprintf("bytea has no maximum length, the limit of %d bytes is dropped", col.T.Len)})
+			}
+			ty, pgIssues := common.ToPGDialectType(col.T, isPrimaryKey(src, colId))
+			for _, issue := range pgIssues {
+				switch issue {
+				case internal.ArrayTypeNotSupported:
+					issues = append(issues, DialectIssue{Table: src.Name, Object: object, Issue: "array columns are converted to varchar columns holding a JSON array"})
+				case internal.NumericPKNotSupported:
+					issues = append(issues, DialectIssue{Table: src.Name, Object: object, Issue: "numeric key columns aren't supported, the column is converted to varchar"})
+				}
+			}
+			col.T = ty
+		}
+		if col.DefaultValue.IsPresent {
+			expr, err := translateExpression(col.DefaultValue.Value.Statement, src, srcDialect, dialect)
+			if err != nil {
+				issues = append(issues, DialectIssue{Table: src.Name, Object: object, Issue: fmt.Sprintf("default value %s is dropped: %v", col.DefaultValue.Value.Statement, err)})
+				col.DefaultValue = ddl.DefaultValue{}
+			} else {
+				col.DefaultValue.Value.Statement = expr
+			}
+		}
+		if col.Generated.IsPresent {
+			expr, err := translateExpression(col.Generated.Value.Statement, src, srcDialect, dialect)
+			if err != nil {
+				issues = append(issues, DialectIssue{Table: src.Name, Object: object, Issue: fmt.Sprintf("generation expression %s is dropped, the column is converted to a plain column: %v", col.Generated.Value.Statement, err)})
+				col.Generated = ddl.GeneratedColumn{}
+			} else {
+				col.Generated.Value.Statement = expr
+			}
+		}
+		ct.ColDefs[colId] = col
+	}
+	ct.CheckConstraints = nil
+	for _, cc := range src.CheckConstraints {
+		expr, err := translateExpression(cc.Expr, src, srcDialect, dialect)
+		if err != nil {
+			issues = append(issues, DialectIssue{Table: src.Name, Object: "check constraint " + cc.Name, Issue: fmt.Sprintf("check %s is dropped: %v", cc.Expr, err)})
+			continue
+		}
+		cc.Expr = expr
+		ct.CheckConstraints = append(ct.CheckConstraints, cc)
+	}
+	return ct, issues
+}
+
+func isPrimaryKey(ct ddl.CreateTable, colId string) bool {
+	for _, pk := range ct.PrimaryKeys {
+		if pk.ColId == colId {
+			return true
+		}
+	}
+	return false
+}
+
+func unquoteName(name string) string {
+	if len(name) >= 2 && (name[0] == '`' || name[0] == '"') && name[len(name)-1] == name[0] {
+		return name[1 : len(name)-1]
+	}
+	return name
+}
+
+// statementHead returns the first words of stmt, e.g. "CREATE VIEW v".
+func statementHead(stmt string) string {
+	words := strings.Fields(stmt)
+	if len(words) > 3 {
+		words = words[:3]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,572 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+type exprTokenKind int
+
+const (
+	wordToken exprTokenKind = iota // Keywords, function names and unquoted identifiers.
+	quotedIdentToken
+	stringLitToken
+	numberLitToken
+	symbolToken
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string // Unquoted for quoted identifiers and string literals.
+}
+
+var (
+	// sameFunctions have the same name and arguments in both dialects.
+	sameFunctions = map[string]bool{
+		"ABS": true, "CAST": true, "CEIL": true, "CHAR_LENGTH": true, "COALESCE": true, "CONCAT": true,
+		"FLOOR": true, "GREATEST": true, "LEAST": true, "LENGTH": true, "LOWER": true, "LTRIM": true,
+		"MOD": true, "NULLIF": true, "REPLACE": true, "ROUND": true, "RTRIM": true, "SIGN": true,
+		"SQRT": true, "STARTS_WITH": true, "SUBSTR": true, "TRIM": true, "UPPER": true,
+	}
+	exprKeywords = map[string]bool{
+		"AND": true, "OR": true, "NOT": true, "IS": true, "NULL": true, "TRUE": true, "FALSE": true,
+		"IN": true, "BETWEEN": true, "LIKE": true, "CASE": true, "WHEN": true, "THEN": true, "ELSE": true,
+		"END": true, "DISTINCT": true, "FROM": true,
+	}
+	// typedLiterals are the type names that can prefix a string literal,
+	// e.g. DATE '2024-01-01'.
+	typedLiterals = map[string]string{
+		"DATE": ddl.Date, "TIMESTAMP": ddl.Timestamp, "TIMESTAMPTZ": ddl.Timestamp, "NUMERIC": ddl.Numeric,
+		"JSON": ddl.JSON, "JSONB": ddl.JSON,
+	}
+	// pgTypeNames maps the names of PostgreSQL types to the types used in
+	// the AST.
+	pgTypeNames = map[string]string{
+		"BIGINT": ddl.Int64, "INT8": ddl.Int64, "INT": ddl.Int64, "INTEGER": ddl.Int64, "INT4": ddl.Int64,
+		"DOUBLE PRECISION": ddl.Float64, "FLOAT8": ddl.Float64, "REAL": ddl.Float32, "FLOAT4": ddl.Float32,
+		"VARCHAR": ddl.String, "CHARACTER VARYING": ddl.String, "TEXT": ddl.String, "BOOLEAN": ddl.Bool,
+		"BOOL": ddl.Bool, "BYTEA": ddl.Bytes, "DATE": ddl.Date, "TIMESTAMPTZ": ddl.Timestamp,
+		"TIMESTAMP WITH TIME ZONE": ddl.Timestamp, "NUMERIC": ddl.Numeric, "DECIMAL": ddl.Numeric, "JSONB": ddl.JSON,
+	}
+	standardTypeNames = map[string]bool{
+		ddl.Bool: true, ddl.Bytes: true, ddl.Date: true, ddl.Float32: true, ddl.Float64: true, ddl.Int64: true,
+		ddl.JSON: true, ddl.Numeric: true, ddl.String: true, ddl.Timestamp: true,
+	}
+	exprIdentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+)
+
+// translateExpression translates a default value or check constraint
+// expression of table ct from dialect from to dialect to. Column names are
+// quoted, and functions, casts and literals are rewritten to their
+// equivalent in the target dialect. Expressions using functions or syntax
+// without a known equivalent return an error.
+func translateExpression(expr string, ct ddl.CreateTable, from, to string) (string, error) {
+	if from == to {
+		return expr, nil
+	}
+	toks, err := tokenizeExpression(expr, from == constants.DIALECT_POSTGRESQL)
+	if err != nil {
+		return "", err
+	}
+	t := &exprTranslator{table: ct, fromPG: from == constants.DIALECT_POSTGRESQL, toks: toks}
+	for !t.done() {
+		if err := t.translateToken(); err != nil {
+			return "", err
+		}
+	}
+	return joinExpression(t.out), nil
+}
+
+func tokenizeExpression(s string, pg bool) ([]exprToken, error) {
+	var toks []exprToken
+	for i := 0; i < len(s); {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case isExprLetter(ch):
+			j := i + 1
+			for j < len(s) && (isExprLetter(s[j]) || isExprDigit(s[j]) || (s[j] == '.' && j+1 < len(s) && isExprLetter(s[j+1]))) {
+				j++
+			}
+			word := s[i:j]
+			if !pg && j < len(s) && (s[j] == '\'' || s[j] == '"') {
+				if !strings.EqualFold(word, "r") {
+					return nil, fmt.Errorf("%s literals are not supported", word)
+				}
+				lit, end, err := readQuoted(s, j, true)
+				if err != nil {
+					return nil, err
+				}
+				toks = append(toks, exprToken{kind: stringLitToken, text: lit})
+				i = end
+				continue
+			}
+			toks = append(toks, exprToken{kind: wordToken, text: word})
+			i = j
+		case isExprDigit(ch) || (ch == '.' && i+1 < len(s) && isExprDigit(s[i+1])):
+			j := i + 1
+			for j < len(s) && (isExprDigit(s[j]) || s[j] == '.' || s[j] == 'e' || s[j] == 'E' ||
+				((s[j] == '+' || s[j] == '-') && (s[j-1] == 'e' || s[j-1] == 'E'))) {
+				j++
+			}
+			toks = append(toks, exprToken{kind: numberLitToken, text: s[i:j]})
+			i = j
+		case ch == '`' && !pg, ch == '"' && pg:
+			end := strings.IndexByte(s[i+1:], ch)
+			for pg && end >= 0 && i+end+2 < len(s) && s[i+end+2] == '"' {
+				// "" is an escaped quote in PostgreSQL identifiers.
+				next := strings.IndexByte(s[i+end+3:], ch)
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += next + 2
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted identifier")
+			}
+			name := s[i+1 : i+1+end]
+			if pg {
+				name = strings.ReplaceAll(name, `""`, `"`)
+			}
+			toks = append(toks, exprToken{kind: quotedIdentToken, text: name})
+			i += end + 2
+		case ch == '\'' || ch == '"':
+			lit, end, err := readQuoted(s, i, false)
+			if pg {
+				lit, end, err = readPGString(s, i)
+			}
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, exprToken{kind: stringLitToken, text: lit})
+			i = end
+		default:
+			sym := string(ch)
+			if i+1 < len(s) {
+				switch two := s[i : i+2]; two {
+				case "::", "<=", ">=", "<>", "!=", "||":
+					sym = two
+				}
+			}
+			toks = append(toks, exprToken{kind: symbolToken, text: sym})
+			i += len(sym)
+		}
+	}
+	return toks, nil
+}
+
+func isExprLetter(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isExprDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// readQuoted reads the GoogleSQL string literal starting at s[i], and returns
+// its value and the offset following it.
+func readQuoted(s string, i int, raw bool) (string, int, error) {
+	quote := s[i]
+	if strings.HasPrefix(s[i:], strings.Repeat(string(quote), 3)) {
+		return "", 0, fmt.Errorf("triple-quoted strings are not supported")
+	}
+	var b strings.Builder
+	for j := i + 1; j < len(s); j++ {
+		switch {
+		case s[j] == quote:
+			return b.String(), j + 1, nil
+		case s[j] == '\\' && j+1 < len(s):
+			j++
+			if raw {
+				b.WriteByte('\\')
+				b.WriteByte(s[j])
+				continue
+			}
+			switch s[j] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '\'', '"', '`', '?':
+				b.WriteByte(s[j])
+			default:
+				return "", 0, fmt.Errorf("escape sequence \\%c is not supported", s[j])
+			}
+		default:
+			b.WriteByte(s[j])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+// readPGString reads the PostgreSQL string literal starting at s[i], and
+// returns its value and the offset following it.
+func readPGString(s string, i int) (string, int, error) {
+	if s[i] != '\'' {
+		return "", 0, fmt.Errorf("unexpected %q", s[i])
+	}
+	var b strings.Builder
+	for j := i + 1; j < len(s); j++ {
+		if s[j] == '\'' {
+			if j+1 < len(s) && s[j+1] == '\'' {
+				b.WriteByte('\'')
+				j++
+				continue
+			}
+			return b.String(), j + 1, nil
+		}
+		b.WriteByte(s[j])
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+type exprTranslator struct {
+	table  ddl.CreateTable
+	fromPG bool
+	toks   []exprToken
+	pos    int
+	out    []string
+}
+
+func (t *exprTranslator) done() bool {
+	return t.pos >= len(t.toks)
+}
+
+func (t *exprTranslator) peek() exprToken {
+	if t.done() {
+		return exprToken{kind: symbolToken}
+	}
+	return t.toks[t.pos]
+}
+
+func (t *exprTranslator) next() exprToken {
+	tok := t.peek()
+	t.pos++
+	return tok
+}
+
+func (t *exprTranslator) expectSymbol(sym string) error {
+	if tok := t.next(); tok.kind != symbolToken || tok.text != sym {
+		return fmt.Errorf("expected %q, found %q", sym, tok.text)
+	}
+	return nil
+}
+
+func (t *exprTranslator) emit(pieces ...string) {
+	t.out = append(t.out, pieces...)
+}
+
+func (t *exprTranslator) translateToken() error {
+	tok := t.next()
+	switch tok.kind {
+	case numberLitToken:
+		t.emit(tok.text)
+	case stringLitToken:
+		t.emit(t.quoteString(tok.text))
+	case quotedIdentToken:
+		name, ok := t.column(tok.text, true)
+		if !ok {
+			return fmt.Errorf("column %s not found", tok.text)
+		}
+		t.emit(t.quoteIdent(name))
+	case symbolToken:
+		if tok.text == "::" {
+			return t.castSuffix()
+		}
+		t.emit(tok.text)
+	case wordToken:
+		return t.translateWord(tok.text)
+	}
+	return nil
+}
+
+func (t *exprTranslator) translateWord(word string) error {
+	upper := strings.ToUpper(word)
+	next := t.peek()
+	if next.kind == symbolToken && next.text == "(" {
+		return t.translateFunction(upper)
+	}
+	if next.kind == stringLitToken {
+		if name, ok := typedLiterals[upper]; ok {
+			t.pos++
+			t.emit("CAST", "(", t.quoteString(next.text), "AS", t.typeName(ddl.Type{Name: name}), ")")
+			return nil
+		}
+	}
+	switch {
+	case exprKeywords[upper]:
+		t.emit(upper)
+	case upper == "AS":
+		ty, err := t.parseType()
+		if err != nil {
+			return err
+		}
+		t.emit("AS", t.typeName(ty))
+	case upper == "CURRENT_TIMESTAMP" || upper == "CURRENT_DATE":
+		// PostgreSQL has no parentheses after these functions.
+		t.emit(upper)
+		if t.fromPG {
+			t.emit("(", ")")
+		}
+	default:
+		name, ok := t.column(word, false)
+		if !ok {
+			return fmt.Errorf("unknown name %s", word)
+		}
+		t.emit(t.quoteIdent(name))
+	}
+	return nil
+}
+
+// translateFunction translates the name of a function call, or the whole
+// call for functions whose arguments differ between dialects.
+func (t *exprTranslator) translateFunction(name string) error {
+	switch {
+	case sameFunctions[name]:
+		t.emit(name)
+	case !t.fromPG && (name == "CURRENT_TIMESTAMP" || name == "CURRENT_DATE"):
+		t.pos++
+		if err := t.expectSymbol(")"); err != nil {
+			return err
+		}
+		t.emit(name)
+	case !t.fromPG && name == "GENERATE_UUID":
+		t.emit("spanner.generate_uuid")
+	case t.fromPG && name == "SPANNER.GENERATE_UUID":
+		t.emit("GENERATE_UUID")
+	case t.fromPG && name == "NOW":
+		t.emit("CURRENT_TIMESTAMP")
+	case !t.fromPG && name == "GET_NEXT_SEQUENCE_VALUE":
+		t.pos++
+		seq := t.next()
+		if !strings.EqualFold(seq.text, "SEQUENCE") {
+			return fmt.Errorf("expected SEQUENCE, found %q", seq.text)
+		}
+		seq = t.next()
+		if seq.kind != wordToken && seq.kind != quotedIdentToken {
+			return fmt.Errorf("expected a sequence name, found %q", seq.text)
+		}
+		if err := t.expectSymbol(")"); err != nil {
+			return err
+		}
+		t.emit("nextval", "(", t.quoteString(seq.text), ")")
+	case t.fromPG && name == "NEXTVAL":
+		t.pos++
+		seq := t.next()
+		if seq.kind != stringLitToken {
+			return fmt.Errorf("expected a sequence name, found %q", seq.text)
+		}
+		if t.peek().text == "::" {
+			t.pos += 2
+		}
+		if err := t.expectSymbol(")"); err != nil {
+			return err
+		}
+		t.emit("GET_NEXT_SEQUENCE_VALUE", "(", "SEQUENCE", seq.text, ")")
+	default:
+		return fmt.Errorf("function %s has no known equivalent in the %s dialect", name, t.targetDialect())
+	}
+	return nil
+}
+
+// castSuffix rewrites the PostgreSQL cast operand::type as
+// CAST(operand AS type).
+func (t *exprTranslator) castSuffix() error {
+	ty, err := t.parseType()
+	if err != nil {
+		return err
+	}
+	start := len(t.out) - 1
+	if start < 0 {
+		return fmt.Errorf("missing operand of ::")
+	}
+	if t.out[start] == ")" {
+		depth := 0
+		for ; start >= 0; start-- {
+			if t.out[start] == ")" {
+				depth++
+			} else if t.out[start] == "(" {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		if start < 0 {
+			return fmt.Errorf("unbalanced parentheses")
+		}
+		if start > 0 && exprIdentRe.MatchString(t.out[start-1]) && !exprKeywords[t.out[start-1]] {
+			start--
+		}
+	}
+	operand := append([]string{}, t.out[start:]...)
+	t.out = append(t.out[:start], "CAST", "(")
+	t.emit(operand...)
+	t.emit("AS", t.typeName(ty), ")")
+	return nil
+}
+
+// parseType parses the type of a cast in the source dialect.
+func (t *exprTranslator) parseType() (ddl.Type, error) {
+	tok := t.next()
+	if tok.kind != wordToken {
+		return ddl.Type{}, fmt.Errorf("expected a type, found %q", tok.text)
+	}
+	name := strings.ToUpper(tok.text)
+	if !t.fromPG {
+		if name == "ARRAY" {
+			if err := t.expectSymbol("<"); err != nil {
+				return ddl.Type{}, err
+			}
+			ty, err := t.parseType()
+			if err != nil {
+				return ddl.Type{}, err
+			}
+			ty.IsArray = true
+			return ty, t.expectSymbol(">")
+		}
+		if !standardTypeNames[name] {
+			return ddl.Type{}, fmt.Errorf("unsupported type %s", tok.text)
+		}
+		return ddl.Type{Name: name}, t.skipTypeLength()
+	}
+	for _, suffix := range [][]string{{"PRECISION"}, {"VARYING"}, {"WITH", "TIME", "ZONE"}} {
+		if t.pos+len(suffix) > len(t.toks) {
+			continue
+		}
+		match := true
+		for i, word := range suffix {
+			if !strings.EqualFold(t.toks[t.pos+i].text, word) {
+				match = false
+			}
+		}
+		if match {
+			name += " " + strings.Join(suffix, " ")
+			t.pos += len(suffix)
+			break
+		}
+	}
+	standard, ok := pgTypeNames[name]
+	if !ok {
+		return ddl.Type{}, fmt.Errorf("unsupported type %s", strings.ToLower(name))
+	}
+	ty := ddl.Type{Name: standard}
+	if err := t.skipTypeLength(); err != nil {
+		return ddl.Type{}, err
+	}
+	if t.peek().text == "[" {
+		t.pos++
+		ty.IsArray = true
+		return ty, t.expectSymbol("]")
+	}
+	return ty, nil
+}
+
+// skipTypeLength skips the length of a type, e.g. (10) or (MAX).
+func (t *exprTranslator) skipTypeLength() error {
+	if t.peek().kind != symbolToken || t.peek().text != "(" {
+		return nil
+	}
+	t.pos++
+	t.next()
+	return t.expectSymbol(")")
+}
+
+// typeName returns the name of type ty in the target dialect.
+func (t *exprTranslator) typeName(ty ddl.Type) string {
+	if t.fromPG {
+		if ty.IsArray {
+			return "ARRAY<" + ty.Name + ">"
+		}
+		return ty.Name
+	}
+	name := ddl.GetPGType(ty)
+	if ty.IsArray {
+		name += "[]"
+	}
+	return name
+}
+
+// column returns the name of the column of the table referenced by name.
+// Unquoted PostgreSQL names are folded to lower case, and GoogleSQL names are
+// case insensitive.
+func (t *exprTranslator) column(name string, quoted bool) (string, bool) {
+	for _, colId := range t.table.ColIds {
+		colName := t.table.ColDefs[colId].Name
+		switch {
+		case t.fromPG && quoted:
+			if colName == name {
+				return colName, true
+			}
+		case t.fromPG:
+			if colName == strings.ToLower(name) {
+				return colName, true
+			}
+		case strings.EqualFold(colName, name):
+			return colName, true
+		}
+	}
+	return "", false
+}
+
+func (t *exprTranslator) quoteIdent(name string) string {
+	if t.fromPG {
+		return "`" + name + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (t *exprTranslator) quoteString(s string) string {
+	if t.fromPG {
+		r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+		return "'" + r.Replace(s) + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func (t *exprTranslator) targetDialect() string {
+	if t.fromPG {
+		return constants.DIALECT_GOOGLESQL
+	}
+	return constants.DIALECT_POSTGRESQL
+}
+
+// joinExpression joins the pieces of a translated expression, with spaces
+// between operands and operators but not around parentheses of calls.
+func joinExpression(pieces []string) string {
+	var b strings.Builder
+	for i, p := range pieces {
+		if i > 0 {
+			prev := pieces[i-1]
+			noSpace := prev == "(" || p == ")" || p == "," ||
+				(p == "(" && exprIdentRe.MatchString(prev) && !exprKeywords[prev])
+			if !noSpace {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanner
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger.Log = zap.NewNop()
+}
+
+func convertDialectDDL(t *testing.T, statements []string, srcDialect, dialect string) ([]string, []string) {
+	conv := internal.MakeConv()
+	conv.SpDialect = dialect
+	conv.Source = constants.SPANNER
+	_, issues, err := ConvertDialect(conv, statements, srcDialect)
+	assert.Nil(t, err)
+	var issueStrings []string
+	for _, issue := range issues {
+		issueStrings = append(issueStrings, issue.String())
+	}
+	return ddl.GetDDL(ddl.Config{ProtectIds: true, Tables: true, ForeignKeys: true, SpDialect: dialect, Source: constants.SPANNER}, conv.SpSchema, conv.SpSequences), issueStrings
+}
+
+func TestConvertDialect_GoogleSQLToPostgreSQL(t *testing.T) {
+	statements := []string{
+		"CREATE SEQUENCE seq OPTIONS (sequence_kind = 'bit_reversed_positive')",
+		"CREATE TABLE Singers (\n" +
+			"  SingerId INT64 NOT NULL DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE seq)),\n" +
+			"  `Order` STRING(MAX),\n" +
+			"  Name STRING(MAX),\n" +
+			"  Tags ARRAY<STRING(MAX)>,\n" +
+			"  Data BYTES(100),\n" +
+			"  Age INT64 DEFAULT (18),\n" +
+			"  Created TIMESTAMP DEFAULT (CURRENT_TIMESTAMP()),\n" +
+			"  Updated TIMESTAMP OPTIONS (allow_commit_timestamp=true),\n" +
+			"  OrderInitial STRING(MAX) AS (REGEXP_EXTRACT(Name, '^.')) STORED,\n" +
+			"  CONSTRAINT chk_age CHECK(Age >= 18 AND `Order` != 'x'),\n" +
+			"  CONSTRAINT chk_order CHECK(REGEXP_CONTAINS(Name, 'a')),\n" +
+			") PRIMARY KEY(SingerId), ROW DELETION POLICY (OLDER_THAN(Created, INTERVAL 30 DAY))",
+		"CREATE TABLE Albums (\n  SingerId INT64 NOT NULL,\n  AlbumId NUMERIC NOT NULL,\n) PRIMARY KEY(SingerId, AlbumId),\n  INTERLEAVE IN PARENT Singers ON DELETE CASCADE",
+		"CREATE NULL_FILTERED INDEX AgeIndex ON Singers(Age)",
+		"CREATE INDEX NameIndex ON Singers(Name) STORING (Age) WHERE Name IS NOT NULL",
+		"CREATE VIEW SingerNames SQL SECURITY INVOKER AS SELECT SingerId FROM Singers",
+		"ALTER TABLE Albums ADD CONSTRAINT fk_singer FOREIGN KEY (SingerId) REFERENCES Singers (SingerId)",
+	}
+	stmts, issues := convertDialectDDL(t, statements, constants.DIALECT_GOOGLESQL, constants.DIALECT_POSTGRESQL)
+	assert.Equal(t, []string{
+		"CREATE SEQUENCE seq BIT_REVERSED_POSITIVE",
+		"CREATE TABLE \"Singers\" (\n" +
+			"\t\"SingerId\" INT8 NOT NULL  DEFAULT NEXTVAL('seq'),\n" +
+			"\t\"Order\" VARCHAR(2621440),\n" +
+			"\t\"Name\" VARCHAR(2621440),\n" +
+			"\t\"Tags\" VARCHAR(2621440),\n" +
+			"\t\"Data\" BYTEA,\n" +
+			"\t\"Age\" INT8 DEFAULT (18),\n" +
+			"\t\"Created\" TIMESTAMPTZ DEFAULT (CURRENT_TIMESTAMP),\n" +
+			"\t\"Updated\" TIMESTAMPTZ,\n" +
+			"\t\"OrderInitial\" VARCHAR(2621440),\n" +
+			"\tCONSTRAINT chk_age CHECK (\"Age\" >= 18 AND \"Order\" != 'x'),\n" +
+			"\tPRIMARY KEY (\"SingerId\")\n" +
+			")",
+		"CREATE INDEX \"AgeIndex\" ON \"Singers\" (\"Age\")",
+		"CREATE INDEX \"NameIndex\" ON \"Singers\" (\"Name\") INCLUDE (\"Age\")",
+		"CREATE TABLE \"Albums\" (\n" +
+			"\t\"SingerId\" INT8 NOT NULL ,\n" +
+			"\t\"AlbumId\" VARCHAR(2621440) NOT NULL ,\n" +
+			"\tPRIMARY KEY (\"SingerId\", \"AlbumId\")\n" +
+			") INTERLEAVE IN PARENT \"Singers\" ON DELETE CASCADE",
+		"ALTER TABLE \"Albums\" ADD CONSTRAINT \"fk_singer\" FOREIGN KEY (\"SingerId\") REFERENCES \"Singers\" (\"SingerId\") ON DELETE NO ACTION",
+	}, stmts)
+	assert.Equal(t, []string{
+		"table Singers, column Updated: the allow_commit_timestamp option is dropped, the column can't be set to the commit timestamp",
+		"table Singers, row deletion policy: the row deletion policy is dropped",
+		"table Singers, index AgeIndex: NULL_FILTERED is dropped, rows with NULL keys are indexed",
+		"table Singers, index NameIndex: the WHERE filter is dropped, rows with NULL keys are indexed",
+		"CREATE VIEW SingerNames: only tables, indexes, sequences and constraints are converted, the statement is skipped",
+		"table Singers, column Tags: array columns are converted to varchar columns holding a JSON array",
+		"table Singers, column Data: bytea has no maximum length, the limit of 100 bytes is dropped",
+		"table Singers, column OrderInitial: generation expression REGEXP_EXTRACT(Name, \"^.\") is dropped, the column is converted to a plain column: function REGEXP_EXTRACT has no known equivalent in the postgresql dialect",
+		"table Singers, check constraint chk_order: check (REGEXP_CONTAINS(Name, \"a\")) is dropped: function REGEXP_CONTAINS has no known equivalent in the postgresql dialect",
+		"table Albums, column AlbumId: numeric key columns aren't supported, the column is converted to varchar",
+	}, issues)
+}
+
+func TestConvertDialect_PostgreSQLToGoogleSQL(t *testing.T) {
+	// Statements as returned by GetDatabaseDdl for a PostgreSQL database.
+	statements := []string{
+		"CREATE SEQUENCE seq BIT_REVERSED_POSITIVE",
+		"CREATE TABLE singers (\n" +
+			"  id bigint DEFAULT nextval('seq'::text) NOT NULL,\n" +
+			"  \"Name\" character varying(100),\n" +
+			"  score numeric,\n" +
+			"  info jsonb,\n" +
+			"  uid character varying DEFAULT spanner.generate_uuid(),\n" +
+			"  updated spanner.commit_timestamp,\n" +
+			"  created timestamp with time zone DEFAULT now(),\n" +
+			"  name_length bigint GENERATED ALWAYS AS (length(\"Name\")) STORED,\n" +
+			"  CONSTRAINT chk_score CHECK ((score > (0)::numeric)),\n" +
+			"  PRIMARY KEY(id)\n" +
+			") TTL INTERVAL '5 days' ON created",
+		"CREATE INDEX name_idx ON singers USING btree (\"Name\") WHERE (\"Name\" IS NOT NULL)",
+	}
+	stmts, issues := convertDialectDDL(t, statements, constants.DIALECT_POSTGRESQL, constants.DIALECT_GOOGLESQL)
+	assert.Equal(t, []string{
+		"CREATE SEQUENCE seq OPTIONS (sequence_kind='bit_reversed_positive') ",
+		"CREATE TABLE `singers` (\n" +
+			"\t`id` INT64 NOT NULL  DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE seq)),\n" +
+			"\t`Name` STRING(100),\n" +
+			"\t`score` NUMERIC,\n" +
+			"\t`info` JSON,\n" +
+			"\t`uid` STRING(MAX) DEFAULT (GENERATE_UUID()),\n" +
+			"\t`updated` TIMESTAMP,\n" +
+			"\t`created` TIMESTAMP DEFAULT (CURRENT_TIMESTAMP()),\n" +
+			"\t`name_length` INT64 AS (LENGTH(`Name`)) STORED,\n" +
+			"\tCONSTRAINT chk_score CHECK ((`score` > CAST((0) AS NUMERIC)))\n" +
+			") PRIMARY KEY (`id`)",
+		"CREATE INDEX `name_idx` ON `singers` (`Name`)",
+	}, stmts)
+	assert.Equal(t, []string{
+		"table singers, column updated: spanner.commit_timestamp is converted to a timestamp column that can't be set to the commit timestamp",
+		"table singers, row deletion policy: the row deletion policy is dropped",
+		"table singers, index name_idx: the WHERE filter is dropped, rows with NULL keys are indexed",
+	}, issues)
+}
+
+func TestTranslateExpression(t *testing.T) {
+	ct := ddl.CreateTable{
+		Name:   "t",
+		ColIds: []string{"c1", "c2", "c3"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Name: "Amount"},
+			"c2": {Name: "order"},
+			"c3": {Name: "day"},
+		},
+	}
+	tests := []struct {
+		name    string
+		expr    string
+		from    string
+		want    string
+		wantErr bool
+	}{
+		{"comparison", "(Amount > 0 AND `order` IS NOT NULL)", constants.DIALECT_GOOGLESQL, `("Amount" > 0 AND "order" IS NOT NULL)`, false},
+		{"case insensitive column", "(amount < 10)", constants.DIALECT_GOOGLESQL, `("Amount" < 10)`, false},
+		{"string escapes", `('it\'s')`, constants.DIALECT_GOOGLESQL, `('it''s')`, false},
+		{"typed literal", "(day > DATE '2024-01-01')", constants.DIALECT_GOOGLESQL, `("day" > CAST('2024-01-01' AS DATE))`, false},
+		{"cast", "(CAST(Amount AS STRING) != '')", constants.DIALECT_GOOGLESQL, `(CAST("Amount" AS VARCHAR) != '')`, false},
+		{"array cast", "(CAST(NULL AS ARRAY<INT64>))", constants.DIALECT_GOOGLESQL, `(CAST(NULL AS INT8[]))`, false},
+		{"same function", "(LENGTH(`order`) > ABS(-1))", constants.DIALECT_GOOGLESQL, `(LENGTH("order") > ABS(- 1))`, false},
+		{"unknown function", "(REGEXP_CONTAINS(`order`, 'a'))", constants.DIALECT_GOOGLESQL, "", true},
+		{"unknown column", "(missing > 0)", constants.DIALECT_GOOGLESQL, "", true},
+		{"bytes literal", "(b'abc')", constants.DIALECT_GOOGLESQL, "", true},
+		{"pg quoted column", `("Amount" > 0)`, constants.DIALECT_POSTGRESQL, "(`Amount` > 0)", false},
+		{"pg folded column", `("order" <> 'it''s')`, constants.DIALECT_POSTGRESQL, "(`order` <> 'it\\'s')", false},
+		{"pg unquoted upper case column", "(Amount > 0)", constants.DIALECT_POSTGRESQL, "", true},
+		{"pg cast suffix", "((day)::text = '')", constants.DIALECT_POSTGRESQL, "(CAST((`day`) AS STRING) = '')", false},
+		{"pg function cast", "(lower(\"order\")::character varying IS NULL)", constants.DIALECT_POSTGRESQL, "(CAST(LOWER(`order`) AS STRING) IS NULL)", false},
+		{"pg array cast", "('{}'::bigint[] IS NULL)", constants.DIALECT_POSTGRESQL, "(CAST('{}' AS ARRAY<INT64>) IS NULL)", false},
+		{"pg current date", "(day <= CURRENT_DATE)", constants.DIALECT_POSTGRESQL, "(`day` <= CURRENT_DATE())", false},
+		{"pg unsupported type", "('1'::money IS NULL)", constants.DIALECT_POSTGRESQL, "", true},
+	}
+	for _, tc := range tests {
+		to := constants.DIALECT_POSTGRESQL
+		if tc.from == constants.DIALECT_POSTGRESQL {
+			to = constants.DIALECT_GOOGLESQL
+		}
+		got, err := translateExpression(tc.expr, ct, tc.from, to)
+		assert.Equal(t, tc.wantErr, err != nil, tc.name)
+		assert.Equal(t, tc.want, got, tc.name)
+	}
+}
@@ -313,6 +313,12 @@ func (isi InfoSchemaImpl) GetForeignKeys(conv *internal.Conv, table common.Schem
 	return foreignKeys, nil
 }
 
+// GetSourceObjects returns nil: there are no triggers or stored procedures
+// in Spanner, and views are not read.
+func (isi InfoSchemaImpl) GetSourceObjects(conv *internal.Conv) ([]schema.SourceObject, error) {
+	return nil, nil
+}
+
 // GetIndexes returns a list of Indexes per table.
 func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAndName, colNameIdMap map[string]string) ([]schema.Index, error) {
 	q := `SELECT distinct c.INDEX_NAME,c.COLUMN_NAME,c.ORDINAL_POSITION,c.COLUMN_ORDERING,i.IS_UNIQUE
@@ -418,6 +424,58 @@ func (isi InfoSchemaImpl) GetInterleaveTables(spSchema ddl.Schema) (map[string]d
 	return parentTables, nil
 }
 
+// GetSequences returns the sequences of the database, keyed by sequence id.
+func (isi InfoSchemaImpl) GetSequences() (map[string]ddl.Sequence, error) {
+	q := `SELECT name, option_name, option_value FROM information_schema.sequence_options WHERE schema = ''`
+	if isi.SpDialect == constants.DIALECT_POSTGRESQL {
+		q = `SELECT sequence_name, 'sequence_kind', sequence_kind FROM information_schema.sequences WHERE sequence_schema = 'public'
+		UNION ALL SELECT sequence_name, 'skip_range_min', CAST(skip_range_min AS VARCHAR) FROM information_schema.sequences WHERE sequence_schema = 'public'
+		UNION ALL SELECT sequence_name, 'skip_range_max', CAST(skip_range_max AS VARCHAR) FROM information_schema.sequences WHERE sequence_schema = 'public'
+		UNION ALL SELECT sequence_name, 'start_with_counter', CAST(counter_start_value AS VARCHAR) FROM information_schema.sequences WHERE sequence_schema = 'public'`
+	}
+	stmt := spanner.Statement{SQL: q}
+	iter := isi.Client.Single().Query(isi.Ctx, stmt)
+	defer iter.Stop()
+
+	var name, option string
+	var value spanner.NullString
+	sequences := map[string]ddl.Sequence{}
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get sequences: %w", err)
+		}
+		err = row.Columns(&name, &option, &value)
+		if err != nil {
+			return nil, err
+		}
+		seq := sequences[name]
+		seq.Name = name
+		switch strings.ToLower(option) {
+		case "sequence_kind":
+			if strings.EqualFold(strings.ReplaceAll(value.StringVal, "_", " "), "bit reversed positive") {
+				seq.SequenceKind = "BIT REVERSED POSITIVE"
+			}
+		case "skip_range_min":
+			seq.SkipRangeMin = value.StringVal
+		case "skip_range_max":
+			seq.SkipRangeMax = value.StringVal
+		case "start_with_counter":
+			seq.StartWithCounter = value.StringVal
+		}
+		sequences[name] = seq
+	}
+	byId := map[string]ddl.Sequence{}
+	for _, seq := range sequences {
+		seq.Id = internal.GenerateSequenceId()
+		byId[seq.Id] = seq
+	}
+	return byId, nil
+}
+
 func toType(dataType string) schema.Type {
 	switch {
 	case strings.Contains(dataType, "ARRAY"):
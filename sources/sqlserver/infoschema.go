@@ -20,10 +20,12 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 
 	sp "cloud.google.com/go/spanner"
 
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
@@ -44,8 +46,9 @@ const (
 )
 
 type InfoSchemaImpl struct {
-	DbName string
-	Db     *sql.DB
+	DbName        string
+	Db            *sql.DB
+	SourceProfile profiles.SourceProfile
 }
 
 // GetToDdl function below implement the common.InfoSchema interface.
@@ -86,6 +89,55 @@ func (isi InfoSchemaImpl) GetTableName(schema string, tableName string) string {
 // *interface{} parameters to row.Scan.
 func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, commonColIds []string, spSchema ddl.CreateTable, additionalAttributes internal.AdditionalDataAttributes) error {
 	srcTableName := conv.SrcSchema[tableId].Name
+	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
+	processRows := func(rows *sql.Rows, mutex *sync.Mutex) {
+		srcCols, _ := rows.Columns()
+		v, scanArgs := buildVals(len(srcCols))
+		for rows.Next() {
+			// get RawBytes from data.
+			err := rows.Scan(scanArgs...)
+			if err != nil {
+				mutex.Lock()
+				conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
+				// Scan failed, so we don't have any data to add to bad rows.
+				conv.StatsAddBadRow(srcTableName, conv.DataMode())
+				mutex.Unlock()
+				continue
+			}
+			values := valsToStrings(v)
+			newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
+			if err != nil {
+				mutex.Lock()
+				conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
+				conv.StatsAddBadRow(srcTableName, conv.DataMode())
+				conv.CollectBadRow(srcTableName, srcCols, values, err)
+				mutex.Unlock()
+				continue
+			}
+			// Convert the row without holding the lock, so that the readers
+			// of a table's key ranges only serialize their updates of conv.
+			spTableName, cvtCols, cvtVals, err := ConvertData(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
+			mutex.Lock()
+			writeDataRow(conv, commonColIds, srcSchema, newValues, spTableName, cvtCols, cvtVals, err)
+			mutex.Unlock()
+		}
+	}
+	// If a read parallelism is configured for the table in the source
+	// profile, read it with concurrent queries over ranges of its primary key.
+	if parallelism := profiles.GetReadParallelism(isi.SourceProfile, srcTableName); parallelism > 1 {
+		tblName := strings.Replace(srcSchema.Name, srcSchema.Schema+".", "", 1)
+		table := fmt.Sprintf("[%s].[%s].[%s]", isi.DbName, srcSchema.Schema, tblName)
+		query := func(where string, args []interface{}) (*sql.Rows, error) {
+			q := getSelectQuery(isi.DbName, srcSchema.Schema, tblName, srcSchema.ColIds, srcSchema.ColDefs)
+			return isi.Db.Query(q+where, args...)
+		}
+		if ok, err := common.ReadKeyRanges(conv, tableId, parallelism, isi.Db, table, queryDialect(srcSchema.Schema, tblName), query, processRows); ok {
+			if err != nil {
+				conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
+			}
+			return err
+		}
+	}
 	rowsInterface, err := isi.GetRowsFromTable(conv, tableId)
 	if err != nil {
 		conv.Unexpected(fmt.Sprintf("Couldn't get data for table %s : err = %s", srcTableName, err))
@@ -93,28 +145,7 @@ func (isi InfoSchemaImpl) ProcessData(conv *internal.Conv, tableId string, srcSc
 	}
 	rows := rowsInterface.(*sql.Rows)
 	defer rows.Close()
-	srcCols, _ := rows.Columns()
-	v, scanArgs := buildVals(len(srcCols))
-	colNameIdMap := internal.GetSrcColNameIdMap(conv.SrcSchema[tableId])
-	for rows.Next() {
-		// get RawBytes from data.
-		err := rows.Scan(scanArgs...)
-		if err != nil {
-			conv.Unexpected(fmt.Sprintf("Couldn't process sql data row: %s", err))
-			// Scan failed, so we don't have any data to add to bad rows.
-			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			continue
-		}
-		values := valsToStrings(v)
-		newValues, err := common.PrepareValues(conv, tableId, colNameIdMap, commonColIds, srcCols, values)
-		if err != nil {
-			conv.Unexpected(fmt.Sprintf("Error while converting data: %s\n", err))
-			conv.StatsAddBadRow(srcTableName, conv.DataMode())
-			conv.CollectBadRow(srcTableName, srcCols, values)
-			continue
-		}
-		ProcessDataRow(conv, tableId, commonColIds, srcSchema, spSchema, newValues)
-	}
+	processRows(rows, &sync.Mutex{})
 	return nil
 }
 
@@ -125,13 +156,53 @@ func (isi InfoSchemaImpl) GetRowsFromTable(conv *internal.Conv, tableId string)
 	tblName := strings.Replace(tbl.Name, tbl.Schema+".", "", 1)
 
 	q := getSelectQuery(isi.DbName, tbl.Schema, tblName, tbl.ColIds, tbl.ColDefs)
-	rows, err := isi.Db.Query(q)
+	clause, args := common.ReadClause(conv, tableId, queryDialect(tbl.Schema, tblName))
+	rows, err := isi.Db.Query(q+clause, args...)
 	if err != nil {
 		return nil, err
 	}
 	return rows, err
 }
 
+// queryDialect returns the dialect for building clauses over the rows of a
+// table. Key columns are qualified with the table name, so that ORDER BY
+// doesn't pick up aliases of converted columns from the select list.
+func queryDialect(schemaName, tableName string) common.QueryDialect {
+	return common.QueryDialect{
+		QuoteCol:    func(col string) string { return fmt.Sprintf("[%s].[%s].[%s]", schemaName, tableName, col) },
+		Placeholder: func(i int) string { return fmt.Sprintf("@p%d", i) },
+		IsInteger: func(srcType schema.Type) bool {
+			switch srcType.Name {
+			case "tinyint", "smallint", "int", "bigint":
+				return true
+			}
+			return false
+		},
+		HashText: func(col string, srcType schema.Type, spType ddl.Type) (string, bool) {
+			if len(srcType.ArrayBounds) > 0 || spType.IsArray {
+				return "", false
+			}
+			switch t := srcType.Name; {
+			case spType.Name == ddl.Int64 && (t == "tinyint" || t == "smallint" || t == "int" || t == "bigint"):
+				return "CAST(" + col + " AS VARCHAR(20))", true
+			case spType.Name == ddl.String && (t == "varchar" || t == "text"):
+				return "CAST(" + col + " AS VARCHAR(MAX))", true
+			case spType.Name == ddl.Date && t == "date":
+				return "CONVERT(VARCHAR(10), " + col + ", 23)", true
+			case spType.Name == ddl.Bool && t == "bit":
+				return "CASE " + col + " WHEN 1 THEN 'true' WHEN 0 THEN 'false' END", true
+			}
+			return "", false
+		},
+		Hash: common.HashDialect{
+			Concat: common.ConcatFunction,
+			Hash: func(text string) string {
+				return "CAST(CAST(SUBSTRING(HASHBYTES('MD5', " + text + "), 1, 3) AS BINARY(3)) AS BIGINT)"
+			},
+		},
+	}
+}
+
 func getSelectQuery(srcDb string, schemaName string, tableName string, colIds []string, colDefs map[string]schema.Column) string {
 	var selects = make([]string, len(colIds))
 
@@ -174,6 +245,32 @@ func buildVals(n int) (v []interface{}, iv []interface{}) {
 	return v, iv
 }
 
+// HashableColumns returns the columns whose values can be hashed in the
+// database (see common.KeyRangeHasher).
+func (isi InfoSchemaImpl) HashableColumns(conv *internal.Conv, tableId string, colIds []string) []string {
+	srcSchema := conv.SrcSchema[tableId]
+	tblName := strings.Replace(srcSchema.Name, srcSchema.Schema+".", "", 1)
+	return common.HashableColumns(conv, tableId, colIds, queryDialect(srcSchema.Schema, tblName))
+}
+
+// HashKeyRanges counts and hashes the rows of a table in ranges of an
+// integer column in the database (see common.KeyRangeHasher).
+func (isi InfoSchemaImpl) HashKeyRanges(conv *internal.Conv, tableId, colId string, bounds []int64, keyColIds, hashColIds []string) ([]common.KeyRangeSummary, bool, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	tblName := strings.Replace(srcSchema.Name, srcSchema.Schema+".", "", 1)
+	table := fmt.Sprintf("[%s].[%s].[%s]", isi.DbName, srcSchema.Schema, tblName)
+	return common.HashKeyRanges(conv, tableId, colId, bounds, keyColIds, hashColIds, isi.Db, table, queryDialect(srcSchema.Schema, tblName))
+}
+
+// CountFilteredRows counts the rows of a table skipped by its row filter in
+// the database (see common.FilteredRowCounter).
+func (isi InfoSchemaImpl) CountFilteredRows(conv *internal.Conv, tableId string) (int64, error) {
+	srcSchema := conv.SrcSchema[tableId]
+	tblName := strings.Replace(srcSchema.Name, srcSchema.Schema+".", "", 1)
+	table := fmt.Sprintf("[%s].[%s].[%s]", isi.DbName, srcSchema.Schema, tblName)
+	return common.CountFilteredRows(conv, tableId, isi.Db, table)
+}
+
 // GetRowCount with number of rows in each table.
 func (isi InfoSchemaImpl) GetRowCount(table common.SchemaAndName) (int64, error) {
 	q := fmt.Sprintf(`SELECT COUNT(1) FROM [%s].[%s].[%s];`, isi.DbName, table.Schema, table.Name)
@@ -220,16 +317,20 @@ func (isi InfoSchemaImpl) GetTables() ([]common.SchemaAndName, error) {
 func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAndName, constraints map[string][]string, primaryKeys []string) (map[string]schema.Column, []string, error) {
 	q := `
 		SELECT 
-			column_name, 
-			data_type, 
-			is_nullable, 
-			column_default, 
-			character_maximum_length, 
-			numeric_precision, 
-			numeric_scale
-		FROM information_schema.COLUMNS 
-		WHERE table_schema = @p1 and table_name = @p2 
-		ORDER BY ordinal_position;
+			c.column_name, 
+			c.data_type, 
+			c.is_nullable, 
+			c.column_default, 
+			c.character_maximum_length, 
+			c.numeric_precision, 
+			c.numeric_scale,
+			cc.definition,
+			cc.is_persisted
+		FROM information_schema.COLUMNS c
+		LEFT JOIN sys.computed_columns cc
+			ON cc.object_id = OBJECT_ID(QUOTENAME(c.table_schema) + '.' + QUOTENAME(c.table_name)) AND cc.name = c.column_name
+		WHERE c.table_schema = @p1 and c.table_name = @p2 
+		ORDER BY c.ordinal_position;
 	`
 	cols, err := isi.Db.Query(q, table.Schema, table.Name)
 	if err != nil {
@@ -240,11 +341,12 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 	var colIds []string
 	var colName, dataType string
 	var isNullable string
-	var colDefault sql.NullString
+	var colDefault, computedDef sql.NullString
+	var isPersisted sql.NullBool
 	// elementDataType
 	var charMaxLen, numericPrecision, numericScale sql.NullInt64
 	for cols.Next() {
-		err := cols.Scan(&colName, &dataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale)
+		err := cols.Scan(&colName, &dataType, &isNullable, &colDefault, &charMaxLen, &numericPrecision, &numericScale, &computedDef, &isPersisted)
 		if err != nil {
 			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
 			continue
@@ -263,12 +365,22 @@ func (isi InfoSchemaImpl) GetColumns(conv *internal.Conv, table common.SchemaAnd
 		}
 		ignored.Default = colDefault.Valid
 		colId := internal.GenerateColumnId()
+		// Computed columns have a definition in sys.computed_columns.
+		var generated ddl.GeneratedColumn
+		if computedDef.Valid {
+			generated = ddl.GeneratedColumn{
+				IsPresent: true,
+				Value:     ddl.Expression{ExpressionId: internal.GenerateExpressionId(), Statement: computedDef.String},
+				Stored:    isPersisted.Bool,
+			}
+		}
 		c := schema.Column{
-			Id:      colId,
-			Name:    colName,
-			Type:    toType(dataType, charMaxLen, numericPrecision, numericScale),
-			NotNull: strings.ToUpper(isNullable) == "NO",
-			Ignored: ignored,
+			Id:        colId,
+			Name:      colName,
+			Type:      toType(dataType, charMaxLen, numericPrecision, numericScale),
+			NotNull:   strings.ToUpper(isNullable) == "NO",
+			Ignored:   ignored,
+			Generated: generated,
 		}
 		colDefs[colId] = c
 		colIds = append(colIds, colId)
@@ -434,6 +546,50 @@ func (isi InfoSchemaImpl) GetIndexes(conv *internal.Conv, table common.SchemaAnd
 	return indexes, nil
 }
 
+// GetSourceObjects returns the views, triggers, stored procedures and
+// functions of the database.
+func (isi InfoSchemaImpl) GetSourceObjects(conv *internal.Conv) ([]schema.SourceObject, error) {
+	q := `
+		SELECT
+			RTRIM(O.type),
+			SCH.name,
+			O.name,
+			COALESCE(M.definition, ''),
+			COALESCE(OBJECT_SCHEMA_NAME(O.parent_object_id), ''),
+			COALESCE(OBJECT_NAME(O.parent_object_id), ''),
+			COALESCE(STUFF((SELECT ', ' + TE.type_desc FROM sys.trigger_events TE WHERE TE.object_id = O.object_id FOR XML PATH('')), 1, 2, ''), '')
+		FROM sys.sql_modules M
+		INNER JOIN sys.objects O
+			ON M.object_id = O.object_id
+		INNER JOIN sys.schemas SCH
+			ON O.schema_id = SCH.schema_id
+		WHERE
+			O.type IN ('V', 'TR', 'P', 'FN', 'IF', 'TF')
+			AND O.is_ms_shipped = 0
+		ORDER BY O.name;
+	`
+	rows, err := isi.Db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	kinds := map[string]string{"V": schema.View, "TR": schema.Trigger, "P": schema.Procedure, "FN": schema.Function, "IF": schema.Function, "TF": schema.Function}
+	var ty, objSchema, name, body, tableSchema, table, event string
+	var objects []schema.SourceObject
+	for rows.Next() {
+		if err := rows.Scan(&ty, &objSchema, &name, &body, &tableSchema, &table, &event); err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't scan: %v", err))
+			continue
+		}
+		o := schema.SourceObject{Kind: kinds[ty], Schema: objSchema, Name: isi.GetTableName(objSchema, name), Body: body, Event: event}
+		if table != "" {
+			o.Table = isi.GetTableName(tableSchema, table)
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
 func toType(dataType string, charLen sql.NullInt64, numericPrecision, numericScale sql.NullInt64) schema.Type {
 	switch {
 	case charLen.Valid:
@@ -25,6 +25,8 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/mocks"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/profiles"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
 	"github.com/stretchr/testify/assert"
@@ -75,11 +77,11 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"dbo", "user"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "definition", "is_persisted"},
 			rows: [][]driver.Value{
-				{"user_id", "text", "NO", nil, nil, nil, nil},
-				{"name", "text", "NO", nil, nil, nil, nil},
-				{"ref", "bigint", "YES", nil, nil, nil, nil}},
+				{"user_id", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"name", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"ref", "bigint", "YES", nil, nil, nil, nil, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -103,45 +105,45 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"dbo", "test"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "definition", "is_persisted"},
 			rows: [][]driver.Value{
-				{"Id", "int", "NO", nil, nil, 10, 0},
-				{"BigInt", "bigint", "YES", nil, nil, 19, 0},
-				{"Binary", "binary", "YES", nil, 50, nil, nil},
-				{"Bit", "bit", "YES", nil, nil, nil, nil},
-				{"Char", "char", "YES", nil, 10, nil, nil},
-				{"Date", "date", "YES", nil, nil, nil, nil},
-				{"DateTime", "datetime", "YES", nil, nil, nil, nil},
-				{"DateTime2", "datetime2", "YES", nil, nil, nil, nil},
-				{"DateTimeOffset", "datetimeoffset", "YES", nil, nil, nil, nil},
-				{"Decimal", "decimal", "YES", nil, nil, 18, 9},
-				{"Float", "float", "YES", nil, nil, 53, nil},
-				{"Geography", "geography", "YES", nil, -1, nil, nil},
-				{"Geometry", "geometry", "YES", nil, -1, nil, nil},
-				{"HierarchyId", "hierarchyid", "YES", nil, 892, nil, nil},
-				{"Image", "image", "YES", nil, 2147483647, nil, nil},
-				{"Int", "int", "YES", nil, nil, 10, 0},
-				{"Money", "money", "YES", nil, nil, 19, 4},
-				{"NChar", "nchar", "YES", nil, 10, nil, nil},
-				{"NText", "ntext", "YES", nil, 1073741823, nil, nil},
-				{"Numeric", "numeric", "YES", nil, nil, 18, 17},
-				{"NVarChar", "nvarchar", "YES", nil, 50, nil, nil},
-				{"NVarCharMax", "nvarchar", "YES", nil, -1, nil, nil},
-				{"Real", "real", "YES", nil, nil, 24, nil},
-				{"SmallDateTime", "smalldatetime", "YES", nil, nil, nil, nil},
-				{"SmallInt", "smallint", "YES", nil, nil, 5, 0},
-				{"SmallMoney", "smallmoney", "YES", nil, nil, 10, 4},
-				{"SQLVariant", "sql_variant", "YES", nil, 0, nil, nil},
-				{"Text", "text", "YES", nil, 2147483647, nil, nil},
-				{"Time", "time", "YES", nil, nil, nil, nil},
-				{"TimeStamp", "timestamp", "YES", nil, nil, nil, nil},
-				{"TinyInt", "tinyint", "YES", nil, nil, 3, 0},
-				{"UniqueIdentifier", "uniqueidentifier", "YES", nil, nil, nil, nil},
-				{"VarBinary", "varbinary", "YES", nil, 50, nil, nil},
-				{"VarBinaryMax", "varbinary", "YES", nil, -1, nil, nil},
-				{"VarChar", "varchar", "YES", nil, 50, nil, nil},
-				{"VarCharMax", "varchar", "YES", nil, -1, nil, nil},
-				{"Xml", "xml", "YES", nil, -1, nil, nil},
+				{"Id", "int", "NO", nil, nil, 10, 0, nil, nil},
+				{"BigInt", "bigint", "YES", nil, nil, 19, 0, nil, nil},
+				{"Binary", "binary", "YES", nil, 50, nil, nil, nil, nil},
+				{"Bit", "bit", "YES", nil, nil, nil, nil, nil, nil},
+				{"Char", "char", "YES", nil, 10, nil, nil, nil, nil},
+				{"Date", "date", "YES", nil, nil, nil, nil, nil, nil},
+				{"DateTime", "datetime", "YES", nil, nil, nil, nil, nil, nil},
+				{"DateTime2", "datetime2", "YES", nil, nil, nil, nil, nil, nil},
+				{"DateTimeOffset", "datetimeoffset", "YES", nil, nil, nil, nil, nil, nil},
+				{"Decimal", "decimal", "YES", nil, nil, 18, 9, nil, nil},
+				{"Float", "float", "YES", nil, nil, 53, nil, nil, nil},
+				{"Geography", "geography", "YES", nil, -1, nil, nil, nil, nil},
+				{"Geometry", "geometry", "YES", nil, -1, nil, nil, nil, nil},
+				{"HierarchyId", "hierarchyid", "YES", nil, 892, nil, nil, nil, nil},
+				{"Image", "image", "YES", nil, 2147483647, nil, nil, nil, nil},
+				{"Int", "int", "YES", nil, nil, 10, 0, nil, nil},
+				{"Money", "money", "YES", nil, nil, 19, 4, nil, nil},
+				{"NChar", "nchar", "YES", nil, 10, nil, nil, nil, nil},
+				{"NText", "ntext", "YES", nil, 1073741823, nil, nil, nil, nil},
+				{"Numeric", "numeric", "YES", nil, nil, 18, 17, nil, nil},
+				{"NVarChar", "nvarchar", "YES", nil, 50, nil, nil, nil, nil},
+				{"NVarCharMax", "nvarchar", "YES", nil, -1, nil, nil, nil, nil},
+				{"Real", "real", "YES", nil, nil, 24, nil, nil, nil},
+				{"SmallDateTime", "smalldatetime", "YES", nil, nil, nil, nil, nil, nil},
+				{"SmallInt", "smallint", "YES", nil, nil, 5, 0, nil, nil},
+				{"SmallMoney", "smallmoney", "YES", nil, nil, 10, 4, nil, nil},
+				{"SQLVariant", "sql_variant", "YES", nil, 0, nil, nil, nil, nil},
+				{"Text", "text", "YES", nil, 2147483647, nil, nil, nil, nil},
+				{"Time", "time", "YES", nil, nil, nil, nil, nil, nil},
+				{"TimeStamp", "timestamp", "YES", nil, nil, nil, nil, nil, nil},
+				{"TinyInt", "tinyint", "YES", nil, nil, 3, 0, nil, nil},
+				{"UniqueIdentifier", "uniqueidentifier", "YES", nil, nil, nil, nil, nil, nil},
+				{"VarBinary", "varbinary", "YES", nil, 50, nil, nil, nil, nil},
+				{"VarBinaryMax", "varbinary", "YES", nil, -1, nil, nil, nil, nil},
+				{"VarChar", "varchar", "YES", nil, 50, nil, nil, nil, nil},
+				{"VarCharMax", "varchar", "YES", nil, -1, nil, nil, nil, nil},
+				{"Xml", "xml", "YES", nil, -1, nil, nil, nil, nil},
 			},
 		},
 		// db call to fetch index happens after fetching of column
@@ -171,11 +173,11 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"dbo", "cart"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "definition", "is_persisted"},
 			rows: [][]driver.Value{
-				{"productid", "text", "NO", nil, nil, nil, nil},
-				{"userid", "text", "NO", nil, nil, nil, nil},
-				{"quantity", "bigint", "YES", nil, nil, 64, 0}},
+				{"productid", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"userid", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"quantity", "bigint", "YES", nil, nil, 64, 0, nil, nil}},
 		},
 		// db call to fetch index happens after fetching of column
 		{
@@ -206,10 +208,10 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"production", "product"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "definition", "is_persisted"},
 			rows: [][]driver.Value{
-				{"product_id", "text", "NO", nil, nil, nil, nil},
-				{"product_name", "text", "NO", nil, nil, nil, nil},
+				{"product_id", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"product_name", "text", "NO", nil, nil, nil, nil, nil, nil},
 			},
 		},
 		// db call to fetch index happens after fetching of column
@@ -236,11 +238,11 @@ func TestProcessSchema(t *testing.T) {
 		{
 			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
 			args:  []driver.Value{"dbo", "test_ref"},
-			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "definition", "is_persisted"},
 			rows: [][]driver.Value{
-				{"ref_id", "bigint", "NO", nil, nil, 64, 0},
-				{"ref_txt", "text", "NO", nil, nil, nil, nil},
-				{"abc", "text", "NO", nil, nil, nil, nil},
+				{"ref_id", "bigint", "NO", nil, nil, 64, 0, nil, nil},
+				{"ref_txt", "text", "NO", nil, nil, nil, nil, nil, nil},
+				{"abc", "text", "NO", nil, nil, nil, nil, nil, nil},
 			},
 		},
 		// db call to fetch index happens after fetching of column
@@ -249,6 +251,14 @@ func TestProcessSchema(t *testing.T) {
 			args:  []driver.Value{"test_ref", "dbo"},
 			cols:  []string{"index_name", "column_name", "column_position", "is_unique", "order", "is_included_column"},
 		},
+		{
+			query: "SELECT (.+) FROM sys.sql_modules M (.+)",
+			cols:  []string{"type", "schema", "name", "definition", "parent_schema", "parent", "events"},
+			rows: [][]driver.Value{
+				{"V", "dbo", "user_carts", "CREATE VIEW user_carts AS SELECT * FROM [user] JOIN cart ON cart.userid = [user].user_id", "", "", ""},
+				{"TR", "sales", "cart_audit", "CREATE TRIGGER sales.cart_audit ON cart AFTER INSERT, UPDATE AS INSERT INTO test_ref SELECT * FROM inserted", "dbo", "cart", "INSERT, UPDATE"},
+			},
+		},
 	}
 	db := mkMockDB(t, ms)
 	conv := internal.MakeConv()
@@ -265,7 +275,7 @@ func TestProcessSchema(t *testing.T) {
 		ExpressionVerificationAccessor: mockAccessor,
 		DdlV:                           &expressions_api.MockDDLVerifier{},
 	}
-	err := processSchema.ProcessSchema(conv, InfoSchemaImpl{"test", db}, 1, internal.AdditionalSchemaAttributes{}, &schemaToSpanner, &common.UtilsOrderImpl{}, &common.InfoSchemaImpl{})
+	err := processSchema.ProcessSchema(conv, InfoSchemaImpl{"test", db, profiles.SourceProfile{}}, 1, internal.AdditionalSchemaAttributes{}, &schemaToSpanner, &common.UtilsOrderImpl{}, &common.InfoSchemaImpl{})
 	assert.Nil(t, err)
 	expectedSchema := map[string]ddl.CreateTable{
 		"user": {
@@ -367,10 +377,43 @@ func TestProcessSchema(t *testing.T) {
 	assert.Equal(t, nil, err)
 	assert.Equal(t, len(conv.SchemaIssues[cartTableId].ColumnLevelIssues), 0)
 	assert.Equal(t, len(conv.SchemaIssues[testTableId].ColumnLevelIssues), 15)
+	assert.Equal(t, []schema.SourceObject{
+		{Kind: schema.View, Schema: "dbo", Name: "user_carts", Body: "CREATE VIEW user_carts AS SELECT * FROM [user] JOIN cart ON cart.userid = [user].user_id", ReferencedTables: []string{"cart", "user"}},
+		{Kind: schema.Trigger, Schema: "sales", Name: "sales.cart_audit", Body: "CREATE TRIGGER sales.cart_audit ON cart AFTER INSERT, UPDATE AS INSERT INTO test_ref SELECT * FROM inserted",
+			ReferencedTables: []string{"cart", "test_ref"}, Table: "cart", Event: "INSERT, UPDATE"},
+	}, conv.SrcObjects)
 	assert.Equal(t, int64(0), conv.Unexpecteds())
 
 }
 
+func TestGetColumns_ComputedColumns(t *testing.T) {
+	ms := []mockSpec{
+		{
+			query: "SELECT (.+) FROM information_schema.COLUMNS (.+)",
+			args:  []driver.Value{"dbo", "orders"},
+			cols:  []string{"column_name", "data_type", "is_nullable", "column_default", "character_maximum_length", "numeric_precision", "numeric_scale", "definition", "is_persisted"},
+			rows: [][]driver.Value{
+				{"Price", "bigint", "NO", nil, nil, 19, 0, nil, nil},
+				{"Total", "bigint", "YES", nil, nil, 19, 0, "([Price]*(2))", true},
+				{"Label", "nvarchar", "YES", nil, 20, nil, nil, "(concat('#',[Price]))", false},
+			},
+		},
+	}
+	db := mkMockDB(t, ms)
+	isi := InfoSchemaImpl{Db: db}
+	conv := internal.MakeConv()
+	colDefs, colIds, err := isi.GetColumns(conv, common.SchemaAndName{Schema: "dbo", Name: "orders"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(colIds))
+	assert.False(t, colDefs[colIds[0]].Generated.IsPresent)
+	total := colDefs[colIds[1]].Generated
+	assert.Equal(t, "([Price]*(2))", total.Value.Statement)
+	assert.True(t, total.Stored)
+	label := colDefs[colIds[2]].Generated
+	assert.True(t, label.IsPresent)
+	assert.False(t, label.Stored)
+}
+
 func mkMockDB(t *testing.T, ms []mockSpec) *sql.DB {
 	db, mock, err := sqlmock.New()
 	assert.Nil(t, err)
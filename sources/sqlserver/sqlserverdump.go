@@ -0,0 +1,181 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+)
+
+// goRegexp matches the GO batch separator of T-SQL scripts, optionally
+// followed by a count.
+var goRegexp = regexp.MustCompile(`(?i)^\s*GO(\s+\d+)?\s*(--.*)?$`)
+
+// DbDumpImpl SQL Server specific implementation for DdlDumpImpl. It reads
+// T-SQL scripts, e.g. the output of SSMS "Generate Scripts".
+type DbDumpImpl struct {
+}
+
+// GetToDdl function below implement the common.DbDump interface.
+func (ddi DbDumpImpl) GetToDdl() common.ToDdl {
+	return ToDdlImpl{}
+}
+
+// ProcessDump processes a T-SQL script. Only the schema of the script is
+// converted: scripted data can't be used for data conversion.
+func (ddi DbDumpImpl) ProcessDump(conv *internal.Conv, r *internal.Reader) error {
+	if !conv.SchemaMode() {
+		return fmt.Errorf("data conversion from SQL Server scripts is not supported: use a direct connection to migrate data")
+	}
+	for {
+		startLine := r.LineNumber
+		batch := readBatch(r)
+		toks, err := common.TokenizeScript(batch, true)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Can't parse batch at line %d: %s", startLine, err))
+			conv.ErrorInStatement("UNKNOWN")
+		}
+		for _, stmt := range splitBatch(toks) {
+			logger.Log.Debug(fmt.Sprintf("Parsed %s statement in batch at line=%d", common.ScriptStatementType(stmt), startLine))
+			common.ProcessScriptStatement(conv, stmt, scriptDialect{})
+		}
+		if r.EOF {
+			break
+		}
+	}
+	common.ResolveScriptForeignKeys(conv)
+	return nil
+}
+
+// readBatch reads the next batch of a T-SQL script, up to the next GO
+// separator.
+func readBatch(r *internal.Reader) string {
+	var b strings.Builder
+	for !r.EOF {
+		line := string(r.ReadLine())
+		if goRegexp.MatchString(line) {
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// batchStatements are the types of statements that T-SQL requires to be the
+// only statement of their batch.
+var batchStatements = map[string]bool{
+	"CREATE PROCEDURE": true, "ALTER PROCEDURE": true, "CREATE FUNCTION": true, "ALTER FUNCTION": true,
+	"CREATE TRIGGER": true, "ALTER TRIGGER": true, "CREATE VIEW": true, "ALTER VIEW": true, "CREATE SCHEMA": true,
+}
+
+// splitBatch splits the tokens of a batch into statements. T-SQL doesn't
+// require statements to be terminated, so statements also end where a new
+// CREATE or ALTER statement starts.
+func splitBatch(toks []common.ScriptToken) [][]common.ScriptToken {
+	if batchStatements[common.ScriptStatementType(toks)] {
+		return [][]common.ScriptToken{toks}
+	}
+	var stmts [][]common.ScriptToken
+	start, depth := 0, 0
+	for i, t := range toks {
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case t.IsPunct(";"):
+			if i > start {
+				stmts = append(stmts, toks[start:i])
+			}
+			start = i + 1
+		case depth == 0 && i > start && (t.IsWord("CREATE") || t.IsWord("ALTER")):
+			stmts = append(stmts, toks[start:i])
+			start = i
+		}
+	}
+	if start < len(toks) {
+		stmts = append(stmts, toks[start:])
+	}
+	return stmts
+}
+
+// scriptDialect implements common.ScriptDialect for SQL Server.
+type scriptDialect struct{}
+
+// Ident returns the name of an identifier.
+func (scriptDialect) Ident(t common.ScriptToken) string {
+	return t.Text
+}
+
+// TableName returns the name of a table like InfoSchemaImpl.GetTableName,
+// for tables in the dbo schema if unqualified.
+func (scriptDialect) TableName(schemaName, name string) string {
+	if schemaName == "" {
+		schemaName = "dbo"
+	}
+	return InfoSchemaImpl{}.GetTableName(schemaName, name)
+}
+
+// ColumnType parses a T-SQL data type into the same schema.Type that
+// InfoSchemaImpl.GetColumns builds from the information schema, e.g.
+// nvarchar(-1) for nvarchar(max).
+func (scriptDialect) ColumnType(p *common.ScriptParser) (schema.Type, error) {
+	t := p.Next()
+	if !t.IsIdent() {
+		return schema.Type{}, fmt.Errorf("expected a data type, found '%s'", t.Text)
+	}
+	// Types can be qualified by their schema, e.g. [sys].[sysname].
+	name := t.Text
+	for p.AcceptPunct(".") {
+		name = p.Next().Text
+	}
+	name = strings.ToLower(name)
+	switch name {
+	case "sysname":
+		return schema.Type{Name: "nvarchar", Mods: []int64{128}}, nil
+	case "double":
+		p.Accept("PRECISION")
+		name = "float"
+	}
+	ty := schema.Type{Name: name}
+	if g, ok := p.Group(); ok {
+		for _, item := range g.List() {
+			if item.Peek().IsWord("max") {
+				ty.Mods = append(ty.Mods, -1)
+			} else if n, err := strconv.ParseInt(item.Peek().Text, 10, 64); err == nil {
+				ty.Mods = append(ty.Mods, n)
+			}
+		}
+	}
+	switch name {
+	case "datetime2", "datetimeoffset", "time":
+		// The information schema has the fractional seconds precision of
+		// these types in a column that InfoSchemaImpl.GetColumns ignores.
+		ty.Mods = nil
+	}
+	return ty, nil
+}
+
+// JSONCheck reports false: SQL Server has no JSON type to convert to.
+func (scriptDialect) JSONCheck(expr []common.ScriptToken) bool {
+	return false
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlserver
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+)
+
+const tsqlScript = `USE [Sales]
+GO
+/****** Object:  Table [dbo].[Customers]    Script Date: 3/1/2024 10:00:00 AM ******/
+SET ANSI_NULLS ON
+GO
+SET QUOTED_IDENTIFIER ON
+GO
+CREATE TABLE [dbo].[Customers](
+	[CustomerID] [nchar](5) NOT NULL,
+	[CompanyName] [nvarchar](40) NOT NULL,
+	[Notes] [nvarchar](max) NULL,
+	[Owner] [sysname] NULL,
+ CONSTRAINT [PK_Customers] PRIMARY KEY CLUSTERED
+(
+	[CustomerID] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF) ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]
+GO
+CREATE TABLE [sales].[Orders](
+	[OrderID] [int] IDENTITY(1,1) NOT NULL,
+	[CustomerID] [nchar](5) NULL,
+	[OrderDate] [datetime2](7) NULL,
+	[Freight] [money] NULL,
+	[Total] AS ([Freight]*(2)),
+	[Amount]  AS (CONVERT([decimal](10,2),[Freight]*(2))) PERSISTED,
+	[Code] [varchar](10) COLLATE SQL_Latin1_General_CP1_CI_AS NULL,
+ CONSTRAINT [PK_Orders] PRIMARY KEY CLUSTERED ([OrderID] DESC),
+ CONSTRAINT [UQ_Orders_Code] UNIQUE NONCLUSTERED ([Code] ASC)
+) ON [PRIMARY]
+GO
+ALTER TABLE [sales].[Orders] ADD  CONSTRAINT [DF_Orders_Freight]  DEFAULT ((0)) FOR [Freight]
+GO
+ALTER TABLE [sales].[Orders]  WITH CHECK ADD  CONSTRAINT [FK_Orders_Customers] FOREIGN KEY([CustomerID])
+REFERENCES [dbo].[Customers] ([CustomerID])
+ON DELETE CASCADE
+GO
+ALTER TABLE [sales].[Orders] CHECK CONSTRAINT [FK_Orders_Customers]
+GO
+ALTER TABLE [sales].[Orders]  WITH CHECK ADD  CONSTRAINT [CK_Freight] CHECK  (([Freight]>=(0)))
+GO
+CREATE NONCLUSTERED INDEX [IX_Orders_Date] ON [sales].[Orders]
+(
+	[OrderDate] DESC
+)
+INCLUDE([Freight]) WITH (SORT_IN_TEMPDB = OFF) ON [PRIMARY]
+GO
+CREATE PROCEDURE [dbo].[GetOrders] AS
+BEGIN
+	CREATE TABLE #tmp (id int);
+	SELECT * FROM [sales].[Orders];
+END
+GO
+SET IDENTITY_INSERT [sales].[Orders] ON
+INSERT [sales].[Orders] ([OrderID]) VALUES (1)
+SET IDENTITY_INSERT [sales].[Orders] OFF
+GO
+`
+
+func TestProcessDump(t *testing.T) {
+	conv := internal.MakeConv()
+	conv.SetSchemaMode()
+	r := internal.NewReader(bufio.NewReader(strings.NewReader(tsqlScript)), nil)
+	assert.Nil(t, DbDumpImpl{}.ProcessDump(conv, r))
+	assert.Len(t, conv.SrcSchema, 2)
+
+	cust, ok := internal.GetSrcTableByName(conv.SrcSchema, "Customers")
+	assert.True(t, ok)
+	assert.Equal(t, "dbo", cust.Schema)
+	ccol := func(name string) schema.Column { return cust.ColDefs[cust.ColNameIdMap[name]] }
+	assert.Equal(t, schema.Type{Name: "nchar", Mods: []int64{5}}, ccol("CustomerID").Type)
+	assert.Equal(t, schema.Type{Name: "nvarchar", Mods: []int64{-1}}, ccol("Notes").Type)
+	assert.Equal(t, schema.Type{Name: "nvarchar", Mods: []int64{128}}, ccol("Owner").Type)
+	assert.True(t, ccol("CompanyName").NotNull)
+	assert.False(t, ccol("Notes").NotNull)
+	assert.Equal(t, []schema.Key{{ColId: cust.ColNameIdMap["CustomerID"]}}, cust.PrimaryKeys)
+
+	orders, ok := internal.GetSrcTableByName(conv.SrcSchema, "sales.Orders")
+	assert.True(t, ok)
+	ocol := func(name string) schema.Column { return orders.ColDefs[orders.ColNameIdMap[name]] }
+	// The computed column without a cast is skipped.
+	assert.Equal(t, 6, len(orders.ColIds))
+	assert.Equal(t, schema.Type{Name: "decimal", Mods: []int64{10, 2}}, ocol("Amount").Type)
+	assert.Equal(t, `CONVERT("decimal"(10, 2), "Freight" * (2))`, ocol("Amount").Generated.Value.Statement)
+	assert.True(t, ocol("Amount").Generated.Stored)
+	assert.Equal(t, schema.Type{Name: "int"}, ocol("OrderID").Type)
+	assert.True(t, ocol("OrderID").Ignored.Identity)
+	assert.Equal(t, schema.Type{Name: "datetime2"}, ocol("OrderDate").Type)
+	assert.True(t, ocol("Freight").Ignored.Default)
+	assert.True(t, ocol("Freight").Ignored.Check)
+	assert.Equal(t, schema.Type{Name: "varchar", Mods: []int64{10}}, ocol("Code").Type)
+	assert.Equal(t, []schema.Key{{ColId: orders.ColNameIdMap["OrderID"], Desc: true}}, orders.PrimaryKeys)
+
+	assert.Equal(t, 2, len(orders.Indexes))
+	assert.Equal(t, schema.Index{Id: orders.Indexes[0].Id, Name: "UQ_Orders_Code", Unique: true, Keys: []schema.Key{{ColId: orders.ColNameIdMap["Code"]}}}, orders.Indexes[0])
+	assert.Equal(t, schema.Index{Id: orders.Indexes[1].Id, Name: "IX_Orders_Date", Keys: []schema.Key{{ColId: orders.ColNameIdMap["OrderDate"], Desc: true}},
+		StoredColumnIds: []string{orders.ColNameIdMap["Freight"]}}, orders.Indexes[1])
+
+	assert.Equal(t, 1, len(orders.ForeignKeys))
+	assert.Equal(t, "FK_Orders_Customers", orders.ForeignKeys[0].Name)
+	assert.Equal(t, cust.Id, orders.ForeignKeys[0].ReferTableId)
+	assert.Equal(t, []string{cust.ColNameIdMap["CustomerID"]}, orders.ForeignKeys[0].ReferColumnIds)
+	assert.Equal(t, constants.FK_CASCADE, orders.ForeignKeys[0].OnDelete)
+	assert.Equal(t, constants.FK_NO_ACTION, orders.ForeignKeys[0].OnUpdate)
+
+	assert.Equal(t, int64(4), conv.Stats.Statement["ALTER TABLE"].Schema)
+	assert.Equal(t, int64(1), conv.Stats.Statement["CREATE PROCEDURE"].Skip)
+	assert.Equal(t, int64(3), conv.Stats.Statement["SET"].Skip)
+	// The only unexpected condition is the skipped computed column.
+	assert.Equal(t, int64(1), conv.Unexpecteds())
+}
+
+func TestSplitBatch(t *testing.T) {
+	for _, tc := range []struct {
+		batch string
+		types []string
+	}{
+		{"SET ANSI_NULLS ON CREATE TABLE t (a int) ALTER TABLE t ADD b int", []string{"SET", "CREATE", "ALTER"}},
+		{"CREATE TABLE t (a int); CREATE INDEX i ON t (a);", []string{"CREATE", "CREATE"}},
+		{"CREATE VIEW v AS SELECT 1 AS a; CREATE TABLE t (a int)", []string{"CREATE"}},
+	} {
+		toks, err := common.TokenizeScript(tc.batch, true)
+		assert.Nil(t, err)
+		var types []string
+		for _, stmt := range splitBatch(toks) {
+			types = append(types, strings.ToUpper(stmt[0].Text))
+		}
+		assert.Equal(t, tc.types, types, tc.batch)
+	}
+}
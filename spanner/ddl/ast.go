@@ -187,6 +187,7 @@ type ColumnDef struct {
 	Id           string
 	AutoGen      AutoGenCol
 	DefaultValue DefaultValue
+	Generated    GeneratedColumn
 }
 
 // Config controls how AST nodes are printed (aka unparsed).
@@ -195,6 +196,7 @@ type Config struct {
 	ProtectIds  bool // If true, table and col names are quoted using backticks (avoids reserved-word issue).
 	Tables      bool // If true, print tables
 	ForeignKeys bool // If true, print foreign key constraints.
+	SkipIndexes bool // If true, don't print secondary indexes along with their tables.
 	SpDialect   string
 	Source      string // SourceDB information for determining case-sensitivity handling for PGSQL
 }
@@ -210,7 +212,7 @@ func isIdentifierReservedInPG(identifier string) bool {
 
 func isSourceCaseSensitive(source string) bool {
 	switch source {
-	case constants.POSTGRES, constants.PGDUMP:
+	case constants.POSTGRES, constants.PGDUMP, constants.SPANNER:
 		return true
 	default:
 		return false
@@ -244,6 +246,7 @@ func (cd ColumnDef) PrintColumnDef(c Config) (string, string) {
 		}
 		s += cd.DefaultValue.PGPrintDefaultValue(cd.T)
 		s += cd.AutoGen.PGPrintAutoGenCol()
+		s += cd.Generated.PGPrintGeneratedColumn()
 	} else {
 		s = fmt.Sprintf("%s %s", c.quote(cd.Name), cd.T.PrintColumnDefType())
 		if cd.NotNull {
@@ -251,6 +254,7 @@ func (cd ColumnDef) PrintColumnDef(c Config) (string, string) {
 		}
 		s += cd.DefaultValue.PrintDefaultValue(cd.T)
 		s += cd.AutoGen.PrintAutoGenCol()
+		s += cd.Generated.PrintGeneratedColumn()
 	}
 	return s, cd.Comment
 }
@@ -439,6 +443,33 @@ type Expression struct {
 	Statement    string
 }
 
+// GeneratedColumn represents the expression of a generated column, whose
+// value is computed from the other columns of its row. Stored is false for
+// source columns that are computed when read (e.g. MySQL VIRTUAL columns);
+// Spanner generated columns are always stored.
+type GeneratedColumn struct {
+	IsPresent bool
+	Value     Expression
+	Stored    bool
+}
+
+// PrintGeneratedColumn unparses the generated column clause of a column.
+func (gc GeneratedColumn) PrintGeneratedColumn() string {
+	if !gc.IsPresent {
+		return ""
+	}
+	return " AS (" + gc.Value.Statement + ") STORED"
+}
+
+// PGPrintGeneratedColumn unparses the generated column clause of a column
+// for the PostgreSQL dialect.
+func (gc GeneratedColumn) PGPrintGeneratedColumn() string {
+	if !gc.IsPresent {
+		return ""
+	}
+	return " GENERATED ALWAYS AS (" + gc.Value.Statement + ") STORED"
+}
+
 func (dv DefaultValue) PrintDefaultValue(ty Type) string {
 	if !dv.IsPresent {
 		return ""
@@ -537,8 +568,8 @@ func isStoredColumnKeyPartOfPrimaryKey(ct CreateTable, colId string) bool {
 func (k Foreignkey) PrintForeignKeyAlterTable(spannerSchema Schema, c Config, tableId string) string {
 	var cols, referCols []string
 	for i, col := range k.ColIds {
-		cols = append(cols, spannerSchema[tableId].ColDefs[col].Name)
-		referCols = append(referCols, spannerSchema[k.ReferTableId].ColDefs[k.ReferColumnIds[i]].Name)
+		cols = append(cols, c.quote(spannerSchema[tableId].ColDefs[col].Name))
+		referCols = append(referCols, c.quote(spannerSchema[k.ReferTableId].ColDefs[k.ReferColumnIds[i]].Name))
 	}
 	var s string
 	if k.Name != "" {
@@ -651,6 +682,9 @@ func GetDDL(c Config, tableSchema Schema, sequenceSchema map[string]Sequence) []
 	if c.Tables {
 		for _, tableId := range tableIds {
 			ddl = append(ddl, tableSchema[tableId].PrintCreateTable(tableSchema, c))
+			if c.SkipIndexes {
+				continue
+			}
 			for _, index := range tableSchema[tableId].Indexes {
 				ddl = append(ddl, index.PrintCreateIndex(tableSchema[tableId], c))
 			}
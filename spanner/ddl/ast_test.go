@@ -90,6 +90,15 @@ func TestPrintColumnDef(t *testing.T) {
 			},
 			expected: "col1 INT64 DEFAULT ((`col2` + 1))",
 		},
+		{
+			in: ColumnDef{
+				Name:      "col1",
+				T:         Type{Name: Int64},
+				NotNull:   true,
+				Generated: GeneratedColumn{IsPresent: true, Value: Expression{Statement: "`col2` + 1"}},
+			},
+			expected: "col1 INT64 NOT NULL  AS (`col2` + 1) STORED",
+		},
 	}
 	for _, tc := range tests {
 		s, _ := tc.in.PrintColumnDef(Config{ProtectIds: tc.protectIds})
@@ -119,6 +128,14 @@ func TestPrintColumnDefPG(t *testing.T) {
 			},
 			expected: "col1 INT8 DEFAULT ((`col2` + 1))",
 		},
+		{
+			in: ColumnDef{
+				Name:      "col1",
+				T:         Type{Name: Int64},
+				Generated: GeneratedColumn{IsPresent: true, Value: Expression{Statement: "col2 + 1"}},
+			},
+			expected: "col1 INT8 GENERATED ALWAYS AS (col2 + 1) STORED",
+		},
 	}
 	for _, tc := range tests {
 		s, _ := tc.in.PrintColumnDef(Config{ProtectIds: tc.protectIds, SpDialect: constants.DIALECT_POSTGRESQL})
@@ -555,7 +572,7 @@ func TestPrintForeignKeyAlterTable(t *testing.T) {
 		fk         Foreignkey
 	}{
 		{"no quote", "t1", false, "", "ALTER TABLE table1 ADD CONSTRAINT fk_test FOREIGN KEY (productid, userid) REFERENCES table2 (productid, userid) ON DELETE CASCADE", spannerSchema["t1"].ForeignKeys[0]},
-		{"quote", "t1", true, "", "ALTER TABLE `table1` ADD CONSTRAINT `fk_test` FOREIGN KEY (productid, userid) REFERENCES `table2` (productid, userid) ON DELETE CASCADE", spannerSchema["t1"].ForeignKeys[0]},
+		{"quote", "t1", true, "", "ALTER TABLE `table1` ADD CONSTRAINT `fk_test` FOREIGN KEY (`productid`, `userid`) REFERENCES `table2` (`productid`, `userid`) ON DELETE CASCADE", spannerSchema["t1"].ForeignKeys[0]},
 		{"no constraint name", "t1", false, "", "ALTER TABLE table1 ADD FOREIGN KEY (productid) REFERENCES table2 (productid) ON DELETE NO ACTION", spannerSchema["t1"].ForeignKeys[1]},
 		{"quote PG", "t1", true, constants.DIALECT_POSTGRESQL, "ALTER TABLE table1 ADD CONSTRAINT fk_test FOREIGN KEY (productid, userid) REFERENCES table2 (productid, userid) ON DELETE CASCADE", spannerSchema["t1"].ForeignKeys[0]},
 		{"foreign key constraints not supported i.e. dont print ON DELETE", "t1", false, "", "ALTER TABLE table1 ADD CONSTRAINT fk_test2 FOREIGN KEY (productid, userid) REFERENCES table2 (productid, userid)", spannerSchema["t1"].ForeignKeys[2]},
@@ -565,6 +582,14 @@ func TestPrintForeignKeyAlterTable(t *testing.T) {
 			assert.Equal(t, tc.expected, tc.fk.PrintForeignKeyAlterTable(spannerSchema, Config{ProtectIds: tc.protectIds, SpDialect: tc.spDialect}, tc.table))
 		})
 	}
+	// Identifiers of case-sensitive sources are always quoted in PostgreSQL
+	// dialect.
+	quoted := `ALTER TABLE "table1" ADD CONSTRAINT "fk_test" FOREIGN KEY ("productid", "userid") REFERENCES "table2" ("productid", "userid") ON DELETE CASCADE`
+	unquoted := "ALTER TABLE table1 ADD CONSTRAINT fk_test FOREIGN KEY (productid, userid) REFERENCES table2 (productid, userid) ON DELETE CASCADE"
+	for source, expected := range map[string]string{constants.SPANNER: quoted, constants.POSTGRES: quoted, constants.MYSQL: unquoted} {
+		c := Config{ProtectIds: true, SpDialect: constants.DIALECT_POSTGRESQL, Source: source}
+		assert.Equal(t, expected, spannerSchema["t1"].ForeignKeys[0].PrintForeignKeyAlterTable(spannerSchema, c, "t1"), source)
+	}
 }
 
 func TestPrintDefaultValue(t *testing.T) {
@@ -904,6 +929,26 @@ func TestGetDDL(t *testing.T) {
 	}
 	assert.ElementsMatch(t, e3, tablesAndFks)
 
+	tablesWithoutIndexes := GetDDL(Config{Tables: true, SkipIndexes: true}, s, make(map[string]Sequence))
+	e5 := []string{
+		"CREATE TABLE table1 (\n" +
+			"	a INT64,\n" +
+			"	b INT64,\n" +
+			") PRIMARY KEY (a)",
+		"CREATE TABLE table2 (\n" +
+			"	a INT64,\n" +
+			"	b INT64,\n" +
+			"	c INT64,\n" +
+			") PRIMARY KEY (a)",
+		"CREATE TABLE table3 (\n" +
+			"	a INT64,\n" +
+			"	b INT64,\n" +
+			"	c INT64,\n" +
+			") PRIMARY KEY (a, b),\n" +
+			"INTERLEAVE IN PARENT table1 ON DELETE NO ACTION",
+	}
+	assert.ElementsMatch(t, e5, tablesWithoutIndexes)
+
 	sequences := make(map[string]Sequence)
 	sequences["s1"] = Sequence{
 		Id:               "s1",
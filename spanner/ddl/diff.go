@@ -0,0 +1,637 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+)
+
+// SchemaDiff is the structural difference between two Spanner schemas. Schema
+// objects are matched by name, since the ids of two schemas are unrelated.
+type SchemaDiff struct {
+	AddedTables       []string    `json:"addedTables,omitempty"`
+	DroppedTables     []string    `json:"droppedTables,omitempty"`
+	ModifiedTables    []TableDiff `json:"modifiedTables,omitempty"`
+	AddedSequences    []string    `json:"addedSequences,omitempty"`
+	DroppedSequences  []string    `json:"droppedSequences,omitempty"`
+	ModifiedSequences []string    `json:"modifiedSequences,omitempty"`
+
+	from, to         Schema
+	fromSeqs, toSeqs map[string]Sequence
+}
+
+// TableDiff is the difference between two versions of a table. Tables whose
+// primary key or parent table change can't be altered, and are recreated.
+type TableDiff struct {
+	Name                     string       `json:"name"`
+	Recreated                bool         `json:"recreated,omitempty"`
+	RecreateReason           string       `json:"recreateReason,omitempty"`
+	AddedColumns             []string     `json:"addedColumns,omitempty"`
+	DroppedColumns           []string     `json:"droppedColumns,omitempty"`
+	ModifiedColumns          []ColumnDiff `json:"modifiedColumns,omitempty"`
+	AddedIndexes             []string     `json:"addedIndexes,omitempty"`
+	DroppedIndexes           []string     `json:"droppedIndexes,omitempty"`
+	ModifiedIndexes          []string     `json:"modifiedIndexes,omitempty"`
+	AddedForeignKeys         []string     `json:"addedForeignKeys,omitempty"`
+	DroppedForeignKeys       []string     `json:"droppedForeignKeys,omitempty"`
+	ModifiedForeignKeys      []string     `json:"modifiedForeignKeys,omitempty"`
+	AddedCheckConstraints    []string     `json:"addedCheckConstraints,omitempty"`
+	DroppedCheckConstraints  []string     `json:"droppedCheckConstraints,omitempty"`
+	ModifiedCheckConstraints []string     `json:"modifiedCheckConstraints,omitempty"`
+	OnDeleteChanged          bool         `json:"onDeleteChanged,omitempty"`
+
+	fromId, toId string
+}
+
+// ColumnDiff lists the properties of a column that changed, e.g. "type".
+type ColumnDiff struct {
+	Name    string   `json:"name"`
+	Changes []string `json:"changes"`
+}
+
+const (
+	typeChange     = "type"
+	notNullChange  = "not null"
+	defaultChange  = "default value"
+	pkChange       = "primary key changed"
+	parentChange   = "parent table changed"
+	parentRecreate = "parent table %s is recreated"
+)
+
+// IsEmpty returns true if the two schemas are the same.
+func (d SchemaDiff) IsEmpty() bool {
+	return len(d.AddedTables) == 0 && len(d.DroppedTables) == 0 && len(d.ModifiedTables) == 0 &&
+		len(d.AddedSequences) == 0 && len(d.DroppedSequences) == 0 && len(d.ModifiedSequences) == 0
+}
+
+// DiffSchemas returns the difference between the schema (from, fromSeqs) and
+// the schema (to, toSeqs).
+func DiffSchemas(from, to Schema, fromSeqs, toSeqs map[string]Sequence) SchemaDiff {
+	d := SchemaDiff{from: from, to: to, fromSeqs: fromSeqs, toSeqs: toSeqs}
+	fromIds := tableIdsByName(from)
+	toIds := tableIdsByName(to)
+
+	// Find the tables to recreate first, since the foreign keys of other
+	// tables that reference them must be recreated too. Parents are sorted
+	// before their children, so recreation propagates down interleaving.
+	recreated := map[string]string{}
+	for _, toId := range GetSortedTableIdsBySpName(to) {
+		t := to[toId]
+		fromId, ok := fromIds[t.Name]
+		if !ok {
+			continue
+		}
+		ft := from[fromId]
+		switch {
+		case !equalStrings(primaryKeySignature(ft), primaryKeySignature(t)):
+			recreated[t.Name] = pkChange
+		case from[ft.ParentTable.Id].Name != to[t.ParentTable.Id].Name:
+			recreated[t.Name] = parentChange
+		case t.ParentTable.Id != "" && recreated[to[t.ParentTable.Id].Name] != "":
+			recreated[t.Name] = fmt.Sprintf(parentRecreate, to[t.ParentTable.Id].Name)
+		}
+	}
+
+	for _, toId := range GetSortedTableIdsBySpName(to) {
+		t := to[toId]
+		fromId, ok := fromIds[t.Name]
+		if !ok {
+			d.AddedTables = append(d.AddedTables, t.Name)
+			continue
+		}
+		if reason, ok := recreated[t.Name]; ok {
+			d.ModifiedTables = append(d.ModifiedTables, TableDiff{Name: t.Name, Recreated: true, RecreateReason: reason, fromId: fromId, toId: toId})
+			continue
+		}
+		if td := diffTable(from, to, fromId, toId, recreated); !td.isEmpty() {
+			d.ModifiedTables = append(d.ModifiedTables, td)
+		}
+	}
+	for _, fromId := range GetSortedTableIdsBySpName(from) {
+		if _, ok := toIds[from[fromId].Name]; !ok {
+			d.DroppedTables = append(d.DroppedTables, from[fromId].Name)
+		}
+	}
+
+	for _, name := range sortedSequenceNames(toSeqs) {
+		fromSeq, ok := sequenceByName(fromSeqs, name)
+		toSeq, _ := sequenceByName(toSeqs, name)
+		switch {
+		case !ok:
+			d.AddedSequences = append(d.AddedSequences, name)
+		case fromSeq.SequenceKind != toSeq.SequenceKind || fromSeq.SkipRangeMin != toSeq.SkipRangeMin ||
+			fromSeq.SkipRangeMax != toSeq.SkipRangeMax || fromSeq.StartWithCounter != toSeq.StartWithCounter:
+			d.ModifiedSequences = append(d.ModifiedSequences, name)
+		}
+	}
+	for _, name := range sortedSequenceNames(fromSeqs) {
+		if _, ok := sequenceByName(toSeqs, name); !ok {
+			d.DroppedSequences = append(d.DroppedSequences, name)
+		}
+	}
+	return d
+}
+
+func diffTable(from, to Schema, fromId, toId string, recreated map[string]string) TableDiff {
+	ft, t := from[fromId], to[toId]
+	td := TableDiff{Name: t.Name, fromId: fromId, toId: toId}
+
+	fromCols := columnIdsByName(ft)
+	toCols := columnIdsByName(t)
+	for _, colId := range t.ColIds {
+		cd := t.ColDefs[colId]
+		fromColId, ok := fromCols[cd.Name]
+		if !ok {
+			td.AddedColumns = append(td.AddedColumns, cd.Name)
+			continue
+		}
+		// The expression of a generated column can't be altered, the column
+		// is dropped and added back instead.
+		if ft.ColDefs[fromColId].Generated.PrintGeneratedColumn() != cd.Generated.PrintGeneratedColumn() {
+			td.DroppedColumns = append(td.DroppedColumns, cd.Name)
+			td.AddedColumns = append(td.AddedColumns, cd.Name)
+			continue
+		}
+		if changes := diffColumn(ft.ColDefs[fromColId], cd); len(changes) > 0 {
+			td.ModifiedColumns = append(td.ModifiedColumns, ColumnDiff{Name: cd.Name, Changes: changes})
+		}
+	}
+	for _, colId := range ft.ColIds {
+		if _, ok := toCols[ft.ColDefs[colId].Name]; !ok {
+			td.DroppedColumns = append(td.DroppedColumns, ft.ColDefs[colId].Name)
+		}
+	}
+
+	fromIdx := map[string]string{}
+	for _, idx := range ft.Indexes {
+		fromIdx[idx.Name] = indexSignature(ft, idx)
+	}
+	td.AddedIndexes, td.DroppedIndexes, td.ModifiedIndexes = diffNamed(fromIdx, func(add func(name, sig string)) {
+		for _, idx := range t.Indexes {
+			add(idx.Name, indexSignature(t, idx))
+		}
+	})
+
+	fromFks := map[string]string{}
+	for _, fk := range ft.ForeignKeys {
+		fromFks[foreignKeyName(from, fromId, fk)] = foreignKeySignature(from, fromId, fk)
+	}
+	td.AddedForeignKeys, td.DroppedForeignKeys, td.ModifiedForeignKeys = diffNamed(fromFks, func(add func(name, sig string)) {
+		for _, fk := range t.ForeignKeys {
+			sig := foreignKeySignature(to, toId, fk)
+			// Foreign keys that reference a recreated table are dropped with
+			// it, and must be added back.
+			if recreated[to[fk.ReferTableId].Name] != "" {
+				sig += " (referenced table is recreated)"
+			}
+			add(foreignKeyName(to, toId, fk), sig)
+		}
+	})
+
+	fromChecks := map[string]string{}
+	for _, ck := range ft.CheckConstraints {
+		fromChecks[checkConstraintName(ck)] = ck.Expr
+	}
+	td.AddedCheckConstraints, td.DroppedCheckConstraints, td.ModifiedCheckConstraints = diffNamed(fromChecks, func(add func(name, sig string)) {
+		for _, ck := range t.CheckConstraints {
+			add(checkConstraintName(ck), ck.Expr)
+		}
+	})
+
+	td.OnDeleteChanged = t.ParentTable.Id != "" && onDeleteAction(ft.ParentTable.OnDelete) != onDeleteAction(t.ParentTable.OnDelete)
+	return td
+}
+
+func (td TableDiff) isEmpty() bool {
+	return !td.Recreated && !td.OnDeleteChanged &&
+		len(td.AddedColumns) == 0 && len(td.DroppedColumns) == 0 && len(td.ModifiedColumns) == 0 &&
+		len(td.AddedIndexes) == 0 && len(td.DroppedIndexes) == 0 && len(td.ModifiedIndexes) == 0 &&
+		len(td.AddedForeignKeys) == 0 && len(td.DroppedForeignKeys) == 0 && len(td.ModifiedForeignKeys) == 0 &&
+		len(td.AddedCheckConstraints) == 0 && len(td.DroppedCheckConstraints) == 0 && len(td.ModifiedCheckConstraints) == 0
+}
+
+// diffNamed compares the signatures of named schema objects. fromSigs maps
+// the names of the old objects to their signature, and toSigs calls add with
+// the name and signature of every new object.
+func diffNamed(fromSigs map[string]string, toSigs func(add func(name, sig string))) (added, dropped, modified []string) {
+	seen := map[string]bool{}
+	toSigs(func(name, sig string) {
+		seen[name] = true
+		fromSig, ok := fromSigs[name]
+		if !ok {
+			added = append(added, name)
+		} else if fromSig != sig {
+			modified = append(modified, name)
+		}
+	})
+	for name := range fromSigs {
+		if !seen[name] {
+			dropped = append(dropped, name)
+		}
+	}
+	sort.Strings(dropped)
+	return added, dropped, modified
+}
+
+func diffColumn(from, to ColumnDef) []string {
+	var changes []string
+	if from.T != to.T {
+		changes = append(changes, typeChange)
+	}
+	if from.NotNull != to.NotNull {
+		changes = append(changes, notNullChange)
+	}
+	if defaultSignature(from) != defaultSignature(to) {
+		changes = append(changes, defaultChange)
+	}
+	return changes
+}
+
+func defaultSignature(cd ColumnDef) string {
+	return cd.DefaultValue.PrintDefaultValue(cd.T) + cd.AutoGen.PrintAutoGenCol()
+}
+
+// Statements returns the DDL statements that change the old schema of d into
+// the new one, in an order that Spanner accepts: objects are dropped before
+// the objects they depend on, and created after them. It also returns
+// warnings about the statements that lose data, and about the unnamed
+// constraints that can't be dropped by name and are skipped. With
+// c.Comments, the statements that drop recreated tables are preceded by a
+// warning comment.
+func (d SchemaDiff) Statements(c Config) ([]string, []string) {
+	var stmts, warnings []string
+	recreated := map[string]bool{}
+	modified := map[string]TableDiff{}
+	for _, td := range d.ModifiedTables {
+		if td.Recreated {
+			recreated[td.Name] = true
+		}
+		modified[td.Name] = td
+	}
+	dropped := map[string]bool{}
+	for _, name := range d.DroppedTables {
+		dropped[name] = true
+	}
+	fromIds := tableIdsByName(d.from)
+	fromOrder := GetSortedTableIdsBySpName(d.from)
+	toOrder := GetSortedTableIdsBySpName(d.to)
+
+	// Drop foreign keys, indexes and check constraints.
+	for _, fromId := range fromOrder {
+		t := d.from[fromId]
+		all := dropped[t.Name] || recreated[t.Name]
+		td := modified[t.Name]
+		for _, fk := range t.ForeignKeys {
+			name := foreignKeyName(d.from, fromId, fk)
+			if all || contains(td.DroppedForeignKeys, name) || contains(td.ModifiedForeignKeys, name) {
+				if fk.Name == "" {
+					warnings = append(warnings, fmt.Sprintf("Unnamed foreign key %s of table %s can't be dropped by name and is skipped, drop it before applying the statements.", name, t.Name))
+					continue
+				}
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", c.quote(t.Name), c.quote(fk.Name)))
+			}
+		}
+	}
+	for _, fromId := range fromOrder {
+		t := d.from[fromId]
+		all := dropped[t.Name] || recreated[t.Name]
+		td := modified[t.Name]
+		for _, idx := range t.Indexes {
+			if all || contains(td.DroppedIndexes, idx.Name) || contains(td.ModifiedIndexes, idx.Name) {
+				stmts = append(stmts, fmt.Sprintf("DROP INDEX %s", c.quote(idx.Name)))
+			}
+		}
+		if all {
+			continue
+		}
+		for _, ck := range t.CheckConstraints {
+			name := checkConstraintName(ck)
+			if contains(td.DroppedCheckConstraints, name) || contains(td.ModifiedCheckConstraints, name) {
+				if ck.Name == "" {
+					warnings = append(warnings, fmt.Sprintf("Unnamed check constraint %s of table %s can't be dropped by name and is skipped, drop it before applying the statements.", ck.Expr, t.Name))
+					continue
+				}
+				stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", c.quote(t.Name), c.quote(ck.Name)))
+			}
+		}
+	}
+
+	// Drop columns and tables. Interleaved tables are dropped before their
+	// parent.
+	for _, td := range d.ModifiedTables {
+		for _, col := range td.DroppedColumns {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", c.quote(td.Name), c.quote(col)))
+		}
+	}
+	for i := len(fromOrder) - 1; i >= 0; i-- {
+		name := d.from[fromOrder[i]].Name
+		stmt := fmt.Sprintf("DROP TABLE %s", c.quote(name))
+		switch {
+		case dropped[name]:
+			stmts = append(stmts, stmt)
+		case recreated[name]:
+			// Tables can't be altered in place, so their rows are lost.
+			w := fmt.Sprintf("Table %s is dropped and recreated (%s), all its rows are deleted.", name, modified[name].RecreateReason)
+			warnings = append(warnings, w)
+			if c.Comments {
+				stmt = "-- WARNING: " + w + "\n" + stmt
+			}
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	// Sequences are created before any column default uses them, and
+	// dropped last, once no column default uses them.
+	for _, name := range d.AddedSequences {
+		seq, _ := sequenceByName(d.toSeqs, name)
+		if c.SpDialect == constants.DIALECT_POSTGRESQL {
+			stmts = append(stmts, seq.PGPrintSequence())
+		} else {
+			stmts = append(stmts, seq.PrintSequence())
+		}
+	}
+	for _, name := range d.ModifiedSequences {
+		fromSeq, _ := sequenceByName(d.fromSeqs, name)
+		toSeq, _ := sequenceByName(d.toSeqs, name)
+		stmts = append(stmts, alterSequence(fromSeq, toSeq, c)...)
+	}
+
+	// Create tables and columns.
+	for _, toId := range toOrder {
+		t := d.to[toId]
+		if _, ok := fromIds[t.Name]; !ok || recreated[t.Name] {
+			stmts = append(stmts, t.PrintCreateTable(d.to, c))
+		}
+	}
+	for _, td := range d.ModifiedTables {
+		t := d.to[td.toId]
+		for _, col := range td.AddedColumns {
+			s, _ := t.ColDefs[columnIdsByName(t)[col]].PrintColumnDef(c)
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", c.quote(t.Name), strings.TrimSpace(s)))
+		}
+		for _, cd := range td.ModifiedColumns {
+			fromCol := d.from[td.fromId].ColDefs[columnIdsByName(d.from[td.fromId])[cd.Name]]
+			toCol := t.ColDefs[columnIdsByName(t)[cd.Name]]
+			stmts = append(stmts, alterColumn(t.Name, fromCol, toCol, cd.Changes, c)...)
+		}
+		if td.OnDeleteChanged {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s SET ON DELETE %s", c.quote(t.Name), onDeleteAction(t.ParentTable.OnDelete)))
+		}
+		for _, ck := range t.CheckConstraints {
+			name := checkConstraintName(ck)
+			if contains(td.AddedCheckConstraints, name) || contains(td.ModifiedCheckConstraints, name) {
+				if ck.Name != "" {
+					stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK %s", c.quote(t.Name), c.quote(ck.Name), ck.Expr))
+				} else {
+					stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD CHECK %s", c.quote(t.Name), ck.Expr))
+				}
+			}
+		}
+	}
+
+	// Create indexes and foreign keys.
+	for _, toId := range toOrder {
+		t := d.to[toId]
+		_, existed := fromIds[t.Name]
+		all := !existed || recreated[t.Name]
+		td := modified[t.Name]
+		for _, idx := range t.Indexes {
+			if all || contains(td.AddedIndexes, idx.Name) || contains(td.ModifiedIndexes, idx.Name) {
+				stmts = append(stmts, idx.PrintCreateIndex(t, c))
+			}
+		}
+	}
+	for _, toId := range toOrder {
+		t := d.to[toId]
+		_, existed := fromIds[t.Name]
+		all := !existed || recreated[t.Name]
+		td := modified[t.Name]
+		for _, fk := range t.ForeignKeys {
+			name := foreignKeyName(d.to, toId, fk)
+			if all || contains(td.AddedForeignKeys, name) || contains(td.ModifiedForeignKeys, name) {
+				stmts = append(stmts, fk.PrintForeignKeyAlterTable(d.to, c, toId))
+			}
+		}
+	}
+	for _, name := range d.DroppedSequences {
+		stmts = append(stmts, fmt.Sprintf("DROP SEQUENCE %s", c.quote(name)))
+	}
+	return stmts, warnings
+}
+
+// alterColumn returns the statements that apply changes to column from.
+func alterColumn(table string, from, to ColumnDef, changes []string, c Config) []string {
+	var stmts []string
+	prefix := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", c.quote(table), c.quote(to.Name))
+	setDefault := contains(changes, defaultChange)
+	if c.SpDialect == constants.DIALECT_POSTGRESQL {
+		if contains(changes, typeChange) {
+			stmts = append(stmts, fmt.Sprintf("%s TYPE %s", prefix, to.T.PGPrintColumnDefType()))
+		}
+		if contains(changes, notNullChange) {
+			if to.NotNull {
+				stmts = append(stmts, prefix+" SET NOT NULL")
+			} else {
+				stmts = append(stmts, prefix+" DROP NOT NULL")
+			}
+		}
+	} else if contains(changes, typeChange) || contains(changes, notNullChange) {
+		// GoogleSQL alters the type, nullability and default value of a
+		// column together.
+		s, _ := to.PrintColumnDef(c)
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", c.quote(table), strings.TrimSpace(s)))
+		setDefault = setDefault && defaultExpression(to, c) == ""
+	}
+	if setDefault {
+		if expr := defaultExpression(to, c); expr != "" {
+			stmts = append(stmts, fmt.Sprintf("%s SET DEFAULT %s", prefix, expr))
+		} else {
+			stmts = append(stmts, prefix+" DROP DEFAULT")
+		}
+	}
+	return stmts
+}
+
+// defaultExpression returns the default value expression of cd, or "" if it
+// has none.
+func defaultExpression(cd ColumnDef, c Config) string {
+	s := cd.DefaultValue.PrintDefaultValue(cd.T) + cd.AutoGen.PrintAutoGenCol()
+	if c.SpDialect == constants.DIALECT_POSTGRESQL {
+		s = cd.DefaultValue.PGPrintDefaultValue(cd.T) + cd.AutoGen.PGPrintAutoGenCol()
+	}
+	return strings.TrimPrefix(s, " DEFAULT ")
+}
+
+func alterSequence(from, to Sequence, c Config) []string {
+	var stmts []string
+	if c.SpDialect == constants.DIALECT_POSTGRESQL {
+		if from.SkipRangeMin != to.SkipRangeMin || from.SkipRangeMax != to.SkipRangeMax {
+			if to.SkipRangeMin != "" && to.SkipRangeMax != "" {
+				stmts = append(stmts, fmt.Sprintf("ALTER SEQUENCE %s SKIP RANGE %s %s", c.quote(to.Name), to.SkipRangeMin, to.SkipRangeMax))
+			} else {
+				stmts = append(stmts, fmt.Sprintf("ALTER SEQUENCE %s NO SKIP RANGE", c.quote(to.Name)))
+			}
+		}
+		if from.StartWithCounter != to.StartWithCounter && to.StartWithCounter != "" {
+			stmts = append(stmts, fmt.Sprintf("ALTER SEQUENCE %s RESTART COUNTER WITH %s", c.quote(to.Name), to.StartWithCounter))
+		}
+		return stmts
+	}
+	var options []string
+	for _, o := range []struct{ name, from, to string }{
+		{"skip_range_min", from.SkipRangeMin, to.SkipRangeMin},
+		{"skip_range_max", from.SkipRangeMax, to.SkipRangeMax},
+		{"start_with_counter", from.StartWithCounter, to.StartWithCounter},
+	} {
+		if o.from == o.to {
+			continue
+		}
+		v := o.to
+		if v == "" {
+			v = "NULL"
+		}
+		options = append(options, fmt.Sprintf("%s = %s", o.name, v))
+	}
+	if len(options) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER SEQUENCE %s SET OPTIONS (%s)", c.quote(to.Name), strings.Join(options, ", ")))
+	}
+	return stmts
+}
+
+func tableIdsByName(s Schema) map[string]string {
+	ids := map[string]string{}
+	for id, t := range s {
+		ids[t.Name] = id
+	}
+	return ids
+}
+
+func columnIdsByName(t CreateTable) map[string]string {
+	ids := map[string]string{}
+	for id, cd := range t.ColDefs {
+		ids[cd.Name] = id
+	}
+	return ids
+}
+
+func sortedSequenceNames(seqs map[string]Sequence) []string {
+	var names []string
+	for _, seq := range seqs {
+		names = append(names, seq.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sequenceByName(seqs map[string]Sequence, name string) (Sequence, bool) {
+	for _, seq := range seqs {
+		if seq.Name == name {
+			return seq, true
+		}
+	}
+	return Sequence{}, false
+}
+
+// primaryKeySignature describes the primary key of t with column names.
+func primaryKeySignature(t CreateTable) []string {
+	pks := append([]IndexKey{}, t.PrimaryKeys...)
+	sort.Slice(pks, func(i, j int) bool { return pks[i].Order < pks[j].Order })
+	var sig []string
+	for _, k := range pks {
+		sig = append(sig, keySignature(t, k))
+	}
+	return sig
+}
+
+func keySignature(t CreateTable, k IndexKey) string {
+	if k.Desc {
+		return t.ColDefs[k.ColId].Name + " DESC"
+	}
+	return t.ColDefs[k.ColId].Name
+}
+
+func indexSignature(t CreateTable, idx CreateIndex) string {
+	keys := append([]IndexKey{}, idx.Keys...)
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Order < keys[j].Order })
+	var cols, stored []string
+	for _, k := range keys {
+		cols = append(cols, keySignature(t, k))
+	}
+	for _, colId := range idx.StoredColumnIds {
+		stored = append(stored, t.ColDefs[colId].Name)
+	}
+	sort.Strings(stored)
+	return fmt.Sprintf("unique=%t (%s) storing (%s)", idx.Unique, strings.Join(cols, ", "), strings.Join(stored, ", "))
+}
+
+func foreignKeySignature(s Schema, tableId string, fk Foreignkey) string {
+	var cols, referCols []string
+	for i, colId := range fk.ColIds {
+		cols = append(cols, s[tableId].ColDefs[colId].Name)
+		if i < len(fk.ReferColumnIds) {
+			referCols = append(referCols, s[fk.ReferTableId].ColDefs[fk.ReferColumnIds[i]].Name)
+		}
+	}
+	return fmt.Sprintf("(%s) REFERENCES %s (%s) ON DELETE %s", strings.Join(cols, ", "), s[fk.ReferTableId].Name, strings.Join(referCols, ", "), onDeleteAction(fk.OnDelete))
+}
+
+// foreignKeyName returns the name of fk, or its signature if it has no name.
+func foreignKeyName(s Schema, tableId string, fk Foreignkey) string {
+	if fk.Name != "" {
+		return fk.Name
+	}
+	return foreignKeySignature(s, tableId, fk)
+}
+
+// checkConstraintName returns the name of ck, or its expression if it has no
+// name.
+func checkConstraintName(ck CheckConstraint) string {
+	if ck.Name != "" {
+		return ck.Name
+	}
+	return ck.Expr
+}
+
+func onDeleteAction(action string) string {
+	if action == "" {
+		return constants.FK_NO_ACTION
+	}
+	return strings.ToUpper(action)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+// buildDiffSchemas returns two versions of a schema with different ids, like
+// the schemas of two independent schema conversions.
+func buildDiffSchemas() (Schema, Schema) {
+	from := Schema{
+		"t1": {
+			Name:   "users",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2", "c3", "c4"},
+			ColDefs: map[string]ColumnDef{
+				"c1": {Name: "id", T: Type{Name: Int64}, NotNull: true},
+				"c2": {Name: "name", T: Type{Name: String, Len: 50}},
+				"c3": {Name: "age", T: Type{Name: Int64}},
+				"c4": {Name: "legacy", T: Type{Name: String, Len: MaxLength}},
+			},
+			PrimaryKeys:      []IndexKey{{ColId: "c1"}},
+			Indexes:          []CreateIndex{{Name: "idx_name", TableId: "t1", Keys: []IndexKey{{ColId: "c2"}}}, {Name: "idx_legacy", TableId: "t1", Keys: []IndexKey{{ColId: "c4"}}}},
+			CheckConstraints: []CheckConstraint{{Name: "ck_age", Expr: "(age > 0)"}},
+		},
+		"t2": {
+			Name:        "orders",
+			Id:          "t2",
+			ColIds:      []string{"c5", "c6"},
+			ColDefs:     map[string]ColumnDef{"c5": {Name: "id", T: Type{Name: Int64}}, "c6": {Name: "user_id", T: Type{Name: Int64}}},
+			PrimaryKeys: []IndexKey{{ColId: "c5"}},
+			ForeignKeys: []Foreignkey{{Name: "fk_user", ColIds: []string{"c6"}, ReferTableId: "t1", ReferColumnIds: []string{"c1"}}},
+		},
+		"t3": {
+			Name:        "audit",
+			Id:          "t3",
+			ColIds:      []string{"c7"},
+			ColDefs:     map[string]ColumnDef{"c7": {Name: "id", T: Type{Name: Int64}}},
+			PrimaryKeys: []IndexKey{{ColId: "c7"}},
+		},
+	}
+	to := Schema{
+		"a": {
+			Name:   "users",
+			Id:     "a",
+			ColIds: []string{"a1", "a2", "a3", "a4"},
+			ColDefs: map[string]ColumnDef{
+				"a1": {Name: "id", T: Type{Name: Int64}, NotNull: true},
+				"a2": {Name: "name", T: Type{Name: String, Len: MaxLength}, NotNull: true},
+				"a3": {Name: "age", T: Type{Name: Int64}, DefaultValue: DefaultValue{IsPresent: true, Value: Expression{Statement: "0"}}},
+				"a4": {Name: "email", T: Type{Name: String, Len: 100}},
+			},
+			PrimaryKeys:      []IndexKey{{ColId: "a1"}},
+			Indexes:          []CreateIndex{{Name: "idx_name", TableId: "a", Keys: []IndexKey{{ColId: "a2", Desc: true}}}, {Name: "idx_email", TableId: "a", Keys: []IndexKey{{ColId: "a4"}}}},
+			CheckConstraints: []CheckConstraint{{Name: "ck_age", Expr: "(age >= 0)"}},
+		},
+		"b": {
+			Name:        "orders",
+			Id:          "b",
+			ColIds:      []string{"b1", "b2"},
+			ColDefs:     map[string]ColumnDef{"b1": {Name: "user_id", T: Type{Name: Int64}}, "b2": {Name: "id", T: Type{Name: Int64}}},
+			PrimaryKeys: []IndexKey{{ColId: "b1", Order: 1}, {ColId: "b2", Order: 2}},
+			ForeignKeys: []Foreignkey{{Name: "fk_user", ColIds: []string{"b1"}, ReferTableId: "a", ReferColumnIds: []string{"a1"}}},
+		},
+		"c": {
+			Name:        "items",
+			Id:          "c",
+			ColIds:      []string{"c1", "c2"},
+			ColDefs:     map[string]ColumnDef{"c1": {Name: "user_id", T: Type{Name: Int64}}, "c2": {Name: "item_id", T: Type{Name: Int64}, AutoGen: AutoGenCol{Name: "seq", GenerationType: constants.SEQUENCE}}},
+			PrimaryKeys: []IndexKey{{ColId: "c1", Order: 1}, {ColId: "c2", Order: 2}},
+			ParentTable: InterleavedParent{Id: "b", OnDelete: constants.FK_CASCADE},
+		},
+	}
+	return from, to
+}
+
+func TestDiffSchemas(t *testing.T) {
+	from, to := buildDiffSchemas()
+	fromSeqs := map[string]Sequence{"s1": {Name: "old_seq", SequenceKind: "BIT REVERSED POSITIVE"}, "s2": {Name: "counter", SequenceKind: "BIT REVERSED POSITIVE", StartWithCounter: "1"}}
+	toSeqs := map[string]Sequence{"x": {Name: "seq", SequenceKind: "BIT REVERSED POSITIVE"}, "y": {Name: "counter", SequenceKind: "BIT REVERSED POSITIVE", StartWithCounter: "1000"}}
+	d := DiffSchemas(from, to, fromSeqs, toSeqs)
+
+	assert.Equal(t, []string{"items"}, d.AddedTables)
+	assert.Equal(t, []string{"audit"}, d.DroppedTables)
+	assert.Equal(t, []string{"seq"}, d.AddedSequences)
+	assert.Equal(t, []string{"old_seq"}, d.DroppedSequences)
+	assert.Equal(t, []string{"counter"}, d.ModifiedSequences)
+	assert.Equal(t, []TableDiff{
+		{Name: "orders", Recreated: true, RecreateReason: pkChange, fromId: "t2", toId: "b"},
+		{
+			Name:                     "users",
+			AddedColumns:             []string{"email"},
+			DroppedColumns:           []string{"legacy"},
+			ModifiedColumns:          []ColumnDiff{{Name: "name", Changes: []string{typeChange, notNullChange}}, {Name: "age", Changes: []string{defaultChange}}},
+			AddedIndexes:             []string{"idx_email"},
+			DroppedIndexes:           []string{"idx_legacy"},
+			ModifiedIndexes:          []string{"idx_name"},
+			ModifiedCheckConstraints: []string{"ck_age"},
+			fromId:                   "t1",
+			toId:                     "a",
+		},
+	}, d.ModifiedTables)
+	assert.False(t, d.IsEmpty())
+	assert.True(t, DiffSchemas(from, from, fromSeqs, fromSeqs).IsEmpty())
+}
+
+func TestSchemaDiffStatements(t *testing.T) {
+	from, to := buildDiffSchemas()
+	fromSeqs := map[string]Sequence{"s1": {Name: "old_seq", SequenceKind: "BIT REVERSED POSITIVE"}, "s2": {Name: "counter", SequenceKind: "BIT REVERSED POSITIVE", StartWithCounter: "1"}}
+	toSeqs := map[string]Sequence{"x": {Name: "seq", SequenceKind: "BIT REVERSED POSITIVE"}, "y": {Name: "counter", SequenceKind: "BIT REVERSED POSITIVE", StartWithCounter: "1000"}}
+	d := DiffSchemas(from, to, fromSeqs, toSeqs)
+
+	stmts, warnings := d.Statements(Config{ProtectIds: true, Tables: true, ForeignKeys: true, SpDialect: constants.DIALECT_GOOGLESQL})
+	assert.Equal(t, []string{"Table orders is dropped and recreated (primary key changed), all its rows are deleted."}, warnings)
+	assert.Equal(t, []string{
+		"ALTER TABLE `orders` DROP CONSTRAINT `fk_user`",
+		"DROP INDEX `idx_name`",
+		"DROP INDEX `idx_legacy`",
+		"ALTER TABLE `users` DROP CONSTRAINT `ck_age`",
+		"ALTER TABLE `users` DROP COLUMN `legacy`",
+		"DROP TABLE `orders`",
+		"DROP TABLE `audit`",
+		"CREATE SEQUENCE seq OPTIONS (sequence_kind='bit_reversed_positive') ",
+		"ALTER SEQUENCE `counter` SET OPTIONS (start_with_counter = 1000)",
+		"CREATE TABLE `orders` (\n\t`user_id` INT64,\n\t`id` INT64,\n) PRIMARY KEY (`user_id`, `id`)",
+		"CREATE TABLE `items` (\n\t`user_id` INT64,\n\t`item_id` INT64 DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE seq)),\n) PRIMARY KEY (`user_id`, `item_id`),\nINTERLEAVE IN PARENT `orders` ON DELETE CASCADE",
+		"ALTER TABLE `users` ADD COLUMN `email` STRING(100)",
+		"ALTER TABLE `users` ALTER COLUMN `name` STRING(MAX) NOT NULL",
+		"ALTER TABLE `users` ALTER COLUMN `age` SET DEFAULT (0)",
+		"ALTER TABLE `users` ADD CONSTRAINT `ck_age` CHECK (age >= 0)",
+		"CREATE INDEX `idx_name` ON `users` (`name` DESC)",
+		"CREATE INDEX `idx_email` ON `users` (`email`)",
+		"ALTER TABLE `orders` ADD CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)",
+		"DROP SEQUENCE `old_seq`",
+	}, stmts)
+
+	// Recreated tables are flagged in the DDL.
+	stmts, _ = d.Statements(Config{Comments: true, ProtectIds: true, Tables: true, ForeignKeys: true, SpDialect: constants.DIALECT_GOOGLESQL})
+	assert.Contains(t, stmts, "-- WARNING: Table orders is dropped and recreated (primary key changed), all its rows are deleted.\nDROP TABLE `orders`")
+
+	stmts, _ = d.Statements(Config{ProtectIds: true, Tables: true, ForeignKeys: true, SpDialect: constants.DIALECT_POSTGRESQL})
+	assert.Contains(t, stmts, "ALTER SEQUENCE counter RESTART COUNTER WITH 1000")
+	assert.Contains(t, stmts, "ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(2621440)")
+	assert.Contains(t, stmts, "ALTER TABLE users ALTER COLUMN name SET NOT NULL")
+	assert.Contains(t, stmts, "ALTER TABLE users ALTER COLUMN age SET DEFAULT (0)")
+}
+
+func TestSchemaDiffOnDelete(t *testing.T) {
+	_, to := buildDiffSchemas()
+	to = Schema{"b": to["b"], "c": to["c"]}
+	from := Schema{"b": to["b"], "c": to["c"]}
+	items := from["c"]
+	items.ParentTable.OnDelete = ""
+	from["c"] = items
+
+	d := DiffSchemas(from, to, nil, nil)
+	assert.Equal(t, []TableDiff{{Name: "items", OnDeleteChanged: true, fromId: "c", toId: "c"}}, d.ModifiedTables)
+	stmts, warnings := d.Statements(Config{ProtectIds: true, SpDialect: constants.DIALECT_GOOGLESQL})
+	assert.Empty(t, warnings)
+	assert.Equal(t, []string{"ALTER TABLE `items` SET ON DELETE CASCADE"}, stmts)
+}
+
+func TestSchemaDiffUnnamedForeignKey(t *testing.T) {
+	from, to := buildDiffSchemas()
+	orders := from["t2"]
+	orders.ForeignKeys[0].Name = ""
+	from["t2"] = orders
+	users := from["t1"]
+	users.CheckConstraints[0].Name = ""
+	from["t1"] = users
+	stmts, warnings := DiffSchemas(from, to, nil, nil).Statements(Config{SpDialect: constants.DIALECT_GOOGLESQL})
+	// Unnamed constraints are skipped rather than dropped.
+	assert.Equal(t, []string{
+		"Unnamed foreign key (user_id) REFERENCES users (id) ON DELETE NO ACTION of table orders can't be dropped by name and is skipped, drop it before applying the statements.",
+		"Unnamed check constraint (age > 0) of table users can't be dropped by name and is skipped, drop it before applying the statements.",
+		"Table orders is dropped and recreated (primary key changed), all its rows are deleted.",
+	}, warnings)
+	assert.NotContains(t, stmts, "ALTER TABLE orders DROP CONSTRAINT fk_user")
+	assert.Equal(t, "DROP INDEX idx_name", stmts[0])
+}
+
+func TestSchemaDiffGeneratedColumn(t *testing.T) {
+	_, to := buildDiffSchemas()
+	to = Schema{"b": to["b"]}
+	from := Schema{"b": to["b"]}
+	orders := to["b"]
+	orders.ColIds = append(orders.ColIds, "b3")
+	orders.ColDefs = map[string]ColumnDef{"b1": orders.ColDefs["b1"], "b2": orders.ColDefs["b2"],
+		"b3": {Name: "next_id", T: Type{Name: Int64}, Generated: GeneratedColumn{IsPresent: true, Value: Expression{Statement: "id + 2"}, Stored: true}}}
+	to["b"] = orders
+	orders.ColDefs = map[string]ColumnDef{"b1": orders.ColDefs["b1"], "b2": orders.ColDefs["b2"],
+		"b3": {Name: "next_id", T: Type{Name: Int64}, Generated: GeneratedColumn{IsPresent: true, Value: Expression{Statement: "id + 1"}, Stored: true}}}
+	from["b"] = orders
+
+	d := DiffSchemas(from, to, nil, nil)
+	assert.Equal(t, []TableDiff{{Name: "orders", AddedColumns: []string{"next_id"}, DroppedColumns: []string{"next_id"}, fromId: "b", toId: "b"}}, d.ModifiedTables)
+	stmts, _ := d.Statements(Config{ProtectIds: true, SpDialect: constants.DIALECT_GOOGLESQL})
+	assert.Equal(t, []string{
+		"ALTER TABLE `orders` DROP COLUMN `next_id`",
+		"ALTER TABLE `orders` ADD COLUMN `next_id` INT64 AS (id + 2) STORED",
+	}, stmts)
+}
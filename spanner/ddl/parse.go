@@ -0,0 +1,951 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+)
+
+// ParseDDL parses Spanner DDL statements of the given dialect, as printed by
+// GetDDL, into a Schema and the sequences it defines. It supports CREATE
+// TABLE, CREATE INDEX and CREATE SEQUENCE statements, and ALTER TABLE
+// statements that add foreign keys and check constraints. GoogleSQL
+// statements are parsed with spansql, PostgreSQL statements with a parser for
+// the subset of the PostgreSQL dialect printed by GetDDL.
+//
+// newId generates the ids of the tables, columns, indexes, foreign keys,
+// check constraints, sequences and expressions from a prefix, e.g.
+// internal.GenerateId. Both maps returned are keyed by those ids.
+func ParseDDL(text string, dialect string, newId func(idPrefix string) string) (Schema, map[string]Sequence, error) {
+	if newId == nil {
+		counter := 0
+		newId = func(idPrefix string) string {
+			counter++
+			return idPrefix + strconv.Itoa(counter)
+		}
+	}
+	b := schemaBuilder{
+		newId:     newId,
+		schema:    NewSchema(),
+		sequences: map[string]Sequence{},
+		tableIds:  map[string]string{},
+	}
+	if dialect == constants.DIALECT_POSTGRESQL {
+		p := &ddlParser{schemaBuilder: &b, src: text}
+		if err := p.parse(); err != nil {
+			return nil, nil, err
+		}
+	} else if err := b.addGoogleSQL(text); err != nil {
+		return nil, nil, err
+	}
+	if err := b.resolve(); err != nil {
+		return nil, nil, err
+	}
+	return b.schema, b.sequences, nil
+}
+
+// schemaBuilder accumulates the tables and sequences of parsed statements.
+// References to tables and sequences are resolved by resolve once all
+// statements have been added, as they may be defined after their use.
+type schemaBuilder struct {
+	newId     func(idPrefix string) string
+	schema    Schema
+	sequences map[string]Sequence
+	tableIds  map[string]string // Keyed by lower case table name.
+	parents   map[string]string // Parent table names keyed by table id.
+	fks       []pendingForeignKey
+}
+
+type ddlTokenKind int
+
+const (
+	identToken ddlTokenKind = iota
+	quotedIdentToken
+	stringToken
+	numberToken
+	symbolToken
+)
+
+type ddlToken struct {
+	kind       ddlTokenKind
+	text       string // Unquoted for quoted identifiers and string literals.
+	start, end int    // Offsets of the token in the source text.
+	line       int
+}
+
+type ddlComment struct {
+	text string
+	line int
+	next int // Index of the token following the comment.
+}
+
+// pendingForeignKey is a foreign key whose referenced table may be defined
+// after the statement declaring the foreign key.
+type pendingForeignKey struct {
+	tableId    string
+	fk         Foreignkey
+	cols       []string
+	referTable string
+	referCols  []string
+}
+
+// ddlParser parses PostgreSQL dialect DDL statements.
+type ddlParser struct {
+	*schemaBuilder
+	src      string
+	toks     []ddlToken
+	comments []ddlComment
+	pos      int
+}
+
+func (p *ddlParser) parse() error {
+	if err := p.tokenize(); err != nil {
+		return err
+	}
+	for !p.done() {
+		if p.eatSymbol(";") {
+			continue
+		}
+		if err := p.parseStatement(); err != nil {
+			return err
+		}
+		if !p.done() && !p.eatSymbol(";") && !p.peekKeyword("CREATE") && !p.peekKeyword("ALTER") {
+			return p.errorf("unexpected %q at end of statement", p.peek().text)
+		}
+	}
+	return nil
+}
+
+func (p *ddlParser) tokenize() error {
+	s := p.src
+	line := 1
+	for i := 0; i < len(s); {
+		ch := s[i]
+		switch {
+		case ch == '\n':
+			line++
+			i++
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			i++
+		case ch == '-' && i+1 < len(s) && s[i+1] == '-':
+			j := strings.IndexByte(s[i:], '\n')
+			if j < 0 {
+				j = len(s) - i
+			}
+			text := strings.TrimLeft(s[i:i+j], "- ")
+			p.comments = append(p.comments, ddlComment{text: strings.TrimSpace(text), line: line, next: len(p.toks)})
+			i += j
+		case ch == '/' && i+1 < len(s) && s[i+1] == '*':
+			j := strings.Index(s[i+2:], "*/")
+			if j < 0 {
+				return fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(s[i:i+j+4], "\n")
+			i += j + 4
+		case ch == '\'' || ch == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s); j++ {
+				if s[j] == '\\' && ch != '"' && j+1 < len(s) {
+					b.WriteByte(s[j])
+					j++
+				} else if s[j] == ch {
+					// Quotes are escaped by doubling them.
+					if j+1 < len(s) && s[j+1] == ch {
+						b.WriteByte(ch)
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return fmt.Errorf("line %d: unterminated quoted string", line)
+			}
+			kind := quotedIdentToken
+			if ch == '\'' {
+				kind = stringToken
+			}
+			p.toks = append(p.toks, ddlToken{kind: kind, text: b.String(), start: i, end: j + 1, line: line})
+			line += strings.Count(s[i:j+1], "\n")
+			i = j + 1
+		case ch == '_' || isDDLLetter(ch):
+			j := i
+			for j < len(s) && (s[j] == '_' || s[j] == '$' || isDDLLetter(s[j]) || isDDLDigit(s[j])) {
+				j++
+			}
+			p.toks = append(p.toks, ddlToken{kind: identToken, text: s[i:j], start: i, end: j, line: line})
+			i = j
+		case isDDLDigit(ch):
+			j := i
+			for j < len(s) && (isDDLDigit(s[j]) || s[j] == '.' || s[j] == 'x' || s[j] == 'X' || (s[j] >= 'a' && s[j] <= 'f') || (s[j] >= 'A' && s[j] <= 'F')) {
+				j++
+			}
+			p.toks = append(p.toks, ddlToken{kind: numberToken, text: s[i:j], start: i, end: j, line: line})
+			i = j
+		default:
+			p.toks = append(p.toks, ddlToken{kind: symbolToken, text: string(ch), start: i, end: i + 1, line: line})
+			i++
+		}
+	}
+	return nil
+}
+
+func isDDLLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDDLDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func (p *ddlParser) done() bool {
+	return p.pos >= len(p.toks)
+}
+
+func (p *ddlParser) peek() ddlToken {
+	if p.done() {
+		return ddlToken{kind: symbolToken, text: "end of input", start: len(p.src), end: len(p.src)}
+	}
+	return p.toks[p.pos]
+}
+
+func (p *ddlParser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if !p.done() {
+		line = p.toks[p.pos].line
+	} else if len(p.toks) > 0 {
+		line = p.toks[len(p.toks)-1].line
+	}
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// peekKeyword reports whether the next tokens are the unquoted keywords kws.
+func (p *ddlParser) peekKeyword(kws ...string) bool {
+	for i, kw := range kws {
+		if p.pos+i >= len(p.toks) {
+			return false
+		}
+		t := p.toks[p.pos+i]
+		if t.kind != identToken || !strings.EqualFold(t.text, kw) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *ddlParser) eatKeyword(kws ...string) bool {
+	if !p.peekKeyword(kws...) {
+		return false
+	}
+	p.pos += len(kws)
+	return true
+}
+
+func (p *ddlParser) expectKeyword(kws ...string) error {
+	if !p.eatKeyword(kws...) {
+		return p.errorf("expected %s, found %q", strings.Join(kws, " "), p.peek().text)
+	}
+	return nil
+}
+
+func (p *ddlParser) peekSymbol(sym string) bool {
+	t := p.peek()
+	return !p.done() && t.kind == symbolToken && t.text == sym
+}
+
+func (p *ddlParser) eatSymbol(sym string) bool {
+	if !p.peekSymbol(sym) {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *ddlParser) expectSymbol(sym string) error {
+	if !p.eatSymbol(sym) {
+		return p.errorf("expected %q, found %q", sym, p.peek().text)
+	}
+	return nil
+}
+
+// parseName parses a possibly qualified identifier. Unquoted identifiers are
+// folded to lower case.
+func (p *ddlParser) parseName() (string, error) {
+	var parts []string
+	for {
+		t := p.peek()
+		switch {
+		case p.done():
+			return "", p.errorf("expected a name, found end of input")
+		case t.kind == quotedIdentToken:
+			parts = append(parts, t.text)
+		case t.kind == identToken:
+			parts = append(parts, strings.ToLower(t.text))
+		default:
+			return "", p.errorf("expected a name, found %q", t.text)
+		}
+		p.pos++
+		if !p.eatSymbol(".") {
+			return strings.Join(parts, "."), nil
+		}
+	}
+}
+
+// parseNameList parses a parenthesized list of names.
+func (p *ddlParser) parseNameList() ([]string, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	var names []string
+	for !p.eatSymbol(")") {
+		if len(names) > 0 {
+			if err := p.expectSymbol(","); err != nil {
+				return nil, err
+			}
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// parseParenthesized returns the source text of a parenthesized expression,
+// including the parentheses.
+func (p *ddlParser) parseParenthesized() (string, error) {
+	if !p.peekSymbol("(") {
+		return "", p.errorf("expected \"(\", found %q", p.peek().text)
+	}
+	start := p.peek().start
+	depth := 0
+	for !p.done() {
+		t := p.toks[p.pos]
+		p.pos++
+		if t.kind == symbolToken && t.text == "(" {
+			depth++
+		} else if t.kind == symbolToken && t.text == ")" {
+			depth--
+			if depth == 0 {
+				return p.src[start:t.end], nil
+			}
+		}
+	}
+	return "", p.errorf("unbalanced parentheses")
+}
+
+// parseUntil returns the source text of the tokens up to the first ",", ")"
+// or ";" outside parentheses, or the first of the keywords stops.
+func (p *ddlParser) parseUntil(stops ...string) string {
+	start := p.peek().start
+	end := start
+	depth := 0
+	for !p.done() {
+		t := p.toks[p.pos]
+		if depth == 0 && t.kind == symbolToken && (t.text == "," || t.text == ")" || t.text == ";") {
+			break
+		}
+		if depth == 0 && t.kind == identToken {
+			stop := false
+			for _, kw := range stops {
+				stop = stop || strings.EqualFold(t.text, kw)
+			}
+			if stop {
+				break
+			}
+		}
+		if t.kind == symbolToken && t.text == "(" {
+			depth++
+		} else if t.kind == symbolToken && t.text == ")" {
+			depth--
+		}
+		end = t.end
+		p.pos++
+	}
+	return strings.TrimSpace(p.src[start:end])
+}
+
+func (p *ddlParser) parseStatement() error {
+	switch {
+	case p.eatKeyword("CREATE", "TABLE"):
+		return p.parseCreateTable()
+	case p.eatKeyword("CREATE", "SEQUENCE"):
+		return p.parseCreateSequence()
+	case p.peekKeyword("CREATE"):
+		p.pos++
+		unique := p.eatKeyword("UNIQUE")
+		p.eatKeyword("NULL_FILTERED")
+		if p.eatKeyword("INDEX") {
+			return p.parseCreateIndex(unique)
+		}
+	case p.eatKeyword("ALTER", "TABLE"):
+		return p.parseAlterTable()
+	}
+	return p.errorf("unsupported DDL statement starting with %q", p.peek().text)
+}
+
+func (p *ddlParser) eatIfNotExists() {
+	p.eatKeyword("IF", "NOT", "EXISTS")
+}
+
+func (p *ddlParser) parseCreateTable() error {
+	createPos := p.pos - 2
+	p.eatIfNotExists()
+	name, err := p.parseName()
+	if err != nil {
+		return err
+	}
+	if _, ok := p.tableIds[strings.ToLower(name)]; ok {
+		return p.errorf("table %s is defined more than once", name)
+	}
+	ct := CreateTable{Name: name, Id: p.newId("t"), ColDefs: map[string]ColumnDef{}, Comment: p.commentBefore(createPos)}
+	var pk []IndexKey
+	var pkNames []string
+	if err := p.expectSymbol("("); err != nil {
+		return err
+	}
+	for !p.eatSymbol(")") {
+		switch {
+		case p.peekKeyword("CONSTRAINT"), p.peekKeyword("CHECK"), p.peekKeyword("FOREIGN"):
+			if err := p.parseTableConstraint(&ct); err != nil {
+				return err
+			}
+		case p.eatKeyword("PRIMARY", "KEY"):
+			if pk, pkNames, err = p.parseKeyList(); err != nil {
+				return err
+			}
+		default:
+			if err := p.parseColumnDef(&ct); err != nil {
+				return err
+			}
+		}
+		if !p.peekSymbol(")") {
+			if err := p.expectSymbol(","); err != nil {
+				return err
+			}
+			p.attachColumnComment(&ct)
+		}
+	}
+	if p.eatKeyword("INTERLEAVE", "IN", "PARENT") {
+		parent, err := p.parseName()
+		if err != nil {
+			return err
+		}
+		if p.parents == nil {
+			p.parents = map[string]string{}
+		}
+		p.parents[ct.Id] = parent
+		if p.eatKeyword("ON", "DELETE") {
+			switch {
+			case p.eatKeyword("CASCADE"):
+				ct.ParentTable.OnDelete = constants.FK_CASCADE
+			case p.eatKeyword("NO", "ACTION"):
+				ct.ParentTable.OnDelete = constants.FK_NO_ACTION
+			default:
+				return p.errorf("unsupported ON DELETE action %q", p.peek().text)
+			}
+		}
+	}
+	for i, key := range pk {
+		colId, ok := findColumnId(ct, pkNames[i])
+		if !ok {
+			return p.errorf("primary key column %s not found in table %s", pkNames[i], name)
+		}
+		key.ColId = colId
+		ct.PrimaryKeys = append(ct.PrimaryKeys, key)
+	}
+	p.tableIds[strings.ToLower(name)] = ct.Id
+	p.schema[ct.Id] = ct
+	return nil
+}
+
+// commentBefore returns the text of the comments immediately preceding the
+// token at index pos, which is how table comments are printed.
+func (p *ddlParser) commentBefore(pos int) string {
+	var lines []string
+	for _, c := range p.comments {
+		if c.next == pos && c.text != "" {
+			lines = append(lines, c.text)
+		}
+	}
+	return strings.Join(lines, " ")
+}
+
+// attachColumnComment sets the comment following the last column of ct on the
+// same line, which is how column comments are printed.
+func (p *ddlParser) attachColumnComment(ct *CreateTable) {
+	if len(ct.ColIds) == 0 {
+		return
+	}
+	comma := p.toks[p.pos-1]
+	for _, c := range p.comments {
+		if c.next == p.pos && c.line == comma.line && c.text != "" {
+			colId := ct.ColIds[len(ct.ColIds)-1]
+			col := ct.ColDefs[colId]
+			if col.Comment == "" {
+				col.Comment = c.text
+				ct.ColDefs[colId] = col
+			}
+		}
+	}
+}
+
+func findColumnId(ct CreateTable, name string) (string, bool) {
+	for _, colId := range ct.ColIds {
+		if strings.EqualFold(ct.ColDefs[colId].Name, name) {
+			return colId, true
+		}
+	}
+	return "", false
+}
+
+// parseKeyList parses the key parts of a primary key or index and returns
+// them along with the names of their columns.
+func (p *ddlParser) parseKeyList() ([]IndexKey, []string, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, nil, err
+	}
+	var keys []IndexKey
+	var names []string
+	for !p.eatSymbol(")") {
+		if len(keys) > 0 {
+			if err := p.expectSymbol(","); err != nil {
+				return nil, nil, err
+			}
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := IndexKey{Order: len(keys) + 1}
+		if p.eatKeyword("DESC") {
+			key.Desc = true
+		} else {
+			p.eatKeyword("ASC")
+		}
+		p.eatKeyword("NULLS", "FIRST")
+		p.eatKeyword("NULLS", "LAST")
+		keys = append(keys, key)
+		names = append(names, name)
+	}
+	return keys, names, nil
+}
+
+func (p *ddlParser) parseColumnDef(ct *CreateTable) error {
+	name, err := p.parseName()
+	if err != nil {
+		return err
+	}
+	if _, ok := findColumnId(*ct, name); ok {
+		return p.errorf("column %s is defined more than once in table %s", name, ct.Name)
+	}
+	ty, err := p.parseType()
+	if err != nil {
+		return err
+	}
+	col := ColumnDef{Name: name, T: ty, Id: p.newId("c")}
+	for !p.done() && !p.peekSymbol(",") && !p.peekSymbol(")") {
+		switch {
+		case p.eatKeyword("NOT", "NULL"):
+			col.NotNull = true
+		case p.eatKeyword("NULL"):
+		case p.eatKeyword("DEFAULT"):
+			var expr string
+			if p.peekSymbol("(") {
+				expr, err = p.parseParenthesized()
+				if err != nil {
+					return err
+				}
+				expr = strings.TrimSpace(expr[1 : len(expr)-1])
+			} else {
+				expr = p.parseUntil("NOT", "NULL", "PRIMARY", "GENERATED")
+			}
+			p.setDefault(&col, expr)
+		case p.eatKeyword("PRIMARY", "KEY"):
+			if len(ct.PrimaryKeys) > 0 {
+				return p.errorf("multiple primary keys for table %s", ct.Name)
+			}
+			ct.PrimaryKeys = []IndexKey{{ColId: col.Id, Order: 1}}
+		case p.eatKeyword("GENERATED", "ALWAYS", "AS"):
+			if !p.peekSymbol("(") {
+				return p.errorf("expected generation expression of column %s", name)
+			}
+			expr, err := p.parseParenthesized()
+			if err != nil {
+				return err
+			}
+			col.Generated = GeneratedColumn{IsPresent: true, Value: Expression{Statement: strings.TrimSpace(expr[1 : len(expr)-1])}, Stored: p.eatKeyword("STORED")}
+		default:
+			return p.errorf("unexpected %q in definition of column %s", p.peek().text, name)
+		}
+	}
+	ct.ColIds = append(ct.ColIds, col.Id)
+	ct.ColDefs[col.Id] = col
+	return nil
+}
+
+var (
+	castDefaultRe       = regexp.MustCompile(`(?is)^CAST\s*\((.*)\s+AS\s+([A-Z0-9_]+)\s*\)$`)
+	pgNextSequenceValRe = regexp.MustCompile(`(?i)^NEXTVAL\s*\(\s*'([^']+)'\s*\)$`)
+	// pgTypeAliases maps alternative names of PostgreSQL types to the names
+	// used by PGSQL_TO_STANDARD_TYPE_TYPEMAP.
+	pgTypeAliases = map[string]string{"BOOLEAN": Bool, "BIGINT": PGInt8, "REAL": PGFloat4, "DECIMAL": Numeric, "TEXT": PGVarchar}
+)
+
+// setDefault records the default value of a column. The defaults printed for
+// auto generated columns are converted back to AutoGenCol, and the casts added
+// when printing defaults of some types are removed.
+func (p *ddlParser) setDefault(col *ColumnDef, expr string) {
+	if strings.ToUpper(strings.Join(strings.Fields(expr), "")) == "SPANNER.GENERATE_UUID()" {
+		col.AutoGen = AutoGenCol{Name: constants.UUID, GenerationType: "Pre-defined"}
+		return
+	}
+	if m := pgNextSequenceValRe.FindStringSubmatch(expr); m != nil {
+		col.AutoGen = AutoGenCol{Name: m[1], GenerationType: constants.SEQUENCE}
+		return
+	}
+	if m := castDefaultRe.FindStringSubmatch(expr); m != nil && balancedParens(m[1]) {
+		if strings.EqualFold(m[2], GetPGType(col.T)) {
+			expr = strings.TrimSpace(m[1])
+		}
+	}
+	col.DefaultValue = DefaultValue{IsPresent: true, Value: Expression{ExpressionId: p.newId("e"), Statement: expr}}
+}
+
+func balancedParens(s string) bool {
+	depth := 0
+	for _, ch := range s {
+		if ch == '(' {
+			depth++
+		} else if ch == ')' {
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// parseType parses a column type. PostgreSQL types are mapped to the
+// google_standard_sql types used in the AST.
+func (p *ddlParser) parseType() (Type, error) {
+	t := p.peek()
+	if t.kind != identToken {
+		return Type{}, p.errorf("expected a type, found %q", t.text)
+	}
+	p.pos++
+	name := strings.ToUpper(t.text)
+	switch {
+	case name == "DOUBLE" && p.eatKeyword("PRECISION"):
+		name = PGFloat8
+	case name == "CHARACTER" && p.eatKeyword("VARYING"):
+		name = PGVarchar
+	case name == "TIMESTAMP" && p.eatKeyword("WITH", "TIME", "ZONE"):
+		name = PGTimestamptz
+	}
+	if alias, ok := pgTypeAliases[name]; ok {
+		name = alias
+	}
+	if standard, ok := PGSQL_TO_STANDARD_TYPE_TYPEMAP[name]; ok {
+		name = standard
+	}
+	ty := Type{Name: name}
+	switch name {
+	case Bool, Int64, Float32, Float64, Numeric, Date, Timestamp, JSON:
+	case String, Bytes:
+		ty.Len = MaxLength
+		if p.eatSymbol("(") {
+			if p.eatKeyword("MAX") {
+				ty.Len = MaxLength
+			} else {
+				n, err := strconv.ParseInt(p.peek().text, 10, 64)
+				if p.peek().kind != numberToken || err != nil {
+					return Type{}, p.errorf("invalid length %q for type %s", p.peek().text, t.text)
+				}
+				p.pos++
+				if n != PGMaxLength {
+					ty.Len = n
+				}
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return Type{}, err
+			}
+		}
+	default:
+		return Type{}, p.errorf("unsupported type %s", t.text)
+	}
+	if p.eatSymbol("[") {
+		if err := p.expectSymbol("]"); err != nil {
+			return Type{}, err
+		}
+		ty.IsArray = true
+	}
+	return ty, nil
+}
+
+// parseTableConstraint parses a check constraint or foreign key declared in a
+// CREATE TABLE statement or added by an ALTER TABLE statement.
+func (p *ddlParser) parseTableConstraint(ct *CreateTable) error {
+	var name string
+	if p.eatKeyword("CONSTRAINT") {
+		var err error
+		if name, err = p.parseName(); err != nil {
+			return err
+		}
+	}
+	switch {
+	case p.eatKeyword("CHECK"):
+		expr, err := p.parseParenthesized()
+		if err != nil {
+			return err
+		}
+		ct.CheckConstraints = append(ct.CheckConstraints, CheckConstraint{Id: p.newId("cc"), Name: name, Expr: expr, ExprId: p.newId("e")})
+		return nil
+	case p.eatKeyword("FOREIGN", "KEY"):
+		cols, err := p.parseNameList()
+		if err != nil {
+			return err
+		}
+		if err := p.expectKeyword("REFERENCES"); err != nil {
+			return err
+		}
+		referTable, err := p.parseName()
+		if err != nil {
+			return err
+		}
+		referCols, err := p.parseNameList()
+		if err != nil {
+			return err
+		}
+		if len(cols) != len(referCols) {
+			return p.errorf("foreign key %s has %d columns but references %d", name, len(cols), len(referCols))
+		}
+		fk := Foreignkey{Name: name, Id: p.newId("f")}
+		for p.peekKeyword("ON") {
+			var action *string
+			switch {
+			case p.eatKeyword("ON", "DELETE"):
+				action = &fk.OnDelete
+			case p.eatKeyword("ON", "UPDATE"):
+				action = &fk.OnUpdate
+			default:
+				return p.errorf("unexpected %q in foreign key %s", p.peek().text, name)
+			}
+			switch {
+			case p.eatKeyword("CASCADE"):
+				*action = constants.FK_CASCADE
+			case p.eatKeyword("NO", "ACTION"):
+				*action = constants.FK_NO_ACTION
+			default:
+				return p.errorf("unsupported foreign key action %q", p.peek().text)
+			}
+		}
+		p.fks = append(p.fks, pendingForeignKey{tableId: ct.Id, fk: fk, cols: cols, referTable: referTable, referCols: referCols})
+		return nil
+	}
+	return p.errorf("unsupported constraint starting with %q", p.peek().text)
+}
+
+func (p *ddlParser) parseAlterTable() error {
+	name, err := p.parseName()
+	if err != nil {
+		return err
+	}
+	tableId, ok := p.tableIds[strings.ToLower(name)]
+	if !ok {
+		return p.errorf("table %s not found", name)
+	}
+	if err := p.expectKeyword("ADD"); err != nil {
+		return err
+	}
+	ct := p.schema[tableId]
+	if err := p.parseTableConstraint(&ct); err != nil {
+		return err
+	}
+	p.schema[tableId] = ct
+	return nil
+}
+
+func (p *ddlParser) parseCreateIndex(unique bool) error {
+	p.eatIfNotExists()
+	name, err := p.parseName()
+	if err != nil {
+		return err
+	}
+	if err := p.expectKeyword("ON"); err != nil {
+		return err
+	}
+	tableName, err := p.parseName()
+	if err != nil {
+		return err
+	}
+	tableId, ok := p.tableIds[strings.ToLower(tableName)]
+	if !ok {
+		return p.errorf("table %s of index %s not found", tableName, name)
+	}
+	ct := p.schema[tableId]
+	keys, names, err := p.parseKeyList()
+	if err != nil {
+		return err
+	}
+	index := CreateIndex{Name: name, TableId: tableId, Unique: unique, Id: p.newId("i")}
+	for i, key := range keys {
+		colId, ok := findColumnId(ct, names[i])
+		if !ok {
+			return p.errorf("column %s of index %s not found in table %s", names[i], name, tableName)
+		}
+		key.ColId = colId
+		index.Keys = append(index.Keys, key)
+	}
+	if p.eatKeyword("INCLUDE") {
+		stored, err := p.parseNameList()
+		if err != nil {
+			return err
+		}
+		index.StoredColumnIds = []string{}
+		for _, colName := range stored {
+			colId, ok := findColumnId(ct, colName)
+			if !ok {
+				return p.errorf("stored column %s of index %s not found in table %s", colName, name, tableName)
+			}
+			index.StoredColumnIds = append(index.StoredColumnIds, colId)
+		}
+	}
+	// Interleaving of indexes and the filters of null filtered indexes aren't
+	// modeled by CreateIndex.
+	if p.eatSymbol(",") || p.peekKeyword("INTERLEAVE") {
+		if err := p.expectKeyword("INTERLEAVE", "IN"); err != nil {
+			return err
+		}
+		if _, err := p.parseName(); err != nil {
+			return err
+		}
+	}
+	if p.eatKeyword("WHERE") {
+		p.parseUntil()
+	}
+	ct.Indexes = append(ct.Indexes, index)
+	p.schema[tableId] = ct
+	return nil
+}
+
+func (p *ddlParser) parseCreateSequence() error {
+	p.eatIfNotExists()
+	name, err := p.parseName()
+	if err != nil {
+		return err
+	}
+	seq := Sequence{Id: p.newId("s"), Name: name, ColumnsUsingSeq: map[string][]string{}}
+	for !p.done() && !p.peekSymbol(";") && !p.peekKeyword("CREATE") && !p.peekKeyword("ALTER") {
+		switch {
+		case p.eatKeyword("BIT_REVERSED_POSITIVE"):
+			seq.SequenceKind = "BIT REVERSED POSITIVE"
+		case p.eatKeyword("SKIP", "RANGE"):
+			if seq.SkipRangeMin, err = p.parseNumber(); err != nil {
+				return err
+			}
+			if seq.SkipRangeMax, err = p.parseNumber(); err != nil {
+				return err
+			}
+		case p.eatKeyword("START", "COUNTER"):
+			p.eatKeyword("WITH")
+			if seq.StartWithCounter, err = p.parseNumber(); err != nil {
+				return err
+			}
+		default:
+			return p.errorf("unsupported option %q of sequence %s", p.peek().text, name)
+		}
+	}
+	p.sequences[seq.Id] = seq
+	return nil
+}
+
+func (p *ddlParser) parseNumber() (string, error) {
+	neg := p.eatSymbol("-")
+	t := p.peek()
+	if t.kind != numberToken {
+		return "", p.errorf("expected a number, found %q", t.text)
+	}
+	p.pos++
+	if neg {
+		return "-" + t.text, nil
+	}
+	return t.text, nil
+}
+
+// resolve replaces the names of interleaving parents, referenced tables and
+// sequences by their ids once all statements have been parsed.
+func (b *schemaBuilder) resolve() error {
+	for tableId, parent := range b.parents {
+		parentId, ok := b.tableIds[strings.ToLower(parent)]
+		if !ok {
+			return fmt.Errorf("parent table %s of table %s not found", parent, b.schema[tableId].Name)
+		}
+		ct := b.schema[tableId]
+		ct.ParentTable.Id = parentId
+		b.schema[tableId] = ct
+	}
+	for _, pending := range b.fks {
+		ct := b.schema[pending.tableId]
+		referId, ok := b.tableIds[strings.ToLower(pending.referTable)]
+		if !ok {
+			return fmt.Errorf("table %s referenced by foreign key %s of table %s not found", pending.referTable, pending.fk.Name, ct.Name)
+		}
+		fk := pending.fk
+		fk.ReferTableId = referId
+		for i := range pending.cols {
+			colId, ok := findColumnId(ct, pending.cols[i])
+			if !ok {
+				return fmt.Errorf("column %s of foreign key %s not found in table %s", pending.cols[i], fk.Name, ct.Name)
+			}
+			referColId, ok := findColumnId(b.schema[referId], pending.referCols[i])
+			if !ok {
+				return fmt.Errorf("column %s referenced by foreign key %s not found in table %s", pending.referCols[i], fk.Name, pending.referTable)
+			}
+			fk.ColIds = append(fk.ColIds, colId)
+			fk.ReferColumnIds = append(fk.ReferColumnIds, referColId)
+		}
+		ct.ForeignKeys = append(ct.ForeignKeys, fk)
+		b.schema[pending.tableId] = ct
+	}
+	seqIds := map[string]string{}
+	for id, seq := range b.sequences {
+		seqIds[strings.ToLower(seq.Name)] = id
+	}
+	for _, tableId := range GetSortedTableIdsBySpName(b.schema) {
+		ct := b.schema[tableId]
+		for _, colId := range ct.ColIds {
+			col := ct.ColDefs[colId]
+			if col.AutoGen.GenerationType != constants.SEQUENCE {
+				continue
+			}
+			seqId, ok := seqIds[strings.ToLower(col.AutoGen.Name)]
+			if !ok {
+				return fmt.Errorf("sequence %s used by column %s of table %s not found", col.AutoGen.Name, col.Name, ct.Name)
+			}
+			b.sequences[seqId].ColumnsUsingSeq[tableId] = append(b.sequences[seqId].ColumnsUsingSeq[tableId], colId)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/spanner/spansql"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+)
+
+// addGoogleSQL parses GoogleSQL dialect DDL statements with spansql and adds
+// the tables and sequences they define.
+func (b *schemaBuilder) addGoogleSQL(text string) error {
+	d, err := spansql.ParseDDL("", text)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range d.List {
+		switch stmt := stmt.(type) {
+		case *spansql.CreateTable:
+			err = b.addSpansqlTable(d, stmt)
+		case *spansql.CreateIndex:
+			err = b.addSpansqlIndex(stmt)
+		case *spansql.CreateSequence:
+			b.addSpansqlSequence(stmt)
+		case *spansql.AlterTable:
+			add, ok := stmt.Alteration.(spansql.AddConstraint)
+			if !ok {
+				return fmt.Errorf("line %d: unsupported ALTER TABLE statement %s", stmt.Position.Line, stmt.SQL())
+			}
+			tableId, ok := b.tableIds[strings.ToLower(string(stmt.Name))]
+			if !ok {
+				return fmt.Errorf("line %d: table %s not found", stmt.Position.Line, stmt.Name)
+			}
+			ct := b.schema[tableId]
+			b.addSpansqlConstraint(&ct, add.Constraint)
+			b.schema[tableId] = ct
+		default:
+			return fmt.Errorf("line %d: unsupported DDL statement %s", stmt.Pos().Line, stmt.SQL())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *schemaBuilder) addSpansqlTable(d *spansql.DDL, stmt *spansql.CreateTable) error {
+	name := string(stmt.Name)
+	if _, ok := b.tableIds[strings.ToLower(name)]; ok {
+		return fmt.Errorf("line %d: table %s is defined more than once", stmt.Position.Line, name)
+	}
+	ct := CreateTable{Name: name, Id: b.newId("t"), ColDefs: map[string]ColumnDef{}}
+	if c := d.LeadingComment(stmt); c != nil {
+		ct.Comment = commentText(c)
+	}
+	for _, cd := range stmt.Columns {
+		if _, ok := findColumnId(ct, string(cd.Name)); ok {
+			return fmt.Errorf("line %d: column %s is defined more than once in table %s", cd.Position.Line, cd.Name, name)
+		}
+		ty, err := spansqlType(cd.Type)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", cd.Position.Line, err)
+		}
+		col := ColumnDef{Name: string(cd.Name), T: ty, NotNull: cd.NotNull, Id: b.newId("c")}
+		if c := d.InlineComment(cd); c != nil {
+			col.Comment = commentText(c)
+		}
+		if cd.Default != nil {
+			b.setSpansqlDefault(&col, cd.Default)
+		}
+		if cd.Generated != nil {
+			col.Generated = GeneratedColumn{IsPresent: true, Value: Expression{Statement: cd.Generated.SQL()}, Stored: true}
+		}
+		ct.ColIds = append(ct.ColIds, col.Id)
+		ct.ColDefs[col.Id] = col
+	}
+	for _, tc := range stmt.Constraints {
+		b.addSpansqlConstraint(&ct, tc)
+	}
+	for i, kp := range stmt.PrimaryKey {
+		colId, ok := findColumnId(ct, string(kp.Column))
+		if !ok {
+			return fmt.Errorf("line %d: primary key column %s not found in table %s", stmt.Position.Line, kp.Column, name)
+		}
+		ct.PrimaryKeys = append(ct.PrimaryKeys, IndexKey{ColId: colId, Desc: kp.Desc, Order: i + 1})
+	}
+	if stmt.Interleave != nil {
+		if b.parents == nil {
+			b.parents = map[string]string{}
+		}
+		b.parents[ct.Id] = string(stmt.Interleave.Parent)
+		ct.ParentTable.OnDelete = spansqlOnDelete(stmt.Interleave.OnDelete)
+	}
+	b.tableIds[strings.ToLower(name)] = ct.Id
+	b.schema[ct.Id] = ct
+	return nil
+}
+
+// commentText joins the lines of a comment, skipping the empty lines printed
+// around table comments.
+func commentText(c *spansql.Comment) string {
+	var lines []string
+	for _, line := range c.Text {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " ")
+}
+
+func spansqlType(t spansql.Type) (Type, error) {
+	ty := Type{IsArray: t.Array}
+	switch t.Base {
+	case spansql.Bool:
+		ty.Name = Bool
+	case spansql.Int64:
+		ty.Name = Int64
+	case spansql.Float64:
+		ty.Name = Float64
+	case spansql.Numeric:
+		ty.Name = Numeric
+	case spansql.String:
+		ty.Name = String
+	case spansql.Bytes:
+		ty.Name = Bytes
+	case spansql.Date:
+		ty.Name = Date
+	case spansql.Timestamp:
+		ty.Name = Timestamp
+	case spansql.JSON:
+		ty.Name = JSON
+	default:
+		return Type{}, fmt.Errorf("unsupported type %s", t.SQL())
+	}
+	if ty.Name == String || ty.Name == Bytes {
+		ty.Len = t.Len
+		if t.Len == spansql.MaxLen {
+			ty.Len = MaxLength
+		}
+	}
+	return ty, nil
+}
+
+func spansqlOnDelete(od spansql.OnDelete) string {
+	if od == spansql.CascadeOnDelete {
+		return constants.FK_CASCADE
+	}
+	return constants.FK_NO_ACTION
+}
+
+// setSpansqlDefault records the default value of a column. The defaults
+// printed for auto generated columns are converted back to AutoGenCol, and
+// the casts added when printing defaults of some types are removed.
+func (b *schemaBuilder) setSpansqlDefault(col *ColumnDef, e spansql.Expr) {
+	if f, ok := e.(spansql.Func); ok {
+		switch strings.ToUpper(f.Name) {
+		case "GENERATE_UUID":
+			col.AutoGen = AutoGenCol{Name: constants.UUID, GenerationType: "Pre-defined"}
+			return
+		case "GET_NEXT_SEQUENCE_VALUE":
+			if len(f.Args) == 1 {
+				if seq, ok := f.Args[0].(spansql.SequenceExpr); ok {
+					col.AutoGen = AutoGenCol{Name: string(seq.Name), GenerationType: constants.SEQUENCE}
+					return
+				}
+			}
+		case "CAST":
+			if len(f.Args) == 1 {
+				if te, ok := f.Args[0].(spansql.TypedExpr); ok && strings.EqualFold(te.Type.SQL(), col.T.Name) {
+					e = te.Expr
+				}
+			}
+		}
+	}
+	col.DefaultValue = DefaultValue{IsPresent: true, Value: Expression{ExpressionId: b.newId("e"), Statement: e.SQL()}}
+}
+
+func (b *schemaBuilder) addSpansqlConstraint(ct *CreateTable, tc spansql.TableConstraint) {
+	switch c := tc.Constraint.(type) {
+	case spansql.Check:
+		ct.CheckConstraints = append(ct.CheckConstraints, CheckConstraint{Id: b.newId("cc"), Name: string(tc.Name), Expr: "(" + c.Expr.SQL() + ")", ExprId: b.newId("e")})
+	case spansql.ForeignKey:
+		fk := Foreignkey{Name: string(tc.Name), Id: b.newId("f"), OnDelete: spansqlOnDelete(c.OnDelete)}
+		pending := pendingForeignKey{tableId: ct.Id, fk: fk, referTable: string(c.RefTable)}
+		for _, col := range c.Columns {
+			pending.cols = append(pending.cols, string(col))
+		}
+		for _, col := range c.RefColumns {
+			pending.referCols = append(pending.referCols, string(col))
+		}
+		b.fks = append(b.fks, pending)
+	}
+}
+
+func (b *schemaBuilder) addSpansqlIndex(stmt *spansql.CreateIndex) error {
+	tableId, ok := b.tableIds[strings.ToLower(string(stmt.Table))]
+	if !ok {
+		return fmt.Errorf("line %d: table %s of index %s not found", stmt.Position.Line, stmt.Table, stmt.Name)
+	}
+	ct := b.schema[tableId]
+	// Interleaving of indexes and the filters of null filtered indexes aren't
+	// modeled by CreateIndex.
+	index := CreateIndex{Name: string(stmt.Name), TableId: tableId, Unique: stmt.Unique, Id: b.newId("i")}
+	for i, kp := range stmt.Columns {
+		colId, ok := findColumnId(ct, string(kp.Column))
+		if !ok {
+			return fmt.Errorf("line %d: column %s of index %s not found in table %s", stmt.Position.Line, kp.Column, stmt.Name, stmt.Table)
+		}
+		index.Keys = append(index.Keys, IndexKey{ColId: colId, Desc: kp.Desc, Order: i + 1})
+	}
+	if stmt.Storing != nil {
+		index.StoredColumnIds = []string{}
+		for _, col := range stmt.Storing {
+			colId, ok := findColumnId(ct, string(col))
+			if !ok {
+				return fmt.Errorf("line %d: stored column %s of index %s not found in table %s", stmt.Position.Line, col, stmt.Name, stmt.Table)
+			}
+			index.StoredColumnIds = append(index.StoredColumnIds, colId)
+		}
+	}
+	ct.Indexes = append(ct.Indexes, index)
+	b.schema[tableId] = ct
+	return nil
+}
+
+func (b *schemaBuilder) addSpansqlSequence(stmt *spansql.CreateSequence) {
+	seq := Sequence{Id: b.newId("s"), Name: string(stmt.Name), ColumnsUsingSeq: map[string][]string{}}
+	opts := stmt.Options
+	if opts.SequenceKind != nil && strings.EqualFold(*opts.SequenceKind, "bit_reversed_positive") {
+		seq.SequenceKind = "BIT REVERSED POSITIVE"
+	}
+	if opts.SkipRangeMin != nil {
+		seq.SkipRangeMin = strconv.Itoa(*opts.SkipRangeMin)
+	}
+	if opts.SkipRangeMax != nil {
+		seq.SkipRangeMax = strconv.Itoa(*opts.SkipRangeMax)
+	}
+	if opts.StartWithCounter != nil {
+		seq.StartWithCounter = strconv.Itoa(*opts.StartWithCounter)
+	}
+	b.sequences[seq.Id] = seq
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func roundTripSchema() (Schema, map[string]Sequence) {
+	s := Schema{
+		"t1": CreateTable{
+			Name:    "Singers",
+			Id:      "t1",
+			ColIds:  []string{"c1", "c2", "c3", "c4", "c5"},
+			Comment: "Singers table",
+			ColDefs: map[string]ColumnDef{
+				"c1": {Name: "SingerId", Id: "c1", T: Type{Name: Int64}, NotNull: true, AutoGen: AutoGenCol{Name: "SingerSeq", GenerationType: constants.SEQUENCE}},
+				"c2": {Name: "Name", Id: "c2", T: Type{Name: String, Len: 100}, Comment: "Full name"},
+				"c3": {Name: "Rating", Id: "c3", T: Type{Name: Float64}, DefaultValue: DefaultValue{IsPresent: true, Value: Expression{ExpressionId: "e1", Statement: "1.5"}}},
+				"c4": {Name: "Active", Id: "c4", T: Type{Name: Bool}, DefaultValue: DefaultValue{IsPresent: true, Value: Expression{ExpressionId: "e2", Statement: "TRUE"}}},
+				"c5": {Name: "Token", Id: "c5", T: Type{Name: String, Len: MaxLength}, AutoGen: AutoGenCol{Name: constants.UUID, GenerationType: "Pre-defined"}},
+			},
+			PrimaryKeys:      []IndexKey{{ColId: "c1", Order: 1}},
+			CheckConstraints: []CheckConstraint{{Id: "cc1", Name: "rating_check", Expr: "(Rating >= 0)", ExprId: "e3"}},
+			Indexes: []CreateIndex{
+				{Name: "SingersByName", TableId: "t1", Unique: true, Id: "i1", Keys: []IndexKey{{ColId: "c2", Desc: true, Order: 1}}, StoredColumnIds: []string{"c3"}},
+			},
+		},
+		"t2": CreateTable{
+			Name:   "Albums",
+			Id:     "t2",
+			ColIds: []string{"c6", "c7", "c8", "c9", "c13"},
+			ColDefs: map[string]ColumnDef{
+				"c6":  {Name: "SingerId", Id: "c6", T: Type{Name: Int64}, NotNull: true},
+				"c7":  {Name: "AlbumId", Id: "c7", T: Type{Name: Int64}, NotNull: true},
+				"c8":  {Name: "Title", Id: "c8", T: Type{Name: String, Len: MaxLength}},
+				"c9":  {Name: "Released", Id: "c9", T: Type{Name: Date}},
+				"c13": {Name: "TitleLength", Id: "c13", T: Type{Name: Int64}, Generated: GeneratedColumn{IsPresent: true, Value: Expression{Statement: "LENGTH(Title)"}, Stored: true}},
+			},
+			PrimaryKeys: []IndexKey{{ColId: "c6", Order: 1}, {ColId: "c7", Order: 2, Desc: true}},
+			ParentTable: InterleavedParent{Id: "t1", OnDelete: constants.FK_CASCADE},
+		},
+		"t3": CreateTable{
+			Name:   "Reviews",
+			Id:     "t3",
+			ColIds: []string{"c10", "c11", "c12"},
+			ColDefs: map[string]ColumnDef{
+				"c10": {Name: "ReviewId", Id: "c10", T: Type{Name: Int64}, NotNull: true},
+				"c11": {Name: "SingerId", Id: "c11", T: Type{Name: Int64}},
+				"c12": {Name: "Score", Id: "c12", T: Type{Name: Numeric}, DefaultValue: DefaultValue{IsPresent: true, Value: Expression{ExpressionId: "e4", Statement: "0"}}},
+			},
+			PrimaryKeys: []IndexKey{{ColId: "c10", Order: 1}},
+			ForeignKeys: []Foreignkey{{Name: "fk_singer", ColIds: []string{"c11"}, ReferTableId: "t1", ReferColumnIds: []string{"c1"}, Id: "f1", OnDelete: constants.FK_NO_ACTION}},
+		},
+	}
+	sequences := map[string]Sequence{
+		"s1": {Id: "s1", Name: "SingerSeq", SequenceKind: "BIT REVERSED POSITIVE", SkipRangeMin: "1", SkipRangeMax: "1000", StartWithCounter: "5", ColumnsUsingSeq: map[string][]string{"t1": {"c1"}}},
+	}
+	return s, sequences
+}
+
+func TestParseDDLRoundTrip(t *testing.T) {
+	for _, dialect := range []string{constants.DIALECT_GOOGLESQL, constants.DIALECT_POSTGRESQL} {
+		s, sequences := roundTripSchema()
+		if dialect == constants.DIALECT_POSTGRESQL {
+			for id, ct := range s {
+				for colId, cd := range ct.ColDefs {
+					cd.Name = strings.ToLower(cd.Name)
+					if cd.AutoGen.GenerationType == constants.SEQUENCE {
+						cd.AutoGen.Name = strings.ToLower(cd.AutoGen.Name)
+					}
+					ct.ColDefs[colId] = cd
+				}
+				ct.Name = strings.ToLower(ct.Name)
+				for i := range ct.Indexes {
+					ct.Indexes[i].Name = strings.ToLower(ct.Indexes[i].Name)
+				}
+				for i := range ct.CheckConstraints {
+					ct.CheckConstraints[i].Expr = strings.ToLower(ct.CheckConstraints[i].Expr)
+				}
+				s[id] = ct
+			}
+			seq := sequences["s1"]
+			seq.Name = strings.ToLower(seq.Name)
+			sequences["s1"] = seq
+		}
+		config := Config{Comments: true, ProtectIds: true, Tables: true, ForeignKeys: true, SpDialect: dialect}
+		printed := strings.Join(GetDDL(config, s, sequences), ";\n")
+		parsed, parsedSequences, err := ParseDDL(printed, dialect, nil)
+		assert.Nil(t, err, dialect)
+		reprinted := strings.Join(GetDDL(config, parsed, parsedSequences), ";\n")
+		assert.Equal(t, printed, reprinted, dialect)
+		assert.Equal(t, 3, len(parsed), dialect)
+		assert.Equal(t, 1, len(parsedSequences), dialect)
+	}
+}
+
+func TestParseDDL(t *testing.T) {
+	text := `
+-- Singers
+CREATE TABLE Singers (
+	SingerId INT64 NOT NULL,
+	Name STRING(MAX) DEFAULT (CAST('x' AS STRING)),  -- The name
+	Tags ARRAY<STRING(10)>,
+	Price NUMERIC DEFAULT (CAST(1.5 AS NUMERIC)),
+	CONSTRAINT positive CHECK (SingerId > 0),
+) PRIMARY KEY (SingerId);
+CREATE TABLE Albums (
+	SingerId INT64 NOT NULL,
+	AlbumId INT64 NOT NULL,
+	NextId INT64 AS (AlbumId + 1) STORED,
+	FOREIGN KEY (SingerId) REFERENCES Singers (SingerId),
+) PRIMARY KEY (SingerId, AlbumId),
+  INTERLEAVE IN PARENT Singers ON DELETE NO ACTION;
+CREATE NULL_FILTERED INDEX AlbumsByAlbumId ON Albums (AlbumId DESC) STORING (SingerId), INTERLEAVE IN Singers;
+ALTER TABLE Albums ADD CONSTRAINT album_check CHECK (AlbumId < 100);`
+	ids := map[string]int{}
+	s, sequences, err := ParseDDL(text, constants.DIALECT_GOOGLESQL, func(idPrefix string) string {
+		ids[idPrefix]++
+		return idPrefix + strings.Repeat("x", ids[idPrefix])
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(sequences))
+	assert.Equal(t, 2, len(s))
+	singers, albums := s["tx"], s["txx"]
+	assert.Equal(t, "Singers", singers.Name)
+	assert.Equal(t, "Singers", singers.Comment)
+	assert.Equal(t, []string{"cx", "cxx", "cxxx", "cxxxx"}, singers.ColIds)
+	assert.Equal(t, ColumnDef{Name: "SingerId", Id: "cx", T: Type{Name: Int64}, NotNull: true}, singers.ColDefs["cx"])
+	assert.Equal(t, ColumnDef{Name: "Name", Id: "cxx", T: Type{Name: String, Len: MaxLength}, Comment: "The name",
+		DefaultValue: DefaultValue{IsPresent: true, Value: Expression{ExpressionId: "ex", Statement: `"x"`}}}, singers.ColDefs["cxx"])
+	assert.Equal(t, Type{Name: String, Len: 10, IsArray: true}, singers.ColDefs["cxxx"].T)
+	assert.Equal(t, "1.5", singers.ColDefs["cxxxx"].DefaultValue.Value.Statement)
+	assert.Equal(t, []CheckConstraint{{Id: "ccx", Name: "positive", Expr: "(SingerId > 0)", ExprId: "exxx"}}, singers.CheckConstraints)
+	assert.Equal(t, []IndexKey{{ColId: "cx", Order: 1}}, singers.PrimaryKeys)
+
+	assert.Equal(t, InterleavedParent{Id: "tx", OnDelete: constants.FK_NO_ACTION}, albums.ParentTable)
+	assert.Equal(t, []IndexKey{{ColId: "cxxxxx", Order: 1}, {ColId: "cxxxxxx", Order: 2}}, albums.PrimaryKeys)
+	assert.Equal(t, GeneratedColumn{IsPresent: true, Value: Expression{Statement: "(AlbumId)+(1)"}, Stored: true}, albums.ColDefs["cxxxxxxx"].Generated)
+	assert.Equal(t, []Foreignkey{{Id: "fx", ColIds: []string{"cxxxxx"}, ReferTableId: "tx", ReferColumnIds: []string{"cx"}, OnDelete: constants.FK_NO_ACTION}}, albums.ForeignKeys)
+	assert.Equal(t, []CreateIndex{{Name: "AlbumsByAlbumId", TableId: "txx", Id: "ix", Keys: []IndexKey{{ColId: "cxxxxxx", Desc: true, Order: 1}}, StoredColumnIds: []string{"cxxxxx"}}}, albums.Indexes)
+	assert.Equal(t, "album_check", albums.CheckConstraints[0].Name)
+	assert.Equal(t, "(AlbumId < 100)", albums.CheckConstraints[0].Expr)
+}
+
+func TestParseDDLPG(t *testing.T) {
+	text := `CREATE SEQUENCE seq BIT_REVERSED_POSITIVE START COUNTER WITH 10;
+CREATE TABLE "Orders" (
+	id bigint NOT NULL DEFAULT nextval('seq'),
+	note text,
+	amount double precision DEFAULT 2,
+	created timestamp with time zone,
+	ok boolean DEFAULT (CAST(true AS BOOL)),
+	data jsonb,
+	PRIMARY KEY (id)
+);
+CREATE INDEX idx ON "Orders" (note) INCLUDE (amount) WHERE note IS NOT NULL;`
+	s, sequences, err := ParseDDL(text, constants.DIALECT_POSTGRESQL, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(s))
+	assert.Equal(t, 1, len(sequences))
+	for _, seq := range sequences {
+		assert.Equal(t, "seq", seq.Name)
+		assert.Equal(t, "BIT REVERSED POSITIVE", seq.SequenceKind)
+		assert.Equal(t, "10", seq.StartWithCounter)
+		for tableId, colIds := range seq.ColumnsUsingSeq {
+			assert.Equal(t, "Orders", s[tableId].Name)
+			assert.Equal(t, "id", s[tableId].ColDefs[colIds[0]].Name)
+		}
+	}
+	for _, ct := range s {
+		var types []Type
+		for _, colId := range ct.ColIds {
+			types = append(types, ct.ColDefs[colId].T)
+		}
+		assert.Equal(t, []Type{{Name: Int64}, {Name: String, Len: MaxLength}, {Name: Float64}, {Name: Timestamp}, {Name: Bool}, {Name: JSON}}, types)
+		assert.Equal(t, AutoGenCol{Name: "seq", GenerationType: constants.SEQUENCE}, ct.ColDefs[ct.ColIds[0]].AutoGen)
+		assert.Equal(t, "2", ct.ColDefs[ct.ColIds[2]].DefaultValue.Value.Statement)
+		assert.Equal(t, "true", ct.ColDefs[ct.ColIds[4]].DefaultValue.Value.Statement)
+		assert.Equal(t, []IndexKey{{ColId: ct.ColIds[0], Order: 1}}, ct.PrimaryKeys)
+		assert.Equal(t, []string{ct.ColIds[2]}, ct.Indexes[0].StoredColumnIds)
+	}
+}
+
+func TestParseDDLErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		dialect     string
+		text        string
+		expectedErr string
+	}{
+		{"unsupported statement", constants.DIALECT_GOOGLESQL, "DROP TABLE t", "unsupported DDL statement"},
+		{"unknown parent", constants.DIALECT_GOOGLESQL, "CREATE TABLE t (a INT64) PRIMARY KEY (a), INTERLEAVE IN PARENT p", "parent table p of table t not found"},
+		{"unknown referenced table", constants.DIALECT_GOOGLESQL, "CREATE TABLE t (a INT64, FOREIGN KEY (a) REFERENCES p (a)) PRIMARY KEY (a)", "table p referenced by foreign key"},
+		{"unknown primary key column", constants.DIALECT_GOOGLESQL, "CREATE TABLE t (a INT64) PRIMARY KEY (b)", "primary key column b not found"},
+		{"unknown type", constants.DIALECT_GOOGLESQL, "CREATE TABLE t (a INTEGER) PRIMARY KEY (a)", `got "INTEGER", want scalar type`},
+		{"missing sequence", constants.DIALECT_GOOGLESQL, "CREATE TABLE t (a INT64 DEFAULT (GET_NEXT_SEQUENCE_VALUE(SEQUENCE s))) PRIMARY KEY (a)", "sequence s used by column a"},
+		{"unsupported PG statement", constants.DIALECT_POSTGRESQL, "DROP TABLE t", "unsupported DDL statement"},
+		{"unknown PG type", constants.DIALECT_POSTGRESQL, "CREATE TABLE t (a int2, PRIMARY KEY (a))", "unsupported type int2"},
+		{"generated column without parentheses", constants.DIALECT_POSTGRESQL, "CREATE TABLE t (a bigint, b bigint GENERATED ALWAYS AS a + 1 STORED, PRIMARY KEY (a))", "expected generation expression of column b"},
+		{"missing PG sequence", constants.DIALECT_POSTGRESQL, "CREATE TABLE t (a bigint DEFAULT nextval('s'), PRIMARY KEY (a))", "sequence s used by column a"},
+		{"unterminated string", constants.DIALECT_POSTGRESQL, "CREATE TABLE t (a text DEFAULT 'x, PRIMARY KEY (a))", "unterminated quoted string"},
+	}
+	for _, tc := range tests {
+		_, _, err := ParseDDL(tc.text, tc.dialect, nil)
+		if assert.NotNil(t, err, tc.name) {
+			assert.Contains(t, err.Error(), tc.expectedErr, tc.name)
+		}
+	}
+}
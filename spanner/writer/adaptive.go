@@ -0,0 +1,253 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writer
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"google.golang.org/grpc/codes"
+)
+
+// Parameters used by adaptive mode to tune batches and concurrency.
+const (
+	minAdaptiveCount      = 500
+	maxAdaptiveCount      = 40 * 1000 // Spanner per-commit limit is 80,000 mutations.
+	defaultTargetLatency  = 2 * time.Second
+	adjustInterval        = 2 * time.Second
+	cpuCheckInterval      = 30 * time.Second
+	minBackoff            = 100 * time.Millisecond
+	maxBackoff            = 30 * time.Second
+	latencySmoothingRatio = 0.2 // Weight of the latest commit in the latency moving average.
+)
+
+// WriterStats reports the current settings and achieved throughput of a
+// BatchWriter.
+type WriterStats struct {
+	Adaptive       bool          // If true, batch size and concurrency are tuned automatically.
+	BatchMutations int64         // Limit on mutations per commit.
+	Writers        int64         // Limit on number of in-progress writes.
+	Latency        time.Duration // Moving average of commit latency; only tracked in adaptive mode.
+	RowsPerSecond  float64       // Rows durably written per second since the first write.
+	CPUPercent     float64       // Last observed Spanner CPU utilization; 0 if not checked.
+}
+
+// String returns a one line summary of s, suitable for progress output.
+func (s WriterStats) String() string {
+	var parts []string
+	parts = append(parts, fmt.Sprintf("%d mutations/batch", s.BatchMutations))
+	parts = append(parts, fmt.Sprintf("%d writers", s.Writers))
+	if s.Adaptive && s.Latency > 0 {
+		parts = append(parts, fmt.Sprintf("%v latency", s.Latency.Round(time.Millisecond)))
+	}
+	if s.CPUPercent > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f%% cpu", s.CPUPercent))
+	}
+	parts = append(parts, fmt.Sprintf("%.0f rows/s", s.RowsPerSecond))
+	return strings.Join(parts, ", ")
+}
+
+// adaptiveState tunes the batch size and number of in-progress writes of a
+// BatchWriter from the latency and errors of its commits. It grows both
+// while commits are fast and error free, and shrinks them when commits get
+// slow, Spanner pushes back or the CPU budget is exceeded.
+// Note: adaptiveState is accessed from go routines writing data to Spanner,
+// so hold lock to access its fields.
+type adaptiveState struct {
+	lock          sync.Mutex
+	count         int64         // Current limit on mutations per batch.
+	maxCount      int64         // Lowered when Spanner rejects a batch for having too many mutations.
+	writers       int64         // Current limit on number of in-progress writes.
+	maxWriters    int64         // Upper bound on writers, i.e. BatchWriterConfig.WriteLimit.
+	targetLatency time.Duration // Commits slower than this shrink the batch size.
+	latency       time.Duration // Moving average of commit latency.
+	pushback      bool          // Whether Spanner pushed back since the last adjustment.
+	lastAdjust    time.Time
+	failures      int // Consecutive throttling errors, used for exponential backoff.
+	// CPU budget, see BatchWriterConfig.MaxCPUPercent.
+	cpuUtilization func() (float64, error)
+	maxCPU         float64
+	cpu            float64
+	lastCPUCheck   time.Time
+	checkingCPU    bool
+}
+
+func newAdaptiveState(config BatchWriterConfig) *adaptiveState {
+	target := config.TargetLatency
+	if target <= 0 {
+		target = defaultTargetLatency
+	}
+	writers := config.WriteLimit / 4
+	if writers < 1 {
+		writers = 1
+	}
+	return &adaptiveState{
+		count:          countThreshold,
+		maxCount:       maxAdaptiveCount,
+		writers:        writers,
+		maxWriters:     config.WriteLimit,
+		targetLatency:  target,
+		lastAdjust:     time.Now(),
+		cpuUtilization: config.CPUUtilization,
+		maxCPU:         config.MaxCPUPercent,
+	}
+}
+
+func (a *adaptiveState) limits() (count int64, writers int64) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.count, a.writers
+}
+
+// observe records the outcome of a commit of count mutations that took
+// latency, and adjusts the limits at most once per adjustInterval.
+func (a *adaptiveState) observe(count int64, latency time.Duration, err error) {
+	a.maybeCheckCPU()
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	switch {
+	case err == nil:
+		a.failures = 0
+		if a.latency == 0 {
+			a.latency = latency
+		} else {
+			a.latency = time.Duration((1-latencySmoothingRatio)*float64(a.latency) + latencySmoothingRatio*float64(latency))
+		}
+	case isTooManyMutations(err):
+		// Spanner counts mutations differently than we do (e.g. for indexes
+		// we don't know about): never build a batch this big again.
+		if count/2 < a.maxCount {
+			a.maxCount = max64(count/2, 1)
+		}
+		a.count = min64(a.count, a.maxCount)
+		a.logLimits("commit exceeded the mutation limit")
+		return
+	case isThrottled(err):
+		a.failures++
+		a.pushback = true
+	}
+	if time.Since(a.lastAdjust) < adjustInterval {
+		return
+	}
+	a.lastAdjust = time.Now()
+	count, writers := a.count, a.writers
+	var reason string
+	switch {
+	case a.pushback:
+		a.count, a.writers = a.count/2, a.writers/2
+		reason = "Spanner pushed back"
+	case a.maxCPU > 0 && a.cpu > a.maxCPU:
+		a.writers = a.writers * 3 / 4
+		reason = fmt.Sprintf("CPU utilization %.0f%% is over budget", a.cpu)
+	case a.latency > a.targetLatency:
+		a.count = a.count * 3 / 4
+		reason = fmt.Sprintf("commit latency %v is over target", a.latency.Round(time.Millisecond))
+	case a.latency < a.targetLatency/2:
+		a.count = a.count * 5 / 4
+		a.writers++
+		reason = fmt.Sprintf("commit latency %v is under target", a.latency.Round(time.Millisecond))
+	}
+	a.pushback = false
+	a.count = max64(min64(a.count, a.maxCount), min64(minAdaptiveCount, a.maxCount))
+	a.writers = max64(min64(a.writers, a.maxWriters), 1)
+	if a.count != count || a.writers != writers {
+		a.logLimits(reason)
+	}
+}
+
+func (a *adaptiveState) logLimits(reason string) {
+	logger.Log.Debug(fmt.Sprintf("Adjusted writes to %d mutations per batch and %d writers: %s\n", a.count, a.writers, reason))
+}
+
+// backoff returns how long to wait before retrying a batch that failed with
+// a throttling error: exponential in the number of consecutive failures,
+// with jitter.
+func (a *adaptiveState) backoff() time.Duration {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	d := minBackoff
+	for i := 1; i < a.failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// maybeCheckCPU refreshes the CPU utilization if it is older than
+// cpuCheckInterval. Only one check runs at a time, and it runs outside the
+// lock since it typically calls Cloud Monitoring.
+func (a *adaptiveState) maybeCheckCPU() {
+	a.lock.Lock()
+	if a.cpuUtilization == nil || a.maxCPU <= 0 || a.checkingCPU || time.Since(a.lastCPUCheck) < cpuCheckInterval {
+		a.lock.Unlock()
+		return
+	}
+	a.checkingCPU = true
+	a.lock.Unlock()
+	cpu, err := a.cpuUtilization()
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.checkingCPU = false
+	a.lastCPUCheck = time.Now()
+	if err != nil {
+		logger.Log.Debug(fmt.Sprintf("Can't get Spanner CPU utilization: %v\n", err))
+		return
+	}
+	a.cpu = cpu
+}
+
+func (a *adaptiveState) stats() (latency time.Duration, cpu float64) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.latency, a.cpu
+}
+
+// isThrottled reports whether err means Spanner is overloaded or the commit
+// conflicted with another transaction, i.e. the same batch can be retried
+// after backing off.
+func isThrottled(err error) bool {
+	switch sp.ErrCode(err) {
+	case codes.Aborted, codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// isTooManyMutations reports whether err means the commit exceeded Spanner's
+// limit on mutations per commit.
+func isTooManyMutations(err error) bool {
+	return sp.ErrCode(err) == codes.InvalidArgument && strings.Contains(strings.ToLower(sp.ErrDesc(err)), "too many mutations")
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
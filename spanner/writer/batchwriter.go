@@ -25,6 +25,7 @@ import (
 	"unsafe"
 
 	sp "cloud.google.com/go/spanner"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/prommetrics"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
 )
 
@@ -47,6 +48,9 @@ const (
 // in a batch is bad.  BatchWriter respects Spanner's limits on byte size
 // and mutation count and has configurable limits on the number of
 // in-progress writes, amount of data buffered and retry behavior.
+// In adaptive mode, BatchWriter also tunes the batch size and number of
+// in-progress writes from the latency and errors of its commits, and
+// backs off before retrying batches when Spanner pushes back.
 // BatchWriter is not threadsafe: only one call to AddRow or Flush should
 // be active at any time.  See ExampleBatchWriter (batchwriter_test.go)
 // for sample usage code.
@@ -60,13 +64,32 @@ type BatchWriter struct {
 	bytesLimit int64                      // Limit on bytes buffered. AddRow blocks if rBytes exceeded this value.
 	retryLimit int64                      // Limit on retries.
 	verbose    bool                       // If true, print out messages about each write batch.
-	async      asyncState
+	// Mutations added to each row of a table by its secondary indexes.
+	indexedCols map[string]int64
+	adaptive    *adaptiveState // Nil unless adaptive mode is enabled.
+	// Cap on throughput, see BatchWriterConfig.MaxRowsPerSecond.
+	maxRowsPerSecond float64
+	rowsStarted      int64 // Rows passed to startWrite.
+	async            asyncState
+	// Called as rows are committed, see BatchWriterConfig.OnCommit.
+	onCommit func(table string, n int64, cols []string, vals []interface{})
+	// Called as rows are dropped, see BatchWriterConfig.OnDrop.
+	onDrop func(table string, cols []string, vals []interface{}, source interface{}, err error)
 }
 
 type row struct {
-	table string
-	cols  []string
-	vals  []interface{}
+	table  string
+	cols   []string
+	vals   []interface{}
+	source interface{} // Opaque to BatchWriter, see AddRowWithSource.
+}
+
+// batch is a group of rows sent to Spanner by a single call to startWrite.
+// Batches are taken from the front of bw.rows, so they are created in the
+// same order that rows were added.
+type batch struct {
+	rows []*row
+	done bool
 }
 
 // Fields in this struct are modified asynchronously e.g. by go routines writing
@@ -84,6 +107,10 @@ type asyncState struct {
 	sampleBadRows      []*row           // A sample of rows that generated errors; protected by lock.
 	sampleBadRowsBytes int64            // Estimate of bytes for sampleBadRows; protected by lock.
 	droppedRows        map[string]int64 // Count of dropped rows, broken down by table.
+	writtenRows        map[string]int64 // Count of rows durably written to Spanner, broken down by table; protected by lock.
+	inflight           []*batch         // Batches in start order that are not yet committed; protected by lock.
+	start              int64            // Time of the first write in Unix nanoseconds; access using atomic.
+	rowsWritten        int64            // Total count of rows durably written to Spanner; access using atomic.
 }
 
 // BatchWriterConfig specifies parameters for configuring BatchWriter.
@@ -93,21 +120,58 @@ type BatchWriterConfig struct {
 	RetryLimit int64                      // Limit on retries.
 	Write      func([]*sp.Mutation) error // Function to call to write to Spanner (typically a closure that calls client.Apply).
 	Verbose    bool                       // If true, print out messages about each write batch.
+	// OnCommit, if set, is called with the last row of each table once that
+	// row and every row added before it have been either written to Spanner
+	// or dropped, along with the number of rows of the table committed since
+	// the previous call. Calls are serialized and made in the order rows
+	// were added.
+	OnCommit func(table string, n int64, cols []string, vals []interface{})
+	// OnDrop, if set, is called for each row that is dropped, along with
+	// the source passed to AddRowWithSource and the error returned by
+	// Spanner for the last attempt to write the row. OnDrop may be called
+	// concurrently from go routines writing to Spanner.
+	OnDrop func(table string, cols []string, vals []interface{}, source interface{}, err error)
+	// IndexedColumns is the number of columns of the secondary indexes on
+	// each table. Spanner counts them as mutations of each row inserted.
+	IndexedColumns map[string]int64
+	// Adaptive enables tuning of the batch size and number of in-progress
+	// writes (up to WriteLimit) from observed commit latency and errors.
+	Adaptive bool
+	// TargetLatency is the commit latency adaptive mode aims for. Defaults
+	// to 2 seconds.
+	TargetLatency time.Duration
+	// MaxRowsPerSecond, if positive, caps the rate at which rows are written.
+	MaxRowsPerSecond float64
+	// MaxCPUPercent, if positive, caps the Spanner CPU utilization reported
+	// by CPUUtilization in adaptive mode: fewer writes are run in parallel
+	// while it is exceeded.
+	MaxCPUPercent  float64
+	CPUUtilization func() (float64, error)
 }
 
 // NewBatchWriter returns a new BatchWriter with parameters defined by config.
 func NewBatchWriter(config BatchWriterConfig) *BatchWriter {
-	return &BatchWriter{
+	bw := &BatchWriter{
 		write:      config.Write,
 		writeLimit: config.WriteLimit,
 		bytesLimit: config.BytesLimit,
 		retryLimit: config.RetryLimit,
 		verbose:    config.Verbose,
+		onCommit:   config.OnCommit,
+		onDrop:     config.OnDrop,
+
+		indexedCols:      config.IndexedColumns,
+		maxRowsPerSecond: config.MaxRowsPerSecond,
 		async: asyncState{
 			errors:      make(map[string]int64),
 			droppedRows: make(map[string]int64),
+			writtenRows: make(map[string]int64),
 		},
 	}
+	if config.Adaptive {
+		bw.adaptive = newAdaptiveState(config)
+	}
+	return bw
 }
 
 // AddRow appends a new row of data to bw's buffer of rows. Depending on the
@@ -115,10 +179,17 @@ func NewBatchWriter(config BatchWriterConfig) *BatchWriter {
 // or it may block (waiting for some of the writes already in progress to
 // complete) and then initiate writes.
 func (bw *BatchWriter) AddRow(table string, cols []string, vals []interface{}) {
-	r := &row{table, cols, vals}
+	bw.AddRowWithSource(table, cols, vals, nil)
+}
+
+// AddRowWithSource is like AddRow, but also records source, e.g. the source
+// row that vals were converted from. BatchWriter doesn't use source, except
+// to pass it to OnDrop if the row is dropped.
+func (bw *BatchWriter) AddRowWithSource(table string, cols []string, vals []interface{}, source interface{}) {
+	r := &row{table: table, cols: cols, vals: vals, source: source}
 	bw.rows = append(bw.rows, r)
 	bw.rBytes += byteSize(r)
-	bw.rCount += int64(len(r.cols))
+	bw.rCount += bw.mutations(r)
 	bw.writeData()
 }
 
@@ -126,7 +197,7 @@ func (bw *BatchWriter) AddRow(table string, cols []string, vals []interface{}) {
 // for them to complete.
 func (bw *BatchWriter) Flush() {
 	for len(bw.rows) > 0 {
-		if atomic.LoadInt64(&bw.async.writes) < bw.writeLimit {
+		if atomic.LoadInt64(&bw.async.writes) < bw.writers() {
 			m, count, bytes := bw.getBatch()
 			if bw.verbose {
 				fmt.Printf("Starting write of %d rows to Spanner (%d bytes, %d mutations) [%d in progress]\n",
@@ -157,6 +228,19 @@ func (bw *BatchWriter) DroppedRowsByTable() map[string]int64 {
 	return m
 }
 
+// WrittenRowsByTable returns a map of tables to counts of rows that
+// were durably written to Spanner.
+func (bw *BatchWriter) WrittenRowsByTable() map[string]int64 {
+	m := make(map[string]int64)
+	bw.async.lock.Lock()
+	defer bw.async.lock.Unlock()
+
+	for t, n := range bw.async.writtenRows {
+		m[t] = n
+	}
+	return m
+}
+
 // SampleBadRows returns a string-formatted list of sample rows that
 // generated errors. Returns at most n rows.
 // Note that we split up batches to isolate errors. Each row returned
@@ -192,17 +276,77 @@ func (bw *BatchWriter) getBadRowsForTest() []*row {
 	return bw.async.sampleBadRows
 }
 
+// Stats returns the current settings and achieved throughput of bw.
+func (bw *BatchWriter) Stats() WriterStats {
+	stats := WriterStats{
+		Adaptive:       bw.adaptive != nil,
+		BatchMutations: countThreshold,
+		Writers:        bw.writeLimit,
+	}
+	if bw.adaptive != nil {
+		stats.BatchMutations, stats.Writers = bw.adaptive.limits()
+		stats.Latency, stats.CPUPercent = bw.adaptive.stats()
+	}
+	if start := atomic.LoadInt64(&bw.async.start); start > 0 {
+		if elapsed := time.Since(time.Unix(0, start)).Seconds(); elapsed > 0 {
+			stats.RowsPerSecond = float64(atomic.LoadInt64(&bw.async.rowsWritten)) / elapsed
+		}
+	}
+	return stats
+}
+
+// mutations returns the number of mutations Spanner counts for inserting r:
+// one per column, plus one per column of each secondary index of the table.
+func (bw *BatchWriter) mutations(r *row) int64 {
+	return int64(len(r.cols)) + bw.indexedCols[r.table]
+}
+
+// batchCount returns the current limit on mutations per batch.
+func (bw *BatchWriter) batchCount() int64 {
+	if bw.adaptive != nil {
+		count, _ := bw.adaptive.limits()
+		return count
+	}
+	return countThreshold
+}
+
+// writers returns the current limit on the number of in-progress writes.
+func (bw *BatchWriter) writers() int64 {
+	if bw.adaptive != nil {
+		_, writers := bw.adaptive.limits()
+		return writers
+	}
+	return bw.writeLimit
+}
+
+// throttle blocks until n more rows can be written without exceeding
+// maxRowsPerSecond on average since the first write.
+func (bw *BatchWriter) throttle(n int) {
+	start := atomic.LoadInt64(&bw.async.start)
+	if start == 0 {
+		atomic.StoreInt64(&bw.async.start, time.Now().UnixNano())
+	} else if bw.maxRowsPerSecond > 0 {
+		due := time.Unix(0, start).Add(time.Duration(float64(bw.rowsStarted) / bw.maxRowsPerSecond * float64(time.Second)))
+		if wait := time.Until(due); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	bw.rowsStarted += int64(n)
+}
+
 // getBatch returns a slice of data from the front of bw.rows.  The slice
-// returned is the largest one not exceeding countThreshold and byteThreshold.
+// returned is the largest one not exceeding the mutation count limit
+// (countThreshold, or the adaptive batch size) and byteThreshold.
 func (bw *BatchWriter) getBatch() (rows []*row, count int64, bytes int64) {
+	countLimit := bw.batchCount()
 	for i := range bw.rows {
-		c := count + int64(len(bw.rows[i].cols))
+		c := count + bw.mutations(bw.rows[i])
 		b := bytes + byteSize(bw.rows[i])
 		// If next row puts us over the thresholds, then stop. But make sure
 		// we have at least one row. If a single row puts us over the
 		// thresholds, there's not much we can do: we just try sending it to Spanner
 		// (it might succeed, since our thresholds are conservative).
-		if (c >= countThreshold || b >= byteThreshold) && len(rows) >= 1 {
+		if (c >= countLimit || b >= byteThreshold) && len(rows) >= 1 {
 			bw.rCount -= count
 			bw.rBytes -= bytes
 			bw.rows = bw.rows[i:]
@@ -218,6 +362,23 @@ func (bw *BatchWriter) getBatch() (rows []*row, count int64, bytes int64) {
 	return rows, count, bytes
 }
 
+// countByTable calls record with the number of rows of each table in rows,
+// if metrics are enabled. Rows of a table are typically added together, so
+// runs of rows of the same table are counted at once.
+func countByTable(rows []*row, record func(table string, n int)) {
+	if !prommetrics.Enabled() {
+		return
+	}
+	for i := 0; i < len(rows); {
+		j := i + 1
+		for j < len(rows) && rows[j].table == rows[i].table {
+			j++
+		}
+		record(rows[i].table, j-i)
+		i = j
+	}
+}
+
 func (bw *BatchWriter) errorStats(rows []*row, err error, retry bool) {
 	if bw.verbose {
 		fmt.Printf("Error while writing %d rows to Spanner: %v\n", len(rows), err)
@@ -230,6 +391,7 @@ func (bw *BatchWriter) errorStats(rows []*row, err error, retry bool) {
 	if retry {
 		return
 	}
+
 	// All rows in r will be dropped.
 	if len(rows) == 1 {
 		// This is a confirmed bad row: add it to the badRows list.
@@ -244,6 +406,7 @@ func (bw *BatchWriter) errorStats(rows []*row, err error, retry bool) {
 	for _, x := range rows {
 		bw.async.droppedRows[x.table]++
 	}
+	countByTable(rows, prommetrics.RowsDropped)
 	return
 }
 
@@ -254,11 +417,44 @@ func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row) {
 	for _, x := range rows {
 		m = append(m, sp.Insert(x.table, x.cols, x.vals))
 	}
-	if err := bw.write(m); err != nil {
+	start := time.Now()
+	err := bw.write(m)
+	prommetrics.ObserveBatch(len(rows), time.Since(start), err)
+	if bw.adaptive != nil {
+		var count int64
+		for _, x := range rows {
+			count += bw.mutations(x)
+		}
+		bw.adaptive.observe(count, time.Since(start), err)
+	}
+	if err == nil {
+		bw.async.lock.Lock()
+		for _, x := range rows {
+			bw.async.writtenRows[x.table]++
+		}
+		bw.async.lock.Unlock()
+		atomic.AddInt64(&bw.async.rowsWritten, int64(len(rows)))
+		countByTable(rows, prommetrics.RowsWritten)
+	} else {
 		hitRetryLimit := atomic.LoadInt64(&bw.async.retries) >= bw.retryLimit
+		if bw.adaptive != nil && !hitRetryLimit && isThrottled(err) {
+			// Spanner is overloaded or the commit was aborted: the rows
+			// are fine, so retry the same batch after backing off.
+			bw.errorStats(rows, err, true)
+			atomic.AddInt64(&bw.async.retries, 1)
+			prommetrics.Retry()
+			time.Sleep(bw.adaptive.backoff())
+			bw.doWriteAndHandleErrors(rows)
+			return
+		}
 		retry := len(rows) > 1 && !hitRetryLimit
 		bw.errorStats(rows, err, retry)
 		if !retry {
+			if bw.onDrop != nil {
+				for _, x := range rows {
+					bw.onDrop(x.table, x.cols, x.vals, x.source, err)
+				}
+			}
 			if hitRetryLimit && bw.verbose {
 				fmt.Printf("Have hit %d retries: will not do any more\n", atomic.LoadInt64(&bw.async.retries))
 			}
@@ -271,8 +467,12 @@ func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row) {
 		// if a batch contains a bad data row (Spanner
 		// will fail the entire batch). In effect we attempt
 		// to narrow down which row (or rows) are bad, and
-		// write the 'good' rows to Spanner.
+		// write the 'good' rows to Spanner. If the batch
+		// was just too big, halving it is enough.
 		k := 1 + len(rows)/10
+		if bw.adaptive != nil && isTooManyMutations(err) {
+			k = (len(rows) + 1) / 2
+		}
 		min := func(i, j int) int {
 			if i <= j {
 				return i
@@ -281,6 +481,7 @@ func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row) {
 		}
 		for i := 0; i < len(rows); i += k {
 			atomic.AddInt64(&bw.async.retries, 1)
+			prommetrics.Retry()
 			bw.doWriteAndHandleErrors(rows[i:min(i+k, len(rows))])
 		}
 	}
@@ -288,17 +489,54 @@ func (bw *BatchWriter) doWriteAndHandleErrors(rows []*row) {
 
 // Note: backgroundWrite must be thread-safe because it is run as
 // a go routine.
-func (bw *BatchWriter) backgroundWrite(rows []*row) {
+func (bw *BatchWriter) backgroundWrite(b *batch) {
 	defer bw.wg.Done()
 	defer atomic.AddInt64(&bw.async.writes, -1)
-	bw.doWriteAndHandleErrors(rows)
+	bw.doWriteAndHandleErrors(b.rows)
+	if bw.onCommit != nil {
+		bw.commit(b)
+	}
 }
 
 // startWrite initiates an asynchronous write of rows to Spanner.
 func (bw *BatchWriter) startWrite(rows []*row) {
+	bw.throttle(len(rows))
+	b := &batch{rows: rows}
+	if bw.onCommit != nil {
+		bw.async.lock.Lock()
+		bw.async.inflight = append(bw.async.inflight, b)
+		bw.async.lock.Unlock()
+	}
 	bw.wg.Add(1)
 	atomic.AddInt64(&bw.async.writes, 1)
-	go bw.backgroundWrite(rows)
+	go bw.backgroundWrite(b)
+}
+
+// commit marks b as done and advances the committed prefix of batches.
+// Writes complete out of order, so a batch only counts as committed once
+// every batch started before it is done as well. For each table in the
+// newly committed batches, onCommit is called with the last row.
+// Note: commit must be thread-safe because it is run inside a go routine.
+func (bw *BatchWriter) commit(b *batch) {
+	bw.async.lock.Lock()
+	defer bw.async.lock.Unlock()
+	b.done = true
+	var tables []string
+	last := make(map[string]*row)
+	count := make(map[string]int64)
+	for len(bw.async.inflight) > 0 && bw.async.inflight[0].done {
+		for _, r := range bw.async.inflight[0].rows {
+			if _, ok := last[r.table]; !ok {
+				tables = append(tables, r.table)
+			}
+			last[r.table] = r
+			count[r.table]++
+		}
+		bw.async.inflight = bw.async.inflight[1:]
+	}
+	for _, t := range tables {
+		bw.onCommit(t, count[t], last[t].cols, last[t].vals)
+	}
 }
 
 // writeData initiates writes to Spanner until either:
@@ -306,8 +544,8 @@ func (bw *BatchWriter) startWrite(rows []*row) {
 // b) we've hit writeLimit and we're under bytesLimit.
 // It will block and re-try till either (a) or (b) holds.
 func (bw *BatchWriter) writeData() {
-	for bw.rCount > countThreshold || bw.rBytes > byteThreshold {
-		if atomic.LoadInt64(&bw.async.writes) < bw.writeLimit {
+	for bw.rCount > bw.batchCount() || bw.rBytes > byteThreshold {
+		if atomic.LoadInt64(&bw.async.writes) < bw.writers() {
 			m, count, bytes := bw.getBatch()
 			if bw.verbose {
 				fmt.Printf("Starting write of %d rows to Spanner (%d bytes, %d mutations) [%d in progress]\n",
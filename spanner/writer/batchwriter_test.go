@@ -28,6 +28,8 @@ import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func init() {
@@ -99,6 +101,187 @@ func TestFlush(t *testing.T) {
 	}
 }
 
+func TestOnCommit(t *testing.T) {
+	data, _ := generateRows(50000, 5)
+	var mutex sync.Mutex
+	var committed int64
+	var last []interface{}
+	config := BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		RetryLimit: 1000,
+		WriteLimit: 40,
+		Write: func(m []*sp.Mutation) error {
+			time.Sleep(time.Duration(len(m)%7) * time.Millisecond) // Complete writes out of order.
+			return nil
+		},
+		OnCommit: func(table string, n int64, cols []string, vals []interface{}) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			committed += n
+			// Rows of the committed prefix are passed in order.
+			if last != nil {
+				assert.Less(t, last[0].(string), vals[0].(string))
+			}
+			last = vals
+		},
+	}
+	bw := NewBatchWriter(config)
+	for i, r := range data {
+		bw.AddRow(r.table, r.cols, []interface{}{fmt.Sprintf("%08d", i), r.vals[1]})
+	}
+	bw.Flush()
+	assert.Equal(t, int64(len(data)), committed)
+	assert.Equal(t, fmt.Sprintf("%08d", len(data)-1), last[0])
+	assert.Equal(t, int64(len(data)), bw.WrittenRowsByTable()[data[0].table])
+}
+
+func TestOnDrop(t *testing.T) {
+	data, _ := generateRows(50, 5)
+	_, badRows := partitionRows(map[int]bool{6: true, 17: true}, data)
+	badMutations := toMutations(badRows)
+	var mutex sync.Mutex
+	var dropped []interface{}
+	config := BatchWriterConfig{
+		BytesLimit: 100 << 20,
+		RetryLimit: 1000,
+		WriteLimit: 40,
+		Write: func(m []*sp.Mutation) error {
+			if intersect(m, badMutations) {
+				return errors.New("bad data")
+			}
+			return nil
+		},
+		OnDrop: func(table string, cols []string, vals []interface{}, source interface{}, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			assert.Equal(t, "bad data", err.Error())
+			dropped = append(dropped, source)
+		},
+	}
+	bw := NewBatchWriter(config)
+	for i, r := range data {
+		bw.AddRowWithSource(r.table, r.cols, r.vals, i)
+	}
+	bw.Flush()
+	sort.Slice(dropped, func(i, j int) bool { return dropped[i].(int) < dropped[j].(int) })
+	assert.Equal(t, []interface{}{6, 17}, dropped)
+}
+
+func TestAdaptive(t *testing.T) {
+	data, _ := generateRows(20000, 5)
+	var mutex sync.Mutex
+	var calls int
+	var rowsWritten []*sp.Mutation
+	config := BatchWriterConfig{
+		BytesLimit:     100 << 20,
+		RetryLimit:     1000,
+		WriteLimit:     8,
+		Adaptive:       true,
+		IndexedColumns: map[string]int64{"table": 2},
+		Write: func(m []*sp.Mutation) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			calls++
+			// Each row counts as 4 mutations: 2 columns and 2 indexed columns.
+			assert.LessOrEqual(t, len(m)*4, countThreshold+4, "Too many mutations in write")
+			switch {
+			case len(m) > 1000:
+				return status.Error(codes.InvalidArgument, "The transaction contains too many mutations.")
+			case calls%5 == 0:
+				return status.Error(codes.Aborted, "Transaction was aborted.")
+			case calls%7 == 0:
+				return status.Error(codes.ResourceExhausted, "Server is busy.")
+			}
+			rowsWritten = append(rowsWritten, m...)
+			return nil
+		},
+	}
+	bw := NewBatchWriter(config)
+	for _, x := range data {
+		bw.AddRow(x.table, x.cols, x.vals)
+	}
+	bw.Flush()
+	// Throttling errors are retried without splitting batches, so no rows
+	// are dropped.
+	equalMutations(t, toMutations(data), rowsWritten, "adaptive")
+	assert.Empty(t, bw.DroppedRowsByTable())
+	stats := bw.Stats()
+	assert.True(t, stats.Adaptive)
+	assert.LessOrEqual(t, stats.BatchMutations, int64(4000))
+	assert.Equal(t, int64(len(data)), bw.WrittenRowsByTable()["table"])
+	assert.Greater(t, stats.RowsPerSecond, 0.0)
+}
+
+func TestAdaptiveStateObserve(t *testing.T) {
+	a := newAdaptiveState(BatchWriterConfig{WriteLimit: 40, TargetLatency: time.Second})
+	assert.Equal(t, int64(10), a.writers)
+	assert.Equal(t, int64(countThreshold), a.count)
+
+	// Fast commits grow batches and concurrency.
+	a.lastAdjust = time.Time{}
+	a.observe(100, 100*time.Millisecond, nil)
+	assert.Equal(t, int64(countThreshold*5/4), a.count)
+	assert.Equal(t, int64(11), a.writers)
+
+	// Slow commits shrink batches.
+	a.latency = 0
+	a.lastAdjust = time.Time{}
+	a.observe(100, 3*time.Second, nil)
+	assert.Equal(t, int64(countThreshold*5/4*3/4), a.count)
+	assert.Equal(t, int64(11), a.writers)
+
+	// Pushback halves both.
+	a.lastAdjust = time.Time{}
+	a.observe(100, 0, status.Error(codes.ResourceExhausted, "busy"))
+	assert.Equal(t, int64(countThreshold*5/4*3/4/2), a.count)
+	assert.Equal(t, int64(5), a.writers)
+
+	// Exceeding the mutation limit caps batches for good.
+	a.observe(4000, 0, status.Error(codes.InvalidArgument, "The transaction contains too many mutations."))
+	assert.Equal(t, int64(2000), a.count)
+	assert.Equal(t, int64(2000), a.maxCount)
+
+	// CPU over budget reduces concurrency.
+	a = newAdaptiveState(BatchWriterConfig{WriteLimit: 40, MaxCPUPercent: 65, CPUUtilization: func() (float64, error) { return 80, nil }})
+	a.lastAdjust = time.Time{}
+	a.observe(100, 100*time.Millisecond, nil)
+	assert.Equal(t, int64(7), a.writers)
+	assert.Equal(t, float64(80), a.cpu)
+}
+
+func TestMaxRowsPerSecond(t *testing.T) {
+	data, _ := generateRows(6000, 5)
+	bw := NewBatchWriter(BatchWriterConfig{
+		BytesLimit:       100 << 20,
+		RetryLimit:       1000,
+		WriteLimit:       40,
+		MaxRowsPerSecond: 20000,
+		Write:            func(m []*sp.Mutation) error { return nil },
+	})
+	start := time.Now()
+	for _, x := range data {
+		bw.AddRow(x.table, x.cols, x.vals)
+	}
+	bw.Flush()
+	// The first batch of 4999 rows goes out right away, the remaining rows
+	// wait until 4999 rows' worth of time has passed.
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+	assert.Equal(t, int64(len(data)), bw.WrittenRowsByTable()["table"])
+}
+
+func TestGetBatchWithIndexedColumns(t *testing.T) {
+	bw := NewBatchWriter(BatchWriterConfig{IndexedColumns: map[string]int64{"table": 3}})
+	data, _ := generateRows(4000, 5)
+	for _, x := range data {
+		bw.rows = append(bw.rows, x)
+		bw.rCount += bw.mutations(x)
+	}
+	rows, count, _ := bw.getBatch()
+	assert.Equal(t, countThreshold/5-1, len(rows))
+	assert.Equal(t, int64(len(rows)*5), count)
+	assert.Equal(t, int64((len(data)-len(rows))*5), bw.rCount)
+}
+
 func TestDroppedRowsByTable(t *testing.T) {
 	bw := NewBatchWriter(BatchWriterConfig{})
 	bw.async.lock.Lock()
@@ -115,8 +298,8 @@ func TestSampleBadRows(t *testing.T) {
 	bw := NewBatchWriter(BatchWriterConfig{})
 	bw.async.lock.Lock()
 	bw.async.sampleBadRows = []*row{
-		&row{"test", []string{"col1", "col2"}, []interface{}{"a", int64(42)}},
-		&row{"test", []string{"col1", "col2"}, []interface{}{"b", int64(6)}},
+		&row{"test", []string{"col1", "col2"}, []interface{}{"a", int64(42)}, nil},
+		&row{"test", []string{"col1", "col2"}, []interface{}{"b", int64(6)}, nil},
 	}
 	bw.async.lock.Unlock()
 	l := bw.SampleBadRows(1)
@@ -170,7 +353,7 @@ func generateRows(count int, size int) ([]*row, int) {
 	for i := 0; i < count; i++ {
 		// vals[0] serves as a unique id for each row.
 		vals := []interface{}{i, val}
-		r = append(r, &row{"table", cols, vals})
+		r = append(r, &row{"table", cols, vals, nil})
 	}
 	// Find the max number of rows in a write for the (fixed sized)
 	// rows generated in this test data.
@@ -0,0 +1,676 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validation compares the data migrated to Spanner with the data in
+// the source database.
+//
+// Each table is divided into chunks of primary key ranges, and the rows of
+// each chunk are counted and hashed in both databases: the hash of a chunk
+// is the sum of the hashes of the values of its rows (see
+// common.RowHashSum), computed from the same text of the values in both
+// databases. Only the chunks whose counts or hashes differ are then read
+// from both databases, converting the source rows exactly like data
+// migration does, and compared row by row to list the keys of the rows that
+// differ. Columns whose values can't be hashed in both databases are only
+// compared when every chunk is read.
+package validation
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	sp "cloud.google.com/go/spanner"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal/reports"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+const (
+	StatusMatch    = "MATCH"
+	StatusMismatch = "MISMATCH"
+	StatusSkipped  = "SKIPPED"
+	StatusError    = "ERROR"
+
+	MissingInSpanner = "MISSING_IN_SPANNER"
+	ExtraInSpanner   = "EXTRA_IN_SPANNER"
+	ValueMismatch    = "VALUE_MISMATCH"
+
+	DefaultChunks            = 64
+	DefaultMaxMismatchedKeys = 100
+)
+
+// KeyRange is a range of the values of an INT64 column of a Spanner table.
+// Start is inclusive and Limit exclusive; nil bounds are unbounded.
+type KeyRange struct {
+	Col   string
+	Start *int64
+	Limit *int64
+}
+
+// Column is a column of a Spanner table.
+type Column struct {
+	Name string
+	T    ddl.Type
+}
+
+// SpannerReader reads the data of Spanner tables.
+type SpannerReader interface {
+	// ReadRows calls f with every row of table in key range kr, with the
+	// values of cols. A nil kr reads all rows of the table.
+	ReadRows(ctx context.Context, table string, cols []string, kr *KeyRange, f func(row *sp.Row) error) error
+	// Hashable reports whether SummarizeRows can hash values of type t.
+	Hashable(t ddl.Type) bool
+	// SummarizeRows returns the number of rows of table in key range kr and
+	// the sum of the hashes of the values of hashCols, keyed by the primary
+	// key columns keyCols (see common.RowHashSum). A nil kr summarizes all
+	// rows of the table, and with no hashCols rows are only counted.
+	SummarizeRows(ctx context.Context, table string, keyCols, hashCols []Column, kr *KeyRange) (common.KeyRangeSummary, error)
+	// MinMax returns the minimum and maximum values of INT64 column col of
+	// table. Returns ok=false if the table is empty.
+	MinMax(ctx context.Context, table, col string) (min, max int64, ok bool, err error)
+}
+
+type SpannerReaderImpl struct {
+	Client    *sp.Client
+	SpDialect string
+}
+
+func (sr *SpannerReaderImpl) ReadRows(ctx context.Context, table string, cols []string, kr *KeyRange, f func(row *sp.Row) error) error {
+	var quoted []string
+	for _, c := range cols {
+		quoted = append(quoted, sr.quote(c))
+	}
+	iter := sr.Client.Single().Query(ctx, sr.statement(strings.Join(quoted, ", "), table, kr))
+	return iter.Do(f)
+}
+
+// spannerHash is the hashing of values in GoogleSQL dialect databases.
+var spannerHash = common.HashDialect{
+	Concat: common.ConcatFunction,
+	Hash: func(text string) string {
+		return "CAST(CONCAT('0x', SUBSTR(TO_HEX(MD5(" + text + ")), 1, 6)) AS INT64)"
+	},
+}
+
+// Hashable implements SpannerReader.Hashable. Values are only hashed in
+// GoogleSQL dialect databases.
+func (sr *SpannerReaderImpl) Hashable(t ddl.Type) bool {
+	_, ok := sr.hashText("", t)
+	return ok
+}
+
+// hashText returns the text of the values of column col of type t, as in
+// the sources (see common.QueryDialect).
+func (sr *SpannerReaderImpl) hashText(col string, t ddl.Type) (string, bool) {
+	if sr.SpDialect == constants.DIALECT_POSTGRESQL || t.IsArray {
+		return "", false
+	}
+	switch t.Name {
+	case ddl.Int64, ddl.Date:
+		return "CAST(" + col + " AS STRING)", true
+	case ddl.String:
+		return col, true
+	case ddl.Bool:
+		return "CASE " + col + " WHEN TRUE THEN 'true' WHEN FALSE THEN 'false' END", true
+	}
+	return "", false
+}
+
+func (sr *SpannerReaderImpl) SummarizeRows(ctx context.Context, table string, keyCols, hashCols []Column, kr *KeyRange) (common.KeyRangeSummary, error) {
+	sel := "COUNT(*)"
+	if len(hashCols) > 0 {
+		texts := func(cols []Column) ([]string, error) {
+			var l []string
+			for _, c := range cols {
+				t, ok := sr.hashText(sr.quote(c.Name), c.T)
+				if !ok {
+					return nil, fmt.Errorf("values of column %s can't be hashed", c.Name)
+				}
+				l = append(l, t)
+			}
+			return l, nil
+		}
+		keyTexts, err := texts(keyCols)
+		if err != nil {
+			return common.KeyRangeSummary{}, err
+		}
+		valueTexts, err := texts(hashCols)
+		if err != nil {
+			return common.KeyRangeSummary{}, err
+		}
+		sel += ", " + common.RowHashSum(spannerHash, keyTexts, valueTexts)
+	}
+	iter := sr.Client.Single().Query(ctx, sr.statement(sel, table, kr))
+	defer iter.Stop()
+	row, err := iter.Next()
+	if err != nil {
+		return common.KeyRangeSummary{}, err
+	}
+	var s common.KeyRangeSummary
+	if len(hashCols) == 0 {
+		err = row.Columns(&s.Rows)
+	} else {
+		err = row.Columns(&s.Rows, &s.Hash)
+	}
+	return s, err
+}
+
+func (sr *SpannerReaderImpl) MinMax(ctx context.Context, table, col string) (int64, int64, bool, error) {
+	stmt := sp.Statement{SQL: fmt.Sprintf("SELECT MIN(%s), MAX(%s) FROM %s", sr.quote(col), sr.quote(col), sr.quote(table))}
+	iter := sr.Client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+	row, err := iter.Next()
+	if err == iterator.Done {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, err
+	}
+	var min, max sp.NullInt64
+	if err := row.Columns(&min, &max); err != nil {
+		return 0, 0, false, err
+	}
+	return min.Int64, max.Int64, min.Valid && max.Valid, nil
+}
+
+func (sr *SpannerReaderImpl) quote(s string) string {
+	if sr.SpDialect == constants.DIALECT_POSTGRESQL {
+		return `"` + s + `"`
+	}
+	return "`" + s + "`"
+}
+
+// statement returns the query selecting sel from the rows of table in key
+// range kr.
+func (sr *SpannerReaderImpl) statement(sel, table string, kr *KeyRange) sp.Statement {
+	stmt := sp.Statement{SQL: fmt.Sprintf("SELECT %s FROM %s", sel, sr.quote(table)), Params: map[string]interface{}{}}
+	if kr == nil {
+		return stmt
+	}
+	var conds []string
+	param := func(v int64) string {
+		// PostgreSQL dialect parameters are numbered, and named p1, p2...
+		name := fmt.Sprintf("p%d", len(stmt.Params)+1)
+		stmt.Params[name] = v
+		if sr.SpDialect == constants.DIALECT_POSTGRESQL {
+			return fmt.Sprintf("$%d", len(stmt.Params))
+		}
+		return "@" + name
+	}
+	if kr.Start != nil {
+		conds = append(conds, fmt.Sprintf("%s >= %s", sr.quote(kr.Col), param(*kr.Start)))
+	}
+	if kr.Limit != nil {
+		conds = append(conds, fmt.Sprintf("%s < %s", sr.quote(kr.Col), param(*kr.Limit)))
+	}
+	if len(conds) > 0 {
+		stmt.SQL += " WHERE " + strings.Join(conds, " AND ")
+	}
+	return stmt
+}
+
+// DataValidator validates the data of the tables in Conv. Source rows are
+// summarized and read with InfoSchema, and Spanner rows with Spanner.
+type DataValidator struct {
+	Conv              *internal.Conv
+	InfoSchema        common.InfoSchema
+	Spanner           SpannerReader
+	Chunks            int  // Maximum number of chunks per table.
+	MaxMismatchedKeys int  // Maximum number of mismatched keys listed per table.
+	CompareValues     bool // Also compare the rows of chunks whose summaries match, which reads every row of both databases.
+}
+
+// Validate validates every table of the Spanner schema that was migrated
+// from the source database.
+func (dv *DataValidator) Validate(ctx context.Context, dbName string) reports.DataValidationReport {
+	report := reports.DataValidationReport{DbName: dbName}
+	dv.Conv.SetDataMode()
+	for _, tableId := range common.GetSortedTableIdsBySpName(dv.Conv.SpSchema) {
+		if _, ok := dv.Conv.SrcSchema[tableId]; !ok {
+			continue
+		}
+		fmt.Printf("Validating data of table %s\n", dv.Conv.SpSchema[tableId].Name)
+		tv := dv.validateTable(ctx, tableId)
+		report.Tables = append(report.Tables, tv)
+		report.Summary.Tables++
+		switch tv.Status {
+		case StatusMatch:
+			report.Summary.MatchedTables++
+		case StatusMismatch:
+			report.Summary.MismatchedTables++
+		case StatusSkipped:
+			report.Summary.SkippedTables++
+		default:
+			report.Summary.FailedTables++
+		}
+	}
+	return report
+}
+
+// tableData describes how the rows of a table are compared.
+type tableData struct {
+	tableId string
+	colIds  []string   // Source column ids migrated to Spanner.
+	cols    []string   // Spanner names of colIds.
+	types   []ddl.Type // Spanner types of colIds.
+	keyIdx  []int      // Indexes of the primary key columns in cols, nil if rows can't be keyed.
+}
+
+// columnIds returns the source column ids at indexes idx.
+func (td tableData) columnIds(idx []int) []string {
+	var l []string
+	for _, i := range idx {
+		l = append(l, td.colIds[i])
+	}
+	return l
+}
+
+// columns returns the Spanner columns at indexes idx.
+func (td tableData) columns(idx []int) []Column {
+	var l []Column
+	for _, i := range idx {
+		l = append(l, Column{Name: td.cols[i], T: td.types[i]})
+	}
+	return l
+}
+
+// keyedRow is a row of a compared chunk.
+type keyedRow struct {
+	key  []string
+	hash uint64
+}
+
+func (dv *DataValidator) validateTable(ctx context.Context, tableId string) reports.TableValidation {
+	srcTable := dv.Conv.SrcSchema[tableId]
+	spTable := dv.Conv.SpSchema[tableId]
+	tv := reports.TableValidation{SrcTableName: srcTable.Name, SpTableName: spTable.Name}
+	td := tableData{tableId: tableId, colIds: common.GetCommonColumnIds(dv.Conv, tableId, spTable.ColIds)}
+	for _, colId := range td.colIds {
+		td.cols = append(td.cols, spTable.ColDefs[colId].Name)
+		td.types = append(td.types, spTable.ColDefs[colId].T)
+	}
+	if len(td.cols) == 0 {
+		tv.Status = StatusSkipped
+		tv.Reason = "no columns are migrated from the source table"
+		return tv
+	}
+	// Rows can only be matched by key if every primary key column comes from
+	// the source, which excludes e.g. synthetic primary keys.
+	if _, synthetic := dv.Conv.SyntheticPKeys[tableId]; !synthetic {
+		for _, k := range spTable.PrimaryKeys {
+			i := indexOf(td.colIds, k.ColId)
+			if i < 0 {
+				td.keyIdx = nil
+				break
+			}
+			td.keyIdx = append(td.keyIdx, i)
+		}
+	}
+
+	ch, err := dv.chunker(ctx, td)
+	if err != nil {
+		return errorResult(tv, err)
+	}
+	hashIdx := dv.hashedColumns(td)
+	for i, col := range td.cols {
+		if !internal.Contains(hashIdx, i) {
+			tv.UnhashedColumns = append(tv.UnhashedColumns, col)
+		}
+	}
+	srcSums, unassigned, err := dv.summarizeSource(td, &ch, hashIdx)
+	if err != nil {
+		return errorResult(tv, err)
+	}
+	var keyCols, hashCols []Column
+	if len(hashIdx) > 0 {
+		keyCols, hashCols = td.columns(td.keyIdx), td.columns(hashIdx)
+	}
+	tv.Chunks = ch.count()
+	tv.SrcRowCount = unassigned
+	for c := 0; c < ch.count(); c++ {
+		spSum, err := dv.Spanner.SummarizeRows(ctx, spTable.Name, keyCols, hashCols, ch.spannerRange(c))
+		if err != nil {
+			return errorResult(tv, fmt.Errorf("couldn't summarize rows of Spanner table %s: %w", spTable.Name, err))
+		}
+		tv.SrcRowCount += srcSums[c].Rows
+		tv.SpRowCount += spSum.Rows
+		countsMatch := srcSums[c].Rows == spSum.Rows
+		if srcSums[c] == spSum && !(dv.CompareValues && td.keyIdx != nil) {
+			continue
+		}
+		// Only read the rows of the chunks that may differ.
+		var mismatchedKeys int64
+		if td.keyIdx != nil {
+			if mismatchedKeys, err = dv.compareChunk(ctx, td, ch, c, &tv); err != nil {
+				return errorResult(tv, err)
+			}
+		}
+		// Hashes can also differ for matching rows, e.g. for source strings
+		// that aren't UTF-8 encoded.
+		if !countsMatch || mismatchedKeys > 0 {
+			tv.MismatchedChunks = append(tv.MismatchedChunks, reports.ChunkMismatch{Chunk: ch.describe(c), SrcRows: srcSums[c].Rows, SpRows: spSum.Rows})
+		}
+	}
+	if tv.SrcRowCount == tv.SpRowCount && len(tv.MismatchedChunks) == 0 {
+		tv.Status = StatusMatch
+		return tv
+	}
+	tv.Status = StatusMismatch
+	switch {
+	case td.keyIdx == nil:
+		tv.Reason = "rows can't be compared by key since the primary key of the table isn't migrated from the source"
+	case tv.MismatchedKeyCount == 0:
+		// Source rows that failed conversion were never migrated.
+		tv.Reason = "some source rows couldn't be converted"
+	}
+	return tv
+}
+
+// compareChunk compares the rows of chunk c of the source and Spanner tables
+// by key, and adds the keys that differ to tv. Returns the number of keys
+// that differ.
+func (dv *DataValidator) compareChunk(ctx context.Context, td tableData, ch rangeChunker, c int, tv *reports.TableValidation) (int64, error) {
+	srcKeyed := make(map[string]keyedRow)
+	spKeyed := make(map[string]keyedRow)
+	collect := func(rows map[string]keyedRow) func(key []string, hash uint64) {
+		return func(key []string, hash uint64) {
+			// Sources may ignore the key range of the read.
+			if ch.chunk(key) == c {
+				rows[strings.Join(key, "\x00")] = keyedRow{key: key, hash: hash}
+			}
+		}
+	}
+	if _, err := dv.readSource(td, ch.sourceRange(c), collect(srcKeyed)); err != nil {
+		return 0, err
+	}
+	if err := dv.readSpanner(ctx, td, ch.spannerRange(c), collect(spKeyed)); err != nil {
+		return 0, err
+	}
+	var keys []string
+	for k := range srcKeyed {
+		keys = append(keys, k)
+	}
+	for k := range spKeyed {
+		if _, ok := srcKeyed[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var mismatched int64
+	for _, k := range keys {
+		src, inSrc := srcKeyed[k]
+		spRow, inSp := spKeyed[k]
+		var km reports.KeyMismatch
+		switch {
+		case !inSp:
+			km = reports.KeyMismatch{Key: src.key, Kind: MissingInSpanner}
+		case !inSrc:
+			km = reports.KeyMismatch{Key: spRow.key, Kind: ExtraInSpanner}
+		case src.hash != spRow.hash:
+			km = reports.KeyMismatch{Key: src.key, Kind: ValueMismatch}
+		default:
+			continue
+		}
+		mismatched++
+		tv.MismatchedKeyCount++
+		if len(tv.MismatchedKeys) < dv.MaxMismatchedKeys {
+			tv.MismatchedKeys = append(tv.MismatchedKeys, km)
+		}
+	}
+	return mismatched, nil
+}
+
+// hashedColumns returns the indexes of the columns of td whose values are
+// hashed in both databases. Values are only hashed if the source supports
+// it and every primary key column can be hashed.
+func (dv *DataValidator) hashedColumns(td tableData) []int {
+	hasher, ok := dv.InfoSchema.(common.KeyRangeHasher)
+	if !ok || td.keyIdx == nil {
+		return nil
+	}
+	var candidates []string
+	for i, colId := range td.colIds {
+		if dv.Spanner.Hashable(td.types[i]) {
+			candidates = append(candidates, colId)
+		}
+	}
+	var hashIdx []int
+	for _, colId := range hasher.HashableColumns(dv.Conv, td.tableId, candidates) {
+		hashIdx = append(hashIdx, indexOf(td.colIds, colId))
+	}
+	sort.Ints(hashIdx)
+	for _, i := range td.keyIdx {
+		if !internal.Contains(hashIdx, i) {
+			return nil
+		}
+	}
+	return hashIdx
+}
+
+// summarizeSource returns the summary of the source rows in each chunk,
+// hashing the columns of td at hashIdx. They're summarized in the database
+// if the source supports it, otherwise rows are only counted by reading
+// every row, in which case rows that fail conversion can't be assigned to a
+// chunk and are returned separately. ch is reduced to a single chunk if the
+// source can't summarize the rows of its key ranges.
+func (dv *DataValidator) summarizeSource(td tableData, ch *rangeChunker, hashIdx []int) ([]common.KeyRangeSummary, int64, error) {
+	srcTable := dv.Conv.SrcSchema[td.tableId]
+	if hasher, ok := dv.InfoSchema.(common.KeyRangeHasher); ok {
+		var keyColIds, hashColIds []string
+		if len(hashIdx) > 0 {
+			keyColIds, hashColIds = td.columnIds(td.keyIdx), td.columnIds(hashIdx)
+		}
+		sums, ok, err := hasher.HashKeyRanges(dv.Conv, td.tableId, ch.colId, ch.bounds, keyColIds, hashColIds)
+		if err == nil && !ok {
+			*ch = rangeChunker{}
+			sums, _, err = hasher.HashKeyRanges(dv.Conv, td.tableId, "", nil, keyColIds, hashColIds)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("couldn't summarize rows of source table %s: %w", srcTable.Name, err)
+		}
+		return sums, 0, nil
+	}
+	sums := make([]common.KeyRangeSummary, ch.count())
+	rows, err := dv.readSource(td, nil, func(key []string, hash uint64) {
+		sums[ch.chunk(key)].Rows++
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	var assigned int64
+	for _, s := range sums {
+		assigned += s.Rows
+	}
+	return sums, rows - assigned, nil
+}
+
+// readSource converts the rows of the source table in key range kr like
+// data migration does, and calls f with the key and hash of each converted
+// row. Returns the number of rows read, including rows that failed
+// conversion.
+func (dv *DataValidator) readSource(td tableData, kr *internal.KeyRange, f func(key []string, hash uint64)) (int64, error) {
+	conv := dv.Conv
+	srcTable := conv.SrcSchema[td.tableId]
+	badRows := conv.Stats.BadRows[srcTable.Name]
+	var rows int64
+	conv.SetDataSink(func(table string, spCols []string, spVals []interface{}) {
+		rows++
+		vals, err := sourceValues(td, spCols, spVals)
+		if err != nil {
+			conv.Unexpected(fmt.Sprintf("Couldn't encode row of table %s for validation: %s", table, err))
+		}
+		f(rowKey(td, vals), rowHash(vals))
+	})
+	defer conv.SetDataSink(nil)
+	conv.SetReadKeyRange(td.tableId, kr)
+	defer conv.SetReadKeyRange(td.tableId, nil)
+	err := dv.InfoSchema.ProcessData(conv, td.tableId, srcTable, td.colIds, conv.SpSchema[td.tableId], internal.AdditionalDataAttributes{})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't read source table %s: %w", srcTable.Name, err)
+	}
+	return rows + conv.Stats.BadRows[srcTable.Name] - badRows, nil
+}
+
+// readSpanner calls f with the key and hash of every row of the Spanner
+// table in key range kr.
+func (dv *DataValidator) readSpanner(ctx context.Context, td tableData, kr *KeyRange, f func(key []string, hash uint64)) error {
+	spTable := dv.Conv.SpSchema[td.tableId].Name
+	err := dv.Spanner.ReadRows(ctx, spTable, td.cols, kr, func(row *sp.Row) error {
+		vals, err := spannerValues(td, row)
+		if err != nil {
+			return err
+		}
+		f(rowKey(td, vals), rowHash(vals))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't read Spanner table %s: %w", spTable, err)
+	}
+	return nil
+}
+
+// chunker returns the chunking of the rows of a table. Tables whose first
+// primary key column is an INT64 are split into ranges of that column, other
+// tables form a single chunk.
+func (dv *DataValidator) chunker(ctx context.Context, td tableData) (rangeChunker, error) {
+	if td.keyIdx == nil || dv.Chunks <= 1 {
+		return rangeChunker{}, nil
+	}
+	first := td.keyIdx[0]
+	if td.types[first].Name != ddl.Int64 || td.types[first].IsArray {
+		return rangeChunker{}, nil
+	}
+	spTable := dv.Conv.SpSchema[td.tableId].Name
+	min, max, ok, err := dv.Spanner.MinMax(ctx, spTable, td.cols[first])
+	if err != nil {
+		return rangeChunker{}, fmt.Errorf("couldn't get key range of Spanner table %s: %w", spTable, err)
+	}
+	if !ok {
+		return rangeChunker{}, nil
+	}
+	return rangeChunker{colId: td.colIds[first], col: td.cols[first], bounds: common.KeyRangeBounds(min, max, dv.Chunks)}, nil
+}
+
+func errorResult(tv reports.TableValidation, err error) reports.TableValidation {
+	logger.Log.Error(fmt.Sprintf("Couldn't validate data of table %s", tv.SpTableName), zap.Error(err))
+	tv.Status = StatusError
+	tv.Reason = err.Error()
+	return tv
+}
+
+func rowKey(td tableData, vals []string) []string {
+	if td.keyIdx == nil {
+		return nil
+	}
+	key := make([]string, len(td.keyIdx))
+	for i, idx := range td.keyIdx {
+		key[i] = vals[idx]
+	}
+	return key
+}
+
+func rowHash(vals []string) uint64 {
+	h := fnv.New64a()
+	for _, v := range vals {
+		// Length prefixes keep the encoding of the row unambiguous.
+		h.Write([]byte(strconv.Itoa(len(v)) + ":" + v))
+	}
+	return h.Sum64()
+}
+
+func indexOf(l []string, s string) int {
+	for i, x := range l {
+		if x == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// rangeChunker assigns rows to ranges of their first key column, which is an
+// INT64. The first and last ranges are unbounded. Without bounds, all rows
+// form a single chunk.
+type rangeChunker struct {
+	colId  string
+	col    string
+	bounds []int64
+}
+
+func (rc rangeChunker) count() int {
+	return len(rc.bounds) + 1
+}
+
+func (rc rangeChunker) chunk(key []string) int {
+	if len(rc.bounds) == 0 {
+		return 0
+	}
+	v, err := strconv.ParseInt(key[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return sort.Search(len(rc.bounds), func(i int) bool { return rc.bounds[i] > v })
+}
+
+// limits returns the bounds of chunk i, nil if unbounded.
+func (rc rangeChunker) limits(i int) (start, limit *int64) {
+	if i > 0 {
+		start = &rc.bounds[i-1]
+	}
+	if i < len(rc.bounds) {
+		limit = &rc.bounds[i]
+	}
+	return start, limit
+}
+
+// sourceRange returns the key range of the source rows of chunk i, nil for
+// a single chunk.
+func (rc rangeChunker) sourceRange(i int) *internal.KeyRange {
+	if len(rc.bounds) == 0 {
+		return nil
+	}
+	start, limit := rc.limits(i)
+	return &internal.KeyRange{ColId: rc.colId, Start: start, Limit: limit}
+}
+
+// spannerRange returns the key range of the Spanner rows of chunk i, nil for
+// a single chunk.
+func (rc rangeChunker) spannerRange(i int) *KeyRange {
+	if len(rc.bounds) == 0 {
+		return nil
+	}
+	start, limit := rc.limits(i)
+	return &KeyRange{Col: rc.col, Start: start, Limit: limit}
+}
+
+func (rc rangeChunker) describe(i int) string {
+	switch {
+	case len(rc.bounds) == 0:
+		return "all rows"
+	case i == 0:
+		return fmt.Sprintf("%s < %d", rc.col, rc.bounds[0])
+	case i == len(rc.bounds):
+		return fmt.Sprintf("%s >= %d", rc.col, rc.bounds[i-1])
+	}
+	return fmt.Sprintf("%d <= %s < %d", rc.bounds[i-1], rc.col, rc.bounds[i])
+}
@@ -0,0 +1,413 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	sp "cloud.google.com/go/spanner"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal/reports"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/logger"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+func init() {
+	logger.Log = zap.NewNop()
+}
+
+type testRow struct {
+	cols []string
+	vals []interface{}
+}
+
+// fakeInfoSchema writes the converted rows of each table to conv, and
+// counts the rows it reads.
+type fakeInfoSchema struct {
+	common.InfoSchema
+	rows     map[string][]testRow
+	badRows  map[string]int64
+	rowsRead *int
+}
+
+func (fis fakeInfoSchema) ProcessData(conv *internal.Conv, tableId string, srcSchema schema.Table, colIds []string, spSchema ddl.CreateTable, additionalAttributes internal.AdditionalDataAttributes) error {
+	kr, restricted := conv.ReadKeyRange(tableId)
+	for _, r := range fis.rows[tableId] {
+		if restricted && !inRange(r, srcSchema.ColDefs[kr.ColId].Name, kr.Start, kr.Limit) {
+			continue
+		}
+		if fis.rowsRead != nil {
+			*fis.rowsRead++
+		}
+		conv.WriteRow(srcSchema.Name, spSchema.Name, r.cols, r.vals)
+	}
+	conv.Stats.BadRows[srcSchema.Name] += fis.badRows[tableId]
+	return nil
+}
+
+// hashingInfoSchema also counts and hashes the rows of key ranges, like SQL
+// sources do in the database. Only bigint and varchar columns are hashed.
+type hashingInfoSchema struct {
+	fakeInfoSchema
+}
+
+func (his hashingInfoSchema) HashableColumns(conv *internal.Conv, tableId string, colIds []string) []string {
+	var l []string
+	for _, colId := range colIds {
+		if t := conv.SrcSchema[tableId].ColDefs[colId].Type.Name; t == "bigint" || t == "varchar" {
+			l = append(l, colId)
+		}
+	}
+	return l
+}
+
+func (his hashingInfoSchema) HashKeyRanges(conv *internal.Conv, tableId, colId string, bounds []int64, keyColIds, hashColIds []string) ([]common.KeyRangeSummary, bool, error) {
+	names := func(colIds []string) []string {
+		var l []string
+		for _, colId := range colIds {
+			l = append(l, conv.SrcSchema[tableId].ColDefs[colId].Name)
+		}
+		return l
+	}
+	sums := make([]common.KeyRangeSummary, len(bounds)+1)
+	for _, r := range his.rows[tableId] {
+		c := 0
+		if len(bounds) > 0 {
+			v, ok := r.vals[indexOf(r.cols, conv.SrcSchema[tableId].ColDefs[colId].Name)].(int64)
+			if !ok {
+				return nil, false, nil
+			}
+			c = sort.Search(len(bounds), func(i int) bool { return bounds[i] > v })
+		}
+		sums[c].Rows++
+		sums[c].Hash += testHash(r, names(keyColIds), names(hashColIds))
+	}
+	return sums, true, nil
+}
+
+// testHash stands for the hash of a row computed in the databases.
+func testHash(r testRow, keyCols, hashCols []string) int64 {
+	text := func(col string) string {
+		switch v := r.vals[indexOf(r.cols, col)].(type) {
+		case sp.NullString:
+			if !v.Valid {
+				return "n"
+			}
+			return "v" + v.StringVal
+		default:
+			return fmt.Sprintf("v%v", v)
+		}
+	}
+	var key []string
+	for _, c := range keyCols {
+		key = append(key, text(c))
+	}
+	var sum int64
+	for i, c := range hashCols {
+		h := fnv.New32a()
+		h.Write([]byte(fmt.Sprintf("%s|%d:%s", strings.Join(key, "|"), i, text(c))))
+		sum += int64(h.Sum32() & 0xffffff)
+	}
+	return sum
+}
+
+type fakeSpannerReader struct {
+	rows     map[string][]testRow
+	rowsRead *int
+}
+
+func (fsr fakeSpannerReader) ReadRows(ctx context.Context, table string, cols []string, kr *KeyRange, f func(row *sp.Row) error) error {
+	for _, r := range fsr.rows[table] {
+		if kr != nil && !inRange(r, kr.Col, kr.Start, kr.Limit) {
+			continue
+		}
+		if fsr.rowsRead != nil {
+			*fsr.rowsRead++
+		}
+		row, err := sp.NewRow(cols, r.vals)
+		if err != nil {
+			return err
+		}
+		if err := f(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fsr fakeSpannerReader) Hashable(t ddl.Type) bool {
+	return !t.IsArray && (t.Name == ddl.Int64 || t.Name == ddl.String)
+}
+
+func (fsr fakeSpannerReader) SummarizeRows(ctx context.Context, table string, keyCols, hashCols []Column, kr *KeyRange) (common.KeyRangeSummary, error) {
+	names := func(cols []Column) []string {
+		var l []string
+		for _, c := range cols {
+			l = append(l, c.Name)
+		}
+		return l
+	}
+	var s common.KeyRangeSummary
+	for _, r := range fsr.rows[table] {
+		if kr == nil || inRange(r, kr.Col, kr.Start, kr.Limit) {
+			s.Rows++
+			s.Hash += testHash(r, names(keyCols), names(hashCols))
+		}
+	}
+	return s, nil
+}
+
+func (fsr fakeSpannerReader) MinMax(ctx context.Context, table, col string) (int64, int64, bool, error) {
+	var min, max int64
+	for i, r := range fsr.rows[table] {
+		v := r.vals[indexOf(r.cols, col)].(int64)
+		if i == 0 || v < min {
+			min = v
+		}
+		if i == 0 || v > max {
+			max = v
+		}
+	}
+	return min, max, len(fsr.rows[table]) > 0, nil
+}
+
+func inRange(r testRow, col string, start, limit *int64) bool {
+	v := r.vals[indexOf(r.cols, col)].(int64)
+	return (start == nil || v >= *start) && (limit == nil || v < *limit)
+}
+
+func buildValidationConv() *internal.Conv {
+	conv := internal.MakeConv()
+	conv.SrcSchema["t1"] = schema.Table{
+		Id:     "t1",
+		Name:   "orders",
+		ColIds: []string{"c1", "c2"},
+		ColDefs: map[string]schema.Column{
+			"c1": {Id: "c1", Name: "id", Type: schema.Type{Name: "bigint"}},
+			"c2": {Id: "c2", Name: "amount", Type: schema.Type{Name: "decimal"}},
+		},
+		PrimaryKeys: []schema.Key{{ColId: "c1"}},
+	}
+	conv.SpSchema["t1"] = ddl.CreateTable{
+		Id:     "t1",
+		Name:   "orders",
+		ColIds: []string{"c1", "c2"},
+		ColDefs: map[string]ddl.ColumnDef{
+			"c1": {Id: "c1", Name: "id", T: ddl.Type{Name: ddl.Int64}},
+			"c2": {Id: "c2", Name: "amount", T: ddl.Type{Name: ddl.Numeric}},
+		},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c1"}},
+	}
+	conv.SrcSchema["t2"] = schema.Table{
+		Id:          "t2",
+		Name:        "tags",
+		ColIds:      []string{"c3"},
+		ColDefs:     map[string]schema.Column{"c3": {Id: "c3", Name: "tag", Type: schema.Type{Name: "varchar"}}},
+		PrimaryKeys: []schema.Key{{ColId: "c3"}},
+	}
+	conv.SpSchema["t2"] = ddl.CreateTable{
+		Id:          "t2",
+		Name:        "tags",
+		ColIds:      []string{"c3"},
+		ColDefs:     map[string]ddl.ColumnDef{"c3": {Id: "c3", Name: "tag", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}}},
+		PrimaryKeys: []ddl.IndexKey{{ColId: "c3"}},
+	}
+	return conv
+}
+
+func orderRows(n int64) []testRow {
+	var rows []testRow
+	for i := int64(1); i <= n; i++ {
+		rows = append(rows, testRow{[]string{"id", "amount"}, []interface{}{i, big.NewRat(i, 2)}})
+	}
+	return rows
+}
+
+func testOrders() (src, spanner []testRow) {
+	src = orderRows(100)
+	spanner = orderRows(101)                                                                    // Extra row 101.
+	spanner = append(spanner[:6], spanner[7:]...)                                               // Missing row 7.
+	spanner[40] = testRow{[]string{"id", "amount"}, []interface{}{int64(42), big.NewRat(1, 3)}} // Different value for row 42.
+	spanner[50] = testRow{[]string{"id", "amount"}, []interface{}{int64(52), sp.NullNumeric{}}} // NULL value for row 52.
+	return src, spanner
+}
+
+func TestValidate(t *testing.T) {
+	srcOrders, spOrders := testOrders()
+	tags := []testRow{{[]string{"tag"}, []interface{}{"a"}}, {[]string{"tag"}, []interface{}{"b"}}}
+	var srcRowsRead, spRowsRead int
+	dv := DataValidator{
+		Conv: buildValidationConv(),
+		InfoSchema: hashingInfoSchema{fakeInfoSchema{
+			rows:     map[string][]testRow{"t1": srcOrders, "t2": tags},
+			badRows:  map[string]int64{},
+			rowsRead: &srcRowsRead,
+		}},
+		Spanner:           fakeSpannerReader{rows: map[string][]testRow{"orders": spOrders, "tags": tags}, rowsRead: &spRowsRead},
+		Chunks:            8,
+		MaxMismatchedKeys: 3,
+	}
+	report := dv.Validate(context.Background(), "db")
+	assert.Equal(t, reports.DataValidationSummary{Tables: 2, MatchedTables: 1, MismatchedTables: 1}, report.Summary)
+
+	orders := report.Tables[0]
+	assert.Equal(t, "orders", orders.SpTableName)
+	assert.Equal(t, StatusMismatch, orders.Status)
+	assert.Equal(t, int64(100), orders.SrcRowCount)
+	assert.Equal(t, int64(100), orders.SpRowCount)
+	assert.Equal(t, 8, orders.Chunks)
+	// Rows are summarized in the databases, and only the rows of the chunks
+	// whose summaries differ are read. Numeric values aren't hashed, so
+	// differences in amount go unnoticed.
+	assert.Equal(t, []reports.ChunkMismatch{
+		{Chunk: "id < 14", SrcRows: 13, SpRows: 12},
+		{Chunk: "id >= 92", SrcRows: 9, SpRows: 10},
+	}, orders.MismatchedChunks)
+	assert.Equal(t, int64(2), orders.MismatchedKeyCount)
+	assert.Equal(t, []reports.KeyMismatch{
+		{Key: []string{"7"}, Kind: MissingInSpanner},
+		{Key: []string{"101"}, Kind: ExtraInSpanner},
+	}, orders.MismatchedKeys)
+	assert.Equal(t, []string{"amount"}, orders.UnhashedColumns)
+	assert.Equal(t, 22, srcRowsRead)
+	assert.Equal(t, 22, spRowsRead)
+
+	assert.Equal(t, reports.TableValidation{SrcTableName: "tags", SpTableName: "tags", Status: StatusMatch, SrcRowCount: 2, SpRowCount: 2, Chunks: 1}, report.Tables[1])
+}
+
+func TestValidateCompareValues(t *testing.T) {
+	srcOrders, spOrders := testOrders()
+	dv := DataValidator{
+		Conv: buildValidationConv(),
+		InfoSchema: hashingInfoSchema{fakeInfoSchema{
+			rows:    map[string][]testRow{"t1": srcOrders},
+			badRows: map[string]int64{},
+		}},
+		Spanner:           fakeSpannerReader{rows: map[string][]testRow{"orders": spOrders}},
+		Chunks:            8,
+		MaxMismatchedKeys: 3,
+		CompareValues:     true,
+	}
+	orders := dv.validateTable(context.Background(), "t1")
+	assert.Equal(t, StatusMismatch, orders.Status)
+	assert.Equal(t, []reports.ChunkMismatch{
+		{Chunk: "id < 14", SrcRows: 13, SpRows: 12},
+		{Chunk: "40 <= id < 53", SrcRows: 13, SpRows: 13},
+		{Chunk: "id >= 92", SrcRows: 9, SpRows: 10},
+	}, orders.MismatchedChunks)
+	assert.Equal(t, int64(4), orders.MismatchedKeyCount)
+	assert.Equal(t, []reports.KeyMismatch{
+		{Key: []string{"7"}, Kind: MissingInSpanner},
+		{Key: []string{"42"}, Kind: ValueMismatch},
+		{Key: []string{"52"}, Kind: ValueMismatch},
+	}, orders.MismatchedKeys)
+}
+
+func TestValidateHashes(t *testing.T) {
+	conv := buildValidationConv()
+	amount := conv.SrcSchema["t1"].ColDefs["c2"]
+	amount.Type = schema.Type{Name: "varchar"}
+	conv.SrcSchema["t1"].ColDefs["c2"] = amount
+	spAmount := conv.SpSchema["t1"].ColDefs["c2"]
+	spAmount.T = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
+	conv.SpSchema["t1"].ColDefs["c2"] = spAmount
+	rows := func() []testRow {
+		var rows []testRow
+		for i := int64(1); i <= 100; i++ {
+			rows = append(rows, testRow{[]string{"id", "amount"}, []interface{}{i, sp.NullString{StringVal: fmt.Sprintf("%d.5", i), Valid: true}}})
+		}
+		return rows
+	}
+	spOrders := rows()
+	spOrders[41].vals[1] = sp.NullString{StringVal: "41.5", Valid: true} // Different value for row 42.
+	spOrders[51].vals[1] = sp.NullString{}                               // NULL value for row 52.
+	var srcRowsRead, spRowsRead int
+	dv := DataValidator{
+		Conv: conv,
+		InfoSchema: hashingInfoSchema{fakeInfoSchema{
+			rows:     map[string][]testRow{"t1": rows()},
+			badRows:  map[string]int64{},
+			rowsRead: &srcRowsRead,
+		}},
+		Spanner:           fakeSpannerReader{rows: map[string][]testRow{"orders": spOrders}, rowsRead: &spRowsRead},
+		Chunks:            8,
+		MaxMismatchedKeys: 3,
+	}
+	orders := dv.validateTable(context.Background(), "t1")
+	assert.Equal(t, StatusMismatch, orders.Status)
+	// Only the chunk whose hashes differ is read.
+	assert.Equal(t, []reports.ChunkMismatch{{Chunk: "40 <= id < 53", SrcRows: 13, SpRows: 13}}, orders.MismatchedChunks)
+	assert.Equal(t, []reports.KeyMismatch{
+		{Key: []string{"42"}, Kind: ValueMismatch},
+		{Key: []string{"52"}, Kind: ValueMismatch},
+	}, orders.MismatchedKeys)
+	assert.Empty(t, orders.UnhashedColumns)
+	assert.Equal(t, 13, srcRowsRead)
+	assert.Equal(t, 13, spRowsRead)
+}
+
+func TestValidateBadRows(t *testing.T) {
+	dv := DataValidator{
+		Conv: buildValidationConv(),
+		InfoSchema: fakeInfoSchema{
+			rows:    map[string][]testRow{"t1": orderRows(10)},
+			badRows: map[string]int64{"t1": 2},
+		},
+		Spanner:           fakeSpannerReader{rows: map[string][]testRow{"orders": orderRows(10)}},
+		Chunks:            4,
+		MaxMismatchedKeys: 10,
+	}
+	tv := dv.validateTable(context.Background(), "t1")
+	assert.Equal(t, StatusMismatch, tv.Status)
+	assert.Equal(t, int64(12), tv.SrcRowCount)
+	assert.Equal(t, int64(10), tv.SpRowCount)
+	assert.Empty(t, tv.MismatchedChunks)
+	assert.Equal(t, "some source rows couldn't be converted", tv.Reason)
+}
+
+func TestCanonicalValue(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		t        ddl.Type
+		v1, v2   *structpb.Value
+		expected string
+	}{
+		{"numeric", ddl.Type{Name: ddl.Numeric}, structpb.NewStringValue("1.500000000"), structpb.NewStringValue("1.5"), "3/2"},
+		{"timestamp", ddl.Type{Name: ddl.Timestamp}, structpb.NewStringValue("2024-01-02T03:04:05.100000000Z"), structpb.NewStringValue("2024-01-02T04:04:05.1+01:00"), "2024-01-02T03:04:05.1Z"},
+		{"json", ddl.Type{Name: ddl.JSON}, structpb.NewStringValue(`{"b": 1, "a": [true]}`), structpb.NewStringValue(`{"a":[true],"b":1}`), `{"a":[true],"b":1}`},
+		{"int64", ddl.Type{Name: ddl.Int64}, structpb.NewStringValue("42"), structpb.NewStringValue("42"), "42"},
+		{"string", ddl.Type{Name: ddl.String}, structpb.NewStringValue("NULL"), structpb.NewStringValue("NULL"), `"NULL"`},
+		{"null", ddl.Type{Name: ddl.String}, structpb.NewNullValue(), nil, "NULL"},
+		{"array", ddl.Type{Name: ddl.Numeric, IsArray: true},
+			structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("2.0"), structpb.NewNullValue()}}),
+			structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewStringValue("2"), structpb.NewNullValue()}}),
+			"[2,NULL]"},
+	} {
+		assert.Equal(t, tc.expected, canonicalValue(tc.t, tc.v1), tc.name)
+		assert.Equal(t, tc.expected, canonicalValue(tc.t, tc.v2), tc.name)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	sp "cloud.google.com/go/spanner"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+)
+
+const nullValue = "NULL"
+
+// sourceValues returns the canonical values of the columns of a converted
+// source row. Columns missing from the row are NULL.
+func sourceValues(td tableData, spCols []string, spVals []interface{}) ([]string, error) {
+	vals := make([]string, len(td.cols))
+	for i := range vals {
+		vals[i] = nullValue
+	}
+	// Encode the values like the Spanner client does when writing them.
+	row, err := sp.NewRow(spCols, spVals)
+	if err != nil {
+		return vals, err
+	}
+	for i, col := range td.cols {
+		j := indexOf(spCols, col)
+		if j < 0 {
+			continue
+		}
+		var v sp.GenericColumnValue
+		if err := row.Column(j, &v); err != nil {
+			return vals, err
+		}
+		vals[i] = canonicalValue(td.types[i], v.Value)
+	}
+	return vals, nil
+}
+
+// spannerValues returns the canonical values of the columns of a Spanner row.
+func spannerValues(td tableData, row *sp.Row) ([]string, error) {
+	vals := make([]string, len(td.cols))
+	for i := range td.cols {
+		var v sp.GenericColumnValue
+		if err := row.Column(i, &v); err != nil {
+			return nil, err
+		}
+		vals[i] = canonicalValue(td.types[i], v.Value)
+	}
+	return vals, nil
+}
+
+// canonicalValue returns a representation of an encoded value of a column
+// of type t that doesn't depend on how the value was written, e.g. the
+// precision of a NUMERIC or the formatting of a JSON document.
+func canonicalValue(t ddl.Type, v *structpb.Value) string {
+	if v == nil {
+		return nullValue
+	}
+	switch k := v.Kind.(type) {
+	case *structpb.Value_NullValue:
+		return nullValue
+	case *structpb.Value_BoolValue:
+		return strconv.FormatBool(k.BoolValue)
+	case *structpb.Value_NumberValue:
+		return strconv.FormatFloat(k.NumberValue, 'g', -1, 64)
+	case *structpb.Value_ListValue:
+		elemType := t
+		elemType.IsArray = false
+		var elems []string
+		for _, e := range k.ListValue.Values {
+			elems = append(elems, canonicalValue(elemType, e))
+		}
+		return "[" + strings.Join(elems, ",") + "]"
+	case *structpb.Value_StringValue:
+		s := k.StringValue
+		switch t.Name {
+		case ddl.Numeric:
+			if r, ok := new(big.Rat).SetString(s); ok {
+				return r.RatString()
+			}
+		case ddl.Timestamp:
+			if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return ts.UTC().Format(time.RFC3339Nano)
+			}
+		case ddl.JSON:
+			var doc interface{}
+			if err := json.Unmarshal([]byte(s), &doc); err == nil {
+				// Marshaling sorts object keys and drops insignificant whitespace.
+				if b, err := json.Marshal(doc); err == nil {
+					return string(b)
+				}
+			}
+		case ddl.Int64, ddl.Float32, ddl.Float64:
+			// INT64 values, and non-finite float values, are encoded as strings.
+			return s
+		}
+		return strconv.Quote(s)
+	}
+	return v.String()
+}
@@ -28,7 +28,7 @@ func dropSecondaryIndexHelper(tableId, idxId string) error {
 
 	usedNames := sessionState.Conv.UsedNames
 	delete(usedNames, strings.ToLower(sp.Indexes[position].Name))
-	index.RemoveIndexIssues(tableId, sp.Indexes[position])
+	index.RemoveIndexIssues(sessionState.Conv, tableId, sp.Indexes[position])
 
 	sp.Indexes = utilities.RemoveSecondaryIndex(sp.Indexes, position)
 	sessionState.Conv.SpSchema[tableId] = sp
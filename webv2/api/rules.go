@@ -50,7 +50,7 @@ func ApplyRule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid rule data", http.StatusInternalServerError)
 			return
 		}
-		setGlobalDataType(typeMap)
+		setGlobalDataType(sessionState.Conv, typeMap)
 	} else if rule.Type == constants.AddIndex {
 		d, err := json.Marshal(rule.Data)
 		if err != nil {
@@ -63,7 +63,7 @@ func ApplyRule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid rule data", http.StatusInternalServerError)
 			return
 		}
-		addedIndex, err := addIndex(newIdx)
+		addedIndex, err := addIndex(sessionState.Conv, newIdx)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -81,7 +81,7 @@ func ApplyRule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid rule data", http.StatusInternalServerError)
 			return
 		}
-		setSpColMaxLength(colMaxLength, rule.AssociatedObjects)
+		setSpColMaxLength(sessionState.Conv, colMaxLength, rule.AssociatedObjects)
 	} else if rule.Type == constants.AddShardIdPrimaryKey {
 		d, err := json.Marshal(rule.Data)
 		if err != nil {
@@ -94,13 +94,13 @@ func ApplyRule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid rule data", http.StatusInternalServerError)
 			return
 		}
-		tableName := checkInterleaving()
+		tableName := checkInterleaving(sessionState.Conv)
 		if tableName != "" {
 			http.Error(w, fmt.Sprintf("Rule cannot be added because some tables, eg: %v are interleaved. Please remove interleaving and try again.", tableName), http.StatusBadRequest)
 			return
 		}
-		setShardIdColumnAsPrimaryKey(shardIdPrimaryKey.AddedAtTheStart)
-		addShardIdColumnToForeignKeys(shardIdPrimaryKey.AddedAtTheStart)
+		setShardIdColumnAsPrimaryKey(sessionState.Conv, shardIdPrimaryKey.AddedAtTheStart)
+		addShardIdColumnToForeignKeys(sessionState.Conv, shardIdPrimaryKey.AddedAtTheStart)
 	} else {
 		http.Error(w, "Invalid rule type", http.StatusInternalServerError)
 		return
@@ -203,7 +203,7 @@ func DropRule(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid rule data", http.StatusInternalServerError)
 			return
 		}
-		tableName := checkInterleaving()
+		tableName := checkInterleaving(conv)
 		if tableName != "" {
 			http.Error(w, fmt.Sprintf("Rule cannot be deleted because some tables, eg: %v are interleaved. Please remove interleaving and try again.", tableName), http.StatusBadRequest)
 			return
@@ -231,33 +231,32 @@ func DropRule(w http.ResponseWriter, r *http.Request) {
 // setGlobalDataType allows to change Spanner type globally.
 // It takes a map from source type to Spanner type and updates
 // the Spanner schema accordingly.
-func setGlobalDataType(typeMap map[string]string) {
-	sessionState := session.GetSessionState()
+func setGlobalDataType(conv *internal.Conv, typeMap map[string]string) {
 
 	// Redo source-to-Spanner typeMap using t (the mapping specified in the http request).
 	// We drive this process by iterating over the Spanner schema because we want to preserve all
 	// other customizations that have been performed via the UI (dropping columns, renaming columns
 	// etc). In particular, note that we can't just blindly redo schema conversion (using an appropriate
 	// version of 'toDDL' with the new typeMap).
-	for tableId, spSchema := range sessionState.Conv.SpSchema {
+	for tableId, spSchema := range conv.SpSchema {
 		for colId := range spSchema.ColDefs {
-			srcColDef := sessionState.Conv.SrcSchema[tableId].ColDefs[colId]
+			srcColDef := conv.SrcSchema[tableId].ColDefs[colId]
 			// If the srcCol's type is in the map, then recalculate the Spanner type
 			// for this column using the map. Otherwise, leave the ColDef for this
 			// column as is. Note that per-column type overrides could be lost in
 			// this process -- the mapping in typeMap always takes precendence.
 			if _, found := typeMap[srcColDef.Type.Name]; found {
-				utilities.UpdateDataType(sessionState.Conv, typeMap[srcColDef.Type.Name], tableId, colId)
+				utilities.UpdateDataType(conv, typeMap[srcColDef.Type.Name], tableId, colId)
 			}
 		}
-		common.ComputeNonKeyColumnSize(sessionState.Conv, tableId)
+		common.ComputeNonKeyColumnSize(conv, tableId)
 	}
 }
 
 // addIndex checks the new name for spanner name validity, ensures the new name is already not used by existing tables
 // secondary indexes or foreign key constraints. If above checks passed then new indexes are added to the schema else appropriate
 // error thrown.
-func addIndex(newIndex ddl.CreateIndex) (ddl.CreateIndex, error) {
+func addIndex(conv *internal.Conv, newIndex ddl.CreateIndex) (ddl.CreateIndex, error) {
 	// Check new name for spanner name validity.
 	newNames := []string{}
 	newNames = append(newNames, newIndex.Name)
@@ -266,50 +265,48 @@ func addIndex(newIndex ddl.CreateIndex) (ddl.CreateIndex, error) {
 		return ddl.CreateIndex{}, fmt.Errorf("following names are not valid Spanner identifiers: %s", strings.Join(invalidNames, ","))
 	}
 	// Check that the new names are not already used by existing tables, secondary indexes or foreign key constraints.
-	if ok, err := utilities.CanRename(newNames, newIndex.TableId); !ok {
+	if ok, err := utilities.CanRename(conv, newNames, newIndex.TableId); !ok {
 		return ddl.CreateIndex{}, err
 	}
 
-	sessionState := session.GetSessionState()
-	sp := sessionState.Conv.SpSchema[newIndex.TableId]
+	sp := conv.SpSchema[newIndex.TableId]
 
 	newIndexes := []ddl.CreateIndex{newIndex}
-	index.CheckIndexSuggestion(newIndexes, sp)
+	index.CheckIndexSuggestion(conv, newIndexes, sp)
 	for i := 0; i < len(newIndexes); i++ {
 		newIndexes[i].Id = internal.GenerateIndexesId()
 	}
 
-	sessionState.Conv.UsedNames[strings.ToLower(newIndex.Name)] = true
+	conv.UsedNames[strings.ToLower(newIndex.Name)] = true
 	sp.Indexes = append(sp.Indexes, newIndexes...)
-	sessionState.Conv.SpSchema[newIndex.TableId] = sp
+	conv.SpSchema[newIndex.TableId] = sp
 	return newIndexes[0], nil
 }
 
-func setSpColMaxLength(spColMaxLength types.ColMaxLength, associatedObjects string) {
-	sessionState := session.GetSessionState()
-	if associatedObjects == "All table" {
-		for tId := range sessionState.Conv.SpSchema {
-			for _, colDef := range sessionState.Conv.SpSchema[tId].ColDefs {
+func setSpColMaxLength(conv *internal.Conv, spColMaxLength types.ColMaxLength, associatedObjects string) {
+	if associatedObjects == "All tables" {
+		for tId := range conv.SpSchema {
+			for _, colDef := range conv.SpSchema[tId].ColDefs {
 				if colDef.T.Name == spColMaxLength.SpDataType {
 					spColDef := colDef
 					if spColDef.T.Len == ddl.MaxLength {
 						spColDef.T.Len, _ = strconv.ParseInt(spColMaxLength.SpColMaxLength, 10, 64)
 					}
-					sessionState.Conv.SpSchema[tId].ColDefs[colDef.Id] = spColDef
+					conv.SpSchema[tId].ColDefs[colDef.Id] = spColDef
 				}
 			}
-			common.ComputeNonKeyColumnSize(sessionState.Conv, tId)
+			common.ComputeNonKeyColumnSize(conv, tId)
 		}
 	} else {
-		for _, colDef := range sessionState.Conv.SpSchema[associatedObjects].ColDefs {
+		for _, colDef := range conv.SpSchema[associatedObjects].ColDefs {
 			if colDef.T.Name == spColMaxLength.SpDataType {
 				spColDef := colDef
 				if spColDef.T.Len == ddl.MaxLength {
-					table.UpdateColumnSize(spColMaxLength.SpColMaxLength, associatedObjects, colDef.Id, sessionState.Conv)
+					table.UpdateColumnSize(spColMaxLength.SpColMaxLength, associatedObjects, colDef.Id, conv)
 				}
 			}
 		}
-		common.ComputeNonKeyColumnSize(sessionState.Conv, associatedObjects)
+		common.ComputeNonKeyColumnSize(conv, associatedObjects)
 	}
 }
 
@@ -396,13 +393,12 @@ func revertShardIdColumnAsPrimaryKey(isAddedAtFirst bool) {
 				pkRequest.Columns = append(pkRequest.Columns, ddl.IndexKey{ColId: pk.ColId, Order: pk.Order - decrement, Desc: pk.Desc})
 			}
 		}
-		primarykey.UpdatePrimaryKey(pkRequest)
+		primarykey.UpdatePrimaryKey(sessionState.Conv, pkRequest)
 	}
 }
 
-func checkInterleaving() string {
-	sessionState := session.GetSessionState()
-	for _, spSchema := range sessionState.Conv.SpSchema {
+func checkInterleaving(conv *internal.Conv) string {
+	for _, spSchema := range conv.SpSchema {
 		if spSchema.ParentTable.Id != "" {
 			return spSchema.Name
 		}
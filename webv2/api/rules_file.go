@@ -0,0 +1,486 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/table"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/types"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/utilities"
+)
+
+// Rule types of a rules file that edit the schema the same way as the
+// corresponding actions of the UI. The rule types of the UI rule engine
+// (constants.GlobalDataTypeChange, constants.AddIndex,
+// constants.EditColumnMaxLength and constants.AddShardIdPrimaryKey) are
+// accepted as well. TransformColumn and SetRowFilter set the transformation
+// applied to the values of a column and the filter applied to the rows of a
+// table during bulk data migration. FixHotspot rewrites the primary key of a
+// table to avoid write hotspots, see internal.HotspotFix.
+// ApplyInterleave applies the interleave proposed for a table by
+// internal.AnalyzeInterleaving, reordering its primary key if needed.
+const (
+	RenameTable         = "rename_table"
+	RenameColumn        = "rename_column"
+	ChangeColumnType    = "change_column_type"
+	DropColumn          = "drop_column"
+	SetInterleaveParent = "set_interleave_parent"
+	TransformColumn     = "transform_column"
+	SetRowFilter        = "set_row_filter"
+	FixHotspot          = "fix_hotspot"
+	ApplyInterleave     = "apply_interleave"
+)
+
+// RulesFile is the YAML (or JSON) file with the list of rules applied to the
+// schema by the --rules flag of the schema commands.
+type RulesFile struct {
+	Rules []FileRule `yaml:"rules"`
+}
+
+// FileRule is a rule of a rules file. Tables and columns are referred to by
+// their Spanner names at the time the rule is applied, so rules that follow a
+// rename rule use the new name.
+type FileRule struct {
+	Type string `yaml:"type"`
+	// Name of the rule recorded in the session for the rule engine types,
+	// defaults to the generated rule id.
+	Name    string `yaml:"name"`
+	Table   string `yaml:"table"`
+	Column  string `yaml:"column"`
+	NewName string `yaml:"newName"`
+	NewType string `yaml:"newType"`
+	// Fields of add_index rules.
+	IndexName string         `yaml:"indexName"`
+	Unique    bool           `yaml:"unique"`
+	Keys      []FileIndexKey `yaml:"keys"`
+	// Field of global_datatype_change rules, mapping source types to Spanner types.
+	TypeMap map[string]string `yaml:"typeMap"`
+	// Fields of edit_column_max_length rules. Table is optional for these rules.
+	SpDataType string `yaml:"spDataType"`
+	MaxLength  int64  `yaml:"maxLength"`
+	// Field of add_shard_id_primary_key rules.
+	AddedAtTheStart bool `yaml:"addedAtTheStart"`
+	// Field of transform_column rules.
+	Transformation *FileTransformation `yaml:"transformation"`
+	// Field of set_row_filter rules, see internal.RowFilter. An empty filter
+	// removes the filter of the table.
+	Filter string `yaml:"filter"`
+	// Fields of fix_hotspot rules. Column defaults to the first primary key
+	// column with a hotspot, and Keys gives the new primary key of
+	// reorder_primary_key fixes.
+	Fix          string `yaml:"fix"`
+	ShardCount   int64  `yaml:"shardCount"`
+	SkipRangeMax int64  `yaml:"skipRangeMax"`
+}
+
+// FileTransformation is the column transformation of a transform_column rule.
+// See internal.ColumnTransformation for the meaning of the fields; the
+// concatenated columns are given by their Spanner names.
+type FileTransformation struct {
+	Kind        string            `yaml:"kind"`
+	Salt        string            `yaml:"salt"`
+	MaskChar    string            `yaml:"maskChar"`
+	KeepFirst   int               `yaml:"keepFirst"`
+	KeepLast    int               `yaml:"keepLast"`
+	Start       int               `yaml:"start"`
+	Length      int               `yaml:"length"`
+	Pattern     string            `yaml:"pattern"`
+	Replacement string            `yaml:"replacement"`
+	Value       string            `yaml:"value"`
+	Lookup      map[string]string `yaml:"lookup"`
+	Columns     []string          `yaml:"columns"`
+	Separator   string            `yaml:"separator"`
+}
+
+// FileIndexKey is a key column of an add_index rule.
+type FileIndexKey struct {
+	Column string `yaml:"column"`
+	Desc   bool   `yaml:"desc"`
+}
+
+// ReadRulesFile reads the rules of the rules file at path. Unknown fields are
+// rejected to catch misspelled rule fields.
+func ReadRulesFile(path string) ([]FileRule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read rules file %s: %v", path, err)
+	}
+	var rf RulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rf); err != nil {
+		return nil, fmt.Errorf("can't parse rules file %s: %v", path, err)
+	}
+	return rf.Rules, nil
+}
+
+// ApplyFileRules applies rules in order to the schema of conv. A rule that
+// fails validation is skipped and the remaining rules are still applied, so
+// that all the invalid rules of a file are reported at once. The returned
+// error lists the failed rules.
+func ApplyFileRules(conv *internal.Conv, rules []FileRule) error {
+	conv.ConvLock.Lock()
+	defer conv.ConvLock.Unlock()
+
+	var errs []error
+	for i, rule := range rules {
+		if err := applyFileRule(conv, rule); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d (%s): %v", i+1, rule.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func applyFileRule(conv *internal.Conv, rule FileRule) error {
+	switch rule.Type {
+	case RenameTable:
+		tableId, err := getRuleTableId(conv, rule)
+		if err != nil {
+			return err
+		}
+		return renameTable(conv, tableId, rule.NewName)
+	case RenameColumn:
+		tableId, colId, err := getRuleColumnId(conv, rule)
+		if err != nil {
+			return err
+		}
+		if err := checkColumnName(conv, tableId, colId, rule.NewName); err != nil {
+			return err
+		}
+		table.RenameColumn(rule.NewName, tableId, colId, conv)
+		common.ComputeNonKeyColumnSize(conv, tableId)
+	case ChangeColumnType:
+		tableId, colId, err := getRuleColumnId(conv, rule)
+		if err != nil {
+			return err
+		}
+		return changeColumnType(conv, tableId, colId, rule.NewType)
+	case DropColumn:
+		tableId, colId, err := getRuleColumnId(conv, rule)
+		if err != nil {
+			return err
+		}
+		if isPrimaryKeyColumn(conv, tableId, colId) {
+			return fmt.Errorf("column %s is part of the primary key of table %s", rule.Column, rule.Table)
+		}
+		table.RemoveColumn(tableId, colId, conv)
+		common.ComputeNonKeyColumnSize(conv, tableId)
+		delete(conv.ColumnTransformations[tableId], colId)
+	case SetInterleaveParent:
+		tableId, err := getRuleTableId(conv, rule)
+		if err != nil {
+			return err
+		}
+		if conv.SpSchema[tableId].ParentTable.Id != "" {
+			return fmt.Errorf("table %s is already interleaved", rule.Table)
+		}
+		status := setParentTableHelper(conv, tableId, true)
+		if conv.SpSchema[tableId].ParentTable.Id == "" {
+			reason := status.Comment
+			if status.Possible {
+				reason = "the primary key columns shared with the parent aren't in the same order"
+			}
+			return fmt.Errorf("table %s can't be interleaved: %s", rule.Table, reason)
+		}
+	case TransformColumn:
+		tableId, colId, err := getRuleColumnId(conv, rule)
+		if err != nil {
+			return err
+		}
+		if rule.Transformation == nil {
+			return fmt.Errorf("transformation is not specified")
+		}
+		t, err := toColumnTransformation(conv, tableId, *rule.Transformation)
+		if err != nil {
+			return err
+		}
+		return conv.SetColumnTransformation(tableId, colId, t)
+	case SetRowFilter:
+		tableId, err := getRuleTableId(conv, rule)
+		if err != nil {
+			return err
+		}
+		srcTable, ok := conv.SrcSchema[tableId]
+		if !ok {
+			return fmt.Errorf("table %s has no source table", rule.Table)
+		}
+		srcTable.RowFilter = rule.Filter
+		conv.SrcSchema[tableId] = srcTable
+	case FixHotspot:
+		tableId, err := getRuleTableId(conv, rule)
+		if err != nil {
+			return err
+		}
+		r, err := toKeyRewrite(conv, tableId, rule)
+		if err != nil {
+			return err
+		}
+		return conv.FixHotspot(tableId, r)
+	case ApplyInterleave:
+		tableId, err := getRuleTableId(conv, rule)
+		if err != nil {
+			return err
+		}
+		return applyInterleaveSuggestion(conv, tableId)
+	case constants.GlobalDataTypeChange:
+		if len(rule.TypeMap) == 0 {
+			return fmt.Errorf("typeMap is empty")
+		}
+		setGlobalDataType(conv, rule.TypeMap)
+		recordRule(conv, rule, "Column", "All Columns", rule.TypeMap)
+	case constants.AddIndex:
+		tableId, err := getRuleTableId(conv, rule)
+		if err != nil {
+			return err
+		}
+		newIdx, err := toCreateIndex(conv, tableId, rule)
+		if err != nil {
+			return err
+		}
+		addedIndex, err := addIndex(conv, newIdx)
+		if err != nil {
+			return err
+		}
+		recordRule(conv, rule, "Table", tableId, addedIndex)
+	case constants.EditColumnMaxLength:
+		if rule.SpDataType != ddl.String && rule.SpDataType != ddl.Bytes {
+			return fmt.Errorf("spDataType must be %s or %s", ddl.String, ddl.Bytes)
+		}
+		if rule.MaxLength <= 0 {
+			return fmt.Errorf("maxLength must be positive")
+		}
+		associatedObjects := "All tables"
+		if rule.Table != "" {
+			tableId, err := getRuleTableId(conv, rule)
+			if err != nil {
+				return err
+			}
+			associatedObjects = tableId
+		}
+		colMaxLength := types.ColMaxLength{SpDataType: rule.SpDataType, SpColMaxLength: strconv.FormatInt(rule.MaxLength, 10)}
+		setSpColMaxLength(conv, colMaxLength, associatedObjects)
+		recordRule(conv, rule, "Table", associatedObjects, colMaxLength)
+	case constants.AddShardIdPrimaryKey:
+		for _, t := range conv.SpSchema {
+			if t.ShardIdColumn == "" {
+				return fmt.Errorf("table %s has no shard id column, shard id primary keys can only be added in sharded migrations", t.Name)
+			}
+		}
+		if tableName := checkInterleaving(conv); tableName != "" {
+			return fmt.Errorf("some tables, eg: %v are interleaved, remove interleaving and try again", tableName)
+		}
+		setShardIdColumnAsPrimaryKey(conv, rule.AddedAtTheStart)
+		addShardIdColumnToForeignKeys(conv, rule.AddedAtTheStart)
+		recordRule(conv, rule, "", "All Tables", types.ShardIdPrimaryKey{AddedAtTheStart: rule.AddedAtTheStart})
+	default:
+		return fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+	return nil
+}
+
+// recordRule adds a rule engine rule to conv like the UI does, so that it is
+// listed and can be dropped in the UI.
+func recordRule(conv *internal.Conv, rule FileRule, objectType, associatedObjects string, data interface{}) {
+	ruleId := internal.GenerateRuleId()
+	name := rule.Name
+	if name == "" {
+		name = ruleId
+	}
+	conv.Rules = append(conv.Rules, internal.Rule{
+		Id:                ruleId,
+		Name:              name,
+		Type:              rule.Type,
+		ObjectType:        objectType,
+		AssociatedObjects: associatedObjects,
+		Enabled:           true,
+		Data:              data,
+	})
+}
+
+// toColumnTransformation converts the transformation of a transform_column
+// rule on a column of table tableId.
+func toColumnTransformation(conv *internal.Conv, tableId string, ft FileTransformation) (internal.ColumnTransformation, error) {
+	t := internal.ColumnTransformation{
+		Kind:        internal.TransformationKind(ft.Kind),
+		Salt:        ft.Salt,
+		MaskChar:    ft.MaskChar,
+		KeepFirst:   ft.KeepFirst,
+		KeepLast:    ft.KeepLast,
+		Start:       ft.Start,
+		Length:      ft.Length,
+		Pattern:     ft.Pattern,
+		Replacement: ft.Replacement,
+		Value:       ft.Value,
+		Lookup:      ft.Lookup,
+		Separator:   ft.Separator,
+	}
+	for _, c := range ft.Columns {
+		colId, err := internal.GetColIdFromSpName(conv.SpSchema[tableId].ColDefs, c)
+		if err != nil {
+			return t, err
+		}
+		t.ColIds = append(t.ColIds, colId)
+	}
+	return t, nil
+}
+
+// toKeyRewrite converts a fix_hotspot rule on table tableId.
+func toKeyRewrite(conv *internal.Conv, tableId string, rule FileRule) (internal.KeyRewrite, error) {
+	if rule.Fix == "" {
+		return internal.KeyRewrite{}, fmt.Errorf("fix is not specified")
+	}
+	r := internal.KeyRewrite{Fix: internal.HotspotFix(rule.Fix), ShardCount: rule.ShardCount, SkipRangeMax: rule.SkipRangeMax}
+	if rule.Column != "" {
+		colId, err := internal.GetColIdFromSpName(conv.SpSchema[tableId].ColDefs, rule.Column)
+		if err != nil {
+			return r, err
+		}
+		r.ColId = colId
+	}
+	for i, k := range rule.Keys {
+		colId, err := internal.GetColIdFromSpName(conv.SpSchema[tableId].ColDefs, k.Column)
+		if err != nil {
+			return r, err
+		}
+		r.PrimaryKeys = append(r.PrimaryKeys, ddl.IndexKey{ColId: colId, Desc: k.Desc, Order: i + 1})
+	}
+	return r, nil
+}
+
+func getRuleTableId(conv *internal.Conv, rule FileRule) (string, error) {
+	if rule.Table == "" {
+		return "", fmt.Errorf("table is not specified")
+	}
+	return internal.GetTableIdFromSpName(conv.SpSchema, rule.Table)
+}
+
+func getRuleColumnId(conv *internal.Conv, rule FileRule) (string, string, error) {
+	tableId, err := getRuleTableId(conv, rule)
+	if err != nil {
+		return "", "", err
+	}
+	if rule.Column == "" {
+		return "", "", fmt.Errorf("column is not specified")
+	}
+	colId, err := internal.GetColIdFromSpName(conv.SpSchema[tableId].ColDefs, rule.Column)
+	if err != nil {
+		return "", "", err
+	}
+	return tableId, colId, nil
+}
+
+// renameTable checks the new name for spanner name validity and ensures the
+// new name is not already used by other tables, secondary indexes or foreign
+// key constraints before renaming the table.
+func renameTable(conv *internal.Conv, tableId, newName string) error {
+	if err := checkSpannerName(newName); err != nil {
+		return err
+	}
+	sp := conv.SpSchema[tableId]
+	if !strings.EqualFold(sp.Name, newName) {
+		if _, ok := conv.UsedNames[strings.ToLower(newName)]; ok {
+			return fmt.Errorf("new name : '%s' is used by another entity", newName)
+		}
+	}
+	delete(conv.UsedNames, strings.ToLower(sp.Name))
+	conv.UsedNames[strings.ToLower(newName)] = true
+	sp.Name = newName
+	conv.SpSchema[tableId] = sp
+	return nil
+}
+
+func checkSpannerName(name string) error {
+	if name == "" {
+		return fmt.Errorf("newName is not specified")
+	}
+	if _, invalid := internal.FixName(name); invalid {
+		return fmt.Errorf("'%s' is not a valid Spanner identifier", name)
+	}
+	return nil
+}
+
+// checkColumnName checks the new name of a column for spanner name validity
+// and ensures that no other column of the table has the same name.
+func checkColumnName(conv *internal.Conv, tableId, colId, newName string) error {
+	if err := checkSpannerName(newName); err != nil {
+		return err
+	}
+	for id, col := range conv.SpSchema[tableId].ColDefs {
+		if id != colId && strings.EqualFold(col.Name, newName) {
+			return fmt.Errorf("new name : '%s' is used by another column of table %s", newName, conv.SpSchema[tableId].Name)
+		}
+	}
+	return nil
+}
+
+func changeColumnType(conv *internal.Conv, tableId, colId, newType string) error {
+	if newType == "" {
+		return fmt.Errorf("newType is not specified")
+	}
+	if _, found := conv.SrcSchema[tableId].ColDefs[colId]; !found {
+		return fmt.Errorf("type of column %s can't be changed since it has no source column", conv.SpSchema[tableId].ColDefs[colId].Name)
+	}
+	typeChange, err := utilities.IsTypeChanged(newType, tableId, colId, conv)
+	if err != nil {
+		return err
+	}
+	if !typeChange {
+		return nil
+	}
+	if err := table.ChangeColumnType(conv, tableId, colId, newType); err != nil {
+		return err
+	}
+	common.ComputeNonKeyColumnSize(conv, tableId)
+	return nil
+}
+
+func isPrimaryKeyColumn(conv *internal.Conv, tableId, colId string) bool {
+	for _, pk := range conv.SpSchema[tableId].PrimaryKeys {
+		if pk.ColId == colId {
+			return true
+		}
+	}
+	return false
+}
+
+func toCreateIndex(conv *internal.Conv, tableId string, rule FileRule) (ddl.CreateIndex, error) {
+	if rule.IndexName == "" {
+		return ddl.CreateIndex{}, fmt.Errorf("indexName is not specified")
+	}
+	if len(rule.Keys) == 0 {
+		return ddl.CreateIndex{}, fmt.Errorf("index %s has no keys", rule.IndexName)
+	}
+	idx := ddl.CreateIndex{Name: rule.IndexName, TableId: tableId, Unique: rule.Unique}
+	for i, k := range rule.Keys {
+		colId, err := internal.GetColIdFromSpName(conv.SpSchema[tableId].ColDefs, k.Column)
+		if err != nil {
+			return ddl.CreateIndex{}, err
+		}
+		idx.Keys = append(idx.Keys, ddl.IndexKey{ColId: colId, Desc: k.Desc, Order: i + 1})
+	}
+	return idx, nil
+}
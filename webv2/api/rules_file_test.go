@@ -0,0 +1,223 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/common/constants"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/schema"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildRulesFileConv() *internal.Conv {
+	conv := internal.MakeConv()
+	conv.Source = constants.MYSQL
+	conv.SrcSchema = map[string]schema.Table{
+		"t1": {
+			Name:   "parent",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2"},
+			ColDefs: map[string]schema.Column{
+				"c1": {Name: "id", Id: "c1", Type: schema.Type{Name: "bigint"}, NotNull: true},
+				"c2": {Name: "name", Id: "c2", Type: schema.Type{Name: "varchar", Mods: []int64{50}}},
+			},
+			PrimaryKeys: []schema.Key{{ColId: "c1", Order: 1}},
+		},
+		"t2": {
+			Name:   "child",
+			Id:     "t2",
+			ColIds: []string{"c3", "c4", "c5", "c6"},
+			ColDefs: map[string]schema.Column{
+				"c3": {Name: "id", Id: "c3", Type: schema.Type{Name: "bigint"}, NotNull: true},
+				"c4": {Name: "item_id", Id: "c4", Type: schema.Type{Name: "bigint"}, NotNull: true},
+				"c5": {Name: "note", Id: "c5", Type: schema.Type{Name: "text"}},
+				"c6": {Name: "legacy", Id: "c6", Type: schema.Type{Name: "text"}},
+			},
+			PrimaryKeys: []schema.Key{{ColId: "c3", Order: 1}, {ColId: "c4", Order: 2}},
+			ForeignKeys: []schema.ForeignKey{{Name: "fk_parent", Id: "f1", ColIds: []string{"c3"}, ReferTableId: "t1", ReferColumnIds: []string{"c1"}}},
+		},
+	}
+	conv.SpSchema = map[string]ddl.CreateTable{
+		"t1": {
+			Name:   "parent",
+			Id:     "t1",
+			ColIds: []string{"c1", "c2"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"c1": {Name: "id", Id: "c1", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"c2": {Name: "name", Id: "c2", T: ddl.Type{Name: ddl.String, Len: 50}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "c1", Order: 1}},
+		},
+		"t2": {
+			Name:   "child",
+			Id:     "t2",
+			ColIds: []string{"c3", "c4", "c5", "c6"},
+			ColDefs: map[string]ddl.ColumnDef{
+				"c3": {Name: "id", Id: "c3", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"c4": {Name: "item_id", Id: "c4", T: ddl.Type{Name: ddl.Int64}, NotNull: true},
+				"c5": {Name: "note", Id: "c5", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+				"c6": {Name: "legacy", Id: "c6", T: ddl.Type{Name: ddl.String, Len: ddl.MaxLength}},
+			},
+			PrimaryKeys: []ddl.IndexKey{{ColId: "c3", Order: 1}, {ColId: "c4", Order: 2}},
+			ForeignKeys: []ddl.Foreignkey{{Name: "fk_parent", Id: "f1", ColIds: []string{"c3"}, ReferTableId: "t1", ReferColumnIds: []string{"c1"}}},
+		},
+	}
+	conv.SchemaIssues = map[string]internal.TableIssues{
+		"t1": {ColumnLevelIssues: map[string][]internal.SchemaIssue{}},
+		"t2": {ColumnLevelIssues: map[string][]internal.SchemaIssue{}},
+	}
+	conv.UsedNames = map[string]bool{"parent": true, "child": true, "fk_parent": true}
+	return conv
+}
+
+func TestApplyFileRules(t *testing.T) {
+	// Restore the id counter that the ids of the UI tests depend on.
+	defer func(objectId string) { internal.Cntr.ObjectId = objectId }(internal.Cntr.ObjectId)
+	conv := buildRulesFileConv()
+	err := api.ApplyFileRules(conv, []api.FileRule{
+		{Type: api.RenameTable, Table: "parent", NewName: "customers"},
+		{Type: api.RenameColumn, Table: "customers", Column: "name", NewName: "full_name"},
+		{Type: api.ChangeColumnType, Table: "child", Column: "note", NewType: ddl.Bytes},
+		{Type: api.DropColumn, Table: "child", Column: "legacy"},
+		{Type: constants.AddIndex, Name: "note index", Table: "child", IndexName: "idx_note", Keys: []api.FileIndexKey{{Column: "note", Desc: true}}},
+		{Type: api.SetInterleaveParent, Table: "child"},
+		{Type: api.DropColumn, Table: "customers", Column: "id"},
+		{Type: api.RenameTable, Table: "child", NewName: "customers"},
+		{Type: "drop_table", Table: "child"},
+		{Type: api.TransformColumn, Table: "customers", Column: "full_name", Transformation: &api.FileTransformation{Kind: "concat", Columns: []string{"id", "full_name"}}},
+		{Type: api.SetRowFilter, Table: "customers", Filter: "id IN (1, 2)"},
+	})
+	assert.Equal(t, "rule 7 (drop_column): column id is part of the primary key of table customers\n"+
+		"rule 8 (rename_table): new name : 'customers' is used by another entity\n"+
+		"rule 9 (drop_table): unknown rule type \"drop_table\"", err.Error())
+
+	assert.Equal(t, "customers", conv.SpSchema["t1"].Name)
+	assert.Equal(t, "id IN (1, 2)", conv.SrcSchema["t1"].RowFilter)
+	assert.Equal(t, map[string]bool{"customers": true, "child": true, "idx_note": true}, conv.UsedNames)
+	assert.Equal(t, "full_name", conv.SpSchema["t1"].ColDefs["c2"].Name)
+	assert.Equal(t, map[string]map[string]internal.ColumnTransformation{
+		"t1": {"c2": {Kind: internal.TransformConcat, ColIds: []string{"c1", "c2"}}},
+	}, conv.ColumnTransformations)
+
+	child := conv.SpSchema["t2"]
+	assert.Equal(t, ddl.Type{Name: ddl.Bytes, Len: ddl.MaxLength}, child.ColDefs["c5"].T)
+	assert.Equal(t, []string{"c3", "c4", "c5"}, child.ColIds)
+	assert.Equal(t, "t1", child.ParentTable.Id)
+	assert.Empty(t, child.ForeignKeys)
+	assert.Equal(t, 1, len(child.Indexes))
+	assert.Equal(t, "idx_note", child.Indexes[0].Name)
+	assert.Equal(t, []ddl.IndexKey{{ColId: "c5", Desc: true, Order: 1}}, child.Indexes[0].Keys)
+
+	assert.Equal(t, 1, len(conv.Rules))
+	assert.Equal(t, "note index", conv.Rules[0].Name)
+	assert.Equal(t, constants.AddIndex, conv.Rules[0].Type)
+	assert.Equal(t, "t2", conv.Rules[0].AssociatedObjects)
+}
+
+func TestApplyFileRulesFixHotspot(t *testing.T) {
+	defer func(objectId string) { internal.Cntr.ObjectId = objectId }(internal.Cntr.ObjectId)
+	conv := buildRulesFileConv()
+	err := api.ApplyFileRules(conv, []api.FileRule{
+		{Type: api.FixHotspot, Table: "parent", Column: "id", Fix: "bit_reversed_sequence", SkipRangeMax: 100000},
+		{Type: api.FixHotspot, Table: "child", Column: "id", Fix: "reorder_primary_key", Keys: []api.FileIndexKey{{Column: "item_id"}, {Column: "id", Desc: true}}},
+		{Type: api.FixHotspot, Table: "child", Column: "id", Fix: "uuid"},
+		{Type: api.FixHotspot, Table: "parent"},
+	})
+	assert.Equal(t, "rule 3 (fix_hotspot): the primary key of table child is already rewritten\n"+
+		"rule 4 (fix_hotspot): fix is not specified", err.Error())
+
+	seqId := conv.KeyRewrites["t1"].SequenceId
+	assert.Equal(t, "parent_id_seq", conv.SpSequences[seqId].Name)
+	assert.Equal(t, "100000", conv.SpSequences[seqId].SkipRangeMax)
+	assert.Equal(t, ddl.AutoGenCol{Name: "parent_id_seq", GenerationType: constants.SEQUENCE}, conv.SpSchema["t1"].ColDefs["c1"].AutoGen)
+	assert.Equal(t, []ddl.IndexKey{{ColId: "c4", Order: 1}, {ColId: "c3", Desc: true, Order: 2}}, conv.SpSchema["t2"].PrimaryKeys)
+}
+
+func TestApplyFileRulesApplyInterleave(t *testing.T) {
+	conv := buildRulesFileConv()
+	child := conv.SpSchema["t2"]
+	child.PrimaryKeys = []ddl.IndexKey{{ColId: "c4", Order: 1}, {ColId: "c3", Order: 2}}
+	conv.SpSchema["t2"] = child
+	err := api.ApplyFileRules(conv, []api.FileRule{
+		{Type: api.ApplyInterleave, Table: "child"},
+		{Type: api.ApplyInterleave, Table: "parent"},
+	})
+	assert.Equal(t, "rule 2 (apply_interleave): no interleave is proposed for table parent", err.Error())
+
+	child = conv.SpSchema["t2"]
+	assert.Equal(t, ddl.InterleavedParent{Id: "t1", OnDelete: constants.FK_NO_ACTION}, child.ParentTable)
+	assert.Equal(t, []ddl.IndexKey{{ColId: "c3", Order: 1}, {ColId: "c4", Order: 2}}, child.PrimaryKeys)
+	assert.Empty(t, child.ForeignKeys)
+	assert.False(t, conv.UsedNames["fk_parent"])
+}
+
+func TestReadRulesFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name    string
+		content string
+		rules   []api.FileRule
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			content: `rules:
+  - type: rename_column
+    table: orders
+    column: cust
+    newName: customer_id
+  - type: add_index
+    table: orders
+    indexName: idx_customer
+    keys:
+      - column: customer_id
+        desc: true
+  - type: global_datatype_change
+    typeMap:
+      bigint: STRING
+`,
+			rules: []api.FileRule{
+				{Type: api.RenameColumn, Table: "orders", Column: "cust", NewName: "customer_id"},
+				{Type: constants.AddIndex, Table: "orders", IndexName: "idx_customer", Keys: []api.FileIndexKey{{Column: "customer_id", Desc: true}}},
+				{Type: constants.GlobalDataTypeChange, TypeMap: map[string]string{"bigint": "STRING"}},
+			},
+		},
+		{
+			name:    "json",
+			content: `{"rules": [{"type": "drop_column", "table": "orders", "column": "legacy"}, {"type": "edit_column_max_length", "spDataType": "STRING", "maxLength": 100}]}`,
+			rules: []api.FileRule{
+				{Type: api.DropColumn, Table: "orders", Column: "legacy"},
+				{Type: constants.EditColumnMaxLength, SpDataType: ddl.String, MaxLength: 100},
+			},
+		},
+		{
+			name:    "unknown field",
+			content: "rules:\n  - type: drop_column\n    tabel: orders\n",
+			wantErr: true,
+		},
+	} {
+		path := filepath.Join(dir, tc.name)
+		assert.Nil(t, os.WriteFile(path, []byte(tc.content), 0644))
+		rules, err := api.ReadRulesFile(path)
+		assert.Equal(t, tc.wantErr, err != nil, tc.name)
+		assert.Equal(t, tc.rules, rules, tc.name)
+	}
+}
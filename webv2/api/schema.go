@@ -88,7 +88,7 @@ func (expressionVerificationHandler *ExpressionsVerificationHandler) ConvertSche
 	}
 	processSchema := common.ProcessSchemaImpl{}
 	ctx := context.Background()
-	ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, conv.SpProjectId, conv.SpInstanceId)
+	ddlVerifier, err := expressions_api.NewDDLVerifierImpl(ctx, conv.SpProjectId, conv.SpInstanceId, false)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Schema Conversion Error : %v", err), http.StatusNotFound)
 		return
@@ -122,8 +122,8 @@ func (expressionVerificationHandler *ExpressionsVerificationHandler) ConvertSche
 	sessionState.Conv = conv
 
 	if sessionState.IsSharded {
-		setShardIdColumnAsPrimaryKey(true)
-		addShardIdColumnToForeignKeys(true)
+		setShardIdColumnAsPrimaryKey(conv, true)
+		addShardIdColumnToForeignKeys(conv, true)
 		ruleId := internal.GenerateRuleId()
 		rule := internal.Rule{
 			Id:                ruleId,
@@ -142,7 +142,7 @@ func (expressionVerificationHandler *ExpressionsVerificationHandler) ConvertSche
 	}
 
 	primarykey.DetectHotspot()
-	index.IndexSuggestion()
+	index.IndexSuggestion(conv)
 
 	sessionMetadata := session.SessionMetadata{
 		SessionName:  "NewSession",
@@ -205,7 +205,7 @@ func (expressionVerificationHandler *ExpressionsVerificationHandler) ConvertSche
 	sessionState.Conv = conv
 
 	primarykey.DetectHotspot()
-	index.IndexSuggestion()
+	index.IndexSuggestion(sessionState.Conv)
 
 	sessionState.SessionMetadata = sessionMetadata
 	sessionState.Driver = dc.Config.Driver
@@ -291,9 +291,9 @@ func SpannerDefaultTypeMap(w http.ResponseWriter, r *http.Request) {
 		typeMap = mysqlDefaultTypeMap
 	case constants.POSTGRES, constants.PGDUMP:
 		typeMap = postgresDefaultTypeMap
-	case constants.SQLSERVER:
+	case constants.SQLSERVER, constants.SQLSERVERDUMP:
 		typeMap = sqlserverDefaultTypeMap
-	case constants.ORACLE:
+	case constants.ORACLE, constants.ORACLEDUMP:
 		typeMap = oracleDefaultTypeMap
 	default:
 		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", sessionState.Driver), http.StatusBadRequest)
@@ -320,9 +320,9 @@ func GetTypeMap(w http.ResponseWriter, r *http.Request) {
 		typeMap = mysqlTypeMap
 	case constants.POSTGRES, constants.PGDUMP:
 		typeMap = postgresTypeMap
-	case constants.SQLSERVER:
+	case constants.SQLSERVER, constants.SQLSERVERDUMP:
 		typeMap = sqlserverTypeMap
-	case constants.ORACLE:
+	case constants.ORACLE, constants.ORACLEDUMP:
 		typeMap = oracleTypeMap
 	default:
 		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", sessionState.Driver), http.StatusBadRequest)
@@ -552,7 +552,7 @@ func RestoreSecondaryIndex(w http.ResponseWriter, r *http.Request) {
 
 	sessionState.Conv = conv
 	index.AssignInitialOrders()
-	index.IndexSuggestion()
+	index.IndexSuggestion(conv)
 
 	convm := session.ConvWithMetadata{
 		SessionMetadata: sessionState.SessionMetadata,
@@ -754,7 +754,7 @@ func UpdateForeignKeys(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check that the new names are not already used by existing tables, secondary indexes or foreign key constraints.
-	if ok, err := utilities.CanRename(newNames, tableId); !ok {
+	if ok, err := utilities.CanRename(sessionState.Conv, newNames, tableId); !ok {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -840,11 +840,11 @@ func RenameIndexes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check that the new names are not already used by existing tables, secondary indexes or foreign key constraints.
-	if ok, err := utilities.CanRename(newNames, table); !ok {
+	sessionState := session.GetSessionState()
+	if ok, err := utilities.CanRename(sessionState.Conv, newNames, table); !ok {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sessionState := session.GetSessionState()
 
 	sp := sessionState.Conv.SpSchema[table]
 
@@ -886,38 +886,7 @@ func SetParentTable(w http.ResponseWriter, r *http.Request) {
 
 	sessionState.Conv.ConvLock.Lock()
 	defer sessionState.Conv.ConvLock.Unlock()
-	tableInterleaveStatus := parentTableHelper(tableId, update)
-
-	if tableInterleaveStatus.Possible {
-
-		childPks := sessionState.Conv.SpSchema[tableId].PrimaryKeys
-		childindex := utilities.GetPrimaryKeyIndexFromOrder(childPks, 1)
-		schemaissue := []internal.SchemaIssue{}
-
-		colId := childPks[childindex].ColId
-		schemaissue = sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colId]
-		if update {
-			schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedOrder)
-		} else {
-			schemaissue = append(schemaissue, internal.InterleavedOrder)
-		}
-
-		sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colId] = schemaissue
-	} else {
-		// Remove "Table cart can be converted as Interleaved Table" suggestion from columns
-		// of the table if interleaving is not possible.
-		for _, colId := range sessionState.Conv.SpSchema[tableId].ColIds {
-			schemaIssue := []internal.SchemaIssue{}
-			for _, v := range sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colId] {
-				if v != internal.InterleavedOrder {
-					schemaIssue = append(schemaIssue, v)
-				}
-			}
-			sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colId] = schemaIssue
-		}
-	}
-
-	index.IndexSuggestion()
+	tableInterleaveStatus := setParentTableHelper(sessionState.Conv, tableId, update)
 	session.UpdateSessionFile()
 	w.WriteHeader(http.StatusOK)
 
@@ -1015,6 +984,82 @@ func RemoveParentTable(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(convm)
 }
 
+// GetInterleaveSuggestions returns the interleaves proposed for the tables
+// of the session, with the issues blocking them.
+func GetInterleaveSuggestions(w http.ResponseWriter, r *http.Request) {
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil || sessionState.Driver == "" {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	sessionState.Conv.ConvLock.RLock()
+	defer sessionState.Conv.ConvLock.RUnlock()
+	suggestions := internal.AnalyzeInterleaving(sessionState.Conv)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// ApplyInterleaveSuggestion reorders the primary key of a table and
+// interleaves it in its parent, as proposed by GetInterleaveSuggestions.
+func ApplyInterleaveSuggestion(w http.ResponseWriter, r *http.Request) {
+	tableId := r.FormValue("table")
+	sessionState := session.GetSessionState()
+	if sessionState.Conv == nil || sessionState.Driver == "" {
+		http.Error(w, fmt.Sprintf("Schema is not converted or Driver is not configured properly. Please retry converting the database to Spanner."), http.StatusNotFound)
+		return
+	}
+	if tableId == "" {
+		http.Error(w, fmt.Sprintf("Table Id is empty"), http.StatusBadRequest)
+		return
+	}
+
+	sessionState.Conv.ConvLock.Lock()
+	defer sessionState.Conv.ConvLock.Unlock()
+	if err := applyInterleaveSuggestion(sessionState.Conv, tableId); err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
+		return
+	}
+	session.UpdateSessionFile()
+
+	convm := session.ConvWithMetadata{
+		SessionMetadata: sessionState.SessionMetadata,
+		Conv:            *sessionState.Conv,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(convm)
+}
+
+// applyInterleaveSuggestion applies the interleave proposed for a table by
+// internal.AnalyzeInterleaving, and removes the interleave issues of its
+// primary key columns.
+func applyInterleaveSuggestion(conv *internal.Conv, tableId string) error {
+	var suggestion *internal.InterleaveSuggestion
+	for _, s := range internal.AnalyzeInterleaving(conv) {
+		if s.TableId == tableId {
+			suggestion = &s
+			break
+		}
+	}
+	if suggestion == nil {
+		return fmt.Errorf("no interleave is proposed for table %s", conv.SpSchema[tableId].Name)
+	}
+	if err := internal.ApplyInterleaveSuggestion(conv, *suggestion); err != nil {
+		return err
+	}
+	if issues, ok := conv.SchemaIssues[tableId]; ok && issues.ColumnLevelIssues != nil {
+		for _, k := range suggestion.PrimaryKeys {
+			schemaissue := issues.ColumnLevelIssues[k.ColId]
+			schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedNotInOrder)
+			schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedAddColumn)
+			schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedRenameColumn)
+			schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedOrder)
+			schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedChangeColumnSize)
+			issues.ColumnLevelIssues[k.ColId] = schemaissue
+		}
+	}
+	return nil
+}
+
 func UpdateIndexes(w http.ResponseWriter, r *http.Request) {
 	table := r.FormValue("table")
 	reqBody, err := ioutil.ReadAll(r.Body)
@@ -1050,7 +1095,7 @@ func UpdateIndexes(w http.ResponseWriter, r *http.Request) {
 	for i, ind := range sp.Indexes {
 		if ind.TableId == newIndexes[0].TableId && ind.Id == newIndexes[0].Id {
 
-			index.RemoveIndexIssues(table, sp.Indexes[i])
+			index.RemoveIndexIssues(sessionState.Conv, table, sp.Indexes[i])
 
 			sp.Indexes[i].Keys = newIndexes[0].Keys
 			sp.Indexes[i].Name = newIndexes[0].Name
@@ -1176,6 +1221,10 @@ func (tableHandler *TableAPIHandler) restoreTableHelper(w http.ResponseWriter, t
 		toddl = mysql.DbDumpImpl{}.GetToDdl()
 	case constants.PGDUMP:
 		toddl = postgres.DbDumpImpl{}.GetToDdl()
+	case constants.ORACLEDUMP:
+		toddl = oracle.DbDumpImpl{}.GetToDdl()
+	case constants.SQLSERVERDUMP:
+		toddl = sqlserver.DbDumpImpl{}.GetToDdl()
 	default:
 		http.Error(w, fmt.Sprintf("Driver : '%s' is not supported", sessionState.Driver), http.StatusBadRequest)
 	}
@@ -1191,8 +1240,8 @@ func (tableHandler *TableAPIHandler) restoreTableHelper(w http.ResponseWriter, t
 		isPresent, isAddedAtFirst := hasShardIdPrimaryKeyRule()
 		if isPresent {
 			table := sessionState.Conv.SpSchema[tableId]
-			setShardIdColumnAsPrimaryKeyPerTable(isAddedAtFirst, table)
-			addShardIdToForeignKeyPerTable(isAddedAtFirst, table)
+			setShardIdColumnAsPrimaryKeyPerTable(conv, isAddedAtFirst, table)
+			addShardIdToForeignKeyPerTable(conv, isAddedAtFirst, table)
 			addShardIdToReferencedTableFks(tableId, isAddedAtFirst)
 			session.UpdateSessionFile()
 		}
@@ -1207,57 +1256,94 @@ func (tableHandler *TableAPIHandler) restoreTableHelper(w http.ResponseWriter, t
 	return convm
 }
 
-func parentTableHelper(tableId string, update bool) *types.TableInterleaveStatus {
+// setParentTableHelper checks whether specified table can be interleaved, updates the interleave
+// suggestions of the table and interleaves the table in its parent if 'update' parameter is set to true.
+func setParentTableHelper(conv *internal.Conv, tableId string, update bool) *types.TableInterleaveStatus {
+	tableInterleaveStatus := parentTableHelper(conv, tableId, update)
+
+	if tableInterleaveStatus.Possible {
+
+		childPks := conv.SpSchema[tableId].PrimaryKeys
+		childindex := utilities.GetPrimaryKeyIndexFromOrder(childPks, 1)
+		schemaissue := []internal.SchemaIssue{}
+
+		colId := childPks[childindex].ColId
+		schemaissue = conv.SchemaIssues[tableId].ColumnLevelIssues[colId]
+		if update {
+			schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedOrder)
+		} else {
+			schemaissue = append(schemaissue, internal.InterleavedOrder)
+		}
+
+		conv.SchemaIssues[tableId].ColumnLevelIssues[colId] = schemaissue
+	} else {
+		// Remove "Table cart can be converted as Interleaved Table" suggestion from columns
+		// of the table if interleaving is not possible.
+		for _, colId := range conv.SpSchema[tableId].ColIds {
+			schemaIssue := []internal.SchemaIssue{}
+			for _, v := range conv.SchemaIssues[tableId].ColumnLevelIssues[colId] {
+				if v != internal.InterleavedOrder {
+					schemaIssue = append(schemaIssue, v)
+				}
+			}
+			conv.SchemaIssues[tableId].ColumnLevelIssues[colId] = schemaIssue
+		}
+	}
+
+	index.IndexSuggestion(conv)
+	return tableInterleaveStatus
+}
+
+func parentTableHelper(conv *internal.Conv, tableId string, update bool) *types.TableInterleaveStatus {
 	tableInterleaveStatus := &types.TableInterleaveStatus{
 		Possible: false,
 		Comment:  "No valid prefix",
 	}
-	sessionState := session.GetSessionState()
 
-	if _, found := sessionState.Conv.SyntheticPKeys[tableId]; found {
+	if _, found := conv.SyntheticPKeys[tableId]; found {
 		tableInterleaveStatus.Possible = false
 		tableInterleaveStatus.Comment = "Has synthetic pk"
 	}
 
-	childPks := sessionState.Conv.SpSchema[tableId].PrimaryKeys
+	childPks := conv.SpSchema[tableId].PrimaryKeys
 
 	// Search this table's foreign keys for a suitable parent table.
 	// If there are several possible parent tables, we pick the first one.
 	// TODO: Allow users to pick which parent to use if more than one.
-	for i, fk := range sessionState.Conv.SpSchema[tableId].ForeignKeys {
+	for i, fk := range conv.SpSchema[tableId].ForeignKeys {
 		refTableId := fk.ReferTableId
 		onDelete := fk.OnDelete
 		var err error
 
-		if _, found := sessionState.Conv.SyntheticPKeys[refTableId]; found {
+		if _, found := conv.SyntheticPKeys[refTableId]; found {
 			continue
 		}
 
-		if checkPrimaryKeyPrefix(tableId, refTableId, fk, tableInterleaveStatus) {
-			sp := sessionState.Conv.SpSchema[tableId]
+		if checkPrimaryKeyPrefix(conv, tableId, refTableId, fk, tableInterleaveStatus) {
+			sp := conv.SpSchema[tableId]
 
-			colIdNotInOrder := checkPrimaryKeyOrder(tableId, refTableId, fk)
+			colIdNotInOrder := checkPrimaryKeyOrder(conv, tableId, refTableId, fk)
 
 			if update && sp.ParentTable.Id == "" && colIdNotInOrder == "" {
-				usedNames := sessionState.Conv.UsedNames
+				usedNames := conv.UsedNames
 				delete(usedNames, strings.ToLower(sp.ForeignKeys[i].Name))
 				sp.ParentTable.Id = refTableId
 				sp.ParentTable.OnDelete = onDelete
-				sp.ForeignKeys, err = utilities.RemoveFk(sp.ForeignKeys, sp.ForeignKeys[i].Id, sessionState.Conv.SrcSchema[tableId], tableId)
+				sp.ForeignKeys, err = utilities.RemoveFk(sp.ForeignKeys, sp.ForeignKeys[i].Id, conv.SrcSchema[tableId], tableId)
 				if err != nil {
 					continue
 				}
 			}
-			sessionState.Conv.SpSchema[tableId] = sp
+			conv.SpSchema[tableId] = sp
 
-			parentpks := sessionState.Conv.SpSchema[refTableId].PrimaryKeys
+			parentpks := conv.SpSchema[refTableId].PrimaryKeys
 			if len(parentpks) >= 1 {
 				if colIdNotInOrder == "" {
 
 					schemaissue := []internal.SchemaIssue{}
 					for _, column := range childPks {
 						colId := column.ColId
-						schemaissue = sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colId]
+						schemaissue = conv.SchemaIssues[tableId].ColumnLevelIssues[colId]
 
 						schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedNotInOrder)
 						schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedAddColumn)
@@ -1265,7 +1351,7 @@ func parentTableHelper(tableId string, update bool) *types.TableInterleaveStatus
 						schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedOrder)
 						schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedChangeColumnSize)
 
-						sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colId] = schemaissue
+						conv.SchemaIssues[tableId].ColumnLevelIssues[colId] = schemaissue
 					}
 
 					tableInterleaveStatus.Possible = true
@@ -1276,7 +1362,7 @@ func parentTableHelper(tableId string, update bool) *types.TableInterleaveStatus
 				} else {
 
 					schemaissue := []internal.SchemaIssue{}
-					schemaissue = sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colIdNotInOrder]
+					schemaissue = conv.SchemaIssues[tableId].ColumnLevelIssues[colIdNotInOrder]
 
 					schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedOrder)
 					schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedAddColumn)
@@ -1285,7 +1371,7 @@ func parentTableHelper(tableId string, update bool) *types.TableInterleaveStatus
 
 					schemaissue = append(schemaissue, internal.InterleavedNotInOrder)
 
-					sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colIdNotInOrder] = schemaissue
+					conv.SchemaIssues[tableId].ColumnLevelIssues[colIdNotInOrder] = schemaissue
 				}
 			}
 		}
@@ -1294,12 +1380,11 @@ func parentTableHelper(tableId string, update bool) *types.TableInterleaveStatus
 	return tableInterleaveStatus
 }
 
-func checkPrimaryKeyOrder(tableId string, refTableId string, fk ddl.Foreignkey) string {
-	sessionState := session.GetSessionState()
-	childPks := sessionState.Conv.SpSchema[tableId].PrimaryKeys
-	parentPks := sessionState.Conv.SpSchema[refTableId].PrimaryKeys
-	childTable := sessionState.Conv.SpSchema[tableId]
-	parentTable := sessionState.Conv.SpSchema[refTableId]
+func checkPrimaryKeyOrder(conv *internal.Conv, tableId string, refTableId string, fk ddl.Foreignkey) string {
+	childPks := conv.SpSchema[tableId].PrimaryKeys
+	parentPks := conv.SpSchema[refTableId].PrimaryKeys
+	childTable := conv.SpSchema[tableId]
+	parentTable := conv.SpSchema[refTableId]
 	for i := 0; i < len(parentPks); i++ {
 		for j := 0; j < len(childPks); j++ {
 			for k := 0; k < len(fk.ReferColumnIds); k++ {
@@ -1319,12 +1404,11 @@ func checkPrimaryKeyOrder(tableId string, refTableId string, fk ddl.Foreignkey)
 	return ""
 }
 
-func checkPrimaryKeyPrefix(tableId string, refTableId string, fk ddl.Foreignkey, tableInterleaveStatus *types.TableInterleaveStatus) bool {
-	sessionState := session.GetSessionState()
-	childTable := sessionState.Conv.SpSchema[tableId]
-	parentTable := sessionState.Conv.SpSchema[refTableId]
-	childPks := sessionState.Conv.SpSchema[tableId].PrimaryKeys
-	parentPks := sessionState.Conv.SpSchema[refTableId].PrimaryKeys
+func checkPrimaryKeyPrefix(conv *internal.Conv, tableId string, refTableId string, fk ddl.Foreignkey, tableInterleaveStatus *types.TableInterleaveStatus) bool {
+	childTable := conv.SpSchema[tableId]
+	parentTable := conv.SpSchema[refTableId]
+	childPks := conv.SpSchema[tableId].PrimaryKeys
+	parentPks := conv.SpSchema[refTableId].PrimaryKeys
 	possibleInterleave := false
 
 	flag := false
@@ -1344,7 +1428,7 @@ func checkPrimaryKeyPrefix(tableId string, refTableId string, fk ddl.Foreignkey,
 	}
 
 	if !possibleInterleave {
-		removeInterleaveSuggestions(fk.ColIds, tableId)
+		removeInterleaveSuggestions(conv, fk.ColIds, tableId)
 		return false
 	}
 
@@ -1460,14 +1544,13 @@ func updateInterleaveSuggestion(colIds []string, tableId string, issue internal.
 	}
 }
 
-func removeInterleaveSuggestions(colIds []string, tableId string) {
-	sessionState := session.GetSessionState()
+func removeInterleaveSuggestions(conv *internal.Conv, colIds []string, tableId string) {
 
 	for i := 0; i < len(colIds); i++ {
 
 		schemaissue := []internal.SchemaIssue{}
 
-		schemaissue = sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colIds[i]]
+		schemaissue = conv.SchemaIssues[tableId].ColumnLevelIssues[colIds[i]]
 
 		if len(schemaissue) == 0 {
 			continue
@@ -1479,16 +1562,16 @@ func removeInterleaveSuggestions(colIds []string, tableId string) {
 		schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedRenameColumn)
 		schemaissue = utilities.RemoveSchemaIssue(schemaissue, internal.InterleavedChangeColumnSize)
 
-		if sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues == nil {
+		if conv.SchemaIssues[tableId].ColumnLevelIssues == nil {
 
 			s := map[string][]internal.SchemaIssue{
 				colIds[i]: schemaissue,
 			}
-			sessionState.Conv.SchemaIssues[tableId] = internal.TableIssues{
+			conv.SchemaIssues[tableId] = internal.TableIssues{
 				ColumnLevelIssues: s,
 			}
 		} else {
-			sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[colIds[i]] = schemaissue
+			conv.SchemaIssues[tableId].ColumnLevelIssues[colIds[i]] = schemaissue
 		}
 
 	}
@@ -1688,14 +1771,13 @@ func addTypeToList(convertedType string, spType string, issues []internal.Schema
 	return l
 }
 
-func setShardIdColumnAsPrimaryKey(isAddedAtFirst bool) {
-	sessionState := session.GetSessionState()
-	for _, table := range sessionState.Conv.SpSchema {
-		setShardIdColumnAsPrimaryKeyPerTable(isAddedAtFirst, table)
+func setShardIdColumnAsPrimaryKey(conv *internal.Conv, isAddedAtFirst bool) {
+	for _, table := range conv.SpSchema {
+		setShardIdColumnAsPrimaryKeyPerTable(conv, isAddedAtFirst, table)
 	}
 }
 
-func setShardIdColumnAsPrimaryKeyPerTable(isAddedAtFirst bool, table ddl.CreateTable) {
+func setShardIdColumnAsPrimaryKeyPerTable(conv *internal.Conv, isAddedAtFirst bool, table ddl.CreateTable) {
 	pkRequest := primarykey.PrimaryKeyRequest{
 		TableId: table.Id,
 		Columns: []ddl.IndexKey{},
@@ -1713,20 +1795,18 @@ func setShardIdColumnAsPrimaryKeyPerTable(isAddedAtFirst bool, table ddl.CreateT
 		size := len(table.PrimaryKeys)
 		pkRequest.Columns = append(pkRequest.Columns, ddl.IndexKey{ColId: table.ShardIdColumn, Order: size + 1})
 	}
-	primarykey.UpdatePrimaryKey(pkRequest)
+	primarykey.UpdatePrimaryKey(conv, pkRequest)
 }
 
-func addShardIdColumnToForeignKeys(isAddedAtFirst bool) {
-	sessionState := session.GetSessionState()
-	for _, table := range sessionState.Conv.SpSchema {
-		addShardIdToForeignKeyPerTable(isAddedAtFirst, table)
+func addShardIdColumnToForeignKeys(conv *internal.Conv, isAddedAtFirst bool) {
+	for _, table := range conv.SpSchema {
+		addShardIdToForeignKeyPerTable(conv, isAddedAtFirst, table)
 	}
 }
 
-func addShardIdToForeignKeyPerTable(isAddedAtFirst bool, table ddl.CreateTable) {
-	sessionState := session.GetSessionState()
+func addShardIdToForeignKeyPerTable(conv *internal.Conv, isAddedAtFirst bool, table ddl.CreateTable) {
 	for i, fk := range table.ForeignKeys {
-		referredTableShardIdColumn := sessionState.Conv.SpSchema[fk.ReferTableId].ShardIdColumn
+		referredTableShardIdColumn := conv.SpSchema[fk.ReferTableId].ShardIdColumn
 		if isAddedAtFirst {
 			fk.ColIds = append([]string{table.ShardIdColumn}, fk.ColIds...)
 			fk.ReferColumnIds = append([]string{referredTableShardIdColumn}, fk.ReferColumnIds...)
@@ -1734,7 +1814,7 @@ func addShardIdToForeignKeyPerTable(isAddedAtFirst bool, table ddl.CreateTable)
 			fk.ColIds = append(fk.ColIds, table.ShardIdColumn)
 			fk.ReferColumnIds = append(fk.ReferColumnIds, referredTableShardIdColumn)
 		}
-		sessionState.Conv.SpSchema[table.Id].ForeignKeys[i] = fk
+		conv.SpSchema[table.Id].ForeignKeys[i] = fk
 	}
 }
 
@@ -2352,6 +2352,88 @@ func TestRemoveParentTable(t *testing.T) {
 	}
 }
 
+func TestApplyInterleaveSuggestion(t *testing.T) {
+	sessionState := session.GetSessionState()
+	sessionState.Driver = constants.MYSQL
+	sessionState.Conv = &internal.Conv{
+		SchemaIssues: map[string]internal.TableIssues{
+			"t1": {ColumnLevelIssues: map[string][]internal.SchemaIssue{"c2": {internal.InterleavedOrder}}},
+		},
+		SrcSchema: map[string]schema.Table{
+			"t1": {
+				Name:        "table1",
+				Id:          "t1",
+				ColIds:      []string{"c1", "c2"},
+				ColDefs:     map[string]schema.Column{"c1": {Name: "b", Id: "c1"}, "c2": {Name: "a", Id: "c2"}},
+				PrimaryKeys: []schema.Key{{ColId: "c1", Order: 1}, {ColId: "c2", Order: 2}},
+				ForeignKeys: []schema.ForeignKey{{Name: "fk1", ColIds: []string{"c2"}, ReferTableId: "t2", ReferColumnIds: []string{"c3"}, Id: "f1", OnDelete: constants.FK_CASCADE}},
+			},
+			"t2": {
+				Name:        "table2",
+				Id:          "t2",
+				ColIds:      []string{"c3"},
+				ColDefs:     map[string]schema.Column{"c3": {Name: "a", Id: "c3"}},
+				PrimaryKeys: []schema.Key{{ColId: "c3", Order: 1}},
+			},
+		},
+		SpSchema: map[string]ddl.CreateTable{
+			"t1": {
+				Name:   "table1",
+				Id:     "t1",
+				ColIds: []string{"c1", "c2"},
+				ColDefs: map[string]ddl.ColumnDef{
+					"c1": {Name: "b", Id: "c1", T: ddl.Type{Name: ddl.Int64}},
+					"c2": {Name: "a", Id: "c2", T: ddl.Type{Name: ddl.Int64}},
+				},
+				PrimaryKeys: []ddl.IndexKey{{ColId: "c1", Order: 1}, {ColId: "c2", Order: 2}},
+				ForeignKeys: []ddl.Foreignkey{{Name: "fk1", ColIds: []string{"c2"}, ReferTableId: "t2", ReferColumnIds: []string{"c3"}, Id: "f1", OnDelete: constants.FK_CASCADE}},
+			},
+			"t2": {
+				Name:        "table2",
+				Id:          "t2",
+				ColIds:      []string{"c3"},
+				ColDefs:     map[string]ddl.ColumnDef{"c3": {Name: "a", Id: "c3", T: ddl.Type{Name: ddl.Int64}}},
+				PrimaryKeys: []ddl.IndexKey{{ColId: "c3", Order: 1}},
+			},
+		},
+		Audit: internal.Audit{
+			MigrationType: migration.MigrationData_SCHEMA_ONLY.Enum(),
+		},
+		UsedNames: map[string]bool{"table1": true, "table2": true, "fk1": true},
+	}
+
+	req, err := http.NewRequest("GET", "/interleave/suggestions", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(api.GetInterleaveSuggestions).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var suggestions []internal.InterleaveSuggestion
+	json.Unmarshal(rr.Body.Bytes(), &suggestions)
+	assert.Equal(t, 1, len(suggestions))
+	assert.Equal(t, []string{"a", "b"}, suggestions[0].PrimaryKey)
+
+	req, err = http.NewRequest("POST", "/interleave/apply?table=t1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(api.ApplyInterleaveSuggestion).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var res *internal.Conv
+	json.Unmarshal(rr.Body.Bytes(), &res)
+	assert.Equal(t, ddl.InterleavedParent{Id: "t2", OnDelete: constants.FK_CASCADE}, res.SpSchema["t1"].ParentTable)
+	assert.Equal(t, []ddl.IndexKey{{ColId: "c2", Order: 1}, {ColId: "c1", Order: 2}}, res.SpSchema["t1"].PrimaryKeys)
+	assert.Empty(t, res.SpSchema["t1"].ForeignKeys)
+	assert.Empty(t, res.SchemaIssues["t1"].ColumnLevelIssues["c2"])
+
+	// Nothing is left to interleave.
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(api.ApplyInterleaveSuggestion).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
 func buildConvMySQL(conv *internal.Conv) {
 	conv.SrcSchema = map[string]schema.Table{
 		"t1": {
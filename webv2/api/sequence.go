@@ -40,7 +40,7 @@ func AddNewSequence(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check that the new names are not already used by existing tables, secondary indexes, sequence or foreign key constraints.
-	if ok, err := utilities.CanRename([]string{seq.Name}, ""); !ok {
+	if ok, err := utilities.CanRename(sessionState.Conv, []string{seq.Name}, ""); !ok {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -177,6 +177,10 @@ func GetSourceDatabaseFromDriver(driver string) (string, error) {
 		return constants.POSTGRES, nil
 	case constants.ORACLE, constants.SQLSERVER:
 		return driver, nil
+	case constants.ORACLEDUMP:
+		return constants.ORACLE, nil
+	case constants.SQLSERVERDUMP:
+		return constants.SQLSERVER, nil
 	default:
 		return "", fmt.Errorf("unsupported driver type: %v", driver)
 	}
@@ -22,12 +22,10 @@ import (
 )
 
 // IndexSuggestion adds redundant index issue and interleved index suggestion in issues and suggestions tab.
-func IndexSuggestion() {
+func IndexSuggestion(conv *internal.Conv) {
 
-	sessionState := session.GetSessionState()
-
-	for _, spannerTable := range sessionState.Conv.SpSchema {
-		CheckIndexSuggestion(spannerTable.Indexes, spannerTable)
+	for _, spannerTable := range conv.SpSchema {
+		CheckIndexSuggestion(conv, spannerTable.Indexes, spannerTable)
 	}
 }
 
@@ -50,15 +48,15 @@ func AssignInitialOrders() {
 }
 
 // Helper method for checking Index Suggestion.
-func CheckIndexSuggestion(index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
+func CheckIndexSuggestion(conv *internal.Conv, index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
 
-	checkRedundantIndex(index, spannerTable)
-	checkInterleaveIndex(index, spannerTable)
+	checkRedundantIndex(conv, index, spannerTable)
+	checkInterleaveIndex(conv, index, spannerTable)
 }
 
 // redundantIndex check for redundant Index.
 // If present adds Redundant as an issue in Issues.
-func checkRedundantIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
+func checkRedundantIndex(conv *internal.Conv, index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
 
 	var primaryKeyFirstColumnId string
 	pks := spannerTable.PrimaryKeys
@@ -76,10 +74,9 @@ func checkRedundantIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 
 			if primaryKeyFirstColumnId == indexFirstColumnId {
 				columnId := indexFirstColumnId
-				sessionState := session.GetSessionState()
-				schemaissue := sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId]
+				schemaissue := conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId]
 				schemaissue = append(schemaissue, internal.RedundantIndex)
-				sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId] = schemaissue
+				conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId] = schemaissue
 			}
 		}
 	}
@@ -87,13 +84,11 @@ func checkRedundantIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 
 // interleaveIndex suggests if an index can be converted to interleave.
 // If possible it gets added as a suggestion.
-func checkInterleaveIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
+func checkInterleaveIndex(conv *internal.Conv, index []ddl.CreateIndex, spannerTable ddl.CreateTable) {
 
 	// Suggestion gets added only if the table can be interleaved.
 	isInterleavable := spannerTable.ParentTable.Id != ""
 
-	sessionState := session.GetSessionState()
-
 	if isInterleavable {
 
 		var primaryKeyFirstColumnId string
@@ -113,13 +108,13 @@ func checkInterleaveIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 				// Ensuring it is not a redundant index.
 				if primaryKeyFirstColumnId != indexFirstColumnId {
 
-					schemaissue := sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[indexFirstColumnId]
+					schemaissue := conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[indexFirstColumnId]
 					fks := spannerTable.ForeignKeys
 
 					for i := range fks {
 						if fks[i].ColIds[0] == indexFirstColumnId {
 							schemaissue = append(schemaissue, internal.InterleaveIndex)
-							sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[indexFirstColumnId] = schemaissue
+							conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[indexFirstColumnId] = schemaissue
 
 						}
 					}
@@ -127,7 +122,7 @@ func checkInterleaveIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 					// Interleave suggestion if the column is of type auto increment.
 					if utilities.IsSchemaIssuePresent(schemaissue, internal.AutoIncrement) {
 						schemaissue = append(schemaissue, internal.AutoIncrementIndex)
-						sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[indexFirstColumnId] = schemaissue
+						conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[indexFirstColumnId] = schemaissue
 					}
 
 					for _, c := range spannerTable.ColDefs {
@@ -137,10 +132,10 @@ func checkInterleaveIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 							if c.T.Name == ddl.Timestamp {
 
 								columnId := c.Id
-								schemaissue := sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId]
+								schemaissue := conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId]
 
 								schemaissue = append(schemaissue, internal.AutoIncrementIndex)
-								sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId] = schemaissue
+								conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId] = schemaissue
 							}
 						}
 					}
@@ -155,37 +150,36 @@ func checkInterleaveIndex(index []ddl.CreateIndex, spannerTable ddl.CreateTable)
 // RemoveIndexIssues removes the issues in a column which is part of the passed Index.
 // This is called when we drop an index or make changes in the primarykey of the current table.
 // Editing the primary key can affect the issues in an index (eg. Changing pk order affects Redundant index issue).
-func RemoveIndexIssues(tableId string, Index ddl.CreateIndex) {
+func RemoveIndexIssues(conv *internal.Conv, tableId string, Index ddl.CreateIndex) {
 
-	sessionState := session.GetSessionState()
 	for i := 0; i < len(Index.Keys); i++ {
 
 		columnId := Index.Keys[i].ColId
 
 		{
 			schemaissue := []internal.SchemaIssue{}
-			if sessionState.Conv.SchemaIssues != nil {
-				schemaissue = sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[columnId]
+			if conv.SchemaIssues != nil {
+				schemaissue = conv.SchemaIssues[tableId].ColumnLevelIssues[columnId]
 			}
 
 			if len(schemaissue) > 0 {
 
 				schemaissue = removeColumnIssue(schemaissue)
 
-				if sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[columnId] == nil {
+				if conv.SchemaIssues[tableId].ColumnLevelIssues[columnId] == nil {
 
 					s := map[string][]internal.SchemaIssue{
 						columnId: schemaissue,
 					}
-					sessionState.Conv.SchemaIssues = map[string]internal.TableIssues{}
+					conv.SchemaIssues = map[string]internal.TableIssues{}
 
-					sessionState.Conv.SchemaIssues[tableId] = internal.TableIssues{
+					conv.SchemaIssues[tableId] = internal.TableIssues{
 						ColumnLevelIssues: s,
 					}
 
 				} else {
 
-					sessionState.Conv.SchemaIssues[tableId].ColumnLevelIssues[columnId] = schemaissue
+					conv.SchemaIssues[tableId].ColumnLevelIssues[columnId] = schemaissue
 
 				}
 			}
@@ -22,85 +22,13 @@ import (
 
 // DetectHotspot adds hotspot detected suggestion in schema conversion process for database.
 func DetectHotspot() {
-
 	sessionState := session.GetSessionState()
-
-	for _, spannerTable := range sessionState.Conv.SpSchema {
-
-		isHotSpot(spannerTable.PrimaryKeys, spannerTable)
-	}
-
+	internal.DetectHotspots(sessionState.Conv)
 }
 
-// Helper method for hotspot detection.
+// isHotSpot adds hotspot issues for the primary key columns insert of
+// spannerTable.
 func isHotSpot(insert []ddl.IndexKey, spannerTable ddl.CreateTable) {
-
-	hotspotTimestamp(insert, spannerTable)
-	hotspotAutoincrement(insert, spannerTable)
-}
-
-// hotspotTimestamp checks Timestamp hotspot.
-// If present adds HotspotTimestamp as an issue in Issues.
-func hotspotTimestamp(insert []ddl.IndexKey, spannerTable ddl.CreateTable) {
-	sessionState := session.GetSessionState()
-
-	for i := 0; i < len(insert); i++ {
-
-		for _, c := range spannerTable.ColDefs {
-
-			if insert[i].ColId == c.Id {
-
-				if c.T.Name == ddl.Timestamp {
-
-					columnId := insert[i].ColId
-					schemaissue := sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId]
-
-					schemaissue = append(schemaissue, internal.HotspotTimestamp)
-					sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId] = schemaissue
-				}
-
-			}
-		}
-
-	}
-}
-
-// hotspotAutoincrement check AutoIncrement hotspot.
-// If present adds AutoIncrement as an issue in Issues.
-func hotspotAutoincrement(insert []ddl.IndexKey, spannerTable ddl.CreateTable) {
-
-	for i := 0; i < len(insert); i++ {
-		for _, c := range spannerTable.ColDefs {
-			if insert[i].ColId == c.Name {
-				spannerColumnId := c.Id
-				detecthotspotAutoincrement(spannerTable, spannerColumnId)
-			}
-
-		}
-	}
-}
-
-// detecthotspotAutoincrement checks for autoincrement hotspot.
-// If present it adds HotspotAutoIncrement as an issue in Issues.
-func detecthotspotAutoincrement(spannerTable ddl.CreateTable, spannerColumnId string) {
-
 	sessionState := session.GetSessionState()
-	sourcetable := sessionState.Conv.SrcSchema[spannerTable.Id]
-
-	for _, s := range sourcetable.ColDefs {
-
-		if s.Id == spannerColumnId {
-
-			if s.Ignored.AutoIncrement {
-
-				columnId := s.Id
-				schemaissue := sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId]
-
-				schemaissue = append(schemaissue, internal.HotspotAutoIncrement)
-				sessionState.Conv.SchemaIssues[spannerTable.Id].ColumnLevelIssues[columnId] = schemaissue
-
-			}
-
-		}
-	}
+	internal.DetectKeyHotspots(sessionState.Conv, spannerTable, insert)
 }
@@ -21,6 +21,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/sources/common"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/index"
@@ -70,7 +71,7 @@ func PrimaryKey(w http.ResponseWriter, r *http.Request) {
 	sessionState := session.GetSessionState()
 	sessionState.Conv.ConvLock.Lock()
 	defer sessionState.Conv.ConvLock.Unlock()
-	spannerTable, found := getSpannerTable(sessionState, pkRequest)
+	spannerTable, found := getSpannerTable(sessionState.Conv, pkRequest)
 
 	if !found {
 		log.Println("TableId not found")
@@ -100,7 +101,7 @@ func PrimaryKey(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	UpdatePrimaryKey(pkRequest)
+	UpdatePrimaryKey(sessionState.Conv, pkRequest)
 	session.UpdateSessionFile()
 
 	convm := session.ConvWithMetadata{
@@ -114,23 +115,22 @@ func PrimaryKey(w http.ResponseWriter, r *http.Request) {
 	log.Println("request completed", "traceid", id.String(), "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
 }
 
-func UpdatePrimaryKey(pkRequest PrimaryKeyRequest) {
+func UpdatePrimaryKey(conv *internal.Conv, pkRequest PrimaryKeyRequest) {
 
-	sessionState := session.GetSessionState()
-	spannerTable, _ := getSpannerTable(sessionState, pkRequest)
+	spannerTable, _ := getSpannerTable(conv, pkRequest)
 	tableId := spannerTable.Id
 	synthColId := ""
-	if synthCol, found := sessionState.Conv.SyntheticPKeys[tableId]; found {
+	if synthCol, found := conv.SyntheticPKeys[tableId]; found {
 		synthColId = synthCol.ColId
 	}
 
 	spannerTable, isSynthPkRemoved := updatePrimaryKey(pkRequest, spannerTable, synthColId)
 
 	if isSynthPkRemoved {
-		synthPks := sessionState.Conv.SyntheticPKeys
+		synthPks := conv.SyntheticPKeys
 		delete(synthPks, tableId)
-		sessionState.Conv.SyntheticPKeys = synthPks
-		table.RemoveColumn(tableId, synthColId, sessionState.Conv)
+		conv.SyntheticPKeys = synthPks
+		table.RemoveColumn(tableId, synthColId, conv)
 		colIds := []string{}
 		for _, colId := range spannerTable.ColIds {
 			if colId != synthColId {
@@ -140,16 +140,16 @@ func UpdatePrimaryKey(pkRequest PrimaryKeyRequest) {
 		spannerTable.ColIds = colIds
 	}
 
-	//update spannerTable into sessionState.Conv.SpSchema.
-	for _, table := range sessionState.Conv.SpSchema {
+	//update spannerTable into conv.SpSchema.
+	for _, table := range conv.SpSchema {
 		if pkRequest.TableId == table.Id {
-			sessionState.Conv.SpSchema[table.Id] = spannerTable
+			conv.SpSchema[table.Id] = spannerTable
 			for _, ind := range spannerTable.Indexes {
-				index.RemoveIndexIssues(spannerTable.Id, ind)
+				index.RemoveIndexIssues(conv, spannerTable.Id, ind)
 			}
 		}
 	}
-	common.ComputeNonKeyColumnSize(sessionState.Conv, pkRequest.TableId)
-	RemoveInterleave(sessionState.Conv, spannerTable)
+	common.ComputeNonKeyColumnSize(conv, pkRequest.TableId)
+	RemoveInterleave(conv, spannerTable)
 
 }
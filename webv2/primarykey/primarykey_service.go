@@ -17,14 +17,13 @@ package primarykey
 import (
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/internal"
 	"github.com/GoogleCloudPlatform/spanner-migration-tool/spanner/ddl"
-	"github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/session"
 	utilities "github.com/GoogleCloudPlatform/spanner-migration-tool/webv2/utilities"
 )
 
 // getSpannerTable return spannerTable for given TableId.
-func getSpannerTable(sessionState *session.SessionState, pkRequest PrimaryKeyRequest) (spannerTable ddl.CreateTable, found bool) {
+func getSpannerTable(conv *internal.Conv, pkRequest PrimaryKeyRequest) (spannerTable ddl.CreateTable, found bool) {
 
-	for _, table := range sessionState.Conv.SpSchema {
+	for _, table := range conv.SpSchema {
 
 		if pkRequest.TableId == table.Id {
 			spannerTable = table
@@ -44,7 +44,7 @@ func getRoutes() *mux.Router {
 		Report: &conversion.ReportImpl{},
 	}
 	ctx := context.Background()
-	ddlVerifier, _ := expressions_api.NewDDLVerifierImpl(ctx, "", "")
+	ddlVerifier, _ := expressions_api.NewDDLVerifierImpl(ctx, "", "", false)
 	tableHandler := api.TableAPIHandler{
 		DDLVerifier: ddlVerifier,
 	}
@@ -88,6 +88,8 @@ func getRoutes() *mux.Router {
 	router.HandleFunc("/getSequenceKind", api.GetSequenceKind).Methods("GET")
 	router.HandleFunc("/setparent", api.SetParentTable).Methods("GET")
 	router.HandleFunc("/removeParent", api.RemoveParentTable).Methods("POST")
+	router.HandleFunc("/interleave/suggestions", api.GetInterleaveSuggestions).Methods("GET")
+	router.HandleFunc("/interleave/apply", api.ApplyInterleaveSuggestion).Methods("POST")
 	router.HandleFunc("/verifyCheckConstraintExpression", expressionVerificationHandler.VerifyCheckConstraintExpression).Methods("GET")
 
 	// TODO:(searce) take constraint names themselves which are guaranteed to be unique for Spanner.
@@ -156,6 +156,10 @@ func SaveRemoteSession(w http.ResponseWriter, r *http.Request) {
 		sm.DatabaseType = constants.MYSQL
 	case constants.PGDUMP:
 		sm.DatabaseType = constants.POSTGRES
+	case constants.ORACLEDUMP:
+		sm.DatabaseType = constants.ORACLE
+	case constants.SQLSERVERDUMP:
+		sm.DatabaseType = constants.SQLSERVER
 	default:
 		sm.DatabaseType = sessionState.Driver
 	}
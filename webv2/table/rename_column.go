@@ -25,7 +25,7 @@ func renameColumn(newName, tableId, colId string, conv *internal.Conv) {
 	sp := conv.SpSchema[tableId]
 
 	// update interleave table relation.
-	isParent, childTableId := utilities.IsParent(tableId)
+	isParent, childTableId := utilities.IsParent(conv, tableId)
 
 	if isParent {
 		childColId, err := utilities.GetColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
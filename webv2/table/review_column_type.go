@@ -140,7 +140,7 @@ func reviewColumnTypeForParentTable(newType, tableId, colId string, conv *intern
 
 func reviewColumnTypeForChildTable(newType, tableId, colId string, conv *internal.Conv, interleaveTableSchema []InterleaveTableSchema, w http.ResponseWriter) (_ []InterleaveTableSchema, childTableId string, err error) {
 	sp := conv.SpSchema[tableId]
-	isParent, childTableId := utilities.IsParent(tableId)
+	isParent, childTableId := utilities.IsParent(conv, tableId)
 	if isParent {
 		childColId, err := utilities.GetColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
 		if err == nil {
@@ -188,7 +188,7 @@ func ReviewColumnSize(colSize int64, tableId, colId string, conv *internal.Conv,
 
 func reviewColumnSizeForChildTable(colSize int64, tableId, colId string, conv *internal.Conv, interleaveTableSchema []InterleaveTableSchema) (_ []InterleaveTableSchema, childTableId string) {
 	sp := conv.SpSchema[tableId]
-	isParent, childTableId := utilities.IsParent(tableId)
+	isParent, childTableId := utilities.IsParent(conv, tableId)
 	if isParent {
 		childColId, err := utilities.GetColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
 		if err == nil {
@@ -43,7 +43,7 @@ func reviewRenameColumn(newName, tableId, colId string, conv *internal.Conv, int
 
 func reviewRenameColumnForChildTable(newName, tableId, colId string, conv *internal.Conv, interleaveTableSchema []InterleaveTableSchema) ([]InterleaveTableSchema, string) {
 	sp := conv.SpSchema[tableId]
-	isParent, childTableId := utilities.IsParent(tableId)
+	isParent, childTableId := utilities.IsParent(conv, tableId)
 
 	if isParent {
 		childColId, err := utilities.GetColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
@@ -26,44 +26,54 @@ import (
 
 // UpdateColumnType updates type of given column to newType.
 func UpdateColumnType(newType, tableId, colId string, conv *internal.Conv, w http.ResponseWriter) {
+	if err := ChangeColumnType(conv, tableId, colId, newType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
 
+// ChangeColumnType changes the type of given column to newType, along with
+// the types of the columns related to it by foreign keys and interleaving.
+func ChangeColumnType(conv *internal.Conv, tableId, colId, newType string) error {
 	// update column type for current table.
-	err := UpdateColumnTypeChangeTableSchema(conv, tableId, colId, newType, w)
+	err := UpdateColumnTypeChangeTableSchema(conv, tableId, colId, newType)
 	if err != nil {
-		return
+		return err
 	}
 
 	// update column type for refer tables.
-	err = updateColumnTypeForReferredTable(newType, tableId, colId, conv, w)
+	err = updateColumnTypeForReferredTable(newType, tableId, colId, conv)
 	if err != nil {
-		return
+		return err
 	}
 
 	// update column type for tables referring to the current table.
-	err = updateColumnTypeForReferringTable(newType, tableId, colId, conv, w)
+	err = updateColumnTypeForReferringTable(newType, tableId, colId, conv)
 	if err != nil {
-		return
+		return err
 	}
 
 	// update column type of child table.
-	updateColumnTypeForChildTable(newType, tableId, colId, conv, w)
+	err = updateColumnTypeForChildTable(newType, tableId, colId, conv)
+	if err != nil {
+		return err
+	}
 
 	// update column type of parent table.
-	updateColumnTypeForParentTable(newType, tableId, colId, conv, w)
+	return updateColumnTypeForParentTable(newType, tableId, colId, conv)
 }
 
-func updateColumnTypeForReferredTable(newType, tableId, colId string, conv *internal.Conv, w http.ResponseWriter) error {
+func updateColumnTypeForReferredTable(newType, tableId, colId string, conv *internal.Conv) error {
 	sp := conv.SpSchema[tableId]
 	for _, fk := range sp.ForeignKeys {
 		fkReferColPosition := getFkColumnPosition(fk.ColIds, colId)
 		if fkReferColPosition == -1 {
 			continue
 		}
-		err := UpdateColumnTypeChangeTableSchema(conv, fk.ReferTableId, fk.ReferColumnIds[fkReferColPosition], newType, w)
+		err := UpdateColumnTypeChangeTableSchema(conv, fk.ReferTableId, fk.ReferColumnIds[fkReferColPosition], newType)
 		if err != nil {
 			return err
 		}
-		err = updateColumnTypeForReferredTable(newType, fk.ReferTableId, fk.ReferColumnIds[fkReferColPosition], conv, w)
+		err = updateColumnTypeForReferredTable(newType, fk.ReferTableId, fk.ReferColumnIds[fkReferColPosition], conv)
 		if err != nil {
 			return err
 		}
@@ -71,7 +81,7 @@ func updateColumnTypeForReferredTable(newType, tableId, colId string, conv *inte
 	return nil
 }
 
-func updateColumnTypeForReferringTable(newType, tableId, colId string, conv *internal.Conv, w http.ResponseWriter) error {
+func updateColumnTypeForReferringTable(newType, tableId, colId string, conv *internal.Conv) error {
 	for _, sp := range conv.SpSchema {
 		for j := 0; j < len(sp.ForeignKeys); j++ {
 			if sp.ForeignKeys[j].ReferTableId == tableId {
@@ -79,11 +89,11 @@ func updateColumnTypeForReferringTable(newType, tableId, colId string, conv *int
 				if fkColPosition == -1 {
 					continue
 				}
-				err := UpdateColumnTypeChangeTableSchema(conv, sp.Id, sp.ForeignKeys[j].ColIds[fkColPosition], newType, w)
+				err := UpdateColumnTypeChangeTableSchema(conv, sp.Id, sp.ForeignKeys[j].ColIds[fkColPosition], newType)
 				if err != nil {
 					return err
 				}
-				err = updateColumnTypeForReferringTable(newType, sp.Id, sp.ForeignKeys[j].ColIds[fkColPosition], conv, w)
+				err = updateColumnTypeForReferringTable(newType, sp.Id, sp.ForeignKeys[j].ColIds[fkColPosition], conv)
 				if err != nil {
 					return err
 				}
@@ -93,36 +103,38 @@ func updateColumnTypeForReferringTable(newType, tableId, colId string, conv *int
 	return nil
 }
 
-func updateColumnTypeForChildTable(newType, tableId, colId string, conv *internal.Conv, w http.ResponseWriter) {
+func updateColumnTypeForChildTable(newType, tableId, colId string, conv *internal.Conv) error {
 	sp := conv.SpSchema[tableId]
 
-	isParent, childTableId := utilities.IsParent(tableId)
+	isParent, childTableId := utilities.IsParent(conv, tableId)
 	if isParent {
 		childColId, err := utilities.GetColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
 		if err == nil {
-			err = UpdateColumnTypeChangeTableSchema(conv, childTableId, childColId, newType, w)
+			err = UpdateColumnTypeChangeTableSchema(conv, childTableId, childColId, newType)
 			if err != nil {
-				return
+				return err
 			}
-			updateColumnTypeForChildTable(newType, childTableId, childColId, conv, w)
+			return updateColumnTypeForChildTable(newType, childTableId, childColId, conv)
 		}
 	}
+	return nil
 }
 
-func updateColumnTypeForParentTable(newType, tableId, colId string, conv *internal.Conv, w http.ResponseWriter) {
+func updateColumnTypeForParentTable(newType, tableId, colId string, conv *internal.Conv) error {
 	sp := conv.SpSchema[tableId]
 
 	parentTableId := conv.SpSchema[tableId].ParentTable.Id
 	if parentTableId != "" {
 		parentColId, err := utilities.GetColIdFromSpannerName(conv, parentTableId, sp.ColDefs[colId].Name)
 		if err == nil {
-			err = UpdateColumnTypeChangeTableSchema(conv, parentTableId, parentColId, newType, w)
+			err = UpdateColumnTypeChangeTableSchema(conv, parentTableId, parentColId, newType)
 			if err != nil {
-				return
+				return err
 			}
-			updateColumnTypeForParentTable(newType, parentTableId, parentColId, conv, w)
+			return updateColumnTypeForParentTable(newType, parentTableId, parentColId, conv)
 		}
 	}
+	return nil
 }
 
 func UpdateColumnSize(newSize, tableId, colId string, conv *internal.Conv) {
@@ -136,7 +148,7 @@ func UpdateColumnSize(newSize, tableId, colId string, conv *internal.Conv) {
 
 func updateColumnSizeForChildTable(newSize, tableId, colId string, conv *internal.Conv) {
 	sp := conv.SpSchema[tableId]
-	isParent, childTableId := utilities.IsParent(tableId)
+	isParent, childTableId := utilities.IsParent(conv, tableId)
 	if isParent {
 		childColId, err := utilities.GetColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
 		if err == nil {
@@ -174,11 +186,6 @@ func UpdateColumnSizeChangeTableSchema(conv *internal.Conv, tableId string, colI
 }
 
 // UpdateColumnTypeTableSchema updates column type to newtype for a column of a table.
-func UpdateColumnTypeChangeTableSchema(conv *internal.Conv, tableId string, colId string, newType string, w http.ResponseWriter) error {
-	err := utilities.UpdateDataType(conv, newType, tableId, colId)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return err
-	}
-	return nil
+func UpdateColumnTypeChangeTableSchema(conv *internal.Conv, tableId string, colId string, newType string) error {
+	return utilities.UpdateDataType(conv, newType, tableId, colId)
 }
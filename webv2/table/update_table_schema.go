@@ -93,19 +93,7 @@ func UpdateTableSchema(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if v.Rename != "" && v.Rename != conv.SpSchema[tableId].ColDefs[colId].Name {
-
-			oldName := conv.SrcSchema[tableId].ColDefs[colId].Name
-
-			// Use a regular expression to match the exact column name
-			re := regexp.MustCompile(`\b` + regexp.QuoteMeta(oldName) + `\b`)
-
-			for i := range conv.SpSchema[tableId].CheckConstraints {
-				originalString := conv.SpSchema[tableId].CheckConstraints[i].Expr
-				updatedValue := re.ReplaceAllString(originalString, v.Rename)
-				conv.SpSchema[tableId].CheckConstraints[i].Expr = updatedValue
-			}
-
-			renameColumn(v.Rename, tableId, colId, conv)
+			RenameColumn(v.Rename, tableId, colId, conv)
 		}
 
 		_, found := conv.SrcSchema[tableId].ColDefs[colId]
@@ -150,3 +138,20 @@ func UpdateTableSchema(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(convm)
 }
+
+// RenameColumn renames given column to newName and updates the check
+// constraints of the table that use the column.
+func RenameColumn(newName, tableId, colId string, conv *internal.Conv) {
+	oldName := conv.SrcSchema[tableId].ColDefs[colId].Name
+
+	// Use a regular expression to match the exact column name
+	re := regexp.MustCompile(`\b` + regexp.QuoteMeta(oldName) + `\b`)
+
+	for i := range conv.SpSchema[tableId].CheckConstraints {
+		originalString := conv.SpSchema[tableId].CheckConstraints[i].Expr
+		updatedValue := re.ReplaceAllString(originalString, newName)
+		conv.SpSchema[tableId].CheckConstraints[i].Expr = updatedValue
+	}
+
+	renameColumn(newName, tableId, colId, conv)
+}
@@ -29,7 +29,11 @@ import (
 )
 
 func GetType(conv *internal.Conv, newType, tableId, colId string) (ddl.CreateTable, ddl.Type, error) {
-	sessionState := session.GetSessionState()
+	// Sessions loaded from older session files don't record the source.
+	driver := conv.Source
+	if driver == "" {
+		driver = session.GetSessionState().Driver
+	}
 
 	sp := conv.SpSchema[tableId]
 	srcCol := conv.SrcSchema[tableId].ColDefs[colId]
@@ -37,21 +41,21 @@ func GetType(conv *internal.Conv, newType, tableId, colId string) (ddl.CreateTab
 	var ty ddl.Type
 	var issues []internal.SchemaIssue
 	var toddl common.ToDdl
-	switch sessionState.Driver {
+	switch driver {
 	case constants.MYSQL, constants.MYSQLDUMP:
 		toddl = mysql.InfoSchemaImpl{}.GetToDdl()
 		ty, issues = toddl.ToSpannerType(conv, newType, srcCol.Type, isPk)
 	case constants.PGDUMP, constants.POSTGRES:
 		toddl = postgres.InfoSchemaImpl{}.GetToDdl()
 		ty, issues = toddl.ToSpannerType(conv, newType, srcCol.Type, isPk)
-	case constants.SQLSERVER:
+	case constants.SQLSERVER, constants.SQLSERVERDUMP:
 		toddl = sqlserver.InfoSchemaImpl{}.GetToDdl()
 		ty, issues = toddl.ToSpannerType(conv, newType, srcCol.Type, isPk)
-	case constants.ORACLE:
+	case constants.ORACLE, constants.ORACLEDUMP:
 		toddl = oracle.InfoSchemaImpl{}.GetToDdl()
 		ty, issues = toddl.ToSpannerType(conv, newType, srcCol.Type, isPk)
 	default:
-		return sp, ty, fmt.Errorf("driver : '%s' is not supported", sessionState.Driver)
+		return sp, ty, fmt.Errorf("driver : '%s' is not supported", driver)
 	}
 	if len(srcCol.Type.ArrayBounds) > 0 && conv.SpDialect == constants.DIALECT_POSTGRESQL {
 		ty = ddl.Type{Name: ddl.String, Len: ddl.MaxLength}
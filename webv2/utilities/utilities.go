@@ -306,10 +306,9 @@ func CheckSpannerNamesValidity(input []string) (bool, []string) {
 	return status, invalidNewNames
 }
 
-func CanRename(names []string, table string) (bool, error) {
-	sessionState := session.GetSessionState()
+func CanRename(conv *internal.Conv, names []string, table string) (bool, error) {
 	for _, name := range names {
-		if _, ok := sessionState.Conv.UsedNames[strings.ToLower(name)]; ok {
+		if _, ok := conv.UsedNames[strings.ToLower(name)]; ok {
 			return false, fmt.Errorf("new name : '%s' is used by another entity", name)
 		}
 	}
@@ -383,7 +382,7 @@ func UpdateMaxColumnLen(conv *internal.Conv, dataType, tableId, colId string, sp
 	}
 	sp := conv.SpSchema[tableId]
 	// update column size of child table.
-	isParent, childTableId := IsParent(tableId)
+	isParent, childTableId := IsParent(conv, tableId)
 	if isParent {
 		childColId, err := GetColIdFromSpannerName(conv, childTableId, sp.ColDefs[colId].Name)
 		if err == nil {
@@ -417,10 +416,8 @@ func GetColIdFromSpannerName(conv *internal.Conv, tableId, colName string) (stri
 	return "", fmt.Errorf("column id not found for spaner column %v", colName)
 }
 
-func IsParent(tableId string) (bool, string) {
-	sessionState := session.GetSessionState()
-
-	for _, spSchema := range sessionState.Conv.SpSchema {
+func IsParent(conv *internal.Conv, tableId string) (bool, string) {
+	for _, spSchema := range conv.SpSchema {
 		if spSchema.ParentTable.Id == tableId {
 			return true, spSchema.Id
 		}
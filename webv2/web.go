@@ -448,7 +448,7 @@ func loadSession(w http.ResponseWriter, r *http.Request) {
 	sessionState.Conv = conv
 
 	primarykey.DetectHotspot()
-	index.IndexSuggestion()
+	index.IndexSuggestion(conv)
 
 	sessionState.Conv.UsedNames = internal.ComputeUsedNames(sessionState.Conv)
 